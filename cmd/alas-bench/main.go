@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/bench"
+	"github.com/dshills/alas/internal/codegen"
+	"github.com/dshills/alas/internal/validator"
+)
+
+func main() {
+	var iterations int
+	var optLevel string
+	var libDir string
+	flag.IntVar(&iterations, "n", 10, "Number of times each backend runs main")
+	flag.StringVar(&optLevel, "O", "2", "Optimization level of the compiled program: 0, 1, 2, or 3")
+	flag.StringVar(&libDir, "lib", "lib", "Directory holding libalas_stdlib.so, linked when present (build it with make build-stdlib)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: alas-bench [flags] [program.alas.json ...]\n\n")
+		fmt.Fprintf(os.Stderr, "Runs each program in the interpreter and compiled, checks that main returns\n")
+		fmt.Fprintf(os.Stderr, "the same result on both, and reports their times. Defaults to the example\n")
+		fmt.Fprintf(os.Stderr, "programs in examples/programs.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := bench.DefaultOptions()
+	opts.Iterations = iterations
+	switch optLevel {
+	case "0":
+		opts.OptLevel = codegen.OptNone
+	case "1":
+		opts.OptLevel = codegen.OptBasic
+	case "2":
+		opts.OptLevel = codegen.OptStandard
+	case "3":
+		opts.OptLevel = codegen.OptAggressive
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid optimization level: %s (use 0, 1, 2, or 3)\n", optLevel)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(filepath.Join(libDir, "libalas_stdlib.so")); err == nil {
+		if abs, err := filepath.Abs(libDir); err == nil {
+			opts.LibDir = abs
+		}
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files, _ = filepath.Glob("examples/programs/*.alas.json")
+		if len(files) == 0 {
+			fmt.Fprintln(os.Stderr, "No programs given and none found in examples/programs")
+			os.Exit(1)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROGRAM\tRESULT\tINTERPRETER\tCOMPILED\tSPEEDUP\n")
+	mismatches := 0
+	for _, file := range files {
+		name := filepath.Base(file)
+		result, err := run(file, opts)
+		if err != nil {
+			fmt.Fprintf(w, "%s\tskipped: %s\t\t\t\n", name, firstLine(err.Error()))
+			continue
+		}
+		if !result.Match() {
+			mismatches++
+			fmt.Fprintf(w, "%s\tMISMATCH: interpreter %q, compiled %q\t%s\t%s\t\n", name, result.Interpreted, result.Compiled,
+				result.Interpreter.Round(time.Microsecond), result.Executable.Round(time.Microsecond))
+			continue
+		}
+		speedup := float64(result.Interpreter) / float64(result.Executable)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.2fx\n", name, result.Compiled,
+			result.Interpreter.Round(time.Microsecond), result.Executable.Round(time.Microsecond), speedup)
+	}
+	w.Flush()
+	fmt.Printf("\nTimes are wall-clock for %d runs of main; compiled times include process start-up.\n", opts.Iterations)
+
+	if mismatches > 0 {
+		fmt.Fprintf(os.Stderr, "%d program(s) returned different results on the two backends\n", mismatches)
+		os.Exit(1)
+	}
+}
+
+// run validates, parses, and benchmarks one program. Anything the program
+// prints while it is interpreted is discarded; the compiled program's output
+// is captured by the benchmark.
+func run(file string, opts bench.Options) (*bench.Result, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := validator.ValidateJSON(data); err != nil {
+		return nil, fmt.Errorf("validation failed: %v", err)
+	}
+	module, err := ast.ParseModule(data, file)
+	if err != nil {
+		return nil, err
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	return bench.Run(module, opts)
+}
+
+// firstLine returns the first line of a possibly multi-line message.
+func firstLine(msg string) string {
+	line, _, _ := strings.Cut(msg, "\n")
+	return line
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/dshills/alas/internal/ast"
 	"github.com/dshills/alas/internal/codegen"
+	"github.com/dshills/alas/internal/modpath"
 	"github.com/dshills/alas/internal/validator"
 )
 
@@ -20,17 +20,26 @@ func main() {
 	var output string
 	var format string
 	var optLevel string
-	var modulePath string
+	var modulePaths modpath.PathList
 	var linkMode string
 	var mainModule string
+	var verbose bool
+	var graph string
+	var cacheDir string
+	var noCache bool
 
 	flag.StringVar(&input, "file", "", "ALaS JSON file to compile")
 	flag.StringVar(&output, "o", "", "Output file (default: input file with .ll extension)")
 	flag.StringVar(&format, "format", "ll", "Output format: ll (LLVM IR text) or bc (LLVM bitcode)")
 	flag.StringVar(&optLevel, "O", "1", "Optimization level: 0 (none), 1 (basic), 2 (standard), 3 (aggressive)")
-	flag.StringVar(&modulePath, "module-path", ".", "Path to search for module dependencies")
+	flag.Var(&modulePaths, "module-path", "Directory to search for module dependencies before $ALAS_PATH and the defaults; may be repeated")
+	flag.Var(&modulePaths, "I", "Shorthand for -module-path")
 	flag.StringVar(&linkMode, "link", "none", "Linking mode: none (separate modules), all (link all modules)")
 	flag.StringVar(&mainModule, "main", "", "Main module name for whole-program compilation")
+	flag.BoolVar(&verbose, "v", false, "Verbose output, including link-time optimization statistics")
+	flag.StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory for cached module IR, reused when a module and its dependencies are unchanged")
+	flag.BoolVar(&noCache, "no-cache", false, "Regenerate every module without reading or writing the module cache")
+	flag.StringVar(&graph, "graph", "", "Print the module dependency graph instead of compiling: tree or dot (Graphviz)")
 	flag.Parse()
 
 	if input == "" {
@@ -57,8 +66,8 @@ func main() {
 	// Create multi-module code generator
 	multiCodegen := codegen.NewMultiModuleCodegen()
 
-	// Register file system module loader
-	moduleLoader := createFileSystemModuleLoader(modulePath)
+	// Resolve every import through the file system module loader
+	moduleLoader := createFileSystemModuleLoader(modpath.SearchPaths(modulePaths...))
 
 	// Load the main module
 	mainModuleAST, err := loadModuleFromFile(input)
@@ -73,14 +82,29 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Register module loader for dependencies
-	multiCodegen.RegisterModuleLoader("math_utils", moduleLoader)
-	multiCodegen.RegisterModuleLoader("format_utils", moduleLoader)
-	// Add more module loaders as needed based on common dependencies
+	// Load dependencies from the module path
+	multiCodegen.SetDefaultLoader(moduleLoader)
+
+	if graph != "" {
+		if err := printDependencyGraph(multiCodegen, mainModuleAST.Name, graph); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !noCache && cacheDir != "" {
+		cache, err := codegen.NewModuleCache(cacheDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: module cache disabled: %v\n", err)
+		} else {
+			multiCodegen.SetCache(cache)
+		}
+	}
 
 	if linkMode == "all" || mainModule != "" {
 		// Whole-program compilation mode
-		err = compileLinkedProgram(multiCodegen, mainModuleAST.Name, output, format, optimizationLevel)
+		err = compileLinkedProgram(multiCodegen, mainModuleAST.Name, output, format, optimizationLevel, verbose)
 	} else {
 		// Separate compilation mode
 		err = compileSeparateModules(multiCodegen, input, output, format, optimizationLevel)
@@ -92,6 +116,16 @@ func main() {
 	}
 }
 
+// defaultCacheDir returns the module cache directory under the user's cache
+// directory, or "" if there is none.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "alas", "modules")
+}
+
 // loadModuleFromFile loads an ALaS module from a JSON file.
 func loadModuleFromFile(filename string) (*ast.Module, error) {
 	data, err := os.ReadFile(filename)
@@ -105,36 +139,59 @@ func loadModuleFromFile(filename string) (*ast.Module, error) {
 	}
 
 	// Parse the module
-	var module ast.Module
-	if err := json.Unmarshal(data, &module); err != nil {
+	module, err := ast.ParseModule(data, filename)
+	if err != nil {
 		return nil, fmt.Errorf("error parsing JSON: %v", err)
 	}
 
-	return &module, nil
+	return module, nil
 }
 
-// createFileSystemModuleLoader creates a module loader that searches the file system.
-func createFileSystemModuleLoader(basePath string) codegen.ModuleLoader {
+// createFileSystemModuleLoader creates a module loader that searches each
+// base path, and its modules and lib subdirectories, in order.
+func createFileSystemModuleLoader(basePaths []string) codegen.ModuleLoader {
 	return func(name string) (*ast.Module, error) {
-		// Try different possible file locations
-		possiblePaths := []string{
-			filepath.Join(basePath, name+".alas.json"),
-			filepath.Join(basePath, "modules", name+".alas.json"),
-			filepath.Join(basePath, "lib", name+".alas.json"),
-		}
+		for _, basePath := range basePaths {
+			// Try different possible file locations
+			possiblePaths := []string{
+				filepath.Join(basePath, name+".alas.json"),
+				filepath.Join(basePath, "modules", name+".alas.json"),
+				filepath.Join(basePath, "lib", name+".alas.json"),
+			}
 
-		for _, path := range possiblePaths {
-			if _, err := os.Stat(path); err == nil {
-				return loadModuleFromFile(path)
+			for _, path := range possiblePaths {
+				if _, err := os.Stat(path); err == nil {
+					return loadModuleFromFile(path)
+				}
 			}
 		}
 
-		return nil, fmt.Errorf("module %s not found in any of the search paths", name)
+		return nil, fmt.Errorf("module %s not found in any of the search paths: %s", name, strings.Join(basePaths, ", "))
 	}
 }
 
+// printDependencyGraph prints the import graph of the main module in the
+// given format. It returns an error if any module is missing or part of an
+// import cycle.
+func printDependencyGraph(multiCodegen *codegen.MultiModuleCodegen, mainModuleName, format string) error {
+	depGraph := multiCodegen.DependencyGraph(mainModuleName)
+	switch format {
+	case "tree":
+		depGraph.WriteTree(os.Stdout)
+	case "dot":
+		depGraph.WriteDOT(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported graph format: %s (use tree or dot)", format)
+	}
+
+	if depGraph.HasProblems() {
+		return fmt.Errorf("dependency graph has problems: %d missing, %d cyclic", len(depGraph.Missing), len(depGraph.Cycles))
+	}
+	return nil
+}
+
 // compileLinkedProgram compiles all modules and links them into a single output.
-func compileLinkedProgram(multiCodegen *codegen.MultiModuleCodegen, mainModuleName, output, format string, optLevel codegen.OptimizationLevel) error {
+func compileLinkedProgram(multiCodegen *codegen.MultiModuleCodegen, mainModuleName, output, format string, optLevel codegen.OptimizationLevel, verbose bool) error {
 	// Compile all modules
 	compiledModules, err := multiCodegen.CompileModules()
 	if err != nil {
@@ -142,6 +199,9 @@ func compileLinkedProgram(multiCodegen *codegen.MultiModuleCodegen, mainModuleNa
 	}
 
 	fmt.Printf("Compiled %d modules successfully\n", len(compiledModules))
+	if hits := multiCodegen.CacheHits(); len(hits) > 0 {
+		fmt.Printf("Reused %d unchanged modules from cache: %s\n", len(hits), strings.Join(hits, ", "))
+	}
 
 	// Link all modules
 	linkedModule, err := multiCodegen.LinkModules(mainModuleName + "_linked")
@@ -149,12 +209,19 @@ func compileLinkedProgram(multiCodegen *codegen.MultiModuleCodegen, mainModuleNa
 		return fmt.Errorf("failed to link modules: %v", err)
 	}
 
-	// Apply optimizations to the linked module
+	// Apply optimizations to the linked module, then optimize across the
+	// original module boundaries
 	if optLevel > codegen.OptNone {
 		optimizer := codegen.NewOptimizer(optLevel)
 		if err := optimizer.OptimizeModule(linkedModule); err != nil {
 			return fmt.Errorf("optimization failed: %v", err)
 		}
+
+		stats := optimizer.LinkTimeOptimize(linkedModule)
+		if verbose {
+			fmt.Printf("Link-time optimization: inlined %d cross-module calls, removed %d dead functions\n", stats.Inlined, stats.FunctionsRemoved)
+			fmt.Printf("Instructions: %d -> %d (%d removed)\n", stats.InstructionsBefore, stats.InstructionsAfter, stats.Reduction())
+		}
 	}
 
 	// Determine output filename
@@ -175,6 +242,9 @@ func compileSeparateModules(multiCodegen *codegen.MultiModuleCodegen, input, out
 	}
 
 	fmt.Printf("Compiled %d modules successfully\n", len(compiledModules))
+	if hits := multiCodegen.CacheHits(); len(hits) > 0 {
+		fmt.Printf("Reused %d unchanged modules from cache: %s\n", len(hits), strings.Join(hits, ", "))
+	}
 
 	// Write each module separately
 	for moduleName, llvmModule := range compiledModules {
@@ -211,25 +281,19 @@ func compileSeparateModules(multiCodegen *codegen.MultiModuleCodegen, input, out
 
 // writeOutput writes LLVM IR to a file in the specified format.
 func writeOutput(llvmModule *ir.Module, output, format string) error {
-	moduleStr := llvmModule.String()
-
 	switch format {
 	case "ll":
-		err := os.WriteFile(output, []byte(moduleStr), 0600)
+		err := os.WriteFile(output, []byte(llvmModule.String()), 0600)
 		if err != nil {
 			return fmt.Errorf("error writing LLVM IR: %v", err)
 		}
 		fmt.Printf("LLVM IR written to %s\n", output)
 
 	case "bc":
-		// For bitcode, write IR first and suggest using llvm-as
-		llFile := strings.TrimSuffix(output, ".bc") + ".ll"
-		err := os.WriteFile(llFile, []byte(moduleStr), 0600)
-		if err != nil {
-			return fmt.Errorf("error writing LLVM IR: %v", err)
+		if err := codegen.WriteBitcode(llvmModule, output); err != nil {
+			return fmt.Errorf("error writing LLVM bitcode: %v", err)
 		}
-		fmt.Printf("LLVM IR written to %s\n", llFile)
-		fmt.Printf("To generate bitcode, run: llvm-as %s -o %s\n", llFile, output)
+		fmt.Printf("LLVM bitcode written to %s\n", output)
 
 	default:
 		return fmt.Errorf("unsupported format: %s", format)
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -11,20 +10,101 @@ import (
 
 	"github.com/dshills/alas/internal/ast"
 	"github.com/dshills/alas/internal/codegen"
+	"github.com/dshills/alas/internal/modpath"
 	"github.com/dshills/alas/internal/validator"
+	"github.com/dshills/alas/internal/watch"
 )
 
+// options holds the command-line settings for a compilation.
+type options struct {
+	input              string
+	output             string
+	format             string
+	libDir             string
+	optLevel           codegen.OptimizationLevel
+	debug              bool
+	prune              bool
+	overflow           bool
+	unsafe             bool
+	heapArrayThreshold int
+	modulePaths        []string // Searched for imported modules before ALAS_PATH and the defaults
+}
+
 func main() {
 	var input string
 	var output string
 	var format string
+	var libDir string
 	var optLevel string
+	var watchMode bool
+	var debugInfo bool
+	var prune bool
+	var checkOverflow bool
+	var unsafe bool
+	var heapArrayThreshold int
+	var modulePaths modpath.PathList
 	flag.StringVar(&input, "file", "", "ALaS JSON file to compile (reads from stdin if not provided)")
 	flag.StringVar(&output, "o", "", "Output file (default: input file with .ll extension)")
-	flag.StringVar(&format, "format", "ll", "Output format: ll (LLVM IR text) or bc (LLVM bitcode)")
+	flag.StringVar(&format, "format", "ll", "Output format: ll (LLVM IR text), bc (LLVM bitcode), exe (native executable), or wasm (WebAssembly, numeric code only)")
+	flag.StringVar(&libDir, "libdir", "lib", "Directory holding libalas_stdlib.so, linked into executables when present")
 	flag.StringVar(&optLevel, "O", "1", "Optimization level: 0 (none), 1 (basic), 2 (standard), 3 (aggressive)")
+	flag.BoolVar(&debugInfo, "g", false, "Emit DWARF debug information")
+	flag.BoolVar(&prune, "prune", false, "Drop functions unreachable from main and exports before code generation")
+	flag.BoolVar(&checkOverflow, "check-overflow", false, "Report a runtime error on int overflow instead of wrapping")
+	flag.BoolVar(&unsafe, "unsafe", false, "Omit division by zero, array bounds and null pointer checks (unsafe: those errors become undefined behavior)")
+	flag.IntVar(&heapArrayThreshold, "heap-array-threshold", codegen.DefaultHeapArrayThreshold, "Allocate array literals with more elements than this on the heap instead of the stack")
+	flag.BoolVar(&watchMode, "watch", false, "Recompile whenever the input file or its imported modules change")
+	flag.Var(&modulePaths, "module-path", "Directory to search for imported modules before $ALAS_PATH and the defaults; may be repeated")
+	flag.Var(&modulePaths, "I", "Shorthand for -module-path")
 	flag.Parse()
 
+	// Parse optimization level
+	var optimizationLevel codegen.OptimizationLevel
+	switch optLevel {
+	case "0":
+		optimizationLevel = codegen.OptNone
+	case "1":
+		optimizationLevel = codegen.OptBasic
+	case "2":
+		optimizationLevel = codegen.OptStandard
+	case "3":
+		optimizationLevel = codegen.OptAggressive
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid optimization level: %s (use 0, 1, 2, or 3)\n", optLevel)
+		os.Exit(1)
+	}
+
+	opts := options{input: input, output: output, format: format, libDir: libDir, optLevel: optimizationLevel, debug: debugInfo, prune: prune, overflow: checkOverflow, unsafe: unsafe, heapArrayThreshold: heapArrayThreshold, modulePaths: modulePaths}
+
+	if watchMode {
+		if input == "" {
+			fmt.Fprintln(os.Stderr, "-watch requires -file")
+			os.Exit(1)
+		}
+		searchPaths := modpath.SearchPaths(append([]string{filepath.Dir(input)}, modulePaths...)...)
+		loader := codegen.NewFileModuleLoader(searchPaths)
+		w := watch.New(func() []string { return watch.ModuleFiles(input, loader) })
+		fmt.Printf("Watching %s for changes (Ctrl-C to stop)\n", input)
+		w.Run(nil, func() {
+			if compile(opts) {
+				fmt.Println("Compilation succeeded; waiting for changes...")
+			} else {
+				fmt.Println("Compilation failed; waiting for changes...")
+			}
+		})
+		return
+	}
+
+	if !compile(opts) {
+		os.Exit(1)
+	}
+}
+
+// compile validates, compiles, and writes a module, reporting errors on
+// stderr. It returns false if any step failed.
+func compile(opts options) bool {
+	input, output, format := opts.input, opts.output, opts.format
+
 	var data []byte
 	var err error
 
@@ -33,70 +113,92 @@ func main() {
 		data, err = io.ReadAll(os.Stdin)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
-			os.Exit(1)
+			return false
 		}
 	} else {
 		// Read from file
 		data, err = os.ReadFile(input)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", input, err)
-			os.Exit(1)
+			return false
 		}
 	}
 
 	// Validate the JSON first
 	if err := validator.ValidateJSON(data); err != nil {
 		fmt.Fprintf(os.Stderr, "Validation failed:\n%v\n", err)
-		os.Exit(1)
+		return false
 	}
 
 	// Parse the module
-	var module ast.Module
-	if err := json.Unmarshal(data, &module); err != nil {
+	sourceName := input
+	if sourceName == "" {
+		sourceName = "<stdin>"
+	}
+	module, err := ast.ParseModule(data, sourceName)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing JSON: %v\n", err)
-		os.Exit(1)
+		return false
 	}
 
-	// Parse optimization level
-	var optimizationLevel codegen.OptimizationLevel
-	switch optLevel {
-	case "0":
-		optimizationLevel = codegen.OptNone
-	case "1":
-		optimizationLevel = codegen.OptBasic
-	case "2":
-		optimizationLevel = codegen.OptStandard
-	case "3":
-		optimizationLevel = codegen.OptAggressive
-	default:
-		fmt.Fprintf(os.Stderr, "Invalid optimization level: %s (use 0, 1, 2, or 3)\n", optLevel)
-		os.Exit(1)
+	// Determine output filename; executables get no extension
+	if output == "" {
+		base := "output"
+		if input != "" {
+			base = strings.TrimSuffix(input, filepath.Ext(input))
+			if format == "exe" {
+				base = strings.TrimSuffix(base, ".alas")
+			}
+		}
+		output = base
+		if format != "exe" {
+			output += "." + format
+		}
+	}
+
+	// WebAssembly is generated directly from the AST, without LLVM
+	if format == "wasm" {
+		wasm, err := codegen.NewWASMCodegen().GenerateModule(module)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Code generation failed: %v\n", err)
+			return false
+		}
+		if err := os.WriteFile(output, wasm, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing WebAssembly: %v\n", err)
+			return false
+		}
+		fmt.Printf("WebAssembly written to %s\n", output)
+		return true
 	}
 
 	// Generate LLVM IR
 	codegenInstance := codegen.NewLLVMCodegen()
-	llvmModule, err := codegenInstance.GenerateModule(&module)
+	codegenInstance.SetModulePaths(opts.modulePaths)
+	if opts.debug {
+		codegenInstance.EnableDebugInfo()
+	}
+	if opts.prune {
+		codegenInstance.EnableDeadFunctionElimination()
+	}
+	if opts.overflow {
+		codegenInstance.EnableOverflowChecks()
+	}
+	if opts.unsafe {
+		codegenInstance.DisableSafetyChecks()
+	}
+	codegenInstance.SetHeapArrayThreshold(opts.heapArrayThreshold)
+	llvmModule, err := codegenInstance.GenerateModule(module)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Code generation failed: %v\n", err)
-		os.Exit(1)
+		return false
 	}
 
 	// Apply optimizations
-	if optimizationLevel > codegen.OptNone {
-		optimizer := codegen.NewOptimizer(optimizationLevel)
+	if opts.optLevel > codegen.OptNone {
+		optimizer := codegen.NewOptimizer(opts.optLevel)
 		if err := optimizer.OptimizeModule(llvmModule); err != nil {
 			fmt.Fprintf(os.Stderr, "Optimization failed: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
-	// Determine output filename
-	if output == "" {
-		if input == "" {
-			output = "output." + format
-		} else {
-			base := strings.TrimSuffix(input, filepath.Ext(input))
-			output = base + "." + format
+			return false
 		}
 	}
 
@@ -106,24 +208,31 @@ func main() {
 		err = os.WriteFile(output, []byte(llvmModule.String()), 0600)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing LLVM IR: %v\n", err)
-			os.Exit(1)
+			return false
 		}
 		fmt.Printf("LLVM IR written to %s\n", output)
 
 	case "bc":
-		// For bitcode, we would need to use LLVM tools
-		// For now, just output the IR and suggest using llvm-as
-		llFile := strings.TrimSuffix(output, ".bc") + ".ll"
-		err = os.WriteFile(llFile, []byte(llvmModule.String()), 0600)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing LLVM IR: %v\n", err)
-			os.Exit(1)
+		if err := codegen.WriteBitcode(llvmModule, output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing LLVM bitcode: %v\n", err)
+			return false
 		}
-		fmt.Printf("LLVM IR written to %s\n", llFile)
-		fmt.Printf("To generate bitcode, run: llvm-as %s -o %s\n", llFile, output)
+		fmt.Printf("LLVM bitcode written to %s\n", output)
+
+	case "exe":
+		libDir := opts.libDir
+		if _, err := os.Stat(filepath.Join(libDir, "libalas_stdlib.so")); err != nil {
+			libDir = ""
+		}
+		if err := codegen.WriteExecutable(llvmModule, output, libDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error building executable: %v\n", err)
+			return false
+		}
+		fmt.Printf("Executable written to %s\n", output)
 
 	default:
 		fmt.Fprintf(os.Stderr, "Unsupported format: %s\n", format)
-		os.Exit(1)
+		return false
 	}
+	return true
 }
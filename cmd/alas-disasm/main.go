@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/llir/llvm/ir"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/codegen"
+	"github.com/dshills/alas/internal/validator"
+)
+
+func main() {
+	var input string
+	var output string
+	var optLevel string
+	var diff bool
+	flag.StringVar(&input, "file", "", "ALaS JSON file to disassemble (reads from stdin if not provided)")
+	flag.StringVar(&output, "o", "", "Output file (default: stdout)")
+	flag.StringVar(&optLevel, "O", "0", "Optimization level: 0 (none), 1 (basic), 2 (standard), 3 (aggressive)")
+	flag.BoolVar(&diff, "diff", false, "Show each function before and after optimization")
+	flag.Parse()
+
+	var level codegen.OptimizationLevel
+	switch optLevel {
+	case "0":
+		level = codegen.OptNone
+	case "1":
+		level = codegen.OptBasic
+	case "2":
+		level = codegen.OptStandard
+	case "3":
+		level = codegen.OptAggressive
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid optimization level: %s (use 0, 1, 2, or 3)\n", optLevel)
+		os.Exit(1)
+	}
+
+	var data []byte
+	var err error
+	if input == "" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := validator.ValidateJSON(data); err != nil {
+		fmt.Fprintf(os.Stderr, "Validation failed:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	sourceName := input
+	if sourceName == "" {
+		sourceName = "<stdin>"
+	}
+	module, err := ast.ParseModule(data, sourceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	optimized, err := generate(module, level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var listing string
+	if diff {
+		// Generate the module a second time, as the optimizer works in place
+		unoptimized, err := generate(module, codegen.OptNone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		listing, err = codegen.DisassembleDiff(unoptimized, optimized, module)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		listing = codegen.Disassemble(optimized, module)
+	}
+
+	if output == "" {
+		fmt.Print(listing)
+		return
+	}
+	if err := os.WriteFile(output, []byte(listing), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate compiles a module with debug info, which carries the source
+// locations the listing is annotated with, and optimizes it at level.
+func generate(module *ast.Module, level codegen.OptimizationLevel) (*ir.Module, error) {
+	g := codegen.NewLLVMCodegen()
+	g.EnableDebugInfo()
+	llvmModule, err := g.GenerateModule(module)
+	if err != nil {
+		return nil, fmt.Errorf("code generation failed: %v", err)
+	}
+	if level > codegen.OptNone {
+		if err := codegen.NewOptimizer(level).OptimizeModule(llvmModule); err != nil {
+			return nil, fmt.Errorf("optimization failed: %v", err)
+		}
+	}
+	return llvmModule, nil
+}
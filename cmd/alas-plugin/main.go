@@ -63,6 +63,7 @@ func printUsage() {
 	fmt.Println("Options:")
 	fmt.Println("  -path <path>            Plugin search path (default: ./plugins)")
 	fmt.Println("  -format <format>        Output format: table, json (default: table)")
+	fmt.Println("  -type <type>            Plugin type for create: module, native, hybrid (default: module)")
 }
 
 func getRegistry(searchPath string) *plugin.Registry {
@@ -238,12 +239,31 @@ func uninstallCommand() {
 }
 
 func createCommand() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: alas-plugin create <name>")
+	var typeFlag string
+
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	fs.StringVar(&typeFlag, "type", "module", "Plugin type (module, native, hybrid)")
+	fs.Parse(os.Args[2:])
+
+	// Allow flags after the plugin name as well as before it
+	args := fs.Args()
+	if len(args) > 0 {
+		fs.Parse(args[1:])
+		args = append(args[:1], fs.Args()...)
+	}
+	if len(args) != 1 {
+		fmt.Println("Usage: alas-plugin create <name> [-type module|native|hybrid]")
 		os.Exit(1)
 	}
 
-	pluginName := os.Args[2]
+	pluginName := args[0]
+	pluginType := plugin.PluginType(typeFlag)
+	switch pluginType {
+	case plugin.PluginTypeModule, plugin.PluginTypeNative, plugin.PluginTypeHybrid:
+	default:
+		fmt.Printf("Unsupported plugin type %q: expected module, native or hybrid\n", typeFlag)
+		os.Exit(1)
+	}
 
 	// Create plugin directory
 	pluginDir := filepath.Join(".", pluginName)
@@ -252,14 +272,56 @@ func createCommand() {
 		os.Exit(1)
 	}
 
-	// Create manifest template
+	manifest := templateManifest(pluginName, pluginType)
+
+	// Save manifest
+	manifestPath := filepath.Join(pluginDir, "plugin.json")
+	if err := manifest.SaveManifest(manifestPath); err != nil {
+		fmt.Printf("Error saving manifest: %v\n", err)
+		os.Exit(1)
+	}
+
+	files := []scaffoldFile{{"plugin.json", "manifest", ""}}
+	if pluginType != plugin.PluginTypeNative {
+		files = append(files, scaffoldFile{pluginName + ".alas.json", "module", templateModule(pluginName)})
+	}
+	if pluginType != plugin.PluginTypeModule {
+		files = append(files, scaffoldFile{"main.go", "native entry points", templateNativeSource(manifest)})
+	}
+	files = append(files,
+		scaffoldFile{pluginName + "_test.alas.json", "tests", templateTests(manifest)},
+		scaffoldFile{"README.md", "documentation", templateReadme(manifest)},
+	)
+
+	for _, file := range files[1:] {
+		if err := os.WriteFile(filepath.Join(pluginDir, file.name), []byte(file.content), 0600); err != nil {
+			fmt.Printf("Error writing %s: %v\n", file.name, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("Created %s plugin template in %s/\n", pluginType, pluginDir)
+	for _, file := range files {
+		fmt.Printf("  - %s (%s)\n", file.name, file.description)
+	}
+}
+
+// scaffoldFile is a file generated by the create command.
+type scaffoldFile struct {
+	name        string
+	description string
+	content     string
+}
+
+// templateManifest returns the manifest of a new plugin of the given type.
+func templateManifest(pluginName string, pluginType plugin.PluginType) *plugin.Manifest {
 	manifest := &plugin.Manifest{
 		Name:         pluginName,
 		Version:      "0.1.0",
 		Description:  fmt.Sprintf("ALaS plugin: %s", pluginName),
 		Author:       "Your Name",
 		License:      "MIT",
-		Type:         plugin.PluginTypeModule,
+		Type:         pluginType,
 		Capabilities: []plugin.Capability{plugin.CapabilityFunction},
 		Module:       pluginName,
 		Functions: []plugin.FunctionDef{
@@ -268,6 +330,7 @@ func createCommand() {
 				Params:      []plugin.ParamDef{{Name: "name", Type: "string"}},
 				Returns:     "string",
 				Description: "Example function that greets someone",
+				Native:      pluginType == plugin.PluginTypeNative,
 			},
 		},
 		AlasVersion: ">=0.1.0",
@@ -284,16 +347,33 @@ func createCommand() {
 		},
 	}
 
-	// Save manifest
-	manifestPath := filepath.Join(pluginDir, "plugin.json")
-	if err := manifest.SaveManifest(manifestPath); err != nil {
-		fmt.Printf("Error saving manifest: %v\n", err)
-		os.Exit(1)
+	if pluginType == plugin.PluginTypeModule {
+		return manifest
+	}
+
+	binary := pluginName + ".so"
+	manifest.Capabilities = append(manifest.Capabilities, plugin.CapabilityNative)
+	manifest.Implementation.BuildCmd = fmt.Sprintf("go build -buildmode=plugin -o %s .", binary)
+	manifest.Implementation.Sources = []string{"main.go"}
+	manifest.Implementation.Binaries = []string{binary}
+	if pluginType == plugin.PluginTypeNative {
+		manifest.Implementation.Language = "go"
+		manifest.Implementation.EntryPoint = binary
+	} else {
+		manifest.Functions = append(manifest.Functions, plugin.FunctionDef{
+			Name:        "shout",
+			Params:      []plugin.ParamDef{{Name: "text", Type: "string"}},
+			Returns:     "string",
+			Description: "Example native function that upper-cases its argument",
+			Native:      true,
+		})
 	}
+	return manifest
+}
 
-	// Create example ALaS module
-	modulePath := filepath.Join(pluginDir, pluginName+".alas.json")
-	moduleContent := fmt.Sprintf(`{
+// templateModule returns the ALaS module of a new module or hybrid plugin.
+func templateModule(pluginName string) string {
+	return fmt.Sprintf(`{
   "type": "module",
   "name": "%s",
   "exports": ["hello"],
@@ -327,16 +407,133 @@ func createCommand() {
       ]
     }
   ]
-}`, pluginName)
+}
+`, pluginName)
+}
 
-	if err := os.WriteFile(modulePath, []byte(moduleContent), 0600); err != nil {
-		fmt.Printf("Error writing module file: %v\n", err)
+// templateNativeSource returns the Go source of the native part of a new
+// native or hybrid plugin. The plugin exports its functions to the native
+// loader through the plugin.NativeSymbol registrar.
+func templateNativeSource(manifest *plugin.Manifest) string {
+	if manifest.Type == plugin.PluginTypeNative {
+		return fmt.Sprintf(`// Package main implements the native functions of the %[1]s plugin.
+// Build it with: %[2]s
+package main
+
+import "fmt"
+
+type registrar struct{}
+
+// Register registers the native functions of the plugin.
+func (registrar) Register(register func(name string, fn func([]interface{}) (interface{}, error))) {
+	register("hello", Hello)
+}
+
+// %[3]s is looked up by the ALaS native plugin loader.
+var %[3]s registrar
+
+// Hello implements %[1]s.hello.
+func Hello(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("hello expects 1 argument, got %%d", len(args))
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("hello expects a string argument")
+	}
+	return "Hello, " + name, nil
+}
+`, manifest.Module, manifest.Implementation.BuildCmd, plugin.NativeSymbol)
+	}
+
+	return fmt.Sprintf(`// Package main implements the native functions of the %[1]s plugin.
+// Build it with: %[2]s
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type registrar struct{}
+
+// Register registers the native functions of the plugin.
+func (registrar) Register(register func(name string, fn func([]interface{}) (interface{}, error))) {
+	register("shout", Shout)
+}
+
+// %[3]s is looked up by the ALaS native plugin loader.
+var %[3]s registrar
+
+// Shout implements %[1]s.shout.
+func Shout(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("shout expects 1 argument, got %%d", len(args))
+	}
+	text, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("shout expects a string argument")
+	}
+	return strings.ToUpper(text), nil
+}
+`, manifest.Module, manifest.Implementation.BuildCmd, plugin.NativeSymbol)
+}
+
+// templateTests returns a plugin test suite with a sample case per function.
+func templateTests(manifest *plugin.Manifest) string {
+	suite := plugin.TestSuite{
+		Name:        manifest.Name + " tests",
+		Description: fmt.Sprintf("Tests for the %s plugin", manifest.Name),
+		Plugin:      manifest.Name,
+	}
+	samples := map[string]plugin.TestCase{
+		"hello": {Args: []interface{}{"World"}, Expected: "Hello, World"},
+		"shout": {Args: []interface{}{"hi"}, Expected: "HI"},
+	}
+	for _, fn := range manifest.Functions {
+		test := samples[fn.Name]
+		test.Name = "test_" + fn.Name
+		test.Description = fmt.Sprintf("%s returns the expected result", fn.Name)
+		test.Function = fn.Name
+		suite.Tests = append(suite.Tests, test)
+	}
+
+	data, err := json.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling tests: %v\n", err)
 		os.Exit(1)
 	}
+	return string(data) + "\n"
+}
+
+// templateReadme returns a README documenting the functions of the manifest.
+func templateReadme(manifest *plugin.Manifest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", manifest.Name, manifest.Description)
+	fmt.Fprintf(&b, "- Type: %s\n- Version: %s\n- Module: `%s`\n\n", manifest.Type, manifest.Version, manifest.Module)
+
+	b.WriteString("## Functions\n\n")
+	for _, fn := range manifest.Functions {
+		params := make([]string, len(fn.Params))
+		for i, param := range fn.Params {
+			params[i] = param.Name + ": " + param.Type
+		}
+		fmt.Fprintf(&b, "### `%s.%s(%s) -> %s`\n\n", manifest.Module, fn.Name, strings.Join(params, ", "), fn.Returns)
+		if fn.Native {
+			b.WriteString("Native function.\n\n")
+		}
+		if fn.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", fn.Description)
+		}
+	}
 
-	fmt.Printf("Created plugin template in %s/\n", pluginDir)
-	fmt.Printf("  - plugin.json (manifest)\n")
-	fmt.Printf("  - %s.alas.json (module)\n", pluginName)
+	b.WriteString("## Development\n\n```sh\n")
+	if manifest.Implementation.BuildCmd != "" {
+		fmt.Fprintf(&b, "%s\n", manifest.Implementation.BuildCmd)
+	}
+	b.WriteString("alas-plugin validate plugin.json\n```\n\n")
+	fmt.Fprintf(&b, "Sample test cases are in `%s_test.alas.json`.\n", manifest.Name)
+	return b.String()
 }
 
 func validateCommand() {
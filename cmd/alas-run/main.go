@@ -2,28 +2,128 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/dshills/alas/internal/ast"
 	"github.com/dshills/alas/internal/interpreter"
+	"github.com/dshills/alas/internal/modpath"
 	"github.com/dshills/alas/internal/runtime"
 	"github.com/dshills/alas/internal/validator"
+	"github.com/dshills/alas/internal/watch"
 )
 
+// options holds the command-line settings for a run.
+type options struct {
+	input         string
+	function      string
+	args          []runtime.Value
+	output        string
+	checkOverflow bool
+	lenient       bool
+	modulePaths   []string // Searched for imported modules before ALAS_PATH and the defaults
+}
+
 func main() {
 	var input string
 	var function string
+	var watchMode bool
+	var output string
+	var argsJSON string
+	var checkOverflow bool
+	var lenient bool
+	var modulePaths modpath.PathList
 	flag.StringVar(&input, "file", "", "ALaS JSON file to run (reads from stdin if not provided)")
 	flag.StringVar(&function, "fn", "main", "Function to execute (default: main)")
+	flag.BoolVar(&watchMode, "watch", false, "Re-run whenever the input file or its imported modules change")
+	flag.StringVar(&argsJSON, "args-json", "", "Function arguments as a JSON array, e.g. '[1, 2.0, \"123\", [1, 2]]', instead of positional arguments")
+	flag.StringVar(&output, "output", "text", "Result format: text (human-readable) or json (void results print null)")
+	flag.BoolVar(&checkOverflow, "check-overflow", false, "Report a runtime error on int overflow instead of promoting it to an arbitrary-precision int")
+	flag.BoolVar(&lenient, "lenient", false, "Make int division by zero yield 0, and out-of-bounds indexes and missing map keys yield void, instead of runtime errors")
+	flag.Var(&modulePaths, "module-path", "Directory to search for imported modules before $ALAS_PATH and the defaults; may be repeated")
+	flag.Var(&modulePaths, "I", "Shorthand for -module-path")
 	flag.Parse()
 
-	// Get function arguments from remaining command line args
-	args := flag.Args()
+	if output != "text" && output != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid output format: %s (use text or json)\n", output)
+		os.Exit(1)
+	}
+
+	// Get function arguments from the JSON array or the remaining command line args
+	args, err := parseArgs(argsJSON, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing arguments: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := options{input: input, function: function, args: args, output: output, checkOverflow: checkOverflow, lenient: lenient, modulePaths: modulePaths}
 
+	if watchMode {
+		if input == "" {
+			fmt.Fprintln(os.Stderr, "-watch requires -file")
+			os.Exit(1)
+		}
+		searchPaths := modpath.SearchPaths(append([]string{filepath.Dir(input)}, modulePaths...)...)
+		loader := interpreter.NewFileModuleLoader(searchPaths)
+		w := watch.New(func() []string { return watch.ModuleFiles(input, loader) })
+		fmt.Printf("Watching %s for changes (Ctrl-C to stop)\n", input)
+		w.Run(nil, func() {
+			if run(opts) == 0 {
+				fmt.Println("Run succeeded; waiting for changes...")
+			} else {
+				fmt.Println("Run failed; waiting for changes...")
+			}
+		})
+		return
+	}
+
+	if status := run(opts); status != 0 {
+		os.Exit(status)
+	}
+}
+
+// parseArgs returns the function arguments. A JSON array maps element types
+// directly to runtime values; otherwise each positional argument is parsed
+// as an int, then a float, then a bool, and finally taken as a string.
+func parseArgs(argsJSON string, positional []string) ([]runtime.Value, error) {
+	if argsJSON != "" {
+		if len(positional) > 0 {
+			return nil, fmt.Errorf("use either -args-json or positional arguments, not both")
+		}
+		var args []runtime.Value
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, fmt.Errorf("-args-json must be a JSON array: %v", err)
+		}
+		return args, nil
+	}
+
+	args := make([]runtime.Value, len(positional))
+	for i, arg := range positional {
+		// Try to parse as int first, then float, then string
+		if val, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			args[i] = runtime.NewInt(val)
+		} else if val, err := strconv.ParseFloat(arg, 64); err == nil {
+			args[i] = runtime.NewFloat(val)
+		} else if val, err := strconv.ParseBool(arg); err == nil {
+			args[i] = runtime.NewBool(val)
+		} else {
+			args[i] = runtime.NewString(arg)
+		}
+	}
+	return args, nil
+}
+
+// run validates, loads, and executes a function of a module, printing its
+// result in the given output format or reporting errors on stderr. It
+// returns the process exit status: 1 if any step failed, or the code the
+// program passed to os.exit.
+func run(opts options) int {
+	input, function, output := opts.input, opts.function, opts.output
 	var data []byte
 	var err error
 
@@ -32,61 +132,77 @@ func main() {
 		data, err = io.ReadAll(os.Stdin)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
-			os.Exit(1)
+			return 1
 		}
 	} else {
 		// Read from file
 		data, err = os.ReadFile(input)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", input, err)
-			os.Exit(1)
+			return 1
 		}
 	}
 
 	// Validate the JSON first
 	if err := validator.ValidateJSON(data); err != nil {
 		fmt.Fprintf(os.Stderr, "Validation failed:\n%v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// Parse the module
-	var module ast.Module
-	if err := json.Unmarshal(data, &module); err != nil {
+	sourceName := input
+	if sourceName == "" {
+		sourceName = "<stdin>"
+	}
+	module, err := ast.ParseModule(data, sourceName)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing JSON: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// Create interpreter and load module
 	interp := interpreter.New()
-	if err := interp.LoadModule(&module); err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading module: %v\n", err)
-		os.Exit(1)
+	interp.SetModulePaths(opts.modulePaths)
+	if opts.checkOverflow {
+		interp.EnableOverflowChecks()
 	}
-
-	// Parse arguments into runtime values
-	runtimeArgs := make([]runtime.Value, len(args))
-	for i, arg := range args {
-		// Try to parse as int first, then float, then string
-		if val, err := strconv.ParseInt(arg, 10, 64); err == nil {
-			runtimeArgs[i] = runtime.NewInt(val)
-		} else if val, err := strconv.ParseFloat(arg, 64); err == nil {
-			runtimeArgs[i] = runtime.NewFloat(val)
-		} else if val, err := strconv.ParseBool(arg); err == nil {
-			runtimeArgs[i] = runtime.NewBool(val)
-		} else {
-			runtimeArgs[i] = runtime.NewString(arg)
-		}
+	if opts.lenient {
+		interp.SetLenient(interpreter.LenientAll)
+	}
+	if err := interp.LoadModule(module); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading module: %v\n", err)
+		return 1
 	}
 
 	// Execute the specified function
-	result, err := interp.Run(function, runtimeArgs)
+	result, err := interp.Run(function, opts.args)
+	var exit *interpreter.ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
-		os.Exit(1)
+		var stack *interpreter.StackError
+		if errors.As(err, &stack) {
+			fmt.Fprintf(os.Stderr, "Stack trace (most recent call first):\n%s", stack.Trace())
+		}
+		return 1
+	}
+
+	if output == "json" {
+		// Void results print null so every run emits exactly one JSON value
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding result as JSON: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(encoded))
+		return 0
 	}
 
 	// Print result if not void
 	if result.Type != runtime.ValueTypeVoid {
 		fmt.Println(result.String())
 	}
+	return 0
 }
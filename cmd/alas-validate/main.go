@@ -1,19 +1,41 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/interpreter"
+	"github.com/dshills/alas/internal/modpath"
 	"github.com/dshills/alas/internal/validator"
 )
 
 func main() {
 	var input string
+	var strict bool
+	var schema bool
+	var modulePaths modpath.PathList
 	flag.StringVar(&input, "file", "", "ALaS JSON file to validate (reads from stdin if not provided)")
+	flag.BoolVar(&strict, "strict", false, "Treat unreachable code, unused variables, implicit int/float conversions, and missing returns as errors")
+	flag.BoolVar(&schema, "schema", false, "Print the JSON Schema of ALaS modules and exit")
+	flag.Var(&modulePaths, "module-path", "Directory to search for imported modules before $ALAS_PATH and the defaults; may be repeated")
+	flag.Var(&modulePaths, "I", "Shorthand for -module-path")
 	flag.Parse()
 
+	if schema {
+		data, err := json.MarshalIndent(ast.Schema(), "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
 	var data []byte
 	var err error
 
@@ -33,8 +55,35 @@ func main() {
 		}
 	}
 
-	// Validate the JSON
-	if err := validator.ValidateJSON(data); err != nil {
+	sourceName := input
+	if sourceName == "" {
+		sourceName = "<stdin>"
+	}
+	module, err := ast.ParseModule(data, sourceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Validation failed:\ninvalid JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Resolve imports so that imported types can be checked
+	searchPaths := modpath.SearchPaths(modulePaths...)
+	if input != "" {
+		searchPaths = append([]string{filepath.Dir(input)}, searchPaths...)
+	}
+	opts := validator.DefaultOptions()
+	if strict {
+		opts = validator.StrictOptions()
+	}
+	opts.AllErrors = true
+	v := validator.NewWithOptions(opts)
+	v.SetModuleLoader(interpreter.NewFileModuleLoader(searchPaths))
+
+	// Validate the module
+	err = v.ValidateModule(module)
+	for _, warning := range v.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Validation failed:\n%v\n", err)
 		os.Exit(1)
 	}
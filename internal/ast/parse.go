@@ -0,0 +1,210 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ParseModule decodes a module from JSON and records the source file of the
+// module and the source location (file, line, column) of every statement and
+// expression. Locations that are already present in the JSON are kept as-is;
+// inferred ones are left out when the module is encoded again.
+// Special float literals such as {"type": "literal", "value": "NaN", "to":
+// "float"} are replaced by their float64 values.
+func ParseModule(data []byte, file string) (*Module, error) {
+	var module Module
+	if err := json.Unmarshal(data, &module); err != nil {
+		return nil, err
+	}
+	module.File = file
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := readJSONNode(dec, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute source locations: %v", err)
+	}
+
+	loc := newLocator(data, file)
+	functions := root.field("functions")
+	for i := range module.Functions {
+		loc.statements(module.Functions[i].Body, functions.item(i).field("body"))
+	}
+
+	return &module, nil
+}
+
+// jsonNode records the starting offset of a JSON value and its children.
+type jsonNode struct {
+	offset int64
+	fields map[string]*jsonNode
+	items  []*jsonNode
+}
+
+// field returns the child node for an object key, or nil.
+func (n *jsonNode) field(name string) *jsonNode {
+	if n == nil {
+		return nil
+	}
+	return n.fields[name]
+}
+
+// item returns the child node for an array index, or nil.
+func (n *jsonNode) item(i int) *jsonNode {
+	if n == nil || i >= len(n.items) {
+		return nil
+	}
+	return n.items[i]
+}
+
+// readJSONNode reads one JSON value from the decoder, recording offsets.
+func readJSONNode(dec *json.Decoder, data []byte) (*jsonNode, error) {
+	node := &jsonNode{offset: skipJSONSeparators(data, dec.InputOffset())}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		node.fields = make(map[string]*jsonNode)
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			child, err := readJSONNode(dec, data)
+			if err != nil {
+				return nil, err
+			}
+			node.fields[key] = child
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case json.Delim('['):
+		for dec.More() {
+			child, err := readJSONNode(dec, data)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// skipJSONSeparators advances past whitespace, commas, and colons.
+func skipJSONSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\n', '\r', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// locator converts byte offsets to line/column positions.
+type locator struct {
+	file       string
+	lineStarts []int
+}
+
+func newLocator(data []byte, file string) *locator {
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &locator{file: file, lineStarts: lineStarts}
+}
+
+// position returns the 1-based line and column for a byte offset.
+func (l *locator) position(offset int64) (int, int) {
+	line := sort.Search(len(l.lineStarts), func(i int) bool {
+		return int64(l.lineStarts[i]) > offset
+	})
+	return line, int(offset) - l.lineStarts[line-1] + 1
+}
+
+func (l *locator) statements(stmts []Statement, node *jsonNode) {
+	for i := range stmts {
+		l.statement(&stmts[i], node.item(i))
+	}
+}
+
+func (l *locator) statement(stmt *Statement, node *jsonNode) {
+	if node == nil {
+		return
+	}
+	if stmt.Line == 0 {
+		stmt.Line, stmt.Column = l.position(node.offset)
+		stmt.inferredLine = true
+	}
+	if stmt.File == "" {
+		stmt.File = l.file
+		stmt.inferredFile = true
+	}
+
+	l.expression(stmt.Value, node.field("value"))
+	l.expression(stmt.Lvalue, node.field("lvalue"))
+	l.expression(stmt.Cond, node.field("cond"))
+	l.statements(stmt.Then, node.field("then"))
+	l.statements(stmt.Else, node.field("else"))
+	l.statements(stmt.Body, node.field("body"))
+	l.statements(stmt.Init, node.field("init"))
+	l.statements(stmt.Update, node.field("update"))
+	l.statements(stmt.Default, node.field("default"))
+	cases := node.field("cases")
+	for i := range stmt.Cases {
+		l.statements(stmt.Cases[i].Body, cases.item(i).field("body"))
+	}
+}
+
+func (l *locator) expression(expr *Expression, node *jsonNode) {
+	if expr == nil || node == nil {
+		return
+	}
+	if expr.Line == 0 {
+		expr.Line, expr.Column = l.position(node.offset)
+		expr.inferredLine = true
+	}
+	if expr.File == "" {
+		expr.File = l.file
+		expr.inferredFile = true
+	}
+	if expr.Type == ExprLiteral {
+		expr.Value = expr.LiteralValue()
+	}
+
+	l.expression(expr.Left, node.field("left"))
+	l.expression(expr.Right, node.field("right"))
+	l.expression(expr.Operand, node.field("operand"))
+	l.expression(expr.Index, node.field("index"))
+	l.expression(expr.Object, node.field("object"))
+	l.expressions(expr.Args, node.field("args"))
+	l.expressions(expr.Elements, node.field("elements"))
+	l.statements(expr.Body, node.field("body"))
+	pairs := node.field("pairs")
+	for i := range expr.Pairs {
+		l.expression(&expr.Pairs[i].Key, pairs.item(i).field("key"))
+		l.expression(&expr.Pairs[i].Value, pairs.item(i).field("value"))
+	}
+}
+
+func (l *locator) expressions(exprs []Expression, node *jsonNode) {
+	for i := range exprs {
+		l.expression(&exprs[i], node.item(i))
+	}
+}
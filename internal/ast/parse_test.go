@@ -0,0 +1,262 @@
+package ast
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestParseModuleLocations(t *testing.T) {
+	data := []byte(`{
+  "type": "module",
+  "name": "test",
+  "functions": [
+    {
+      "type": "function",
+      "name": "main",
+      "params": [],
+      "returns": "int",
+      "body": [
+        {"type": "assign", "target": "x", "value": {"type": "literal", "value": 1}},
+        {
+          "type": "return",
+          "value": {
+            "type": "binary",
+            "op": "/",
+            "left": {"type": "variable", "name": "x"},
+            "right": {"type": "literal", "value": 0}
+          }
+        }
+      ]
+    }
+  ]
+}`)
+
+	module, err := ParseModule(data, "test.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	body := module.Functions[0].Body
+	tests := []struct {
+		name   string
+		file   string
+		line   int
+		column int
+	}{
+		{"assign statement", body[0].File, body[0].Line, body[0].Column},
+		{"assign value", body[0].Value.File, body[0].Value.Line, body[0].Value.Column},
+		{"return statement", body[1].File, body[1].Line, body[1].Column},
+		{"binary expression", body[1].Value.File, body[1].Value.Line, body[1].Value.Column},
+		{"binary right operand", body[1].Value.Right.File, body[1].Value.Right.Line, body[1].Value.Right.Column},
+	}
+	expected := []struct{ line, column int }{
+		{11, 9},
+		{11, 52},
+		{12, 9},
+		{14, 20},
+		{18, 22},
+	}
+
+	for i, tt := range tests {
+		if tt.file != "test.alas.json" {
+			t.Errorf("%s: file = %q, want %q", tt.name, tt.file, "test.alas.json")
+		}
+		if tt.line != expected[i].line || tt.column != expected[i].column {
+			t.Errorf("%s: position = %d:%d, want %d:%d", tt.name, tt.line, tt.column, expected[i].line, expected[i].column)
+		}
+	}
+}
+
+func TestParseModuleKeepsExplicitLocations(t *testing.T) {
+	data := []byte(`{"type": "module", "name": "test", "functions": [{"type": "function", "name": "main",
+"params": [], "returns": "void", "body": [{"type": "expr", "line": 42, "column": 7, "file": "orig.alas",
+"value": {"type": "literal", "value": 1}}]}]}`)
+
+	module, err := ParseModule(data, "generated.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	stmt := module.Functions[0].Body[0]
+	if stmt.File != "orig.alas" || stmt.Line != 42 || stmt.Column != 7 {
+		t.Errorf("statement location = %s:%d:%d, want orig.alas:42:7", stmt.File, stmt.Line, stmt.Column)
+	}
+	if stmt.Value.File != "generated.json" || stmt.Value.Line != 3 {
+		t.Errorf("value location = %s:%d, want generated.json:3", stmt.Value.File, stmt.Value.Line)
+	}
+
+	encoded, err := json.Marshal(stmt)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"type":"expr","value":{"type":"literal","value":1},"file":"orig.alas","line":42,"column":7}`
+	if string(encoded) != want {
+		t.Errorf("encoded statement = %s, want %s", encoded, want)
+	}
+}
+
+func TestParseModuleKeepsComments(t *testing.T) {
+	data := []byte(`{
+  "type": "module",
+  "name": "test",
+  "comment": "Entry point of the tool",
+  "functions": [{
+    "type": "function",
+    "name": "main",
+    "comment": "Returns the exit code",
+    "params": [],
+    "returns": "int",
+    "body": [
+      {"type": "if", "comment": "outer", "cond": {"type": "literal", "value": true},
+        "then": [{"type": "return", "comment": "inner", "value": {"type": "literal", "value": 0}}]},
+      {"type": "return", "value": {"type": "literal", "value": 1}}
+    ]
+  }]
+}`)
+
+	module, err := ParseModule(data, "test.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	encoded, err := json.Marshal(module)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("json.Unmarshal() of the input error = %v", err)
+	}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatalf("json.Unmarshal() of the encoded module error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("encoded module = %s, want it to match the input", encoded)
+	}
+	module, err = ParseModule(encoded, "test.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() of the encoded module error = %v", err)
+	}
+
+	fn := module.Functions[0]
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"module", module.Comment, "Entry point of the tool"},
+		{"function", fn.Comment, "Returns the exit code"},
+		{"statement", fn.Body[0].Comment, "outer"},
+		{"nested statement", fn.Body[0].Then[0].Comment, "inner"},
+		{"statement without comment", fn.Body[1].Comment, ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s comment = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseModuleInvalidJSON(t *testing.T) {
+	if _, err := ParseModule([]byte(`{"type": "module",`), "bad.json"); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestParseModuleSpecialFloats(t *testing.T) {
+	data := []byte(`{"type": "module", "name": "m", "functions": [{"type": "function", "name": "f", "params": [], "returns": "void", "body": [
+		{"type": "expr", "value": {"type": "literal", "value": "NaN", "to": "float"}},
+		{"type": "expr", "value": {"type": "literal", "value": "Infinity", "to": "float"}},
+		{"type": "expr", "value": {"type": "literal", "value": "-Infinity", "to": "float"}},
+		{"type": "expr", "value": {"type": "literal", "value": "NaN"}}
+	]}]}`)
+	module, err := ParseModule(data, "")
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	body := module.Functions[0].Body
+
+	if f, ok := body[0].Value.Value.(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("NaN literal = %#v, want NaN", body[0].Value.Value)
+	}
+	if f, ok := body[1].Value.Value.(float64); !ok || !math.IsInf(f, 1) {
+		t.Errorf("Infinity literal = %#v, want +Inf", body[1].Value.Value)
+	}
+	if f, ok := body[2].Value.Value.(float64); !ok || !math.IsInf(f, -1) {
+		t.Errorf("-Infinity literal = %#v, want -Inf", body[2].Value.Value)
+	}
+	// Without "to": "float" the literal is an ordinary string
+	if s, ok := body[3].Value.Value.(string); !ok || s != "NaN" {
+		t.Errorf("string literal = %#v, want \"NaN\"", body[3].Value.Value)
+	}
+}
+
+func TestParseModuleFloatLiterals(t *testing.T) {
+	tests := []struct {
+		number string
+		want   interface{}
+		wantTo string
+	}{
+		{number: "22", want: int64(22)},
+		{number: "-7", want: int64(-7)},
+		{number: "22.0", want: 22.0, wantTo: TypeFloat},
+		{number: "-0.5", want: -0.5, wantTo: TypeFloat},
+		{number: "1e3", want: 1e3, wantTo: TypeFloat},
+		{number: "2E-2", want: 2e-2, wantTo: TypeFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			data := []byte(`{"type": "module", "name": "m", "functions": [{"type": "function", "name": "f", "params": [], "returns": "void",
+				"body": [{"type": "expr", "value": {"type": "literal", "value": ` + tt.number + `}}]}]}`)
+			module, err := ParseModule(data, "")
+			if err != nil {
+				t.Fatalf("ParseModule failed: %v", err)
+			}
+			literal := module.Functions[0].Body[0].Value
+			if literal.Value != tt.want {
+				t.Errorf("literal %s = %#v, want %#v", tt.number, literal.Value, tt.want)
+			}
+			if literal.To != tt.wantTo {
+				t.Errorf("literal %s has type %q, want %q", tt.number, literal.To, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestParseModuleBigIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		number string
+		want   interface{}
+	}{
+		{number: "42", want: int64(42)},
+		{number: "9223372036854775807", want: int64(9223372036854775807)},
+		{number: "9223372036854775808", want: "9223372036854775808"},
+		{number: "25852016738884976640000", want: "25852016738884976640000"},
+		{number: "-9223372036854775809", want: "-9223372036854775809"},
+		{number: "1e30", want: 1e30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			data := []byte(`{"type": "module", "name": "m", "functions": [{"type": "function", "name": "f", "params": [], "returns": "void",
+				"body": [{"type": "expr", "value": {"type": "literal", "value": ` + tt.number + `}}]}]}`)
+			module, err := ParseModule(data, "")
+			if err != nil {
+				t.Fatalf("ParseModule failed: %v", err)
+			}
+			got := module.Functions[0].Body[0].Value.Value
+			if want, ok := tt.want.(string); ok {
+				if n, ok := got.(*big.Int); !ok || n.String() != want {
+					t.Errorf("literal %s = %#v, want big integer %s", tt.number, got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("literal %s = %#v, want %#v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
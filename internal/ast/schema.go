@@ -0,0 +1,405 @@
+package ast
+
+// SchemaDialect is the JSON Schema version Schema is written in.
+const SchemaDialect = "http://json-schema.org/draft-07/schema#"
+
+// Schema returns a JSON Schema describing valid module JSON: modules,
+// functions, custom types, statements, and expressions, with the fields
+// each statement and expression type requires. It checks structure only;
+// scoping, typing, and the other semantic rules are left to the validator.
+// The result is a fresh value that can be encoded with encoding/json.
+func Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":     SchemaDialect,
+		"title":       "ALaS module",
+		"allOf":       []interface{}{schemaRef("module")},
+		"definitions": schemaDefinitions(),
+	}
+}
+
+// StatementTypes lists the values of a statement's type field.
+func StatementTypes() []string {
+	return []string{StmtAssign, StmtIf, StmtWhile, StmtFor, StmtReturn, StmtExpr, StmtAssert, StmtMatch, StmtDefer, StmtDestructure}
+}
+
+// ExpressionTypes lists the values of an expression's type field.
+func ExpressionTypes() []string {
+	return []string{
+		ExprLiteral, ExprVariable, ExprBinary, ExprUnary, ExprCall, ExprIndex, ExprField, ExprArrayLit,
+		ExprMapLit, ExprModuleCall, ExprBuiltin, ExprCast, ExprMethodCall, ExprVariant, ExprLambda, ExprFuncRef,
+	}
+}
+
+// BinaryOperators lists the operators of binary expressions.
+func BinaryOperators() []string {
+	return []string{OpAdd, OpSub, OpMul, OpDiv, OpMod, OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpAnd, OpOr}
+}
+
+// UnaryOperators lists the operators of unary expressions.
+func UnaryOperators() []string {
+	return []string{OpNot, OpNeg}
+}
+
+// BasicTypes lists the built-in types. Custom types, module.Type names,
+// and parameterized array<T> and map<K,V> types are also valid types.
+func BasicTypes() []string {
+	return []string{TypeInt, TypeFloat, TypeString, TypeBool, TypeArray, TypeMap, TypeVoid, TypeFunc, TypeDecimal}
+}
+
+// castTargets are the types a cast expression converts to.
+var castTargets = []string{TypeInt, TypeFloat, TypeBool, TypeString}
+
+// statementRequired gives the fields each statement type must have, besides
+// type. Assignments need a target or an lvalue, and destructures targets or
+// bindings; those alternatives are added by statementSchema.
+var statementRequired = map[string][]string{
+	StmtAssign:      {"value"},
+	StmtIf:          {"cond", "then"},
+	StmtWhile:       {"cond", "body"},
+	StmtFor:         {"cond", "body"},
+	StmtReturn:      {},
+	StmtExpr:        {"value"},
+	StmtAssert:      {"cond"},
+	StmtMatch:       {"value"},
+	StmtDefer:       {"value"},
+	StmtDestructure: {"value"},
+}
+
+// expressionRequired gives the fields each expression type must have,
+// besides type.
+var expressionRequired = map[string][]string{
+	ExprLiteral:    {"value"},
+	ExprVariable:   {"name"},
+	ExprBinary:     {"op", "left", "right"},
+	ExprUnary:      {"op", "operand"},
+	ExprCall:       {"name", "args"},
+	ExprIndex:      {"object", "index"},
+	ExprField:      {"object", "field"},
+	ExprArrayLit:   {"elements"},
+	ExprMapLit:     {"pairs"},
+	ExprModuleCall: {"module", "name", "args"},
+	ExprBuiltin:    {"name", "args"},
+	ExprCast:       {"to", "operand"},
+	ExprMethodCall: {"object", "name", "args"},
+	ExprVariant:    {"enum", "variant"},
+	ExprLambda:     {"body"},
+	ExprFuncRef:    {"name"},
+}
+
+func schemaDefinitions() map[string]interface{} {
+	return map[string]interface{}{
+		"identifier": map[string]interface{}{
+			"type":    "string",
+			"pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+		},
+		"moduleName": map[string]interface{}{
+			"type":    "string",
+			"pattern": "^[a-zA-Z_]([a-zA-Z0-9_.]*[a-zA-Z0-9_])?$",
+		},
+		"typeName": map[string]interface{}{
+			"description": "A basic type, a custom type, module.Type, array<T>, or map<K,V>",
+			"anyOf": []interface{}{
+				map[string]interface{}{"enum": toInterfaces(BasicTypes())},
+				map[string]interface{}{"type": "string", "pattern": "^[a-zA-Z_][a-zA-Z0-9_.]*(<.+>)?$"},
+			},
+		},
+		"meta": map[string]interface{}{
+			"type": "object",
+		},
+		"comment": map[string]interface{}{
+			"description": "A note for readers, kept when a module is re-encoded and otherwise ignored",
+			"type":        "string",
+		},
+		"module": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"type", "name", "functions"},
+			"properties": map[string]interface{}{
+				"type":         map[string]interface{}{"const": "module"},
+				"name":         schemaRef("moduleName"),
+				"kind":         map[string]interface{}{"enum": []interface{}{ModuleKindProgram, ModuleKindLibrary}},
+				"comment":      schemaRef("comment"),
+				"exports":      schemaArray(schemaRef("identifier")),
+				"type_exports": schemaArray(schemaRef("identifier")),
+				"imports":      schemaArray(schemaRef("import")),
+				"functions":    schemaArray(schemaRef("function")),
+				"types":        schemaArray(schemaRef("typeDefinition")),
+				"meta":         schemaRef("meta"),
+			},
+		},
+		"import": map[string]interface{}{
+			"oneOf": []interface{}{
+				schemaRef("moduleName"),
+				map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"module"},
+					"properties": map[string]interface{}{
+						"module": schemaRef("moduleName"),
+						"as":     schemaRef("identifier"),
+					},
+				},
+			},
+		},
+		"function": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"type", "name"},
+			"properties": map[string]interface{}{
+				"type":     map[string]interface{}{"const": "function"},
+				"name":     schemaRef("identifier"),
+				"comment":  schemaRef("comment"),
+				"receiver": schemaRef("parameter"),
+				"params":   schemaArray(schemaRef("parameter")),
+				"returns":  schemaRef("typeName"),
+				"body":     schemaRef("block"),
+				"extern":   schemaRef("identifier"),
+				"meta":     schemaRef("functionMeta"),
+			},
+			// A function has a body unless it is bound to a C symbol
+			"if":   map[string]interface{}{"required": []interface{}{"extern"}},
+			"then": map[string]interface{}{"not": map[string]interface{}{"required": []interface{}{"body"}}},
+			"else": map[string]interface{}{"required": []interface{}{"body"}},
+		},
+		"functionMeta": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				MetaInline: map[string]interface{}{"enum": []interface{}{InlineAlways, InlineNever}},
+				MetaNoOpt:  map[string]interface{}{"type": "boolean"},
+				MetaExport: map[string]interface{}{"type": "boolean"},
+			},
+		},
+		"parameter": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name", "type"},
+			"properties": map[string]interface{}{
+				"name": schemaRef("identifier"),
+				"type": schemaRef("typeName"),
+			},
+		},
+		"typeDefinition": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name", "definition"},
+			"properties": map[string]interface{}{
+				"name":       schemaRef("identifier"),
+				"definition": schemaRef("typeDefinitionDef"),
+			},
+		},
+		"typeDefinitionDef": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"kind"},
+			"properties": map[string]interface{}{
+				"kind":     map[string]interface{}{"enum": []interface{}{TypeKindStruct, TypeKindEnum, TypeKindAlias}},
+				"fields":   map[string]interface{}{"type": "array", "items": schemaRef("typeField"), "minItems": 1},
+				"values":   schemaArray(schemaRef("identifier")),
+				"variants": schemaArray(schemaRef("enumVariant")),
+				"type":     schemaRef("typeName"),
+			},
+			"allOf": []interface{}{
+				schemaWhen("kind", TypeKindStruct, "fields"),
+				schemaWhen("kind", TypeKindAlias, "type"),
+				map[string]interface{}{
+					"if":   schemaTypeIs("kind", TypeKindEnum),
+					"then": schemaExactlyOne("values", "variants"),
+				},
+			},
+		},
+		"typeField": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name", "type"},
+			"properties": map[string]interface{}{
+				"name": schemaRef("identifier"),
+				"type": schemaRef("typeName"),
+			},
+		},
+		"enumVariant": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name"},
+			"properties": map[string]interface{}{
+				"name":   schemaRef("identifier"),
+				"fields": schemaArray(schemaRef("typeField")),
+			},
+		},
+		"block": schemaArray(schemaRef("statement")),
+		"bindings": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaRef("identifier"),
+		},
+		"statement":  statementSchema(),
+		"expression": expressionSchema(),
+		"matchCase": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"variant", "body"},
+			"properties": map[string]interface{}{
+				"variant":  schemaRef("identifier"),
+				"bindings": schemaRef("bindings"),
+				"body":     schemaRef("block"),
+			},
+		},
+		"mapPair": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"key", "value"},
+			"properties": map[string]interface{}{
+				"key":   schemaRef("expression"),
+				"value": schemaRef("expression"),
+			},
+		},
+	}
+}
+
+func statementSchema() map[string]interface{} {
+	properties := map[string]interface{}{
+		"type":     map[string]interface{}{"enum": toInterfaces(StatementTypes())},
+		"comment":  schemaRef("comment"),
+		"value":    schemaRef("expression"),
+		"target":   schemaRef("identifier"),
+		"lvalue":   schemaRef("expression"),
+		"cond":     schemaRef("expression"),
+		"then":     schemaRef("block"),
+		"else":     schemaRef("block"),
+		"body":     schemaRef("block"),
+		"init":     schemaRef("block"),
+		"update":   schemaRef("block"),
+		"message":  map[string]interface{}{"type": "string"},
+		"cases":    schemaArray(schemaRef("matchCase")),
+		"default":  schemaRef("block"),
+		"targets":  schemaArray(schemaRef("identifier")),
+		"bindings": schemaRef("bindings"),
+	}
+	addPositionProperties(properties)
+
+	rules := requiredRules(StatementTypes(), statementRequired)
+	rules = append(rules,
+		map[string]interface{}{
+			"if":   schemaTypeIs("type", StmtAssign),
+			"then": schemaExactlyOne("target", "lvalue"),
+		},
+		map[string]interface{}{
+			"if":   schemaTypeIs("type", StmtDestructure),
+			"then": schemaExactlyOne("targets", "bindings"),
+		},
+		map[string]interface{}{
+			"if": schemaTypeIs("type", StmtMatch),
+			"then": map[string]interface{}{"anyOf": []interface{}{
+				map[string]interface{}{"required": []interface{}{"cases"}},
+				map[string]interface{}{"required": []interface{}{"default"}},
+			}},
+		},
+	)
+	return map[string]interface{}{
+		"type":       "object",
+		"required":   []interface{}{"type"},
+		"properties": properties,
+		"allOf":      rules,
+	}
+}
+
+func expressionSchema() map[string]interface{} {
+	properties := map[string]interface{}{
+		"type":     map[string]interface{}{"enum": toInterfaces(ExpressionTypes())},
+		"value":    map[string]interface{}{"type": []interface{}{"number", "string", "boolean"}},
+		"name":     map[string]interface{}{"type": "string", "minLength": 1},
+		"module":   schemaRef("moduleName"),
+		"op":       map[string]interface{}{"enum": toInterfaces(append(BinaryOperators(), OpNot))},
+		"left":     schemaRef("expression"),
+		"right":    schemaRef("expression"),
+		"operand":  schemaRef("expression"),
+		"args":     schemaArray(schemaRef("expression")),
+		"elements": schemaArray(schemaRef("expression")),
+		"pairs":    schemaArray(schemaRef("mapPair")),
+		"index":    schemaRef("expression"),
+		"object":   schemaRef("expression"),
+		"field":    schemaRef("identifier"),
+		"to":       schemaRef("typeName"),
+		"enum":     map[string]interface{}{"type": "string", "minLength": 1},
+		"variant":  schemaRef("identifier"),
+		"params":   schemaArray(schemaRef("parameter")),
+		"returns":  schemaRef("typeName"),
+		"body":     schemaRef("block"),
+	}
+	addPositionProperties(properties)
+
+	rules := requiredRules(ExpressionTypes(), expressionRequired)
+	rules = append(rules,
+		schemaOpsFor(ExprBinary, BinaryOperators()),
+		schemaOpsFor(ExprUnary, UnaryOperators()),
+		map[string]interface{}{
+			"if": schemaTypeIs("type", ExprCast),
+			"then": map[string]interface{}{"properties": map[string]interface{}{
+				"to": map[string]interface{}{"enum": toInterfaces(castTargets)},
+			}},
+		},
+	)
+	return map[string]interface{}{
+		"type":       "object",
+		"required":   []interface{}{"type"},
+		"properties": properties,
+		"allOf":      rules,
+	}
+}
+
+// addPositionProperties adds the source position fields statements and
+// expressions may carry.
+func addPositionProperties(properties map[string]interface{}) {
+	properties["file"] = map[string]interface{}{"type": "string"}
+	properties["line"] = map[string]interface{}{"type": "integer", "minimum": 0}
+	properties["column"] = map[string]interface{}{"type": "integer", "minimum": 0}
+}
+
+// requiredRules returns one if/then rule per node type that requires fields.
+func requiredRules(types []string, required map[string][]string) []interface{} {
+	var rules []interface{}
+	for _, typ := range types {
+		if fields := required[typ]; len(fields) > 0 {
+			rules = append(rules, schemaWhen("type", typ, fields...))
+		}
+	}
+	return rules
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/definitions/" + name}
+}
+
+func schemaArray(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+// schemaTypeIs matches objects whose discriminator field has value.
+func schemaTypeIs(field, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"required":   []interface{}{field},
+		"properties": map[string]interface{}{field: map[string]interface{}{"const": value}},
+	}
+}
+
+// schemaWhen requires fields of objects whose discriminator field has value.
+func schemaWhen(field, value string, fields ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"if":   schemaTypeIs(field, value),
+		"then": map[string]interface{}{"required": toInterfaces(fields)},
+	}
+}
+
+// schemaExactlyOne requires exactly one of the fields a and b.
+func schemaExactlyOne(a, b string) map[string]interface{} {
+	return map[string]interface{}{"oneOf": []interface{}{
+		map[string]interface{}{"required": []interface{}{a}},
+		map[string]interface{}{"required": []interface{}{b}},
+	}}
+}
+
+// schemaOpsFor restricts the operators of one expression type.
+func schemaOpsFor(exprType string, ops []string) map[string]interface{} {
+	return map[string]interface{}{
+		"if": schemaTypeIs("type", exprType),
+		"then": map[string]interface{}{"properties": map[string]interface{}{
+			"op": map[string]interface{}{"enum": toInterfaces(ops)},
+		}},
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
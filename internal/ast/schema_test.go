@@ -0,0 +1,109 @@
+package ast
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaReferencesResolve(t *testing.T) {
+	schema := Schema()
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("json.Marshal(Schema()) error = %v", err)
+	}
+	definitions := schema["definitions"].(map[string]interface{})
+
+	var walk func(node interface{}, path string)
+	walk = func(node interface{}, path string) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for key, value := range n {
+				if ref, ok := value.(string); ok && key == "$ref" {
+					if _, ok := definitions[strings.TrimPrefix(ref, "#/definitions/")]; !ok {
+						t.Errorf("%s: unresolved reference %s", path, ref)
+					}
+				}
+				walk(value, path+"/"+key)
+			}
+		case []interface{}:
+			for _, value := range n {
+				walk(value, path)
+			}
+		}
+	}
+	walk(schema, "")
+}
+
+func TestSchemaCoversASTFields(t *testing.T) {
+	definitions := Schema()["definitions"].(map[string]interface{})
+	tests := []struct {
+		definition string
+		value      interface{}
+	}{
+		{"module", Module{}},
+		{"function", Function{}},
+		{"parameter", Parameter{}},
+		{"typeDefinition", TypeDefinition{}},
+		{"typeDefinitionDef", TypeDefinitionDef{}},
+		{"typeField", TypeField{}},
+		{"enumVariant", EnumVariant{}},
+		{"statement", Statement{}},
+		{"expression", Expression{}},
+		{"matchCase", MatchCase{}},
+		{"mapPair", MapPair{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.definition, func(t *testing.T) {
+			definition := definitions[tt.definition].(map[string]interface{})
+			properties := definition["properties"].(map[string]interface{})
+			typ := reflect.TypeOf(tt.value)
+			for i := 0; i < typ.NumField(); i++ {
+				name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+				if name == "" || name == "-" {
+					continue
+				}
+				if _, ok := properties[name]; !ok {
+					t.Errorf("field %s.%s (%q) is missing from the schema", typ.Name(), typ.Field(i).Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaNodeTypes(t *testing.T) {
+	definitions := Schema()["definitions"].(map[string]interface{})
+	tests := []struct {
+		definition string
+		types      []string
+		required   map[string][]string
+	}{
+		{"statement", StatementTypes(), statementRequired},
+		{"expression", ExpressionTypes(), expressionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.definition, func(t *testing.T) {
+			properties := definitions[tt.definition].(map[string]interface{})["properties"].(map[string]interface{})
+			enum := properties["type"].(map[string]interface{})["enum"].([]interface{})
+			if !reflect.DeepEqual(enum, toInterfaces(tt.types)) {
+				t.Errorf("type enum = %v, want %v", enum, tt.types)
+			}
+			if len(tt.required) != len(tt.types) {
+				t.Errorf("required fields are given for %d types, want %d", len(tt.required), len(tt.types))
+			}
+			for _, typ := range tt.types {
+				fields, ok := tt.required[typ]
+				if !ok {
+					t.Errorf("no required fields given for %s", typ)
+				}
+				for _, field := range fields {
+					if _, ok := properties[field]; !ok {
+						t.Errorf("%s requires unknown field %s", typ, field)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,24 +1,138 @@
 package ast
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // Module represents an ALaS module.
 type Module struct {
-	Type      string                 `json:"type"`
-	Name      string                 `json:"name"`
-	Exports   []string               `json:"exports,omitempty"`
-	Imports   []string               `json:"imports,omitempty"`
-	Functions []Function             `json:"functions"`
-	Types     []TypeDefinition       `json:"types,omitempty"`
-	Meta      map[string]interface{} `json:"meta,omitempty"`
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Kind        string                 `json:"kind,omitempty"`    // ModuleKindProgram, ModuleKindLibrary, or "" for either
+	Comment     string                 `json:"comment,omitempty"` // Note for readers; ignored by the toolchain
+	Exports     []string               `json:"exports,omitempty"`
+	TypeExports []string               `json:"type_exports,omitempty"` // Custom types visible to importing modules
+	Imports     []Import               `json:"imports,omitempty"`
+	Functions   []Function             `json:"functions"`
+	Types       []TypeDefinition       `json:"types,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+	File        string                 `json:"-"` // Source file the module was parsed from, if any
+}
+
+// Module kinds. A program is run through its main function, and a library
+// is imported for the functions it exports.
+const (
+	ModuleKindProgram = "program"
+	ModuleKindLibrary = "library"
+)
+
+// ExportsType reports whether the module exports the named custom type.
+func (m *Module) ExportsType(name string) bool {
+	for _, exported := range m.TypeExports {
+		if exported == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Import names a module the module depends on. Alias, when set, is the
+// local name module calls and function references use for it. An import
+// without an alias is written in JSON as the plain module name.
+type Import struct {
+	Module string `json:"module"`
+	Alias  string `json:"as,omitempty"`
+}
+
+// Name returns the local name of the imported module.
+func (imp Import) Name() string {
+	if imp.Alias != "" {
+		return imp.Alias
+	}
+	return imp.Module
+}
+
+// MarshalJSON writes an import without an alias as a plain string.
+func (imp Import) MarshalJSON() ([]byte, error) {
+	if imp.Alias == "" {
+		return json.Marshal(imp.Module)
+	}
+	type plain Import
+	return json.Marshal(plain(imp))
+}
+
+// UnmarshalJSON accepts either a module name or an object with module and
+// as fields.
+func (imp *Import) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*imp = Import{Module: name}
+		return nil
+	}
+	type plain Import
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("import must be a module name or an object with module and as: %w", err)
+	}
+	*imp = Import(p)
+	return nil
+}
+
+// ImportedModules returns the names of the modules the module imports,
+// regardless of the aliases they are imported under.
+func (m *Module) ImportedModules() []string {
+	names := make([]string, len(m.Imports))
+	for i, imp := range m.Imports {
+		names[i] = imp.Module
+	}
+	return names
 }
 
 // Function represents a function definition.
 type Function struct {
-	Type    string                 `json:"type"`
-	Name    string                 `json:"name"`
-	Params  []Parameter            `json:"params"`
-	Returns string                 `json:"returns"`
-	Body    []Statement            `json:"body"`
-	Meta    map[string]interface{} `json:"meta,omitempty"`
+	Type     string                 `json:"type"`
+	Name     string                 `json:"name"`
+	Comment  string                 `json:"comment,omitempty"`  // Note for readers; ignored by the toolchain
+	Receiver *Parameter             `json:"receiver,omitempty"` // For methods on struct types
+	Params   []Parameter            `json:"params"`
+	Returns  string                 `json:"returns"`
+	Body     []Statement            `json:"body"`
+	Extern   string                 `json:"extern,omitempty"` // C symbol an external function is bound to; it has no body
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Function meta keys honored by the compiler. Other keys are ignored.
+const (
+	MetaInline = "inline" // InlineAlways or InlineNever
+	MetaNoOpt  = "noopt"  // true leaves the function unoptimized
+	MetaExport = "export" // true keeps the function visible, false makes it internal
+)
+
+// Values of the MetaInline function meta key.
+const (
+	InlineAlways = "always"
+	InlineNever  = "never"
+)
+
+// MetaString returns the string value of a meta key, or "" if it is
+// missing or not a string.
+func (f *Function) MetaString(key string) string {
+	s, _ := f.Meta[key].(string)
+	return s
+}
+
+// MetaBool returns the bool value of a meta key, and whether it is set to
+// a bool.
+func (f *Function) MetaBool(key string) (value, ok bool) {
+	value, ok = f.Meta[key].(bool)
+	return value, ok
 }
 
 // Parameter represents a function parameter.
@@ -29,13 +143,55 @@ type Parameter struct {
 
 // Statement represents any statement in ALaS.
 type Statement struct {
-	Type   string      `json:"type"`
-	Value  *Expression `json:"value,omitempty"`
-	Target string      `json:"target,omitempty"`
-	Cond   *Expression `json:"cond,omitempty"`
-	Then   []Statement `json:"then,omitempty"`
-	Else   []Statement `json:"else,omitempty"`
-	Body   []Statement `json:"body,omitempty"`
+	Type     string            `json:"type"`
+	Comment  string            `json:"comment,omitempty"` // Note for readers; ignored by the toolchain
+	Value    *Expression       `json:"value,omitempty"`
+	Target   string            `json:"target,omitempty"`
+	Lvalue   *Expression       `json:"lvalue,omitempty"` // Index or field expression an assignment stores into, instead of Target
+	Cond     *Expression       `json:"cond,omitempty"`
+	Then     []Statement       `json:"then,omitempty"`
+	Else     []Statement       `json:"else,omitempty"`
+	Body     []Statement       `json:"body,omitempty"`
+	Init     []Statement       `json:"init,omitempty"`     // For for statements: run once before the first condition check
+	Update   []Statement       `json:"update,omitempty"`   // For for statements: run after each pass through the body
+	Message  string            `json:"message,omitempty"`  // For assert statements
+	Cases    []MatchCase       `json:"cases,omitempty"`    // For match statements
+	Default  []Statement       `json:"default,omitempty"`  // For match statements
+	Targets  []string          `json:"targets,omitempty"`  // For destructure statements: variables bound to array elements in order, "_" skips one
+	Bindings map[string]string `json:"bindings,omitempty"` // For destructure statements: field -> variable
+	File     string            `json:"file,omitempty"`     // Source file, for error reporting
+	Line     int               `json:"line,omitempty"`     // 1-based source line, 0 if unknown
+	Column   int               `json:"column,omitempty"`   // 1-based source column, 0 if unknown
+
+	// Set when ParseModule inferred File or Line and Column from the JSON
+	// rather than reading them from it, so that they are not written back
+	inferredFile bool
+	inferredLine bool
+}
+
+// MarshalJSON writes the statement without the location ParseModule
+// inferred for it, so that a parsed module encodes as it was written.
+func (s Statement) MarshalJSON() ([]byte, error) {
+	type plain Statement
+	p := plain(s)
+	if s.inferredFile {
+		p.File = ""
+	}
+	if s.inferredLine {
+		p.Line, p.Column = 0, 0
+	}
+	return json.Marshal(p)
+}
+
+// BoundFields returns the fields a destructure statement binds, sorted so
+// they are always bound in the same order.
+func (s *Statement) BoundFields() []string {
+	fields := make([]string, 0, len(s.Bindings))
+	for field := range s.Bindings {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
 }
 
 // Expression represents any expression in ALaS.
@@ -54,6 +210,120 @@ type Expression struct {
 	Index    *Expression  `json:"index,omitempty"`    // For indexing operations
 	Object   *Expression  `json:"object,omitempty"`   // For field/index access
 	Field    string       `json:"field,omitempty"`    // For field access
+	To       string       `json:"to,omitempty"`       // Target type for casts, or the type of a float or decimal literal
+	Enum     string       `json:"enum,omitempty"`     // Enum type for variant construction
+	Variant  string       `json:"variant,omitempty"`  // Variant name for variant construction
+	Params   []Parameter  `json:"params,omitempty"`   // Parameters of a lambda
+	Returns  string       `json:"returns,omitempty"`  // Return type of a lambda
+	Body     []Statement  `json:"body,omitempty"`     // Body of a lambda
+	File     string       `json:"file,omitempty"`     // Source file, for error reporting
+	Line     int          `json:"line,omitempty"`     // 1-based source line, 0 if unknown
+	Column   int          `json:"column,omitempty"`   // 1-based source column, 0 if unknown
+
+	// Set when ParseModule inferred File or Line and Column from the JSON
+	// rather than reading them from it, so that they are not written back
+	inferredFile bool
+	inferredLine bool
+	inferredTo   bool // To was set because the number was written as a float
+}
+
+// MarshalJSON writes float literals with a fraction, and infinite or NaN
+// float literals with their special spellings, so that they decode as floats
+// again. Integral float64 values not marked as floats are ints, and are
+// written as such. Locations and float types inferred from the JSON are left
+// out.
+func (e Expression) MarshalJSON() ([]byte, error) {
+	type plain Expression
+	p := plain(e)
+	if e.inferredFile {
+		p.File = ""
+	}
+	if e.inferredLine {
+		p.Line, p.Column = 0, 0
+	}
+	if e.inferredTo {
+		p.To = ""
+	}
+	if f, ok := e.Value.(float64); ok {
+		switch {
+		case math.IsNaN(f):
+			p.Value, p.To = FloatNaN, TypeFloat
+		case math.IsInf(f, 1):
+			p.Value, p.To = FloatInfinity, TypeFloat
+		case math.IsInf(f, -1):
+			p.Value, p.To = FloatNegInfinity, TypeFloat
+		default:
+			text := strconv.FormatFloat(f, 'g', -1, 64)
+			if e.To == TypeFloat && !strings.ContainsAny(text, ".e") {
+				text += ".0"
+			}
+			p.Value = json.Number(text)
+		}
+	}
+	return json.Marshal(p)
+}
+
+// UnmarshalJSON decodes an expression. Literal numbers written with a
+// fraction or an exponent, such as 22.0, become float64 values marked as
+// floats with "to": "float"; other numbers become int64 values, or *big.Int
+// values when they are too large for int64.
+func (e *Expression) UnmarshalJSON(data []byte) error {
+	type plain Expression
+	var p struct {
+		plain
+		Value json.RawMessage `json:"value,omitempty"`
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*e = Expression(p.plain)
+	if len(p.Value) == 0 {
+		return nil
+	}
+	value, err := decodeLiteralValue(p.Value)
+	if err != nil {
+		return err
+	}
+	e.Value = value
+	if _, ok := value.(float64); ok && e.Type == ExprLiteral && e.To == "" {
+		e.To, e.inferredTo = TypeFloat, true
+	}
+	return nil
+}
+
+// decodeLiteralValue decodes the JSON value of a literal, keeping integers
+// apart from floats.
+func decodeLiteralValue(data json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		// Numbers nested in other values keep encoding/json's float64
+		var plain interface{}
+		err := json.Unmarshal(data, &plain)
+		return plain, err
+	}
+	if !strings.ContainsAny(number.String(), ".eE") {
+		if n, err := number.Int64(); err == nil {
+			return n, nil
+		}
+		if n, ok := new(big.Int).SetString(number.String(), 10); ok {
+			return n, nil
+		}
+	}
+	return number.Float64()
+}
+
+// MatchCase represents one arm of a match statement. Bindings maps payload
+// field names of the variant to the variables they are bound to in Body.
+type MatchCase struct {
+	Variant  string            `json:"variant"`
+	Bindings map[string]string `json:"bindings,omitempty"`
+	Body     []Statement       `json:"body"`
 }
 
 // MapPair represents a key-value pair in a map literal.
@@ -70,9 +340,52 @@ type TypeDefinition struct {
 
 // TypeDefinitionDef represents the definition of a custom type.
 type TypeDefinitionDef struct {
-	Kind   string      `json:"kind"` // "struct" or "enum"
+	Kind     string        `json:"kind"` // "struct", "enum", or "alias"
+	Fields   []TypeField   `json:"fields,omitempty"`
+	Values   []string      `json:"values,omitempty"`
+	Variants []EnumVariant `json:"variants,omitempty"` // Enum variants with associated data
+	Type     string        `json:"type,omitempty"`     // Aliased type, for aliases
+}
+
+// EnumVariant represents an enum variant that may carry typed payload fields.
+type EnumVariant struct {
+	Name   string      `json:"name"`
 	Fields []TypeField `json:"fields,omitempty"`
-	Values []string    `json:"values,omitempty"`
+}
+
+// EnumVariants returns the variants of an enum definition. Plain enum
+// values are returned as variants without payload fields.
+func (d *TypeDefinitionDef) EnumVariants() []EnumVariant {
+	if len(d.Variants) > 0 {
+		return d.Variants
+	}
+	variants := make([]EnumVariant, len(d.Values))
+	for i, name := range d.Values {
+		variants[i] = EnumVariant{Name: name}
+	}
+	return variants
+}
+
+// VariantIndex returns the position of the named variant among the enum's
+// variants. It is the tag the variant is represented by and the value of the
+// variant cast to int.
+func (d *TypeDefinitionDef) VariantIndex(name string) (int, bool) {
+	for i, variant := range d.EnumVariants() {
+		if variant.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// HasPayload reports whether any enum variant carries payload fields.
+func (d *TypeDefinitionDef) HasPayload() bool {
+	for _, variant := range d.Variants {
+		if len(variant.Fields) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // TypeField represents a field in a struct type.
@@ -83,12 +396,16 @@ type TypeField struct {
 
 // Statement types.
 const (
-	StmtAssign = "assign"
-	StmtIf     = "if"
-	StmtWhile  = "while"
-	StmtFor    = "for"
-	StmtReturn = "return"
-	StmtExpr   = "expr"
+	StmtAssign      = "assign"
+	StmtIf          = "if"
+	StmtWhile       = "while"
+	StmtFor         = "for"
+	StmtReturn      = "return"
+	StmtExpr        = "expr"
+	StmtAssert      = "assert"
+	StmtMatch       = "match"
+	StmtDefer       = "defer"
+	StmtDestructure = "destructure"
 )
 
 // Expression types.
@@ -104,6 +421,11 @@ const (
 	ExprMapLit     = "map_literal"
 	ExprModuleCall = "module_call"
 	ExprBuiltin    = "builtin"
+	ExprCast       = "cast"
+	ExprMethodCall = "method_call"
+	ExprVariant    = "variant"
+	ExprLambda     = "lambda"
+	ExprFuncRef    = "func_ref"
 )
 
 // Binary operators.
@@ -131,17 +453,176 @@ const (
 
 // Basic types.
 const (
-	TypeInt    = "int"
-	TypeFloat  = "float"
-	TypeString = "string"
-	TypeBool   = "bool"
-	TypeArray  = "array"
-	TypeMap    = "map"
-	TypeVoid   = "void"
+	TypeInt     = "int"
+	TypeFloat   = "float"
+	TypeString  = "string"
+	TypeBool    = "bool"
+	TypeArray   = "array"
+	TypeMap     = "map"
+	TypeVoid    = "void"
+	TypeFunc    = "function"
+	TypeDecimal = "decimal"
 )
 
 // Custom type kinds.
 const (
 	TypeKindStruct = "struct"
 	TypeKindEnum   = "enum"
+	TypeKindAlias  = "alias"
+)
+
+// ParseType splits a type into its base name and type arguments, so
+// "map<string, array<int>>" has base "map" and arguments "string" and
+// "array<int>". Types without arguments, such as "int", have none.
+func ParseType(t string) (string, []string, error) {
+	t = strings.TrimSpace(t)
+	open := strings.IndexByte(t, '<')
+	if open < 0 {
+		if strings.ContainsAny(t, ">,") {
+			return "", nil, fmt.Errorf("malformed type '%s'", t)
+		}
+		return t, nil, nil
+	}
+	if !strings.HasSuffix(t, ">") {
+		return "", nil, fmt.Errorf("malformed type '%s': missing '>'", t)
+	}
+
+	base := strings.TrimSpace(t[:open])
+	var args []string
+	depth, start := 0, open+1
+	for i := open + 1; i < len(t)-1; i++ {
+		switch t[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth < 0 {
+				return "", nil, fmt.Errorf("malformed type '%s': unbalanced '>'", t)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(t[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return "", nil, fmt.Errorf("malformed type '%s': unbalanced '<'", t)
+	}
+	args = append(args, strings.TrimSpace(t[start:len(t)-1]))
+	for _, arg := range args {
+		if arg == "" {
+			return "", nil, fmt.Errorf("malformed type '%s': empty type argument", t)
+		}
+	}
+	return base, args, nil
+}
+
+// BaseType returns the base name of a type, such as "array" for "array<int>".
+func BaseType(t string) string {
+	base, _, err := ParseType(t)
+	if err != nil {
+		return t
+	}
+	return base
+}
+
+// ElementType returns the type of the elements of array<T>, or of the
+// values of map<K,V>. It is empty for types without element types,
+// including plain "array" and "map".
+func ElementType(t string) string {
+	base, args, err := ParseType(t)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case base == TypeArray && len(args) == 1:
+		return args[0]
+	case base == TypeMap && len(args) == 2:
+		return args[1]
+	}
+	return ""
+}
+
+// ContainerType returns the parameterized type base<args...>.
+func ContainerType(base string, args ...string) string {
+	return base + "<" + strings.Join(args, ",") + ">"
+}
+
+// ResolveTypeAlias returns the type that name stands for, following aliases
+// of aliases, with lookup returning the custom type definition of a name or
+// nil. Names that are not aliases are returned unchanged. Type arguments
+// are resolved too, and the result is written without spaces, as in
+// "map<string,array<int>>".
+func ResolveTypeAlias(name string, lookup func(name string) *TypeDefinition) (string, error) {
+	return resolveTypeAlias(name, lookup, nil)
+}
+
+// resolveTypeAlias resolves name for ResolveTypeAlias, where chain holds the
+// aliases being resolved, so that an alias of a container of itself is
+// reported as a cycle.
+func resolveTypeAlias(name string, lookup func(name string) *TypeDefinition, chain []string) (string, error) {
+	for {
+		if base, args, err := ParseType(name); err == nil && len(args) > 0 {
+			for i, arg := range args {
+				resolved, err := resolveTypeAlias(arg, lookup, chain)
+				if err != nil {
+					return "", err
+				}
+				args[i] = resolved
+			}
+			return ContainerType(base, args...), nil
+		}
+		typeDef := lookup(name)
+		if typeDef == nil || typeDef.Definition.Kind != TypeKindAlias {
+			return name, nil
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return "", fmt.Errorf("type alias cycle: %s", strings.Join(append(chain, name), " -> "))
+			}
+		}
+		chain = append(chain, name)
+		name = typeDef.Definition.Type
+	}
+}
+
+// Special float literal spellings. JSON numbers cannot be infinite or NaN, so
+// these values are written as string literals with "to": "float".
+const (
+	FloatNaN         = "NaN"
+	FloatInfinity    = "Infinity"
+	FloatNegInfinity = "-Infinity"
 )
+
+// LiteralValue returns the value of a literal expression. Integers are
+// int64 and floats are float64; special float literals and integers marked
+// with "to": "float" are resolved to their float64 values.
+func (e *Expression) LiteralValue() interface{} {
+	if e.To == TypeFloat {
+		switch v := e.Value.(type) {
+		case string:
+			if f, ok := SpecialFloat(v); ok {
+				return f
+			}
+		case int:
+			return float64(v)
+		case int64:
+			return float64(v)
+		}
+	}
+	return e.Value
+}
+
+// SpecialFloat returns the value of a special float literal spelling.
+func SpecialFloat(s string) (float64, bool) {
+	switch s {
+	case FloatNaN:
+		return math.NaN(), true
+	case FloatInfinity:
+		return math.Inf(1), true
+	case FloatNegInfinity:
+		return math.Inf(-1), true
+	}
+	return 0, false
+}
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"bytes"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -26,7 +27,16 @@ func TestModule(t *testing.T) {
 				Type:      "module",
 				Name:      "test",
 				Exports:   []string{"foo", "bar"},
-				Imports:   []string{"std.io", "std.math"},
+				Imports:   []Import{{Module: "std.io"}, {Module: "std.math"}},
+				Functions: []Function{},
+			},
+		},
+		{
+			name: "module with aliased imports",
+			module: Module{
+				Type:      "module",
+				Name:      "test",
+				Imports:   []Import{{Module: "geometry.shapes", Alias: "shapes"}, {Module: "std.io"}},
 				Functions: []Function{},
 			},
 		},
@@ -83,6 +93,42 @@ func TestModule(t *testing.T) {
 	}
 }
 
+func TestImportJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Import
+		wantErr bool
+	}{
+		{name: "plain module name", json: `"std.io"`, want: Import{Module: "std.io"}},
+		{name: "object without alias", json: `{"module": "std.io"}`, want: Import{Module: "std.io"}},
+		{name: "object with alias", json: `{"module": "geometry.shapes", "as": "shapes"}`, want: Import{Module: "geometry.shapes", Alias: "shapes"}},
+		{name: "number", json: `42`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Import
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Imports without an alias are written as plain strings
+	data, err := json.Marshal([]Import{{Module: "std.io"}, {Module: "geometry.shapes", Alias: "shapes"}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `["std.io",{"module":"geometry.shapes","as":"shapes"}]`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
 func TestFunction(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -549,6 +595,73 @@ func TestExpression(t *testing.T) {
 	}
 }
 
+func TestLiteralJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want interface{}
+	}{
+		{name: "int", json: `42`, want: int64(42)},
+		{name: "float with fraction", json: `22.0`, want: 22.0},
+		{name: "float with exponent", json: `1e3`, want: 1e3},
+		{name: "string", json: `"x"`, want: "x"},
+		{name: "bool", json: `true`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Expression
+			if err := json.Unmarshal([]byte(`{"type": "literal", "value": `+tt.json+`}`), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.Value != tt.want {
+				t.Fatalf("Unmarshal() value = %#v, want %#v", got.Value, tt.want)
+			}
+
+			// Floats keep their type when encoded again
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var again Expression
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatalf("Unmarshal() of %s error = %v", data, err)
+			}
+			if again.Value != tt.want {
+				t.Errorf("value after Marshal() = %#v, want %#v (JSON %s)", again.Value, tt.want, data)
+			}
+			if bytes.Contains(data, []byte(`"to"`)) {
+				t.Errorf("Marshal() = %s, want the inferred float type left out", data)
+			}
+		})
+	}
+}
+
+func TestLiteralMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expression
+		want string
+	}{
+		// Integral float64 values built in Go are ints unless marked as floats
+		{name: "integral float64", expr: Expression{Type: ExprLiteral, Value: float64(42)}, want: `{"type":"literal","value":42}`},
+		{name: "fractional float64", expr: Expression{Type: ExprLiteral, Value: 2.5}, want: `{"type":"literal","value":2.5}`},
+		{name: "explicit float", expr: Expression{Type: ExprLiteral, Value: float64(42), To: TypeFloat}, want: `{"type":"literal","value":42.0,"to":"float"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.expr)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
 func TestMapPair(t *testing.T) {
 	tests := []struct {
 		name string
@@ -608,10 +721,10 @@ func TestMapPair(t *testing.T) {
 func TestConstants(t *testing.T) {
 	// Test statement type constants
 	stmtTypes := []string{
-		StmtAssign, StmtIf, StmtWhile, StmtFor, StmtReturn, StmtExpr,
+		StmtAssign, StmtIf, StmtWhile, StmtFor, StmtReturn, StmtExpr, StmtAssert, StmtMatch,
 	}
 	expectedStmtTypes := []string{
-		"assign", "if", "while", "for", "return", "expr",
+		"assign", "if", "while", "for", "return", "expr", "assert", "match",
 	}
 	for i, got := range stmtTypes {
 		if got != expectedStmtTypes[i] {
@@ -623,10 +736,12 @@ func TestConstants(t *testing.T) {
 	exprTypes := []string{
 		ExprLiteral, ExprVariable, ExprBinary, ExprUnary, ExprCall,
 		ExprIndex, ExprField, ExprArrayLit, ExprMapLit, ExprModuleCall, ExprBuiltin,
+		ExprCast, ExprMethodCall, ExprVariant, ExprLambda, ExprFuncRef,
 	}
 	expectedExprTypes := []string{
 		"literal", "variable", "binary", "unary", "call",
 		"index", "field", "array_literal", "map_literal", "module_call", "builtin",
+		"cast", "method_call", "variant", "lambda", "func_ref",
 	}
 	for i, got := range exprTypes {
 		if got != expectedExprTypes[i] {
@@ -767,3 +882,146 @@ func TestComplexStructures(t *testing.T) {
 		t.Errorf("Complex module mismatch after marshal/unmarshal\ngot:  %s\nwant: %s", gotJSON, wantJSON)
 	}
 }
+
+func TestEnumVariants(t *testing.T) {
+	plain := TypeDefinitionDef{Kind: TypeKindEnum, Values: []string{"red", "green"}}
+	variants := plain.EnumVariants()
+	if len(variants) != 2 || variants[0].Name != "red" || variants[1].Name != "green" || len(variants[0].Fields) != 0 {
+		t.Errorf("EnumVariants() for plain enum = %+v", variants)
+	}
+	if plain.HasPayload() {
+		t.Error("plain enum should not have payload")
+	}
+
+	tagged := TypeDefinitionDef{
+		Kind: TypeKindEnum,
+		Variants: []EnumVariant{
+			{Name: "Ok", Fields: []TypeField{{Name: "value", Type: "int"}}},
+			{Name: "None"},
+		},
+	}
+	if got := tagged.EnumVariants(); len(got) != 2 || got[0].Name != "Ok" {
+		t.Errorf("EnumVariants() for tagged enum = %+v", got)
+	}
+	if !tagged.HasPayload() {
+		t.Error("tagged enum should have payload")
+	}
+
+	for _, tt := range []struct {
+		def     TypeDefinitionDef
+		variant string
+		want    int
+		wantOK  bool
+	}{
+		{def: plain, variant: "green", want: 1, wantOK: true},
+		{def: tagged, variant: "Ok", want: 0, wantOK: true},
+		{def: tagged, variant: "Err", wantOK: false},
+	} {
+		if got, ok := tt.def.VariantIndex(tt.variant); got != tt.want || ok != tt.wantOK {
+			t.Errorf("VariantIndex(%q) = %d, %v, want %d, %v", tt.variant, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveTypeAlias(t *testing.T) {
+	alias := func(name, target string) TypeDefinition {
+		return TypeDefinition{Name: name, Definition: TypeDefinitionDef{Kind: TypeKindAlias, Type: target}}
+	}
+	defs := map[string]TypeDefinition{
+		"Celsius": alias("Celsius", TypeFloat),
+		"Temp":    alias("Temp", "Celsius"),
+		"Point":   {Name: "Point", Definition: TypeDefinitionDef{Kind: TypeKindStruct, Fields: []TypeField{{Name: "x", Type: TypeInt}}}},
+		"Pos":     alias("Pos", "Point"),
+		"A":       alias("A", "B"),
+		"B":       alias("B", "A"),
+		"Self":    alias("Self", "Self"),
+		"Temps":   alias("Temps", "array<Temp>"),
+		"Nested":  alias("Nested", "array<Nested>"),
+	}
+	lookup := func(name string) *TypeDefinition {
+		if def, ok := defs[name]; ok {
+			return &def
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: TypeInt, want: TypeInt},
+		{name: "Point", want: "Point"},
+		{name: "Celsius", want: TypeFloat},
+		{name: "Temp", want: TypeFloat},
+		{name: "Pos", want: "Point"},
+		{name: "A", wantErr: "type alias cycle: A -> B -> A"},
+		{name: "Self", wantErr: "type alias cycle: Self -> Self"},
+		{name: "array<Pos>", want: "array<Point>"},
+		{name: "map< string , Temps >", want: "map<string,array<float>>"},
+		{name: "Nested", wantErr: "type alias cycle: Nested -> Nested"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTypeAlias(tt.name, lookup)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ResolveTypeAlias() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveTypeAlias() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveTypeAlias() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		typ      string
+		wantBase string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{typ: TypeInt, wantBase: TypeInt},
+		{typ: TypeArray, wantBase: TypeArray},
+		{typ: "array<int>", wantBase: TypeArray, wantArgs: []string{TypeInt}},
+		{typ: "map<string, array<int>>", wantBase: TypeMap, wantArgs: []string{TypeString, "array<int>"}},
+		{typ: "map<map<string,int>,float>", wantBase: TypeMap, wantArgs: []string{"map<string,int>", TypeFloat}},
+		{typ: "array<int", wantErr: true},
+		{typ: "array<>", wantErr: true},
+		{typ: "map<string,>", wantErr: true},
+		{typ: "array<int>>", wantErr: true},
+		{typ: "int>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			base, args, err := ParseType(tt.typ)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseType() = %q, %q, want error", base, args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseType() error = %v", err)
+			}
+			if base != tt.wantBase || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ParseType() = %q, %q, want %q, %q", base, args, tt.wantBase, tt.wantArgs)
+			}
+		})
+	}
+
+	if got := ElementType("map<string,array<int>>"); got != "array<int>" {
+		t.Errorf("ElementType() = %q, want array<int>", got)
+	}
+	if got := ElementType(TypeArray); got != "" {
+		t.Errorf("ElementType(array) = %q, want empty", got)
+	}
+}
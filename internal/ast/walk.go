@@ -0,0 +1,53 @@
+package ast
+
+// WalkStatements calls visitStmt for every statement and visitExpr for every
+// expression in stmts, including those nested in other expressions, control
+// flow bodies, and lambda bodies. Either visitor may be nil.
+func WalkStatements(stmts []Statement, visitStmt func(*Statement), visitExpr func(*Expression)) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		if visitStmt != nil {
+			visitStmt(stmt)
+		}
+		WalkExpression(stmt.Value, visitStmt, visitExpr)
+		WalkExpression(stmt.Lvalue, visitStmt, visitExpr)
+		WalkExpression(stmt.Cond, visitStmt, visitExpr)
+		WalkStatements(stmt.Then, visitStmt, visitExpr)
+		WalkStatements(stmt.Else, visitStmt, visitExpr)
+		WalkStatements(stmt.Init, visitStmt, visitExpr)
+		WalkStatements(stmt.Body, visitStmt, visitExpr)
+		WalkStatements(stmt.Update, visitStmt, visitExpr)
+		for j := range stmt.Cases {
+			WalkStatements(stmt.Cases[j].Body, visitStmt, visitExpr)
+		}
+		WalkStatements(stmt.Default, visitStmt, visitExpr)
+	}
+}
+
+// WalkExpression calls visitExpr for expr and every expression nested in it,
+// and visitStmt for the statements of lambda bodies. Either visitor may be
+// nil.
+func WalkExpression(expr *Expression, visitStmt func(*Statement), visitExpr func(*Expression)) {
+	if expr == nil {
+		return
+	}
+	if visitExpr != nil {
+		visitExpr(expr)
+	}
+	WalkExpression(expr.Left, visitStmt, visitExpr)
+	WalkExpression(expr.Right, visitStmt, visitExpr)
+	WalkExpression(expr.Operand, visitStmt, visitExpr)
+	WalkExpression(expr.Index, visitStmt, visitExpr)
+	WalkExpression(expr.Object, visitStmt, visitExpr)
+	for i := range expr.Args {
+		WalkExpression(&expr.Args[i], visitStmt, visitExpr)
+	}
+	for i := range expr.Elements {
+		WalkExpression(&expr.Elements[i], visitStmt, visitExpr)
+	}
+	for i := range expr.Pairs {
+		WalkExpression(&expr.Pairs[i].Key, visitStmt, visitExpr)
+		WalkExpression(&expr.Pairs[i].Value, visitStmt, visitExpr)
+	}
+	WalkStatements(expr.Body, visitStmt, visitExpr)
+}
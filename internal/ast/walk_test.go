@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalkStatements(t *testing.T) {
+	variable := func(name string) *Expression { return &Expression{Type: ExprVariable, Name: name} }
+	body := []Statement{
+		{Type: StmtIf, Cond: variable("cond"), Then: []Statement{
+			{Type: StmtAssign, Target: "f", Value: &Expression{Type: ExprLambda, Body: []Statement{
+				{Type: StmtReturn, Value: &Expression{Type: ExprBinary, Op: OpAdd, Left: variable("a"), Right: variable("b")}},
+			}}},
+		}},
+		{Type: StmtMatch, Value: variable("s"), Cases: []MatchCase{
+			{Variant: "Some", Body: []Statement{{Type: StmtExpr, Value: &Expression{Type: ExprCall, Name: "g", Args: []Expression{*variable("c")}}}}},
+		}},
+		{Type: StmtReturn, Value: &Expression{Type: ExprMapLit, Pairs: []MapPair{{Key: *variable("k"), Value: *variable("v")}}}},
+	}
+
+	var stmts []string
+	var names []string
+	WalkStatements(body, func(stmt *Statement) {
+		stmts = append(stmts, stmt.Type)
+	}, func(expr *Expression) {
+		if expr.Name != "" {
+			names = append(names, expr.Name)
+		}
+	})
+
+	if want := []string{StmtIf, StmtAssign, StmtReturn, StmtMatch, StmtExpr, StmtReturn}; !reflect.DeepEqual(stmts, want) {
+		t.Errorf("statements = %v, want %v", stmts, want)
+	}
+	if want := []string{"cond", "a", "b", "s", "g", "c", "k", "v"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expression names = %v, want %v", names, want)
+	}
+
+	// A nil visitor is skipped
+	count := 0
+	WalkStatements(body, nil, func(*Expression) { count++ })
+	if count != 11 {
+		t.Errorf("visited %d expressions, want 11", count)
+	}
+}
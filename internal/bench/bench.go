@@ -0,0 +1,242 @@
+// Package bench runs an ALaS program through the interpreter and as a
+// compiled native executable, checks that both backends compute the same
+// result, and times each of them.
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/codegen"
+	"github.com/dshills/alas/internal/interpreter"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// ErrToolchainNotFound is returned when llc or a C compiler to link the
+// compiled program is missing.
+var ErrToolchainNotFound = errors.New("llc and cc are required to compile programs")
+
+// Options controls a benchmark run.
+type Options struct {
+	Iterations int                       // Times main is run by each backend, at least 1
+	OptLevel   codegen.OptimizationLevel // Optimization level of the compiled program
+	LibDir     string                    // Directory holding libalas_stdlib.so, if programs need builtins
+}
+
+// DefaultOptions returns options that run main 10 times at -O 2.
+func DefaultOptions() Options {
+	return Options{Iterations: 10, OptLevel: codegen.OptStandard}
+}
+
+// Result is the outcome of running a program on both backends.
+type Result struct {
+	Interpreted string        // Result of main in the interpreter, formatted as the compiled program prints it
+	Compiled    string        // Result of main printed by the compiled program
+	Interpreter time.Duration // Wall-clock time of all interpreter runs
+	Executable  time.Duration // Wall-clock time of the compiled program, including process start-up
+}
+
+// Match reports whether both backends computed the same result.
+func (r *Result) Match() bool {
+	return r.Interpreted == r.Compiled
+}
+
+// Run runs the main function of module opts.Iterations times in the
+// interpreter and in a native executable built with llc and cc, and returns
+// both results and timings. main must take no parameters and return an int,
+// float, bool, string, or nothing. Compilation happens in a temporary
+// directory and is not included in the timings.
+func Run(module *ast.Module, opts Options) (*Result, error) {
+	if opts.Iterations < 1 {
+		return nil, fmt.Errorf("iterations must be at least 1, got %d", opts.Iterations)
+	}
+	mainFn := findMain(module)
+	if mainFn == nil {
+		return nil, fmt.Errorf("module %s has no main function", module.Name)
+	}
+	if len(mainFn.Params) > 0 {
+		return nil, fmt.Errorf("main must take no parameters, got %d", len(mainFn.Params))
+	}
+
+	result := &Result{}
+
+	interp := interpreter.New()
+	if err := interp.LoadModule(module); err != nil {
+		return nil, fmt.Errorf("failed to load module: %v", err)
+	}
+	var value runtime.Value
+	start := time.Now()
+	for i := 0; i < opts.Iterations; i++ {
+		var err error
+		value, err = interp.Run("main", nil)
+		if err != nil {
+			return nil, fmt.Errorf("interpreter failed: %v", err)
+		}
+	}
+	result.Interpreter = time.Since(start)
+	formatted, err := formatValue(value, mainFn.Returns)
+	if err != nil {
+		return nil, err
+	}
+	result.Interpreted = formatted
+
+	dir, err := os.MkdirTemp("", "alas-bench-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	executable, err := compile(module, mainFn.Returns, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(executable, strconv.Itoa(opts.Iterations))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start = time.Now()
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("compiled program failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	result.Executable = time.Since(start)
+	result.Compiled = lastLine(stdout.String())
+
+	return result, nil
+}
+
+// findMain returns the main function of a module, or nil.
+func findMain(module *ast.Module) *ast.Function {
+	for i := range module.Functions {
+		if module.Functions[i].Name == "main" && module.Functions[i].Receiver == nil {
+			return &module.Functions[i]
+		}
+	}
+	return nil
+}
+
+// formatValue formats a result of main the way the compiled driver prints it.
+func formatValue(v runtime.Value, returns string) (string, error) {
+	switch returns {
+	case ast.TypeVoid, "":
+		return "", nil
+	case ast.TypeInt:
+		n, err := v.AsInt()
+		return strconv.FormatInt(n, 10), err
+	case ast.TypeFloat:
+		f, err := v.AsFloat()
+		return fmt.Sprintf("%.17g", f), err
+	case ast.TypeBool:
+		b, err := v.AsBool()
+		return strconv.FormatBool(b), err
+	case ast.TypeString:
+		return v.AsString()
+	}
+	return "", fmt.Errorf("cannot compare results of type %s", returns)
+}
+
+// lastLine returns the last line of the program output, which holds the
+// result printed by the driver.
+func lastLine(output string) string {
+	output = strings.TrimSuffix(output, "\n")
+	if i := strings.LastIndexByte(output, '\n'); i >= 0 {
+		return output[i+1:]
+	}
+	return output
+}
+
+// compile builds module into a native executable in dir and returns its path.
+func compile(module *ast.Module, returns, dir string, opts Options) (string, error) {
+	llvmModule, err := codegen.NewLLVMCodegen().GenerateModule(module)
+	if err != nil {
+		return "", fmt.Errorf("code generation failed: %v", err)
+	}
+	if opts.OptLevel > codegen.OptNone {
+		if err := codegen.NewOptimizer(opts.OptLevel).OptimizeModule(llvmModule); err != nil {
+			return "", fmt.Errorf("optimization failed: %v", err)
+		}
+	}
+	if err := addDriver(llvmModule, returns); err != nil {
+		return "", err
+	}
+
+	executable := filepath.Join(dir, "program")
+	err = codegen.LinkExecutable(llvmModule, executable, opts.LibDir, "-O2")
+	if errors.Is(err, codegen.ErrToolchainNotFound) {
+		return "", ErrToolchainNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return executable, nil
+}
+
+// addDriver renames the ALaS main function and adds a C main that calls it
+// the number of times given by its first argument, then prints the result of
+// the last call on a line of its own after any output of the program.
+func addDriver(module *ir.Module, returns string) error {
+	alasMain, driver, err := codegen.NewDriver(module)
+	if err != nil {
+		return err
+	}
+
+	i8Ptr := types.NewPointer(types.I8)
+	printf := module.NewFunc("printf", types.I32, ir.NewParam("format", i8Ptr))
+	printf.Sig.Variadic = true
+	atol := module.NewFunc("atol", types.I64, ir.NewParam("s", i8Ptr))
+
+	argv := driver.Params[1]
+	entry := driver.NewBlock("entry")
+	loop := driver.NewBlock("loop")
+	exit := driver.NewBlock("exit")
+
+	arg := entry.NewLoad(i8Ptr, entry.NewGetElementPtr(i8Ptr, argv, constant.NewInt(types.I64, 1)))
+	iterations := entry.NewCall(atol, arg)
+	entry.NewBr(loop)
+
+	counter := loop.NewPhi(ir.NewIncoming(constant.NewInt(types.I64, 0), entry))
+	result := loop.NewCall(alasMain)
+	next := loop.NewAdd(counter, constant.NewInt(types.I64, 1))
+	counter.Incs = append(counter.Incs, ir.NewIncoming(next, loop))
+	loop.NewCondBr(loop.NewICmp(enum.IPredSLT, next, iterations), loop, exit)
+
+	format := func(s string) *ir.Global {
+		def := module.NewGlobalDef("", constant.NewCharArrayFromString(s+"\x00"))
+		def.Immutable = true
+		return def
+	}
+	pointer := func(g *ir.Global) *constant.ExprGetElementPtr {
+		zero := constant.NewInt(types.I64, 0)
+		return constant.NewGetElementPtr(g.ContentType, g, zero, zero)
+	}
+	switch returns {
+	case ast.TypeVoid, "":
+		exit.NewCall(printf, pointer(format("\n\n")))
+	case ast.TypeInt:
+		exit.NewCall(printf, pointer(format("\n%lld\n")), result)
+	case ast.TypeFloat:
+		exit.NewCall(printf, pointer(format("\n%.17g\n")), result)
+	case ast.TypeBool:
+		name := exit.NewSelect(result, pointer(format("true")), pointer(format("false")))
+		exit.NewCall(printf, pointer(format("\n%s\n")), name)
+	case ast.TypeString:
+		exit.NewCall(printf, pointer(format("\n%s\n")), result)
+	default:
+		return fmt.Errorf("cannot compare results of type %s", returns)
+	}
+	exit.NewRet(constant.NewInt(types.I32, 0))
+	return nil
+}
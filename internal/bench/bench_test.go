@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    runtime.Value
+		returns  string
+		expected string
+	}{
+		{"int", runtime.NewInt(-42), ast.TypeInt, "-42"},
+		{"float", runtime.NewFloat(0.1), ast.TypeFloat, "0.10000000000000001"},
+		{"bool", runtime.NewBool(true), ast.TypeBool, "true"},
+		{"string", runtime.NewString("hi"), ast.TypeString, "hi"},
+		{"void", runtime.NewVoid(), ast.TypeVoid, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatValue(tt.value, tt.returns)
+			if err != nil {
+				t.Fatalf("formatValue() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("formatValue() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := formatValue(runtime.NewInt(1), ast.TypeArray); err == nil {
+		t.Error("formatValue() accepted an array result")
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{"\n55\n", "55"},
+		{"Hello\nworld\n\n", ""},
+		{"no newline", "no newline"},
+	}
+
+	for _, tt := range tests {
+		if got := lastLine(tt.output); got != tt.expected {
+			t.Errorf("lastLine(%q) = %q, want %q", tt.output, got, tt.expected)
+		}
+	}
+}
+
+func TestRunRejectsMainWithParameters(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "params",
+		Functions: []ast.Function{{
+			Type:    "function",
+			Name:    "main",
+			Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+			Returns: ast.TypeInt,
+		}},
+	}
+	if _, err := Run(module, DefaultOptions()); err == nil {
+		t.Error("Run() accepted a main function with parameters")
+	}
+}
@@ -0,0 +1,234 @@
+// Package builtins describes the signatures of the ALaS builtin functions so
+// the validator and the code generator agree on how they may be called.
+package builtins
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// Parameter types used in signatures besides the ALaS types.
+const (
+	TypeAny            = "any"            // accepts a value of any type
+	TypeNumber         = "number"         // accepts an int or a float
+	TypeDecimalOperand = "decimal or int" // accepts a decimal or an int
+	TypeStringBuilder  = "stringBuilder"  // a builder from string.builderCreate
+	TypeChannel        = "channel"        // a channel from channel.make
+	TypeMutex          = "mutex"          // a mutex from sync.mutexCreate
+)
+
+// Signature describes the parameters of a builtin function.
+type Signature struct {
+	Params   []string // parameter types
+	Optional int      // number of trailing parameters that may be omitted
+}
+
+// MinArgs returns the smallest number of arguments the builtin accepts.
+func (s Signature) MinArgs() int {
+	return len(s.Params) - s.Optional
+}
+
+// MaxArgs returns the largest number of arguments the builtin accepts.
+func (s Signature) MaxArgs() int {
+	return len(s.Params)
+}
+
+// CheckArgCount returns an error if n arguments are not valid for the
+// builtin name with this signature.
+func (s Signature) CheckArgCount(name string, n int) error {
+	if n >= s.MinArgs() && n <= s.MaxArgs() {
+		return nil
+	}
+	switch {
+	case s.Optional > 0:
+		return fmt.Errorf("%s expects %d to %d arguments, got %d", name, s.MinArgs(), s.MaxArgs(), n)
+	case s.MaxArgs() == 1:
+		return fmt.Errorf("%s expects 1 argument, got %d", name, n)
+	}
+	return fmt.Errorf("%s expects %d arguments, got %d", name, s.MaxArgs(), n)
+}
+
+// Accepts reports whether an argument of type argType may be passed for
+// parameter i. Unknown argument types and custom types are accepted, since
+// they can only be checked at runtime.
+func (s Signature) Accepts(i int, argType string) bool {
+	param := s.Params[i]
+	// Parameterized containers such as array<int> pass for their base type
+	argType = ast.BaseType(argType)
+	switch {
+	case param == TypeAny || argType == "" || param == argType:
+		return true
+	case param == TypeNumber:
+		return argType == ast.TypeInt || argType == ast.TypeFloat
+	case param == TypeDecimalOperand:
+		return argType == ast.TypeDecimal || argType == ast.TypeInt
+	}
+	switch argType {
+	case ast.TypeInt, ast.TypeFloat, ast.TypeString, ast.TypeBool,
+		ast.TypeArray, ast.TypeMap, ast.TypeFunc, ast.TypeDecimal:
+		return false
+	}
+	return true
+}
+
+// Lookup returns the signature of a builtin function.
+func Lookup(name string) (Signature, bool) {
+	sig, ok := signatures[name]
+	return sig, ok
+}
+
+// Names returns the names of all builtins with a known signature.
+func Names() []string {
+	names := make([]string, 0, len(signatures))
+	for name := range signatures {
+		names = append(names, name)
+	}
+	return names
+}
+
+func params(types ...string) Signature {
+	return Signature{Params: types}
+}
+
+// signatures covers the builtins of both the interpreter's standard library
+// and the compiled runtime.
+var signatures = map[string]Signature{
+	"io.print":     params(TypeAny),
+	"io.debug":     params(TypeAny),
+	"io.readFile":  params(ast.TypeString),
+	"io.writeFile": params(ast.TypeString, ast.TypeString),
+	"io.readLine":  params(),
+
+	"os.getenv": params(ast.TypeString),
+	"os.setenv": params(ast.TypeString, ast.TypeString),
+	"os.exit":   params(ast.TypeInt),
+
+	"decimal.fromString": params(ast.TypeString),
+	"decimal.toString":   {Params: []string{ast.TypeDecimal, ast.TypeInt, ast.TypeString}, Optional: 2},
+	"decimal.add":        {Params: []string{TypeDecimalOperand, TypeDecimalOperand, ast.TypeInt, ast.TypeString}, Optional: 2},
+	"decimal.sub":        {Params: []string{TypeDecimalOperand, TypeDecimalOperand, ast.TypeInt, ast.TypeString}, Optional: 2},
+	"decimal.mul":        {Params: []string{TypeDecimalOperand, TypeDecimalOperand, ast.TypeInt, ast.TypeString}, Optional: 2},
+	"decimal.div":        {Params: []string{TypeDecimalOperand, TypeDecimalOperand, ast.TypeInt, ast.TypeString}, Optional: 2},
+
+	"math.E":         params(),
+	"math.PI":        params(),
+	"math.pi":        params(),
+	"math.abs":       params(TypeNumber),
+	"math.acos":      params(TypeNumber),
+	"math.asin":      params(TypeNumber),
+	"math.atan":      params(TypeNumber),
+	"math.ceil":      params(TypeNumber),
+	"math.cos":       params(TypeNumber),
+	"math.floor":     params(TypeNumber),
+	"math.log":       params(TypeNumber),
+	"math.round":     params(TypeNumber),
+	"math.sin":       params(TypeNumber),
+	"math.sqrt":      params(TypeNumber),
+	"math.tan":       params(TypeNumber),
+	"math.max":       params(TypeNumber, TypeNumber),
+	"math.min":       params(TypeNumber, TypeNumber),
+	"math.pow":       params(TypeNumber, TypeNumber),
+	"math.random":    params(),
+	"math.randomInt": params(ast.TypeInt, ast.TypeInt),
+
+	"collections.length":   params(TypeAny),
+	"collections.append":   params(ast.TypeArray, TypeAny),
+	"collections.contains": params(TypeAny, TypeAny),
+	"collections.indexOf":  params(TypeAny, TypeAny),
+	"collections.slice":    {Params: []string{TypeAny, ast.TypeInt, ast.TypeInt}, Optional: 1},
+	"collections.map":      params(ast.TypeArray, ast.TypeFunc),
+	"collections.filter":   params(ast.TypeArray, ast.TypeFunc),
+
+	"array.length":   params(ast.TypeArray),
+	"array.push":     params(ast.TypeArray, TypeAny),
+	"array.pop":      params(ast.TypeArray),
+	"array.slice":    params(ast.TypeArray, ast.TypeInt, ast.TypeInt),
+	"array.map":      params(ast.TypeArray, ast.TypeFunc),
+	"array.filter":   params(ast.TypeArray, ast.TypeFunc),
+	"array.reduce":   params(ast.TypeArray, ast.TypeFunc, TypeAny),
+	"array.reverse":  params(ast.TypeArray),
+	"array.indexOf":  params(ast.TypeArray, TypeAny),
+	"array.contains": params(ast.TypeArray, TypeAny),
+	"array.concat":   params(ast.TypeArray, ast.TypeArray),
+	"array.sort":     {Params: []string{ast.TypeArray, ast.TypeFunc}, Optional: 1},
+
+	"map.get":      params(ast.TypeMap, TypeAny),
+	"map.put":      params(ast.TypeMap, TypeAny, TypeAny),
+	"map.contains": params(ast.TypeMap, TypeAny),
+	"map.remove":   params(ast.TypeMap, TypeAny),
+	"map.size":     params(ast.TypeMap),
+	"map.keys":     params(ast.TypeMap),
+	"map.values":   params(ast.TypeMap),
+
+	"string.length":       params(ast.TypeString),
+	"string.toUpper":      params(ast.TypeString),
+	"string.toLower":      params(ast.TypeString),
+	"string.trim":         params(ast.TypeString),
+	"string.split":        params(ast.TypeString, ast.TypeString),
+	"string.join":         params(ast.TypeArray, ast.TypeString),
+	"string.replace":      params(ast.TypeString, ast.TypeString, ast.TypeString),
+	"string.substring":    params(ast.TypeString, ast.TypeInt, ast.TypeInt),
+	"string.indexOf":      params(ast.TypeString, ast.TypeString),
+	"string.startsWith":   params(ast.TypeString, ast.TypeString),
+	"string.endsWith":     params(ast.TypeString, ast.TypeString),
+	"string.contains":     params(ast.TypeString, ast.TypeString),
+	"string.concat":       params(ast.TypeString, ast.TypeString),
+	"string.format":       params(ast.TypeString, TypeAny),
+	"string.charAt":       params(ast.TypeString, ast.TypeInt),
+	"string.charCodeAt":   params(ast.TypeString, ast.TypeInt),
+	"string.fromCharCode": params(ast.TypeInt),
+	"string.repeat":       params(ast.TypeString, ast.TypeInt),
+	"string.padStart":     params(ast.TypeString, ast.TypeInt, ast.TypeString),
+	"string.padEnd":       params(ast.TypeString, ast.TypeInt, ast.TypeString),
+
+	"string.builderCreate":   params(),
+	"string.builderAppend":   params(TypeStringBuilder, TypeAny),
+	"string.builderToString": params(TypeStringBuilder),
+
+	"type.typeOf":     params(TypeAny),
+	"type.isInt":      params(TypeAny),
+	"type.isFloat":    params(TypeAny),
+	"type.isString":   params(TypeAny),
+	"type.isBool":     params(TypeAny),
+	"type.isArray":    params(TypeAny),
+	"type.isMap":      params(TypeAny),
+	"type.toString":   params(TypeAny),
+	"type.parseInt":   params(ast.TypeString),
+	"type.parseFloat": params(ast.TypeString),
+
+	"result.ok":       params(TypeAny),
+	"result.error":    params(TypeAny),
+	"result.isOk":     params(TypeAny),
+	"result.isError":  params(TypeAny),
+	"result.getValue": params(TypeAny),
+	"result.getError": params(TypeAny),
+
+	"async.spawn":        {Params: []string{ast.TypeFunc, ast.TypeArray}, Optional: 1},
+	"async.await":        params(TypeAny),
+	"async.awaitTimeout": params(TypeAny, ast.TypeInt),
+	"async.parallel":     params(ast.TypeArray),
+	"async.race":         params(ast.TypeArray),
+	"async.sleep":        params(ast.TypeInt),
+	"async.timeout":      params(ast.TypeFunc, ast.TypeInt),
+	"async.cancel":       params(TypeAny),
+	"async.isRunning":    params(TypeAny),
+	"async.isCompleted":  params(TypeAny),
+
+	"channel.make":  params(ast.TypeInt),
+	"channel.send":  {Params: []string{TypeChannel, TypeAny, ast.TypeInt}, Optional: 1},
+	"channel.recv":  {Params: []string{TypeChannel, ast.TypeInt}, Optional: 1},
+	"channel.close": params(TypeChannel),
+
+	"sync.mutexCreate": params(),
+	"sync.mutexLock":   {Params: []string{TypeMutex, ast.TypeInt}, Optional: 1},
+	"sync.mutexUnlock": params(TypeMutex),
+
+	"runtime.version":    params(),
+	"runtime.hasBuiltin": params(ast.TypeString),
+
+	"log.debug": {Params: []string{TypeAny, ast.TypeMap}, Optional: 1},
+	"log.info":  {Params: []string{TypeAny, ast.TypeMap}, Optional: 1},
+	"log.warn":  {Params: []string{TypeAny, ast.TypeMap}, Optional: 1},
+	"log.error": {Params: []string{TypeAny, ast.TypeMap}, Optional: 1},
+}
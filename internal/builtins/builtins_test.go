@@ -0,0 +1,72 @@
+package builtins
+
+import (
+	"testing"
+
+	"github.com/dshills/alas/internal/stdlib"
+)
+
+func TestCheckArgCount(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  Signature
+		n    int
+		want string
+	}{
+		{name: "exact", sig: Signature{Params: []string{"int", "int"}}, n: 2},
+		{name: "too few single", sig: Signature{Params: []string{"string"}}, n: 0, want: "f expects 1 argument, got 0"},
+		{name: "too many", sig: Signature{Params: []string{"int", "int"}}, n: 3, want: "f expects 2 arguments, got 3"},
+		{name: "optional omitted", sig: Signature{Params: []string{"array", "int", "int"}, Optional: 1}, n: 2},
+		{name: "optional range", sig: Signature{Params: []string{"array", "int", "int"}, Optional: 1}, n: 1, want: "f expects 2 to 3 arguments, got 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sig.CheckArgCount("f", tt.n)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("CheckArgCount() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("CheckArgCount() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	sig := Signature{Params: []string{"string", TypeNumber, TypeAny, TypeDecimalOperand}}
+	tests := []struct {
+		i       int
+		argType string
+		want    bool
+	}{
+		{0, "string", true},
+		{0, "int", false},
+		{0, "", true},
+		{0, "Point", true},
+		{1, "int", true},
+		{1, "float", true},
+		{1, "bool", false},
+		{2, "map", true},
+		{0, "decimal", false},
+		{1, "decimal", false},
+		{3, "decimal", true},
+		{3, "int", true},
+		{3, "float", false},
+	}
+	for _, tt := range tests {
+		if got := sig.Accepts(tt.i, tt.argType); got != tt.want {
+			t.Errorf("Accepts(%d, %q) = %v, want %v", tt.i, tt.argType, got, tt.want)
+		}
+	}
+}
+
+func TestStdlibFunctionsHaveSignatures(t *testing.T) {
+	for _, name := range stdlib.NewRegistry().ListFunctions() {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("no signature for stdlib builtin %s", name)
+		}
+	}
+}
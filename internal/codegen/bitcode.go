@@ -0,0 +1,75 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+)
+
+// ErrLLVMAsNotFound is returned when bitcode is requested but no llvm-as
+// assembler can be found.
+var ErrLLVMAsNotFound = errors.New("llvm-as not found: install LLVM or add its bin directory to PATH to write bitcode, or use -format ll")
+
+// llvmAsCandidates lists the assembler names searched for on PATH in order of
+// preference: an unversioned llvm-as, then versioned names from newest.
+var llvmAsCandidates = []string{"llvm-as", "llvm-as-18", "llvm-as-17", "llvm-as-16", "llvm-as-15", "llvm-as-14"}
+
+// FindLLVMAs returns the path of the llvm-as assembler, looking on PATH
+// and then in the bin directory reported by llvm-config.
+func FindLLVMAs() (string, error) {
+	for _, name := range llvmAsCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if config, err := exec.LookPath("llvm-config"); err == nil {
+		out, err := exec.Command(config, "--bindir").Output()
+		if err == nil {
+			path := filepath.Join(strings.TrimSpace(string(out)), "llvm-as")
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+	}
+	return "", ErrLLVMAsNotFound
+}
+
+// WriteBitcode writes a module as LLVM bitcode. The IR is assembled with
+// llvm-as from a temporary .ll file, which is removed afterwards.
+func WriteBitcode(module *ir.Module, output string) error {
+	assembler, err := FindLLVMAs()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "alas-*.ll")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary IR file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(module.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary IR file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary IR file: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(assembler, tmp.Name(), "-o", output)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("llvm-as failed: %s", msg)
+		}
+		return fmt.Errorf("llvm-as failed: %w", err)
+	}
+	return nil
+}
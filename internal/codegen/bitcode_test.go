@@ -0,0 +1,49 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestWriteBitcode(t *testing.T) {
+	if _, err := FindLLVMAs(); err != nil {
+		t.Skip("llvm-as not available")
+	}
+	g := NewLLVMCodegen()
+	module, err := g.GenerateModule(singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 7.0}},
+	}))
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.bc")
+	if err := WriteBitcode(module, output); err != nil {
+		t.Fatalf("WriteBitcode() error = %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("reading bitcode: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("BC\xC0\xDE")) {
+		t.Errorf("expected bitcode magic, got % x", data[:min(len(data), 4)])
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the bitcode file in the output directory, got %d entries", len(entries))
+	}
+}
+
+func TestWriteBitcodeWithoutLLVMAs(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := WriteBitcode(nil, filepath.Join(t.TempDir(), "out.bc"))
+	if !errors.Is(err, ErrLLVMAsNotFound) {
+		t.Errorf("WriteBitcode() error = %v, want ErrLLVMAsNotFound", err)
+	}
+}
@@ -0,0 +1,82 @@
+package codegen
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/value"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// constantArrayLengths returns the lengths of the array literals held by
+// the variables of fn that are assigned only once, from an array literal.
+// Such a variable holds the same array wherever it is in scope, and arrays
+// never change length in place, so its length is a compile-time constant.
+func constantArrayLengths(fn *ast.Function) map[string]int64 {
+	assignments := make(map[string]int)
+	lengths := make(map[string]int64)
+	for _, param := range functionParams(fn) {
+		assignments[param.Name]++
+	}
+	countAssignments(fn.Body, assignments, lengths)
+	for name := range lengths {
+		if assignments[name] != 1 {
+			delete(lengths, name)
+		}
+	}
+	return lengths
+}
+
+// countAssignments counts the statements in stmts binding each variable,
+// and records the length of each array literal assigned to a variable.
+func countAssignments(stmts []ast.Statement, assignments map[string]int, lengths map[string]int64) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		if stmt.Target != "" {
+			assignments[stmt.Target]++
+			if stmt.Type == ast.StmtAssign && stmt.Value != nil && stmt.Value.Type == ast.ExprArrayLit {
+				lengths[stmt.Target] = int64(len(stmt.Value.Elements))
+			}
+		}
+		for _, name := range stmt.Targets {
+			assignments[name]++
+		}
+		for _, name := range stmt.Bindings {
+			assignments[name]++
+		}
+		for _, matchCase := range stmt.Cases {
+			for _, name := range matchCase.Bindings {
+				assignments[name]++
+			}
+			countAssignments(matchCase.Body, assignments, lengths)
+		}
+		countAssignments(stmt.Then, assignments, lengths)
+		countAssignments(stmt.Else, assignments, lengths)
+		countAssignments(stmt.Init, assignments, lengths)
+		countAssignments(stmt.Body, assignments, lengths)
+		countAssignments(stmt.Update, assignments, lengths)
+		countAssignments(stmt.Default, assignments, lengths)
+	}
+}
+
+// indexInBounds reports whether index is a constant known to be a valid
+// index of the array object evaluates to, so that its bounds check can be
+// left out.
+func (g *LLVMCodegen) indexInBounds(object *ast.Expression, index value.Value) bool {
+	c, ok := index.(*constant.Int)
+	if !ok || !c.X.IsInt64() {
+		return false
+	}
+
+	var length int64
+	switch object.Type {
+	case ast.ExprArrayLit:
+		length = int64(len(object.Elements))
+	case ast.ExprVariable:
+		if length, ok = g.arrayLengths[object.Name]; !ok {
+			return false
+		}
+	default:
+		return false
+	}
+	return c.X.Int64() >= 0 && c.X.Int64() < length
+}
@@ -0,0 +1,120 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestLLVMCodegen_ConstantIndexBoundsChecks(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(v float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	array := func(n int) *ast.Expression {
+		elements := make([]ast.Expression, n)
+		for i := range elements {
+			elements[i] = *lit(float64(i))
+		}
+		return &ast.Expression{Type: ast.ExprArrayLit, Elements: elements}
+	}
+	index := func(object, idx *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: idx}
+	}
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+
+	tests := []struct {
+		name       string
+		body       []ast.Statement
+		wantChecks int
+	}{
+		{
+			name:       "constant index in range",
+			body:       []ast.Statement{assign("xs", array(3)), ret(index(variable("xs"), lit(2)))},
+			wantChecks: 0,
+		},
+		{
+			name:       "array literal",
+			body:       []ast.Statement{ret(index(array(2), lit(1)))},
+			wantChecks: 0,
+		},
+		{
+			name: "element assignment",
+			body: []ast.Statement{
+				assign("xs", array(3)),
+				{Type: ast.StmtAssign, Lvalue: index(variable("xs"), lit(0)), Value: lit(7)},
+				ret(index(variable("xs"), lit(0))),
+			},
+			wantChecks: 0,
+		},
+		{
+			name:       "constant index out of range",
+			body:       []ast.Statement{assign("xs", array(3)), ret(index(variable("xs"), lit(3)))},
+			wantChecks: 1,
+		},
+		{
+			name:       "dynamic index",
+			body:       []ast.Statement{assign("xs", array(3)), ret(index(variable("xs"), variable("n")))},
+			wantChecks: 1,
+		},
+		{
+			name: "reassigned variable",
+			body: []ast.Statement{
+				assign("xs", array(3)),
+				{Type: ast.StmtIf, Cond: &ast.Expression{Type: ast.ExprLiteral, Value: true}, Then: []ast.Statement{assign("xs", array(1))}},
+				ret(index(variable("xs"), lit(2))),
+			},
+			wantChecks: 1,
+		},
+		{
+			name:       "parameter",
+			body:       []ast.Statement{ret(index(variable("arr"), lit(0)))},
+			wantChecks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := singleFunctionModule(ast.TypeInt, []ast.Parameter{{Name: "arr", Type: ast.TypeArray}, {Name: "n", Type: ast.TypeInt}}, tt.body)
+			ir := generateIR(t, module)
+			if got := strings.Count(ir, "call void @alas_runtime_check_bounds("); got != tt.wantChecks {
+				t.Errorf("got %d bounds checks, want %d:\n%s", got, tt.wantChecks, ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_DisableSafetyChecks(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	module := singleFunctionModule(ast.TypeInt, []ast.Parameter{{Name: "arr", Type: ast.TypeArray}, {Name: "n", Type: ast.TypeInt}}, []ast.Statement{{
+		Type: ast.StmtReturn,
+		Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpDiv,
+			Left:  &ast.Expression{Type: ast.ExprIndex, Object: variable("arr"), Index: variable("n")},
+			Right: variable("n")},
+	}})
+	checks := []string{"call void @alas_runtime_check_bounds(", "call void @alas_runtime_check_div_zero("}
+
+	ir := generateIR(t, module)
+	for _, check := range checks {
+		if !strings.Contains(ir, check) {
+			t.Errorf("expected IR to contain %q by default, got:\n%s", check, ir)
+		}
+	}
+
+	g := NewLLVMCodegen()
+	g.DisableSafetyChecks()
+	llvmModule, err := g.GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule() error = %v", err)
+	}
+	ir = llvmModule.String()
+	for _, check := range checks {
+		if strings.Contains(ir, check) {
+			t.Errorf("expected IR without %q after DisableSafetyChecks, got:\n%s", check, ir)
+		}
+	}
+}
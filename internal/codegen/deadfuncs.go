@@ -0,0 +1,81 @@
+package codegen
+
+import (
+	"github.com/dshills/alas/internal/ast"
+)
+
+// EnableDeadFunctionElimination makes GenerateModule drop functions that are
+// unreachable from main and the module's exports before generating code.
+// It must be called before GenerateModule.
+func (g *LLVMCodegen) EnableDeadFunctionElimination() {
+	g.pruneFunctions = true
+}
+
+// pruneUnreachableFunctions returns a copy of module without the functions
+// that cannot be reached from main, exported functions, functions with meta
+// export true, or methods. Methods are dispatched by receiver type at
+// runtime, so they are all kept. The original module is not modified.
+func pruneUnreachableFunctions(module *ast.Module) *ast.Module {
+	reachable := reachableFunctions(module)
+	pruned := *module
+	pruned.Functions = make([]ast.Function, 0, len(reachable))
+	for _, fn := range module.Functions {
+		if fn.Receiver != nil || reachable[fn.Name] {
+			pruned.Functions = append(pruned.Functions, fn)
+		}
+	}
+	return &pruned
+}
+
+// reachableFunctions returns the names of the module's non-method functions
+// reachable through calls, function references, and calls qualified with
+// the module's own name.
+func reachableFunctions(module *ast.Module) map[string]bool {
+	local := make(map[string]*ast.Function)
+	for i := range module.Functions {
+		if fn := &module.Functions[i]; fn.Receiver == nil {
+			local[fn.Name] = fn
+		}
+	}
+
+	reachable := make(map[string]bool)
+	var worklist []*ast.Function
+	mark := func(name string) {
+		if fn, ok := local[name]; ok && !reachable[name] {
+			reachable[name] = true
+			worklist = append(worklist, fn)
+		}
+	}
+
+	mark("main")
+	for _, name := range module.Exports {
+		mark(name)
+	}
+	for name, fn := range local {
+		if exported, _ := fn.MetaBool(ast.MetaExport); exported {
+			mark(name)
+		}
+	}
+	for i := range module.Functions {
+		if fn := &module.Functions[i]; fn.Receiver != nil {
+			worklist = append(worklist, fn)
+		}
+	}
+
+	visit := func(expr *ast.Expression) {
+		switch expr.Type {
+		case ast.ExprCall:
+			mark(expr.Name)
+		case ast.ExprFuncRef, ast.ExprModuleCall:
+			if expr.Module == "" || expr.Module == module.Name {
+				mark(expr.Name)
+			}
+		}
+	}
+	for len(worklist) > 0 {
+		fn := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		ast.WalkStatements(fn.Body, nil, visit)
+	}
+	return reachable
+}
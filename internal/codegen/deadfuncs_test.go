@@ -0,0 +1,106 @@
+package codegen
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+const deadFunctionsSource = `{
+  "type": "module",
+  "name": "app",
+  "exports": ["exported"],
+  "types": [{"name": "Point", "definition": {"kind": "struct", "fields": [{"name": "x", "type": "int"}]}}],
+  "functions": [
+    {"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+      {"type": "assign", "target": "f", "value": {"type": "lambda", "params": [], "returns": "int",
+        "body": [{"type": "return", "value": {"type": "call", "name": "fromLambda", "args": []}}]}},
+      {"type": "assign", "target": "g", "value": {"type": "func_ref", "name": "referenced"}},
+      {"type": "if", "cond": {"type": "literal", "value": true},
+        "then": [{"type": "expr", "value": {"type": "module_call", "module": "app", "name": "selfQualified", "args": []}}]},
+      {"type": "return", "value": {"type": "call", "name": "direct", "args": []}}
+    ]},
+    {"type": "function", "name": "direct", "params": [], "returns": "int", "body": [
+      {"type": "return", "value": {"type": "call", "name": "transitive", "args": []}}]},
+    {"type": "function", "name": "transitive", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 1}}]},
+    {"type": "function", "name": "fromLambda", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 2}}]},
+    {"type": "function", "name": "referenced", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 3}}]},
+    {"type": "function", "name": "selfQualified", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 4}}]},
+    {"type": "function", "name": "exported", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 5}}]},
+    {"type": "function", "name": "plugin", "params": [], "returns": "int", "meta": {"export": true}, "body": [{"type": "return", "value": {"type": "literal", "value": 8}}]},
+    {"type": "function", "name": "norm", "receiver": {"name": "p", "type": "Point"}, "params": [], "returns": "int", "body": [
+      {"type": "return", "value": {"type": "call", "name": "fromMethod", "args": []}}]},
+    {"type": "function", "name": "fromMethod", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 6}}]},
+    {"type": "function", "name": "unused", "params": [], "returns": "int", "body": [
+      {"type": "return", "value": {"type": "call", "name": "onlyFromUnused", "args": []}}]},
+    {"type": "function", "name": "onlyFromUnused", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 7}}]}
+  ]
+}`
+
+func TestPruneUnreachableFunctions(t *testing.T) {
+	module, err := ast.ParseModule([]byte(deadFunctionsSource), "app.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	pruned := pruneUnreachableFunctions(module)
+	var kept []string
+	for _, fn := range pruned.Functions {
+		kept = append(kept, fn.Name)
+	}
+	sort.Strings(kept)
+	want := []string{"direct", "exported", "fromLambda", "fromMethod", "main", "norm", "plugin", "referenced", "selfQualified", "transitive"}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept functions = %v, want %v", kept, want)
+	}
+	if len(module.Functions) != 12 {
+		t.Errorf("expected original module to keep its 12 functions, got %d", len(module.Functions))
+	}
+}
+
+func TestLLVMCodegen_DeadFunctionElimination(t *testing.T) {
+	// Lambdas are interpreter-only, so compile just the plainly called functions
+	source := `{
+  "type": "module",
+  "name": "app",
+  "exports": ["exported"],
+  "functions": [
+    {"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+      {"type": "return", "value": {"type": "call", "name": "transitive", "args": []}}]},
+    {"type": "function", "name": "transitive", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 1}}]},
+    {"type": "function", "name": "exported", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 5}}]},
+    {"type": "function", "name": "unused", "params": [], "returns": "int", "body": [
+      {"type": "return", "value": {"type": "call", "name": "onlyFromUnused", "args": []}}]},
+    {"type": "function", "name": "onlyFromUnused", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 7}}]}
+  ]
+}`
+	module, err := ast.ParseModule([]byte(source), "app.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	if ir := generateIR(t, module); !strings.Contains(ir, "@unused(") {
+		t.Errorf("expected unreachable functions to be kept by default")
+	}
+
+	g := NewLLVMCodegen()
+	g.EnableDeadFunctionElimination()
+	llvmModule, err := g.GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	ir := llvmModule.String()
+	for _, name := range []string{"@unused(", "@onlyFromUnused("} {
+		if strings.Contains(ir, name) {
+			t.Errorf("expected %s to be eliminated\nIR:\n%s", name, ir)
+		}
+	}
+	for _, name := range []string{"@main(", "@transitive(", "@exported("} {
+		if !strings.Contains(ir, name) {
+			t.Errorf("expected %s to be kept\nIR:\n%s", name, ir)
+		}
+	}
+}
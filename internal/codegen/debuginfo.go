@@ -0,0 +1,243 @@
+package codegen
+
+import (
+	"path/filepath"
+	"reflect"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/metadata"
+	"github.com/llir/llvm/ir/types"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// debugInfo holds the DWARF metadata of a module being generated with debug info.
+type debugInfo struct {
+	unit        *metadata.DICompileUnit
+	files       map[string]*metadata.DIFile
+	subroutine  *metadata.DISubroutineType
+	subprograms map[*ir.Func]*metadata.DISubprogram
+	locations   map[debugLocationKey]*metadata.DILocation
+	marks       map[*ir.Block]int // number of instructions already given a location
+}
+
+// debugLocationKey identifies a DILocation so identical locations are shared.
+type debugLocationKey struct {
+	scope *metadata.DISubprogram
+	line  int
+}
+
+// EnableDebugInfo makes GenerateModule emit DWARF debug information: a compile
+// unit, a subprogram for each function, and source line locations on
+// instructions. It must be called before GenerateModule.
+func (g *LLVMCodegen) EnableDebugInfo() {
+	g.debug = &debugInfo{
+		files:       make(map[string]*metadata.DIFile),
+		subprograms: make(map[*ir.Func]*metadata.DISubprogram),
+		locations:   make(map[debugLocationKey]*metadata.DILocation),
+		marks:       make(map[*ir.Block]int),
+	}
+}
+
+// addMetadata registers an unnamed metadata definition with the module.
+func (g *LLVMCodegen) addMetadata(md metadata.Definition) {
+	md.SetID(-1)
+	g.module.MetadataDefs = append(g.module.MetadataDefs, md)
+}
+
+// initDebugInfo creates the compile unit and module flags for module.
+func (g *LLVMCodegen) initDebugInfo(module *ast.Module) {
+	d := g.debug
+	source := module.File
+	if source == "" {
+		source = module.Name + ".alas"
+	}
+
+	d.unit = &metadata.DICompileUnit{
+		Distinct:     true,
+		Language:     enum.DwarfLangC99,
+		File:         g.debugFile(source),
+		Producer:     "alas-compile",
+		EmissionKind: enum.EmissionKindFullDebug,
+	}
+	g.addMetadata(d.unit)
+
+	signature := &metadata.Tuple{Fields: []metadata.Field{&metadata.NullLit{}}}
+	g.addMetadata(signature)
+	d.subroutine = &metadata.DISubroutineType{Types: signature}
+	g.addMetadata(d.subroutine)
+
+	dwarfVersion := &metadata.Tuple{Fields: []metadata.Field{
+		metadataInt(7), &metadata.String{Value: "Dwarf Version"}, metadataInt(4),
+	}}
+	debugInfoVersion := &metadata.Tuple{Fields: []metadata.Field{
+		metadataInt(2), &metadata.String{Value: "Debug Info Version"}, metadataInt(3),
+	}}
+	g.addMetadata(dwarfVersion)
+	g.addMetadata(debugInfoVersion)
+
+	g.module.NamedMetadataDefs["llvm.dbg.cu"] = &metadata.NamedDef{
+		Name:  "llvm.dbg.cu",
+		Nodes: []metadata.Node{d.unit},
+	}
+	g.module.NamedMetadataDefs["llvm.module.flags"] = &metadata.NamedDef{
+		Name:  "llvm.module.flags",
+		Nodes: []metadata.Node{dwarfVersion, debugInfoVersion},
+	}
+}
+
+// metadataInt returns an i32 metadata operand.
+func metadataInt(n int64) metadata.Field {
+	return constant.NewInt(types.I32, n)
+}
+
+// debugFile returns the DIFile for a source path, creating it on first use.
+func (g *LLVMCodegen) debugFile(path string) *metadata.DIFile {
+	if file, ok := g.debug.files[path]; ok {
+		return file
+	}
+	dir := filepath.Dir(path)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	file := &metadata.DIFile{Filename: filepath.Base(path), Directory: dir}
+	g.addMetadata(file)
+	g.debug.files[path] = file
+	return file
+}
+
+// functionLocation returns the source location of a function, taken from its
+// first statement that has one.
+func functionLocation(fn *ast.Function) (string, int) {
+	for _, stmt := range fn.Body {
+		if stmt.Line > 0 {
+			return stmt.File, stmt.Line
+		}
+	}
+	return "", 0
+}
+
+// beginDebugFunction creates the subprogram for a function whose body is about
+// to be generated and attaches it to the function.
+func (g *LLVMCodegen) beginDebugFunction(fn *ast.Function, llvmFunc *ir.Func) {
+	if g.debug == nil {
+		return
+	}
+	file, line := functionLocation(fn)
+	diFile := g.debug.unit.File
+	if file != "" {
+		diFile = g.debugFile(file)
+	}
+
+	name := fn.Name
+	if fn.Receiver != nil {
+		name = methodSymbol(fn.Receiver.Type, fn.Name)
+	}
+	sp := &metadata.DISubprogram{
+		Distinct:     true,
+		Scope:        diFile,
+		Name:         name,
+		LinkageName:  llvmFunc.Name(),
+		File:         diFile,
+		Line:         int64(line),
+		Type:         g.debug.subroutine,
+		ScopeLine:    int64(line),
+		IsDefinition: true,
+		SPFlags:      enum.DISPFlagDefinition,
+		Unit:         g.debug.unit,
+	}
+	g.addMetadata(sp)
+	g.debug.subprograms[llvmFunc] = sp
+	llvmFunc.Metadata = append(llvmFunc.Metadata, &metadata.Attachment{Name: "dbg", Node: sp})
+}
+
+// attachDebugLocations gives every instruction emitted in the current function
+// since the last call the current source line as its !dbg location.
+func (g *LLVMCodegen) attachDebugLocations() {
+	if g.debug == nil || g.builder == nil || g.builder.Parent == nil {
+		return
+	}
+	fn := g.builder.Parent
+	sp, ok := g.debug.subprograms[fn]
+	if !ok {
+		return
+	}
+	var pending []interface{}
+	for _, block := range fn.Blocks {
+		for _, inst := range block.Insts[g.debug.marks[block]:] {
+			pending = append(pending, inst)
+		}
+		g.debug.marks[block] = len(block.Insts)
+		if block.Term != nil && !hasDebugLocation(block.Term) {
+			pending = append(pending, block.Term)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	loc := g.debugLocation(sp, g.currentLine)
+	for _, inst := range pending {
+		setDebugLocation(inst, loc)
+	}
+}
+
+// debugLocation returns the shared DILocation for a line in a subprogram.
+func (g *LLVMCodegen) debugLocation(sp *metadata.DISubprogram, line int) *metadata.DILocation {
+	key := debugLocationKey{scope: sp, line: line}
+	if loc, ok := g.debug.locations[key]; ok {
+		return loc
+	}
+	loc := &metadata.DILocation{Line: int64(line), Scope: sp}
+	g.addMetadata(loc)
+	g.debug.locations[key] = loc
+	return loc
+}
+
+// metadataField returns the settable attachment list of an instruction or
+// terminator. llir embeds the list by value with no setter, so it is
+// accessed through reflection.
+func metadataField(inst interface{}) (reflect.Value, bool) {
+	field := reflect.ValueOf(inst).Elem().FieldByName("Metadata")
+	return field, field.IsValid() && field.CanSet()
+}
+
+// hasDebugLocation reports whether inst already has a !dbg location.
+func hasDebugLocation(inst interface{}) bool {
+	field, ok := metadataField(inst)
+	if !ok {
+		return false
+	}
+	for _, md := range field.Interface().(ir.Metadata) {
+		if md.Name == "dbg" {
+			return true
+		}
+	}
+	return false
+}
+
+// debugLocationOf returns the !dbg location of an instruction or terminator,
+// or nil if it has none.
+func debugLocationOf(inst interface{}) *metadata.DILocation {
+	field, ok := metadataField(inst)
+	if !ok {
+		return nil
+	}
+	for _, md := range field.Interface().(ir.Metadata) {
+		if loc, ok := md.Node.(*metadata.DILocation); ok && md.Name == "dbg" {
+			return loc
+		}
+	}
+	return nil
+}
+
+// setDebugLocation attaches loc to an instruction or terminator unless it
+// already has a !dbg location.
+func setDebugLocation(inst interface{}, loc *metadata.DILocation) {
+	field, ok := metadataField(inst)
+	if !ok || hasDebugLocation(inst) {
+		return
+	}
+	field.Set(reflect.Append(field, reflect.ValueOf(&metadata.Attachment{Name: "dbg", Node: loc})))
+}
@@ -0,0 +1,84 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestLLVMCodegen_DebugInfo(t *testing.T) {
+	src := `{
+  "type": "module",
+  "name": "dbg",
+  "functions": [
+    {
+      "type": "function",
+      "name": "main",
+      "params": [],
+      "returns": "int",
+      "body": [
+        {"type": "assign", "target": "x", "value": {"type": "literal", "value": 2}},
+        {"type": "return", "value": {"type": "call", "name": "twice", "args": [{"type": "variable", "name": "x"}]}}
+      ]
+    },
+    {
+      "type": "function",
+      "name": "twice",
+      "params": [{"name": "n", "type": "int"}],
+      "returns": "int",
+      "body": [
+        {"type": "return", "value": {"type": "binary", "op": "*", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 2}}}
+      ]
+    }
+  ]
+}`
+	module, err := ast.ParseModule([]byte(src), "testdata/dbg.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	g := NewLLVMCodegen()
+	g.EnableDebugInfo()
+	llvmModule, err := g.GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	ir := llvmModule.String()
+
+	expected := []string{
+		"!llvm.dbg.cu = !{",
+		`!"Debug Info Version", i32 3}`,
+		`!DIFile(filename: "dbg.alas.json"`,
+		`distinct !DICompileUnit(language: DW_LANG_C99`,
+		`distinct !DISubprogram(name: "main", linkageName: "main"`,
+		`distinct !DISubprogram(name: "twice", linkageName: "twice"`,
+		"!DILocation(line: 11,",
+		"!DILocation(line: 12,",
+		"!DILocation(line: 21,",
+	}
+	for _, want := range expected {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+
+	// Every call in a function with debug info needs a location
+	for _, line := range strings.Split(ir, "\n") {
+		if strings.Contains(line, " call ") && !strings.Contains(line, "!dbg") {
+			t.Errorf("call without debug location: %s", line)
+		}
+	}
+	if !strings.Contains(ir, "define i64 @main() !dbg ") {
+		t.Errorf("expected main to have a subprogram attached\nIR:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_NoDebugInfoByDefault(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}, Line: 3},
+	})
+	if ir := generateIR(t, module); strings.Contains(ir, "!dbg") || strings.Contains(ir, "DICompileUnit") {
+		t.Errorf("expected no debug info without EnableDebugInfo\nIR:\n%s", ir)
+	}
+}
@@ -0,0 +1,193 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DependencyGraph describes the import structure reachable from a module.
+type DependencyGraph struct {
+	Root    string              // Module the graph was built from
+	Imports map[string][]string // Module name -> imports, in declaration order
+	Missing map[string]error    // Modules that could not be loaded
+	Order   []string            // Build order, dependencies first; nil if HasProblems
+	Cycles  [][]string          // Import cycles, each starting and ending at the same module
+}
+
+// DependencyGraph loads the imports of root recursively, using the registered
+// and default loaders, and returns the resulting graph. Unlike
+// ResolveDependencies it does not stop at the first problem: modules that
+// cannot be loaded and import cycles are recorded in the graph.
+func (m *MultiModuleCodegen) DependencyGraph(root string) *DependencyGraph {
+	graph := &DependencyGraph{
+		Root:    root,
+		Imports: make(map[string][]string),
+		Missing: make(map[string]error),
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var order []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		switch state[name] {
+		case visiting:
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					cycle := append(append([]string{}, stack[i:]...), name)
+					graph.Cycles = append(graph.Cycles, cycle)
+					break
+				}
+			}
+			return
+		case done:
+			return
+		}
+
+		module, exists := m.modules[name]
+		if !exists {
+			var err error
+			if module, err = m.LoadModule(name); err != nil {
+				graph.Missing[name] = err
+				state[name] = done
+				return
+			}
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		graph.Imports[name] = module.ImportedModules()
+		for _, dep := range graph.Imports[name] {
+			visit(dep)
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		order = append(order, name)
+	}
+	visit(root)
+
+	if !graph.HasProblems() {
+		graph.Order = order
+	}
+	return graph
+}
+
+// HasProblems reports whether any module is missing or part of a cycle.
+func (g *DependencyGraph) HasProblems() bool {
+	return len(g.Missing) > 0 || len(g.Cycles) > 0
+}
+
+// WriteTree writes the import tree rooted at g.Root, followed by the build
+// order and any cycles or missing modules. Modules already shown are not
+// expanded again.
+func (g *DependencyGraph) WriteTree(w io.Writer) {
+	fmt.Fprintln(w, g.Root+g.annotation(g.Root))
+	shown := map[string]bool{g.Root: true}
+	ancestors := map[string]bool{g.Root: true}
+
+	var walk func(name, prefix string)
+	walk = func(name, prefix string) {
+		imports := g.Imports[name]
+		for i, dep := range imports {
+			branch, indent := "├── ", "│   "
+			if i == len(imports)-1 {
+				branch, indent = "└── ", "    "
+			}
+			switch {
+			case ancestors[dep]:
+				fmt.Fprintf(w, "%s%s%s (cycle)\n", prefix, branch, dep)
+			case shown[dep]:
+				fmt.Fprintf(w, "%s%s%s (see above)\n", prefix, branch, dep)
+			default:
+				fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, dep, g.annotation(dep))
+				shown[dep] = true
+				ancestors[dep] = true
+				walk(dep, prefix+indent)
+				delete(ancestors, dep)
+			}
+		}
+	}
+	walk(g.Root, "")
+
+	if g.Order != nil {
+		fmt.Fprintf(w, "\nBuild order: %s\n", strings.Join(g.Order, ", "))
+	}
+	for _, cycle := range g.Cycles {
+		fmt.Fprintf(w, "\nCycle: %s\n", strings.Join(cycle, " -> "))
+	}
+	for _, name := range g.missingNames() {
+		fmt.Fprintf(w, "\nMissing module %s: %v\n", name, g.Missing[name])
+	}
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Edges that close a
+// cycle are drawn in red and missing modules are dashed.
+func (g *DependencyGraph) WriteDOT(w io.Writer) {
+	cycleEdges := make(map[[2]string]bool)
+	for _, cycle := range g.Cycles {
+		for i := 0; i+1 < len(cycle); i++ {
+			cycleEdges[[2]string{cycle[i], cycle[i+1]}] = true
+		}
+	}
+
+	fmt.Fprintln(w, "digraph dependencies {")
+	fmt.Fprintf(w, "\t%q [shape=box];\n", g.Root)
+	for _, name := range g.missingNames() {
+		fmt.Fprintf(w, "\t%q [style=dashed];\n", name)
+	}
+	for _, name := range g.reached() {
+		for _, dep := range g.Imports[name] {
+			if cycleEdges[[2]string{name, dep}] {
+				fmt.Fprintf(w, "\t%q -> %q [color=red];\n", name, dep)
+			} else {
+				fmt.Fprintf(w, "\t%q -> %q;\n", name, dep)
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// annotation returns the suffix printed after a module name in the tree.
+func (g *DependencyGraph) annotation(name string) string {
+	if _, missing := g.Missing[name]; missing {
+		return " (missing)"
+	}
+	return ""
+}
+
+// reached returns the names of the modules reached from the root, loaded or
+// missing, in the order they were first reached.
+func (g *DependencyGraph) reached() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(name string)
+	walk = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+		for _, dep := range g.Imports[name] {
+			walk(dep)
+		}
+	}
+	walk(g.Root)
+	return names
+}
+
+// missingNames returns the missing modules in the order they were reached.
+func (g *DependencyGraph) missingNames() []string {
+	var names []string
+	for _, name := range g.reached() {
+		if _, missing := g.Missing[name]; missing {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,227 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/metadata"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// Disassemble returns the textual IR of module with comments naming the ALaS
+// function each definition was generated from and the statement each run of
+// instructions belongs to. The module must have been generated from source
+// with debug info enabled; instructions without a !dbg location, such as
+// those created by the optimizer, belong to the statement before them.
+func Disassemble(module *ir.Module, source *ast.Module) string {
+	functions := sourceFunctions(source)
+	text := module.String()
+	for _, fn := range module.Funcs {
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		text = strings.Replace(text, fn.LLString(), annotateFunc(fn, functions), 1)
+	}
+	return text
+}
+
+// DisassembleDiff returns annotated listings, as produced by Disassemble, of
+// every function whose IR differs between before and after, typically the
+// same module generated twice and optimized the second time.
+func DisassembleDiff(before, after *ir.Module, source *ast.Module) (string, error) {
+	// Metadata is printed inline until it has been numbered
+	for _, module := range []*ir.Module{before, after} {
+		if err := module.AssignMetadataIDs(); err != nil {
+			return "", fmt.Errorf("failed to number metadata: %v", err)
+		}
+	}
+
+	functions := sourceFunctions(source)
+	optimized := make(map[string]*ir.Func)
+	for _, fn := range after.Funcs {
+		optimized[fn.Name()] = fn
+	}
+
+	var b strings.Builder
+	changed := 0
+	for _, fn := range before.Funcs {
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		opt := optimized[fn.Name()]
+		if opt != nil && opt.LLString() == fn.LLString() {
+			continue
+		}
+		if changed > 0 {
+			b.WriteString("\n")
+		}
+		changed++
+		fmt.Fprintf(&b, "; ===== @%s\n; --- before\n%s\n; --- after\n", fn.Name(), annotateFunc(fn, functions))
+		if opt == nil {
+			b.WriteString("; removed\n")
+		} else {
+			fmt.Fprintf(&b, "%s\n", annotateFunc(opt, functions))
+		}
+	}
+	if changed == 0 {
+		b.WriteString("; no functions changed\n")
+	}
+	return b.String(), nil
+}
+
+// sourceFunction is an ALaS function with its statements in source order.
+type sourceFunction struct {
+	fn         *ast.Function
+	statements []*ast.Statement
+}
+
+// sourceFunctions indexes the functions of a module by the name their debug
+// subprograms carry.
+func sourceFunctions(module *ast.Module) map[string]*sourceFunction {
+	functions := make(map[string]*sourceFunction)
+	for i := range module.Functions {
+		fn := &module.Functions[i]
+		name := fn.Name
+		if fn.Receiver != nil {
+			name = methodSymbol(fn.Receiver.Type, fn.Name)
+		}
+		sf := &sourceFunction{fn: fn}
+		sf.collect(fn.Body)
+		functions[name] = sf
+	}
+	return functions
+}
+
+// collect appends stmts and the statements nested in them in source order.
+func (sf *sourceFunction) collect(stmts []ast.Statement) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		sf.statements = append(sf.statements, stmt)
+		sf.collect(stmt.Then)
+		sf.collect(stmt.Else)
+		sf.collect(stmt.Init)
+		sf.collect(stmt.Body)
+		sf.collect(stmt.Update)
+		for j := range stmt.Cases {
+			sf.collect(stmt.Cases[j].Body)
+		}
+		sf.collect(stmt.Default)
+	}
+}
+
+// statementAt returns the innermost statement that starts at or before line,
+// or nil if there is none.
+func (sf *sourceFunction) statementAt(line int) *ast.Statement {
+	var found *ast.Statement
+	for _, stmt := range sf.statements {
+		if stmt.Line > 0 && stmt.Line <= line && (found == nil || stmt.Line >= found.Line) {
+			found = stmt
+		}
+	}
+	return found
+}
+
+// describeStatement returns a short description of a statement for comments.
+func describeStatement(stmt *ast.Statement) string {
+	switch stmt.Type {
+	case ast.StmtAssign:
+		if stmt.Lvalue != nil {
+			return "assign " + describeLvalue(stmt.Lvalue)
+		}
+		return "assign " + stmt.Target
+	case ast.StmtExpr:
+		if stmt.Value == nil {
+			break
+		}
+		switch stmt.Value.Type {
+		case ast.ExprCall, ast.ExprBuiltin:
+			return "call " + stmt.Value.Name
+		case ast.ExprModuleCall:
+			return "call " + stmt.Value.Module + "." + stmt.Value.Name
+		}
+	}
+	return stmt.Type
+}
+
+// describeLvalue returns an assignment target such as a[...].x, leaving out
+// index expressions.
+func describeLvalue(expr *ast.Expression) string {
+	switch {
+	case expr == nil:
+		return ""
+	case expr.Type == ast.ExprIndex:
+		return describeLvalue(expr.Object) + "[...]"
+	case expr.Type == ast.ExprField:
+		return describeLvalue(expr.Object) + "." + expr.Field
+	default:
+		return expr.Name
+	}
+}
+
+// annotateFunc returns the textual IR of a function definition with source
+// comments: the ALaS function before the definition, and the statement an
+// instruction was generated from at the start of each block and wherever it
+// changes.
+func annotateFunc(fn *ir.Func, functions map[string]*sourceFunction) string {
+	text := fn.LLString()
+	header := text[:strings.Index(text, "{\n")+2]
+
+	var b strings.Builder
+	sp := subprogramOf(fn)
+	var source *sourceFunction
+	if sp != nil {
+		source = functions[sp.Name]
+		fmt.Fprintf(&b, "; function %s", sp.Name)
+		if sp.Line > 0 {
+			fmt.Fprintf(&b, ", line %d", sp.Line)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(header)
+
+	var current string
+	annotate := func(inst interface{}) {
+		loc := debugLocationOf(inst)
+		if loc == nil {
+			return
+		}
+		comment := fmt.Sprintf("line %d", loc.Line)
+		if source != nil {
+			if stmt := source.statementAt(int(loc.Line)); stmt != nil {
+				comment = fmt.Sprintf("line %d: %s", stmt.Line, describeStatement(stmt))
+			}
+		}
+		if comment != current {
+			current = comment
+			fmt.Fprintf(&b, "\t; %s\n", comment)
+		}
+	}
+	for i, block := range fn.Blocks {
+		if i != 0 {
+			b.WriteString("\n")
+		}
+		label, _, _ := strings.Cut(block.LLString(), "\n")
+		fmt.Fprintf(&b, "%s\n", label)
+		current = ""
+		for _, inst := range block.Insts {
+			annotate(inst)
+			fmt.Fprintf(&b, "\t%s\n", inst.LLString())
+		}
+		annotate(block.Term)
+		fmt.Fprintf(&b, "\t%s\n", block.Term.LLString())
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// subprogramOf returns the debug subprogram attached to a function, or nil.
+func subprogramOf(fn *ir.Func) *metadata.DISubprogram {
+	for _, md := range fn.Metadata {
+		if sp, ok := md.Node.(*metadata.DISubprogram); ok && md.Name == "dbg" {
+			return sp
+		}
+	}
+	return nil
+}
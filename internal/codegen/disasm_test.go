@@ -0,0 +1,107 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/ir"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+const disasmSource = `{
+  "type": "module",
+  "name": "disasm",
+  "functions": [
+    {
+      "type": "function",
+      "name": "main",
+      "params": [],
+      "returns": "int",
+      "body": [
+        {"type": "assign", "target": "x", "value": {"type": "binary", "op": "+", "left": {"type": "literal", "value": 2}, "right": {"type": "literal", "value": 3}}},
+        {"type": "expr", "value": {"type": "call", "name": "id", "args": [{"type": "variable", "name": "x"}]}},
+        {"type": "return", "value": {"type": "variable", "name": "x"}}
+      ]
+    },
+    {
+      "type": "function",
+      "name": "id",
+      "params": [{"name": "n", "type": "int"}],
+      "returns": "int",
+      "body": [
+        {"type": "return", "value": {"type": "variable", "name": "n"}}
+      ]
+    }
+  ]
+}`
+
+// generateDisasmModule compiles disasmSource with debug info, optimized at level.
+func generateDisasmModule(t *testing.T, source *ast.Module, level OptimizationLevel) *ir.Module {
+	t.Helper()
+	g := NewLLVMCodegen()
+	g.EnableDebugInfo()
+	module, err := g.GenerateModule(source)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if level > OptNone {
+		if err := NewOptimizer(level).OptimizeModule(module); err != nil {
+			t.Fatalf("OptimizeModule failed: %v", err)
+		}
+	}
+	return module
+}
+
+func TestDisassemble(t *testing.T) {
+	source, err := ast.ParseModule([]byte(disasmSource), "disasm.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	listing := Disassemble(generateDisasmModule(t, source, OptNone), source)
+	expected := []string{
+		"; function main, line 11\ndefine i64 @main()",
+		"entry:\n\t%x_ptr = alloca i64\n\t; line 11: assign x\n",
+		"\t; line 12: call id\n",
+		"\t; line 13: return\n",
+		"; function id, line 22\ndefine i64 @id(i64 %n)",
+		"!DILocation(line: 11,",
+	}
+	for _, want := range expected {
+		if !strings.Contains(listing, want) {
+			t.Errorf("expected listing to contain %q, got:\n%s", want, listing)
+		}
+	}
+}
+
+func TestDisassembleDiff(t *testing.T) {
+	source, err := ast.ParseModule([]byte(disasmSource), "disasm.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	before := generateDisasmModule(t, source, OptNone)
+	listing, err := DisassembleDiff(before, generateDisasmModule(t, source, OptBasic), source)
+	if err != nil {
+		t.Fatalf("DisassembleDiff() error = %v", err)
+	}
+
+	// Constant folding changes main, leaving id as it was
+	for _, want := range []string{"; ===== @main\n; --- before\n", "add i64 2, 3", "; --- after\n", "store i64 5"} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("expected listing to contain %q, got:\n%s", want, listing)
+		}
+	}
+	if strings.Contains(listing, "@id(i64 %n)") {
+		t.Errorf("unchanged function id should not be listed, got:\n%s", listing)
+	}
+
+	listing, err = DisassembleDiff(before, generateDisasmModule(t, source, OptNone), source)
+	if err != nil {
+		t.Fatalf("DisassembleDiff() error = %v", err)
+	}
+	if listing != "; no functions changed\n" {
+		t.Errorf("DisassembleDiff() of identical modules = %q", listing)
+	}
+}
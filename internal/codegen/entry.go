@@ -0,0 +1,173 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+)
+
+// ErrToolchainNotFound is returned when an executable is requested but llc
+// or a C compiler to link it cannot be found.
+var ErrToolchainNotFound = errors.New("llc and cc are required to build executables: install LLVM and a C compiler, or use -format ll")
+
+// entryName is the name the ALaS main function is given once a C main
+// calling it has been added.
+const entryName = "alas_main"
+
+// AddEntryPoint renames the ALaS main function of a compiled module and adds
+// a C main that calls it. A main taking a single array receives the
+// command-line arguments after the program name as an array of strings. An
+// int result becomes the process exit code; other results are discarded and
+// the exit code is 0.
+func AddEntryPoint(module *ir.Module) error {
+	alasMain, err := findMain(module)
+	if err != nil {
+		return err
+	}
+
+	arrayType := types.NewStruct(types.I8Ptr, types.I64)
+	switch {
+	case len(alasMain.Params) == 0:
+	case len(alasMain.Params) == 1 && alasMain.Params[0].Type().Equal(arrayType):
+	default:
+		return fmt.Errorf("main must take no parameters or a single array of arguments")
+	}
+
+	driver := addDriver(module, alasMain)
+	argc, argv := driver.Params[0], driver.Params[1]
+	entry := driver.NewBlock("entry")
+
+	var result *ir.InstCall
+	if len(alasMain.Params) == 0 {
+		result = entry.NewCall(alasMain)
+	} else {
+		// Strings are i8* elements, so the arguments after argv[0] already
+		// have the layout of an array's data
+		first := entry.NewGetElementPtr(types.I8Ptr, argv, constant.NewInt(types.I64, 1))
+		data := entry.NewBitCast(first, types.I8Ptr)
+		length := entry.NewSub(entry.NewSExt(argc, types.I64), constant.NewInt(types.I64, 1))
+		args := entry.NewInsertValue(constant.NewUndef(arrayType), data, 0)
+		args = entry.NewInsertValue(args, length, 1)
+		result = entry.NewCall(alasMain, args)
+	}
+
+	if result.Type().Equal(types.I64) {
+		entry.NewRet(entry.NewTrunc(result, types.I32))
+	} else {
+		entry.NewRet(constant.NewInt(types.I32, 0))
+	}
+	return nil
+}
+
+// NewDriver renames the ALaS main function of a compiled module and adds an
+// empty C main(argc, argv) for the caller to fill in with calls to it. It
+// returns the renamed ALaS main and the C main.
+func NewDriver(module *ir.Module) (alasMain, driver *ir.Func, err error) {
+	alasMain, err = findMain(module)
+	if err != nil {
+		return nil, nil, err
+	}
+	return alasMain, addDriver(module, alasMain), nil
+}
+
+// findMain returns the ALaS main function of a compiled module.
+func findMain(module *ir.Module) (*ir.Func, error) {
+	for _, fn := range module.Funcs {
+		if fn.Name() == "main" {
+			return fn, nil
+		}
+	}
+	return nil, fmt.Errorf("compiled module has no main function")
+}
+
+// addDriver renames alasMain and adds an empty C main to module.
+func addDriver(module *ir.Module, alasMain *ir.Func) *ir.Func {
+	alasMain.SetName(entryName)
+	argc := ir.NewParam("argc", types.I32)
+	argv := ir.NewParam("argv", types.NewPointer(types.I8Ptr))
+	return module.NewFunc("main", types.I32, argc, argv)
+}
+
+// WriteExecutable adds an entry point to a module and builds it into a
+// native executable with llc and cc. When libDir is set, the executable is
+// linked against the libalas_stdlib.so in it, which provides the builtins.
+func WriteExecutable(module *ir.Module, output, libDir string) error {
+	llc, err := exec.LookPath("llc")
+	if err != nil {
+		return ErrToolchainNotFound
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		return ErrToolchainNotFound
+	}
+	if err := AddEntryPoint(module); err != nil {
+		return err
+	}
+	return link(module, output, libDir, llc, cc)
+}
+
+// LinkExecutable builds a module that already has a C main into a native
+// executable with llc and cc, passing llcArgs to llc. When libDir is set,
+// the executable is linked against the libalas_stdlib.so in it.
+func LinkExecutable(module *ir.Module, output, libDir string, llcArgs ...string) error {
+	llc, err := exec.LookPath("llc")
+	if err != nil {
+		return ErrToolchainNotFound
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		return ErrToolchainNotFound
+	}
+	return link(module, output, libDir, llc, cc, llcArgs...)
+}
+
+// link writes module to a temporary directory and builds it with the llc
+// and cc at the given paths.
+func link(module *ir.Module, output, libDir, llc, cc string, llcArgs ...string) error {
+	dir, err := os.MkdirTemp("", "alas-exe-*")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	irFile := filepath.Join(dir, "program.ll")
+	objFile := filepath.Join(dir, "program.o")
+	if err := os.WriteFile(irFile, []byte(module.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write temporary IR file: %w", err)
+	}
+	args := append(append([]string{}, llcArgs...), "-filetype=obj", "-relocation-model=pic", irFile, "-o", objFile)
+	if err := runTool(llc, args...); err != nil {
+		return err
+	}
+	linkArgs := []string{objFile, "-o", output}
+	if libDir != "" {
+		abs, err := filepath.Abs(libDir)
+		if err != nil {
+			return fmt.Errorf("invalid library directory %s: %w", libDir, err)
+		}
+		linkArgs = append(linkArgs, "-L"+abs, "-Wl,-rpath,"+abs, "-lalas_stdlib")
+	}
+	return runTool(cc, linkArgs...)
+}
+
+// runTool runs an external tool, returning its diagnostics if it fails.
+func runTool(path string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s failed: %s", filepath.Base(path), msg)
+		}
+		return fmt.Errorf("%s failed: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package codegen
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestWriteExecutableFreesHeapArrays(t *testing.T) {
+	if _, err := exec.LookPath("llc"); err != nil {
+		t.Skip("llc not available")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not available")
+	}
+	libDir := filepath.Join("..", "..", "lib")
+	if _, err := os.Stat(filepath.Join(libDir, "libalas_stdlib.so")); err != nil {
+		t.Skip("libalas_stdlib.so not built")
+	}
+	// With a threshold of 2, both array literals go on the heap: one is
+	// built on every pass of the loop and one on every call of third
+	module, err := ast.ParseModule([]byte(`{"type": "module", "name": "heap", "functions": [
+		{"type": "function", "name": "third", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+			{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [
+				{"type": "variable", "name": "n"}, {"type": "variable", "name": "n"}, {"type": "literal", "value": 1}]}},
+			{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 2}}}]},
+		{"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+			{"type": "assign", "target": "total", "value": {"type": "literal", "value": 0}},
+			{"type": "assign", "target": "i", "value": {"type": "literal", "value": 0}},
+			{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 1000000}}, "body": [
+				{"type": "assign", "target": "b", "value": {"type": "array_literal", "elements": [
+					{"type": "literal", "value": 1}, {"type": "variable", "name": "i"}, {"type": "variable", "name": "i"}]}},
+				{"type": "assign", "target": "total", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "total"},
+					"right": {"type": "binary", "op": "+",
+						"left": {"type": "index", "object": {"type": "variable", "name": "b"}, "index": {"type": "literal", "value": 0}},
+						"right": {"type": "call", "name": "third", "args": [{"type": "variable", "name": "i"}]}}}},
+				{"type": "assign", "target": "i", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 1}}}]},
+			{"type": "if", "cond": {"type": "binary", "op": "==", "left": {"type": "variable", "name": "total"}, "right": {"type": "literal", "value": 2000000}},
+				"then": [{"type": "return", "value": {"type": "literal", "value": 20}}]},
+			{"type": "return", "value": {"type": "literal", "value": 1}}]}]}`), "heap.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	g := NewLLVMCodegen()
+	g.SetHeapArrayThreshold(2)
+	compiled, err := g.GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	executable := filepath.Join(t.TempDir(), "program")
+	if err := WriteExecutable(compiled, executable, libDir); err != nil {
+		t.Fatalf("WriteExecutable() error = %v", err)
+	}
+
+	cmd := exec.Command(executable)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 20 {
+		t.Fatalf("running: error = %v, want exit code 20", err)
+	}
+	// A million leaked blocks would take more than 16 MiB on their own
+	if rusage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		if maxRSS := rusage.Maxrss / 1024; maxRSS > 16 {
+			t.Errorf("peak memory = %d MiB, want at most 16 MiB", maxRSS)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestAddEntryPoint(t *testing.T) {
+	returnLit := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 7.0}}}
+	argsParam := []ast.Parameter{{Name: "args", Type: ast.TypeArray}}
+
+	tests := []struct {
+		name    string
+		module  *ast.Module
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "no parameters",
+			module: singleFunctionModule(ast.TypeInt, []ast.Parameter{}, returnLit),
+			want: []string{
+				"define i32 @main(i32 %argc, i8** %argv)",
+				"call i64 @alas_main()",
+				"trunc i64",
+			},
+		},
+		{
+			name:   "argument array",
+			module: singleFunctionModule(ast.TypeInt, argsParam, returnLit),
+			want: []string{
+				"getelementptr i8*, i8** %argv, i64 1",
+				"sub i64",
+				"call i64 @alas_main({ i8*, i64 }",
+			},
+		},
+		{
+			name:   "void result exits with zero",
+			module: singleFunctionModule(ast.TypeVoid, []ast.Parameter{}, []ast.Statement{{Type: ast.StmtReturn}}),
+			want:   []string{"call void @alas_main()", "ret i32 0"},
+		},
+		{
+			name:    "unsupported parameters",
+			module:  singleFunctionModule(ast.TypeInt, []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, returnLit),
+			wantErr: "main must take no parameters or a single array of arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := NewLLVMCodegen().GenerateModule(tt.module)
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			err = AddEntryPoint(module)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AddEntryPoint() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddEntryPoint() error = %v", err)
+			}
+			ir := module.String()
+			for _, expected := range tt.want {
+				if !strings.Contains(ir, expected) {
+					t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteExecutable(t *testing.T) {
+	if _, err := exec.LookPath("llc"); err != nil {
+		t.Skip("llc not available")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not available")
+	}
+	// main returns the number of arguments it was given
+	module, err := NewLLVMCodegen().GenerateModule(singleFunctionModule(ast.TypeInt,
+		[]ast.Parameter{{Name: "args", Type: ast.TypeArray}},
+		[]ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+			Type: ast.ExprBuiltin, Name: "collections.length", Args: []ast.Expression{{Type: ast.ExprVariable, Name: "args"}},
+		}}},
+	))
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	libDir := filepath.Join("..", "..", "lib")
+	if _, err := os.Stat(filepath.Join(libDir, "libalas_stdlib.so")); err != nil {
+		t.Skip("libalas_stdlib.so not built")
+	}
+
+	executable := filepath.Join(t.TempDir(), "program")
+	if err := WriteExecutable(module, executable, libDir); err != nil {
+		t.Fatalf("WriteExecutable() error = %v", err)
+	}
+	err = exec.Command(executable, "a", "b", "c").Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("running with 3 arguments: error = %v, want exit code 3", err)
+	}
+	if err := exec.Command(executable).Run(); err != nil {
+		t.Errorf("running without arguments: error = %v, want exit code 0", err)
+	}
+}
+
+func TestWriteExecutableExit(t *testing.T) {
+	if _, err := exec.LookPath("llc"); err != nil {
+		t.Skip("llc not available")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not available")
+	}
+	// os.exit ends the program before main returns 1
+	module, err := NewLLVMCodegen().GenerateModule(singleFunctionModule(ast.TypeInt, []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtExpr, Value: &ast.Expression{
+			Type: ast.ExprBuiltin, Name: "os.exit", Args: []ast.Expression{{Type: ast.ExprLiteral, Value: 4.0}},
+		}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}},
+	}))
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+
+	// exit comes from libc, so the standard library is not needed
+	executable := filepath.Join(t.TempDir(), "program")
+	if err := WriteExecutable(module, executable, ""); err != nil {
+		t.Fatalf("WriteExecutable() error = %v", err)
+	}
+	err = exec.Command(executable).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("running: error = %v, want exit code 4", err)
+	}
+}
+
+func TestWriteExecutableExtern(t *testing.T) {
+	if _, err := exec.LookPath("llc"); err != nil {
+		t.Skip("llc not available")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not available")
+	}
+	call := func(name string, arg interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCall, Name: name, Args: []ast.Expression{{Type: ast.ExprLiteral, Value: arg}}}
+	}
+	// main returns labs(-5) + strlen("hello"), both from libc
+	module := &ast.Module{
+		Type: "module",
+		Name: "ffi",
+		Functions: []ast.Function{
+			{Type: "function", Name: "abs", Extern: "labs", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt},
+			{Type: "function", Name: "length", Extern: "strlen", Params: []ast.Parameter{{Name: "s", Type: ast.TypeString}}, Returns: ast.TypeInt},
+			{Type: "function", Name: "main", Returns: ast.TypeInt, Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBinary, Op: ast.OpAdd, Left: call("abs", -5.0), Right: call("length", "hello"),
+			}}}},
+		},
+	}
+	compiled, err := NewLLVMCodegen().GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+
+	executable := filepath.Join(t.TempDir(), "program")
+	if err := WriteExecutable(compiled, executable, ""); err != nil {
+		t.Fatalf("WriteExecutable() error = %v", err)
+	}
+	err = exec.Command(executable).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 10 {
+		t.Errorf("running: error = %v, want exit code 10", err)
+	}
+}
+
+func TestWriteExecutableCasts(t *testing.T) {
+	if _, err := exec.LookPath("llc"); err != nil {
+		t.Skip("llc not available")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc not available")
+	}
+	libDir := filepath.Join("..", "..", "lib")
+	if _, err := os.Stat(filepath.Join(libDir, "libalas_stdlib.so")); err != nil {
+		t.Skip("libalas_stdlib.so not built")
+	}
+	cast := func(to string, operand *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCast, To: to, Operand: operand}
+	}
+	lit := func(value interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: value} }
+
+	tests := []struct {
+		name     string
+		result   *ast.Expression // main's result, which becomes the exit code
+		exitCode int
+		stderr   string
+	}{
+		{name: "string to int", result: cast(ast.TypeInt, lit(" 42 ")), exitCode: 42},
+		{name: "int through string", result: cast(ast.TypeInt, cast(ast.TypeString, lit(7.0))), exitCode: 7},
+		{name: "float through string", result: cast(ast.TypeInt, cast(ast.TypeFloat, cast(ast.TypeString, lit(2.5)))), exitCode: 2},
+		{name: "bool through string", result: cast(ast.TypeInt, cast(ast.TypeBool, cast(ast.TypeString, cast(ast.TypeBool, lit("true"))))), exitCode: 1},
+		{name: "invalid int", result: cast(ast.TypeInt, lit("abc")), exitCode: 1, stderr: `runtime error: cannot cast string "abc" to int`},
+		{name: "float to int", result: cast(ast.TypeInt, cast(ast.TypeFloat, lit("9.9"))), exitCode: 9},
+		{name: "float out of range", result: cast(ast.TypeInt, cast(ast.TypeFloat, lit("1e19"))), exitCode: 1, stderr: "runtime error: cannot cast float to int"},
+		{name: "negative infinity", result: cast(ast.TypeInt, cast(ast.TypeFloat, lit("-Inf"))), exitCode: 1, stderr: "runtime error: cannot cast float to int"},
+		{name: "nan", result: cast(ast.TypeInt, cast(ast.TypeFloat, lit("NaN"))), exitCode: 1, stderr: "runtime error: cannot cast float to int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := NewLLVMCodegen().GenerateModule(singleFunctionModule(ast.TypeInt, []ast.Parameter{},
+				[]ast.Statement{{Type: ast.StmtReturn, Value: tt.result}}))
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			executable := filepath.Join(t.TempDir(), "program")
+			if err := WriteExecutable(module, executable, libDir); err != nil {
+				t.Fatalf("WriteExecutable() error = %v", err)
+			}
+
+			var stderr bytes.Buffer
+			cmd := exec.Command(executable)
+			cmd.Stderr = &stderr
+			err = cmd.Run()
+			exitCode := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("running: error = %v", err)
+			}
+			if exitCode != tt.exitCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", exitCode, tt.exitCode, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
+
+func TestWriteExecutableWithoutToolchain(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := WriteExecutable(nil, filepath.Join(t.TempDir(), "program"), "")
+	if !errors.Is(err, ErrToolchainNotFound) {
+		t.Errorf("WriteExecutable() error = %v, want ErrToolchainNotFound", err)
+	}
+}
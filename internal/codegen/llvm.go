@@ -9,15 +9,21 @@ import (
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 
-	"encoding/json"
 	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/builtins"
+	"github.com/dshills/alas/internal/consteval"
+	"github.com/dshills/alas/internal/modpath"
+	"github.com/dshills/alas/internal/runtime"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
 	// DynamicMapType represents a dynamically-typed map variable
 	DynamicMapType = "_dynamic_map"
+	// BoxedValueType represents a variable holding a CValue pointer, such as a map lookup result
+	BoxedValueType = "_boxed_value"
 )
 
 // LLVMCodegen generates LLVM IR from ALaS AST.
@@ -34,10 +40,28 @@ type LLVMCodegen struct {
 	structTypes       map[string]types.Type          // LLVM types for custom types
 	fieldIndices      map[string]map[string]int      // type name -> field name -> index
 	variableTypes     map[string]string              // variable name -> ALaS type name
+	arrayLengths      map[string]int64               // variable name -> length of the only array literal assigned to it
+	enumTags          map[string]map[string]int            // enum name -> variant name -> tag
+	variantFields     map[string]map[string]map[string]int // enum name -> variant -> field -> struct index
 	currentFunction   *ast.Function                  // Current function being generated
 	astFunctions      map[string]*ast.Function       // AST function definitions
 	loadedModules     map[string]*ast.Module         // Cache of loaded modules
+	importAliases     map[string]string              // local import name -> module name
 	compiledModules   map[string]*ir.Module          // Cache of compiled modules
+	currentFile       string                         // Source file of the node being generated
+	currentLine       int                            // Source line of the node being generated
+	debug             *debugInfo                     // DWARF metadata, nil unless debug info is enabled
+	pruneFunctions    bool                           // drop unreachable functions before generating code
+	checkOverflow     bool                           // trap on int overflow instead of wrapping
+	omitSafetyChecks  bool                           // leave out division by zero, bounds and null checks
+	destructureCount  int                            // numbers the hidden variables holding destructured values
+	stringGlobals     map[string]*ir.Global          // string contents -> global constant holding them
+	scopedMaps        map[*ast.Expression]value.Value // non-escaping map literal -> its handle
+	scopedMapOrder    []value.Value                  // scoped map handles of the current function
+	heapArrayThreshold int                           // array literals with more elements go on the heap
+	heapArrays        map[*ast.Expression]value.Value // heap array literal -> its handle
+	heapArrayOrder    []value.Value                  // heap array handles of the current function
+	entryBlock        *ir.Block                      // entry block of the current function
 }
 
 // ModuleResolver interface for loading modules.
@@ -63,11 +87,11 @@ func (l *FileModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
 	for _, searchPath := range l.searchPaths {
 		fileName := filepath.Join(searchPath, name+".alas.json")
 		if data, err := os.ReadFile(fileName); err == nil {
-			var module ast.Module
-			if err := json.Unmarshal(data, &module); err != nil {
+			module, err := ast.ParseModule(data, fileName)
+			if err != nil {
 				return nil, fmt.Errorf("failed to parse module %s: %v", name, err)
 			}
-			return &module, nil
+			return module, nil
 		}
 	}
 	
@@ -77,11 +101,11 @@ func (l *FileModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
 		for _, searchPath := range l.searchPaths {
 			fileName := filepath.Join(searchPath, simpleName+".alas.json")
 			if data, err := os.ReadFile(fileName); err == nil {
-				var module ast.Module
-				if err := json.Unmarshal(data, &module); err != nil {
+				module, err := ast.ParseModule(data, fileName)
+				if err != nil {
 					return nil, fmt.Errorf("failed to parse module %s: %v", name, err)
 				}
-				return &module, nil
+				return module, nil
 			}
 		}
 	}
@@ -92,8 +116,7 @@ func (l *FileModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
 // NewLLVMCodegen creates a new LLVM code generator.
 func NewLLVMCodegen() *LLVMCodegen {
 	// Create with default module loader
-	searchPaths := []string{".", "examples/modules", "../examples/modules", "stdlib"}
-	return NewLLVMCodegenWithLoader(NewFileModuleLoader(searchPaths))
+	return NewLLVMCodegenWithLoader(NewFileModuleLoader(modpath.SearchPaths()))
 }
 
 // NewLLVMCodegenWithLoader creates a new LLVM code generator with a custom module loader.
@@ -110,10 +133,16 @@ func NewLLVMCodegenWithLoader(loader ModuleResolver) *LLVMCodegen {
 		structTypes:       make(map[string]types.Type),
 		fieldIndices:      make(map[string]map[string]int),
 		variableTypes:     make(map[string]string),
+		arrayLengths:      make(map[string]int64),
+		enumTags:          make(map[string]map[string]int),
+		variantFields:     make(map[string]map[string]map[string]int),
 		currentFunction:   nil,
 		astFunctions:      make(map[string]*ast.Function),
 		loadedModules:     make(map[string]*ast.Module),
+		importAliases:     make(map[string]string),
 		compiledModules:   make(map[string]*ir.Module),
+		stringGlobals:     make(map[string]*ir.Global),
+		heapArrayThreshold: DefaultHeapArrayThreshold,
 	}
 	g.declareGCFunctions()
 	g.declareErrorHandlingFunctions()
@@ -121,6 +150,13 @@ func NewLLVMCodegenWithLoader(loader ModuleResolver) *LLVMCodegen {
 	return g
 }
 
+// SetModulePaths makes imported modules be looked for in paths, in order,
+// before the directories in ALAS_PATH and the default search paths. It
+// replaces the module loader, so it must be called before generating code.
+func (g *LLVMCodegen) SetModulePaths(paths []string) {
+	g.moduleLoader = NewFileModuleLoader(modpath.SearchPaths(paths...))
+}
+
 // declareCustomType declares a custom type in LLVM IR.
 func (g *LLVMCodegen) declareCustomType(typeDef *ast.TypeDefinition) error {
 	// Skip if type definition is incomplete or uses unsupported format
@@ -151,9 +187,34 @@ func (g *LLVMCodegen) declareCustomType(typeDef *ast.TypeDefinition) error {
 		g.fieldIndices[typeDef.Name] = fieldIndexMap
 
 	case ast.TypeKindEnum:
-		// Enums are represented as i32 (could also use string pointers)
-		// For now, we'll use i32 for enum values
-		g.structTypes[typeDef.Name] = types.I32
+		// Plain enums are represented as an i32 tag. Enums whose variants
+		// carry data become {i32 tag, <payload fields of every variant>}.
+		tags := make(map[string]int)
+		variantFields := make(map[string]map[string]int)
+		fieldTypes := []types.Type{types.I32}
+		// Tags are the variants' indexes, as given by VariantIndex
+		for tag, variant := range typeDef.Definition.EnumVariants() {
+			tags[variant.Name] = tag
+			variantFields[variant.Name] = make(map[string]int)
+			for _, field := range variant.Fields {
+				fieldType, err := g.convertType(field.Type)
+				if err != nil {
+					return fmt.Errorf("invalid field type %s: %v", field.Type, err)
+				}
+				variantFields[variant.Name][field.Name] = len(fieldTypes)
+				fieldTypes = append(fieldTypes, fieldType)
+			}
+		}
+		g.enumTags[typeDef.Name] = tags
+		g.variantFields[typeDef.Name] = variantFields
+		if typeDef.Definition.HasPayload() {
+			g.structTypes[typeDef.Name] = types.NewStruct(fieldTypes...)
+		} else {
+			g.structTypes[typeDef.Name] = types.I32
+		}
+
+	case ast.TypeKindAlias:
+		// Aliases declare nothing; resolveType maps them to the type they stand for
 
 	default:
 		return fmt.Errorf("unknown type kind: %s", typeDef.Definition.Kind)
@@ -162,9 +223,30 @@ func (g *LLVMCodegen) declareCustomType(typeDef *ast.TypeDefinition) error {
 	return nil
 }
 
+// lookupType returns the custom type definition of a name, or nil.
+func (g *LLVMCodegen) lookupType(name string) *ast.TypeDefinition {
+	return g.customTypes[name]
+}
+
+// resolveType returns the type an alias stands for, or alasType itself if it
+// is not an alias. Alias cycles are reported by convertType.
+func (g *LLVMCodegen) resolveType(alasType string) string {
+	resolved, err := ast.ResolveTypeAlias(alasType, g.lookupType)
+	if err != nil {
+		return alasType
+	}
+	return resolved
+}
+
 // GenerateModule generates LLVM IR for an entire ALaS module.
 func (g *LLVMCodegen) GenerateModule(module *ast.Module) (*ir.Module, error) {
+	if g.pruneFunctions {
+		module = pruneUnreachableFunctions(module)
+	}
 	g.module.SourceFilename = module.Name + ".alas"
+	if g.debug != nil {
+		g.initDebugInfo(module)
+	}
 
 	// Process custom types first
 	for idx := range module.Types {
@@ -177,7 +259,7 @@ func (g *LLVMCodegen) GenerateModule(module *ast.Module) (*ir.Module, error) {
 
 	// Resolve module dependencies recursively
 	visited := make(map[string]bool)
-	for _, importName := range module.Imports {
+	for _, importName := range module.ImportedModules() {
 		if err := g.resolveModuleDependencies(importName, visited); err != nil {
 			return nil, fmt.Errorf("failed to resolve dependencies: %v", err)
 		}
@@ -191,14 +273,17 @@ func (g *LLVMCodegen) GenerateModule(module *ast.Module) (*ir.Module, error) {
 	// First pass: declare all functions
 	for i := range module.Functions {
 		fn := &module.Functions[i]
-		g.astFunctions[fn.Name] = fn
+		g.astFunctions[functionSymbol(fn)] = fn
 		if err := g.declareFunction(fn); err != nil {
 			return nil, fmt.Errorf("failed to declare function %s: %v", fn.Name, err)
 		}
 	}
 
-	// Second pass: generate function bodies
+	// Second pass: generate function bodies; extern functions have none
 	for _, fn := range module.Functions {
+		if fn.Extern != "" {
+			continue
+		}
 		if err := g.generateFunction(&fn); err != nil {
 			return nil, fmt.Errorf("failed to generate function %s: %v", fn.Name, err)
 		}
@@ -207,8 +292,62 @@ func (g *LLVMCodegen) GenerateModule(module *ast.Module) (*ir.Module, error) {
 	return g.module, nil
 }
 
+// GenerateFunction generates LLVM IR for a single function in the module
+// under construction and returns it. The builtin and runtime declarations
+// are shared with the rest of the module, and the function can call itself
+// and the functions generated or declared before it; custom types it uses
+// must already be known from GenerateModule. An extern function is only
+// declared. On error the function is removed, so it can be generated again.
+func (g *LLVMCodegen) GenerateFunction(fn *ast.Function) (*ir.Func, error) {
+	symbol := functionSymbol(fn)
+	if _, ok := g.functions[symbol]; ok {
+		return nil, fmt.Errorf("function %s is already declared", symbol)
+	}
+	if g.debug != nil && g.debug.unit == nil {
+		file, _ := functionLocation(fn)
+		g.initDebugInfo(&ast.Module{Name: "snippet", File: file})
+	}
+
+	g.astFunctions[symbol] = fn
+	if err := g.declareFunction(fn); err != nil {
+		delete(g.astFunctions, symbol)
+		return nil, fmt.Errorf("failed to declare function %s: %v", fn.Name, err)
+	}
+	if fn.Extern != "" {
+		return g.functions[fn.Name], nil
+	}
+	llvmFunc := g.functions[symbol]
+	if err := g.generateFunction(fn); err != nil {
+		g.removeFunction(symbol, llvmFunc)
+		return nil, fmt.Errorf("failed to generate function %s: %v", fn.Name, err)
+	}
+	return llvmFunc, nil
+}
+
+// removeFunction drops a function that failed to generate from the module.
+func (g *LLVMCodegen) removeFunction(symbol string, llvmFunc *ir.Func) {
+	delete(g.functions, symbol)
+	delete(g.astFunctions, symbol)
+	funcs := g.module.Funcs[:0]
+	for _, f := range g.module.Funcs {
+		if f != llvmFunc {
+			funcs = append(funcs, f)
+		}
+	}
+	g.module.Funcs = funcs
+}
+
 // declareFunction declares a function signature in LLVM IR.
 func (g *LLVMCodegen) declareFunction(fn *ast.Function) error {
+	if fn.Extern != "" {
+		externFunc, err := g.declareExtern(fn)
+		if err != nil {
+			return err
+		}
+		g.functions[fn.Name] = externFunc
+		return nil
+	}
+
 	// Convert return type
 	returnType, err := g.convertType(fn.Returns)
 	if err != nil {
@@ -216,10 +355,11 @@ func (g *LLVMCodegen) declareFunction(fn *ast.Function) error {
 	}
 
 	// Create function with return type only
-	llvmFunc := g.module.NewFunc(fn.Name, returnType)
+	symbol := functionSymbol(fn)
+	llvmFunc := g.module.NewFunc(symbol, returnType)
 
 	// Add parameters
-	for _, param := range fn.Params {
+	for _, param := range functionParams(fn) {
 		paramType, err := g.convertType(param.Type)
 		if err != nil {
 			return fmt.Errorf("invalid parameter type %s: %v", param.Type, err)
@@ -227,21 +367,108 @@ func (g *LLVMCodegen) declareFunction(fn *ast.Function) error {
 		llvmParam := ir.NewParam(param.Name, paramType)
 		llvmFunc.Params = append(llvmFunc.Params, llvmParam)
 	}
+	applyFunctionMeta(fn, llvmFunc)
 
-	g.functions[fn.Name] = llvmFunc
+	g.functions[symbol] = llvmFunc
 	return nil
 }
 
+// applyFunctionMeta turns the meta keys of fn into attributes and linkage
+// of its LLVM function, which the optimizer honors: inline "always" and
+// "never" become alwaysinline and noinline, noopt becomes optnone, and
+// export true and false make the function external and internal.
+func applyFunctionMeta(fn *ast.Function, llvmFunc *ir.Func) {
+	if noopt, _ := fn.MetaBool(ast.MetaNoOpt); noopt {
+		// LLVM requires optnone functions to be noinline
+		llvmFunc.FuncAttrs = append(llvmFunc.FuncAttrs, enum.FuncAttrNoInline, enum.FuncAttrOptNone)
+	} else {
+		switch fn.MetaString(ast.MetaInline) {
+		case ast.InlineAlways:
+			llvmFunc.FuncAttrs = append(llvmFunc.FuncAttrs, enum.FuncAttrAlwaysInline)
+		case ast.InlineNever:
+			llvmFunc.FuncAttrs = append(llvmFunc.FuncAttrs, enum.FuncAttrNoInline)
+		}
+	}
+
+	if exported, ok := fn.MetaBool(ast.MetaExport); ok && llvmFunc.Name() != "main" {
+		if exported {
+			llvmFunc.Linkage = enum.LinkageExternal
+		} else {
+			llvmFunc.Linkage = enum.LinkageInternal
+		}
+	}
+}
+
+// declareExtern declares the C function an extern function is bound to.
+// Functions bound to the same symbol share its declaration. Bool arguments
+// are zero-extended as C expects.
+func (g *LLVMCodegen) declareExtern(fn *ast.Function) (*ir.Func, error) {
+	returnType, err := g.convertType(fn.Returns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid return type %s: %v", fn.Returns, err)
+	}
+	params := make([]*ir.Param, len(fn.Params))
+	paramTypes := make([]types.Type, len(fn.Params))
+	for i, param := range fn.Params {
+		paramTypes[i], err = g.convertType(param.Type)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter type %s: %v", param.Type, err)
+		}
+		params[i] = ir.NewParam(param.Name, paramTypes[i])
+		if paramTypes[i].Equal(types.I1) {
+			params[i].Attrs = append(params[i].Attrs, enum.ParamAttrZeroExt)
+		}
+	}
+
+	for _, existing := range g.module.Funcs {
+		if existing.Name() != fn.Extern {
+			continue
+		}
+		if len(existing.Blocks) > 0 || !existing.Sig.Equal(types.NewFunc(returnType, paramTypes...)) {
+			return nil, fmt.Errorf("extern symbol %s is already declared with a different signature", fn.Extern)
+		}
+		return existing, nil
+	}
+
+	return g.module.NewFunc(fn.Extern, returnType, params...), nil
+}
+
+// functionSymbol returns the LLVM symbol for a function. Methods are
+// qualified with their receiver type so each type has its own namespace.
+func functionSymbol(fn *ast.Function) string {
+	if fn.Receiver != nil {
+		return methodSymbol(fn.Receiver.Type, fn.Name)
+	}
+	return fn.Name
+}
+
+// methodSymbol returns the LLVM symbol for a method on a struct type.
+func methodSymbol(typeName, methodName string) string {
+	return typeName + "." + methodName
+}
+
+// functionParams returns a function's LLVM-level parameters. Methods take
+// their receiver as the first parameter.
+func functionParams(fn *ast.Function) []ast.Parameter {
+	if fn.Receiver == nil {
+		return fn.Params
+	}
+	return append([]ast.Parameter{*fn.Receiver}, fn.Params...)
+}
+
 // generateFunction generates the body of a function.
 func (g *LLVMCodegen) generateFunction(fn *ast.Function) error {
-	llvmFunc := g.functions[fn.Name]
+	llvmFunc := g.functions[functionSymbol(fn)]
 
 	// Create entry block
 	entry := llvmFunc.NewBlock("entry")
 	g.builder = entry
+	g.entryBlock = entry
 
 	// Set current function
 	g.currentFunction = fn
+	g.beginDebugFunction(fn, llvmFunc)
+	defer g.enterLocation(functionLocation(fn))()
 
 	// Create new variable scope for this function
 	oldVars := g.variables
@@ -250,16 +477,18 @@ func (g *LLVMCodegen) generateFunction(fn *ast.Function) error {
 	// Create new type tracking scope for this function
 	oldVarTypes := g.variableTypes
 	g.variableTypes = make(map[string]string)
+	oldArrayLengths := g.arrayLengths
+	g.arrayLengths = constantArrayLengths(fn)
 
 	// Add parameters to variable scope
-	for i, param := range fn.Params {
+	for i, param := range functionParams(fn) {
 		if i < len(llvmFunc.Params) {
 			// Create alloca for the parameter
 			paramAlloca := g.builder.NewAlloca(llvmFunc.Params[i].Type())
 			paramAlloca.SetName(param.Name + "_ptr")
 
 			// Track parameter type
-			g.variableTypes[param.Name] = param.Type
+			g.variableTypes[param.Name] = g.resolveType(param.Type)
 
 			// Store the parameter value into the alloca
 			g.builder.NewStore(llvmFunc.Params[i], paramAlloca)
@@ -269,6 +498,14 @@ func (g *LLVMCodegen) generateFunction(fn *ast.Function) error {
 		}
 	}
 
+	// Map literals that do not outlive the call are released when it returns
+	oldScopedMaps, oldScopedMapOrder := g.scopedMaps, g.scopedMapOrder
+	g.allocateScopedMaps(fn)
+	defer func() { g.scopedMaps, g.scopedMapOrder = oldScopedMaps, oldScopedMapOrder }()
+	oldHeapArrays, oldHeapArrayOrder := g.heapArrays, g.heapArrayOrder
+	g.allocateHeapArrays(fn)
+	defer func() { g.heapArrays, g.heapArrayOrder = oldHeapArrays, oldHeapArrayOrder }()
+
 	// Generate function body
 	var lastValue value.Value
 	for _, stmt := range fn.Body {
@@ -283,6 +520,8 @@ func (g *LLVMCodegen) generateFunction(fn *ast.Function) error {
 	}
 
 	// If no explicit return and function expects void, add return
+	g.releaseScopedMaps()
+	g.releaseHeapArrays()
 	if fn.Returns == "void" || fn.Returns == "" {
 		g.builder.NewRet(nil)
 	} else if lastValue != nil {
@@ -298,11 +537,14 @@ func (g *LLVMCodegen) generateFunction(fn *ast.Function) error {
 	// Restore previous variable scope
 	g.variables = oldVars
 	g.variableTypes = oldVarTypes
+	g.arrayLengths = oldArrayLengths
 	return nil
 }
 
 // generateStatement generates LLVM IR for a statement.
 func (g *LLVMCodegen) generateStatement(stmt *ast.Statement) (value.Value, bool, error) {
+	defer g.enterLocation(stmt.File, stmt.Line)()
+
 	switch stmt.Type {
 	case ast.StmtAssign:
 		val, err := g.generateExpression(stmt.Value)
@@ -310,11 +552,18 @@ func (g *LLVMCodegen) generateStatement(stmt *ast.Statement) (value.Value, bool,
 			return nil, false, err
 		}
 
+		if stmt.Lvalue != nil {
+			if err := g.generateElementAssignment(stmt.Lvalue, stmt.Value, val); err != nil {
+				return nil, false, err
+			}
+			return val, false, nil
+		}
+
 		// Check if variable already has an alloca
 		varAlloca, exists := g.variables[stmt.Target]
 		if !exists {
 			// First assignment - allocate memory for the variable
-			newAlloca := g.builder.NewAlloca(val.Type())
+			newAlloca := g.entryAlloca(val.Type())
 			newAlloca.SetName(stmt.Target + "_ptr")
 
 			// Keep track of the alloca for later loads
@@ -336,8 +585,15 @@ func (g *LLVMCodegen) generateStatement(stmt *ast.Statement) (value.Value, bool,
 			if err != nil {
 				return nil, false, err
 			}
+			if g.currentFunction != nil {
+				val = g.coerceBoxedValue(stmt.Value, val, g.currentFunction.Returns)
+			}
+			g.releaseScopedMaps()
+			g.releaseHeapArrays()
 			g.builder.NewRet(val)
 		} else {
+			g.releaseScopedMaps()
+			g.releaseHeapArrays()
 			g.builder.NewRet(nil)
 		}
 		return nil, true, nil
@@ -358,16 +614,90 @@ func (g *LLVMCodegen) generateStatement(stmt *ast.Statement) (value.Value, bool,
 	case ast.StmtFor:
 		return g.generateFor(stmt)
 
+	case ast.StmtMatch:
+		return g.generateMatch(stmt)
+
+	case ast.StmtAssert:
+		cond, err := g.generateExpression(stmt.Cond)
+		if err != nil {
+			return nil, false, err
+		}
+		if !cond.Type().Equal(types.I1) {
+			return nil, false, fmt.Errorf("assert condition must be a boolean, got %s", cond.Type())
+		}
+		message := stmt.Message
+		if message == "" {
+			message = "assertion failed"
+		}
+		g.generateAssert(cond, message)
+		return nil, false, nil
+
+	case ast.StmtDestructure:
+		return g.generateDestructure(stmt)
+
+	case ast.StmtDefer:
+		return nil, false, fmt.Errorf("defer statements are not supported by the compiler yet")
+
 	default:
 		return nil, false, fmt.Errorf("unsupported statement type: %s", stmt.Type)
 	}
 }
 
+// generateDestructure lowers a destructure statement to assignments from
+// index or field loads. The source is first stored in a hidden variable,
+// unless it already is a variable, so it is evaluated only once.
+func (g *LLVMCodegen) generateDestructure(stmt *ast.Statement) (value.Value, bool, error) {
+	source := stmt.Value
+	if source.Type != ast.ExprVariable || destructureBinds(stmt, source.Name) {
+		name := fmt.Sprintf("destructure.%d", g.destructureCount)
+		g.destructureCount++
+		if _, _, err := g.generateStatement(&ast.Statement{Type: ast.StmtAssign, Target: name, Value: source, File: stmt.File, Line: stmt.Line}); err != nil {
+			return nil, false, err
+		}
+		source = &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+
+	var loads []ast.Statement
+	for idx, name := range stmt.Targets {
+		if name != "_" {
+			index := &ast.Expression{Type: ast.ExprLiteral, Value: float64(idx)}
+			loads = append(loads, ast.Statement{Type: ast.StmtAssign, Target: name, Value: &ast.Expression{Type: ast.ExprIndex, Object: source, Index: index}})
+		}
+	}
+	for _, field := range stmt.BoundFields() {
+		loads = append(loads, ast.Statement{Type: ast.StmtAssign, Target: stmt.Bindings[field], Value: &ast.Expression{Type: ast.ExprField, Object: source, Field: field}})
+	}
+	for idx := range loads {
+		loads[idx].File, loads[idx].Line = stmt.File, stmt.Line
+		if _, _, err := g.generateStatement(&loads[idx]); err != nil {
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
+}
+
+// destructureBinds reports whether a destructure statement binds name.
+func destructureBinds(stmt *ast.Statement, name string) bool {
+	for _, target := range stmt.Targets {
+		if target == name {
+			return true
+		}
+	}
+	for _, variable := range stmt.Bindings {
+		if variable == name {
+			return true
+		}
+	}
+	return false
+}
+
 // generateExpression generates LLVM IR for an expression.
 func (g *LLVMCodegen) generateExpression(expr *ast.Expression) (value.Value, error) {
+	defer g.enterLocation(expr.File, expr.Line)()
+
 	switch expr.Type {
 	case ast.ExprLiteral:
-		return g.generateLiteral(expr.Value)
+		return g.generateLiteral(expr)
 
 	case ast.ExprVariable:
 		varAlloca, ok := g.variables[expr.Name]
@@ -412,34 +742,46 @@ func (g *LLVMCodegen) generateExpression(expr *ast.Expression) (value.Value, err
 	case ast.ExprField:
 		return g.generateFieldAccess(expr)
 
+	case ast.ExprCast:
+		return g.generateCast(expr)
+
+	case ast.ExprMethodCall:
+		return g.generateMethodCall(expr)
+
+	case ast.ExprVariant:
+		return g.generateVariant(expr)
+
+	case ast.ExprLambda:
+		return nil, fmt.Errorf("lambda expressions are not supported in compiled mode")
+
+	case ast.ExprFuncRef:
+		return nil, fmt.Errorf("function references are not supported in compiled mode")
+
 	default:
 		return nil, fmt.Errorf("unsupported expression type: %s", expr.Type)
 	}
 }
 
 // generateLiteral generates LLVM IR for a literal value.
-func (g *LLVMCodegen) generateLiteral(value interface{}) (value.Value, error) {
-	switch v := value.(type) {
+func (g *LLVMCodegen) generateLiteral(expr *ast.Expression) (value.Value, error) {
+	lit, err := consteval.EvalConst(expr)
+	if err != nil {
+		return nil, err
+	}
+	switch v := lit.Value.(type) {
+	case int64:
+		return constant.NewInt(types.I64, v), nil
 	case float64:
-		// JSON numbers are always float64 - check if it's actually an int
-		if float64(int64(v)) == v {
-			return constant.NewInt(types.I64, int64(v)), nil
-		}
 		return constant.NewFloat(types.Double, v), nil
 	case string:
-		// Create a global string constant
-		charArray := constant.NewCharArrayFromString(v + "\x00")
-		str := g.module.NewGlobalDef("", charArray)
-		str.Immutable = true
-		// Return pointer to the first character of the string
-		return g.builder.NewGetElementPtr(charArray.Type(), str, constant.NewInt(types.I64, 0), constant.NewInt(types.I64, 0)), nil
+		return g.createStringLiteral(v), nil
 	case bool:
 		if v {
 			return constant.NewInt(types.I1, 1), nil
 		}
 		return constant.NewInt(types.I1, 0), nil
 	default:
-		return nil, fmt.Errorf("unsupported literal type: %T", value)
+		return nil, fmt.Errorf("unsupported literal type: %T", expr.LiteralValue())
 	}
 }
 
@@ -455,6 +797,10 @@ func (g *LLVMCodegen) generateBinary(expr *ast.Expression) (value.Value, error)
 		return nil, err
 	}
 
+	// Unbox map lookup results to match the other operand
+	left = g.coerceBoxedValue(expr.Left, left, scalarTypeName(right.Type()))
+	right = g.coerceBoxedValue(expr.Right, right, scalarTypeName(left.Type()))
+
 	// Type promotion: if either operand is float, promote both to float
 	leftType := left.Type()
 	rightType := right.Type()
@@ -476,18 +822,27 @@ func (g *LLVMCodegen) generateBinary(expr *ast.Expression) (value.Value, error)
 		if isFloat {
 			return g.builder.NewFAdd(left, right), nil
 		}
+		if g.checkOverflow {
+			return g.generateCheckedArithmetic(expr.Op, left, right), nil
+		}
 		return g.builder.NewAdd(left, right), nil
 
 	case ast.OpSub:
 		if isFloat {
 			return g.builder.NewFSub(left, right), nil
 		}
+		if g.checkOverflow {
+			return g.generateCheckedArithmetic(expr.Op, left, right), nil
+		}
 		return g.builder.NewSub(left, right), nil
 
 	case ast.OpMul:
 		if isFloat {
 			return g.builder.NewFMul(left, right), nil
 		}
+		if g.checkOverflow {
+			return g.generateCheckedArithmetic(expr.Op, left, right), nil
+		}
 		return g.builder.NewMul(left, right), nil
 
 	case ast.OpDiv:
@@ -518,7 +873,8 @@ func (g *LLVMCodegen) generateBinary(expr *ast.Expression) (value.Value, error)
 
 	case ast.OpNe:
 		if isFloat {
-			return g.builder.NewFCmp(enum.FPredONE, left, right), nil
+			// Unordered, so that NaN != NaN as IEEE 754 requires
+			return g.builder.NewFCmp(enum.FPredUNE, left, right), nil
 		}
 		return g.builder.NewICmp(enum.IPredNE, left, right), nil
 
@@ -593,6 +949,100 @@ func (g *LLVMCodegen) generateUnary(expr *ast.Expression) (value.Value, error) {
 	}
 }
 
+// generateCast generates LLVM IR for explicit type conversions.
+// Float to int conversion uses fptosi, which truncates toward zero.
+func (g *LLVMCodegen) generateCast(expr *ast.Expression) (value.Value, error) {
+	if expr.Operand == nil {
+		return nil, fmt.Errorf("cast expression missing operand")
+	}
+
+	operand, err := g.generateExpression(expr.Operand)
+	if err != nil {
+		return nil, err
+	}
+
+	targetType, err := g.convertType(expr.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cast target type %s: %v", expr.To, err)
+	}
+
+	srcType := operand.Type()
+	if srcType.Equal(targetType) {
+		return operand, nil
+	}
+
+	switch {
+	case srcType.Equal(types.I64) && targetType.Equal(types.Double):
+		return g.builder.NewSIToFP(operand, types.Double), nil
+	case srcType.Equal(types.Double) && targetType.Equal(types.I64):
+		// fptosi yields poison for NaN, infinities and values outside the
+		// i64 range, so those are reported as runtime errors first
+		tooSmall := g.builder.NewFCmp(enum.FPredULT, operand, constant.NewFloat(types.Double, -0x1p63))
+		tooLarge := g.builder.NewFCmp(enum.FPredUGE, operand, constant.NewFloat(types.Double, 0x1p63))
+		g.generateRuntimeErrorIf(g.builder.NewOr(tooSmall, tooLarge), "cast", "cannot cast float to int: value out of range")
+		return g.builder.NewFPToSI(operand, types.I64), nil
+	case srcType.Equal(types.I1) && targetType.Equal(types.I64):
+		return g.builder.NewZExt(operand, types.I64), nil
+	case srcType.Equal(types.I32) && targetType.Equal(types.I64):
+		// Plain enums are i32 tags, which are their variant's index
+		return g.builder.NewZExt(operand, types.I64), nil
+	case g.enumTags[g.structTypeNameOf(expr.Operand, srcType)] != nil && targetType.Equal(types.I64):
+		return g.builder.NewZExt(g.builder.NewExtractValue(operand, 0), types.I64), nil
+	case srcType.Equal(types.I1) && targetType.Equal(types.Double):
+		return g.builder.NewUIToFP(operand, types.Double), nil
+	case srcType.Equal(types.I64) && targetType.Equal(types.I1):
+		return g.builder.NewICmp(enum.IPredNE, operand, constant.NewInt(types.I64, 0)), nil
+	case srcType.Equal(types.Double) && targetType.Equal(types.I1):
+		return g.builder.NewFCmp(enum.FPredUNE, operand, constant.NewFloat(types.Double, 0.0)), nil
+	case targetType.Equal(types.I8Ptr):
+		// Conversions to string are delegated to the runtime
+		var convName string
+		switch {
+		case srcType.Equal(types.I64):
+			convName = "alas_runtime_int_to_string"
+		case srcType.Equal(types.Double):
+			convName = "alas_runtime_float_to_string"
+		case srcType.Equal(types.I1):
+			convName = "alas_runtime_bool_to_string"
+		default:
+			return nil, fmt.Errorf("cannot cast %s to %s", srcType, expr.To)
+		}
+		return g.builder.NewCall(g.declareCastFunction(convName, types.I8Ptr, srcType), operand), nil
+	case srcType.Equal(types.I8Ptr):
+		// Conversions from string are parsed by the runtime, which reports parse errors
+		var convName string
+		switch {
+		case targetType.Equal(types.I64):
+			convName = "alas_runtime_string_to_int"
+		case targetType.Equal(types.Double):
+			convName = "alas_runtime_string_to_float"
+		case targetType.Equal(types.I1):
+			convName = "alas_runtime_string_to_bool"
+		default:
+			return nil, fmt.Errorf("cannot cast string to %s", expr.To)
+		}
+		fileName, lineNumber := g.locationArgs()
+		convFunc := g.declareCastFunction(convName, targetType, types.I8Ptr, types.I8Ptr, types.I32)
+		return g.builder.NewCall(convFunc, operand, fileName, lineNumber), nil
+	default:
+		return nil, fmt.Errorf("cannot cast %s to %s", srcType, expr.To)
+	}
+}
+
+// declareCastFunction declares a runtime conversion function if not already declared.
+func (g *LLVMCodegen) declareCastFunction(name string, returnType types.Type, paramTypes ...types.Type) *ir.Func {
+	if fn, exists := g.builtinFunctions[name]; exists {
+		return fn
+	}
+
+	fn := g.module.NewFunc(name, returnType)
+	for _, paramType := range paramTypes {
+		fn.Params = append(fn.Params, ir.NewParam("", paramType))
+	}
+	g.builtinFunctions[name] = fn
+	return fn
+}
+
 // generateCall generates LLVM IR for function calls.
 func (g *LLVMCodegen) generateCall(expr *ast.Expression) (value.Value, error) {
 	fn, ok := g.functions[expr.Name]
@@ -613,6 +1063,271 @@ func (g *LLVMCodegen) generateCall(expr *ast.Expression) (value.Value, error) {
 	return g.builder.NewCall(fn, args...), nil
 }
 
+// generateMethodCall generates LLVM IR for a method call by calling the
+// receiver type's method with the receiver as the first argument.
+func (g *LLVMCodegen) generateMethodCall(expr *ast.Expression) (value.Value, error) {
+	if expr.Object == nil {
+		return nil, fmt.Errorf("method call missing receiver")
+	}
+	receiver, err := g.generateExpression(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	typeName := g.structTypeName(expr.Object, receiver)
+	if typeName == "" {
+		return nil, fmt.Errorf("cannot determine struct type of receiver for method %s", expr.Name)
+	}
+	fn, ok := g.functions[methodSymbol(typeName, expr.Name)]
+	if !ok {
+		return nil, fmt.Errorf("undefined method: %s.%s", typeName, expr.Name)
+	}
+	if !receiver.Type().Equal(fn.Params[0].Type()) {
+		return nil, fmt.Errorf("receiver for method %s.%s has type %s, expected %s",
+			typeName, expr.Name, receiver.Type(), fn.Params[0].Type())
+	}
+
+	args := make([]value.Value, 0, len(expr.Args)+1)
+	args = append(args, receiver)
+	for _, arg := range expr.Args {
+		val, err := g.generateExpression(&arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, val)
+	}
+
+	return g.builder.NewCall(fn, args...), nil
+}
+
+// structTypeName returns the custom struct type of a generated value, using
+// variable type tracking first and falling back to the value's LLVM type.
+func (g *LLVMCodegen) structTypeName(expr *ast.Expression, val value.Value) string {
+	return g.structTypeNameOf(expr, val.Type())
+}
+
+// structTypeNameOf is structTypeName for an expression of LLVM type t.
+func (g *LLVMCodegen) structTypeNameOf(expr *ast.Expression, t types.Type) string {
+	if expr.Type == ast.ExprVariable {
+		if typeName := g.variableTypes[expr.Name]; typeName != "" && typeName != DynamicMapType {
+			if _, ok := g.fieldIndices[typeName]; ok {
+				return typeName
+			}
+		}
+	}
+	if structType, ok := t.(*types.StructType); ok {
+		for typeName, llvmType := range g.structTypes {
+			if llvmType == structType {
+				return typeName
+			}
+		}
+	}
+	return ""
+}
+
+// generateVariant generates LLVM IR for constructing an enum variant.
+func (g *LLVMCodegen) generateVariant(expr *ast.Expression) (value.Value, error) {
+	enumName := g.resolveType(expr.Enum)
+	tags, ok := g.enumTags[enumName]
+	if !ok {
+		return nil, fmt.Errorf("unknown enum type: %s", expr.Enum)
+	}
+	tag, ok := tags[expr.Variant]
+	if !ok {
+		return nil, fmt.Errorf("enum %s has no variant %s", expr.Enum, expr.Variant)
+	}
+	tagValue := constant.NewInt(types.I32, int64(tag))
+
+	structType, ok := g.structTypes[enumName].(*types.StructType)
+	if !ok {
+		if len(expr.Pairs) > 0 {
+			return nil, fmt.Errorf("variant %s.%s has no fields", expr.Enum, expr.Variant)
+		}
+		return tagValue, nil
+	}
+
+	var result value.Value = g.builder.NewInsertValue(constant.NewZeroInitializer(structType), tagValue, 0)
+	fieldIndices := g.variantFields[enumName][expr.Variant]
+	for _, pair := range expr.Pairs {
+		name, ok := pair.Key.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("variant field name must be a string literal")
+		}
+		idx, ok := fieldIndices[name]
+		if !ok {
+			return nil, fmt.Errorf("variant %s.%s has no field %s", expr.Enum, expr.Variant, name)
+		}
+		val, err := g.generateExpression(&pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = g.builder.NewInsertValue(result, val, uint64(idx))
+	}
+	return result, nil
+}
+
+// generateMatch generates LLVM IR for a match statement as a switch on the enum tag.
+func (g *LLVMCodegen) generateMatch(stmt *ast.Statement) (value.Value, bool, error) {
+	subject, err := g.generateExpression(stmt.Value)
+	if err != nil {
+		return nil, false, err
+	}
+	enumName := g.matchEnumName(stmt, subject)
+	if enumName == "" {
+		return nil, false, fmt.Errorf("cannot determine enum type of match value")
+	}
+
+	tag := subject
+	if _, isStruct := subject.Type().(*types.StructType); isStruct {
+		tag = g.builder.NewExtractValue(subject, 0)
+	}
+	if !tag.Type().Equal(types.I32) {
+		return nil, false, fmt.Errorf("match value of type %s is not an enum", subject.Type())
+	}
+
+	currentFunc := g.builder.Parent
+	switchBlock := g.builder
+	defaultBlock := currentFunc.NewBlock("match.default")
+	endBlock := currentFunc.NewBlock("match.end")
+
+	allReturn := true
+	var cases []*ir.Case
+	seen := make(map[string]bool)
+	for _, matchCase := range stmt.Cases {
+		// Cases that can never match (unknown or duplicate variants) are
+		// reported as warnings by the validator and generate no code
+		tagIdx, ok := g.enumTags[enumName][matchCase.Variant]
+		if !ok || seen[matchCase.Variant] {
+			continue
+		}
+		seen[matchCase.Variant] = true
+		caseBlock := currentFunc.NewBlock("match." + matchCase.Variant)
+		cases = append(cases, ir.NewCase(constant.NewInt(types.I32, int64(tagIdx)), caseBlock))
+
+		g.builder = caseBlock
+		for field, varName := range matchCase.Bindings {
+			idx, ok := g.variantFields[enumName][matchCase.Variant][field]
+			if !ok {
+				return nil, false, fmt.Errorf("variant %s has no field %s", matchCase.Variant, field)
+			}
+			fieldVal := g.builder.NewExtractValue(subject, uint64(idx))
+			alloca := g.builder.NewAlloca(fieldVal.Type())
+			alloca.SetName(varName + "_ptr")
+			g.builder.NewStore(fieldVal, alloca)
+			g.variables[varName] = alloca
+			if fieldType, ok := g.variantFieldType(enumName, matchCase.Variant, field); ok {
+				g.variableTypes[varName] = fieldType
+			}
+		}
+
+		returned, err := g.generateBlockStatements(matchCase.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		if !returned {
+			g.builder.NewBr(endBlock)
+			allReturn = false
+		}
+	}
+
+	// Without a default the validator guarantees the cases are exhaustive
+	g.builder = defaultBlock
+	if stmt.Default != nil {
+		returned, err := g.generateBlockStatements(stmt.Default)
+		if err != nil {
+			return nil, false, err
+		}
+		if !returned {
+			g.builder.NewBr(endBlock)
+			allReturn = false
+		}
+	} else {
+		g.builder.NewUnreachable()
+	}
+
+	switchBlock.NewSwitch(tag, defaultBlock, cases...)
+
+	g.builder = endBlock
+	if allReturn {
+		g.builder.NewUnreachable()
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+// generateBlockStatements generates a list of statements, reporting whether
+// it ended in a return.
+func (g *LLVMCodegen) generateBlockStatements(stmts []ast.Statement) (bool, error) {
+	for _, s := range stmts {
+		_, isReturn, err := g.generateStatement(&s)
+		if err != nil {
+			return false, err
+		}
+		if isReturn {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchEnumName determines the enum type of a match subject from the variant
+// expression, variable type tracking, or its LLVM type, falling back to the
+// only enum that declares every case.
+func (g *LLVMCodegen) matchEnumName(stmt *ast.Statement, subject value.Value) string {
+	switch stmt.Value.Type {
+	case ast.ExprVariant:
+		return g.resolveType(stmt.Value.Enum)
+	case ast.ExprVariable:
+		if typeName := g.variableTypes[stmt.Value.Name]; g.enumTags[typeName] != nil {
+			return typeName
+		}
+	}
+	if structType, ok := subject.Type().(*types.StructType); ok {
+		for typeName := range g.enumTags {
+			if g.structTypes[typeName] == structType {
+				return typeName
+			}
+		}
+	}
+
+	var match string
+	for typeName, tags := range g.enumTags {
+		declaresAll := true
+		for _, matchCase := range stmt.Cases {
+			if _, ok := tags[matchCase.Variant]; !ok {
+				declaresAll = false
+				break
+			}
+		}
+		if declaresAll {
+			if match != "" {
+				return ""
+			}
+			match = typeName
+		}
+	}
+	return match
+}
+
+// variantFieldType returns the ALaS type of a variant payload field.
+func (g *LLVMCodegen) variantFieldType(enumName, variantName, field string) (string, bool) {
+	typeDef, ok := g.customTypes[enumName]
+	if !ok {
+		return "", false
+	}
+	for _, variant := range typeDef.Definition.EnumVariants() {
+		if variant.Name != variantName {
+			continue
+		}
+		for _, f := range variant.Fields {
+			if f.Name == field {
+				return f.Type, true
+			}
+		}
+	}
+	return "", false
+}
+
 // generateIf generates LLVM IR for if statements.
 func (g *LLVMCodegen) generateIf(stmt *ast.Statement) (value.Value, bool, error) {
 	// Generate condition
@@ -692,8 +1407,16 @@ func (g *LLVMCodegen) generateIf(stmt *ast.Statement) (value.Value, bool, error)
 }
 
 // generateLoop generates LLVM IR for loop statements (while and for).
-// Both while and for loops in ALaS have the same structure: condition and body.
+// Both have a condition and body; a for loop may also have init statements,
+// emitted before the condition block, and update statements, emitted at the
+// end of the body before the back edge.
 func (g *LLVMCodegen) generateLoop(stmt *ast.Statement, loopType string) (value.Value, bool, error) {
+	for _, s := range stmt.Init {
+		if _, _, err := g.generateStatement(&s); err != nil {
+			return nil, false, err
+		}
+	}
+
 	currentFunc := g.builder.Parent
 	condBlock := currentFunc.NewBlock(loopType + ".cond")
 	bodyBlock := currentFunc.NewBlock(loopType + ".body")
@@ -721,6 +1444,11 @@ func (g *LLVMCodegen) generateLoop(stmt *ast.Statement, loopType string) (value.
 			return nil, true, nil
 		}
 	}
+	for _, s := range stmt.Update {
+		if _, _, err := g.generateStatement(&s); err != nil {
+			return nil, false, err
+		}
+	}
 	g.builder.NewBr(condBlock) // Loop back to condition
 
 	// Continue with end block
@@ -733,16 +1461,20 @@ func (g *LLVMCodegen) generateWhile(stmt *ast.Statement) (value.Value, bool, err
 	return g.generateLoop(stmt, "while")
 }
 
-// generateFor generates LLVM IR for for loops.
-// ALaS for loops are similar to while loops with a condition and body.
-// Traditional for(init; cond; update) can be desugared to init + while(cond) { body; update }.
+// generateFor generates LLVM IR for for loops, desugaring
+// for(init; cond; update) to init + while(cond) { body; update }.
 func (g *LLVMCodegen) generateFor(stmt *ast.Statement) (value.Value, bool, error) {
 	return g.generateLoop(stmt, "for")
 }
 
 // convertType converts ALaS type to LLVM type.
 func (g *LLVMCodegen) convertType(alasType string) (types.Type, error) {
-	switch alasType {
+	alasType, err := ast.ResolveTypeAlias(alasType, g.lookupType)
+	if err != nil {
+		return nil, err
+	}
+	// array<T> and map<K,V> share the representation of array and map
+	switch ast.BaseType(alasType) {
 	case ast.TypeInt:
 		return types.I64, nil
 	case ast.TypeFloat:
@@ -757,8 +1489,7 @@ func (g *LLVMCodegen) convertType(alasType string) (types.Type, error) {
 		// struct { i8* data, i64 length }
 		return types.NewStruct(types.NewPointer(types.I8), types.I64), nil
 	case ast.TypeMap:
-		// Represent maps as a simple pointer (simplified implementation)
-		// In a real implementation, this would be a hash table structure
+		// Maps are opaque handles to runtime hash tables (alas_runtime_map_*)
 		return types.NewPointer(types.I8), nil
 	case "any":
 		// Represent "any" type as a generic pointer - this allows stdlib functions to accept any type
@@ -839,14 +1570,22 @@ func (g *LLVMCodegen) generateArrayLiteral(expr *ast.Expression) (value.Value, e
 	if elementCount < 0 || elementCount > 0x7FFFFFFF {
 		return nil, fmt.Errorf("array element count out of valid range: %d", elementCount)
 	}
-	arrayAlloca := g.builder.NewAlloca(types.NewArray(uint64(elementCount), elemType))
-	arrayAlloca.SetName("array_literal")
+	// Arrays too large for the stack get a heap block instead
+	storageType := types.NewArray(uint64(elementCount), elemType)
+	var arrayAlloca value.Value
+	if handle, ok := g.heapArrays[expr]; ok {
+		arrayAlloca = g.allocateHeapArray(storageType, handle)
+	} else {
+		stackArray := g.entryAlloca(storageType)
+		stackArray.SetName("array_literal")
+		arrayAlloca = stackArray
+	}
 
 	// Store elements
 	for i, elem := range elements {
 		// Get pointer to element
 		elemPtr := g.builder.NewGetElementPtr(
-			types.NewArray(uint64(elementCount), elemType),
+			storageType,
 			arrayAlloca,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, int64(i)),
@@ -860,7 +1599,7 @@ func (g *LLVMCodegen) generateArrayLiteral(expr *ast.Expression) (value.Value, e
 	structType := arrayType.(*types.StructType)
 
 	// Allocate struct on stack
-	structAlloca := g.builder.NewAlloca(structType)
+	structAlloca := g.entryAlloca(structType)
 	structAlloca.SetName("array_struct")
 
 	// Store data pointer
@@ -887,97 +1626,69 @@ func (g *LLVMCodegen) generateArrayLiteral(expr *ast.Expression) (value.Value, e
 	return g.builder.NewLoad(structType, structAlloca), nil
 }
 
-// generateMapLiteral generates LLVM IR for map literals.
-// This is a simplified implementation - a full implementation would need a proper hash table.
+// generateMapLiteral generates LLVM IR for map literals, which are backed by
+// the runtime hash table.
 func (g *LLVMCodegen) generateMapLiteral(expr *ast.Expression) (value.Value, error) {
 	// Check if this should be a struct construction
 	if g.currentFunction != nil && g.currentFunction.Returns != "" {
+		returns := g.resolveType(g.currentFunction.Returns)
 		// Check if the return type is a custom type
-		if typeDef, isCustomType := g.customTypes[g.currentFunction.Returns]; isCustomType {
+		if typeDef, isCustomType := g.customTypes[returns]; isCustomType {
 			// Check if it's a struct type
 			if typeDef.Definition.Kind == ast.TypeKindStruct {
-				if structType, isStruct := g.structTypes[g.currentFunction.Returns]; isStruct {
+				if structType, isStruct := g.structTypes[returns]; isStruct {
 					if _, ok := structType.(*types.StructType); ok {
 						// This is a struct construction
-						return g.generateStructConstruction(expr, g.currentFunction.Returns)
+						return g.generateStructConstruction(expr, returns)
 					}
 				}
 			}
 		}
 	}
 
-	// Regular map literal generation
+	// Regular map literal: build an array of {key, value} CValue pointer pairs
+	// and hand it to the runtime hash table
 	pairCount := len(expr.Pairs)
-
-	// Define a key-value pair struct type {i8* key, i8* value}
-	// Using i8* (char*) pointers to handle both strings and boxed values
 	kvPairType := types.NewStruct(types.I8Ptr, types.I8Ptr)
+	pairsType := types.NewArray(uint64(pairCount), kvPairType)
+	pairsAlloca := g.builder.NewAlloca(pairsType)
 
-	// Allocate array of pairs
-	pairsAlloca := g.builder.NewAlloca(types.NewArray(uint64(pairCount), kvPairType))
-	pairsAlloca.SetName("map_pairs")
-
-	// Store key-value pairs
-	for i, pair := range expr.Pairs {
-		// Generate key and value
+	for i := range expr.Pairs {
+		pair := &expr.Pairs[i]
 		key, err := g.generateExpression(&pair.Key)
 		if err != nil {
 			return nil, err
 		}
+		keyCVal, err := g.mapKeyCValue(&pair.Key, key)
+		if err != nil {
+			return nil, err
+		}
 		val, err := g.generateExpression(&pair.Value)
 		if err != nil {
 			return nil, err
 		}
+		valCVal := g.exprCValue(&pair.Value, val)
 
-		// Get pointer to pair
-		pairPtr := g.builder.NewGetElementPtr(
-			types.NewArray(uint64(pairCount), kvPairType),
-			pairsAlloca,
+		keyPtr := g.builder.NewGetElementPtr(pairsType, pairsAlloca,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, int64(i)),
-		)
-
-		// Convert key to string pointer if needed
-		keyPtr := g.builder.NewGetElementPtr(
-			kvPairType,
-			pairPtr,
-			constant.NewInt(types.I32, 0),
-			constant.NewInt(types.I32, 0),
-		)
-
-		// Box key if needed and store it
-		keyAsPtr := g.boxToI8Ptr(key, "boxed_key")
-		g.builder.NewStore(keyAsPtr, keyPtr)
+			constant.NewInt(types.I32, 0))
+		g.builder.NewStore(keyCVal, keyPtr)
 
-		// Store value
-		valPtr := g.builder.NewGetElementPtr(
-			kvPairType,
-			pairPtr,
+		valPtr := g.builder.NewGetElementPtr(pairsType, pairsAlloca,
 			constant.NewInt(types.I32, 0),
-			constant.NewInt(types.I32, 1),
-		)
-
-		// Box value if needed and store it
-		valAsPtr := g.boxToI8Ptr(val, "boxed_value")
-		g.builder.NewStore(valAsPtr, valPtr)
+			constant.NewInt(types.I32, int64(i)),
+			constant.NewInt(types.I32, 1))
+		g.builder.NewStore(valCVal, valPtr)
 	}
 
-	// Create a proper map by calling the runtime map creation function
-	if g.builtinFunctions["alas_runtime_map_create"] == nil {
-		// Declare map creation function
-		mapCreateFunc := g.module.NewFunc("alas_runtime_map_create", types.NewPointer(types.I8))
-		mapCreateFunc.Params = append(mapCreateFunc.Params,
-			ir.NewParam("pairs", types.NewPointer(types.I8)),
-			ir.NewParam("count", types.I64))
-		g.builtinFunctions["alas_runtime_map_create"] = mapCreateFunc
+	pairsPtr := g.builder.NewBitCast(pairsAlloca, types.I8Ptr)
+	if handle, ok := g.scopedMaps[expr]; ok {
+		g.builder.NewCall(g.scopedMapInit(), handle, pairsPtr, constant.NewInt(types.I64, int64(pairCount)))
+		return handle, nil
 	}
-
-	// Cast pairs array to i8* and call runtime function
-	pairsPtr := g.builder.NewBitCast(pairsAlloca, types.NewPointer(types.I8))
-	mapResult := g.builder.NewCall(g.builtinFunctions["alas_runtime_map_create"],
-		pairsPtr, constant.NewInt(types.I64, int64(pairCount)))
-
-	return mapResult, nil
+	mapCreateFunc := g.runtimeMapFunc("alas_runtime_map_create", types.I8Ptr, types.I8Ptr, types.I64)
+	return g.builder.NewCall(mapCreateFunc, pairsPtr, constant.NewInt(types.I64, int64(pairCount))), nil
 }
 
 // generateIndexAccess generates LLVM IR for array/map indexing.
@@ -1000,20 +1711,20 @@ func (g *LLVMCodegen) generateIndexAccess(expr *ast.Expression) (value.Value, er
 		// This is explicitly identified as our array struct
 		// Extract data pointer
 		dataPtr := g.builder.NewExtractValue(obj, 0)
-		dataPtr.SetName("array_data_ptr")
 
-		// Add bounds checking using the length field
-		length := g.builder.NewExtractValue(obj, 1)
-		g.generateBoundsCheck(index, length)
+		// Add bounds checking using the length field, unless the index is
+		// a constant known to be in range
+		if !g.indexInBounds(expr.Object, index) {
+			length := g.builder.NewExtractValue(obj, 1)
+			g.generateBoundsCheck(index, length)
+		}
 
-		// For now, assume the array contains i64 elements (should be determined from context)
-		// Cast i8* back to proper element type pointer
-		elemType := types.I64
+		// Cast i8* back to the element type pointer
+		elemType := g.arrayElementType(expr.Object)
 		typedPtr := g.builder.NewBitCast(dataPtr, types.NewPointer(elemType))
 
 		// Calculate element address
 		elemPtr := g.builder.NewGetElementPtr(elemType, typedPtr, index)
-		elemPtr.SetName("elem_ptr")
 
 		// Load and return element value
 		return g.builder.NewLoad(elemType, elemPtr), nil
@@ -1023,7 +1734,11 @@ func (g *LLVMCodegen) generateIndexAccess(expr *ast.Expression) (value.Value, er
 	if obj.Type().Equal(types.NewPointer(types.I8)) {
 		// This could be a map or string (i8* pointer) - determine which based on context
 		// For now, we'll assume it's a map. String indexing would need runtime type detection
-		return g.generateMapIndexAccess(obj, index)
+		key, err := g.mapKeyCValue(expr.Index, index)
+		if err != nil {
+			return nil, err
+		}
+		return g.generateMapIndexAccess(obj, key)
 	}
 
 	// For other types, return placeholder for now
@@ -1105,14 +1820,29 @@ func (g *LLVMCodegen) generateStructConstruction(expr *ast.Expression, typeName
 
 // inferVariableType tries to infer the ALaS type of a variable from its value expression.
 func (g *LLVMCodegen) inferVariableType(varName string, valueExpr *ast.Expression) {
+	// Containers declared as array<T> or map<K,V> keep their element types
+	if declared := g.declaredTypeOf(valueExpr); ast.ElementType(declared) != "" && g.pointerKindOf(valueExpr) != pointerKindCValue {
+		g.variableTypes[varName] = declared
+		return
+	}
 	switch valueExpr.Type {
 	case ast.ExprCall:
 		// Check if the called function returns a custom type
 		if astFn, ok := g.astFunctions[valueExpr.Name]; ok {
-			if _, isCustomType := g.customTypes[astFn.Returns]; isCustomType {
-				g.variableTypes[varName] = astFn.Returns
+			returns := g.resolveType(astFn.Returns)
+			if _, isCustomType := g.customTypes[returns]; isCustomType {
+				g.variableTypes[varName] = returns
+			} else if returns == ast.TypeMap {
+				g.variableTypes[varName] = DynamicMapType
 			}
 		}
+	case ast.ExprBuiltin, ast.ExprIndex, ast.ExprField:
+		// Map lookups and builtin results are CValue pointers
+		if g.pointerKindOf(valueExpr) == pointerKindCValue {
+			g.variableTypes[varName] = BoxedValueType
+		} else if g.variableTypes[varName] == BoxedValueType {
+			delete(g.variableTypes, varName)
+		}
 	case ast.ExprMapLit:
 		// Try to infer struct type from map literal structure
 		// Look for custom types that match the field pattern
@@ -1145,6 +1875,17 @@ func (g *LLVMCodegen) inferVariableType(varName string, valueExpr *ast.Expressio
 		}
 		// If no perfect match, mark as dynamic map type for field access
 		g.variableTypes[varName] = DynamicMapType
+	case ast.ExprArrayLit:
+		// Elements of a literal are loaded with the type they were stored as
+		if elemType := g.literalElementType(valueExpr); elemType != "" {
+			g.variableTypes[varName] = ast.ContainerType(ast.TypeArray, elemType)
+		} else {
+			delete(g.variableTypes, varName)
+		}
+	default:
+		if g.variableTypes[varName] == BoxedValueType {
+			delete(g.variableTypes, varName)
+		}
 	}
 }
 
@@ -1212,72 +1953,102 @@ func (g *LLVMCodegen) generateFieldAccess(expr *ast.Expression) (value.Value, er
 		return g.generateDynamicFieldAccess(obj, expr.Field)
 	}
 
-	// Check if object is an array struct with dynamic map-like behavior
-	if structType, ok := obj.Type().(*types.StructType); ok && len(structType.Fields) == 2 {
-		// Check if this looks like our map representation (key-value pairs)
-		// For now, implement a simplified lookup that returns a default value
-		// In a full implementation, this would search through the key-value pairs
-		return g.generateMapFieldLookup(obj, expr.Field)
-	}
-
 	return nil, fmt.Errorf("cannot determine type of object for field access on %T", obj.Type())
 }
 
-// generateDynamicFieldAccess generates LLVM IR for dynamic field access on map-like objects.
+// generateDynamicFieldAccess generates LLVM IR for field access on a map,
+// returning the looked-up value as a CValue pointer (void if the field is missing).
 func (g *LLVMCodegen) generateDynamicFieldAccess(mapObj value.Value, fieldName string) (value.Value, error) {
-	// For now, we'll implement a simplified version that assumes the map contains
-	// the field and returns a placeholder value. In a full implementation, this would:
-	// 1. Call a runtime function to lookup the field in the map
-	// 2. Handle type conversion between the stored value and expected type
-	// 3. Return appropriate error handling for missing fields
-
-	// Declare runtime map field access function if not already declared
-	mapGetFieldFunc, exists := g.builtinFunctions["map_get_field"]
-	if !exists {
-		// Create function signature: map_get_field(map i8*, field i8*) -> i8*
-		fieldNameType := types.NewPointer(types.I8) // string
-		mapGetFieldFunc = g.module.NewFunc("alas_runtime_map_get_field", types.NewPointer(types.I8))
-		mapGetFieldFunc.Params = append(mapGetFieldFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)),
-			ir.NewParam("field", fieldNameType))
-		g.builtinFunctions["map_get_field"] = mapGetFieldFunc
-	}
-
-	// Create string literal for field name
+	mapGetFieldFunc := g.runtimeMapFunc("alas_runtime_map_get_field", types.I8Ptr, types.I8Ptr, types.I8Ptr)
 	fieldNameLiteral := g.createStringLiteral(fieldName)
-
-	// Call runtime function to get field value
-	result := g.builder.NewCall(mapGetFieldFunc, mapObj, fieldNameLiteral)
-
-	// For now, assume the result needs to be converted to the expected type
-	// In this simplified implementation, we'll return the raw pointer
-	// and let the caller handle type conversion
-	return result, nil
-}
-
-// generateMapFieldLookup generates LLVM IR for field lookup in map structures.
-func (g *LLVMCodegen) generateMapFieldLookup(mapStruct value.Value, fieldName string) (value.Value, error) {
-	// This is a simplified implementation for map field access
-	// In a real implementation, this would iterate through key-value pairs
-	// and compare keys to find the matching field
-
-	// For now, return a placeholder that indicates the field was "found"
-	// This allows the compilation to proceed while we develop the full implementation
-	return constant.NewInt(types.I64, 42), nil // Placeholder return value
+	return g.builder.NewCall(mapGetFieldFunc, mapObj, fieldNameLiteral), nil
 }
 
 // createStringLiteral creates a string literal constant.
 func (g *LLVMCodegen) createStringLiteral(str string) value.Value {
-	// Create a global string constant
-	charArray := constant.NewCharArrayFromString(str + "\x00")
-	globalStr := g.module.NewGlobalDef("", charArray)
-	globalStr.Immutable = true
+	// Strings are immutable, so every use of the same contents shares one
+	// global constant
+	globalStr, exists := g.stringGlobals[str]
+	if !exists {
+		globalStr = g.module.NewGlobalDef("", constant.NewCharArrayFromString(str+"\x00"))
+		globalStr.Immutable = true
+		g.stringGlobals[str] = globalStr
+	}
 
 	// Return pointer to the first character of the string
-	return g.builder.NewGetElementPtr(charArray.Type(), globalStr,
+	return g.builder.NewGetElementPtr(globalStr.ContentType, globalStr,
 		constant.NewInt(types.I64, 0), constant.NewInt(types.I64, 0))
 }
 
+// arrayElementType returns the LLVM type of the elements of an array
+// expression declared as array<T>, or assigned an array literal whose
+// element type is known. Elements of other arrays are assumed to be i64.
+func (g *LLVMCodegen) arrayElementType(expr *ast.Expression) types.Type {
+	if elemType := ast.ElementType(g.declaredTypeOf(expr)); elemType != "" {
+		if t, err := g.convertType(elemType); err == nil && !t.Equal(types.Void) {
+			return t
+		}
+	}
+	return types.I64
+}
+
+// literalElementType returns the ALaS type of the elements of an array
+// literal, which generateArrayLiteral takes from its first element. It is
+// empty when that type is not known.
+func (g *LLVMCodegen) literalElementType(expr *ast.Expression) string {
+	if len(expr.Elements) == 0 {
+		return ""
+	}
+	first := &expr.Elements[0]
+	if first.Type == ast.ExprArrayLit {
+		if elemType := g.literalElementType(first); elemType != "" {
+			return ast.ContainerType(ast.TypeArray, elemType)
+		}
+		return ast.TypeArray
+	}
+	if declared := g.declaredTypeOf(first); declared != "" {
+		return declared
+	}
+	if val, err := consteval.EvalConst(first); err == nil {
+		switch val.Type {
+		case runtime.ValueTypeInt:
+			return ast.TypeInt
+		case runtime.ValueTypeFloat:
+			return ast.TypeFloat
+		case runtime.ValueTypeString:
+			return ast.TypeString
+		case runtime.ValueTypeBool:
+			return ast.TypeBool
+		}
+	}
+	return ""
+}
+
+// declaredTypeOf returns the ALaS type an expression was declared with: the
+// tracked type of a variable, the return type of a function, or the type of
+// a struct field or array element. It is empty when no type is declared.
+func (g *LLVMCodegen) declaredTypeOf(expr *ast.Expression) string {
+	switch expr.Type {
+	case ast.ExprVariable:
+		return g.variableTypes[expr.Name]
+	case ast.ExprCall:
+		if astFn, ok := g.astFunctions[expr.Name]; ok {
+			return g.resolveType(astFn.Returns)
+		}
+	case ast.ExprField:
+		if typeDef, ok := g.customTypes[g.declaredTypeOf(expr.Object)]; ok {
+			for _, field := range typeDef.Definition.Fields {
+				if field.Name == expr.Field {
+					return g.resolveType(field.Type)
+				}
+			}
+		}
+	case ast.ExprIndex:
+		return g.resolveType(ast.ElementType(g.declaredTypeOf(expr.Object)))
+	}
+	return ""
+}
+
 // isArrayStructType checks if a struct type represents our array structure.
 func (g *LLVMCodegen) isArrayStructType(structType *types.StructType) bool {
 	// Our array struct has exactly 2 fields: {i8* data, i64 length}
@@ -1302,20 +2073,22 @@ func (g *LLVMCodegen) generateBoundsCheck(index, length value.Value) {
 	g.generateBoundsCheckWithError(index, length, "array")
 }
 
-// generateArrayElementAssignment generates LLVM IR for array element assignment.
-func (g *LLVMCodegen) generateArrayElementAssignment(arrayObj, index, value value.Value) error {
+// generateArrayElementAssignment generates LLVM IR for array element
+// assignment to arrayObj, the value of the object expression.
+func (g *LLVMCodegen) generateArrayElementAssignment(object *ast.Expression, arrayObj, index, value value.Value) error {
 	// Check if object is an array struct
 	objType := arrayObj.Type()
 	if structType, ok := objType.(*types.StructType); ok && g.isArrayStructType(structType) {
-		// Extract data pointer and length
+		// Extract data pointer
 		dataPtr := g.builder.NewExtractValue(arrayObj, 0)
-		length := g.builder.NewExtractValue(arrayObj, 1)
 
-		// Bounds check
-		g.generateBoundsCheck(index, length)
+		// Bounds check, unless the index is a constant known to be in range
+		if !g.indexInBounds(object, index) {
+			length := g.builder.NewExtractValue(arrayObj, 1)
+			g.generateBoundsCheck(index, length)
+		}
 
-		// Determine element type - for now assume i64
-		elemType := types.I64
+		elemType := value.Type()
 		typedPtr := g.builder.NewBitCast(dataPtr, types.NewPointer(elemType))
 
 		// Calculate element address and store value
@@ -1328,6 +2101,99 @@ func (g *LLVMCodegen) generateArrayElementAssignment(arrayObj, index, value valu
 	return fmt.Errorf("cannot assign to non-array object")
 }
 
+// generateElementAssignment generates LLVM IR storing val, the value of
+// valueExpr, into the array element, map entry, or struct field named by
+// target. Arrays and maps are updated in place; a struct is rebuilt with the
+// new field and stored back into the variable or element holding it.
+func (g *LLVMCodegen) generateElementAssignment(target, valueExpr *ast.Expression, val value.Value) error {
+	if target.Type != ast.ExprIndex && target.Type != ast.ExprField {
+		return fmt.Errorf("cannot assign to %s expression", target.Type)
+	}
+	obj, err := g.generateExpression(target.Object)
+	if err != nil {
+		return err
+	}
+	if obj.Type().Equal(types.I8Ptr) && g.pointerKindOf(target.Object) == pointerKindCValue {
+		// A map nested in a map is boxed
+		if obj, err = g.convertFromCValue(obj, ast.TypeMap); err != nil {
+			return err
+		}
+	}
+
+	if target.Type == ast.ExprIndex {
+		index, err := g.generateExpression(target.Index)
+		if err != nil {
+			return err
+		}
+		if structType, ok := obj.Type().(*types.StructType); ok && g.isArrayStructType(structType) {
+			return g.generateArrayElementAssignment(target.Object, obj, index, val)
+		}
+		if obj.Type().Equal(types.I8Ptr) {
+			key, err := g.mapKeyCValue(target.Index, index)
+			if err != nil {
+				return err
+			}
+			return g.generateMapElementAssignment(obj, key, g.exprCValue(valueExpr, val))
+		}
+		return fmt.Errorf("cannot index into %s", obj.Type())
+	}
+
+	if obj.Type().Equal(types.I8Ptr) {
+		key := g.newPointerCValue(cvalueTagString, 2, g.createStringLiteral(target.Field))
+		return g.generateMapElementAssignment(obj, key, g.exprCValue(valueExpr, val))
+	}
+	typeName := g.structTypeName(target.Object, obj)
+	fieldIdx, ok := g.fieldIndices[typeName][target.Field]
+	if !ok {
+		return fmt.Errorf("cannot assign to field %s of %s", target.Field, obj.Type())
+	}
+	if fieldIdx < 0 || fieldIdx > 0xFFFFFFFF {
+		return fmt.Errorf("field index out of valid range: %d", fieldIdx)
+	}
+	structType := obj.Type().(*types.StructType)
+	if fieldType := structType.Fields[fieldIdx]; fieldType.Equal(types.Double) && val.Type().Equal(types.I64) {
+		val = g.builder.NewSIToFP(val, types.Double)
+	}
+
+	if ptr := g.structAddress(target.Object); ptr != nil {
+		fieldPtr := g.builder.NewGetElementPtr(structType, ptr,
+			constant.NewInt(types.I32, 0),
+			constant.NewInt(types.I32, int64(fieldIdx)))
+		g.builder.NewStore(val, fieldPtr)
+		return nil
+	}
+	// The struct is held in an array element or map entry: store a copy
+	// with the new field there
+	updated := g.builder.NewInsertValue(obj, val, uint64(fieldIdx))
+	return g.generateElementAssignment(target.Object, nil, updated)
+}
+
+// structAddress returns a pointer to the storage of a struct held in a
+// variable or in a field of such a struct, or nil if it is held elsewhere.
+func (g *LLVMCodegen) structAddress(expr *ast.Expression) value.Value {
+	switch expr.Type {
+	case ast.ExprVariable:
+		return g.variables[expr.Name]
+	case ast.ExprField:
+		ptr := g.structAddress(expr.Object)
+		if ptr == nil {
+			return nil
+		}
+		structType, ok := ptr.Type().(*types.PointerType).ElemType.(*types.StructType)
+		if !ok {
+			return nil
+		}
+		fieldIdx, ok := g.fieldIndices[g.structTypeNameOf(expr.Object, structType)][expr.Field]
+		if !ok {
+			return nil
+		}
+		return g.builder.NewGetElementPtr(structType, ptr,
+			constant.NewInt(types.I32, 0),
+			constant.NewInt(types.I32, int64(fieldIdx)))
+	}
+	return nil
+}
+
 // generateArrayLength generates LLVM IR for getting array length.
 func (g *LLVMCodegen) generateArrayLength(arrayObj value.Value) (value.Value, error) {
 	// Check if object is an array struct
@@ -1385,163 +2251,173 @@ func (g *LLVMCodegen) generateArraySlice(arrayObj, start, end value.Value) (valu
 }
 
 // generateMapIndexAccess generates LLVM IR for map indexing operations.
+// The key is a CValue pointer; the result is a CValue pointer (void if the key is missing).
 func (g *LLVMCodegen) generateMapIndexAccess(mapObj, key value.Value) (value.Value, error) {
-	// Declare runtime map get function if not already declared
-	mapGetFunc, exists := g.builtinFunctions["alas_runtime_map_get"]
-	if !exists {
-		// Create function signature: map_get(map i8*, key i8*) -> i8*
-		mapGetFunc = g.module.NewFunc("alas_runtime_map_get", types.NewPointer(types.I8))
-		mapGetFunc.Params = append(mapGetFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)),
-			ir.NewParam("key", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_get"] = mapGetFunc
-	}
-
-	// Convert key to i8* if needed
-	keyPtr := g.boxToI8Ptr(key, "map_key")
-
-	// Call runtime function to get value
-	result := g.builder.NewCall(mapGetFunc, mapObj, keyPtr)
-
-	// The result is an i8* pointer that may need type conversion
-	// For now, we'll return it as-is and let the caller handle conversion
-	return result, nil
+	mapGetFunc := g.runtimeMapFunc("alas_runtime_map_get", types.I8Ptr, types.I8Ptr, types.I8Ptr)
+	return g.builder.NewCall(mapGetFunc, mapObj, key), nil
 }
 
 // generateMapElementAssignment generates LLVM IR for map element assignment.
 func (g *LLVMCodegen) generateMapElementAssignment(mapObj, key, value value.Value) error {
-	// Declare runtime map put function if not already declared
-	mapPutFunc, exists := g.builtinFunctions["alas_runtime_map_put"]
-	if !exists {
-		// Create function signature: map_put(map i8*, key i8*, value i8*) -> void
-		mapPutFunc = g.module.NewFunc("alas_runtime_map_put", types.Void)
-		mapPutFunc.Params = append(mapPutFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)),
-			ir.NewParam("key", types.NewPointer(types.I8)),
-			ir.NewParam("value", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_put"] = mapPutFunc
-	}
-
-	// Convert key and value to i8* if needed
-	keyPtr := g.boxToI8Ptr(key, "map_key")
-	valuePtr := g.boxToI8Ptr(value, "map_value")
-
-	// Call runtime function to set value
-	g.builder.NewCall(mapPutFunc, mapObj, keyPtr, valuePtr)
-
+	mapPutFunc := g.runtimeMapFunc("alas_runtime_map_put", types.Void, types.I8Ptr, types.I8Ptr, types.I8Ptr)
+	g.builder.NewCall(mapPutFunc, mapObj, key, value)
 	return nil
 }
 
 // generateMapLength generates LLVM IR for getting map length.
 func (g *LLVMCodegen) generateMapLength(mapObj value.Value) (value.Value, error) {
-	// Declare runtime map size function if not already declared
-	mapSizeFunc, exists := g.builtinFunctions["alas_runtime_map_size"]
-	if !exists {
-		// Create function signature: map_size(map i8*) -> i64
-		mapSizeFunc = g.module.NewFunc("alas_runtime_map_size", types.I64)
-		mapSizeFunc.Params = append(mapSizeFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_size"] = mapSizeFunc
-	}
-
-	// Call runtime function to get size
+	mapSizeFunc := g.runtimeMapFunc("alas_runtime_map_size", types.I64, types.I8Ptr)
 	return g.builder.NewCall(mapSizeFunc, mapObj), nil
-}
-
-// generateMapContains generates LLVM IR for checking if map contains a key.
-func (g *LLVMCodegen) generateMapContains(mapObj, key value.Value) (value.Value, error) {
-	// Declare runtime map contains function if not already declared
-	mapContainsFunc, exists := g.builtinFunctions["alas_runtime_map_contains"]
-	if !exists {
-		// Create function signature: map_contains(map i8*, key i8*) -> i1
-		mapContainsFunc = g.module.NewFunc("alas_runtime_map_contains", types.I1)
-		mapContainsFunc.Params = append(mapContainsFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)),
-			ir.NewParam("key", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_contains"] = mapContainsFunc
-	}
-
-	// Convert key to i8* if needed
-	keyPtr := g.boxToI8Ptr(key, "map_key")
+}
 
-	// Call runtime function to check containment
-	return g.builder.NewCall(mapContainsFunc, mapObj, keyPtr), nil
+// generateMapContains generates LLVM IR for checking if map contains a key.
+func (g *LLVMCodegen) generateMapContains(mapObj, key value.Value) (value.Value, error) {
+	mapContainsFunc := g.runtimeMapFunc("alas_runtime_map_contains", types.I1, types.I8Ptr, types.I8Ptr)
+	return g.builder.NewCall(mapContainsFunc, mapObj, key), nil
 }
 
 // generateMapRemove generates LLVM IR for removing a key from map.
 func (g *LLVMCodegen) generateMapRemove(mapObj, key value.Value) error {
-	// Declare runtime map remove function if not already declared
-	mapRemoveFunc, exists := g.builtinFunctions["alas_runtime_map_remove"]
-	if !exists {
-		// Create function signature: map_remove(map i8*, key i8*) -> void
-		mapRemoveFunc = g.module.NewFunc("alas_runtime_map_remove", types.Void)
-		mapRemoveFunc.Params = append(mapRemoveFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)),
-			ir.NewParam("key", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_remove"] = mapRemoveFunc
-	}
-
-	// Convert key to i8* if needed
-	keyPtr := g.boxToI8Ptr(key, "map_key")
-
-	// Call runtime function to remove key
-	g.builder.NewCall(mapRemoveFunc, mapObj, keyPtr)
-
+	mapRemoveFunc := g.runtimeMapFunc("alas_runtime_map_remove", types.Void, types.I8Ptr, types.I8Ptr)
+	g.builder.NewCall(mapRemoveFunc, mapObj, key)
 	return nil
 }
 
 // generateMapKeys generates LLVM IR for getting all keys from a map.
 func (g *LLVMCodegen) generateMapKeys(mapObj value.Value) (value.Value, error) {
-	// Declare runtime map keys function if not already declared
-	mapKeysFunc, exists := g.builtinFunctions["alas_runtime_map_keys"]
-	if !exists {
-		// Create function signature: map_keys(map i8*) -> array struct
-		arrayType, _ := g.convertType(ast.TypeArray)
-		mapKeysFunc = g.module.NewFunc("alas_runtime_map_keys", arrayType)
-		mapKeysFunc.Params = append(mapKeysFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_keys"] = mapKeysFunc
-	}
-
-	// Call runtime function to get keys array
+	arrayType, _ := g.convertType(ast.TypeArray)
+	mapKeysFunc := g.runtimeMapFunc("alas_runtime_map_keys", arrayType, types.I8Ptr)
 	return g.builder.NewCall(mapKeysFunc, mapObj), nil
 }
 
 // generateMapValues generates LLVM IR for getting all values from a map.
 func (g *LLVMCodegen) generateMapValues(mapObj value.Value) (value.Value, error) {
-	// Declare runtime map values function if not already declared
-	mapValuesFunc, exists := g.builtinFunctions["alas_runtime_map_values"]
-	if !exists {
-		// Create function signature: map_values(map i8*) -> array struct
-		arrayType, _ := g.convertType(ast.TypeArray)
-		mapValuesFunc = g.module.NewFunc("alas_runtime_map_values", arrayType)
-		mapValuesFunc.Params = append(mapValuesFunc.Params,
-			ir.NewParam("map", types.NewPointer(types.I8)))
-		g.builtinFunctions["alas_runtime_map_values"] = mapValuesFunc
+	arrayType, _ := g.convertType(ast.TypeArray)
+	mapValuesFunc := g.runtimeMapFunc("alas_runtime_map_values", arrayType, types.I8Ptr)
+	return g.builder.NewCall(mapValuesFunc, mapObj), nil
+}
+
+// generateMapBuiltin generates LLVM IR for the map.* builtins.
+func (g *LLVMCodegen) generateMapBuiltin(expr *ast.Expression) (value.Value, error) {
+	sig, _ := builtins.Lookup(expr.Name)
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return nil, err
 	}
+	expectedArgs := len(sig.Params)
 
-	// Call runtime function to get values array
-	return g.builder.NewCall(mapValuesFunc, mapObj), nil
+	mapObj, err := g.generateExpression(&expr.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	if !mapObj.Type().Equal(types.I8Ptr) || g.pointerKindOf(&expr.Args[0]) != pointerKindMap {
+		return nil, fmt.Errorf("%s: first argument must be a map", expr.Name)
+	}
+
+	var key value.Value
+	if expectedArgs > 1 {
+		keyVal, err := g.generateExpression(&expr.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		if key, err = g.mapKeyCValue(&expr.Args[1], keyVal); err != nil {
+			return nil, err
+		}
+	}
+
+	switch expr.Name {
+	case "map.get":
+		return g.generateMapIndexAccess(mapObj, key)
+	case "map.put":
+		val, err := g.generateExpression(&expr.Args[2])
+		if err != nil {
+			return nil, err
+		}
+		if err := g.generateMapElementAssignment(mapObj, key, g.exprCValue(&expr.Args[2], val)); err != nil {
+			return nil, err
+		}
+		return constant.NewInt(types.I32, 0), nil
+	case "map.contains":
+		return g.generateMapContains(mapObj, key)
+	case "map.remove":
+		if err := g.generateMapRemove(mapObj, key); err != nil {
+			return nil, err
+		}
+		return constant.NewInt(types.I32, 0), nil
+	case "map.size":
+		return g.generateMapLength(mapObj)
+	case "map.keys":
+		return g.generateMapKeys(mapObj)
+	case "map.values":
+		return g.generateMapValues(mapObj)
+	default:
+		return nil, fmt.Errorf("unknown builtin function: %s", expr.Name)
+	}
+}
+
+// runtimeMapFunc returns the declaration of a runtime hash table function,
+// declaring it on first use.
+func (g *LLVMCodegen) runtimeMapFunc(name string, retType types.Type, paramTypes ...types.Type) *ir.Func {
+	if fn, exists := g.builtinFunctions[name]; exists {
+		return fn
+	}
+	params := make([]*ir.Param, len(paramTypes))
+	for i, t := range paramTypes {
+		params[i] = ir.NewParam("", t)
+	}
+	fn := g.module.NewFunc(name, retType, params...)
+	g.builtinFunctions[name] = fn
+	return fn
 }
 
 // generateModuleCall generates LLVM IR for module function calls.
 func (g *LLVMCodegen) generateModuleCall(expr *ast.Expression) (value.Value, error) {
-	// Create qualified function name: module_name__function_name
-	qualifiedName := fmt.Sprintf("%s__%s", expr.Module, expr.Name)
+	// Create qualified function name: module_name__function_name, with an
+	// import alias resolved to the module it names
+	moduleName := expr.Module
+	if imported, ok := g.importAliases[moduleName]; ok {
+		moduleName = imported
+	}
+	qualifiedName := fmt.Sprintf("%s__%s", moduleName, expr.Name)
 
 	// Look up the external function
 	externalFunc, exists := g.externalFunctions[qualifiedName]
 	if !exists {
+		if imported, ok := g.loadedModules[moduleName]; ok {
+			for _, fn := range imported.Functions {
+				if fn.Name == expr.Name && fn.Receiver == nil {
+					return nil, fmt.Errorf("function '%s' is not exported from module '%s'", expr.Name, expr.Module)
+				}
+			}
+		}
 		return nil, fmt.Errorf("external function %s not declared", qualifiedName)
 	}
+	if len(expr.Args) != len(externalFunc.Params) {
+		return nil, fmt.Errorf("function '%s' from module '%s' expects %d arguments, got %d",
+			expr.Name, expr.Module, len(externalFunc.Params), len(expr.Args))
+	}
 
-	// Generate arguments
+	// Generate arguments, converted to the imported parameter types where
+	// the validator allows it
 	args := make([]value.Value, len(expr.Args))
 	for i, arg := range expr.Args {
 		argVal, err := g.generateExpression(&arg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate argument %d for %s: %v", i, qualifiedName, err)
 		}
+		paramType := externalFunc.Params[i].Type()
+		if fn, ok := g.astFunctions[qualifiedName]; ok && i < len(fn.Params) {
+			if fn.Params[i].Type == "any" && !argVal.Type().Equal(types.I8Ptr) {
+				argVal = g.convertToCValue(argVal)
+			}
+			argVal = g.coerceBoxedValue(&arg, argVal, fn.Params[i].Type)
+		}
+		if argVal.Type().Equal(types.I64) && paramType.Equal(types.Double) {
+			argVal = g.builder.NewSIToFP(argVal, types.Double)
+		}
+		if !argVal.Type().Equal(paramType) {
+			return nil, fmt.Errorf("argument %d to function '%s' from module '%s' has type %s, expected %s",
+				i, expr.Name, expr.Module, argVal.Type(), paramType)
+		}
 		args[i] = argVal
 	}
 
@@ -1558,11 +2434,12 @@ func (g *LLVMCodegen) DeclareExternalFunction(moduleName, functionName string, p
 		return existing, nil
 	}
 
-	// Create function signature
-	sig := types.NewFunc(returnType, paramTypes...)
-
 	// Declare the function as external in this module
-	externalFunc := g.module.NewFunc(qualifiedName, sig)
+	params := make([]*ir.Param, len(paramTypes))
+	for i, paramType := range paramTypes {
+		params[i] = ir.NewParam("", paramType)
+	}
+	externalFunc := g.module.NewFunc(qualifiedName, returnType, params...)
 
 	// Store the external function
 	g.externalFunctions[qualifiedName] = externalFunc
@@ -1588,6 +2465,14 @@ func (g *LLVMCodegen) declareGCFunctions() {
 		ir.NewParam("", types.I64))
 	g.gcFunctions["alas_gc_alloc_array"] = arrayAllocFunc
 
+	// Array literal storage: alas_runtime_array_init(handle **i8, size i64) -> *i8
+	// and alas_runtime_array_release(handle **i8) -> void
+	handlePtrType := types.NewPointer(types.I8Ptr)
+	g.gcFunctions["alas_runtime_array_init"] = g.module.NewFunc("alas_runtime_array_init", types.I8Ptr,
+		ir.NewParam("handle", handlePtrType), ir.NewParam("size", types.I64))
+	g.gcFunctions["alas_runtime_array_release"] = g.module.NewFunc("alas_runtime_array_release", types.Void,
+		ir.NewParam("handle", handlePtrType))
+
 	// Map allocation: alas_gc_alloc_map(pairs *MapPair, count i64) -> *GCObject
 	mapAllocFunc := g.module.NewFunc("alas_gc_alloc_map", gcObjectPtrType)
 	mapAllocFunc.Params = append(mapAllocFunc.Params,
@@ -1656,6 +2541,15 @@ func (g *LLVMCodegen) declareErrorHandlingFunctions() {
 		ir.NewParam("line", types.I32))
 	g.builtinFunctions["alas_runtime_assert"] = assertFunc
 
+	// Logging: alas_runtime_log(level i32, function *i8, message *CValue, fields *CValue) -> void
+	logFunc := g.module.NewFunc("alas_runtime_log", types.Void)
+	logFunc.Params = append(logFunc.Params,
+		ir.NewParam("level", types.I32),
+		ir.NewParam("function", stringPtrType),
+		ir.NewParam("message", stringPtrType),
+		ir.NewParam("fields", stringPtrType))
+	g.builtinFunctions["alas_runtime_log"] = logFunc
+
 	// Division by zero check: alas_runtime_check_div_zero(divisor i64, file *i8, line i32) -> void
 	checkDivZeroFunc := g.module.NewFunc("alas_runtime_check_div_zero", types.Void)
 	checkDivZeroFunc.Params = append(checkDivZeroFunc.Params,
@@ -1682,8 +2576,80 @@ func (g *LLVMCodegen) declareErrorHandlingFunctions() {
 	g.builtinFunctions["alas_runtime_check_null"] = checkNullFunc
 }
 
+// DisableSafetyChecks makes GenerateModule leave out the runtime division by
+// zero, array bounds and null pointer checks. This is unsafe: dividing by
+// zero or indexing outside an array is undefined behavior instead of a
+// runtime error. Assertions and overflow checks are kept. It must be called
+// before GenerateModule.
+func (g *LLVMCodegen) DisableSafetyChecks() {
+	g.omitSafetyChecks = true
+}
+
+// DefaultHeapArrayThreshold is the largest number of elements an array
+// literal may have and still be allocated on the stack.
+const DefaultHeapArrayThreshold = 1024
+
+// SetHeapArrayThreshold sets the largest number of elements an array literal
+// may have and still be allocated on the stack; larger literals are allocated
+// on the heap by alas_runtime_array_init and freed when their function
+// returns. It must be called before GenerateModule.
+func (g *LLVMCodegen) SetHeapArrayThreshold(elements int) {
+	g.heapArrayThreshold = elements
+}
+
+// allocateHeapArrays reserves a handle in the entry block of the current
+// function for each array literal of fn above the heap array threshold. A
+// literal's heap block is allocated the first time it runs, reused when it
+// runs again, as in a loop, and freed by releaseHeapArrays before the
+// function returns.
+func (g *LLVMCodegen) allocateHeapArrays(fn *ast.Function) {
+	g.heapArrays = make(map[*ast.Expression]value.Value)
+	g.heapArrayOrder = nil
+	ast.WalkStatements(fn.Body, nil, func(expr *ast.Expression) {
+		if expr.Type != ast.ExprArrayLit || len(expr.Elements) <= g.heapArrayThreshold {
+			return
+		}
+		handle := g.builder.NewAlloca(types.I8Ptr)
+		handle.SetName("array_handle")
+		g.builder.NewStore(constant.NewNull(types.I8Ptr), handle)
+		g.heapArrays[expr] = handle
+		g.heapArrayOrder = append(g.heapArrayOrder, handle)
+	})
+}
+
+// releaseHeapArrays frees the heap blocks held in the current function's
+// array handles. Releasing a handle whose literal never ran does nothing.
+func (g *LLVMCodegen) releaseHeapArrays() {
+	for _, handle := range g.heapArrayOrder {
+		g.builder.NewCall(g.gcFunctions["alas_runtime_array_release"], handle)
+	}
+}
+
+// allocateHeapArray returns the heap storage of arrayType held in handle,
+// which is allocated zeroed the first time.
+func (g *LLVMCodegen) allocateHeapArray(arrayType *types.ArrayType, handle value.Value) value.Value {
+	// The size of arrayType in bytes is the address of the element after
+	// one at address null
+	size := constant.NewPtrToInt(constant.NewGetElementPtr(arrayType,
+		constant.NewNull(types.NewPointer(arrayType)), constant.NewInt(types.I32, 1)), types.I64)
+	block := g.builder.NewCall(g.gcFunctions["alas_runtime_array_init"], handle, size)
+	return g.builder.NewBitCast(block, types.NewPointer(arrayType))
+}
+
+// entryAlloca allocates a stack slot of typ at the start of the current
+// function, so that code run repeatedly, as in a loop, reuses the slot
+// instead of growing the frame.
+func (g *LLVMCodegen) entryAlloca(typ types.Type) *ir.InstAlloca {
+	alloca := ir.NewAlloca(typ)
+	g.entryBlock.Insts = append([]ir.Instruction{alloca}, g.entryBlock.Insts...)
+	return alloca
+}
+
 // generateDivisionByZeroCheck generates runtime division by zero checking.
 func (g *LLVMCodegen) generateDivisionByZeroCheck(divisor value.Value) {
+	if g.omitSafetyChecks {
+		return
+	}
 	// Get the division by zero check function
 	checkFunc, exists := g.builtinFunctions["alas_runtime_check_div_zero"]
 	if !exists {
@@ -1709,9 +2675,8 @@ func (g *LLVMCodegen) generateDivisionByZeroCheck(divisor value.Value) {
 		return
 	}
 
-	// Create filename and line number literals (for now use placeholder values)
-	fileName := g.createStringLiteral("unknown.alas")
-	lineNumber := constant.NewInt(types.I32, 0)
+	// Create filename and line number literals for the current source location
+	fileName, lineNumber := g.locationArgs()
 
 	// Call the runtime check function
 	call := g.builder.NewCall(checkFunc, divisorI64, fileName, lineNumber)
@@ -1720,6 +2685,9 @@ func (g *LLVMCodegen) generateDivisionByZeroCheck(divisor value.Value) {
 
 // generateBoundsCheckWithError generates enhanced bounds checking with error reporting.
 func (g *LLVMCodegen) generateBoundsCheckWithError(index, length value.Value, arrayName string) {
+	if g.omitSafetyChecks {
+		return
+	}
 	// Get the bounds check function
 	checkFunc, exists := g.builtinFunctions["alas_runtime_check_bounds"]
 	if !exists {
@@ -1745,9 +2713,8 @@ func (g *LLVMCodegen) generateBoundsCheckWithError(index, length value.Value, ar
 		return
 	}
 
-	// Create filename and line number literals
-	fileName := g.createStringLiteral("unknown.alas")
-	lineNumber := constant.NewInt(types.I32, 0)
+	// Create filename and line number literals for the current source location
+	fileName, lineNumber := g.locationArgs()
 
 	// Call the runtime check function
 	g.builder.NewCall(checkFunc, indexI64, lengthI64, fileName, lineNumber)
@@ -1755,6 +2722,9 @@ func (g *LLVMCodegen) generateBoundsCheckWithError(index, length value.Value, ar
 
 // generateNullPointerCheck generates null pointer checking.
 func (g *LLVMCodegen) generateNullPointerCheck(ptr value.Value, context string) {
+	if g.omitSafetyChecks {
+		return
+	}
 	// Get the null check function
 	checkFunc, exists := g.builtinFunctions["alas_runtime_check_null"]
 	if !exists {
@@ -1766,9 +2736,8 @@ func (g *LLVMCodegen) generateNullPointerCheck(ptr value.Value, context string)
 		return
 	}
 
-	// Create filename and line number literals
-	fileName := g.createStringLiteral("unknown.alas")
-	lineNumber := constant.NewInt(types.I32, 0)
+	// Create filename and line number literals for the current source location
+	fileName, lineNumber := g.locationArgs()
 
 	// Call the runtime check function
 	g.builder.NewCall(checkFunc, ptr, fileName, lineNumber)
@@ -1785,14 +2754,77 @@ func (g *LLVMCodegen) generateAssert(condition value.Value, message string) {
 	// Create message literal
 	messageLiteral := g.createStringLiteral(message)
 
-	// Create filename and line number literals
-	fileName := g.createStringLiteral("unknown.alas")
-	lineNumber := constant.NewInt(types.I32, 0)
+	// Create filename and line number literals for the current source location
+	fileName, lineNumber := g.locationArgs()
 
 	// Call the runtime assert function
 	g.builder.NewCall(assertFunc, condition, messageLiteral, fileName, lineNumber)
 }
 
+// generateBuiltinFailureCheck reports a runtime error with the given message
+// when a fallible builtin returns a null CValue.
+func (g *LLVMCodegen) generateBuiltinFailureCheck(result value.Value, message string) {
+	ptrType, isPtr := result.Type().(*types.PointerType)
+	if !isPtr {
+		return
+	}
+	isNull := g.builder.NewICmp(enum.IPredEQ, result, constant.NewNull(ptrType))
+	g.generateRuntimeErrorIf(isNull, "builtin", message)
+}
+
+// generateRuntimeErrorIf reports a runtime error with the given message when
+// failed is true. Generation continues in the block taken otherwise; label
+// names the blocks of the check.
+func (g *LLVMCodegen) generateRuntimeErrorIf(failed value.Value, label, message string) {
+	errorFunc, exists := g.builtinFunctions["alas_runtime_error"]
+	if !exists {
+		return // Function not declared, skip check
+	}
+
+	// Suffix the labels so several checks in one function stay distinct
+	currentFunc := g.builder.Parent
+	suffix := len(currentFunc.Blocks)
+	failBlock := currentFunc.NewBlock(fmt.Sprintf("%s.fail.%d", label, suffix))
+	okBlock := currentFunc.NewBlock(fmt.Sprintf("%s.ok.%d", label, suffix))
+	g.builder.NewCondBr(failed, failBlock, okBlock)
+
+	// The runtime error handler does not return
+	g.builder = failBlock
+	fileName, lineNumber := g.locationArgs()
+	g.builder.NewCall(errorFunc, g.createStringLiteral(message), fileName, lineNumber, constant.NewInt(types.I32, 0))
+	g.builder.NewUnreachable()
+
+	g.builder = okBlock
+}
+
+// enterLocation makes the given source location current for runtime error
+// reporting and returns a function that restores the previous location.
+// Nodes without a line number keep the enclosing node's location.
+func (g *LLVMCodegen) enterLocation(file string, line int) func() {
+	if line <= 0 {
+		return func() {}
+	}
+	g.attachDebugLocations()
+	prevFile, prevLine := g.currentFile, g.currentLine
+	if file != "" {
+		g.currentFile = file
+	}
+	g.currentLine = line
+	return func() {
+		g.attachDebugLocations()
+		g.currentFile, g.currentLine = prevFile, prevLine
+	}
+}
+
+// locationArgs returns the file name and line literals passed to runtime check functions.
+func (g *LLVMCodegen) locationArgs() (value.Value, value.Value) {
+	file := g.currentFile
+	if file == "" {
+		file = "unknown.alas"
+	}
+	return g.createStringLiteral(file), constant.NewInt(types.I32, int64(g.currentLine))
+}
+
 // declareBuiltinFunctions declares external builtin standard library functions.
 func (g *LLVMCodegen) declareBuiltinFunctions() {
 	// For C compatibility, use simple i8* (void*) for CValue parameters
@@ -1809,6 +2841,28 @@ func (g *LLVMCodegen) declareBuiltinFunctions() {
 	printFunc.Params = append(printFunc.Params, ir.NewParam("", cvalueArgType))
 	g.builtinFunctions["io.print"] = printFunc
 
+	// void alas_builtin_io_debug(void* val)
+	debugFunc := g.module.NewFunc("alas_builtin_io_debug", types.Void)
+	debugFunc.Params = append(debugFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["io.debug"] = debugFunc
+
+	// Environment functions
+	// void* alas_builtin_os_getenv(void* name)
+	getenvFunc := g.module.NewFunc("alas_builtin_os_getenv", cvalueReturnType)
+	getenvFunc.Params = append(getenvFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["os.getenv"] = getenvFunc
+
+	// void alas_builtin_os_setenv(void* name, void* value)
+	setenvFunc := g.module.NewFunc("alas_builtin_os_setenv", types.Void)
+	setenvFunc.Params = append(setenvFunc.Params, ir.NewParam("", cvalueArgType))
+	setenvFunc.Params = append(setenvFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["os.setenv"] = setenvFunc
+
+	// void exit(int status), called directly from libc
+	exitFunc := g.module.NewFunc("exit", types.Void, ir.NewParam("status", types.I32))
+	exitFunc.FuncAttrs = append(exitFunc.FuncAttrs, enum.FuncAttrNoReturn)
+	g.builtinFunctions["os.exit"] = exitFunc
+
 	// Math functions
 	// void* alas_builtin_math_sqrt(void* val) - simplified for C compatibility
 	sqrtFunc := g.module.NewFunc("alas_builtin_math_sqrt", cvalueReturnType)
@@ -1830,6 +2884,23 @@ func (g *LLVMCodegen) declareBuiltinFunctions() {
 	minFunc.Params = append(minFunc.Params, ir.NewParam("", cvalueArgType))
 	g.builtinFunctions["math.min"] = minFunc
 
+	// math.pow promotes integer arguments to float like mixed binary operations
+	powFunc := g.module.NewFunc("alas_builtin_math_pow", cvalueReturnType)
+	powFunc.Params = append(powFunc.Params, ir.NewParam("", cvalueArgType))
+	powFunc.Params = append(powFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["math.pow"] = powFunc
+
+	// Single-argument rounding, trigonometric and logarithm functions
+	for _, name := range []string{"floor", "ceil", "round", "sin", "cos", "log"} {
+		fn := g.module.NewFunc("alas_builtin_math_"+name, cvalueReturnType)
+		fn.Params = append(fn.Params, ir.NewParam("", cvalueArgType))
+		g.builtinFunctions["math."+name] = fn
+	}
+
+	// void* alas_builtin_math_pi()
+	piFunc := g.module.NewFunc("alas_builtin_math_pi", cvalueReturnType)
+	g.builtinFunctions["math.pi"] = piFunc
+
 	// Collections functions
 	// void* alas_builtin_collections_length(void* val)
 	lengthFunc := g.module.NewFunc("alas_builtin_collections_length", cvalueReturnType)
@@ -1866,46 +2937,53 @@ func (g *LLVMCodegen) declareBuiltinFunctions() {
 	arraySliceFunc.Params = append(arraySliceFunc.Params, ir.NewParam("", cvalueArgType))
 	g.builtinFunctions["array.slice"] = arraySliceFunc
 
-	// Map functions
-	// void* alas_builtin_map_get(void* map, void* key)
-	mapGetBuiltinFunc := g.module.NewFunc("alas_builtin_map_get", cvalueReturnType)
-	mapGetBuiltinFunc.Params = append(mapGetBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	mapGetBuiltinFunc.Params = append(mapGetBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.get"] = mapGetBuiltinFunc
-
-	// void alas_builtin_map_put(void* map, void* key, void* value)
-	mapPutBuiltinFunc := g.module.NewFunc("alas_builtin_map_put", types.Void)
-	mapPutBuiltinFunc.Params = append(mapPutBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	mapPutBuiltinFunc.Params = append(mapPutBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	mapPutBuiltinFunc.Params = append(mapPutBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.put"] = mapPutBuiltinFunc
-
-	// void* alas_builtin_map_size(void* map)
-	mapSizeBuiltinFunc := g.module.NewFunc("alas_builtin_map_size", cvalueReturnType)
-	mapSizeBuiltinFunc.Params = append(mapSizeBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.size"] = mapSizeBuiltinFunc
-
-	// void* alas_builtin_map_contains(void* map, void* key)
-	mapContainsBuiltinFunc := g.module.NewFunc("alas_builtin_map_contains", cvalueReturnType)
-	mapContainsBuiltinFunc.Params = append(mapContainsBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	mapContainsBuiltinFunc.Params = append(mapContainsBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.contains"] = mapContainsBuiltinFunc
-
-	// void alas_builtin_map_remove(void* map, void* key)
-	mapRemoveBuiltinFunc := g.module.NewFunc("alas_builtin_map_remove", types.Void)
-	mapRemoveBuiltinFunc.Params = append(mapRemoveBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	mapRemoveBuiltinFunc.Params = append(mapRemoveBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.remove"] = mapRemoveBuiltinFunc
-
-	// void* alas_builtin_map_keys(void* map)
-	mapKeysBuiltinFunc := g.module.NewFunc("alas_builtin_map_keys", cvalueReturnType)
-	mapKeysBuiltinFunc.Params = append(mapKeysBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.keys"] = mapKeysBuiltinFunc
-
-	// void* alas_builtin_map_values(void* map)
-	mapValuesBuiltinFunc := g.module.NewFunc("alas_builtin_map_values", cvalueReturnType)
-	mapValuesBuiltinFunc.Params = append(mapValuesBuiltinFunc.Params, ir.NewParam("", cvalueArgType))
-	g.builtinFunctions["map.values"] = mapValuesBuiltinFunc
+	// void* alas_builtin_array_map(void* array, void* fn)
+	arrayMapFunc := g.module.NewFunc("alas_builtin_array_map", cvalueReturnType)
+	arrayMapFunc.Params = append(arrayMapFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayMapFunc.Params = append(arrayMapFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.map"] = arrayMapFunc
+
+	// void* alas_builtin_array_filter(void* array, void* fn)
+	arrayFilterFunc := g.module.NewFunc("alas_builtin_array_filter", cvalueReturnType)
+	arrayFilterFunc.Params = append(arrayFilterFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayFilterFunc.Params = append(arrayFilterFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.filter"] = arrayFilterFunc
+
+	// void* alas_builtin_array_reduce(void* array, void* fn, void* init)
+	arrayReduceFunc := g.module.NewFunc("alas_builtin_array_reduce", cvalueReturnType)
+	arrayReduceFunc.Params = append(arrayReduceFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayReduceFunc.Params = append(arrayReduceFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayReduceFunc.Params = append(arrayReduceFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.reduce"] = arrayReduceFunc
+
+	// void* alas_builtin_array_sort(void* array, void* comparator) - comparator may be NULL
+	arraySortFunc := g.module.NewFunc("alas_builtin_array_sort", cvalueReturnType)
+	arraySortFunc.Params = append(arraySortFunc.Params, ir.NewParam("", cvalueArgType))
+	arraySortFunc.Params = append(arraySortFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.sort"] = arraySortFunc
+
+	// void* alas_builtin_array_reverse(void* array)
+	arrayReverseFunc := g.module.NewFunc("alas_builtin_array_reverse", cvalueReturnType)
+	arrayReverseFunc.Params = append(arrayReverseFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.reverse"] = arrayReverseFunc
+
+	// void* alas_builtin_array_indexOf(void* array, void* item)
+	arrayIndexOfFunc := g.module.NewFunc("alas_builtin_array_indexOf", cvalueReturnType)
+	arrayIndexOfFunc.Params = append(arrayIndexOfFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayIndexOfFunc.Params = append(arrayIndexOfFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.indexOf"] = arrayIndexOfFunc
+
+	// void* alas_builtin_array_contains(void* array, void* item)
+	arrayContainsFunc := g.module.NewFunc("alas_builtin_array_contains", cvalueReturnType)
+	arrayContainsFunc.Params = append(arrayContainsFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayContainsFunc.Params = append(arrayContainsFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.contains"] = arrayContainsFunc
+
+	// void* alas_builtin_array_concat(void* a, void* b)
+	arrayConcatFunc := g.module.NewFunc("alas_builtin_array_concat", cvalueReturnType)
+	arrayConcatFunc.Params = append(arrayConcatFunc.Params, ir.NewParam("", cvalueArgType))
+	arrayConcatFunc.Params = append(arrayConcatFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["array.concat"] = arrayConcatFunc
 
 	// String functions
 	// void* alas_builtin_string_toUpper(void* val)
@@ -2036,151 +3114,214 @@ func (g *LLVMCodegen) declareBuiltinFunctions() {
 	isIntFunc.Params = append(isIntFunc.Params, ir.NewParam("", cvalueArgType))
 	g.builtinFunctions["type.isInt"] = isIntFunc
 
+	toStringFunc := g.module.NewFunc("alas_builtin_type_toString", cvalueReturnType)
+	toStringFunc.Params = append(toStringFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["type.toString"] = toStringFunc
+
+	// Parse functions return NULL when the string is not a valid number
+	parseIntFunc := g.module.NewFunc("alas_builtin_type_parseInt", cvalueReturnType)
+	parseIntFunc.Params = append(parseIntFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["type.parseInt"] = parseIntFunc
+
+	parseFloatFunc := g.module.NewFunc("alas_builtin_type_parseFloat", cvalueReturnType)
+	parseFloatFunc.Params = append(parseFloatFunc.Params, ir.NewParam("", cvalueArgType))
+	g.builtinFunctions["type.parseFloat"] = parseFloatFunc
+
 	// TODO: Add more builtin functions as needed
 }
 
+// builtinResultTypes gives the ALaS result type of builtins whose results are
+// unboxed from their CValue. Builtins not listed here return the CValue pointer.
+var builtinResultTypes = map[string]string{
+	"os.getenv":            ast.TypeString,
+	"math.sqrt":            ast.TypeFloat,
+	"math.abs":             ast.TypeFloat,
+	"math.max":             ast.TypeFloat,
+	"math.min":             ast.TypeFloat,
+	"math.pow":             ast.TypeFloat,
+	"math.floor":           ast.TypeFloat,
+	"math.ceil":            ast.TypeFloat,
+	"math.round":           ast.TypeFloat,
+	"math.sin":             ast.TypeFloat,
+	"math.cos":             ast.TypeFloat,
+	"math.log":             ast.TypeFloat,
+	"math.pi":              ast.TypeFloat,
+	"collections.length":   ast.TypeInt,
+	"collections.contains": ast.TypeBool,
+	"array.length":         ast.TypeInt,
+	"array.indexOf":        ast.TypeInt,
+	"array.contains":       ast.TypeBool,
+	"string.toUpper":       ast.TypeString,
+	"string.toLower":       ast.TypeString,
+	"string.length":        ast.TypeInt,
+	"string.substring":     ast.TypeString,
+	"string.indexOf":       ast.TypeInt,
+	"string.join":          ast.TypeString,
+	"string.replace":       ast.TypeString,
+	"string.trim":          ast.TypeString,
+	"string.startsWith":    ast.TypeBool,
+	"string.endsWith":      ast.TypeBool,
+	"string.format":        ast.TypeString,
+	"string.charAt":        ast.TypeString,
+	"string.charCodeAt":    ast.TypeInt,
+	"string.fromCharCode":  ast.TypeString,
+	"string.repeat":        ast.TypeString,
+	"string.padStart":      ast.TypeString,
+	"string.padEnd":        ast.TypeString,
+	"string.contains":      ast.TypeBool,
+	"string.concat":        ast.TypeString,
+	"type.typeOf":          ast.TypeString,
+	"type.isInt":           ast.TypeBool,
+	"type.toString":        ast.TypeString,
+	"type.parseInt":        ast.TypeInt,
+	"type.parseFloat":      ast.TypeFloat,
+	"runtime.version":      ast.TypeString,
+	"runtime.hasBuiltin":   ast.TypeBool,
+}
+
+// fallibleBuiltins lists builtins whose runtime implementation returns a null
+// CValue on failure, which compiled code reports as a runtime error.
+var fallibleBuiltins = map[string]string{
+	"type.parseInt":   "type.parseInt: cannot parse string as integer",
+	"type.parseFloat": "type.parseFloat: cannot parse string as float",
+	"math.log":        "math.log: logarithm of non-positive number",
+}
+
 // generateBuiltinCall generates LLVM IR for builtin function calls.
 func (g *LLVMCodegen) generateBuiltinCall(expr *ast.Expression) (value.Value, error) {
+	// Map builtins are backed by the runtime hash table
+	switch expr.Name {
+	case "map.get", "map.put", "map.contains", "map.remove", "map.size", "map.keys", "map.values":
+		return g.generateMapBuiltin(expr)
+	case "os.exit":
+		return g.generateExit(expr)
+	case "runtime.version", "runtime.hasBuiltin":
+		return g.generateRuntimeBuiltin(expr)
+	case "log.debug", "log.info", "log.warn", "log.error":
+		return g.generateLog(expr)
+	}
+
 	// Look up the builtin function
 	builtinFunc, exists := g.builtinFunctions[expr.Name]
 	if !exists {
 		return nil, fmt.Errorf("unknown builtin function: %s", expr.Name)
 	}
 
-	// For now, we'll handle a simplified case with single arguments
-	// A full implementation would handle multiple arguments and complex types
-
-	if expr.Name == "io.print" {
-		// Special case for io.print which returns void
-		if len(expr.Args) != 1 {
-			return nil, fmt.Errorf("io.print expects 1 argument, got %d", len(expr.Args))
-		}
+	// The signature table is shared with the validator; the declaration's
+	// parameter count covers builtins only the compiled runtime provides
+	sig, ok := builtins.Lookup(expr.Name)
+	if !ok {
+		sig = builtins.Signature{Params: make([]string, len(builtinFunc.Params))}
+	}
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return nil, err
+	}
+	expectedArgs := len(builtinFunc.Params)
 
-		// Generate the argument
-		argVal, err := g.generateExpression(&expr.Args[0])
+	// Generate the arguments and convert them to CValues
+	args := make([]value.Value, 0, expectedArgs)
+	for i := range expr.Args {
+		argVal, err := g.generateExpression(&expr.Args[i])
 		if err != nil {
 			return nil, err
 		}
+		args = append(args, g.exprCValue(&expr.Args[i], argVal))
+	}
+	// Omitted optional arguments are passed as NULL CValues
+	for i := len(args); i < expectedArgs; i++ {
+		args = append(args, constant.NewNull(builtinFunc.Params[i].Typ.(*types.PointerType)))
+	}
 
-		// Convert to CValue
-		cval := g.convertToCValue(argVal)
-
-		// Call the function
-		g.builder.NewCall(builtinFunc, cval)
-		// Return a dummy value for void functions
+	result := g.builder.NewCall(builtinFunc, args...)
+	if builtinFunc.Sig.RetType.Equal(types.Void) {
+		// Return a dummy value for void functions such as io.print
 		return constant.NewInt(types.I32, 0), nil
 	}
-
-	// Handle functions that take multiple arguments (2 args)
-	if expr.Name == "math.max" || expr.Name == "math.min" || expr.Name == "collections.contains" ||
-		expr.Name == "array.push" || expr.Name == "map.get" || expr.Name == "map.contains" ||
-		expr.Name == "map.remove" || expr.Name == "string.indexOf" || expr.Name == "string.split" ||
-		expr.Name == "string.join" || expr.Name == "string.startsWith" || expr.Name == "string.endsWith" ||
-		expr.Name == "string.format" || expr.Name == "string.charAt" || expr.Name == "string.charCodeAt" ||
-		expr.Name == "string.repeat" || expr.Name == "string.contains" || expr.Name == "string.concat" {
-		// These functions take 2 arguments
-		expectedArgs := 2
-		if len(expr.Args) != expectedArgs {
-			return nil, fmt.Errorf("%s expects %d arguments, got %d", expr.Name, expectedArgs, len(expr.Args))
-		}
-
-		// Generate and convert both arguments
-		var args []value.Value
-		for i := 0; i < expectedArgs; i++ {
-			argVal, err := g.generateExpression(&expr.Args[i])
-			if err != nil {
-				return nil, err
-			}
-			args = append(args, g.convertToCValue(argVal))
-		}
-
-		// Call the function with both arguments
-		result := g.builder.NewCall(builtinFunc, args...)
-
-		// Convert result from CValue
-		return g.convertFromCValue(result)
+	if message, ok := fallibleBuiltins[expr.Name]; ok {
+		g.generateBuiltinFailureCheck(result, message)
 	}
 
-	// Handle functions that take three arguments
-	if expr.Name == "array.slice" || expr.Name == "map.put" || expr.Name == "string.substring" ||
-		expr.Name == "string.replace" || expr.Name == "string.padStart" || expr.Name == "string.padEnd" {
-		// These functions take 3 arguments
-		expectedArgs := 3
-		if len(expr.Args) != expectedArgs {
-			return nil, fmt.Errorf("%s expects %d arguments, got %d", expr.Name, expectedArgs, len(expr.Args))
-		}
-
-		// Generate and convert all arguments
-		var args []value.Value
-		for i := 0; i < expectedArgs; i++ {
-			argVal, err := g.generateExpression(&expr.Args[i])
-			if err != nil {
-				return nil, err
-			}
-			args = append(args, g.convertToCValue(argVal))
-		}
-
-		// Call the function with all arguments
-		result := g.builder.NewCall(builtinFunc, args...)
-
-		// Convert result from CValue
-		return g.convertFromCValue(result)
-	}
+	// Unbox results of known type; others stay as CValue pointers for reuse
+	return g.convertFromCValue(result, builtinResultTypes[expr.Name])
+}
 
-	// For functions that return values with single argument
-	if len(expr.Args) != 1 {
-		return nil, fmt.Errorf("%s expects 1 argument, got %d", expr.Name, len(expr.Args))
+// generateExit generates os.exit as a call to libc exit with the code
+// truncated to the C int status.
+func (g *LLVMCodegen) generateExit(expr *ast.Expression) (value.Value, error) {
+	sig, _ := builtins.Lookup(expr.Name)
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return nil, err
 	}
-
-	// Generate the argument
-	argVal, err := g.generateExpression(&expr.Args[0])
+	code, err := g.generateExpression(&expr.Args[0])
 	if err != nil {
 		return nil, err
 	}
+	if !code.Type().Equal(types.I64) {
+		return nil, fmt.Errorf("os.exit expects an int exit code")
+	}
+	g.builder.NewCall(g.builtinFunctions[expr.Name], g.builder.NewTrunc(code, types.I32))
+	// Return a dummy value as for other void builtins
+	return constant.NewInt(types.I32, 0), nil
+}
 
-	// Convert to CValue - check if it's already a CValue* (i8*)
-	var cval value.Value
+// generateLog generates a log builtin as a call to alas_runtime_log, which
+// writes every level to stderr, with the name of the calling function.
+func (g *LLVMCodegen) generateLog(expr *ast.Expression) (value.Value, error) {
+	sig, _ := builtins.Lookup(expr.Name)
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return nil, err
+	}
+	levels := map[string]int64{"log.debug": 0, "log.info": 1, "log.warn": 2, "log.error": 3}
 
-	// Check if this is already an i8* (CValue pointer)
-	if ptrType, isPtr := argVal.Type().(*types.PointerType); isPtr {
-		if ptrType.ElemType.Equal(types.I8) {
-			// Already a CValue* (i8*), use directly
-			cval = argVal
-		} else {
-			// Other pointer type, convert to CValue
-			cval = g.convertToCValue(argVal)
+	args := []value.Value{constant.NewInt(types.I32, levels[expr.Name])}
+	function := ""
+	if g.currentFunction != nil {
+		function = g.currentFunction.Name
+	}
+	args = append(args, g.createStringLiteral(function))
+	for i := range expr.Args {
+		argVal, err := g.generateExpression(&expr.Args[i])
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		// Not a pointer type, convert to CValue
-		cval = g.convertToCValue(argVal)
+		args = append(args, g.exprCValue(&expr.Args[i], argVal))
 	}
-
-	// Call the function and get result
-	result := g.builder.NewCall(builtinFunc, cval)
-
-	// Known issue: The LLVM Go library has a type handling issue where
-	// NewCall().Type() returns *types.FuncType instead of the expected return type
-	// However, the generated LLVM IR is correct and shows proper function calls
-	// For now, we work around this by using a context-aware placeholder
-
-	// For functions that return values, return the raw CValue* so it can be reused
-	// For io.print (void), we don't need to return anything meaningful
-	if expr.Name == "io.print" {
-		return constant.NewInt(types.I32, 0), nil // Dummy return for void functions
+	if len(expr.Args) == 1 {
+		args = append(args, constant.NewNull(types.I8Ptr))
 	}
+	g.builder.NewCall(g.builtinFunctions["alas_runtime_log"], args...)
+	// Return a dummy value as for other void builtins
+	return constant.NewInt(types.I32, 0), nil
+}
 
-	// The LLVM Go library has a type issue where result.Type() returns the wrong type
-	// But the actual LLVM IR is correct (i8*). We need to cast to the correct type.
-
-	// Cast the result to i8* to fix the type issue
-	if _, isFuncType := result.Type().(*types.FuncType); isFuncType {
-		// The LLVM IR is correct but the Go type is wrong, create a bitcast to fix it
-		correctResult := g.builder.NewBitCast(result, types.NewPointer(types.I8))
-		return correctResult, nil
+// generateRuntimeBuiltin generates runtime.version and runtime.hasBuiltin as
+// constants, since compiled code can only call the builtins it was compiled
+// with. The builtin name passed to runtime.hasBuiltin must be a literal.
+func (g *LLVMCodegen) generateRuntimeBuiltin(expr *ast.Expression) (value.Value, error) {
+	sig, _ := builtins.Lookup(expr.Name)
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return nil, err
+	}
+	if expr.Name == "runtime.version" {
+		return g.createStringLiteral(runtime.Version), nil
+	}
+	name, ok := expr.Args[0].LiteralValue().(string)
+	if expr.Args[0].Type != ast.ExprLiteral || !ok {
+		return nil, fmt.Errorf("runtime.hasBuiltin expects a string literal in compiled code")
 	}
+	return constant.NewBool(g.hasCompiledBuiltin(name)), nil
+}
 
-	// Return the raw CValue* result for reuse in variables and other calls
-	return result, nil
+// hasCompiledBuiltin reports whether compiled code can call the builtin name.
+func (g *LLVMCodegen) hasCompiledBuiltin(name string) bool {
+	switch name {
+	case "map.get", "map.put", "map.contains", "map.remove", "map.size", "map.keys", "map.values",
+		"runtime.version", "runtime.hasBuiltin", "log.debug", "log.info", "log.warn", "log.error":
+		return true
+	}
+	// Runtime support functions are declared alongside the builtins
+	_, ok := g.builtinFunctions[name]
+	return ok && strings.Contains(name, ".")
 }
 
 // convertToCValue converts an LLVM value to a CValue pointer.
@@ -2218,10 +3359,11 @@ func (g *LLVMCodegen) convertToCValue(val value.Value) value.Value {
 
 	// Determine value type and store
 	valType := val.Type()
+	arrayType, isStruct := valType.(*types.StructType)
 	switch {
 	case valType.Equal(types.I64):
 		// Integer
-		g.builder.NewStore(constant.NewInt(types.I32, 0), typeField) // CValueTypeInt
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagInt), typeField)
 		intField := g.builder.NewGetElementPtr(dataField.Type().(*types.PointerType).ElemType, dataField,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, 0))
@@ -2229,7 +3371,7 @@ func (g *LLVMCodegen) convertToCValue(val value.Value) value.Value {
 
 	case valType.Equal(types.Double):
 		// Float
-		g.builder.NewStore(constant.NewInt(types.I32, 1), typeField) // CValueTypeFloat
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagFloat), typeField)
 		floatField := g.builder.NewGetElementPtr(dataField.Type().(*types.PointerType).ElemType, dataField,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, 1))
@@ -2237,7 +3379,7 @@ func (g *LLVMCodegen) convertToCValue(val value.Value) value.Value {
 
 	case valType.Equal(types.I1):
 		// Boolean
-		g.builder.NewStore(constant.NewInt(types.I32, 3), typeField) // CValueTypeBool
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagBool), typeField)
 		intField := g.builder.NewGetElementPtr(dataField.Type().(*types.PointerType).ElemType, dataField,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, 0))
@@ -2247,76 +3389,255 @@ func (g *LLVMCodegen) convertToCValue(val value.Value) value.Value {
 
 	case valType.Equal(types.NewPointer(types.I8)):
 		// String
-		g.builder.NewStore(constant.NewInt(types.I32, 2), typeField) // CValueTypeString
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagString), typeField)
 		stringField := g.builder.NewGetElementPtr(dataField.Type().(*types.PointerType).ElemType, dataField,
 			constant.NewInt(types.I32, 0),
 			constant.NewInt(types.I32, 2))
 		g.builder.NewStore(val, stringField)
 
+	case isStruct && g.isArrayStructType(arrayType):
+		// Array: array_val points to a copy of the {data, length} struct,
+		// which shares the elements
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagArray), typeField)
+		arrayCopy := g.builder.NewAlloca(valType)
+		g.builder.NewStore(val, arrayCopy)
+		arrayField := g.builder.NewGetElementPtr(dataField.Type().(*types.PointerType).ElemType, dataField,
+			constant.NewInt(types.I32, 0),
+			constant.NewInt(types.I32, 3))
+		g.builder.NewStore(g.builder.NewBitCast(arrayCopy, types.I8Ptr), arrayField)
+
 	default:
 		// Void or unsupported
-		g.builder.NewStore(constant.NewInt(types.I32, 6), typeField) // CValueTypeVoid
+		g.builder.NewStore(constant.NewInt(types.I32, cvalueTagVoid), typeField)
 	}
 
 	// Cast to i8* for C compatibility
 	return g.builder.NewBitCast(cval, types.NewPointer(types.I8))
 }
 
-// convertFromCValue converts a CValue to an LLVM value.
-func (g *LLVMCodegen) convertFromCValue(cval value.Value) (value.Value, error) {
-	// This function handles extracting values from CValue structs returned by builtin functions
+// convertFromCValue converts a CValue pointer to an LLVM value of alasType,
+// branching on the CValue's type tag. Values of other types, such as arrays
+// and "any", stay boxed as the CValue pointer.
+func (g *LLVMCodegen) convertFromCValue(cval value.Value, alasType string) (value.Value, error) {
+	if !cval.Type().Equal(types.I8Ptr) {
+		return nil, fmt.Errorf("expected a CValue pointer, got %s", cval.Type())
+	}
+	alasType = ast.BaseType(alasType)
+	switch alasType {
+	case ast.TypeInt, ast.TypeFloat, ast.TypeBool, ast.TypeString, ast.TypeMap:
+	default:
+		return cval, nil
+	}
+
+	cvalueType := cvalueStructType()
+	cvalueStruct := g.builder.NewLoad(cvalueType, g.builder.NewBitCast(cval, types.NewPointer(cvalueType)))
+	tag := g.builder.NewExtractValue(cvalueStruct, 0)
+	data := g.builder.NewExtractValue(cvalueStruct, 1)
+	hasTag := func(t int64) value.Value {
+		return g.builder.NewICmp(enum.IPredEQ, tag, constant.NewInt(types.I32, t))
+	}
+
+	switch alasType {
+	case ast.TypeInt:
+		// Ints and bools live in int_val; floats are truncated
+		intVal := g.builder.NewExtractValue(data, 0)
+		fromFloat := g.builder.NewFPToSI(g.builder.NewExtractValue(data, 1), types.I64)
+		return g.builder.NewSelect(hasTag(cvalueTagFloat), fromFloat, intVal), nil
+	case ast.TypeFloat:
+		floatVal := g.builder.NewExtractValue(data, 1)
+		fromInt := g.builder.NewSIToFP(g.builder.NewExtractValue(data, 0), types.Double)
+		return g.builder.NewSelect(hasTag(cvalueTagFloat), floatVal, fromInt), nil
+	case ast.TypeBool:
+		intTruthy := g.builder.NewICmp(enum.IPredNE, g.builder.NewExtractValue(data, 0), constant.NewInt(types.I64, 0))
+		floatTruthy := g.builder.NewFCmp(enum.FPredUNE, g.builder.NewExtractValue(data, 1), constant.NewFloat(types.Double, 0))
+		return g.builder.NewSelect(hasTag(cvalueTagFloat), floatTruthy, intTruthy), nil
+	case ast.TypeString:
+		// Values of any other type yield a null string
+		return g.builder.NewSelect(hasTag(cvalueTagString), g.builder.NewExtractValue(data, 2), constant.NewNull(types.I8Ptr)), nil
+	default:
+		return g.builder.NewSelect(hasTag(cvalueTagMap), g.builder.NewExtractValue(data, 4), constant.NewNull(types.I8Ptr)), nil
+	}
+}
+
+// CValue type tags, matching the constants in internal/stdlib/cgo_exports.go.
+const (
+	cvalueTagInt    = 0
+	cvalueTagFloat  = 1
+	cvalueTagString = 2
+	cvalueTagBool   = 3
+	cvalueTagArray  = 4
+	cvalueTagMap    = 5
+	cvalueTagVoid   = 6
+)
 
-	// Define CValue struct type to match our CGO definition
-	cvalueType := types.NewStruct(
+// cvalueStructType returns the LLVM layout of the C CValue struct.
+func cvalueStructType() *types.StructType {
+	return types.NewStruct(
 		types.I32, // type field
-		types.NewStruct( // data union
-			types.I64,                  // int_val
-			types.Double,               // float_val
-			types.NewPointer(types.I8), // string_val
-			types.NewPointer(types.I8), // array_val
-			types.NewPointer(types.I8), // map_val
+		types.NewStruct( // data union (simplified as struct with all fields)
+			types.I64,    // int_val
+			types.Double, // float_val
+			types.I8Ptr,  // string_val
+			types.I8Ptr,  // array_val
+			types.I8Ptr,  // map_val
 		),
 	)
+}
 
-	// Check if we have a pointer type (i8* from builtin function call)
-	if ptrType, isPtr := cval.Type().(*types.PointerType); isPtr {
-		if ptrType.ElemType.Equal(types.I8) {
-			// This is an i8* pointer to CValue, cast it to CValue* and load
-			cvaluePtr := g.builder.NewBitCast(cval, types.NewPointer(cvalueType))
-			cvalueStruct := g.builder.NewLoad(cvalueType, cvaluePtr)
+// pointerKind describes what an i8* value points to.
+type pointerKind int
 
-			// Extract the type field to determine what kind of value this is
-			_ = g.builder.NewExtractValue(cvalueStruct, 0) // typeField for future type switching
-			dataUnion := g.builder.NewExtractValue(cvalueStruct, 1)
+const (
+	pointerKindString pointerKind = iota
+	pointerKindMap
+	pointerKindCValue
+)
 
-			// For now, assume it's a float value (type 1) and extract the float field
-			// TODO: Add proper type switching based on typeField
-			floatVal := g.builder.NewExtractValue(dataUnion, 1)
-			return floatVal, nil
+// pointerKindOf reports what the i8* produced by expr points to: a C string,
+// a runtime map handle, or a CValue (builtin results and map lookups).
+func (g *LLVMCodegen) pointerKindOf(expr *ast.Expression) pointerKind {
+	switch expr.Type {
+	case ast.ExprMapLit:
+		return pointerKindMap
+	case ast.ExprBuiltin:
+		switch builtinResultTypes[expr.Name] {
+		case ast.TypeString:
+			return pointerKindString
+		case ast.TypeMap:
+			return pointerKindMap
+		}
+		return pointerKindCValue
+	case ast.ExprIndex:
+		// Array elements are stored unboxed; map lookups return CValues
+		if declared := g.declaredTypeOf(expr.Object); ast.BaseType(declared) == ast.TypeArray {
+			if ast.BaseType(ast.ElementType(declared)) == ast.TypeMap {
+				return pointerKindMap
+			}
+			return pointerKindString
 		}
+		return pointerKindCValue
+	case ast.ExprField:
+		if expr.Object != nil && expr.Object.Type == ast.ExprVariable {
+			if typeDef, ok := g.customTypes[g.variableTypes[expr.Object.Name]]; ok {
+				for _, field := range typeDef.Definition.Fields {
+					if field.Name == expr.Field && ast.BaseType(g.resolveType(field.Type)) == ast.TypeMap {
+						return pointerKindMap
+					} else if field.Name == expr.Field {
+						return pointerKindString
+					}
+				}
+			}
+		}
+		return pointerKindCValue
+	case ast.ExprVariable:
+		switch ast.BaseType(g.variableTypes[expr.Name]) {
+		case ast.TypeMap, DynamicMapType:
+			return pointerKindMap
+		case BoxedValueType:
+			return pointerKindCValue
+		}
+	case ast.ExprCall:
+		if astFn, ok := g.astFunctions[expr.Name]; ok && ast.BaseType(g.resolveType(astFn.Returns)) == ast.TypeMap {
+			return pointerKindMap
+		}
+	}
+	return pointerKindString
+}
+
+// newPointerCValue wraps a string or map handle in a stack-allocated CValue.
+func (g *LLVMCodegen) newPointerCValue(tag int64, fieldIndex int64, ptr value.Value) value.Value {
+	cvalueType := cvalueStructType()
+	cval := g.builder.NewAlloca(cvalueType)
+	typeField := g.builder.NewGetElementPtr(cvalueType, cval,
+		constant.NewInt(types.I32, 0),
+		constant.NewInt(types.I32, 0))
+	g.builder.NewStore(constant.NewInt(types.I32, tag), typeField)
+	dataField := g.builder.NewGetElementPtr(cvalueType, cval,
+		constant.NewInt(types.I32, 0),
+		constant.NewInt(types.I32, 1),
+		constant.NewInt(types.I32, fieldIndex))
+	g.builder.NewStore(ptr, dataField)
+	return g.builder.NewBitCast(cval, types.I8Ptr)
+}
+
+// mapKeyCValue converts a map key to a CValue pointer. Keys must be int,
+// bool or string.
+func (g *LLVMCodegen) mapKeyCValue(expr *ast.Expression, key value.Value) (value.Value, error) {
+	switch {
+	case key.Type().Equal(types.I64), key.Type().Equal(types.I1):
+		return g.convertToCValue(key), nil
+	case key.Type().Equal(types.I8Ptr):
+		switch g.pointerKindOf(expr) {
+		case pointerKindString:
+			return g.newPointerCValue(cvalueTagString, 2, key), nil
+		case pointerKindCValue:
+			// Checked by the runtime
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("map keys must be int, bool or string, got %s", key.Type())
+}
+
+// exprCValue converts the value of expr to a CValue pointer, for map entries
+// and builtin arguments.
+func (g *LLVMCodegen) exprCValue(expr *ast.Expression, val value.Value) value.Value {
+	if !val.Type().Equal(types.I8Ptr) {
+		return g.convertToCValue(val)
 	}
+	switch g.pointerKindOf(expr) {
+	case pointerKindMap:
+		return g.newPointerCValue(cvalueTagMap, 4, val)
+	case pointerKindCValue:
+		return val
+	default:
+		return g.newPointerCValue(cvalueTagString, 2, val)
+	}
+}
 
-	// Check if we have the expected struct type directly
-	if _, isStruct := cval.Type().(*types.StructType); isStruct {
-		// We have a proper struct! Extract the value based on the type field
-		dataUnion := g.builder.NewExtractValue(cval, 1)
-		floatVal := g.builder.NewExtractValue(dataUnion, 1)
-		return floatVal, nil
+// coerceBoxedValue unboxes val when expr yields a CValue pointer and a value
+// of alasType is expected. Other values are returned unchanged.
+func (g *LLVMCodegen) coerceBoxedValue(expr *ast.Expression, val value.Value, alasType string) value.Value {
+	if !val.Type().Equal(types.I8Ptr) || g.pointerKindOf(expr) != pointerKindCValue {
+		return val
+	}
+	converted, err := g.convertFromCValue(val, alasType)
+	if err != nil {
+		return val
 	}
+	return converted
+}
 
-	// Fallback for unexpected types
-	return constant.NewFloat(types.Double, 0.0), nil
+// scalarTypeName returns the ALaS type name for an int, float, or bool LLVM type.
+func scalarTypeName(t types.Type) string {
+	switch {
+	case t.Equal(types.I64):
+		return ast.TypeInt
+	case t.Equal(types.Double):
+		return ast.TypeFloat
+	case t.Equal(types.I1):
+		return ast.TypeBool
+	}
+	return ""
 }
 
 // declareImportedFunctions declares external functions from imported modules.
-func (g *LLVMCodegen) declareImportedFunctions(imports []string) error {
+func (g *LLVMCodegen) declareImportedFunctions(imports []ast.Import) error {
+	// Module calls name an aliased import by its alias, while its functions
+	// keep the module's own name so they link against it
+	for _, imp := range imports {
+		if imp.Alias != "" {
+			g.importAliases[imp.Alias] = imp.Module
+		}
+	}
+
 	// If no module loader is set, we can't load imports
 	// This is okay for single-module compilation
 	if g.moduleLoader == nil {
 		return nil
 	}
 
-	for _, importName := range imports {
+	for _, imp := range imports {
+		importName := imp.Module
 		// Check if module is already loaded (caching)
 		var importedModule *ast.Module
 		var err error
@@ -2334,11 +3655,14 @@ func (g *LLVMCodegen) declareImportedFunctions(imports []string) error {
 			g.loadedModules[importName] = importedModule
 		}
 
-		// Import custom types from the module
+		// Import custom types from the module. Every type gets an LLVM layout,
+		// since exported function signatures may use it, but only exported
+		// types are visible under their module__Type name
 		for _, typeDef := range importedModule.Types {
-			// Check if type is exported (assume all types are exported for now)
 			qualifiedTypeName := fmt.Sprintf("%s__%s", importName, typeDef.Name)
-			g.customTypes[qualifiedTypeName] = &typeDef
+			if importedModule.ExportsType(typeDef.Name) {
+				g.customTypes[qualifiedTypeName] = &typeDef
+			}
 
 			// Generate LLVM struct type for custom types
 			if err := g.declareCustomType(&typeDef); err != nil {
@@ -2366,6 +3690,16 @@ func (g *LLVMCodegen) declareImportedFunctions(imports []string) error {
 					continue
 				}
 
+				// Calls to an imported extern function go straight to C
+				if fn.Extern != "" {
+					externFunc, err := g.declareExtern(&fn)
+					if err != nil {
+						return fmt.Errorf("failed to declare %s: %v", qualifiedName, err)
+					}
+					g.externalFunctions[qualifiedName] = externFunc
+					continue
+				}
+
 				// Convert return type
 				retType, err := g.convertType(fn.Returns)
 				if err != nil {
@@ -2422,7 +3756,7 @@ func (g *LLVMCodegen) resolveModuleDependencies(moduleName string, visited map[s
 	}
 
 	// Recursively resolve dependencies
-	for _, importName := range module.Imports {
+	for _, importName := range module.ImportedModules() {
 		if err := g.resolveModuleDependencies(importName, visited); err != nil {
 			return err
 		}
@@ -2460,6 +3794,12 @@ func (g *LLVMCodegen) compileModule(moduleName string) (*ir.Module, error) {
 	for name, fieldMap := range g.fieldIndices {
 		moduleCodegen.fieldIndices[name] = fieldMap
 	}
+	for name, tags := range g.enumTags {
+		moduleCodegen.enumTags[name] = tags
+	}
+	for name, variantFields := range g.variantFields {
+		moduleCodegen.variantFields[name] = variantFields
+	}
 
 	// Generate the module
 	compiledModule, err := moduleCodegen.GenerateModule(module)
@@ -2531,11 +3871,23 @@ func (g *LLVMCodegen) boxToI8Ptr(val value.Value, name string) value.Value {
 	heapPtr := g.builder.NewCall(mallocFunc, size)
 	heapPtr.SetName(name)
 
-	// Note: We're not checking for malloc failure here as it would require
-	// complex control flow manipulation. In a production system, consider:
-	// 1. Using a runtime that guarantees allocation or aborts
-	// 2. Implementing a separate allocation wrapper function
-	// 3. Using LLVM's garbage collection infrastructure
+	// Panic rather than store through a null pointer when out of memory.
+	// Unlike the other safety checks this one is kept with -unsafe.
+	if panicFunc, exists := g.builtinFunctions["alas_runtime_panic"]; exists {
+		currentFunc := g.builder.Parent
+		suffix := len(currentFunc.Blocks)
+		oomBlock := currentFunc.NewBlock(fmt.Sprintf("%s.oom.%d", name, suffix))
+		okBlock := currentFunc.NewBlock(fmt.Sprintf("%s.ok.%d", name, suffix))
+		isNull := g.builder.NewICmp(enum.IPredEQ, heapPtr, constant.NewNull(types.I8Ptr))
+		g.builder.NewCondBr(isNull, oomBlock, okBlock)
+
+		// The runtime panic handler does not return
+		g.builder = oomBlock
+		g.builder.NewCall(panicFunc, g.createStringLiteral("out of memory"))
+		g.builder.NewUnreachable()
+
+		g.builder = okBlock
+	}
 
 	// Cast to proper type and store value
 	typedPtr := g.builder.NewBitCast(heapPtr, types.NewPointer(val.Type()))
@@ -0,0 +1,1398 @@
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/builtins"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// generateIR compiles a module and returns its textual LLVM IR.
+func generateIR(t *testing.T, module *ast.Module) string {
+	t.Helper()
+	g := NewLLVMCodegen()
+	llvmModule, err := g.GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	return llvmModule.String()
+}
+
+// singleFunctionModule wraps a function body in a module with one function.
+func singleFunctionModule(returns string, params []ast.Parameter, body []ast.Statement) *ast.Module {
+	return &ast.Module{
+		Type: "module",
+		Name: "test",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  params,
+				Returns: returns,
+				Body:    body,
+			},
+		},
+	}
+}
+
+func TestLLVMCodegen_Cast(t *testing.T) {
+	tests := []struct {
+		name      string
+		paramType string
+		to        string
+		expected  string
+	}{
+		{name: "int to float", paramType: "int", to: "float", expected: "sitofp i64"},
+		{name: "float to int", paramType: "float", to: "int", expected: "fptosi double"},
+		{name: "bool to int", paramType: "bool", to: "int", expected: "zext i1"},
+		{name: "int to bool", paramType: "int", to: "bool", expected: "icmp ne i64"},
+		{name: "float to bool", paramType: "float", to: "bool", expected: "fcmp une double"},
+		{name: "int to string", paramType: "int", to: "string", expected: "@alas_runtime_int_to_string"},
+		{name: "string to int", paramType: "string", to: "int", expected: "@alas_runtime_string_to_int"},
+		{name: "string to float", paramType: "string", to: "float", expected: "@alas_runtime_string_to_float"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := singleFunctionModule(tt.to,
+				[]ast.Parameter{{Name: "x", Type: tt.paramType}},
+				[]ast.Statement{
+					{
+						Type: ast.StmtReturn,
+						Value: &ast.Expression{
+							Type:    ast.ExprCast,
+							To:      tt.to,
+							Operand: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+						},
+					},
+				})
+
+			ir := generateIR(t, module)
+			if !strings.Contains(ir, tt.expected) {
+				t.Errorf("expected IR to contain %q, got:\n%s", tt.expected, ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_SpecialFloatLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       string
+		left     string
+		right    string
+		expected string
+	}{
+		{name: "NaN equality", op: ast.OpEq, left: ast.FloatNaN, right: ast.FloatNaN, expected: "fcmp oeq double 0x7FF8000000000000, 0x7FF8000000000000"},
+		{name: "NaN inequality is unordered", op: ast.OpNe, left: ast.FloatNaN, right: ast.FloatNaN, expected: "fcmp une double 0x7FF8000000000000"},
+		{name: "infinities", op: ast.OpLt, left: ast.FloatNegInfinity, right: ast.FloatInfinity, expected: "fcmp olt double 0xFFF0000000000000, 0x7FF0000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := singleFunctionModule("bool", []ast.Parameter{}, []ast.Statement{
+				{
+					Type: ast.StmtReturn,
+					Value: &ast.Expression{
+						Type:  ast.ExprBinary,
+						Op:    tt.op,
+						Left:  &ast.Expression{Type: ast.ExprLiteral, Value: tt.left, To: ast.TypeFloat},
+						Right: &ast.Expression{Type: ast.ExprLiteral, Value: tt.right, To: ast.TypeFloat},
+					},
+				},
+			})
+
+			ir := generateIR(t, module)
+			if !strings.Contains(ir, tt.expected) {
+				t.Errorf("expected IR to contain %q, got:\n%s", tt.expected, ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_FloatLiteralDivision(t *testing.T) {
+	data := []byte(`{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [], "returns": "float",
+		"body": [{"type": "return", "value": {"type": "binary", "op": "/",
+			"left": {"type": "literal", "value": 22.0}, "right": {"type": "literal", "value": 7.0}}}]}]}`)
+	module, err := ast.ParseModule(data, "")
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+
+	// Literals written as floats stay floats, as in the interpreter
+	ir := generateIR(t, module)
+	if !strings.Contains(ir, "fdiv double 22.0, 7.0") {
+		t.Errorf("expected float division, got:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_CastInvalidTarget(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{
+			Type: ast.StmtReturn,
+			Value: &ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "array",
+				Operand: &ast.Expression{Type: ast.ExprLiteral, Value: float64(1)},
+			},
+		},
+	})
+
+	if _, err := NewLLVMCodegen().GenerateModule(module); err == nil {
+		t.Error("expected error for cast to array")
+	}
+}
+
+func TestLLVMCodegen_Assert(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{{Name: "x", Type: "int"}}, []ast.Statement{
+		{
+			Type: ast.StmtAssert,
+			Cond: &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    ast.OpGt,
+				Left:  &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0},
+			},
+			Message: "x must be positive",
+		},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}},
+	})
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{"call void @alas_runtime_assert(i1", "x must be positive"} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_AssertNonBoolCondition(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{{Name: "x", Type: "int"}}, []ast.Statement{
+		{Type: ast.StmtAssert, Cond: &ast.Expression{Type: ast.ExprVariable, Name: "x"}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}},
+	})
+
+	if _, err := NewLLVMCodegen().GenerateModule(module); err == nil {
+		t.Fatal("expected error for non-boolean assert condition")
+	}
+}
+
+func TestLLVMCodegen_FunctionValuesNotSupported(t *testing.T) {
+	values := map[string]*ast.Expression{
+		"lambda": {
+			Type:   ast.ExprLambda,
+			Params: []ast.Parameter{{Name: "x", Type: "int"}},
+			Body:   []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}}},
+		},
+		"function reference": {Type: ast.ExprFuncRef, Name: "main"},
+	}
+
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtAssign, Target: "f", Value: value},
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+			})
+
+			_, err := NewLLVMCodegen().GenerateModule(module)
+			if err == nil || !strings.Contains(err.Error(), "not supported in compiled mode") {
+				t.Fatalf("expected %s to be rejected in compiled mode, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_ArrayHigherOrderBuiltinsDeclared(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+	})
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		"declare i8* @alas_builtin_array_map(i8* %0, i8* %1)",
+		"declare i8* @alas_builtin_array_filter(i8* %0, i8* %1)",
+		"declare i8* @alas_builtin_array_reduce(i8* %0, i8* %1, i8* %2)",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_RuntimeCheckLocation(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{{Name: "x", Type: "int"}}, []ast.Statement{
+		{
+			Type: ast.StmtAssert,
+			File: "checks.alas.json",
+			Line: 12,
+			Cond: &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    ast.OpGt,
+				Left:  &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0},
+			},
+		},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}},
+	})
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{`c"checks.alas.json\00"`, "i32 12)"} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+	if strings.Contains(ir, "unknown.alas") {
+		t.Errorf("expected no placeholder file name in IR, got:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_MethodCall(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "test",
+		Types: []ast.TypeDefinition{
+			{
+				Name: "Point",
+				Definition: ast.TypeDefinitionDef{
+					Kind:   ast.TypeKindStruct,
+					Fields: []ast.TypeField{{Name: "x", Type: "int"}, {Name: "y", Type: "int"}},
+				},
+			},
+		},
+		Functions: []ast.Function{
+			{
+				Type:     "function",
+				Name:     "scaled_sum",
+				Receiver: &ast.Parameter{Name: "p", Type: "Point"},
+				Params:   []ast.Parameter{{Name: "k", Type: "int"}},
+				Returns:  "int",
+				Body: []ast.Statement{
+					{
+						Type: ast.StmtReturn,
+						Value: &ast.Expression{
+							Type: ast.ExprBinary,
+							Op:   ast.OpMul,
+							Left: &ast.Expression{
+								Type:  ast.ExprBinary,
+								Op:    ast.OpAdd,
+								Left:  &ast.Expression{Type: ast.ExprField, Object: &ast.Expression{Type: ast.ExprVariable, Name: "p"}, Field: "x"},
+								Right: &ast.Expression{Type: ast.ExprField, Object: &ast.Expression{Type: ast.ExprVariable, Name: "p"}, Field: "y"},
+							},
+							Right: &ast.Expression{Type: ast.ExprVariable, Name: "k"},
+						},
+					},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "make_point",
+				Params:  []ast.Parameter{},
+				Returns: "Point",
+				Body: []ast.Statement{
+					{
+						Type: ast.StmtReturn,
+						Value: &ast.Expression{
+							Type: ast.ExprMapLit,
+							Pairs: []ast.MapPair{
+								{Key: ast.Expression{Type: ast.ExprLiteral, Value: "x"}, Value: ast.Expression{Type: ast.ExprLiteral, Value: 1.0}},
+								{Key: ast.Expression{Type: ast.ExprLiteral, Value: "y"}, Value: ast.Expression{Type: ast.ExprLiteral, Value: 2.0}},
+							},
+						},
+					},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:   ast.StmtAssign,
+						Target: "pt",
+						Value:  &ast.Expression{Type: ast.ExprCall, Name: "make_point", Args: []ast.Expression{}},
+					},
+					{
+						Type: ast.StmtReturn,
+						Value: &ast.Expression{
+							Type:   ast.ExprMethodCall,
+							Object: &ast.Expression{Type: ast.ExprVariable, Name: "pt"},
+							Name:   "scaled_sum",
+							Args:   []ast.Expression{{Type: ast.ExprLiteral, Value: 3.0}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{`define i64 @Point.scaled_sum({ i64, i64 } %p, i64 %k)`, `call i64 @Point.scaled_sum({ i64, i64 }`} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_EnumVariantsAndMatch(t *testing.T) {
+	returnVar := func(name string) []ast.Statement {
+		return []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: name}}}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "test",
+		Types: []ast.TypeDefinition{
+			{
+				Name: "Result",
+				Definition: ast.TypeDefinitionDef{
+					Kind: ast.TypeKindEnum,
+					Variants: []ast.EnumVariant{
+						{Name: "Ok", Fields: []ast.TypeField{{Name: "value", Type: "int"}}},
+						{Name: "Err", Fields: []ast.TypeField{{Name: "code", Type: "int"}}},
+					},
+				},
+			},
+		},
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "unwrap",
+				Params:  []ast.Parameter{{Name: "r", Type: "Result"}},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:  ast.StmtMatch,
+						Value: &ast.Expression{Type: ast.ExprVariable, Name: "r"},
+						Cases: []ast.MatchCase{
+							{Variant: "Ok", Bindings: map[string]string{"value": "v"}, Body: returnVar("v")},
+							{Variant: "Err", Bindings: map[string]string{"code": "c"}, Body: returnVar("c")},
+						},
+					},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type: ast.StmtReturn,
+						Value: &ast.Expression{
+							Type: ast.ExprCall,
+							Name: "unwrap",
+							Args: []ast.Expression{{
+								Type:    ast.ExprVariant,
+								Enum:    "Result",
+								Variant: "Err",
+								Pairs: []ast.MapPair{{
+									Key:   ast.Expression{Type: ast.ExprLiteral, Value: "code"},
+									Value: ast.Expression{Type: ast.ExprLiteral, Value: 7.0},
+								}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		"define i64 @unwrap({ i32, i64, i64 } %r)",
+		"insertvalue { i32, i64, i64 } zeroinitializer, i32 1, 0",
+		"i64 7, 2",
+		"switch i32",
+		"i32 0, label %match.Ok",
+		"i32 1, label %match.Err",
+		"extractvalue { i32, i64, i64 }",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_PlainEnumMatch(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "test",
+		Types: []ast.TypeDefinition{
+			{Name: "Color", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Values: []string{"red", "green"}}},
+		},
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:  ast.StmtMatch,
+						Value: &ast.Expression{Type: ast.ExprVariant, Enum: "Color", Variant: "green"},
+						Cases: []ast.MatchCase{
+							{Variant: "red", Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}}}},
+						},
+						Default: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 2.0}}},
+					},
+				},
+			},
+		},
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{"switch i32 1, label %match.default", "i32 0, label %match.red"} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_EnumCastToInt(t *testing.T) {
+	castToInt := func(operand *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: operand}}
+	}
+	color := ast.TypeDefinition{Name: "Color", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Values: []string{"red", "green"}}}
+	shape := ast.TypeDefinition{Name: "Shape", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Variants: []ast.EnumVariant{
+		{Name: "Circle", Fields: []ast.TypeField{{Name: "radius", Type: "int"}}},
+		{Name: "Empty"},
+	}}}
+
+	tests := []struct {
+		name   string
+		params []ast.Parameter
+		body   ast.Statement
+		want   []string
+	}{
+		{
+			name: "plain enum variant",
+			body: castToInt(&ast.Expression{Type: ast.ExprVariant, Enum: "Color", Variant: "green"}),
+			want: []string{"zext i32 1 to i64"},
+		},
+		{
+			name:   "enum with payload",
+			params: []ast.Parameter{{Name: "s", Type: "Shape"}},
+			body:   castToInt(&ast.Expression{Type: ast.ExprVariable, Name: "s"}),
+			want:   []string{"extractvalue { i32, i64 } %0, 0", "zext i32 %1 to i64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := singleFunctionModule("int", tt.params, []ast.Statement{tt.body})
+			module.Types = []ast.TypeDefinition{color, shape}
+			ir := generateIR(t, module)
+			for _, want := range tt.want {
+				if !strings.Contains(ir, want) {
+					t.Errorf("expected IR to contain %q, got:\n%s", want, ir)
+				}
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_MatchSkipsUnreachableCases(t *testing.T) {
+	returnInt := func(n float64) []ast.Statement {
+		return []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: n}}}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "test",
+		Types: []ast.TypeDefinition{
+			{Name: "Color", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Values: []string{"red", "green"}}},
+		},
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:  ast.StmtMatch,
+						Value: &ast.Expression{Type: ast.ExprVariant, Enum: "Color", Variant: "green"},
+						Cases: []ast.MatchCase{
+							{Variant: "red", Body: returnInt(1)},
+							{Variant: "purple", Body: returnInt(2)},
+							{Variant: "red", Body: returnInt(3)},
+						},
+						Default: returnInt(4),
+					},
+				},
+			},
+		},
+	}
+
+	ir := generateIR(t, module)
+	if n := strings.Count(ir, "label %match.red"); n != 1 {
+		t.Errorf("expected one switch case for red, got %d:\n%s", n, ir)
+	}
+	for _, unexpected := range []string{"match.purple", "ret i64 2", "ret i64 3"} {
+		if strings.Contains(ir, unexpected) {
+			t.Errorf("expected IR not to contain %q, got:\n%s", unexpected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_MapUsesRuntimeHashTable(t *testing.T) {
+	str := func(s string) ast.Expression { return ast.Expression{Type: ast.ExprLiteral, Value: s} }
+	num := func(n float64) ast.Expression { return ast.Expression{Type: ast.ExprLiteral, Value: n} }
+	person := ast.Expression{Type: ast.ExprVariable, Name: "person"}
+	builtin := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: args}
+	}
+
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtAssign, Target: "person", Value: &ast.Expression{
+			Type: ast.ExprMapLit,
+			Pairs: []ast.MapPair{
+				{Key: str("name"), Value: str("Alice")},
+				{Key: str("age"), Value: num(30)},
+				{Key: num(1), Value: num(2)},
+			},
+		}},
+		{Type: ast.StmtExpr, Value: builtin("map.put", person, str("city"), str("Paris"))},
+		{Type: ast.StmtExpr, Value: builtin("map.remove", person, num(1))},
+		{Type: ast.StmtAssign, Target: "has", Value: builtin("map.contains", person, str("city"))},
+		{Type: ast.StmtAssign, Target: "size", Value: builtin("map.size", person)},
+		{Type: ast.StmtAssign, Target: "keys", Value: builtin("map.keys", person)},
+		{Type: ast.StmtAssign, Target: "values", Value: builtin("map.values", person)},
+		{Type: ast.StmtAssign, Target: "name", Value: &ast.Expression{Type: ast.ExprIndex, Object: &person, Index: &ast.Expression{Type: ast.ExprLiteral, Value: "name"}}},
+		{Type: ast.StmtAssign, Target: "age", Value: &ast.Expression{Type: ast.ExprField, Object: &person, Field: "age"}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{
+			Type:  ast.ExprBinary,
+			Op:    ast.OpAdd,
+			Left:  &ast.Expression{Type: ast.ExprVariable, Name: "age"},
+			Right: &ast.Expression{Type: ast.ExprVariable, Name: "size"},
+		}},
+	})
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		// person does not escape, so it is released when main returns
+		"call void @alas_runtime_map_init(i8* %map_handle, i8* %",
+		"call void @alas_runtime_map_release(i8* %map_handle)",
+		"call void @alas_runtime_map_put(",
+		"call void @alas_runtime_map_remove(",
+		"call i1 @alas_runtime_map_contains(",
+		"call i64 @alas_runtime_map_size(",
+		"call { i8*, i64 } @alas_runtime_map_keys(",
+		"call { i8*, i64 } @alas_runtime_map_values(",
+		"call i8* @alas_runtime_map_get(",
+		"call i8* @alas_runtime_map_get_field(",
+		"extractvalue { i64, double, i8*, i8*, i8* }",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+	if strings.Contains(ir, "i64 42") {
+		t.Errorf("expected no placeholder lookup results, got:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_MapInvalidKey(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtAssign, Target: "m", Value: &ast.Expression{
+			Type:  ast.ExprMapLit,
+			Pairs: []ast.MapPair{{Key: ast.Expression{Type: ast.ExprLiteral, Value: 1.5}, Value: ast.Expression{Type: ast.ExprLiteral, Value: 1.0}}},
+		}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+	})
+
+	_, err := NewLLVMCodegen().GenerateModule(module)
+	if err == nil || !strings.Contains(err.Error(), "map keys must be int, bool or string") {
+		t.Fatalf("expected invalid map key error, got %v", err)
+	}
+}
+
+func TestLLVMCodegen_BuiltinResultsUseTypeTag(t *testing.T) {
+	tests := []struct {
+		builtin  string
+		returns  string
+		expected []string
+	}{
+		{
+			builtin:  "string.length",
+			returns:  "int",
+			expected: []string{"icmp eq i32", "fptosi double", "select i1", "ret i64"},
+		},
+		{
+			builtin:  "math.sqrt",
+			returns:  "float",
+			expected: []string{"icmp eq i32", "sitofp i64", "select i1", "ret double"},
+		},
+		{
+			builtin:  "string.startsWith",
+			returns:  "bool",
+			expected: []string{"icmp ne i64", "fcmp une double", "select i1", "ret i1"},
+		},
+		{
+			builtin:  "string.toUpper",
+			returns:  "string",
+			expected: []string{"icmp eq i32", "select i1", "i8* null", "ret i8*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.builtin, func(t *testing.T) {
+			arg := ast.Expression{Type: ast.ExprLiteral, Value: "abc"}
+			args := []ast.Expression{arg}
+			if tt.builtin == "string.startsWith" {
+				args = append(args, arg)
+			}
+			module := singleFunctionModule(tt.returns, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: tt.builtin, Args: args}},
+			})
+
+			ir := generateIR(t, module)
+			for _, expected := range tt.expected {
+				if !strings.Contains(ir, expected) {
+					t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+				}
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_BuiltinSignaturesMatchDeclarations(t *testing.T) {
+	g := NewLLVMCodegen()
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+	})
+	if _, err := g.GenerateModule(module); err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	for name, fn := range g.builtinFunctions {
+		if strings.HasPrefix(name, "alas_") {
+			continue
+		}
+		sig, ok := builtins.Lookup(name)
+		if !ok {
+			t.Errorf("no signature for compiled builtin %s", name)
+			continue
+		}
+		if sig.MaxArgs() != len(fn.Params) {
+			t.Errorf("%s: signature has %d parameters, declaration has %d", name, sig.MaxArgs(), len(fn.Params))
+		}
+	}
+}
+
+func TestLLVMCodegen_ParseBuiltinReportsFailure(t *testing.T) {
+	parse := func(name, text string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: []ast.Expression{{Type: ast.ExprLiteral, Value: text}}}
+	}
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtAssign, Target: "f", Value: parse("type.parseFloat", "2.5")},
+		{Type: ast.StmtReturn, Value: parse("type.parseInt", "42")},
+	})
+	ir := generateIR(t, module)
+
+	for _, want := range []string{
+		"call i8* @alas_builtin_type_parseInt(",
+		"call i8* @alas_builtin_type_parseFloat(",
+		"icmp eq i8* ",
+		"call void @alas_runtime_error(",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+	// Each check gets its own failure block
+	if n := strings.Count(ir, "\nbuiltin.fail."); n != 2 {
+		t.Errorf("expected 2 failure blocks, got %d\nIR:\n%s", n, ir)
+	}
+}
+
+func TestLLVMCodegen_RuntimeBuiltinsAreConstants(t *testing.T) {
+	hasBuiltin := func(arg ast.Expression) *ast.Module {
+		return singleFunctionModule("bool", []ast.Parameter{{Name: "name", Type: "string"}}, []ast.Statement{{
+			Type:  ast.StmtReturn,
+			Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "runtime.hasBuiltin", Args: []ast.Expression{arg}},
+		}})
+	}
+
+	tests := []struct {
+		name    string
+		module  *ast.Module
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "compiled builtin",
+			module: hasBuiltin(ast.Expression{Type: ast.ExprLiteral, Value: "math.sqrt"}),
+			want:   "ret i1 true",
+		},
+		{
+			name:   "map builtin",
+			module: hasBuiltin(ast.Expression{Type: ast.ExprLiteral, Value: "map.get"}),
+			want:   "ret i1 true",
+		},
+		{
+			name:   "interpreter only builtin",
+			module: hasBuiltin(ast.Expression{Type: ast.ExprLiteral, Value: "sync.mutexCreate"}),
+			want:   "ret i1 false",
+		},
+		{
+			name:   "runtime support function",
+			module: hasBuiltin(ast.Expression{Type: ast.ExprLiteral, Value: "alas_runtime_error"}),
+			want:   "ret i1 false",
+		},
+		{
+			name:    "name not known at compile time",
+			module:  hasBuiltin(ast.Expression{Type: ast.ExprVariable, Name: "name"}),
+			wantErr: "runtime.hasBuiltin expects a string literal in compiled code",
+		},
+		{
+			name: "version",
+			module: singleFunctionModule("string", []ast.Parameter{}, []ast.Statement{{
+				Type:  ast.StmtReturn,
+				Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "runtime.version", Args: []ast.Expression{}},
+			}}),
+			want: `c"` + runtime.Version + `\00"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := NewLLVMCodegen().GenerateModule(tt.module)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GenerateModule error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			if ir := module.String(); !strings.Contains(ir, tt.want) {
+				t.Errorf("expected IR to contain %q\nIR:\n%s", tt.want, ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_LogBuiltins(t *testing.T) {
+	message := ast.Expression{Type: ast.ExprLiteral, Value: "started"}
+	fields := ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{
+		Key:   ast.Expression{Type: ast.ExprLiteral, Value: "user"},
+		Value: ast.Expression{Type: ast.ExprLiteral, Value: "ann"},
+	}}}
+	module := singleFunctionModule("void", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "log.warn", Args: []ast.Expression{message}}},
+		{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "log.debug", Args: []ast.Expression{message, fields}}},
+		{Type: ast.StmtReturn},
+	})
+
+	result, err := NewLLVMCodegen().GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	ir := result.String()
+	for _, want := range []string{
+		"declare void @alas_runtime_log(i32 %level, i8* %function, i8* %message, i8* %fields)",
+		`c"main\00"`,
+		"call void @alas_runtime_log(i32 2, ",
+		"i8* null)",
+		"call void @alas_runtime_log(i32 0, ",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+
+	bad := singleFunctionModule("void", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "log.info", Args: []ast.Expression{}}},
+	})
+	if _, err := NewLLVMCodegen().GenerateModule(bad); err == nil {
+		t.Error("GenerateModule with log.info() and no message succeeded, want error")
+	}
+}
+
+func TestLLVMCodegen_StringLiteralsShareGlobals(t *testing.T) {
+	lit := func(v string) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	module := singleFunctionModule("string", []ast.Parameter{{Name: "ok", Type: "bool"}}, []ast.Statement{
+		{
+			Type: ast.StmtIf,
+			Cond: &ast.Expression{Type: ast.ExprVariable, Name: "ok"},
+			Then: []ast.Statement{{Type: ast.StmtReturn, Value: lit("done")}},
+		},
+		{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "io.print", Args: []ast.Expression{*lit("error")}}},
+		{Type: ast.StmtReturn, Value: lit("error")},
+	})
+
+	result, err := NewLLVMCodegen().GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if len(result.Globals) != 2 {
+		t.Errorf("expected 2 string globals, got %d\nIR:\n%s", len(result.Globals), result)
+	}
+	for _, global := range result.Globals {
+		if !global.Immutable {
+			t.Errorf("string global %s is not constant", global.Ident())
+		}
+	}
+	ir := result.String()
+	if got := strings.Count(ir, `c"error\00"`); got != 1 {
+		t.Errorf("expected one definition of \"error\", got %d\nIR:\n%s", got, ir)
+	}
+	if got := strings.Count(ir, "getelementptr [6 x i8], [6 x i8]* @1, i64 0, i64 0"); got != 2 {
+		t.Errorf("expected both uses of \"error\" to point at its first character, got %d\nIR:\n%s", got, ir)
+	}
+}
+
+func TestLLVMCodegen_HeapArrayThreshold(t *testing.T) {
+	elements := []ast.Expression{{Type: ast.ExprLiteral, Value: 1.0}, {Type: ast.ExprLiteral, Value: 2.0}, {Type: ast.ExprLiteral, Value: 3.0}}
+	module := singleFunctionModule("int", []ast.Parameter{}, []ast.Statement{
+		{Type: ast.StmtAssign, Target: "xs", Value: &ast.Expression{Type: ast.ExprArrayLit, Elements: elements}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprIndex, Object: &ast.Expression{Type: ast.ExprVariable, Name: "xs"}, Index: &ast.Expression{Type: ast.ExprLiteral, Value: 2.0}}},
+	})
+	heapAlloc := "call i8* @alas_runtime_array_init(i8** %array_handle, i64 ptrtoint ([3 x i64]* getelementptr ([3 x i64], [3 x i64]* null, i32 1) to i64))"
+
+	tests := []struct {
+		name      string
+		threshold int
+		wantHeap  bool
+	}{
+		{name: "default", threshold: DefaultHeapArrayThreshold},
+		{name: "at threshold", threshold: 3},
+		{name: "above threshold", threshold: 2, wantHeap: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewLLVMCodegen()
+			g.SetHeapArrayThreshold(tt.threshold)
+			result, err := g.GenerateModule(module)
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			ir := result.String()
+			if got := strings.Contains(ir, heapAlloc); got != tt.wantHeap {
+				t.Errorf("heap allocation = %v, want %v\nIR:\n%s", got, tt.wantHeap, ir)
+			}
+			if got := strings.Contains(ir, "alloca [3 x i64]"); got == tt.wantHeap {
+				t.Errorf("stack allocation = %v, want %v\nIR:\n%s", got, !tt.wantHeap, ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_BoxToI8PtrChecksMalloc(t *testing.T) {
+	g := NewLLVMCodegen()
+	fn := g.module.NewFunc("box", types.I8Ptr)
+	g.builder = fn.NewBlock("entry")
+	boxed := g.boxToI8Ptr(constant.NewInt(types.I64, 7), "boxed")
+	g.builder.NewRet(boxed)
+
+	if got := g.boxToI8Ptr(boxed, "again"); got != boxed {
+		t.Errorf("boxToI8Ptr of an i8* = %v, want it unchanged", got)
+	}
+
+	ir := g.module.String()
+	for _, want := range []string{
+		"%boxed = call i8* @malloc(i64 8)",
+		"icmp eq i8* %boxed, null",
+		"label %boxed.oom.1, label %boxed.ok.1",
+		"call void @alas_runtime_panic(",
+		"unreachable",
+		"store i64 7, i64* %",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+	// The value is stored only after the null check
+	if strings.Index(ir, "store i64 7") < strings.Index(ir, "boxed.ok.1:") {
+		t.Errorf("expected the store in the block after the null check\nIR:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_GenerateFunction(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	double := &ast.Function{Type: "function", Name: "double", Params: []ast.Parameter{{Name: "x", Type: ast.TypeInt}}, Returns: ast.TypeInt,
+		Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: variable("x"), Right: variable("x")}}}}
+	main := &ast.Function{Type: "function", Name: "main", Returns: ast.TypeInt, Body: []ast.Statement{
+		{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "io.print", Args: []ast.Expression{{Type: ast.ExprLiteral, Value: "hi"}}}},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprCall, Name: "double", Args: []ast.Expression{{Type: ast.ExprLiteral, Value: 21.0}}}},
+	}}
+
+	g := NewLLVMCodegen()
+
+	// main calls double, which is not known yet
+	if _, err := g.GenerateFunction(main); err == nil || !strings.Contains(err.Error(), "undefined function: double") {
+		t.Fatalf("GenerateFunction(main) before double error = %v, want undefined function", err)
+	}
+
+	if _, err := g.GenerateFunction(double); err != nil {
+		t.Fatalf("GenerateFunction(double) error = %v", err)
+	}
+	fn, err := g.GenerateFunction(main)
+	if err != nil {
+		t.Fatalf("GenerateFunction(main) error = %v", err)
+	}
+	if fn.Name() != "main" {
+		t.Errorf("GenerateFunction(main) returned %s", fn.Name())
+	}
+	ir := fn.LLString()
+	for _, want := range []string{"call void @alas_builtin_io_print(", "call i64 @double(i64 21)"} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected function IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+	if got := strings.Count(g.module.String(), "define i64 @main("); got != 1 {
+		t.Errorf("module defines main %d times, want once after the failed attempt", got)
+	}
+
+	if _, err := g.GenerateFunction(double); err == nil || !strings.Contains(err.Error(), "function double is already declared") {
+		t.Errorf("GenerateFunction(double) again error = %v, want already declared", err)
+	}
+}
+
+func TestLLVMCodegen_TypeAliases(t *testing.T) {
+	alias := func(name, target string) ast.TypeDefinition {
+		return ast.TypeDefinition{Name: name, Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: target}}
+	}
+	module := singleFunctionModule("Temp", []ast.Parameter{{Name: "p", Type: "Pos"}}, []ast.Statement{{
+		Type: ast.StmtReturn,
+		Value: &ast.Expression{
+			Type:   ast.ExprField,
+			Object: &ast.Expression{Type: ast.ExprVariable, Name: "p"},
+			Field:  "y",
+		},
+	}})
+	module.Types = []ast.TypeDefinition{
+		alias("Temp", "Celsius"),
+		alias("Celsius", "float"),
+		{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+			{Name: "x", Type: "int"}, {Name: "y", Type: "Celsius"},
+		}}},
+		alias("Pos", "Point"),
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		"define double @main({ i64, double } %p)",
+		"ret double",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+
+	module.Types = []ast.TypeDefinition{alias("Temp", "Celsius"), alias("Celsius", "Temp")}
+	module.Functions[0].Params = nil
+	module.Functions[0].Body = []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}}}
+	_, err := NewLLVMCodegen().GenerateModule(module)
+	if err == nil || !strings.Contains(err.Error(), "type alias cycle: Temp -> Celsius -> Temp") {
+		t.Errorf("GenerateModule() error = %v, want alias cycle", err)
+	}
+}
+
+func TestLLVMCodegen_ElementAssignment(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	lit := func(v interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	module := singleFunctionModule("float", []ast.Parameter{
+		{Name: "a", Type: "array"}, {Name: "m", Type: "map"}, {Name: "p", Type: "Point"},
+	}, []ast.Statement{
+		{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprIndex, Object: variable("a"), Index: lit(1.0)}, Value: lit(9.0)},
+		{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprIndex, Object: variable("m"), Index: lit("k")}, Value: lit(2.0)},
+		{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "y"}, Value: lit(3.0)},
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "y"}},
+	})
+	module.Types = []ast.TypeDefinition{
+		{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+			{Name: "x", Type: "int"}, {Name: "y", Type: "float"},
+		}}},
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		"store i64 9, i64*",
+		"call void @alas_runtime_map_put(",
+		"sitofp i64 3 to double",
+		"getelementptr { i64, double }, { i64, double }* %p_ptr, i32 0, i32 1",
+		"store double",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+
+	// A field of a struct held in a struct field is stored in place
+	module = singleFunctionModule("int", []ast.Parameter{{Name: "s", Type: "Shape"}}, []ast.Statement{
+		{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField,
+			Object: &ast.Expression{Type: ast.ExprField, Object: variable("s"), Field: "origin"}, Field: "x"}, Value: lit(4.0)},
+		{Type: ast.StmtReturn, Value: lit(0.0)},
+	})
+	module.Types = []ast.TypeDefinition{
+		{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+			{Name: "x", Type: "int"}, {Name: "y", Type: "float"},
+		}}},
+		{Name: "Shape", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+			{Name: "sides", Type: "int"}, {Name: "origin", Type: "Point"},
+		}}},
+	}
+	ir = generateIR(t, module)
+	if !strings.Contains(ir, "getelementptr { i64, double }, { i64, double }*") || !strings.Contains(ir, "store i64 4, i64*") {
+		t.Errorf("expected a store through the nested field, got:\n%s", ir)
+	}
+}
+
+func TestLLVMCodegen_Destructure(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	lit := func(v interface{}) ast.Expression {
+		return ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	module := singleFunctionModule("float", []ast.Parameter{{Name: "p", Type: "Point"}}, []ast.Statement{
+		{Type: ast.StmtDestructure, Bindings: map[string]string{"x": "px", "y": "py"}, Value: variable("p")},
+		{Type: ast.StmtDestructure, Targets: []string{"_", "b"}, Value: &ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{lit(1.0), lit(2.0)}}},
+		{Type: ast.StmtReturn, Value: variable("py")},
+	})
+	module.Types = []ast.TypeDefinition{
+		{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+			{Name: "x", Type: "int"}, {Name: "y", Type: "float"},
+		}}},
+	}
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		// Struct fields are loaded straight from the variable
+		"extractvalue { i64, double } %0, 0",
+		"%px_ptr = alloca i64",
+		"%py_ptr = alloca double",
+		// Other sources are evaluated once into a hidden variable
+		"%destructure.0_ptr = alloca",
+		"%b_ptr = alloca i64",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_ParameterizedContainers(t *testing.T) {
+	index := func(object *ast.Expression, i float64) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: &ast.Expression{Type: ast.ExprLiteral, Value: i}}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	module := singleFunctionModule("float", []ast.Parameter{
+		{Name: "xs", Type: "array<float>"},
+		{Name: "grid", Type: "array<array<bool>>"},
+		{Name: "m", Type: "map<string,int>"},
+	}, []ast.Statement{
+		{Type: ast.StmtAssign, Target: "flag", Value: index(index(variable("grid"), 0), 1)},
+		{Type: ast.StmtReturn, Value: index(variable("xs"), 0)},
+	})
+
+	ir := generateIR(t, module)
+	for _, expected := range []string{
+		"define double @main({ i8*, i64 } %xs, { i8*, i64 } %grid, i8* %m)",
+		// Element types come from the declared array types instead of i64
+		"load { i8*, i64 }, { i8*, i64 }* %5",
+		"load i1, i1*",
+		"load double, double*",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_ArrayLiteralElementTypes(t *testing.T) {
+	lit := func(value interface{}) ast.Expression { return ast.Expression{Type: ast.ExprLiteral, Value: value} }
+	array := func(elements ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprArrayLit, Elements: elements}
+	}
+	index := func(object *ast.Expression, i float64) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: &ast.Expression{Type: ast.ExprLiteral, Value: i}}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+
+	tests := []struct {
+		name    string
+		returns string
+		literal *ast.Expression
+		index   func(*ast.Expression) *ast.Expression
+		want    []string
+	}{
+		{
+			name:    "floats",
+			returns: ast.TypeFloat,
+			literal: array(lit(0.5), lit(1.25)),
+			index:   func(a *ast.Expression) *ast.Expression { return index(a, 1) },
+			want:    []string{"load double, double*", "ret double"},
+		},
+		{
+			name:    "strings are returned unboxed",
+			returns: ast.TypeString,
+			literal: array(lit("red"), lit("blue")),
+			index:   func(a *ast.Expression) *ast.Expression { return index(a, 1) },
+			want:    []string{"load i8*, i8**", "ret i8* %"},
+		},
+		{
+			name:    "nested bools",
+			returns: ast.TypeBool,
+			literal: array(*array(lit(true)), *array(lit(false))),
+			index:   func(a *ast.Expression) *ast.Expression { return index(index(a, 1), 0) },
+			want:    []string{"load { i8*, i64 }, { i8*, i64 }*", "load i1, i1*", "ret i1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ir := generateIR(t, singleFunctionModule(tt.returns, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtAssign, Target: "a", Value: tt.literal},
+				{Type: ast.StmtReturn, Value: tt.index(variable("a"))},
+			}))
+			for _, want := range tt.want {
+				if !strings.Contains(ir, want) {
+					t.Errorf("expected IR to contain %q, got:\n%s", want, ir)
+				}
+			}
+			// Elements are never unboxed as if they were map lookup results
+			if strings.Contains(ir, "select i1") {
+				t.Errorf("expected the element to be used as loaded, got:\n%s", ir)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_CollectionsLengthOfArray(t *testing.T) {
+	module := singleFunctionModule("int", []ast.Parameter{{Name: "a", Type: "array"}}, []ast.Statement{{
+		Type: ast.StmtReturn,
+		Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "collections.length", Args: []ast.Expression{
+			{Type: ast.ExprVariable, Name: "a"},
+		}},
+	}})
+
+	ir := generateIR(t, module)
+	// The array is boxed with the array tag and a pointer to its {data, length} struct
+	for _, expected := range []string{
+		"store i32 4, i32*",
+		"store { i8*, i64 } %",
+		"call i8* @alas_builtin_collections_length(",
+	} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_ExternFunctions(t *testing.T) {
+	extern := func(name, symbol, returns string, params ...ast.Parameter) ast.Function {
+		return ast.Function{Type: "function", Name: name, Extern: symbol, Params: params, Returns: returns}
+	}
+	call := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCall, Name: name, Args: args}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "ffi",
+		Functions: []ast.Function{
+			extern("abs", "labs", ast.TypeInt, ast.Parameter{Name: "n", Type: ast.TypeInt}),
+			extern("magnitude", "labs", ast.TypeInt, ast.Parameter{Name: "n", Type: ast.TypeInt}),
+			extern("set_flag", "set_flag", ast.TypeVoid, ast.Parameter{Name: "on", Type: ast.TypeBool}),
+			{
+				Type:    "function",
+				Name:    "main",
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtExpr, Value: call("set_flag", ast.Expression{Type: ast.ExprLiteral, Value: true})},
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd,
+						Left:  call("abs", ast.Expression{Type: ast.ExprLiteral, Value: -3.0}),
+						Right: call("magnitude", ast.Expression{Type: ast.ExprLiteral, Value: -4.0}),
+					}},
+				},
+			},
+		},
+	}
+	ir := generateIR(t, module)
+
+	for _, want := range []string{
+		"declare i64 @labs(i64 %n)",
+		"declare void @set_flag(i1 zeroext %on)",
+		"call i64 @labs(i64 -3)",
+		"call i64 @labs(i64 -4)",
+		"call void @set_flag(i1 true)",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+	if n := strings.Count(ir, "@labs("); n != 3 {
+		t.Errorf("expected one shared labs declaration and two calls, got %d references\nIR:\n%s", n, ir)
+	}
+
+	// One symbol cannot be bound to two signatures
+	module.Functions[1].Returns = ast.TypeFloat
+	if _, err := NewLLVMCodegen().GenerateModule(module); err == nil || !strings.Contains(err.Error(), "extern symbol labs is already declared with a different signature") {
+		t.Errorf("GenerateModule() error = %v, want a signature conflict", err)
+	}
+}
+
+func TestLLVMCodegen_FunctionMeta(t *testing.T) {
+	fn := func(name string, meta map[string]interface{}) ast.Function {
+		return ast.Function{Type: "function", Name: name, Returns: ast.TypeInt, Meta: meta,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}}}}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "meta",
+		Functions: []ast.Function{
+			fn("hot", map[string]interface{}{"inline": "always"}),
+			fn("cold", map[string]interface{}{"inline": "never"}),
+			fn("debug", map[string]interface{}{"noopt": true, "inline": "never"}),
+			fn("helper", map[string]interface{}{"export": false}),
+			fn("api", map[string]interface{}{"export": true}),
+			fn("plain", map[string]interface{}{"author": "someone"}),
+		},
+	}
+	ir := generateIR(t, module)
+
+	for _, want := range []string{
+		"define i64 @hot() alwaysinline {",
+		"define i64 @cold() noinline {",
+		"define i64 @debug() noinline optnone {",
+		"define internal i64 @helper() {",
+		"define external i64 @api() {",
+		"define i64 @plain() {",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+}
+
+func TestLLVMCodegen_InlineHints(t *testing.T) {
+	v := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(n float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: n} }
+	// largeBody branches on n and then runs past the inlining size limit
+	largeBody := func() []ast.Statement {
+		body := []ast.Statement{
+			{Type: ast.StmtAssign, Target: "x", Value: v("n")},
+			{Type: ast.StmtIf, Cond: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpLt, Left: v("n"), Right: lit(0)},
+				Then: []ast.Statement{{Type: ast.StmtReturn, Value: lit(0)}}},
+		}
+		for i := 0; i < maxInlineInstructions; i++ {
+			body = append(body, ast.Statement{Type: ast.StmtAssign, Target: "x", Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd,
+				Left: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: v("x"), Right: v("n")}, Right: lit(float64(i))}})
+		}
+		return append(body, ast.Statement{Type: ast.StmtReturn, Value: v("x")})
+	}
+	smallBody := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: v("n"), Right: lit(1)}}}
+	fn := func(name string, body []ast.Statement, meta map[string]interface{}) ast.Function {
+		return ast.Function{Type: "function", Name: name, Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt, Meta: meta, Body: body}
+	}
+	call := func(name string, arg *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCall, Name: name, Args: []ast.Expression{*arg}}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "hints",
+		Functions: []ast.Function{
+			fn("large", largeBody(), nil),
+			fn("forced", largeBody(), map[string]interface{}{"inline": "always"}),
+			fn("small", smallBody, nil),
+			fn("kept", smallBody, map[string]interface{}{"inline": "never"}),
+			{Type: "function", Name: "main", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt, Body: []ast.Statement{
+				{Type: ast.StmtReturn, Value: call("large", call("forced", call("small", call("kept", v("n")))))},
+			}},
+		},
+	}
+
+	tests := []struct {
+		level OptimizationLevel
+		calls []string
+	}{
+		{level: OptBasic, calls: []string{"kept", "large", "small"}},
+		{level: OptAggressive, calls: []string{"kept", "large"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("level %d", tt.level), func(t *testing.T) {
+			compiled, err := NewLLVMCodegen().GenerateModule(module)
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			if err := NewOptimizer(tt.level).OptimizeModule(compiled); err != nil {
+				t.Fatalf("OptimizeModule failed: %v", err)
+			}
+
+			var calls []string
+			for _, fn := range compiled.Funcs {
+				if fn.Name() != "main" {
+					continue
+				}
+				for _, block := range fn.Blocks {
+					for _, inst := range block.Insts {
+						if c, ok := inst.(*ir.InstCall); ok {
+							calls = append(calls, c.Callee.Ident()[1:])
+						}
+					}
+				}
+			}
+			sort.Strings(calls)
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("main calls %v, want %v\nIR:\n%s", calls, tt.calls, compiled)
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_ForInitUpdate(t *testing.T) {
+	v := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(n float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: n} }
+	module := &ast.Module{
+		Type: "module",
+		Name: "loops",
+		Functions: []ast.Function{{
+			Type:    "function",
+			Name:    "sum",
+			Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+			Returns: ast.TypeInt,
+			Body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "total", Value: lit(0)},
+				{
+					Type:   ast.StmtFor,
+					Init:   []ast.Statement{{Type: ast.StmtAssign, Target: "i", Value: lit(7)}},
+					Cond:   &ast.Expression{Type: ast.ExprBinary, Op: ast.OpLt, Left: v("i"), Right: v("n")},
+					Update: []ast.Statement{{Type: ast.StmtAssign, Target: "i", Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: v("i"), Right: lit(3)}}},
+					Body:   []ast.Statement{{Type: ast.StmtAssign, Target: "total", Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: v("total"), Right: v("i")}}},
+				},
+				{Type: ast.StmtReturn, Value: v("total")},
+			},
+		}},
+	}
+	ir := generateIR(t, module)
+
+	for _, want := range []string{
+		// Init runs once, before the first jump to the condition
+		"store i64 7, i64* %i_ptr\n\tbr label %for.cond\n\nfor.cond:",
+		// Update ends the body, just before the back edge
+		"%7 = add i64 %6, 3\n\tstore i64 %7, i64* %i_ptr\n\tbr label %for.cond\n\nfor.end:",
+	} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+}
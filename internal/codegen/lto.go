@@ -0,0 +1,128 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/ir"
+)
+
+// LTOStats reports the effect of LinkTimeOptimize on a linked module.
+type LTOStats struct {
+	InstructionsBefore int // Instructions and terminators before the pass
+	InstructionsAfter  int // Instructions and terminators after the pass
+	Inlined            int // Cross-module call sites inlined
+	FunctionsRemoved   int // Module-qualified functions removed as dead
+}
+
+// Reduction returns the number of instructions removed by the pass.
+func (s LTOStats) Reduction() int {
+	return s.InstructionsBefore - s.InstructionsAfter
+}
+
+// LinkTimeOptimize optimizes a module produced by LinkModules as a whole
+// program. Small functions called across the original module boundaries are
+// inlined, the callers are optimized again, and module-qualified functions
+// no longer reachable from main are removed.
+func (opt *Optimizer) LinkTimeOptimize(module *ir.Module) LTOStats {
+	stats := LTOStats{InstructionsBefore: countInstructions(module)}
+
+	candidates := make(map[*ir.Func]bool)
+	for _, fn := range module.Funcs {
+		if linkedModuleOf(fn) != "" && opt.shouldInlineFunction(fn) {
+			candidates[fn] = true
+		}
+	}
+
+	for _, fn := range module.Funcs {
+		// Collect call sites first since inlining splits blocks
+		var calls []*ir.InstCall
+		for _, block := range fn.Blocks {
+			for _, inst := range block.Insts {
+				if call, ok := inst.(*ir.InstCall); ok {
+					if callee, ok := call.Callee.(*ir.Func); ok && candidates[callee] && linkedModuleOf(callee) != linkedModuleOf(fn) {
+						calls = append(calls, call)
+					}
+				}
+			}
+		}
+		inlined := 0
+		for _, call := range calls {
+			if opt.inlineFunction(fn, call) {
+				inlined++
+			}
+		}
+		if inlined > 0 {
+			stats.Inlined += inlined
+			opt.optimizeFunction(fn)
+		}
+	}
+
+	stats.FunctionsRemoved = removeUnreachableLinkedFunctions(module)
+	stats.InstructionsAfter = countInstructions(module)
+	return stats
+}
+
+// linkedModuleOf returns the module a linked function came from, taken from
+// its module__function name, or "" for main and unqualified functions.
+func linkedModuleOf(fn *ir.Func) string {
+	if i := strings.Index(fn.Name(), "__"); i > 0 {
+		return fn.Name()[:i]
+	}
+	return ""
+}
+
+// removeUnreachableLinkedFunctions removes module-qualified functions that
+// main cannot reach through calls or function references, and returns how
+// many were removed. Unqualified functions and declarations are kept.
+func removeUnreachableLinkedFunctions(module *ir.Module) int {
+	var root *ir.Func
+	for _, fn := range module.Funcs {
+		if fn.Name() == "main" {
+			root = fn
+		}
+	}
+	if root == nil {
+		return 0 // Without an entry point every export may be used
+	}
+
+	reachable := map[*ir.Func]bool{root: true}
+	worklist := []*ir.Func{root}
+	for len(worklist) > 0 {
+		fn := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		for _, block := range fn.Blocks {
+			operands := block.Term.Operands()
+			for _, inst := range block.Insts {
+				operands = append(operands, inst.Operands()...)
+			}
+			for _, operand := range operands {
+				if callee, ok := (*operand).(*ir.Func); ok && !reachable[callee] {
+					reachable[callee] = true
+					worklist = append(worklist, callee)
+				}
+			}
+		}
+	}
+
+	kept := make([]*ir.Func, 0, len(module.Funcs))
+	for _, fn := range module.Funcs {
+		if reachable[fn] || len(fn.Blocks) == 0 || linkedModuleOf(fn) == "" {
+			kept = append(kept, fn)
+		}
+	}
+	removed := len(module.Funcs) - len(kept)
+	module.Funcs = kept
+	return removed
+}
+
+// countInstructions returns the number of instructions and terminators in
+// the module's function bodies.
+func countInstructions(module *ir.Module) int {
+	count := 0
+	for _, fn := range module.Funcs {
+		for _, block := range fn.Blocks {
+			count += len(block.Insts) + 1
+		}
+	}
+	return count
+}
@@ -0,0 +1,121 @@
+package codegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// moduleCacheVersion is part of every cache key. Bump it when the cache
+// layout changes.
+const moduleCacheVersion = "alas-module-cache-1"
+
+// ModuleCache stores the LLVM IR generated for modules on disk, keyed by a
+// hash of each module's source and the keys of the modules it imports, so a
+// module is only regenerated when it or a transitive dependency changes.
+type ModuleCache struct {
+	dir string
+}
+
+// NewModuleCache returns a cache that keeps its files in dir, creating the
+// directory if needed.
+func NewModuleCache(dir string) (*ModuleCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create module cache directory: %w", err)
+	}
+	return &ModuleCache{dir: dir}, nil
+}
+
+// moduleCacheKey returns the cache key for module given the keys of its
+// imports. The key also covers the compiler binary, so upgrading the
+// compiler does not reuse IR generated by an older version.
+func moduleCacheKey(module *ast.Module, importKeys map[string]string) (string, error) {
+	source, err := json.Marshal(module)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash module %s: %w", module.Name, err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", moduleCacheVersion, compilerFingerprint(), module.File)
+	hash.Write(source)
+	imports := module.ImportedModules()
+	sort.Strings(imports)
+	for _, name := range imports {
+		fmt.Fprintf(hash, "\n%s=%s", name, importKeys[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Load returns the cached IR for the named module and key, if present.
+func (c *ModuleCache) Load(name, key string) (*ir.Module, bool) {
+	module, err := asm.ParseFile(c.path(name, key))
+	if err != nil {
+		return nil, false
+	}
+	return module, true
+}
+
+// Store writes the IR for the named module and key to the cache. The file
+// is written under a temporary name and renamed, so concurrent builds never
+// read a partial file.
+func (c *ModuleCache) Store(name, key string, module *ir.Module) error {
+	tmp, err := os.CreateTemp(c.dir, name+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write module cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(module.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write module cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write module cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(name, key)); err != nil {
+		return fmt.Errorf("failed to write module cache: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding the IR for the named module and key.
+func (c *ModuleCache) path(name, key string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s-%s.ll", name, key))
+}
+
+var (
+	fingerprintOnce sync.Once
+	fingerprint     string
+)
+
+// compilerFingerprint returns a hash of the running executable, or an empty
+// string if it cannot be read.
+func compilerFingerprint() string {
+	fingerprintOnce.Do(func() {
+		path, err := os.Executable()
+		if err != nil {
+			return
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err == nil {
+			fingerprint = hex.EncodeToString(hash.Sum(nil))
+		}
+	})
+	return fingerprint
+}
@@ -0,0 +1,92 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// compileWithCache compiles app, which imports mathx, with the given cache
+// and returns the modules loaded from it.
+func compileWithCache(t *testing.T, cache *ModuleCache, factor float64) (*MultiModuleCodegen, []string) {
+	t.Helper()
+	mathx := &ast.Module{
+		Name:    "mathx",
+		Exports: []string{"scale"},
+		Functions: []ast.Function{{
+			Name:    "scale",
+			Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+			Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    ast.OpMul,
+				Left:  &ast.Expression{Type: ast.ExprVariable, Name: "n"},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: factor},
+			}}},
+		}},
+	}
+	app := &ast.Module{
+		Name:    "app",
+		Imports: []ast.Import{{Module: "mathx"}},
+		Functions: []ast.Function{{
+			Name:    "main",
+			Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type:   ast.ExprModuleCall,
+				Module: "mathx",
+				Name:   "scale",
+				Args:   []ast.Expression{{Type: ast.ExprLiteral, Value: 21.0}},
+			}}},
+		}},
+	}
+
+	codegen := NewMultiModuleCodegen()
+	codegen.SetCache(cache)
+	for _, module := range []*ast.Module{mathx, app} {
+		if err := codegen.AddModule(module); err != nil {
+			t.Fatalf("AddModule failed: %v", err)
+		}
+	}
+	if _, err := codegen.CompileModules(); err != nil {
+		t.Fatalf("CompileModules failed: %v", err)
+	}
+	return codegen, codegen.CacheHits()
+}
+
+func TestModuleCache(t *testing.T) {
+	cache, err := NewModuleCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewModuleCache failed: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		factor   float64
+		wantHits string
+	}{
+		{"cold cache", 2, ""},
+		{"unchanged", 2, "mathx,app"},
+		{"dependency changed", 3, ""},
+		{"unchanged again", 3, "mathx,app"},
+	}
+	for _, step := range steps {
+		codegen, hits := compileWithCache(t, cache, step.factor)
+		if got := strings.Join(hits, ","); got != step.wantHits {
+			t.Errorf("%s: cache hits = %q, want %q", step.name, got, step.wantHits)
+		}
+
+		// Modules read back from the cache must still link
+		linked, err := codegen.LinkModules("app_linked")
+		if err != nil {
+			t.Fatalf("%s: LinkModules failed: %v", step.name, err)
+		}
+		want := "mul i64 %1, 3"
+		if step.factor == 2 {
+			want = "mul i64 %1, 2"
+		}
+		if !strings.Contains(linked.String(), want) {
+			t.Errorf("%s: linked module does not contain %q:\n%s", step.name, want, linked)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
 
 	"github.com/dshills/alas/internal/ast"
 )
@@ -17,6 +18,9 @@ type MultiModuleCodegen struct {
 	dependencies      map[string][]string          // Module name -> list of dependencies
 	externalFunctions map[string]*ExternalFunction // Qualified name -> function info
 	moduleLoaders     map[string]ModuleLoader      // Module name -> loader function
+	defaultLoader     ModuleLoader                 // Loader for modules without a registered loader
+	cache             *ModuleCache                 // On-disk cache of generated IR, if enabled
+	cacheHits         []string                     // Modules loaded from the cache by CompileModules
 }
 
 // ExternalFunction represents a function from another module.
@@ -47,6 +51,24 @@ func (m *MultiModuleCodegen) RegisterModuleLoader(moduleName string, loader Modu
 	m.moduleLoaders[moduleName] = loader
 }
 
+// SetDefaultLoader sets the loader used for modules that have no loader
+// registered with RegisterModuleLoader.
+func (m *MultiModuleCodegen) SetDefaultLoader(loader ModuleLoader) {
+	m.defaultLoader = loader
+}
+
+// SetCache makes CompileModules reuse IR from cache for modules whose
+// source and dependencies are unchanged, and store the IR it generates.
+func (m *MultiModuleCodegen) SetCache(cache *ModuleCache) {
+	m.cache = cache
+}
+
+// CacheHits returns the modules the last CompileModules loaded from the
+// cache instead of generating.
+func (m *MultiModuleCodegen) CacheHits() []string {
+	return m.cacheHits
+}
+
 // AddModule adds a module to be compiled.
 func (m *MultiModuleCodegen) AddModule(module *ast.Module) error {
 	if module.Name == "" {
@@ -54,7 +76,7 @@ func (m *MultiModuleCodegen) AddModule(module *ast.Module) error {
 	}
 
 	m.modules[module.Name] = module
-	m.dependencies[module.Name] = module.Imports
+	m.dependencies[module.Name] = module.ImportedModules()
 	return nil
 }
 
@@ -68,6 +90,9 @@ func (m *MultiModuleCodegen) LoadModule(name string) (*ast.Module, error) {
 	// Try to load using registered loaders
 	loader, exists := m.moduleLoaders[name]
 	if !exists {
+		loader = m.defaultLoader
+	}
+	if loader == nil {
 		return nil, fmt.Errorf("no loader registered for module: %s", name)
 	}
 
@@ -75,6 +100,9 @@ func (m *MultiModuleCodegen) LoadModule(name string) (*ast.Module, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load module %s: %v", name, err)
 	}
+	if module.Name != name {
+		return nil, fmt.Errorf("failed to load module %s: file declares module %s", name, module.Name)
+	}
 
 	// Add the loaded module
 	err = m.AddModule(module)
@@ -118,7 +146,7 @@ func (m *MultiModuleCodegen) loadDependenciesRecursive(moduleName string, visite
 	}
 
 	// Load all dependencies
-	for _, dep := range module.Imports {
+	for _, dep := range module.ImportedModules() {
 		if _, exists := m.modules[dep]; !exists {
 			if _, err := m.LoadModule(dep); err != nil {
 				return fmt.Errorf("failed to load dependency %s for module %s: %v", dep, moduleName, err)
@@ -233,12 +261,34 @@ func (m *MultiModuleCodegen) CompileModules() (map[string]*ir.Module, error) {
 	}
 
 	// Compile modules in order
+	m.cacheHits = nil
+	cacheKeys := make(map[string]string)
 	for _, moduleName := range order {
 		module := m.modules[moduleName]
 
+		// Reuse the cached IR when neither the module nor its dependencies changed
+		var cacheKey string
+		if m.cache != nil {
+			if cacheKey, err = moduleCacheKey(module, cacheKeys); err != nil {
+				return nil, err
+			}
+			cacheKeys[moduleName] = cacheKey
+			if cached, ok := m.cache.Load(moduleName, cacheKey); ok {
+				m.compiledModules[moduleName] = cached
+				m.cacheHits = append(m.cacheHits, moduleName)
+				continue
+			}
+		}
+
 		// Create enhanced LLVM codegen for this module
 		codegen := NewLLVMCodegen()
 
+		// Resolve imports from the modules already loaded here rather than
+		// searching the file system again
+		for name, loaded := range m.modules {
+			codegen.loadedModules[name] = loaded
+		}
+
 		// Set up external function declarations for this module's dependencies
 		if err := m.setupExternalDeclarations(codegen, module); err != nil {
 			return nil, fmt.Errorf("failed to setup external declarations for module %s: %v", moduleName, err)
@@ -250,8 +300,12 @@ func (m *MultiModuleCodegen) CompileModules() (map[string]*ir.Module, error) {
 			return nil, fmt.Errorf("failed to compile module %s: %v", moduleName, err)
 		}
 
-		// Store the compiled module
+		// Store the compiled module; a cache that cannot be written only
+		// costs a regeneration next time
 		m.compiledModules[moduleName] = llvmModule
+		if m.cache != nil {
+			_ = m.cache.Store(moduleName, cacheKey, llvmModule)
+		}
 	}
 
 	return m.compiledModules, nil
@@ -260,14 +314,24 @@ func (m *MultiModuleCodegen) CompileModules() (map[string]*ir.Module, error) {
 // setupExternalDeclarations sets up external function declarations for a module's dependencies.
 func (m *MultiModuleCodegen) setupExternalDeclarations(codegen *LLVMCodegen, module *ast.Module) error {
 	// For each dependency, declare its exported functions as external
-	for _, depName := range module.Imports {
+	for _, depName := range module.ImportedModules() {
 		depModule, exists := m.modules[depName]
 		if !exists {
 			return fmt.Errorf("dependency module %s not found", depName)
 		}
 
-		// Declare external functions for all functions in the dependency
+		// Declare external functions for the functions the dependency
+		// exports; calls to any other function are left undeclared
+		exported := make(map[string]bool, len(depModule.Exports))
+		for _, name := range depModule.Exports {
+			exported[name] = true
+		}
 		for _, fn := range depModule.Functions {
+			// Extern functions are left to the module's own import
+			// handling, which declares them by their C symbol
+			if !exported[fn.Name] || fn.Extern != "" {
+				continue
+			}
 			// Convert parameter types
 			paramTypes := make([]types.Type, len(fn.Params))
 			for i, param := range fn.Params {
@@ -284,52 +348,168 @@ func (m *MultiModuleCodegen) setupExternalDeclarations(codegen *LLVMCodegen, mod
 				return fmt.Errorf("invalid return type %s in function %s.%s: %v", fn.Returns, depName, fn.Name, err)
 			}
 
-			// Declare the external function
-			_, err = m.DeclareExternalFunction(codegen.module, depName, fn.Name, paramTypes, returnType)
+			// Declare the external function in this module's code generator,
+			// which also keeps its own import handling from declaring it again
+			llvmFunc, err := codegen.DeclareExternalFunction(depName, fn.Name, paramTypes, returnType)
 			if err != nil {
 				return fmt.Errorf("failed to declare external function %s.%s: %v", depName, fn.Name, err)
 			}
+			m.externalFunctions[m.GetQualifiedFunctionName(depName, fn.Name)] = &ExternalFunction{
+				Module:     depName,
+				Name:       fn.Name,
+				ParamTypes: paramTypes,
+				ReturnType: returnType,
+				LLVMFunc:   llvmFunc,
+			}
 		}
 	}
 
 	return nil
 }
 
-// LinkModules combines multiple LLVM modules into a single module.
+// LinkModules combines the compiled modules into a single module. Functions
+// other than main are renamed to their qualified module__function names, so
+// cross-module declarations resolve to the definitions they name and
+// functions of the same name in different modules do not collide.
 func (m *MultiModuleCodegen) LinkModules(targetName string) (*ir.Module, error) {
 	if len(m.compiledModules) == 0 {
 		return nil, fmt.Errorf("no modules to link")
 	}
+	order, err := m.topologicalSort()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a new module for the linked result
 	linkedModule := ir.NewModule()
 	linkedModule.SourceFilename = targetName
+	valueMap := make(map[value.Value]value.Value)
+	funcs := make(map[string]*ir.Func)
+	typeDefs := make(map[string]bool)
 
-	// Copy all functions from all modules into the linked module
-	for moduleName, module := range m.compiledModules {
+	// Create every global and function first so bodies can refer to any of them
+	for _, moduleName := range order {
+		module := m.compiledModules[moduleName]
+		if module == nil {
+			continue
+		}
+		for _, typeDef := range module.TypeDefs {
+			if !typeDefs[typeDef.Name()] {
+				typeDefs[typeDef.Name()] = true
+				linkedModule.TypeDefs = append(linkedModule.TypeDefs, typeDef)
+			}
+		}
+		for _, global := range module.Globals {
+			newGlobal := linkedModule.NewGlobalDef("", global.Init)
+			newGlobal.Immutable = global.Immutable
+			valueMap[global] = newGlobal
+		}
 		for _, fn := range module.Funcs {
-			// Create a new function in the linked module
-			_ = linkedModule.NewFunc(fn.Name(), fn.Sig)
+			if len(fn.Blocks) > 0 {
+				name := fn.Name()
+				if name != "main" {
+					name = m.GetQualifiedFunctionName(moduleName, name)
+				}
+				if _, exists := funcs[name]; exists && name == "main" {
+					return nil, fmt.Errorf("main is defined in more than one module")
+				}
+				funcs[name] = newLinkedFunc(linkedModule, name, fn)
+				valueMap[fn] = funcs[name]
+			}
+		}
+	}
 
-			// Copy function body if it exists
+	// Declarations resolve to a definition of the same name or to a single
+	// shared declaration
+	for _, moduleName := range order {
+		module := m.compiledModules[moduleName]
+		if module == nil {
+			continue
+		}
+		for _, fn := range module.Funcs {
 			if len(fn.Blocks) > 0 {
-				// For simplicity, we'll need to implement a proper function cloning mechanism
-				// This is a placeholder that shows the structure
-				_ = moduleName // Acknowledge parameter usage
-				// TODO: Implement proper function body copying with value mapping
+				continue
+			}
+			linked, exists := funcs[fn.Name()]
+			if !exists {
+				linked = newLinkedFunc(linkedModule, fn.Name(), fn)
+				funcs[fn.Name()] = linked
 			}
+			valueMap[fn] = linked
 		}
+	}
 
-		// Copy global variables
-		for _, global := range module.Globals {
-			newGlobal := linkedModule.NewGlobalDef(global.Name(), global.Init)
-			newGlobal.Immutable = global.Immutable
+	// Copy function bodies with their operands pointing into the linked module
+	for _, moduleName := range order {
+		module := m.compiledModules[moduleName]
+		if module == nil {
+			continue
+		}
+		for _, fn := range module.Funcs {
+			if len(fn.Blocks) > 0 {
+				if err := cloneFunctionBody(valueMap[fn].(*ir.Func), fn, valueMap); err != nil {
+					return nil, fmt.Errorf("failed to link %s.%s: %v", moduleName, fn.Name(), err)
+				}
+			}
 		}
 	}
 
 	return linkedModule, nil
 }
 
+// newLinkedFunc adds a function with fn's signature to the linked module.
+func newLinkedFunc(module *ir.Module, name string, fn *ir.Func) *ir.Func {
+	params := make([]*ir.Param, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = ir.NewParam(param.LocalName, param.Typ)
+	}
+	linked := module.NewFunc(name, fn.Sig.RetType, params...)
+	linked.Sig.Variadic = fn.Sig.Variadic
+	linked.Linkage = fn.Linkage
+	linked.FuncAttrs = fn.FuncAttrs
+	return linked
+}
+
+// cloneFunctionBody copies the blocks of src into dst. valueMap must map the
+// globals and functions src refers to; it is extended with src's parameters,
+// blocks and instructions.
+func cloneFunctionBody(dst, src *ir.Func, valueMap map[value.Value]value.Value) error {
+	for i, param := range src.Params {
+		valueMap[param] = dst.Params[i]
+	}
+	for _, block := range src.Blocks {
+		clone := dst.NewBlock(block.LocalName)
+		valueMap[block] = clone
+	}
+
+	cloner := NewOptimizer(OptNone)
+	for i, block := range src.Blocks {
+		clone := dst.Blocks[i]
+		for _, inst := range block.Insts {
+			instClone := cloner.cloneInstruction(inst)
+			if instClone == nil {
+				return fmt.Errorf("cannot copy instruction %s", inst.LLString())
+			}
+			if val, ok := inst.(value.Value); ok {
+				valueMap[val] = instClone.(value.Value)
+			}
+			clone.Insts = append(clone.Insts, instClone)
+		}
+		if clone.Term = cloneTerminator(block.Term); clone.Term == nil {
+			return fmt.Errorf("cannot copy terminator %s", block.Term.LLString())
+		}
+	}
+	for _, block := range dst.Blocks {
+		for _, inst := range block.Insts {
+			remapOperands(inst.Operands(), valueMap)
+			inheritMetadata(inst, nil)
+		}
+		remapOperands(block.Term.Operands(), valueMap)
+		inheritMetadata(block.Term, nil)
+	}
+	return nil
+}
+
 // GetExternalFunctions returns all declared external functions.
 func (m *MultiModuleCodegen) GetExternalFunctions() map[string]*ExternalFunction {
 	return m.externalFunctions
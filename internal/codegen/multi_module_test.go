@@ -2,8 +2,10 @@ package codegen
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/types"
 
 	"github.com/dshills/alas/internal/ast"
@@ -44,19 +46,19 @@ func TestMultiModuleCodegen_ResolveDependencies(t *testing.T) {
 	// Create modules with dependencies: A -> B -> C
 	moduleC := &ast.Module{
 		Name:      "moduleC",
-		Imports:   []string{},
+		Imports:   []ast.Import{},
 		Functions: []ast.Function{{Name: "funcC", Returns: "int"}},
 	}
 
 	moduleB := &ast.Module{
 		Name:      "moduleB",
-		Imports:   []string{"moduleC"},
+		Imports:   []ast.Import{{Module: "moduleC"}},
 		Functions: []ast.Function{{Name: "funcB", Returns: "int"}},
 	}
 
 	moduleA := &ast.Module{
 		Name:      "moduleA",
-		Imports:   []string{"moduleB"},
+		Imports:   []ast.Import{{Module: "moduleB"}},
 		Functions: []ast.Function{{Name: "funcA", Returns: "int"}},
 	}
 
@@ -101,13 +103,13 @@ func TestMultiModuleCodegen_CircularDependency(t *testing.T) {
 	// Create circular dependency: A -> B -> A
 	moduleA := &ast.Module{
 		Name:      "moduleA",
-		Imports:   []string{"moduleB"},
+		Imports:   []ast.Import{{Module: "moduleB"}},
 		Functions: []ast.Function{{Name: "funcA", Returns: "int"}},
 	}
 
 	moduleB := &ast.Module{
 		Name:      "moduleB",
-		Imports:   []string{"moduleA"},
+		Imports:   []ast.Import{{Module: "moduleA"}},
 		Functions: []ast.Function{{Name: "funcB", Returns: "int"}},
 	}
 
@@ -170,6 +172,56 @@ func TestMultiModuleCodegen_RegisterModuleLoader(t *testing.T) {
 	}
 }
 
+func TestMultiModuleCodegen_DefaultLoader(t *testing.T) {
+	available := map[string]*ast.Module{
+		"geometry": {Name: "geometry", Imports: []ast.Import{{Module: "numbers"}}},
+		"numbers":  {Name: "numbers"},
+		"renamed":  {Name: "something_else"},
+	}
+	var requested []string
+	loader := func(name string) (*ast.Module, error) {
+		requested = append(requested, name)
+		if module, ok := available[name]; ok {
+			return module, nil
+		}
+		return nil, errors.New("module not found")
+	}
+
+	codegen := NewMultiModuleCodegen()
+	codegen.SetDefaultLoader(loader)
+	if err := codegen.AddModule(&ast.Module{Name: "app", Imports: []ast.Import{{Module: "geometry"}}}); err != nil {
+		t.Fatalf("AddModule failed: %v", err)
+	}
+
+	order, err := codegen.ResolveDependencies()
+	if err != nil {
+		t.Fatalf("ResolveDependencies failed: %v", err)
+	}
+	want := []string{"numbers", "geometry", "app"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected order %v, got %v", want, order)
+	}
+	if strings.Join(requested, ",") != "geometry,numbers" {
+		t.Errorf("Expected the default loader to load geometry then numbers, got %v", requested)
+	}
+
+	// A registered loader takes precedence over the default loader
+	registered := &ast.Module{Name: "numbers"}
+	codegen = NewMultiModuleCodegen()
+	codegen.SetDefaultLoader(loader)
+	codegen.RegisterModuleLoader("numbers", func(string) (*ast.Module, error) { return registered, nil })
+	if module, err := codegen.LoadModule("numbers"); err != nil || module != registered {
+		t.Errorf("Expected the registered loader's module, got %v, %v", module, err)
+	}
+
+	if _, err := codegen.LoadModule("missing"); err == nil {
+		t.Error("Expected an error for a module the loader cannot find")
+	}
+	if _, err := codegen.LoadModule("renamed"); err == nil || !strings.Contains(err.Error(), "something_else") {
+		t.Errorf("Expected an error naming the declared module, got %v", err)
+	}
+}
+
 func TestLLVMCodegen_DeclareExternalFunction(t *testing.T) {
 	codegen := NewLLVMCodegen()
 
@@ -197,6 +249,9 @@ func TestLLVMCodegen_DeclareExternalFunction(t *testing.T) {
 	if externalFunc.Name() != expectedName {
 		t.Errorf("Expected function name %s, got %s", expectedName, externalFunc.Name())
 	}
+	if !externalFunc.Sig.RetType.Equal(returnType) || len(externalFunc.Params) != 2 {
+		t.Errorf("Expected signature i64 (i64, i64), got %s", externalFunc.Sig)
+	}
 
 	// Test that redeclaring returns the same function
 	externalFunc2, err := codegen.DeclareExternalFunction("math_utils", "add", paramTypes, returnType)
@@ -267,3 +322,443 @@ func TestMultiModuleCodegen_CompileModules_Simple(t *testing.T) {
 		t.Errorf("simple_module not found in compiled modules")
 	}
 }
+
+// newLTOTestCodegen returns a codegen holding an app module whose main calls
+// mathx.double, and a mathx module that also exports an unused function.
+func newLTOTestCodegen(t *testing.T) *MultiModuleCodegen {
+	t.Helper()
+	mathx := &ast.Module{
+		Name:    "mathx",
+		Exports: []string{"double", "unused"},
+		Functions: []ast.Function{
+			{
+				Name:    "double",
+				Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+					Type:  ast.ExprBinary,
+					Op:    ast.OpMul,
+					Left:  &ast.Expression{Type: ast.ExprVariable, Name: "n"},
+					Right: &ast.Expression{Type: ast.ExprLiteral, Value: 2.0},
+				}}},
+			},
+			{
+				Name:    "unused",
+				Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body:    []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "n"}}},
+			},
+		},
+	}
+	app := &ast.Module{
+		Name:    "app",
+		Imports: []ast.Import{{Module: "mathx"}},
+		Functions: []ast.Function{
+			{
+				Name:    "main",
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+					Type:   ast.ExprModuleCall,
+					Module: "mathx",
+					Name:   "double",
+					Args:   []ast.Expression{{Type: ast.ExprLiteral, Value: 21.0}},
+				}}},
+			},
+		},
+	}
+
+	codegen := NewMultiModuleCodegen()
+	for _, module := range []*ast.Module{mathx, app} {
+		if err := codegen.AddModule(module); err != nil {
+			t.Fatalf("AddModule failed: %v", err)
+		}
+	}
+	if _, err := codegen.CompileModules(); err != nil {
+		t.Fatalf("CompileModules failed: %v", err)
+	}
+	return codegen
+}
+
+func TestMultiModuleCodegen_LinkModules(t *testing.T) {
+	codegen := newLTOTestCodegen(t)
+	linked, err := codegen.LinkModules("app_linked")
+	if err != nil {
+		t.Fatalf("LinkModules failed: %v", err)
+	}
+
+	defined := make(map[string]*ir.Func)
+	for _, fn := range linked.Funcs {
+		if len(fn.Blocks) > 0 {
+			defined[fn.Name()] = fn
+		}
+	}
+	for _, name := range []string{"main", "mathx__double", "mathx__unused"} {
+		if defined[name] == nil {
+			t.Fatalf("linked module does not define %s:\n%s", name, linked)
+		}
+	}
+
+	// main's call must resolve to the linked definition, not a declaration
+	var callee *ir.Func
+	for _, block := range defined["main"].Blocks {
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok {
+				callee, _ = call.Callee.(*ir.Func)
+			}
+		}
+	}
+	if callee != defined["mathx__double"] {
+		t.Errorf("main calls %v, want the linked mathx__double", callee)
+	}
+}
+
+func TestOptimizer_LinkTimeOptimize(t *testing.T) {
+	codegen := newLTOTestCodegen(t)
+	linked, err := codegen.LinkModules("app_linked")
+	if err != nil {
+		t.Fatalf("LinkModules failed: %v", err)
+	}
+
+	stats := NewOptimizer(OptBasic).LinkTimeOptimize(linked)
+	if stats.Inlined != 1 {
+		t.Errorf("Inlined = %d, want 1", stats.Inlined)
+	}
+	if stats.FunctionsRemoved != 2 {
+		t.Errorf("FunctionsRemoved = %d, want 2", stats.FunctionsRemoved)
+	}
+	if stats.Reduction() <= 0 {
+		t.Errorf("expected an instruction count reduction, got %d -> %d", stats.InstructionsBefore, stats.InstructionsAfter)
+	}
+
+	for _, fn := range linked.Funcs {
+		if strings.HasPrefix(fn.Name(), "mathx__") {
+			t.Errorf("dead function %s was not removed", fn.Name())
+		}
+		for _, block := range fn.Blocks {
+			for _, inst := range block.Insts {
+				if call, ok := inst.(*ir.InstCall); ok {
+					if callee, ok := call.Callee.(*ir.Func); ok && callee.Name() == "mathx__double" {
+						t.Errorf("call to mathx__double was not inlined")
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMultiModuleCodegen_DependencyGraph(t *testing.T) {
+	available := map[string]*ast.Module{
+		"geometry": {Name: "geometry", Imports: []ast.Import{{Module: "numbers"}, {Module: "format"}}},
+		"numbers":  {Name: "numbers"},
+		"format":   {Name: "format", Imports: []ast.Import{{Module: "numbers"}}},
+		"ping":     {Name: "ping", Imports: []ast.Import{{Module: "pong"}}},
+		"pong":     {Name: "pong", Imports: []ast.Import{{Module: "ping"}}},
+	}
+	loader := func(name string) (*ast.Module, error) {
+		if module, ok := available[name]; ok {
+			return module, nil
+		}
+		return nil, errors.New("module not found")
+	}
+
+	tests := []struct {
+		name       string
+		imports    []ast.Import
+		wantOrder  string
+		wantCycles string
+		wantTree   []string
+		wantDOT    []string
+	}{
+		{
+			name:      "shared dependency",
+			imports:   []ast.Import{{Module: "geometry"}},
+			wantOrder: "numbers,format,geometry,app",
+			wantTree:  []string{"└── geometry\n", "    ├── numbers\n", "        └── numbers (see above)\n", "Build order: numbers, format, geometry, app"},
+			wantDOT:   []string{`"app" [shape=box];`, `"geometry" -> "format";`, `"format" -> "numbers";`},
+		},
+		{
+			name:       "cycle",
+			imports:    []ast.Import{{Module: "ping"}},
+			wantCycles: "ping,pong,ping",
+			wantTree:   []string{"        └── ping (cycle)\n", "Cycle: ping -> pong -> ping"},
+			wantDOT:    []string{`"pong" -> "ping" [color=red];`},
+		},
+		{
+			name:     "missing module",
+			imports:  []ast.Import{{Module: "numbers"}, {Module: "absent"}},
+			wantTree: []string{"└── absent (missing)\n", "Missing module absent:"},
+			wantDOT:  []string{`"absent" [style=dashed];`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codegen := NewMultiModuleCodegen()
+			codegen.SetDefaultLoader(loader)
+			if err := codegen.AddModule(&ast.Module{Name: "app", Imports: tt.imports}); err != nil {
+				t.Fatalf("AddModule failed: %v", err)
+			}
+
+			graph := codegen.DependencyGraph("app")
+			if got := strings.Join(graph.Order, ","); got != tt.wantOrder {
+				t.Errorf("Order = %q, want %q", got, tt.wantOrder)
+			}
+			var cycles []string
+			for _, cycle := range graph.Cycles {
+				cycles = append(cycles, strings.Join(cycle, ","))
+			}
+			if got := strings.Join(cycles, ";"); got != tt.wantCycles {
+				t.Errorf("Cycles = %q, want %q", got, tt.wantCycles)
+			}
+			if graph.HasProblems() != (tt.wantOrder == "") {
+				t.Errorf("HasProblems = %v with order %q", graph.HasProblems(), tt.wantOrder)
+			}
+
+			var tree, dot strings.Builder
+			graph.WriteTree(&tree)
+			graph.WriteDOT(&dot)
+			for _, want := range tt.wantTree {
+				if !strings.Contains(tree.String(), want) {
+					t.Errorf("tree does not contain %q:\n%s", want, tree.String())
+				}
+			}
+			for _, want := range tt.wantDOT {
+				if !strings.Contains(dot.String(), want) {
+					t.Errorf("DOT output does not contain %q:\n%s", want, dot.String())
+				}
+			}
+		})
+	}
+}
+
+func TestMultiModuleCodegen_ImportAlias(t *testing.T) {
+	mathx := &ast.Module{
+		Name:    "mathx",
+		Exports: []string{"double"},
+		Functions: []ast.Function{{
+			Name:    "double",
+			Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+			Returns: ast.TypeInt,
+			Body:    []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "n"}}},
+		}},
+	}
+	app := &ast.Module{
+		Name:    "app",
+		Imports: []ast.Import{{Module: "mathx", Alias: "m"}},
+		Functions: []ast.Function{{
+			Name:    "main",
+			Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type:   ast.ExprModuleCall,
+				Module: "m",
+				Name:   "double",
+				Args:   []ast.Expression{{Type: ast.ExprLiteral, Value: 21.0}},
+			}}},
+		}},
+	}
+
+	codegen := NewMultiModuleCodegen()
+	for _, module := range []*ast.Module{mathx, app} {
+		if err := codegen.AddModule(module); err != nil {
+			t.Fatalf("AddModule failed: %v", err)
+		}
+	}
+	compiled, err := codegen.CompileModules()
+	if err != nil {
+		t.Fatalf("CompileModules failed: %v", err)
+	}
+
+	// The call goes through the alias to the function under its module's name
+	if ir := compiled["app"].String(); !strings.Contains(ir, "call i64 @mathx__double(i64 21)") {
+		t.Errorf("app does not call mathx__double:\n%s", ir)
+	}
+}
+
+func TestMultiModuleCodegen_ImportedExtern(t *testing.T) {
+	cmath := &ast.Module{
+		Name:    "cmath",
+		Exports: []string{"abs"},
+		Functions: []ast.Function{{
+			Name:    "abs",
+			Extern:  "labs",
+			Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+			Returns: ast.TypeInt,
+		}},
+	}
+	app := &ast.Module{
+		Name:    "app",
+		Imports: []ast.Import{{Module: "cmath"}},
+		Functions: []ast.Function{{
+			Name:    "main",
+			Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type:   ast.ExprModuleCall,
+				Module: "cmath",
+				Name:   "abs",
+				Args:   []ast.Expression{{Type: ast.ExprLiteral, Value: -4.0}},
+			}}},
+		}},
+	}
+
+	codegen := NewMultiModuleCodegen()
+	for _, module := range []*ast.Module{cmath, app} {
+		if err := codegen.AddModule(module); err != nil {
+			t.Fatalf("AddModule failed: %v", err)
+		}
+	}
+	if _, err := codegen.CompileModules(); err != nil {
+		t.Fatalf("CompileModules failed: %v", err)
+	}
+	linked, err := codegen.LinkModules("app")
+	if err != nil {
+		t.Fatalf("LinkModules failed: %v", err)
+	}
+
+	// The call goes to the C symbol, not to a cmath__abs definition
+	ir := linked.String()
+	for _, want := range []string{"declare i64 @labs(i64 %n)", "call i64 @labs(i64 -4)"} {
+		if !strings.Contains(ir, want) {
+			t.Errorf("expected linked IR to contain %q\nIR:\n%s", want, ir)
+		}
+	}
+	if strings.Contains(ir, "cmath__abs") {
+		t.Errorf("linked IR refers to cmath__abs:\n%s", ir)
+	}
+}
+
+// staticResolver resolves modules from a map.
+type staticResolver map[string]*ast.Module
+
+func (r staticResolver) LoadModuleByName(name string) (*ast.Module, error) {
+	if module, ok := r[name]; ok {
+		return module, nil
+	}
+	return nil, errors.New("module not found")
+}
+
+func TestLLVMCodegen_ImportedExports(t *testing.T) {
+	returnN := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "n"}}}
+	mathx := &ast.Module{
+		Name:        "mathx",
+		Exports:     []string{"double"},
+		TypeExports: []string{"Pair"},
+		Types: []ast.TypeDefinition{
+			{Name: "Pair", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{{Name: "a", Type: ast.TypeInt}}}},
+			{Name: "Cache", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{{Name: "n", Type: ast.TypeInt}}}},
+		},
+		Functions: []ast.Function{
+			{Name: "double", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt, Body: returnN},
+			{Name: "secret", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt, Body: returnN},
+		},
+	}
+	callMathx := func(name string) *ast.Module {
+		return &ast.Module{
+			Name:    "app",
+			Imports: []ast.Import{{Module: "mathx"}},
+			Functions: []ast.Function{{
+				Name:    "main",
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+					Type: ast.ExprModuleCall, Module: "mathx", Name: name,
+					Args: []ast.Expression{{Type: ast.ExprLiteral, Value: 1.0}},
+				}}},
+			}},
+		}
+	}
+
+	g := NewLLVMCodegenWithLoader(staticResolver{"mathx": mathx})
+	if _, err := g.GenerateModule(callMathx("double")); err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if _, ok := g.externalFunctions["mathx__secret"]; ok {
+		t.Errorf("unexported function mathx.secret was declared")
+	}
+	if _, ok := g.customTypes["mathx__Pair"]; !ok {
+		t.Errorf("exported type mathx.Pair is not visible")
+	}
+	if _, ok := g.customTypes["mathx__Cache"]; ok {
+		t.Errorf("unexported type mathx.Cache is visible")
+	}
+
+	g = NewLLVMCodegenWithLoader(staticResolver{"mathx": mathx})
+	_, err := g.GenerateModule(callMathx("secret"))
+	if err == nil || !strings.Contains(err.Error(), "function 'secret' is not exported from module 'mathx'") {
+		t.Errorf("GenerateModule error = %v, want unexported function error", err)
+	}
+
+	codegen := NewMultiModuleCodegen()
+	for _, module := range []*ast.Module{mathx, callMathx("secret")} {
+		if err := codegen.AddModule(module); err != nil {
+			t.Fatalf("AddModule failed: %v", err)
+		}
+	}
+	if _, err := codegen.CompileModules(); err == nil || !strings.Contains(err.Error(), "is not exported") {
+		t.Errorf("CompileModules error = %v, want unexported function error", err)
+	}
+}
+
+func TestLLVMCodegen_ImportedCallSignatures(t *testing.T) {
+	returnX := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}}}
+	mathx := &ast.Module{
+		Name:    "mathx",
+		Exports: []string{"half"},
+		Functions: []ast.Function{
+			{Name: "half", Params: []ast.Parameter{{Name: "x", Type: ast.TypeFloat}}, Returns: ast.TypeFloat, Body: returnX},
+		},
+	}
+	callHalf := func(args ...ast.Expression) *ast.Module {
+		return &ast.Module{
+			Name:    "app",
+			Imports: []ast.Import{{Module: "mathx"}},
+			Functions: []ast.Function{{
+				Name:    "main",
+				Returns: ast.TypeFloat,
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+					Type: ast.ExprModuleCall, Module: "mathx", Name: "half", Args: args,
+				}}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []ast.Expression
+		wantIR  string
+		wantErr string
+	}{
+		{
+			name:   "int argument converted to a float parameter",
+			args:   []ast.Expression{{Type: ast.ExprLiteral, Value: 4.0}},
+			wantIR: "sitofp i64 4 to double",
+		},
+		{
+			name:    "wrong argument count",
+			args:    []ast.Expression{},
+			wantErr: "function 'half' from module 'mathx' expects 1 arguments, got 0",
+		},
+		{
+			name:    "wrong argument type",
+			args:    []ast.Expression{{Type: ast.ExprLiteral, Value: "four"}},
+			wantErr: "argument 0 to function 'half' from module 'mathx' has type i8*, expected double",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewLLVMCodegenWithLoader(staticResolver{"mathx": mathx})
+			module, err := g.GenerateModule(callHalf(tt.args...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GenerateModule error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			if ir := module.String(); !strings.Contains(ir, tt.wantIR) {
+				t.Errorf("IR does not contain %q:\n%s", tt.wantIR, ir)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package codegen
 
 import (
 	"fmt"
+	"math/bits"
+	"reflect"
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
@@ -15,9 +18,9 @@ type OptimizationLevel int
 const (
 	// OptNone - No optimizations.
 	OptNone OptimizationLevel = iota
-	// OptBasic - Basic optimizations (constant folding, DCE).
+	// OptBasic - Basic optimizations (constant folding, peephole, DCE).
 	OptBasic
-	// OptStandard - Standard optimizations (includes mem2reg, CSE).
+	// OptStandard - Standard optimizations (includes mem2reg, constant propagation, strength reduction, CSE).
 	OptStandard
 	// OptAggressive - Aggressive optimizations (includes inlining, loop opts).
 	OptAggressive
@@ -58,16 +61,22 @@ func (opt *Optimizer) optimizeFunction(fn *ir.Func) {
 	if len(fn.Blocks) == 0 {
 		return // External function
 	}
+	if hasFuncAttr(fn, enum.FuncAttrOptNone) {
+		return // Left unoptimized on request
+	}
 
 	// mem2reg should run first as it enables other optimizations
 	if opt.level >= OptBasic {
 		opt.mem2reg(fn)
 		opt.constantFolding(fn)
+		opt.peephole(fn)
 		opt.deadCodeElimination(fn)
 	}
 
 	// Standard optimizations
 	if opt.level >= OptStandard {
+		opt.constantPropagation(fn)
+		opt.strengthReduction(fn)
 		opt.commonSubexpressionElimination(fn)
 		opt.simplifyCFG(fn)
 	}
@@ -80,57 +89,48 @@ func (opt *Optimizer) optimizeFunction(fn *ir.Func) {
 
 // OptimizeModule applies module-level optimizations after function optimizations.
 func (opt *Optimizer) optimizeModule(module *ir.Module) error {
-	// Aggressive optimizations
-	if opt.level >= OptAggressive {
-		opt.inlineSmallFunctions(module)
-	}
+	// Below OptAggressive only alwaysinline functions are inlined
+	opt.inlineSmallFunctions(module)
 
 	return nil
 }
 
-// inlineSmallFunctions inlines small functions to improve performance.
+// maxInlineInstructions is the size, in instructions and terminators, above
+// which a function is not inlined.
+const maxInlineInstructions = 40
+
+// inlineSmallFunctions inlines calls to small functions, and to functions
+// marked alwaysinline whatever their size. Below OptAggressive only the
+// alwaysinline functions are inlined. Only call sites that exist before
+// inlining starts are expanded, so recursive functions cannot grow without
+// bound.
 func (opt *Optimizer) inlineSmallFunctions(module *ir.Module) {
 	// Find functions that are candidates for inlining
-	inlineCandidates := make(map[string]*ir.Func)
-
+	inlineCandidates := make(map[*ir.Func]bool)
 	for _, fn := range module.Funcs {
-		if opt.shouldInlineFunction(fn) {
-			inlineCandidates[fn.GlobalName] = fn
+		if opt.shouldInlineFunction(fn) && (opt.level >= OptAggressive || hasFuncAttr(fn, enum.FuncAttrAlwaysInline)) {
+			inlineCandidates[fn] = true
 		}
 	}
 
-	// Look for call sites to inline and collect them first
-	type inlineCandidate struct {
-		call   *ir.InstCall
-		target *ir.Func
-		block  *ir.Block
-		index  int
-	}
-	var toInline []inlineCandidate
-
 	for _, fn := range module.Funcs {
+		if hasFuncAttr(fn, enum.FuncAttrOptNone) {
+			continue
+		}
+		// Collect call sites first since inlining splits blocks
+		var calls []*ir.InstCall
 		for _, block := range fn.Blocks {
-			for i, inst := range block.Insts {
+			for _, inst := range block.Insts {
 				if call, ok := inst.(*ir.InstCall); ok {
-					if calledFn, ok := call.Callee.(*ir.Func); ok {
-						if candidate, exists := inlineCandidates[calledFn.GlobalName]; exists {
-							toInline = append(toInline, inlineCandidate{
-								call:   call,
-								target: candidate,
-								block:  block,
-								index:  i,
-							})
-						}
+					if callee, ok := call.Callee.(*ir.Func); ok && callee != fn && inlineCandidates[callee] {
+						calls = append(calls, call)
 					}
 				}
 			}
 		}
-	}
-
-	// Inline functions (in reverse order to avoid index issues)
-	for i := len(toInline) - 1; i >= 0; i-- {
-		candidate := toInline[i]
-		opt.inlineFunction(candidate.call, candidate.target, candidate.block, candidate.index)
+		for _, call := range calls {
+			opt.inlineFunction(fn, call)
+		}
 	}
 }
 
@@ -141,113 +141,371 @@ func (opt *Optimizer) shouldInlineFunction(fn *ir.Func) bool {
 		return false
 	}
 
-	// Don't inline external functions
-	if len(fn.Blocks) == 0 {
+	// Don't inline external or variadic functions, or ones marked noinline
+	if len(fn.Blocks) == 0 || fn.Sig.Variadic || hasFuncAttr(fn, enum.FuncAttrNoInline) {
 		return false
 	}
 
-	// Count instructions
+	// Count instructions, rejecting directly recursive functions
 	instructionCount := 0
 	for _, block := range fn.Blocks {
-		instructionCount += len(block.Insts)
+		instructionCount += len(block.Insts) + 1
+		for _, inst := range block.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && call.Callee == fn {
+				return false
+			}
+		}
 	}
+	return instructionCount <= maxInlineInstructions || hasFuncAttr(fn, enum.FuncAttrAlwaysInline)
+}
 
-	// Inline if function is small (fewer than 5 instructions)
-	// and has only one basic block (no control flow)
-	return instructionCount < 5 && len(fn.Blocks) == 1
+// hasFuncAttr reports whether fn has the function attribute attr.
+func hasFuncAttr(fn *ir.Func, attr enum.FuncAttr) bool {
+	for _, a := range fn.FuncAttrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
 }
 
-// inlineFunction inlines a function call.
-func (opt *Optimizer) inlineFunction(call *ir.InstCall, targetFn *ir.Func, block *ir.Block, callIndex int) {
-	// This is a simplified inlining implementation for single-block functions
-	if len(targetFn.Blocks) != 1 {
-		return // Skip complex functions for now
+// inlineFunction replaces call, located in fn, with a copy of the callee's
+// body. The block containing the call is split at the call site: the
+// instructions after the call move to a continuation block, the callee's
+// returns branch there, and a phi collects the returned values. It returns
+// false, leaving fn unchanged, if the callee cannot be cloned.
+func (opt *Optimizer) inlineFunction(fn *ir.Func, call *ir.InstCall) bool {
+	callee, ok := call.Callee.(*ir.Func)
+	if !ok || len(callee.Blocks) == 0 || len(call.Args) != len(callee.Params) {
+		return false
+	}
+	block, callIndex := findInstruction(fn, call)
+	if block == nil {
+		return false
 	}
 
-	targetBlock := targetFn.Blocks[0]
+	// Map parameters to arguments and callee blocks to their copies
+	valueMap := make(map[value.Value]value.Value)
+	for i, param := range callee.Params {
+		valueMap[param] = call.Args[i]
+	}
+	clones := make([]*ir.Block, len(callee.Blocks))
+	for i, calleeBlock := range callee.Blocks {
+		clones[i] = ir.NewBlock("")
+		clones[i].Parent = fn
+		valueMap[calleeBlock] = clones[i]
+	}
 
-	// Create a mapping from parameters to arguments
-	paramMap := make(map[value.Value]value.Value)
-	for i, param := range targetFn.Params {
-		if i < len(call.Args) {
-			paramMap[param] = call.Args[i]
+	// Clone instructions, then point their operands at the copies
+	for i, calleeBlock := range callee.Blocks {
+		for _, inst := range calleeBlock.Insts {
+			clone := opt.cloneInstruction(inst)
+			if clone == nil {
+				return false
+			}
+			if val, ok := inst.(value.Value); ok {
+				valueMap[val] = clone.(value.Value)
+			}
+			clones[i].Insts = append(clones[i].Insts, clone)
+		}
+		if clones[i].Term = cloneTerminator(calleeBlock.Term); clones[i].Term == nil {
+			return false
 		}
 	}
+	for _, clone := range clones {
+		for _, inst := range clone.Insts {
+			remapOperands(inst.Operands(), valueMap)
+			inheritMetadata(inst, call.Metadata)
+		}
+		remapOperands(clone.Term.Operands(), valueMap)
+		inheritMetadata(clone.Term, call.Metadata)
+	}
 
-	// Clone instructions from target function
-	var newInstructions []ir.Instruction
-	var returnValue value.Value
-
-	for _, inst := range targetBlock.Insts {
-		// Clone and substitute the instruction
-		newInst := opt.cloneInstruction(inst, paramMap)
-		if newInst != nil {
-			newInstructions = append(newInstructions, newInst)
+	// Split the caller block after the call
+	continuation := ir.NewBlock("")
+	continuation.Parent = fn
+	continuation.Insts = append(continuation.Insts, block.Insts[callIndex+1:]...)
+	continuation.Term = block.Term
+	for _, succ := range continuation.Term.Succs() {
+		replacePhiPredecessor(succ, block, continuation)
+	}
+	block.Insts = block.Insts[:callIndex]
+	entryBr := ir.NewBr(clones[0])
+	entryBr.Metadata = call.Metadata
+	block.Term = entryBr
+
+	// Returns become branches to the continuation
+	var incomings []*ir.Incoming
+	for _, clone := range clones {
+		if ret, ok := clone.Term.(*ir.TermRet); ok {
+			if ret.X != nil {
+				incomings = append(incomings, ir.NewIncoming(ret.X, clone))
+			}
+			br := ir.NewBr(continuation)
+			br.Metadata = ret.Metadata
+			clone.Term = br
 		}
 	}
+	var result value.Value
+	switch {
+	case len(incomings) == 1:
+		result = incomings[0].X
+	case len(incomings) > 1:
+		phi := ir.NewPhi(incomings...)
+		continuation.Insts = append([]ir.Instruction{phi}, continuation.Insts...)
+		result = phi
+	}
 
-	// Handle the return terminator
-	if targetBlock.Term != nil {
-		if retTerm, ok := targetBlock.Term.(*ir.TermRet); ok && retTerm.X != nil {
-			returnValue = opt.substituteOperands(retTerm.X, paramMap)
+	// Hoist the callee's stack allocations into the caller's entry block so
+	// calls inlined into loops don't grow the stack on every iteration
+	var allocas, rest []ir.Instruction
+	for _, inst := range clones[0].Insts {
+		if alloca, ok := inst.(*ir.InstAlloca); ok && alloca.NElems == nil {
+			allocas = append(allocas, inst)
+		} else {
+			rest = append(rest, inst)
 		}
 	}
+	clones[0].Insts = rest
+	fn.Blocks[0].Insts = append(allocas, fn.Blocks[0].Insts...)
+
+	// Place the callee's blocks between the call site and the continuation
+	newBlocks := make([]*ir.Block, 0, len(fn.Blocks)+len(clones)+1)
+	for _, b := range fn.Blocks {
+		newBlocks = append(newBlocks, b)
+		if b == block {
+			newBlocks = append(newBlocks, clones...)
+			newBlocks = append(newBlocks, continuation)
+		}
+	}
+	fn.Blocks = newBlocks
 
-	// Replace the call instruction with the inlined instructions
-	newBlockInsts := make([]ir.Instruction, 0, len(block.Insts)-1+len(newInstructions))
-	if callIndex > 0 && callIndex <= len(block.Insts) {
-		newBlockInsts = append(newBlockInsts, block.Insts[:callIndex]...)
+	if result != nil {
+		opt.replaceInstructionUses(call, result, fn)
 	}
-	newBlockInsts = append(newBlockInsts, newInstructions...)
-	if callIndex+1 < len(block.Insts) {
-		newBlockInsts = append(newBlockInsts, block.Insts[callIndex+1:]...)
+	return true
+}
+
+// findInstruction returns the block containing inst and its index.
+func findInstruction(fn *ir.Func, inst ir.Instruction) (*ir.Block, int) {
+	for _, block := range fn.Blocks {
+		for i, candidate := range block.Insts {
+			if candidate == inst {
+				return block, i
+			}
+		}
 	}
+	return nil, -1
+}
+
+// replacePhiPredecessor updates the phi nodes of block to name newPred
+// instead of oldPred as a predecessor.
+func replacePhiPredecessor(block, oldPred, newPred *ir.Block) {
+	for _, inst := range block.Insts {
+		if phi, ok := inst.(*ir.InstPhi); ok {
+			for _, inc := range phi.Incs {
+				if inc.Pred == oldPred {
+					inc.Pred = newPred
+				}
+			}
+		}
+	}
+}
 
-	block.Insts = newBlockInsts
+// remapOperands replaces operands found in valueMap with their mapped values.
+func remapOperands(operands []*value.Value, valueMap map[value.Value]value.Value) {
+	for _, operand := range operands {
+		if mapped, ok := valueMap[*operand]; ok {
+			*operand = mapped
+		}
+	}
+}
 
-	// Replace uses of the call result with the return value
-	if returnValue != nil {
-		opt.replaceAllUsesWith(call, returnValue)
+// inheritMetadata gives an inlined instruction or terminator the metadata of
+// the call it replaces, so debug locations refer to the caller.
+func inheritMetadata(inst interface{}, md ir.Metadata) {
+	if field, ok := metadataField(inst); ok {
+		field.Set(reflect.ValueOf(append(ir.Metadata(nil), md...)))
 	}
 }
 
-// cloneInstruction creates a copy of an instruction with substituted operands.
-func (opt *Optimizer) cloneInstruction(inst ir.Instruction, paramMap map[value.Value]value.Value) ir.Instruction {
-	// This is a simplified implementation that handles basic arithmetic
+// cloneInstruction returns an unnamed copy of inst with the same operands, or
+// nil if the instruction kind cannot be cloned. Operand lists are copied so
+// the clone's operands can be changed without affecting the original.
+func (opt *Optimizer) cloneInstruction(inst ir.Instruction) ir.Instruction {
+	var clone ir.Instruction
 	switch i := inst.(type) {
+	// Binary and bitwise instructions
 	case *ir.InstAdd:
-		x := opt.substituteOperands(i.X, paramMap)
-		y := opt.substituteOperands(i.Y, paramMap)
-		return ir.NewAdd(x, y)
+		c := *i
+		clone = &c
+	case *ir.InstFAdd:
+		c := *i
+		clone = &c
 	case *ir.InstSub:
-		x := opt.substituteOperands(i.X, paramMap)
-		y := opt.substituteOperands(i.Y, paramMap)
-		return ir.NewSub(x, y)
+		c := *i
+		clone = &c
+	case *ir.InstFSub:
+		c := *i
+		clone = &c
 	case *ir.InstMul:
-		x := opt.substituteOperands(i.X, paramMap)
-		y := opt.substituteOperands(i.Y, paramMap)
-		return ir.NewMul(x, y)
+		c := *i
+		clone = &c
+	case *ir.InstFMul:
+		c := *i
+		clone = &c
+	case *ir.InstUDiv:
+		c := *i
+		clone = &c
 	case *ir.InstSDiv:
-		x := opt.substituteOperands(i.X, paramMap)
-		y := opt.substituteOperands(i.Y, paramMap)
-		return ir.NewSDiv(x, y)
+		c := *i
+		clone = &c
+	case *ir.InstFDiv:
+		c := *i
+		clone = &c
+	case *ir.InstURem:
+		c := *i
+		clone = &c
+	case *ir.InstSRem:
+		c := *i
+		clone = &c
+	case *ir.InstFRem:
+		c := *i
+		clone = &c
+	case *ir.InstFNeg:
+		c := *i
+		clone = &c
+	case *ir.InstShl:
+		c := *i
+		clone = &c
+	case *ir.InstLShr:
+		c := *i
+		clone = &c
+	case *ir.InstAShr:
+		c := *i
+		clone = &c
+	case *ir.InstAnd:
+		c := *i
+		clone = &c
+	case *ir.InstOr:
+		c := *i
+		clone = &c
+	case *ir.InstXor:
+		c := *i
+		clone = &c
+
+	// Memory instructions
+	case *ir.InstAlloca:
+		c := *i
+		clone = &c
+	case *ir.InstLoad:
+		c := *i
+		clone = &c
+	case *ir.InstStore:
+		c := *i
+		clone = &c
+	case *ir.InstGetElementPtr:
+		c := *i
+		c.Indices = append([]value.Value(nil), i.Indices...)
+		clone = &c
+
+	// Conversion instructions
+	case *ir.InstTrunc:
+		c := *i
+		clone = &c
+	case *ir.InstZExt:
+		c := *i
+		clone = &c
+	case *ir.InstSExt:
+		c := *i
+		clone = &c
+	case *ir.InstFPTrunc:
+		c := *i
+		clone = &c
+	case *ir.InstFPExt:
+		c := *i
+		clone = &c
+	case *ir.InstFPToSI:
+		c := *i
+		clone = &c
+	case *ir.InstSIToFP:
+		c := *i
+		clone = &c
+	case *ir.InstPtrToInt:
+		c := *i
+		clone = &c
+	case *ir.InstIntToPtr:
+		c := *i
+		clone = &c
+	case *ir.InstBitCast:
+		c := *i
+		clone = &c
+
+	// Other instructions
 	case *ir.InstICmp:
-		x := opt.substituteOperands(i.X, paramMap)
-		y := opt.substituteOperands(i.Y, paramMap)
-		return ir.NewICmp(i.Pred, x, y)
-	// Add more instruction types as needed
+		c := *i
+		clone = &c
+	case *ir.InstFCmp:
+		c := *i
+		clone = &c
+	case *ir.InstSelect:
+		c := *i
+		clone = &c
+	case *ir.InstExtractValue:
+		c := *i
+		clone = &c
+	case *ir.InstInsertValue:
+		c := *i
+		clone = &c
+	case *ir.InstPhi:
+		c := *i
+		c.Incs = make([]*ir.Incoming, len(i.Incs))
+		for idx, inc := range i.Incs {
+			c.Incs[idx] = &ir.Incoming{X: inc.X, Pred: inc.Pred}
+		}
+		clone = &c
+	case *ir.InstCall:
+		c := *i
+		c.Args = append([]value.Value(nil), i.Args...)
+		clone = &c
 	default:
-		// For now, skip unknown instruction types
 		return nil
 	}
+
+	if named, ok := clone.(value.Named); ok {
+		named.SetName("")
+	}
+	return clone
 }
 
-// substituteOperands replaces parameters with arguments in operands.
-func (opt *Optimizer) substituteOperands(operand value.Value, paramMap map[value.Value]value.Value) value.Value {
-	if replacement, exists := paramMap[operand]; exists {
-		return replacement
+// cloneTerminator returns a copy of term with the same operands, or nil if the
+// terminator kind cannot be cloned.
+func cloneTerminator(term ir.Terminator) ir.Terminator {
+	switch t := term.(type) {
+	case *ir.TermRet:
+		c := *t
+		return &c
+	case *ir.TermBr:
+		c := *t
+		c.Successors = nil
+		return &c
+	case *ir.TermCondBr:
+		c := *t
+		c.Successors = nil
+		return &c
+	case *ir.TermSwitch:
+		c := *t
+		c.Successors = nil
+		c.Cases = make([]*ir.Case, len(t.Cases))
+		for idx, cs := range t.Cases {
+			c.Cases[idx] = &ir.Case{X: cs.X, Target: cs.Target}
+		}
+		return &c
+	case *ir.TermUnreachable:
+		c := *t
+		return &c
 	}
-	return operand
+	return nil
 }
 
 // constantFolding performs constant folding optimization.
@@ -306,6 +564,29 @@ func (opt *Optimizer) tryFoldInstruction(inst ir.Instruction) value.Value {
 			}
 			return a / b
 		})
+	case *ir.InstICmp:
+		return foldConstantOperands(i, i.X, i.Y)
+	case *ir.InstFCmp:
+		return foldConstantOperands(i, i.X, i.Y)
+	case *ir.InstAnd:
+		return foldConstantOperands(i, i.X, i.Y)
+	case *ir.InstOr:
+		return foldConstantOperands(i, i.X, i.Y)
+	}
+	return nil
+}
+
+// foldConstantOperands evaluates a binary instruction whose operands are both
+// constants, using the same rules as constant propagation. Comparisons fold
+// to i1 constants.
+func foldConstantOperands(inst ir.Instruction, x, y value.Value) value.Value {
+	constX, okX := x.(constant.Constant)
+	constY, okY := y.(constant.Constant)
+	if !okX || !okY {
+		return nil
+	}
+	if result := evaluateConstant(inst, []constant.Constant{constX, constY}); result != nil {
+		return result
 	}
 	return nil
 }
@@ -338,6 +619,197 @@ func (opt *Optimizer) foldFloatBinaryOp(x, y value.Value, op func(float64, float
 	return nil
 }
 
+// peephole applies local algebraic simplifications such as x+0 -> x and
+// x-x -> 0, replacing each simplified instruction with the equivalent value.
+func (opt *Optimizer) peephole(fn *ir.Func) {
+	changed := true
+	for changed {
+		changed = false
+		for _, block := range fn.Blocks {
+			for i := 0; i < len(block.Insts); i++ {
+				inst := block.Insts[i]
+				simplified := opt.simplifyInstruction(inst)
+				if simplified == nil {
+					continue
+				}
+				if instValue, ok := inst.(value.Value); ok {
+					opt.replaceInstructionUses(instValue, simplified, fn)
+					block.Insts = append(block.Insts[:i], block.Insts[i+1:]...)
+					i-- // Adjust index since we removed an instruction
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// simplifyInstruction returns a simpler value equivalent to inst, or nil if
+// no peephole rule applies. Floating-point identities such as x+0.0 are not
+// applied because they do not hold for -0.0 and NaN.
+func (opt *Optimizer) simplifyInstruction(inst ir.Instruction) value.Value {
+	switch i := inst.(type) {
+	case *ir.InstAdd:
+		if isIntConst(i.Y, 0) {
+			return i.X
+		}
+		if isIntConst(i.X, 0) {
+			return i.Y
+		}
+	case *ir.InstSub:
+		if isIntConst(i.Y, 0) {
+			return i.X
+		}
+		if i.X == i.Y {
+			return zeroOfIntType(i.Type())
+		}
+		// 0 - (0 - x) -> x
+		if inner, ok := i.Y.(*ir.InstSub); ok && isIntConst(i.X, 0) && isIntConst(inner.X, 0) {
+			return inner.Y
+		}
+	case *ir.InstMul:
+		if isIntConst(i.Y, 1) {
+			return i.X
+		}
+		if isIntConst(i.X, 1) {
+			return i.Y
+		}
+	case *ir.InstXor:
+		if isIntConst(i.Y, 0) {
+			return i.X
+		}
+		if isIntConst(i.X, 0) {
+			return i.Y
+		}
+		if i.X == i.Y {
+			return zeroOfIntType(i.Type())
+		}
+		// (x ^ c) ^ c -> x, which covers double boolean negation
+		if c, ok := i.Y.(*constant.Int); ok {
+			if inner, ok := i.X.(*ir.InstXor); ok {
+				if innerC, ok := inner.Y.(*constant.Int); ok && innerC.X.Cmp(c.X) == 0 {
+					return inner.X
+				}
+			}
+		}
+	case *ir.InstFNeg:
+		if inner, ok := i.X.(*ir.InstFNeg); ok {
+			return inner.X
+		}
+	case *ir.InstZExt:
+		if i.From.Type().Equal(i.To) {
+			return i.From
+		}
+	case *ir.InstSExt:
+		if i.From.Type().Equal(i.To) {
+			return i.From
+		}
+	case *ir.InstTrunc:
+		if i.From.Type().Equal(i.To) {
+			return i.From
+		}
+		// trunc (zext/sext x) back to the type of x -> x
+		switch ext := i.From.(type) {
+		case *ir.InstZExt:
+			if ext.From.Type().Equal(i.To) {
+				return ext.From
+			}
+		case *ir.InstSExt:
+			if ext.From.Type().Equal(i.To) {
+				return ext.From
+			}
+		}
+	}
+	return nil
+}
+
+// isIntConst reports whether v is the integer constant n.
+func isIntConst(v value.Value, n int64) bool {
+	c, ok := v.(*constant.Int)
+	return ok && c.X.IsInt64() && c.X.Int64() == n
+}
+
+// zeroOfIntType returns the zero constant of an integer type, or nil for
+// other types.
+func zeroOfIntType(t types.Type) value.Value {
+	intType, ok := t.(*types.IntType)
+	if !ok {
+		return nil
+	}
+	return constant.NewInt(intType, 0)
+}
+
+// strengthReduction rewrites integer multiplication and signed division by a
+// constant power of two into shifts.
+func (opt *Optimizer) strengthReduction(fn *ir.Func) {
+	for _, block := range fn.Blocks {
+		for i := 0; i < len(block.Insts); i++ {
+			replacement := opt.reduceInstruction(block.Insts[i])
+			if len(replacement) == 0 {
+				continue
+			}
+			result := replacement[len(replacement)-1].(value.Value)
+			opt.replaceInstructionUses(block.Insts[i].(value.Value), result, fn)
+
+			newInsts := make([]ir.Instruction, 0, len(block.Insts)+len(replacement)-1)
+			newInsts = append(newInsts, block.Insts[:i]...)
+			newInsts = append(newInsts, replacement...)
+			newInsts = append(newInsts, block.Insts[i+1:]...)
+			block.Insts = newInsts
+			i += len(replacement) - 1
+		}
+	}
+}
+
+// reduceInstruction returns the instructions replacing inst, the last of
+// which produces its result, or nil if inst cannot be strength-reduced.
+func (opt *Optimizer) reduceInstruction(inst ir.Instruction) []ir.Instruction {
+	switch i := inst.(type) {
+	case *ir.InstMul:
+		x, shift := i.X, powerOfTwoShift(i.Y)
+		if shift == nil {
+			x, shift = i.Y, powerOfTwoShift(i.X)
+		}
+		if shift == nil {
+			return nil
+		}
+		return []ir.Instruction{ir.NewShl(x, shift)}
+
+	case *ir.InstSDiv:
+		shift := powerOfTwoShift(i.Y)
+		if shift == nil {
+			return nil
+		}
+		// An arithmetic shift rounds toward negative infinity, but sdiv rounds
+		// toward zero. Adding 2^k-1 to negative dividends first corrects this:
+		// bias = (x >> (n-1)) >>> (n-k), result = (x + bias) >> k.
+		intType := i.Type().(*types.IntType)
+		n := int64(intType.BitSize)
+		sign := ir.NewAShr(i.X, constant.NewInt(intType, n-1))
+		bias := ir.NewLShr(sign, constant.NewInt(intType, n-shift.X.Int64()))
+		biased := ir.NewAdd(i.X, bias)
+		return []ir.Instruction{sign, bias, biased, ir.NewAShr(biased, shift)}
+	}
+	return nil
+}
+
+// powerOfTwoShift returns log2 of v as a constant of v's type if v is an
+// integer constant 2^k with 0 < k < bit width - 1, or nil otherwise.
+func powerOfTwoShift(v value.Value) *constant.Int {
+	c, ok := v.(*constant.Int)
+	if !ok || !c.X.IsInt64() {
+		return nil
+	}
+	n := c.X.Int64()
+	if n <= 1 || n&(n-1) != 0 {
+		return nil
+	}
+	k := bits.TrailingZeros64(uint64(n))
+	if k >= int(c.Typ.BitSize)-1 {
+		return nil
+	}
+	return constant.NewInt(c.Typ, int64(k))
+}
+
 // deadCodeElimination removes unused instructions and unreachable blocks.
 func (opt *Optimizer) deadCodeElimination(fn *ir.Func) {
 	// Mark all used instructions
@@ -405,11 +877,40 @@ func (opt *Optimizer) findUsedStores(fn *ir.Func) map[*ir.InstStore]bool {
 		}
 	}
 
-	// Second pass: mark stores to loaded allocas as used
+	// Allocas whose address is used other than by loads and stores, such as
+	// one passed to a call, may be read through the escaped pointer
+	for _, block := range fn.Blocks {
+		for _, inst := range block.Insts {
+			for _, operand := range inst.Operands() {
+				alloca, ok := (*operand).(*ir.InstAlloca)
+				if !ok {
+					continue
+				}
+				if load, ok := inst.(*ir.InstLoad); ok && load.Src == alloca {
+					continue
+				}
+				if store, ok := inst.(*ir.InstStore); ok && store.Dst == alloca && store.Src != alloca {
+					continue
+				}
+				loadedAllocas[alloca] = true
+			}
+		}
+		if block.Term != nil {
+			for _, operand := range block.Term.Operands() {
+				if alloca, ok := (*operand).(*ir.InstAlloca); ok {
+					loadedAllocas[alloca] = true
+				}
+			}
+		}
+	}
+
+	// Second pass: mark stores to loaded allocas as used. Stores through any
+	// other pointer, such as an array element, may be read through an alias
+	// and are kept.
 	for _, block := range fn.Blocks {
 		for _, inst := range block.Insts {
 			if store, ok := inst.(*ir.InstStore); ok {
-				if loadedAllocas[store.Dst] {
+				if _, isAlloca := store.Dst.(*ir.InstAlloca); loadedAllocas[store.Dst] || !isAlloca {
 					usedStores[store] = true
 				}
 			}
@@ -555,8 +1056,23 @@ func (opt *Optimizer) getExpressionKey(inst ir.Instruction) string {
 	return ""
 }
 
-// simplifyCFG simplifies the control flow graph.
+// simplifyCFG simplifies the control flow graph. Branches whose target is
+// known at compile time become unconditional, blocks that are no longer
+// reachable are removed, and blocks are merged into their only predecessor.
 func (opt *Optimizer) simplifyCFG(fn *ir.Func) {
+	folded := false
+	for _, block := range fn.Blocks {
+		if br := foldConstantBranch(block.Term); br != nil {
+			block.Term = br
+			folded = true
+		}
+	}
+	if folded {
+		// Phis keep the incoming values of the predecessors that remain
+		opt.removeUnreachableBlocks(fn)
+		removeStaleIncomings(fn)
+	}
+
 	changed := true
 	for changed {
 		changed = false
@@ -570,9 +1086,23 @@ func (opt *Optimizer) simplifyCFG(fn *ir.Func) {
 			if br, ok := block.Term.(*ir.TermBr); ok && br.Target == nextBlock {
 				// Check if nextBlock has only one predecessor
 				if opt.hasOnePredecessor(nextBlock, fn) {
+					// With a single predecessor, phis have one incoming value
+					insts := nextBlock.Insts
+					for len(insts) > 0 {
+						phi, ok := insts[0].(*ir.InstPhi)
+						if !ok {
+							break
+						}
+						if len(phi.Incs) == 1 {
+							opt.replaceInstructionUses(phi, phi.Incs[0].X, fn)
+						}
+						insts = insts[1:]
+					}
+
 					// Merge blocks
-					block.Insts = append(block.Insts, nextBlock.Insts...)
+					block.Insts = append(block.Insts, insts...)
 					block.Term = nextBlock.Term
+					retargetIncomings(nextBlock, block)
 
 					// Remove nextBlock from function
 					fn.Blocks = append(fn.Blocks[:i+1], fn.Blocks[i+2:]...)
@@ -584,6 +1114,53 @@ func (opt *Optimizer) simplifyCFG(fn *ir.Func) {
 	}
 }
 
+// foldConstantBranch returns an unconditional branch equivalent to a
+// conditional branch or switch whose target is known at compile time, or nil
+// if the target depends on a run-time value.
+func foldConstantBranch(term ir.Terminator) *ir.TermBr {
+	var br *ir.TermBr
+	switch t := term.(type) {
+	case *ir.TermCondBr:
+		switch {
+		case isIntConst(t.Cond, 1):
+			br = ir.NewBr(t.TargetTrue.(*ir.Block))
+		case isIntConst(t.Cond, 0):
+			br = ir.NewBr(t.TargetFalse.(*ir.Block))
+		case t.TargetTrue == t.TargetFalse:
+			br = ir.NewBr(t.TargetTrue.(*ir.Block))
+		default:
+			return nil
+		}
+		br.Metadata = t.Metadata
+	case *ir.TermSwitch:
+		x, ok := t.X.(*constant.Int)
+		if !ok {
+			return nil
+		}
+		br = ir.NewBr(switchTarget(t, x))
+		br.Metadata = t.Metadata
+	}
+	return br
+}
+
+// retargetIncomings updates the phis in the successors of to that name from
+// as a predecessor, after from has been merged into to.
+func retargetIncomings(from, to *ir.Block) {
+	for _, succ := range to.Term.Succs() {
+		for _, inst := range succ.Insts {
+			phi, ok := inst.(*ir.InstPhi)
+			if !ok {
+				break
+			}
+			for _, inc := range phi.Incs {
+				if inc.Pred == from {
+					inc.Pred = to
+				}
+			}
+		}
+	}
+}
+
 // hasOnePredecessor checks if a block has exactly one predecessor.
 func (opt *Optimizer) hasOnePredecessor(target *ir.Block, fn *ir.Func) bool {
 	count := 0
@@ -775,7 +1352,8 @@ func (opt *Optimizer) eliminateDeadFunctions(module *ir.Module) {
 	// Remove unreferenced functions
 	newFuncs := make([]*ir.Func, 0, len(module.Funcs))
 	for _, fn := range module.Funcs {
-		if referenced[fn.Name()] || len(fn.Blocks) == 0 { // Keep external functions
+		// Keep declarations and functions exported by their meta
+		if referenced[fn.Name()] || len(fn.Blocks) == 0 || fn.Linkage == enum.LinkageExternal {
 			newFuncs = append(newFuncs, fn)
 		}
 	}
@@ -815,18 +1393,3 @@ func (opt *Optimizer) replaceInstructionUses(oldVal, newVal value.Value, fn *ir.
 		}
 	}
 }
-
-// replaceAllUsesWith replaces all uses of oldVal with newVal.
-func (opt *Optimizer) replaceAllUsesWith(oldVal, newVal value.Value) {
-	// This is a simplified implementation
-	// A full implementation would update all use-def chains
-
-	// For the LLVM IR library we're using, this would require
-	// iterating through all instructions and updating operands
-	// This is a complex operation that requires careful handling
-	// of the IR structure
-
-	// For now, we'll mark this as a TODO
-	_ = oldVal
-	_ = newVal
-}
@@ -0,0 +1,646 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+func TestOptimizer_Peephole(t *testing.T) {
+	i64 := func(n int64) *constant.Int { return constant.NewInt(types.I64, n) }
+
+	tests := []struct {
+		name      string
+		paramType types.Type
+		build     func(b *ir.Block, x value.Value) value.Value
+		wantInsts int
+		wantParam bool  // result should be the parameter itself
+		wantConst int64 // otherwise, the constant the result should fold to
+	}{
+		{
+			name:      "add zero",
+			paramType: types.I64,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewAdd(i64(0), b.NewAdd(x, i64(0))) },
+			wantInsts: 0,
+			wantParam: true,
+		},
+		{
+			name:      "mul one",
+			paramType: types.I64,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewMul(i64(1), b.NewMul(x, i64(1))) },
+			wantInsts: 0,
+			wantParam: true,
+		},
+		{
+			name:      "sub self",
+			paramType: types.I64,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewSub(x, x) },
+			wantInsts: 0,
+			wantConst: 0,
+		},
+		{
+			name:      "xor zero",
+			paramType: types.I64,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewXor(x, i64(0)) },
+			wantInsts: 0,
+			wantParam: true,
+		},
+		{
+			name:      "double integer negation",
+			paramType: types.I64,
+			build: func(b *ir.Block, x value.Value) value.Value {
+				return b.NewSub(i64(0), b.NewSub(i64(0), x))
+			},
+			wantInsts: 1, // The inner negation is left for dead code elimination
+			wantParam: true,
+		},
+		{
+			name:      "double boolean not",
+			paramType: types.I1,
+			build: func(b *ir.Block, x value.Value) value.Value {
+				return b.NewXor(b.NewXor(x, constant.True), constant.True)
+			},
+			wantInsts: 1,
+			wantParam: true,
+		},
+		{
+			name:      "double float negation",
+			paramType: types.Double,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewFNeg(b.NewFNeg(x)) },
+			wantInsts: 1,
+			wantParam: true,
+		},
+		{
+			name:      "trunc of zext",
+			paramType: types.I1,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewTrunc(b.NewZExt(x, types.I64), types.I1) },
+			wantInsts: 1,
+			wantParam: true,
+		},
+		{
+			name:      "non-identity is kept",
+			paramType: types.I64,
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewAdd(x, i64(2)) },
+			wantInsts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := ir.NewModule()
+			param := ir.NewParam("x", tt.paramType)
+			fn := module.NewFunc("f", tt.paramType, param)
+			block := fn.NewBlock("entry")
+			result := tt.build(block, param)
+			ret := block.NewRet(result)
+			before := len(block.Insts)
+
+			NewOptimizer(OptBasic).peephole(fn)
+
+			if got := len(block.Insts); got != tt.wantInsts {
+				t.Errorf("instruction count = %d (was %d), want %d\n%s", got, before, tt.wantInsts, fn.LLString())
+			}
+			switch {
+			case tt.wantParam:
+				if ret.X != param {
+					t.Errorf("ret operand = %s, want %%x", ret.X.Ident())
+				}
+			case tt.wantInsts == 0:
+				if !isIntConst(ret.X, tt.wantConst) {
+					t.Errorf("ret operand = %s, want %d", ret.X.Ident(), tt.wantConst)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizer_FoldComparisons(t *testing.T) {
+	i64 := func(n int64) *constant.Int { return constant.NewInt(types.I64, n) }
+	f64 := func(f float64) *constant.Float { return constant.NewFloat(types.Double, f) }
+
+	tests := []struct {
+		name  string
+		build func(b *ir.Block) value.Value
+		want  bool
+	}{
+		{name: "1 < 2", build: func(b *ir.Block) value.Value { return b.NewICmp(enum.IPredSLT, i64(1), i64(2)) }, want: true},
+		{name: "3 == 4", build: func(b *ir.Block) value.Value { return b.NewICmp(enum.IPredEQ, i64(3), i64(4)) }, want: false},
+		{name: "-1 >= 0", build: func(b *ir.Block) value.Value { return b.NewICmp(enum.IPredSGE, i64(-1), i64(0)) }, want: false},
+		{name: "-1 unsigned > 0", build: func(b *ir.Block) value.Value { return b.NewICmp(enum.IPredUGT, i64(-1), i64(0)) }, want: true},
+		{name: "1.5 <= 1.5", build: func(b *ir.Block) value.Value { return b.NewFCmp(enum.FPredOLE, f64(1.5), f64(1.5)) }, want: true},
+		{name: "2.0 != 2.0", build: func(b *ir.Block) value.Value { return b.NewFCmp(enum.FPredONE, f64(2), f64(2)) }, want: false},
+		{name: "true and false", build: func(b *ir.Block) value.Value { return b.NewAnd(constant.True, constant.False) }, want: false},
+		{name: "false or true", build: func(b *ir.Block) value.Value { return b.NewOr(constant.False, constant.True) }, want: true},
+		{
+			name: "nested comparison",
+			build: func(b *ir.Block) value.Value {
+				return b.NewAnd(b.NewICmp(enum.IPredSGT, i64(5), i64(2)), b.NewFCmp(enum.FPredOLT, f64(0.5), f64(1)))
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := ir.NewModule()
+			fn := module.NewFunc("f", types.I1)
+			block := fn.NewBlock("entry")
+			ret := block.NewRet(tt.build(block))
+
+			NewOptimizer(OptBasic).constantFolding(fn)
+
+			if len(block.Insts) != 0 {
+				t.Errorf("%d instructions left, want 0\n%s", len(block.Insts), fn.LLString())
+			}
+			want := constant.False
+			if tt.want {
+				want = constant.True
+			}
+			if c, ok := ret.X.(*constant.Int); !ok || !c.Type().Equal(types.I1) || c.X.Cmp(want.X) != 0 {
+				t.Errorf("ret operand = %s, want %s", ret.X.Ident(), want.Ident())
+			}
+		})
+	}
+
+	t.Run("non-constant operand", func(t *testing.T) {
+		module := ir.NewModule()
+		param := ir.NewParam("x", types.I64)
+		fn := module.NewFunc("f", types.I1, param)
+		block := fn.NewBlock("entry")
+		block.NewRet(block.NewICmp(enum.IPredSLT, param, i64(2)))
+
+		NewOptimizer(OptBasic).constantFolding(fn)
+
+		if len(block.Insts) != 1 {
+			t.Errorf("%d instructions left, want 1", len(block.Insts))
+		}
+	})
+}
+
+func TestOptimizer_SimplifyCFGConstantBranch(t *testing.T) {
+	module := ir.NewModule()
+	fn := module.NewFunc("f", types.I64)
+	entry := fn.NewBlock("entry")
+	then := fn.NewBlock("then")
+	els := fn.NewBlock("else")
+	end := fn.NewBlock("end")
+	entry.NewCondBr(entry.NewICmp(enum.IPredSLT, constant.NewInt(types.I64, 1), constant.NewInt(types.I64, 2)), then, els)
+	then.NewBr(end)
+	els.NewBr(end)
+	end.NewRet(end.NewPhi(ir.NewIncoming(constant.NewInt(types.I64, 10), then), ir.NewIncoming(constant.NewInt(types.I64, 20), els)))
+
+	opt := NewOptimizer(OptStandard)
+	opt.constantFolding(fn)
+	opt.simplifyCFG(fn)
+
+	// The dead branch is removed and the remaining blocks merged, resolving
+	// the phi to the value from the taken branch
+	if len(fn.Blocks) != 1 || len(fn.Blocks[0].Insts) != 0 {
+		t.Fatalf("want a single block without instructions\n%s", fn.LLString())
+	}
+	if ret, ok := fn.Blocks[0].Term.(*ir.TermRet); !ok || !isIntConst(ret.X, 10) {
+		t.Errorf("want ret i64 10\n%s", fn.LLString())
+	}
+}
+
+func TestOptimizer_SimplifyCFGFoldsBranches(t *testing.T) {
+	i64 := func(n int64) *constant.Int { return constant.NewInt(types.I64, n) }
+
+	tests := []struct {
+		name  string
+		build func(fn *ir.Func)
+		want  int64
+	}{
+		{
+			name: "if true",
+			build: func(fn *ir.Func) {
+				entry := fn.NewBlock("entry")
+				then := fn.NewBlock("then")
+				els := fn.NewBlock("else")
+				entry.NewCondBr(constant.True, then, els)
+				then.NewRet(i64(1))
+				els.NewRet(i64(2))
+			},
+			want: 1,
+		},
+		{
+			name: "constant switch",
+			build: func(fn *ir.Func) {
+				entry := fn.NewBlock("entry")
+				one := fn.NewBlock("one")
+				two := fn.NewBlock("two")
+				other := fn.NewBlock("other")
+				entry.NewSwitch(i64(2), other, ir.NewCase(i64(1), one), ir.NewCase(i64(2), two))
+				one.NewRet(i64(10))
+				two.NewRet(i64(20))
+				other.NewRet(i64(30))
+			},
+			want: 20,
+		},
+		{
+			name: "constant switch default",
+			build: func(fn *ir.Func) {
+				entry := fn.NewBlock("entry")
+				one := fn.NewBlock("one")
+				other := fn.NewBlock("other")
+				entry.NewSwitch(i64(7), other, ir.NewCase(i64(1), one))
+				one.NewRet(i64(10))
+				other.NewRet(i64(30))
+			},
+			want: 30,
+		},
+		{
+			name: "same targets",
+			build: func(fn *ir.Func) {
+				x := ir.NewParam("x", types.I1)
+				fn.Params = append(fn.Params, x)
+				entry := fn.NewBlock("entry")
+				end := fn.NewBlock("end")
+				entry.NewCondBr(x, end, end)
+				end.NewRet(i64(5))
+			},
+			want: 5,
+		},
+		{
+			name: "same targets with a phi",
+			build: func(fn *ir.Func) {
+				x := ir.NewParam("x", types.I1)
+				fn.Params = append(fn.Params, x)
+				entry := fn.NewBlock("entry")
+				end := fn.NewBlock("end")
+				entry.NewCondBr(x, end, end)
+				// One incoming per edge, as LLVM requires
+				end.NewRet(end.NewPhi(ir.NewIncoming(i64(6), entry), ir.NewIncoming(i64(6), entry)))
+			},
+			want: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := ir.NewModule()
+			fn := module.NewFunc("f", types.I64)
+			tt.build(fn)
+
+			NewOptimizer(OptStandard).simplifyCFG(fn)
+
+			// The branch is folded and the function collapses to
+			// straight-line code
+			if len(fn.Blocks) != 1 {
+				t.Fatalf("want a single block\n%s", fn.LLString())
+			}
+			if ret, ok := fn.Blocks[0].Term.(*ir.TermRet); !ok || !isIntConst(ret.X, tt.want) {
+				t.Errorf("want ret i64 %d\n%s", tt.want, fn.LLString())
+			}
+		})
+	}
+}
+
+// evalInt evaluates the integer instructions produced by strength reduction
+// for a given value of the parameter x.
+func evalInt(t *testing.T, v value.Value, x *ir.Param, arg int64) int64 {
+	t.Helper()
+	operand := func(v value.Value) int64 { return evalInt(t, v, x, arg) }
+	switch i := v.(type) {
+	case *ir.Param:
+		return arg
+	case *constant.Int:
+		return i.X.Int64()
+	case *ir.InstShl:
+		return operand(i.X) << uint64(operand(i.Y))
+	case *ir.InstAShr:
+		return operand(i.X) >> uint64(operand(i.Y))
+	case *ir.InstLShr:
+		return int64(uint64(operand(i.X)) >> uint64(operand(i.Y)))
+	case *ir.InstAdd:
+		return operand(i.X) + operand(i.Y)
+	case *ir.InstMul:
+		return operand(i.X) * operand(i.Y)
+	case *ir.InstSDiv:
+		return operand(i.X) / operand(i.Y)
+	}
+	t.Fatalf("cannot evaluate %s", v.Ident())
+	return 0
+}
+
+func TestOptimizer_StrengthReduction(t *testing.T) {
+	i64 := func(n int64) *constant.Int { return constant.NewInt(types.I64, n) }
+
+	tests := []struct {
+		name        string
+		build       func(b *ir.Block, x value.Value) value.Value
+		wantInsts   int
+		wantReduced bool
+		reference   func(x int64) int64
+	}{
+		{
+			name:        "multiply by 8",
+			build:       func(b *ir.Block, x value.Value) value.Value { return b.NewMul(x, i64(8)) },
+			wantInsts:   1,
+			wantReduced: true,
+			reference:   func(x int64) int64 { return x * 8 },
+		},
+		{
+			name:        "constant on the left",
+			build:       func(b *ir.Block, x value.Value) value.Value { return b.NewMul(i64(1024), x) },
+			wantInsts:   1,
+			wantReduced: true,
+			reference:   func(x int64) int64 { return 1024 * x },
+		},
+		{
+			name:        "divide by 4",
+			build:       func(b *ir.Block, x value.Value) value.Value { return b.NewSDiv(x, i64(4)) },
+			wantInsts:   4,
+			wantReduced: true,
+			reference:   func(x int64) int64 { return x / 4 },
+		},
+		{
+			name:        "divide by 2",
+			build:       func(b *ir.Block, x value.Value) value.Value { return b.NewSDiv(x, i64(2)) },
+			wantInsts:   4,
+			wantReduced: true,
+			reference:   func(x int64) int64 { return x / 2 },
+		},
+		{
+			name:      "multiply by non-power of two",
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewMul(x, i64(6)) },
+			wantInsts: 1,
+			reference: func(x int64) int64 { return x * 6 },
+		},
+		{
+			name:      "divide by non-power of two",
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewSDiv(x, i64(3)) },
+			wantInsts: 1,
+			reference: func(x int64) int64 { return x / 3 },
+		},
+		{
+			name:      "divide by negative power of two",
+			build:     func(b *ir.Block, x value.Value) value.Value { return b.NewSDiv(x, i64(-4)) },
+			wantInsts: 1,
+			reference: func(x int64) int64 { return x / -4 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := ir.NewModule()
+			param := ir.NewParam("x", types.I64)
+			fn := module.NewFunc("f", types.I64, param)
+			block := fn.NewBlock("entry")
+			ret := block.NewRet(tt.build(block, param))
+			original := ret.X
+
+			NewOptimizer(OptStandard).strengthReduction(fn)
+
+			if got := len(block.Insts); got != tt.wantInsts {
+				t.Fatalf("instruction count = %d, want %d\n%s", got, tt.wantInsts, fn.LLString())
+			}
+			if reduced := ret.X != original; reduced != tt.wantReduced {
+				t.Errorf("rewritten = %v, want %v\n%s", reduced, tt.wantReduced, fn.LLString())
+			}
+			for _, inst := range block.Insts {
+				switch inst.(type) {
+				case *ir.InstMul, *ir.InstSDiv:
+					if tt.wantReduced {
+						t.Errorf("expected multiply/divide to be replaced\n%s", fn.LLString())
+					}
+				}
+			}
+
+			for _, x := range []int64{0, 1, -1, 7, -7, 9, -9, 1 << 40, -(1 << 40), -1 << 63, 1<<63 - 1} {
+				if got, want := evalInt(t, ret.X, param, x), tt.reference(x); got != want {
+					t.Errorf("f(%d) = %d, want %d", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizer_InlineMultiBlockFunction(t *testing.T) {
+	module := ir.NewModule()
+
+	// abs(n) branches to one of two returns and keeps n in a stack slot
+	n := ir.NewParam("n", types.I64)
+	abs := module.NewFunc("abs", types.I64, n)
+	absEntry := abs.NewBlock("entry")
+	absNeg := abs.NewBlock("neg")
+	absPos := abs.NewBlock("pos")
+	slot := absEntry.NewAlloca(types.I64)
+	absEntry.NewStore(n, slot)
+	loaded := absEntry.NewLoad(types.I64, slot)
+	absEntry.NewCondBr(absEntry.NewICmp(enum.IPredSLT, loaded, constant.NewInt(types.I64, 0)), absNeg, absPos)
+	absNeg.NewRet(absNeg.NewSub(constant.NewInt(types.I64, 0), loaded))
+	absPos.NewRet(loaded)
+
+	// main calls abs in the middle of a block whose successor has a phi
+	x := ir.NewParam("x", types.I64)
+	caller := module.NewFunc("caller", types.I64, x)
+	entry := caller.NewBlock("entry")
+	exit := caller.NewBlock("exit")
+	call := entry.NewCall(abs, x)
+	doubled := entry.NewAdd(call, call)
+	entry.NewBr(exit)
+	phi := exit.NewPhi(ir.NewIncoming(doubled, entry))
+	exit.NewRet(phi)
+
+	NewOptimizer(OptAggressive).inlineSmallFunctions(module)
+
+	var calls, phis int
+	for _, block := range caller.Blocks {
+		for _, inst := range block.Insts {
+			switch inst.(type) {
+			case *ir.InstCall:
+				calls++
+			case *ir.InstPhi:
+				phis++
+			}
+		}
+	}
+	if calls != 0 {
+		t.Errorf("expected call to be inlined\n%s", caller.LLString())
+	}
+	// One phi collects abs's two return values, plus the original phi in exit
+	if phis != 2 {
+		t.Errorf("expected 2 phis, got %d\n%s", phis, caller.LLString())
+	}
+	if len(caller.Blocks) != 6 {
+		t.Errorf("expected 6 blocks (entry, 3 inlined, continuation, exit), got %d\n%s", len(caller.Blocks), caller.LLString())
+	}
+	if _, ok := caller.Blocks[0].Insts[0].(*ir.InstAlloca); !ok {
+		t.Errorf("expected inlined alloca to be hoisted into the entry block\n%s", caller.LLString())
+	}
+
+	continuation := caller.Blocks[4]
+	if pred := phi.Incs[0].Pred; pred != continuation {
+		t.Errorf("exit phi predecessor = %s, want continuation block %s", pred.Ident(), continuation.Ident())
+	}
+	retPhi, ok := continuation.Insts[0].(*ir.InstPhi)
+	if !ok || len(retPhi.Incs) != 2 || doubled.X != retPhi || doubled.Y != retPhi {
+		t.Errorf("expected uses of the call to read the return phi\n%s", caller.LLString())
+	}
+
+	// The callee itself is untouched
+	if len(abs.Blocks) != 3 || len(absEntry.Insts) != 4 {
+		t.Errorf("expected callee to be unchanged\n%s", abs.LLString())
+	}
+}
+
+func TestOptimizer_InlineSkipsRecursiveFunctions(t *testing.T) {
+	module := ir.NewModule()
+	n := ir.NewParam("n", types.I64)
+	rec := module.NewFunc("rec", types.I64, n)
+	recEntry := rec.NewBlock("entry")
+	recEntry.NewRet(recEntry.NewCall(rec, n))
+
+	caller := module.NewFunc("caller", types.I64)
+	entry := caller.NewBlock("entry")
+	entry.NewRet(entry.NewCall(rec, constant.NewInt(types.I64, 1)))
+
+	NewOptimizer(OptAggressive).inlineSmallFunctions(module)
+
+	if len(caller.Blocks) != 1 || len(entry.Insts) != 1 {
+		t.Errorf("expected recursive function not to be inlined\n%s", caller.LLString())
+	}
+}
+
+func TestOptimizer_InlineHonorsFunctionAttributes(t *testing.T) {
+	// newCallee builds a function over the inlining size limit
+	newCallee := func(module *ir.Module, name string, attrs ...ir.FuncAttribute) *ir.Func {
+		n := ir.NewParam("n", types.I64)
+		fn := module.NewFunc(name, types.I64, n)
+		fn.FuncAttrs = attrs
+		entry := fn.NewBlock("entry")
+		var sum value.Value = n
+		for i := 0; i <= maxInlineInstructions; i++ {
+			sum = entry.NewAdd(sum, n)
+		}
+		entry.NewRet(sum)
+		return fn
+	}
+
+	tests := []struct {
+		name        string
+		calleeAttrs []ir.FuncAttribute
+		callerAttrs []ir.FuncAttribute
+		inlined     bool
+	}{
+		{name: "large function", inlined: false},
+		{name: "alwaysinline", calleeAttrs: []ir.FuncAttribute{enum.FuncAttrAlwaysInline}, inlined: true},
+		{name: "noinline", calleeAttrs: []ir.FuncAttribute{enum.FuncAttrNoInline}, inlined: false},
+		{name: "optnone caller", calleeAttrs: []ir.FuncAttribute{enum.FuncAttrAlwaysInline},
+			callerAttrs: []ir.FuncAttribute{enum.FuncAttrNoInline, enum.FuncAttrOptNone}, inlined: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := ir.NewModule()
+			callee := newCallee(module, "callee", tt.calleeAttrs...)
+			caller := module.NewFunc("caller", types.I64)
+			caller.FuncAttrs = tt.callerAttrs
+			entry := caller.NewBlock("entry")
+			entry.NewRet(entry.NewCall(callee, constant.NewInt(types.I64, 1)))
+
+			NewOptimizer(OptAggressive).inlineSmallFunctions(module)
+
+			called := false
+			for _, block := range caller.Blocks {
+				for _, inst := range block.Insts {
+					if _, ok := inst.(*ir.InstCall); ok {
+						called = true
+					}
+				}
+			}
+			if called == tt.inlined {
+				t.Errorf("inlined = %v, want %v\n%s", !called, tt.inlined, caller.LLString())
+			}
+		})
+	}
+}
+
+func TestOptimizer_OptNoneFunctionIsLeftAlone(t *testing.T) {
+	module := ir.NewModule()
+	fn := module.NewFunc("debug", types.I64)
+	fn.FuncAttrs = []ir.FuncAttribute{enum.FuncAttrNoInline, enum.FuncAttrOptNone}
+	entry := fn.NewBlock("entry")
+	slot := entry.NewAlloca(types.I64)
+	entry.NewStore(constant.NewInt(types.I64, 2), slot)
+	entry.NewRet(entry.NewMul(entry.NewLoad(types.I64, slot), constant.NewInt(types.I64, 3)))
+	before := fn.LLString()
+
+	NewOptimizer(OptAggressive).optimizeFunction(fn)
+
+	if after := fn.LLString(); after != before {
+		t.Errorf("expected optnone function to be unchanged\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}
+
+func TestOptimizer_DeadFunctionsKeepExternalLinkage(t *testing.T) {
+	module := ir.NewModule()
+	for _, linkage := range []enum.Linkage{enum.LinkageNone, enum.LinkageInternal, enum.LinkageExternal} {
+		fn := module.NewFunc("f_"+linkage.String(), types.Void)
+		fn.Linkage = linkage
+		fn.NewBlock("entry").NewRet(nil)
+	}
+	main := module.NewFunc("main", types.I64)
+	main.NewBlock("entry").NewRet(constant.NewInt(types.I64, 0))
+
+	NewOptimizer(OptAggressive).eliminateDeadFunctions(module)
+
+	var kept []string
+	for _, fn := range module.Funcs {
+		kept = append(kept, fn.Name())
+	}
+	if len(kept) != 2 || kept[0] != "f_external" || kept[1] != "main" {
+		t.Errorf("kept functions = %v, want [f_external main]", kept)
+	}
+}
+
+func TestOptimizer_DeadCodeEliminationKeepsElementStores(t *testing.T) {
+	module := ir.NewModule()
+	fn := module.NewFunc("f", types.Void, ir.NewParam("data", types.I8Ptr))
+	entry := fn.NewBlock("entry")
+	unread := entry.NewAlloca(types.I64)
+	deadStore := entry.NewStore(constant.NewInt(types.I64, 1), unread)
+	elems := entry.NewBitCast(fn.Params[0], types.NewPointer(types.I64))
+	elemStore := entry.NewStore(constant.NewInt(types.I64, 2), entry.NewGetElementPtr(types.I64, elems, constant.NewInt(types.I64, 1)))
+	entry.NewRet(nil)
+
+	NewOptimizer(OptStandard).deadCodeElimination(fn)
+
+	stores := make(map[*ir.InstStore]bool)
+	for _, inst := range entry.Insts {
+		if store, ok := inst.(*ir.InstStore); ok {
+			stores[store] = true
+		}
+	}
+	// A store to a local that is never loaded is dead, but a store through a
+	// pointer the caller may read is not
+	if stores[deadStore] || !stores[elemStore] {
+		t.Errorf("want only the element store kept\n%s", fn.LLString())
+	}
+}
+
+func TestOptimizer_DeadCodeEliminationKeepsEscapedAllocaStores(t *testing.T) {
+	module := ir.NewModule()
+	callee := module.NewFunc("use", types.Void, ir.NewParam("p", types.I8Ptr))
+	fn := module.NewFunc("f", types.Void)
+	entry := fn.NewBlock("entry")
+	slot := entry.NewAlloca(types.I64)
+	store := entry.NewStore(constant.NewInt(types.I64, 3), slot)
+	entry.NewCall(callee, entry.NewBitCast(slot, types.I8Ptr))
+	entry.NewRet(nil)
+
+	NewOptimizer(OptStandard).deadCodeElimination(fn)
+
+	// The callee may read the slot through the pointer it is passed
+	for _, inst := range entry.Insts {
+		if inst == store {
+			return
+		}
+	}
+	t.Errorf("want the store to the escaped alloca kept\n%s", fn.LLString())
+}
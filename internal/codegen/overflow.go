@@ -0,0 +1,33 @@
+package codegen
+
+import (
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// overflowIntrinsics maps the int operators that can overflow to the LLVM
+// intrinsics computing them together with a signed overflow flag.
+var overflowIntrinsics = map[string]string{
+	ast.OpAdd: "llvm.sadd.with.overflow.i64",
+	ast.OpSub: "llvm.ssub.with.overflow.i64",
+	ast.OpMul: "llvm.smul.with.overflow.i64",
+}
+
+// EnableOverflowChecks makes int addition, subtraction and multiplication
+// report a runtime error when the result does not fit in 64 bits, instead
+// of wrapping around. It must be called before GenerateModule.
+func (g *LLVMCodegen) EnableOverflowChecks() {
+	g.checkOverflow = true
+}
+
+// generateCheckedArithmetic generates an int +, - or * that branches to the
+// runtime error handler when it overflows.
+func (g *LLVMCodegen) generateCheckedArithmetic(op string, left, right value.Value) value.Value {
+	resultType := types.NewStruct(types.I64, types.I1)
+	intrinsic := g.runtimeMapFunc(overflowIntrinsics[op], resultType, types.I64, types.I64)
+	result := g.builder.NewCall(intrinsic, left, right)
+	g.generateRuntimeErrorIf(g.builder.NewExtractValue(result, 1), "overflow", "integer overflow")
+	return g.builder.NewExtractValue(result, 0)
+}
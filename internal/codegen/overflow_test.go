@@ -0,0 +1,122 @@
+package codegen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestLLVMCodegen_OverflowChecks(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	module := func(op string, paramType string) *ast.Module {
+		return singleFunctionModule(paramType, []ast.Parameter{{Name: "a", Type: paramType}, {Name: "b", Type: paramType}}, []ast.Statement{
+			{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: op, Left: variable("a"), Right: variable("b")}},
+		})
+	}
+
+	tests := []struct {
+		name    string
+		module  *ast.Module
+		enable  bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "checked addition",
+			module: module(ast.OpAdd, ast.TypeInt),
+			enable: true,
+			want: []string{
+				"call { i64, i1 } @llvm.sadd.with.overflow.i64(i64",
+				"br i1",
+				"overflow.fail.",
+				"call void @alas_runtime_error(",
+				"unreachable",
+			},
+			notWant: []string{"add i64"},
+		},
+		{
+			name:   "checked subtraction",
+			module: module(ast.OpSub, ast.TypeInt),
+			enable: true,
+			want:   []string{"@llvm.ssub.with.overflow.i64"},
+		},
+		{
+			name:   "checked multiplication",
+			module: module(ast.OpMul, ast.TypeInt),
+			enable: true,
+			want:   []string{"@llvm.smul.with.overflow.i64"},
+		},
+		{
+			name:    "floats are not checked",
+			module:  module(ast.OpAdd, ast.TypeFloat),
+			enable:  true,
+			want:    []string{"fadd double"},
+			notWant: []string{"with.overflow"},
+		},
+		{
+			name:    "wrapping by default",
+			module:  module(ast.OpMul, ast.TypeInt),
+			want:    []string{"mul i64"},
+			notWant: []string{"with.overflow", "overflow.fail."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewLLVMCodegen()
+			if tt.enable {
+				g.EnableOverflowChecks()
+			}
+			compiled, err := g.GenerateModule(tt.module)
+			if err != nil {
+				t.Fatalf("GenerateModule failed: %v", err)
+			}
+			ir := compiled.String()
+			for _, expected := range tt.want {
+				if !strings.Contains(ir, expected) {
+					t.Errorf("expected IR to contain %q, got:\n%s", expected, ir)
+				}
+			}
+			for _, unexpected := range tt.notWant {
+				if strings.Contains(ir, unexpected) {
+					t.Errorf("expected IR not to contain %q, got:\n%s", unexpected, ir)
+				}
+			}
+		})
+	}
+}
+
+func TestLLVMCodegen_OverflowChecksOptimized(t *testing.T) {
+	g := NewLLVMCodegen()
+	g.EnableOverflowChecks()
+	compiled, err := g.GenerateModule(singleFunctionModule(ast.TypeInt, []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, []ast.Statement{
+		{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd,
+			Left: &ast.Expression{Type: ast.ExprVariable, Name: "n"}, Right: &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}}},
+	}))
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if err := NewOptimizer(OptAggressive).OptimizeModule(compiled); err != nil {
+		t.Fatalf("OptimizeModule failed: %v", err)
+	}
+	ir := compiled.String()
+	if !strings.Contains(ir, "@llvm.sadd.with.overflow.i64") || !strings.Contains(ir, "@alas_runtime_error(") {
+		t.Fatalf("optimization removed the overflow check:\n%s", ir)
+	}
+
+	llc, err := exec.LookPath("llc")
+	if err != nil {
+		t.Skip("llc not available")
+	}
+	file := filepath.Join(t.TempDir(), "overflow.ll")
+	if err := os.WriteFile(file, []byte(ir), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(llc, "-filetype=obj", file, "-o", file+".o").CombinedOutput(); err != nil {
+		t.Errorf("llc rejected the checked IR: %v\n%s", err, out)
+	}
+}
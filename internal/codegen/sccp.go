@@ -0,0 +1,598 @@
+package codegen
+
+import (
+	"math"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// latticeState is the position of a value in the constant propagation lattice.
+type latticeState int
+
+const (
+	// latticeUnknown - No evidence yet; the value may still become constant.
+	latticeUnknown latticeState = iota
+	// latticeConstant - The value is the same constant on every executable path.
+	latticeConstant
+	// latticeOverdefined - The value is not a compile-time constant.
+	latticeOverdefined
+)
+
+// latticeValue is a lattice element, holding the constant when known.
+type latticeValue struct {
+	state latticeState
+	value constant.Constant
+}
+
+// cfgEdge is a control flow edge between two blocks.
+type cfgEdge struct {
+	from, to *ir.Block
+}
+
+// sccp holds the state of sparse conditional constant propagation over one function.
+type sccp struct {
+	values     map[value.Value]latticeValue
+	edges      map[cfgEdge]bool
+	executable map[*ir.Block]bool
+	users      map[value.Value][]interface{} // instructions and terminators using a value
+	blockOf    map[interface{}]*ir.Block
+
+	edgeWork  []cfgEdge
+	valueWork []value.Value
+}
+
+// constantPropagation performs sparse conditional constant propagation: it
+// tracks which values are constant along executable paths, including through
+// phi nodes and branches, replaces them with their constants, turns
+// conditional branches on constant conditions into unconditional ones, and
+// removes the blocks that become unreachable.
+func (opt *Optimizer) constantPropagation(fn *ir.Func) {
+	if len(fn.Blocks) == 0 {
+		return
+	}
+	s := &sccp{
+		values:     make(map[value.Value]latticeValue),
+		edges:      make(map[cfgEdge]bool),
+		executable: make(map[*ir.Block]bool),
+		users:      make(map[value.Value][]interface{}),
+		blockOf:    make(map[interface{}]*ir.Block),
+	}
+	for _, block := range fn.Blocks {
+		for _, inst := range block.Insts {
+			s.addUses(inst, inst.Operands(), block)
+		}
+		if block.Term != nil {
+			s.addUses(block.Term, block.Term.Operands(), block)
+		}
+	}
+
+	s.markExecutable(fn.Blocks[0])
+	for len(s.edgeWork) > 0 || len(s.valueWork) > 0 {
+		for len(s.edgeWork) > 0 {
+			edge := s.edgeWork[0]
+			s.edgeWork = s.edgeWork[1:]
+			if s.executable[edge.to] {
+				// Only phi nodes depend on which incoming edges are executable
+				for _, inst := range edge.to.Insts {
+					if phi, ok := inst.(*ir.InstPhi); ok {
+						s.visitInstruction(phi, edge.to)
+					}
+				}
+				continue
+			}
+			s.markExecutable(edge.to)
+		}
+		for len(s.valueWork) > 0 {
+			val := s.valueWork[0]
+			s.valueWork = s.valueWork[1:]
+			for _, user := range s.users[val] {
+				if block := s.blockOf[user]; s.executable[block] {
+					s.visit(user, block)
+				}
+			}
+		}
+	}
+
+	s.rewrite(fn, opt)
+}
+
+// addUses records owner, located in block, as a user of its operands.
+func (s *sccp) addUses(owner interface{}, operands []*value.Value, block *ir.Block) {
+	s.blockOf[owner] = block
+	for _, operand := range operands {
+		s.users[*operand] = append(s.users[*operand], owner)
+	}
+}
+
+// markExecutable marks a block reachable and evaluates its contents.
+func (s *sccp) markExecutable(block *ir.Block) {
+	s.executable[block] = true
+	for _, inst := range block.Insts {
+		s.visitInstruction(inst, block)
+	}
+	if block.Term != nil {
+		s.visitTerminator(block.Term, block)
+	}
+}
+
+// markEdge marks a control flow edge executable.
+func (s *sccp) markEdge(from, to *ir.Block) {
+	edge := cfgEdge{from: from, to: to}
+	if s.edges[edge] {
+		return
+	}
+	s.edges[edge] = true
+	s.edgeWork = append(s.edgeWork, edge)
+}
+
+// visit evaluates an instruction or terminator.
+func (s *sccp) visit(user interface{}, block *ir.Block) {
+	switch u := user.(type) {
+	case ir.Instruction:
+		s.visitInstruction(u, block)
+	case ir.Terminator:
+		s.visitTerminator(u, block)
+	}
+}
+
+// lattice returns the lattice element of an operand. Constants are constant
+// and function parameters, globals, and unevaluated instructions' results
+// are handled by their current state.
+func (s *sccp) lattice(v value.Value) latticeValue {
+	switch v := v.(type) {
+	case *constant.Int, *constant.Float:
+		return latticeValue{state: latticeConstant, value: v.(constant.Constant)}
+	case ir.Instruction:
+		return s.values[v.(value.Value)]
+	}
+	return latticeValue{state: latticeOverdefined}
+}
+
+// update lowers the lattice element of val, queueing its users on change.
+func (s *sccp) update(val value.Value, next latticeValue) {
+	current := s.values[val]
+	if current.state == latticeOverdefined || next.state == latticeUnknown {
+		return
+	}
+	if current.state == latticeConstant && next.state == latticeConstant && sameConstant(current.value, next.value) {
+		return
+	}
+	if current.state == latticeConstant && next.state == latticeConstant {
+		next = latticeValue{state: latticeOverdefined}
+	}
+	s.values[val] = next
+	s.valueWork = append(s.valueWork, val)
+}
+
+// visitInstruction evaluates an instruction over the lattice.
+func (s *sccp) visitInstruction(inst ir.Instruction, block *ir.Block) {
+	val, ok := inst.(value.Value)
+	if !ok {
+		return
+	}
+
+	if phi, ok := inst.(*ir.InstPhi); ok {
+		result := latticeValue{}
+		for _, inc := range phi.Incs {
+			pred, ok := inc.Pred.(*ir.Block)
+			if !ok || !s.edges[cfgEdge{from: pred, to: block}] {
+				continue
+			}
+			result = meet(result, s.lattice(inc.X))
+		}
+		s.update(val, result)
+		return
+	}
+
+	if sel, ok := inst.(*ir.InstSelect); ok {
+		cond := s.lattice(sel.Cond)
+		switch cond.state {
+		case latticeConstant:
+			if isIntConst(cond.value, 1) {
+				s.update(val, s.lattice(sel.ValueTrue))
+			} else {
+				s.update(val, s.lattice(sel.ValueFalse))
+			}
+		case latticeOverdefined:
+			s.update(val, meet(s.lattice(sel.ValueTrue), s.lattice(sel.ValueFalse)))
+		}
+		return
+	}
+
+	operands := make([]constant.Constant, 0, len(inst.Operands()))
+	for _, operand := range inst.Operands() {
+		cell := s.lattice(*operand)
+		switch cell.state {
+		case latticeUnknown:
+			return
+		case latticeOverdefined:
+			s.update(val, latticeValue{state: latticeOverdefined})
+			return
+		}
+		operands = append(operands, cell.value)
+	}
+	if folded := evaluateConstant(inst, operands); folded != nil {
+		s.update(val, latticeValue{state: latticeConstant, value: folded})
+		return
+	}
+	s.update(val, latticeValue{state: latticeOverdefined})
+}
+
+// visitTerminator marks the outgoing edges a terminator may take.
+func (s *sccp) visitTerminator(term ir.Terminator, block *ir.Block) {
+	switch t := term.(type) {
+	case *ir.TermCondBr:
+		cond := s.lattice(t.Cond)
+		switch cond.state {
+		case latticeConstant:
+			if isIntConst(cond.value, 1) {
+				s.markEdge(block, t.TargetTrue.(*ir.Block))
+			} else {
+				s.markEdge(block, t.TargetFalse.(*ir.Block))
+			}
+		case latticeOverdefined:
+			s.markEdge(block, t.TargetTrue.(*ir.Block))
+			s.markEdge(block, t.TargetFalse.(*ir.Block))
+		}
+	case *ir.TermSwitch:
+		x := s.lattice(t.X)
+		switch x.state {
+		case latticeConstant:
+			s.markEdge(block, switchTarget(t, x.value))
+		case latticeOverdefined:
+			for _, succ := range t.Succs() {
+				s.markEdge(block, succ)
+			}
+		}
+	default:
+		for _, succ := range term.Succs() {
+			s.markEdge(block, succ)
+		}
+	}
+}
+
+// switchTarget returns the block a switch branches to for a constant value.
+func switchTarget(t *ir.TermSwitch, x constant.Constant) *ir.Block {
+	for _, c := range t.Cases {
+		if caseValue, ok := c.X.(constant.Constant); ok && sameConstant(caseValue, x) {
+			return c.Target.(*ir.Block)
+		}
+	}
+	return t.TargetDefault.(*ir.Block)
+}
+
+// rewrite applies the results of the analysis to fn.
+func (s *sccp) rewrite(fn *ir.Func, opt *Optimizer) {
+	for _, block := range fn.Blocks {
+		if !s.executable[block] {
+			continue
+		}
+		kept := block.Insts[:0]
+		for _, inst := range block.Insts {
+			if val, ok := inst.(value.Value); ok {
+				if cell := s.values[val]; cell.state == latticeConstant {
+					opt.replaceInstructionUses(val, cell.value, fn)
+					continue
+				}
+			}
+			kept = append(kept, inst)
+		}
+		block.Insts = kept
+
+		// Branch directly when only one successor is executable
+		switch t := block.Term.(type) {
+		case *ir.TermCondBr:
+			if cell := s.lattice(t.Cond); cell.state == latticeConstant {
+				target := t.TargetFalse.(*ir.Block)
+				if isIntConst(cell.value, 1) {
+					target = t.TargetTrue.(*ir.Block)
+				}
+				br := ir.NewBr(target)
+				br.Metadata = t.Metadata
+				block.Term = br
+			}
+		case *ir.TermSwitch:
+			if cell := s.lattice(t.X); cell.state == latticeConstant {
+				br := ir.NewBr(switchTarget(t, cell.value))
+				br.Metadata = t.Metadata
+				block.Term = br
+			}
+		}
+	}
+
+	opt.removeUnreachableBlocks(fn)
+	removeStaleIncomings(fn)
+}
+
+// removeStaleIncomings drops phi incomings from blocks that no longer branch
+// to the phi's block. A phi keeps one incoming per remaining edge, so when a
+// branch with several edges to the same block is folded to a single edge,
+// the duplicate incomings for its block are dropped too.
+func removeStaleIncomings(fn *ir.Func) {
+	edges := make(map[*ir.Block]map[*ir.Block]int)
+	for _, block := range fn.Blocks {
+		if block.Term == nil {
+			continue
+		}
+		for _, succ := range block.Term.Succs() {
+			if edges[succ] == nil {
+				edges[succ] = make(map[*ir.Block]int)
+			}
+			edges[succ][block]++
+		}
+	}
+	for _, block := range fn.Blocks {
+		for _, inst := range block.Insts {
+			phi, ok := inst.(*ir.InstPhi)
+			if !ok {
+				continue
+			}
+			remaining := make(map[*ir.Block]int, len(edges[block]))
+			for pred, n := range edges[block] {
+				remaining[pred] = n
+			}
+			kept := phi.Incs[:0]
+			for _, inc := range phi.Incs {
+				if pred, ok := inc.Pred.(*ir.Block); ok && remaining[pred] > 0 {
+					remaining[pred]--
+					kept = append(kept, inc)
+				}
+			}
+			phi.Incs = kept
+		}
+	}
+}
+
+// meet combines two lattice elements.
+func meet(a, b latticeValue) latticeValue {
+	switch {
+	case a.state == latticeUnknown:
+		return b
+	case b.state == latticeUnknown:
+		return a
+	case a.state == latticeOverdefined || b.state == latticeOverdefined:
+		return latticeValue{state: latticeOverdefined}
+	case sameConstant(a.value, b.value):
+		return a
+	default:
+		return latticeValue{state: latticeOverdefined}
+	}
+}
+
+// sameConstant reports whether two constants have the same type and value.
+func sameConstant(a, b constant.Constant) bool {
+	return a.Type().Equal(b.Type()) && a.Ident() == b.Ident()
+}
+
+// intConstant returns an integer constant of type t holding v wrapped to the
+// width of the type.
+func intConstant(t *types.IntType, v int64) *constant.Int {
+	if t.BitSize == 1 {
+		return constant.NewInt(t, v&1)
+	}
+	if t.BitSize < 64 {
+		shift := 64 - t.BitSize
+		v = v << shift >> shift
+	}
+	return constant.NewInt(t, v)
+}
+
+// unsignedValue returns the value of an integer constant as an unsigned
+// number of the constant's width.
+func unsignedValue(c *constant.Int) uint64 {
+	v := uint64(c.X.Int64())
+	if c.Typ.BitSize < 64 {
+		v &= 1<<c.Typ.BitSize - 1
+	}
+	return v
+}
+
+// evaluateConstant computes the result of inst applied to constant operands,
+// or returns nil if the instruction cannot be evaluated at compile time.
+func evaluateConstant(inst ir.Instruction, operands []constant.Constant) constant.Constant {
+	ints := make([]*constant.Int, 0, len(operands))
+	floats := make([]float64, 0, len(operands))
+	for _, operand := range operands {
+		switch c := operand.(type) {
+		case *constant.Int:
+			if !c.X.IsInt64() {
+				return nil
+			}
+			ints = append(ints, c)
+		case *constant.Float:
+			f, _ := c.X.Float64()
+			floats = append(floats, f)
+		}
+	}
+
+	if len(ints) == 2 && len(operands) == 2 {
+		return evaluateIntBinary(inst, ints[0], ints[1])
+	}
+	if len(floats) == 2 && len(operands) == 2 {
+		return evaluateFloatBinary(inst, floats[0], floats[1])
+	}
+	if len(operands) != 1 {
+		return nil
+	}
+
+	switch i := inst.(type) {
+	case *ir.InstFNeg:
+		if len(floats) == 1 {
+			return constant.NewFloat(i.Type().(*types.FloatType), -floats[0])
+		}
+	case *ir.InstZExt:
+		if to, ok := i.To.(*types.IntType); ok && len(ints) == 1 {
+			return intConstant(to, int64(unsignedValue(ints[0])))
+		}
+	case *ir.InstSExt:
+		if to, ok := i.To.(*types.IntType); ok && len(ints) == 1 {
+			v := ints[0].X.Int64()
+			if ints[0].Typ.BitSize == 1 {
+				v = -v
+			}
+			return intConstant(to, v)
+		}
+	case *ir.InstTrunc:
+		if to, ok := i.To.(*types.IntType); ok && len(ints) == 1 {
+			return intConstant(to, ints[0].X.Int64())
+		}
+	case *ir.InstSIToFP:
+		if to, ok := i.To.(*types.FloatType); ok && len(ints) == 1 {
+			return constant.NewFloat(to, float64(ints[0].X.Int64()))
+		}
+	case *ir.InstFPToSI:
+		if to, ok := i.To.(*types.IntType); ok && len(floats) == 1 {
+			f := floats[0]
+			if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+				return nil
+			}
+			return intConstant(to, int64(f))
+		}
+	}
+	return nil
+}
+
+// evaluateIntBinary computes an integer binary operation or comparison.
+func evaluateIntBinary(inst ir.Instruction, x, y *constant.Int) constant.Constant {
+	a, b := x.X.Int64(), y.X.Int64()
+	ua, ub := unsignedValue(x), unsignedValue(y)
+	width := x.Typ.BitSize
+
+	switch i := inst.(type) {
+	case *ir.InstAdd:
+		return intConstant(x.Typ, a+b)
+	case *ir.InstSub:
+		return intConstant(x.Typ, a-b)
+	case *ir.InstMul:
+		return intConstant(x.Typ, a*b)
+	case *ir.InstSDiv:
+		// Division by zero and overflow are left to the runtime
+		if b == 0 || (b == -1 && a == math.MinInt64) {
+			return nil
+		}
+		return intConstant(x.Typ, a/b)
+	case *ir.InstSRem:
+		if b == 0 || (b == -1 && a == math.MinInt64) {
+			return nil
+		}
+		return intConstant(x.Typ, a%b)
+	case *ir.InstUDiv:
+		if ub == 0 {
+			return nil
+		}
+		return intConstant(x.Typ, int64(ua/ub))
+	case *ir.InstURem:
+		if ub == 0 {
+			return nil
+		}
+		return intConstant(x.Typ, int64(ua%ub))
+	case *ir.InstAnd:
+		return intConstant(x.Typ, a&b)
+	case *ir.InstOr:
+		return intConstant(x.Typ, a|b)
+	case *ir.InstXor:
+		return intConstant(x.Typ, a^b)
+	case *ir.InstShl:
+		if ub >= width {
+			return nil
+		}
+		return intConstant(x.Typ, a<<ub)
+	case *ir.InstLShr:
+		if ub >= width {
+			return nil
+		}
+		return intConstant(x.Typ, int64(ua>>ub))
+	case *ir.InstAShr:
+		if ub >= width {
+			return nil
+		}
+		return intConstant(x.Typ, a>>ub)
+	case *ir.InstICmp:
+		var result bool
+		switch i.Pred {
+		case enum.IPredEQ:
+			result = a == b
+		case enum.IPredNE:
+			result = a != b
+		case enum.IPredSGT:
+			result = a > b
+		case enum.IPredSGE:
+			result = a >= b
+		case enum.IPredSLT:
+			result = a < b
+		case enum.IPredSLE:
+			result = a <= b
+		case enum.IPredUGT:
+			result = ua > ub
+		case enum.IPredUGE:
+			result = ua >= ub
+		case enum.IPredULT:
+			result = ua < ub
+		case enum.IPredULE:
+			result = ua <= ub
+		default:
+			return nil
+		}
+		return constant.NewBool(result)
+	}
+	return nil
+}
+
+// evaluateFloatBinary computes a floating-point binary operation or comparison.
+func evaluateFloatBinary(inst ir.Instruction, a, b float64) constant.Constant {
+	switch i := inst.(type) {
+	case *ir.InstFAdd:
+		return constant.NewFloat(i.Type().(*types.FloatType), a+b)
+	case *ir.InstFSub:
+		return constant.NewFloat(i.Type().(*types.FloatType), a-b)
+	case *ir.InstFMul:
+		return constant.NewFloat(i.Type().(*types.FloatType), a*b)
+	case *ir.InstFDiv:
+		return constant.NewFloat(i.Type().(*types.FloatType), a/b)
+	case *ir.InstFCmp:
+		unordered := math.IsNaN(a) || math.IsNaN(b)
+		var result bool
+		switch i.Pred {
+		case enum.FPredFalse:
+			result = false
+		case enum.FPredTrue:
+			result = true
+		case enum.FPredORD:
+			result = !unordered
+		case enum.FPredUNO:
+			result = unordered
+		case enum.FPredOEQ:
+			result = !unordered && a == b
+		case enum.FPredONE:
+			result = !unordered && a != b
+		case enum.FPredOGT:
+			result = !unordered && a > b
+		case enum.FPredOGE:
+			result = !unordered && a >= b
+		case enum.FPredOLT:
+			result = !unordered && a < b
+		case enum.FPredOLE:
+			result = !unordered && a <= b
+		case enum.FPredUEQ:
+			result = unordered || a == b
+		case enum.FPredUNE:
+			result = unordered || a != b
+		case enum.FPredUGT:
+			result = unordered || a > b
+		case enum.FPredUGE:
+			result = unordered || a >= b
+		case enum.FPredULT:
+			result = unordered || a < b
+		case enum.FPredULE:
+			result = unordered || a <= b
+		default:
+			return nil
+		}
+		return constant.NewBool(result)
+	}
+	return nil
+}
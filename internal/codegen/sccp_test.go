@@ -0,0 +1,131 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+func i64Const(n int64) *constant.Int { return constant.NewInt(types.I64, n) }
+
+func TestOptimizer_ConstantPropagationBranches(t *testing.T) {
+	module := ir.NewModule()
+	x := ir.NewParam("x", types.I64)
+	fn := module.NewFunc("f", types.I64, x)
+	entry := fn.NewBlock("entry")
+	then := fn.NewBlock("then")
+	els := fn.NewBlock("else")
+	merge := fn.NewBlock("merge")
+
+	cond := entry.NewICmp(enum.IPredSLT, i64Const(1), i64Const(2))
+	entry.NewCondBr(cond, then, els)
+	sum := then.NewAdd(i64Const(3), i64Const(4))
+	then.NewBr(merge)
+	product := els.NewMul(x, i64Const(2))
+	els.NewBr(merge)
+	phi := merge.NewPhi(ir.NewIncoming(sum, then), ir.NewIncoming(product, els))
+	result := merge.NewAdd(phi, i64Const(1))
+	ret := merge.NewRet(result)
+
+	NewOptimizer(OptStandard).constantPropagation(fn)
+
+	if !isIntConst(ret.X, 8) {
+		t.Errorf("ret operand = %s, want 8\n%s", ret.X.Ident(), fn.LLString())
+	}
+	if _, ok := entry.Term.(*ir.TermBr); !ok {
+		t.Errorf("expected constant condition to become an unconditional branch\n%s", fn.LLString())
+	}
+	for _, block := range fn.Blocks {
+		if block == els {
+			t.Errorf("expected untaken block to be removed\n%s", fn.LLString())
+		}
+	}
+	if len(merge.Insts) != 0 {
+		t.Errorf("expected phi and add to fold away, got %d instructions\n%s", len(merge.Insts), fn.LLString())
+	}
+}
+
+func TestOptimizer_ConstantPropagationLoop(t *testing.T) {
+	module := ir.NewModule()
+	x := ir.NewParam("x", types.I64)
+	fn := module.NewFunc("f", types.I64, x)
+	entry := fn.NewBlock("entry")
+	loop := fn.NewBlock("loop")
+	exit := fn.NewBlock("exit")
+
+	entry.NewBr(loop)
+	counter := loop.NewPhi(ir.NewIncoming(i64Const(0), entry))
+	invariant := loop.NewPhi(ir.NewIncoming(i64Const(5), entry))
+	next := loop.NewAdd(counter, i64Const(1))
+	counter.Incs = append(counter.Incs, ir.NewIncoming(next, loop))
+	invariant.Incs = append(invariant.Incs, ir.NewIncoming(invariant, loop))
+	loop.NewCondBr(loop.NewICmp(enum.IPredSLT, next, x), loop, exit)
+	ret := exit.NewRet(exit.NewAdd(invariant, i64Const(1)))
+
+	NewOptimizer(OptStandard).constantPropagation(fn)
+
+	if !isIntConst(ret.X, 6) {
+		t.Errorf("ret operand = %s, want 6\n%s", ret.X.Ident(), fn.LLString())
+	}
+	// The counter varies across iterations and must survive
+	found := false
+	for _, inst := range loop.Insts {
+		if inst == counter {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected loop counter phi to be kept\n%s", fn.LLString())
+	}
+	if _, ok := loop.Term.(*ir.TermCondBr); !ok {
+		t.Errorf("expected loop condition to stay conditional\n%s", fn.LLString())
+	}
+}
+
+func TestOptimizer_ConstantPropagationKeepsMergedValues(t *testing.T) {
+	module := ir.NewModule()
+	flag := ir.NewParam("flag", types.I1)
+	fn := module.NewFunc("f", types.I64, flag)
+	entry := fn.NewBlock("entry")
+	then := fn.NewBlock("then")
+	merge := fn.NewBlock("merge")
+
+	entry.NewCondBr(flag, then, merge)
+	then.NewBr(merge)
+	phi := merge.NewPhi(ir.NewIncoming(i64Const(1), entry), ir.NewIncoming(i64Const(2), then))
+	ret := merge.NewRet(phi)
+
+	NewOptimizer(OptStandard).constantPropagation(fn)
+
+	if ret.X != phi || len(fn.Blocks) != 3 || len(phi.Incs) != 2 {
+		t.Errorf("expected phi of different constants to be kept\n%s", fn.LLString())
+	}
+}
+
+func TestOptimizer_ConstantPropagationSwitch(t *testing.T) {
+	module := ir.NewModule()
+	fn := module.NewFunc("f", types.I64)
+	entry := fn.NewBlock("entry")
+	one := fn.NewBlock("one")
+	two := fn.NewBlock("two")
+	other := fn.NewBlock("other")
+
+	selector := entry.NewSub(i64Const(5), i64Const(3))
+	entry.NewSwitch(selector, other, ir.NewCase(i64Const(1), one), ir.NewCase(i64Const(2), two))
+	one.NewRet(i64Const(10))
+	two.NewRet(i64Const(20))
+	other.NewRet(i64Const(30))
+
+	NewOptimizer(OptStandard).constantPropagation(fn)
+
+	br, ok := entry.Term.(*ir.TermBr)
+	if !ok || br.Target != two {
+		t.Fatalf("expected switch on constant to branch to case 2\n%s", fn.LLString())
+	}
+	if len(fn.Blocks) != 2 {
+		t.Errorf("expected 2 blocks after removing untaken cases, got %d\n%s", len(fn.Blocks), fn.LLString())
+	}
+}
@@ -0,0 +1,123 @@
+package codegen
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// scopedMapLiterals returns the map literals of fn, in source order, whose
+// map provably does not outlive the call, so that it can be released when
+// the call returns. The maps are not moved to the stack; they are allocated
+// by the runtime like any other map. A literal qualifies when it is the only
+// value assigned to its variable, and the variable is only indexed, read
+// by field, or passed as the map to a map builtin. Any other use, such as
+// returning the map, storing it, or passing it to a function, counts as an
+// escape.
+func scopedMapLiterals(fn *ast.Function) []*ast.Expression {
+	assignments := make(map[string]int)
+	for _, param := range functionParams(fn) {
+		assignments[param.Name]++
+	}
+	countAssignments(fn.Body, assignments, make(map[string]int64))
+
+	literals := make(map[string]*ast.Expression)
+	var order []string
+	collectMapLiterals(fn.Body, literals, &order)
+
+	// Count every reference to each variable, and the ones that cannot
+	// let the map escape
+	refs := make(map[string]int)
+	safeRefs := make(map[string]int)
+	ast.WalkStatements(fn.Body, nil, func(expr *ast.Expression) {
+		switch expr.Type {
+		case ast.ExprVariable:
+			refs[expr.Name]++
+		case ast.ExprIndex, ast.ExprField:
+			if expr.Object != nil && expr.Object.Type == ast.ExprVariable {
+				safeRefs[expr.Object.Name]++
+			}
+		case ast.ExprBuiltin:
+			if isMapBuiltin(expr.Name) && len(expr.Args) > 0 && expr.Args[0].Type == ast.ExprVariable {
+				safeRefs[expr.Args[0].Name]++
+			}
+		}
+	})
+
+	var result []*ast.Expression
+	for _, name := range order {
+		if assignments[name] == 1 && refs[name] == safeRefs[name] {
+			result = append(result, literals[name])
+		}
+	}
+	return result
+}
+
+// collectMapLiterals records, for each variable assigned a map literal in
+// stmts, the literal and the order in which the variables first appear.
+func collectMapLiterals(stmts []ast.Statement, literals map[string]*ast.Expression, order *[]string) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		if stmt.Type == ast.StmtAssign && stmt.Lvalue == nil && stmt.Value != nil && stmt.Value.Type == ast.ExprMapLit {
+			if _, seen := literals[stmt.Target]; !seen {
+				*order = append(*order, stmt.Target)
+			}
+			literals[stmt.Target] = stmt.Value
+		}
+		collectMapLiterals(stmt.Then, literals, order)
+		collectMapLiterals(stmt.Else, literals, order)
+		collectMapLiterals(stmt.Init, literals, order)
+		collectMapLiterals(stmt.Body, literals, order)
+		collectMapLiterals(stmt.Update, literals, order)
+		for j := range stmt.Cases {
+			collectMapLiterals(stmt.Cases[j].Body, literals, order)
+		}
+		collectMapLiterals(stmt.Default, literals, order)
+	}
+}
+
+// isMapBuiltin reports whether name is a builtin taking a map as its first
+// argument without keeping a reference to it.
+func isMapBuiltin(name string) bool {
+	switch name {
+	case "map.get", "map.put", "map.contains", "map.remove", "map.size", "map.keys", "map.values":
+		return true
+	}
+	return false
+}
+
+// allocateScopedMaps reserves a handle in the entry block of the current
+// function for each non-escaping map literal of fn. The map itself is still
+// a runtime hash table on the heap; the handle's stack address only names
+// it, so that releaseScopedMaps can drop it before the function returns
+// instead of leaving it registered for the rest of the program.
+func (g *LLVMCodegen) allocateScopedMaps(fn *ast.Function) {
+	g.scopedMaps = make(map[*ast.Expression]value.Value)
+	g.scopedMapOrder = nil
+	for _, literal := range scopedMapLiterals(fn) {
+		handle := g.builder.NewAlloca(types.I8)
+		handle.SetName("map_handle")
+		g.scopedMaps[literal] = handle
+		g.scopedMapOrder = append(g.scopedMapOrder, handle)
+	}
+}
+
+// releaseScopedMaps drops the maps named by the current function's scoped
+// handles. Releasing a handle whose literal never ran does nothing.
+func (g *LLVMCodegen) releaseScopedMaps() {
+	if len(g.scopedMapOrder) == 0 {
+		return
+	}
+	releaseFunc := g.runtimeMapFunc("alas_runtime_map_release", types.Void, types.I8Ptr)
+	for _, handle := range g.scopedMapOrder {
+		g.builder.NewCall(releaseFunc, handle)
+	}
+}
+
+// scopedMapInit returns the runtime function registering a map literal
+// under a scoped handle.
+func (g *LLVMCodegen) scopedMapInit() *ir.Func {
+	return g.runtimeMapFunc("alas_runtime_map_init", types.Void, types.I8Ptr, types.I8Ptr, types.I64)
+}
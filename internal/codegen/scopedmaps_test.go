@@ -0,0 +1,115 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestLLVMCodegen_ScopedMapsReleased(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	mapLit := func() *ast.Expression {
+		return &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: *lit("a"), Value: *lit(1.0)}}}
+	}
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	builtin := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: args}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+	size := builtin("map.size", *variable("m"))
+
+	tests := []struct {
+		name         string
+		returns      string
+		body         []ast.Statement
+		wantScoped   int
+		wantReleases int
+	}{
+		{
+			name:    "local use",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				{Type: ast.StmtExpr, Value: builtin("map.put", *variable("m"), *lit("b"), *lit(2.0))},
+				{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprIndex, Object: variable("m"), Index: lit("c")}, Value: lit(3.0)},
+				ret(size),
+			},
+			wantScoped:   1,
+			wantReleases: 1,
+		},
+		{
+			name:    "released on every return",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				{Type: ast.StmtIf, Cond: variable("flag"), Then: []ast.Statement{ret(lit(0.0))}},
+				ret(size),
+			},
+			wantScoped:   1,
+			wantReleases: 2,
+		},
+		{
+			name:    "returned",
+			returns: ast.TypeMap,
+			body:    []ast.Statement{assign("m", mapLit()), ret(variable("m"))},
+		},
+		{
+			name:    "stored in another map",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				assign("outer", &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: *lit("m"), Value: *variable("m")}}}),
+				ret(builtin("map.size", *variable("outer"))),
+			},
+			// outer itself does not escape
+			wantScoped:   1,
+			wantReleases: 1,
+		},
+		{
+			name:    "passed to a builtin",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				{Type: ast.StmtExpr, Value: builtin("io.print", *variable("m"))},
+				ret(size),
+			},
+		},
+		{
+			name:    "stored as a map value",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				{Type: ast.StmtExpr, Value: builtin("map.put", *variable("m"), *lit("self"), *variable("m"))},
+				ret(size),
+			},
+		},
+		{
+			name:    "reassigned",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				{Type: ast.StmtIf, Cond: variable("flag"), Then: []ast.Statement{assign("m", mapLit())}},
+				ret(size),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := singleFunctionModule(tt.returns, []ast.Parameter{{Name: "flag", Type: ast.TypeBool}}, tt.body)
+			ir := generateIR(t, module)
+			if got := strings.Count(ir, "call void @alas_runtime_map_init("); got != tt.wantScoped {
+				t.Errorf("got %d scoped maps, want %d:\n%s", got, tt.wantScoped, ir)
+			}
+			if got := strings.Count(ir, "call void @alas_runtime_map_release("); got != tt.wantReleases {
+				t.Errorf("got %d releases, want %d:\n%s", got, tt.wantReleases, ir)
+			}
+		})
+	}
+}
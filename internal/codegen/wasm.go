@@ -0,0 +1,672 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/consteval"
+)
+
+// WebAssembly value types. wasmVoid is the empty block type, and also marks
+// expressions that leave no value on the stack.
+const (
+	wasmI32  byte = 0x7F
+	wasmI64  byte = 0x7E
+	wasmF64  byte = 0x7C
+	wasmVoid byte = 0x40
+)
+
+// WebAssembly instruction opcodes used by the emitter.
+const (
+	wasmOpUnreachable    byte = 0x00
+	wasmOpBlock          byte = 0x02
+	wasmOpLoop           byte = 0x03
+	wasmOpIf             byte = 0x04
+	wasmOpElse           byte = 0x05
+	wasmOpEnd            byte = 0x0B
+	wasmOpBr             byte = 0x0C
+	wasmOpBrIf           byte = 0x0D
+	wasmOpReturn         byte = 0x0F
+	wasmOpCall           byte = 0x10
+	wasmOpDrop           byte = 0x1A
+	wasmOpLocalGet       byte = 0x20
+	wasmOpLocalSet       byte = 0x21
+	wasmOpI32Const       byte = 0x41
+	wasmOpI64Const       byte = 0x42
+	wasmOpF64Const       byte = 0x44
+	wasmOpI32Eqz         byte = 0x45
+	wasmOpI64Eqz         byte = 0x50
+	wasmOpF64Ne          byte = 0x62
+	wasmOpI64Mul         byte = 0x7E
+	wasmOpF64Neg         byte = 0x9A
+	wasmOpI64ExtendI32U  byte = 0xAD
+	wasmOpI64TruncF64S   byte = 0xB0
+	wasmOpF64ConvertI32U byte = 0xB8
+	wasmOpF64ConvertI64S byte = 0xB9
+)
+
+// Opcodes of binary operators by operand type.
+var wasmBinaryOps = map[byte]map[string]byte{
+	wasmI64: {
+		ast.OpAdd: 0x7C, ast.OpSub: 0x7D, ast.OpMul: 0x7E, ast.OpDiv: 0x7F, ast.OpMod: 0x81,
+		ast.OpEq: 0x51, ast.OpNe: 0x52, ast.OpLt: 0x53, ast.OpGt: 0x55, ast.OpLe: 0x57, ast.OpGe: 0x59,
+	},
+	wasmF64: {
+		ast.OpAdd: 0xA0, ast.OpSub: 0xA1, ast.OpMul: 0xA2, ast.OpDiv: 0xA3,
+		ast.OpEq: 0x61, ast.OpNe: 0x62, ast.OpLt: 0x63, ast.OpGt: 0x64, ast.OpLe: 0x65, ast.OpGe: 0x66,
+	},
+	wasmI32: {
+		ast.OpEq: 0x46, ast.OpNe: 0x47,
+	},
+}
+
+// WASMCodegen compiles ALaS modules directly to WebAssembly binaries that run
+// without a linker, for example in a browser. It covers int, float, and bool
+// values, control flow, and calls between the module's functions; strings,
+// arrays, maps, custom types, and builtins are reported as unsupported. Every
+// function is exported under its own name. Ints are i64, so JavaScript passes
+// and receives them as BigInt.
+type WASMCodegen struct {
+	module    *ast.Module
+	funcIndex map[string]uint32
+	signature map[string]wasmSignature
+
+	// State of the function being generated
+	function   *ast.Function
+	locals     map[string]uint32
+	localTypes []byte // Types of all locals, parameters first
+	body       *bytes.Buffer
+}
+
+// wasmSignature holds the value types of a function's parameters and result.
+type wasmSignature struct {
+	params []byte
+	result byte
+}
+
+// NewWASMCodegen creates a new WebAssembly code generator.
+func NewWASMCodegen() *WASMCodegen {
+	return &WASMCodegen{}
+}
+
+// GenerateModule compiles a module to the WebAssembly binary format.
+func (g *WASMCodegen) GenerateModule(module *ast.Module) ([]byte, error) {
+	g.module = module
+	g.funcIndex = make(map[string]uint32)
+	g.signature = make(map[string]wasmSignature)
+
+	var typeSection, funcSection, exportSection []byte
+	typeSection = appendULEB(typeSection, uint64(len(module.Functions)))
+	funcSection = appendULEB(funcSection, uint64(len(module.Functions)))
+	exportSection = appendULEB(exportSection, uint64(len(module.Functions)))
+	for i := range module.Functions {
+		fn := &module.Functions[i]
+		if fn.Receiver != nil {
+			return nil, fmt.Errorf("function %s: methods are not supported by the wasm target", functionSymbol(fn))
+		}
+		if fn.Extern != "" {
+			return nil, fmt.Errorf("function %s: extern functions are not supported by the wasm target", fn.Name)
+		}
+		sig, err := g.functionSignature(fn)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+		}
+		g.funcIndex[fn.Name] = uint32(i)
+		g.signature[fn.Name] = sig
+
+		// Each function gets its own type, with the same index
+		typeSection = append(typeSection, 0x60)
+		typeSection = appendULEB(typeSection, uint64(len(sig.params)))
+		typeSection = append(typeSection, sig.params...)
+		if sig.result == wasmVoid {
+			typeSection = appendULEB(typeSection, 0)
+		} else {
+			typeSection = append(typeSection, 1, sig.result)
+		}
+		funcSection = appendULEB(funcSection, uint64(i))
+		exportSection = appendName(exportSection, fn.Name)
+		exportSection = append(exportSection, 0x00) // function export
+		exportSection = appendULEB(exportSection, uint64(i))
+	}
+
+	var codeSection []byte
+	codeSection = appendULEB(codeSection, uint64(len(module.Functions)))
+	for i := range module.Functions {
+		code, err := g.generateFunction(&module.Functions[i])
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", module.Functions[i].Name, err)
+		}
+		codeSection = appendULEB(codeSection, uint64(len(code)))
+		codeSection = append(codeSection, code...)
+	}
+
+	out := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	out = appendSection(out, 1, typeSection)
+	out = appendSection(out, 3, funcSection)
+	out = appendSection(out, 7, exportSection)
+	out = appendSection(out, 10, codeSection)
+	return out, nil
+}
+
+// functionSignature returns the value types of a function's parameters and result.
+func (g *WASMCodegen) functionSignature(fn *ast.Function) (wasmSignature, error) {
+	var sig wasmSignature
+	for _, param := range fn.Params {
+		t, err := g.convertType(param.Type)
+		if err != nil {
+			return sig, err
+		}
+		if t == wasmVoid {
+			return sig, fmt.Errorf("parameter %s cannot be void", param.Name)
+		}
+		sig.params = append(sig.params, t)
+	}
+	result, err := g.convertType(fn.Returns)
+	if err != nil {
+		return sig, err
+	}
+	sig.result = result
+	return sig, nil
+}
+
+// convertType converts an ALaS type to a WebAssembly value type.
+func (g *WASMCodegen) convertType(alasType string) (byte, error) {
+	resolved, err := ast.ResolveTypeAlias(alasType, g.lookupType)
+	if err != nil {
+		return 0, err
+	}
+	switch resolved {
+	case ast.TypeInt:
+		return wasmI64, nil
+	case ast.TypeFloat:
+		return wasmF64, nil
+	case ast.TypeBool:
+		return wasmI32, nil
+	case ast.TypeVoid, "":
+		return wasmVoid, nil
+	default:
+		return 0, fmt.Errorf("%s values are not supported by the wasm target", resolved)
+	}
+}
+
+// lookupType returns the module's definition of a custom type, or nil.
+func (g *WASMCodegen) lookupType(name string) *ast.TypeDefinition {
+	for i := range g.module.Types {
+		if g.module.Types[i].Name == name {
+			return &g.module.Types[i]
+		}
+	}
+	return nil
+}
+
+// generateFunction returns the code section entry of a function: its local
+// declarations followed by its instructions.
+func (g *WASMCodegen) generateFunction(fn *ast.Function) ([]byte, error) {
+	sig := g.signature[fn.Name]
+	g.function = fn
+	g.locals = make(map[string]uint32)
+	g.localTypes = append([]byte(nil), sig.params...)
+	g.body = &bytes.Buffer{}
+	for i, param := range fn.Params {
+		g.locals[param.Name] = uint32(i)
+	}
+
+	for i := range fn.Body {
+		if err := g.generateStatement(&fn.Body[i]); err != nil {
+			return nil, err
+		}
+	}
+	// Falling off the end returns the zero value
+	if sig.result != wasmVoid {
+		g.emitZero(sig.result)
+	}
+	g.emit(wasmOpEnd)
+
+	declared := g.localTypes[len(sig.params):]
+	code := appendULEB(nil, uint64(len(declared)))
+	for _, t := range declared {
+		code = append(code, 1, t)
+	}
+	return append(code, g.body.Bytes()...), nil
+}
+
+// generateStatement emits the instructions of a statement, leaving the stack
+// as it found it.
+func (g *WASMCodegen) generateStatement(stmt *ast.Statement) error {
+	switch stmt.Type {
+	case ast.StmtAssign:
+		if stmt.Lvalue != nil {
+			return fmt.Errorf("element assignment is not supported by the wasm target")
+		}
+		t, err := g.generateExpression(stmt.Value)
+		if err != nil {
+			return err
+		}
+		if t == wasmVoid {
+			return fmt.Errorf("cannot assign a void value to '%s'", stmt.Target)
+		}
+		index, exists := g.locals[stmt.Target]
+		if !exists {
+			// The first assignment declares the variable with the value's type
+			index = uint32(len(g.localTypes))
+			g.locals[stmt.Target] = index
+			g.localTypes = append(g.localTypes, t)
+		} else if err := g.coerce(t, g.localTypes[index]); err != nil {
+			return fmt.Errorf("assignment to '%s': %w", stmt.Target, err)
+		}
+		g.emit(wasmOpLocalSet)
+		g.emitULEB(uint64(index))
+		return nil
+
+	case ast.StmtReturn:
+		result := g.signature[g.function.Name].result
+		if stmt.Value != nil {
+			if result == wasmVoid {
+				return fmt.Errorf("void function cannot return a value")
+			}
+			t, err := g.generateExpression(stmt.Value)
+			if err != nil {
+				return err
+			}
+			if err := g.coerce(t, result); err != nil {
+				return fmt.Errorf("return: %w", err)
+			}
+		} else if result != wasmVoid {
+			return fmt.Errorf("missing return value")
+		}
+		g.emit(wasmOpReturn)
+		return nil
+
+	case ast.StmtExpr:
+		t, err := g.generateExpression(stmt.Value)
+		if err != nil {
+			return err
+		}
+		if t != wasmVoid {
+			g.emit(wasmOpDrop)
+		}
+		return nil
+
+	case ast.StmtIf:
+		if err := g.generateCondition(stmt.Cond); err != nil {
+			return err
+		}
+		g.emit(wasmOpIf, wasmVoid)
+		if err := g.generateBlock(stmt.Then); err != nil {
+			return err
+		}
+		if len(stmt.Else) > 0 {
+			g.emit(wasmOpElse)
+			if err := g.generateBlock(stmt.Else); err != nil {
+				return err
+			}
+		}
+		g.emit(wasmOpEnd)
+		return nil
+
+	case ast.StmtWhile, ast.StmtFor:
+		// init; block { loop { br_if (!cond) 1; body; update; br 0 } }
+		if err := g.generateBlock(stmt.Init); err != nil {
+			return err
+		}
+		g.emit(wasmOpBlock, wasmVoid, wasmOpLoop, wasmVoid)
+		if err := g.generateCondition(stmt.Cond); err != nil {
+			return err
+		}
+		g.emit(wasmOpI32Eqz, wasmOpBrIf, 1)
+		if err := g.generateBlock(stmt.Body); err != nil {
+			return err
+		}
+		if err := g.generateBlock(stmt.Update); err != nil {
+			return err
+		}
+		g.emit(wasmOpBr, 0, wasmOpEnd, wasmOpEnd)
+		return nil
+
+	case ast.StmtAssert:
+		// A failed assertion traps
+		if err := g.generateCondition(stmt.Cond); err != nil {
+			return err
+		}
+		g.emit(wasmOpI32Eqz, wasmOpIf, wasmVoid, wasmOpUnreachable, wasmOpEnd)
+		return nil
+
+	default:
+		return fmt.Errorf("%s statements are not supported by the wasm target", stmt.Type)
+	}
+}
+
+// generateBlock emits a list of statements.
+func (g *WASMCodegen) generateBlock(stmts []ast.Statement) error {
+	for i := range stmts {
+		if err := g.generateStatement(&stmts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateCondition emits an expression that must be a bool.
+func (g *WASMCodegen) generateCondition(expr *ast.Expression) error {
+	t, err := g.generateExpression(expr)
+	if err != nil {
+		return err
+	}
+	if t != wasmI32 {
+		return fmt.Errorf("condition must be a boolean, got %s", wasmTypeName(t))
+	}
+	return nil
+}
+
+// generateExpression emits the instructions of an expression and returns the
+// type of the value it leaves on the stack.
+func (g *WASMCodegen) generateExpression(expr *ast.Expression) (byte, error) {
+	switch expr.Type {
+	case ast.ExprLiteral:
+		return g.generateLiteral(expr)
+
+	case ast.ExprVariable:
+		index, ok := g.locals[expr.Name]
+		if !ok {
+			return 0, fmt.Errorf("undefined variable: %s", expr.Name)
+		}
+		g.emit(wasmOpLocalGet)
+		g.emitULEB(uint64(index))
+		return g.localTypes[index], nil
+
+	case ast.ExprBinary:
+		return g.generateBinary(expr)
+
+	case ast.ExprUnary:
+		return g.generateUnary(expr)
+
+	case ast.ExprCall:
+		return g.generateCall(expr)
+
+	case ast.ExprCast:
+		return g.generateCast(expr)
+
+	default:
+		return 0, fmt.Errorf("%s expressions are not supported by the wasm target", expr.Type)
+	}
+}
+
+// generateLiteral emits a constant. Integral JSON numbers are ints unless
+// the literal is marked as a float.
+func (g *WASMCodegen) generateLiteral(expr *ast.Expression) (byte, error) {
+	lit, err := consteval.EvalConst(expr)
+	if err != nil {
+		return 0, err
+	}
+	switch v := lit.Value.(type) {
+	case int64:
+		g.emit(wasmOpI64Const)
+		g.emitSLEB(v)
+		return wasmI64, nil
+	case float64:
+		g.emitF64(v)
+		return wasmF64, nil
+	case bool:
+		g.emit(wasmOpI32Const)
+		if v {
+			g.emit(1)
+		} else {
+			g.emit(0)
+		}
+		return wasmI32, nil
+	default:
+		return 0, fmt.Errorf("%T literals are not supported by the wasm target", expr.LiteralValue())
+	}
+}
+
+// generateBinary emits a binary operation. Mixed int and float operands are
+// promoted to float; && and || short-circuit.
+func (g *WASMCodegen) generateBinary(expr *ast.Expression) (byte, error) {
+	if expr.Left == nil || expr.Right == nil {
+		return 0, fmt.Errorf("binary expression missing operand")
+	}
+	left, err := g.generateExpression(expr.Left)
+	if err != nil {
+		return 0, err
+	}
+
+	if expr.Op == ast.OpAnd || expr.Op == ast.OpOr {
+		if left != wasmI32 {
+			return 0, fmt.Errorf("operator %s expects booleans, got %s", expr.Op, wasmTypeName(left))
+		}
+		g.emit(wasmOpIf, wasmI32)
+		if expr.Op == ast.OpOr {
+			g.emit(wasmOpI32Const, 1, wasmOpElse)
+		}
+		if err := g.generateCondition(expr.Right); err != nil {
+			return 0, err
+		}
+		if expr.Op == ast.OpAnd {
+			g.emit(wasmOpElse, wasmOpI32Const, 0)
+		}
+		g.emit(wasmOpEnd)
+		return wasmI32, nil
+	}
+
+	// The right operand is generated aside so the left one can be promoted first
+	outer := g.body
+	g.body = &bytes.Buffer{}
+	right, err := g.generateExpression(expr.Right)
+	rightCode := g.body.Bytes()
+	g.body = outer
+	if err != nil {
+		return 0, err
+	}
+	if left == wasmI64 && right == wasmF64 {
+		g.emit(wasmOpF64ConvertI64S)
+		left = wasmF64
+	}
+	g.body.Write(rightCode)
+	if right == wasmI64 && left == wasmF64 {
+		g.emit(wasmOpF64ConvertI64S)
+		right = wasmF64
+	}
+	if left != right {
+		return 0, fmt.Errorf("operator %s: mismatched operand types %s and %s", expr.Op, wasmTypeName(left), wasmTypeName(right))
+	}
+
+	op, ok := wasmBinaryOps[left][expr.Op]
+	if !ok {
+		return 0, fmt.Errorf("operator %s on %s values is not supported by the wasm target", expr.Op, wasmTypeName(left))
+	}
+	g.emit(op)
+	switch expr.Op {
+	case ast.OpEq, ast.OpNe, ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe:
+		return wasmI32, nil
+	}
+	return left, nil
+}
+
+// generateUnary emits a logical not or a negation.
+func (g *WASMCodegen) generateUnary(expr *ast.Expression) (byte, error) {
+	operandExpr := expr.Operand
+	if operandExpr == nil {
+		operandExpr = expr.Right
+	}
+	if operandExpr == nil {
+		return 0, fmt.Errorf("unary expression missing operand")
+	}
+	t, err := g.generateExpression(operandExpr)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case expr.Op == ast.OpNot && t == wasmI32:
+		g.emit(wasmOpI32Eqz)
+	case expr.Op == ast.OpNeg && t == wasmI64:
+		g.emit(wasmOpI64Const)
+		g.emitSLEB(-1)
+		g.emit(wasmOpI64Mul)
+	case expr.Op == ast.OpNeg && t == wasmF64:
+		g.emit(wasmOpF64Neg)
+	default:
+		return 0, fmt.Errorf("unary operator %s on %s values is not supported by the wasm target", expr.Op, wasmTypeName(t))
+	}
+	return t, nil
+}
+
+// generateCall emits a call to a function of the module.
+func (g *WASMCodegen) generateCall(expr *ast.Expression) (byte, error) {
+	index, ok := g.funcIndex[expr.Name]
+	if !ok {
+		return 0, fmt.Errorf("undefined function: %s", expr.Name)
+	}
+	sig := g.signature[expr.Name]
+	if len(expr.Args) != len(sig.params) {
+		return 0, fmt.Errorf("function '%s' expects %d arguments, got %d", expr.Name, len(sig.params), len(expr.Args))
+	}
+	for i := range expr.Args {
+		t, err := g.generateExpression(&expr.Args[i])
+		if err != nil {
+			return 0, err
+		}
+		if err := g.coerce(t, sig.params[i]); err != nil {
+			return 0, fmt.Errorf("argument %d of %s: %w", i+1, expr.Name, err)
+		}
+	}
+	g.emit(wasmOpCall)
+	g.emitULEB(uint64(index))
+	return sig.result, nil
+}
+
+// generateCast emits a conversion between int, float, and bool.
+func (g *WASMCodegen) generateCast(expr *ast.Expression) (byte, error) {
+	if expr.Operand == nil {
+		return 0, fmt.Errorf("cast expression missing operand")
+	}
+	from, err := g.generateExpression(expr.Operand)
+	if err != nil {
+		return 0, err
+	}
+	to, err := g.convertType(expr.To)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case from == to:
+	case from == wasmI64 && to == wasmF64:
+		g.emit(wasmOpF64ConvertI64S)
+	case from == wasmF64 && to == wasmI64:
+		g.emit(wasmOpI64TruncF64S)
+	case from == wasmI32 && to == wasmI64:
+		g.emit(wasmOpI64ExtendI32U)
+	case from == wasmI32 && to == wasmF64:
+		g.emit(wasmOpF64ConvertI32U)
+	case from == wasmI64 && to == wasmI32:
+		g.emit(wasmOpI64Eqz, wasmOpI32Eqz)
+	case from == wasmF64 && to == wasmI32:
+		g.emitF64(0)
+		g.emit(wasmOpF64Ne)
+	default:
+		return 0, fmt.Errorf("cannot cast %s to %s", wasmTypeName(from), expr.To)
+	}
+	return to, nil
+}
+
+// coerce converts the value on top of the stack from one type to another,
+// allowing only the promotion of ints to floats.
+func (g *WASMCodegen) coerce(from, to byte) error {
+	switch {
+	case from == to:
+		return nil
+	case from == wasmI64 && to == wasmF64:
+		g.emit(wasmOpF64ConvertI64S)
+		return nil
+	default:
+		return fmt.Errorf("expected %s, got %s", wasmTypeName(to), wasmTypeName(from))
+	}
+}
+
+// emitZero emits the zero value of a type.
+func (g *WASMCodegen) emitZero(t byte) {
+	switch t {
+	case wasmI32:
+		g.emit(wasmOpI32Const, 0)
+	case wasmI64:
+		g.emit(wasmOpI64Const, 0)
+	case wasmF64:
+		g.emitF64(0)
+	}
+}
+
+// emit appends raw bytes to the function body.
+func (g *WASMCodegen) emit(b ...byte) {
+	g.body.Write(b)
+}
+
+// emitULEB appends an unsigned LEB128 immediate.
+func (g *WASMCodegen) emitULEB(v uint64) {
+	g.body.Write(appendULEB(nil, v))
+}
+
+// emitSLEB appends a signed LEB128 immediate.
+func (g *WASMCodegen) emitSLEB(v int64) {
+	g.body.Write(appendSLEB(nil, v))
+}
+
+// emitF64 appends an f64.const instruction.
+func (g *WASMCodegen) emitF64(v float64) {
+	g.emit(wasmOpF64Const)
+	g.body.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+// wasmTypeName returns the ALaS name of a WebAssembly value type.
+func wasmTypeName(t byte) string {
+	switch t {
+	case wasmI32:
+		return ast.TypeBool
+	case wasmI64:
+		return ast.TypeInt
+	case wasmF64:
+		return ast.TypeFloat
+	default:
+		return ast.TypeVoid
+	}
+}
+
+// appendULEB appends v in unsigned LEB128 encoding.
+func appendULEB(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// appendSLEB appends v in signed LEB128 encoding.
+func appendSLEB(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// appendName appends a length-prefixed UTF-8 name.
+func appendName(b []byte, name string) []byte {
+	b = appendULEB(b, uint64(len(name)))
+	return append(b, name...)
+}
+
+// appendSection appends a section with the given id and contents.
+func appendSection(b []byte, id byte, contents []byte) []byte {
+	b = append(b, id)
+	b = appendULEB(b, uint64(len(contents)))
+	return append(b, contents...)
+}
@@ -0,0 +1,130 @@
+package codegen
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// wasmNumericModule covers the features the wasm target supports: int and
+// float arithmetic, comparisons, short-circuit logic, casts, loops, and
+// recursive calls.
+const wasmNumericModule = `{"type": "module", "name": "numeric", "functions": [
+	{"type": "function", "name": "factorial", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "if", "cond": {"type": "binary", "op": "<=", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 1}},
+			"then": [{"type": "return", "value": {"type": "literal", "value": 1}}]},
+		{"type": "return", "value": {"type": "binary", "op": "*", "left": {"type": "variable", "name": "n"},
+			"right": {"type": "call", "name": "factorial", "args": [{"type": "binary", "op": "-", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 1}}]}}}]},
+	{"type": "function", "name": "mean", "params": [{"name": "a", "type": "float"}, {"name": "b", "type": "int"}], "returns": "float", "body": [
+		{"type": "return", "value": {"type": "binary", "op": "/",
+			"left": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "a"}, "right": {"type": "variable", "name": "b"}},
+			"right": {"type": "literal", "value": 2}}}]},
+	{"type": "function", "name": "sumTo", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "assign", "target": "i", "value": {"type": "literal", "value": 0}},
+		{"type": "assign", "target": "sum", "value": {"type": "literal", "value": 0}},
+		{"type": "while", "cond": {"type": "binary", "op": "<=", "left": {"type": "variable", "name": "i"}, "right": {"type": "variable", "name": "n"}}, "body": [
+			{"type": "assign", "target": "sum", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "sum"}, "right": {"type": "variable", "name": "i"}}},
+			{"type": "assign", "target": "i", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 1}}}]},
+		{"type": "return", "value": {"type": "variable", "name": "sum"}}]},
+	{"type": "function", "name": "inRange", "params": [{"name": "x", "type": "int"}], "returns": "bool", "body": [
+		{"type": "return", "value": {"type": "binary", "op": "&&",
+			"left": {"type": "binary", "op": ">=", "left": {"type": "variable", "name": "x"}, "right": {"type": "literal", "value": 0}},
+			"right": {"type": "unary", "op": "!", "operand": {"type": "binary", "op": ">", "left": {"type": "variable", "name": "x"}, "right": {"type": "literal", "value": 10}}}}}]},
+	{"type": "function", "name": "truncate", "params": [{"name": "x", "type": "float"}], "returns": "int", "body": [
+		{"type": "return", "value": {"type": "unary", "op": "-", "operand": {"type": "cast", "to": "int", "operand": {"type": "variable", "name": "x"}}}}]}]}`
+
+func TestWASMCodegen_GenerateModule(t *testing.T) {
+	module, err := ast.ParseModule([]byte(wasmNumericModule), "numeric.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	wasm, err := NewWASMCodegen().GenerateModule(module)
+	if err != nil {
+		t.Fatalf("GenerateModule failed: %v", err)
+	}
+	if !bytes.HasPrefix(wasm, []byte("\x00asm\x01\x00\x00\x00")) {
+		t.Fatalf("output does not start with the wasm header: % x", wasm[:8])
+	}
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not available to run the module")
+	}
+	file := filepath.Join(t.TempDir(), "numeric.wasm")
+	if err := os.WriteFile(file, wasm, 0600); err != nil {
+		t.Fatal(err)
+	}
+	script := `const fs = require("fs");
+const m = new WebAssembly.Instance(new WebAssembly.Module(fs.readFileSync(process.argv[1]))).exports;
+console.log([m.factorial(10n), m.mean(1.5, 4n), m.sumTo(100n), m.inRange(5n), m.inRange(11n), m.truncate(7.9)].join(","));`
+	out, err := exec.Command(node, "-e", script, file).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running the module failed: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "3628800,2.75,5050,1,0,-7"; got != want {
+		t.Errorf("results = %s, want %s", got, want)
+	}
+}
+
+func TestWASMCodegen_Unsupported(t *testing.T) {
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+
+	tests := []struct {
+		name    string
+		module  *ast.Module
+		wantErr string
+	}{
+		{
+			name:    "string result",
+			module:  singleFunctionModule(ast.TypeString, []ast.Parameter{}, []ast.Statement{{Type: ast.StmtReturn, Value: lit("hi")}}),
+			wantErr: "string values are not supported by the wasm target",
+		},
+		{
+			name:    "array parameter",
+			module:  singleFunctionModule(ast.TypeInt, []ast.Parameter{{Name: "xs", Type: ast.TypeArray}}, []ast.Statement{}),
+			wantErr: "array values are not supported by the wasm target",
+		},
+		{
+			name: "string literal",
+			module: singleFunctionModule(ast.TypeVoid, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtAssign, Target: "s", Value: lit("hi")},
+			}),
+			wantErr: "string literals are not supported by the wasm target",
+		},
+		{
+			name: "builtin call",
+			module: singleFunctionModule(ast.TypeVoid, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "io.print", Args: []ast.Expression{*lit(1.0)}}},
+			}),
+			wantErr: "builtin expressions are not supported by the wasm target",
+		},
+		{
+			name: "float remainder",
+			module: singleFunctionModule(ast.TypeFloat, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMod, Left: lit(2.5), Right: lit(2.0)}},
+			}),
+			wantErr: "operator % on float values is not supported by the wasm target",
+		},
+		{
+			name: "non-boolean condition",
+			module: singleFunctionModule(ast.TypeVoid, []ast.Parameter{}, []ast.Statement{
+				{Type: ast.StmtIf, Cond: lit(1.0), Then: []ast.Statement{}},
+			}),
+			wantErr: "condition must be a boolean, got int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWASMCodegen().GenerateModule(tt.module)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GenerateModule() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Package consteval evaluates ALaS expressions whose value is known at
+// compile time, so the validator and the code generators agree on what a
+// constant expression means.
+package consteval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+var (
+	// ErrNotConstant is returned when an expression depends on values only
+	// known at run time.
+	ErrNotConstant = errors.New("expression is not constant")
+	// ErrOverflow is returned when constant integer arithmetic overflows.
+	ErrOverflow = errors.New("integer overflow")
+	// ErrDivisionByZero is returned when a constant division or modulo has a
+	// zero divisor.
+	ErrDivisionByZero = errors.New("division by zero")
+)
+
+// EvalConst evaluates a constant expression: literals, and unary, binary and
+// cast expressions over constants. Any other expression fails with
+// ErrNotConstant.
+func EvalConst(expr *ast.Expression) (runtime.Value, error) {
+	return EvalConstIn(expr, nil)
+}
+
+// EvalConstIn evaluates a constant expression in which variables name the
+// given constants.
+func EvalConstIn(expr *ast.Expression, constants map[string]runtime.Value) (runtime.Value, error) {
+	if expr == nil {
+		return runtime.NewVoid(), ErrNotConstant
+	}
+	switch expr.Type {
+	case ast.ExprLiteral:
+		return literal(expr)
+
+	case ast.ExprVariable:
+		if value, ok := constants[expr.Name]; ok {
+			return value, nil
+		}
+		return runtime.NewVoid(), fmt.Errorf("%w: variable %s", ErrNotConstant, expr.Name)
+
+	case ast.ExprUnary:
+		operandExpr := expr.Operand
+		if operandExpr == nil {
+			operandExpr = expr.Right
+		}
+		operand, err := EvalConstIn(operandExpr, constants)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return unary(expr.Op, operand)
+
+	case ast.ExprBinary:
+		left, err := EvalConstIn(expr.Left, constants)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		right, err := EvalConstIn(expr.Right, constants)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return binary(expr.Op, left, right)
+
+	case ast.ExprCast:
+		operand, err := EvalConstIn(expr.Operand, constants)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return cast(expr.To, operand)
+	}
+	return runtime.NewVoid(), fmt.Errorf("%w: %s expression", ErrNotConstant, expr.Type)
+}
+
+// literal returns the value of a literal. Integral JSON numbers are ints
+// unless the literal is marked as a float.
+func literal(expr *ast.Expression) (runtime.Value, error) {
+	if expr.To == ast.TypeDecimal {
+		// Only the interpreter has decimals
+		return runtime.NewVoid(), fmt.Errorf("decimal literals are only supported by the interpreter")
+	}
+	switch v := expr.LiteralValue().(type) {
+	case float64:
+		if expr.To != ast.TypeFloat && float64(int64(v)) == v {
+			return runtime.NewInt(int64(v)), nil
+		}
+		return runtime.NewFloat(v), nil
+	case float32:
+		return runtime.NewFloat(float64(v)), nil
+	case int:
+		return runtime.NewInt(int64(v)), nil
+	case int8:
+		return runtime.NewInt(int64(v)), nil
+	case int16:
+		return runtime.NewInt(int64(v)), nil
+	case int32:
+		return runtime.NewInt(int64(v)), nil
+	case int64:
+		return runtime.NewInt(v), nil
+	case *big.Int:
+		// Only the interpreter has bigints
+		if !v.IsInt64() {
+			return runtime.NewVoid(), fmt.Errorf("integer literal %s does not fit in 64 bits", v)
+		}
+		return runtime.NewInt(v.Int64()), nil
+	case string:
+		return runtime.NewString(v), nil
+	case bool:
+		return runtime.NewBool(v), nil
+	case nil:
+		return runtime.NewVoid(), nil
+	default:
+		return runtime.NewVoid(), fmt.Errorf("unsupported literal type: %T", v)
+	}
+}
+
+// unary applies a unary operator to a constant.
+func unary(op string, operand runtime.Value) (runtime.Value, error) {
+	switch op {
+	case ast.OpNot:
+		return runtime.NewBool(!operand.IsTruthy()), nil
+	case ast.OpNeg:
+		switch operand.Type {
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			return runtime.NewFloat(-f), nil
+		case runtime.ValueTypeInt:
+			n, _ := operand.AsInt()
+			if n == math.MinInt64 {
+				return runtime.NewVoid(), fmt.Errorf("%w: -(%d)", ErrOverflow, n)
+			}
+			return runtime.NewInt(-n), nil
+		}
+		return runtime.NewVoid(), fmt.Errorf("cannot negate %s", typeName(operand))
+	}
+	return runtime.NewVoid(), fmt.Errorf("unknown unary operator: %s", op)
+}
+
+// binary applies a binary operator to two constants. Int arithmetic that
+// does not fit in 64 bits fails with ErrOverflow.
+func binary(op string, left, right runtime.Value) (runtime.Value, error) {
+	switch op {
+	case ast.OpAnd:
+		return runtime.NewBool(left.IsTruthy() && right.IsTruthy()), nil
+	case ast.OpOr:
+		return runtime.NewBool(left.IsTruthy() || right.IsTruthy()), nil
+	case ast.OpEq, ast.OpNe:
+		equal, err := equal(left, right)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return runtime.NewBool(equal == (op == ast.OpEq)), nil
+	case ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe:
+		return compare(op, left, right)
+	case ast.OpAdd:
+		if left.Type == runtime.ValueTypeString && right.Type == runtime.ValueTypeString {
+			l, _ := left.AsString()
+			r, _ := right.AsString()
+			return runtime.NewString(l + r), nil
+		}
+	case ast.OpSub, ast.OpMul, ast.OpDiv, ast.OpMod:
+	default:
+		return runtime.NewVoid(), fmt.Errorf("unknown binary operator: %s", op)
+	}
+
+	if !isNumeric(left) || !isNumeric(right) {
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, typeName(left), typeName(right))
+	}
+	if left.Type == runtime.ValueTypeFloat || right.Type == runtime.ValueTypeFloat {
+		return floatArithmetic(op, left, right)
+	}
+	l, _ := left.AsInt()
+	r, _ := right.AsInt()
+	return intArithmetic(op, l, r)
+}
+
+// intArithmetic applies an arithmetic operator to two ints, checking for
+// overflow.
+func intArithmetic(op string, l, r int64) (runtime.Value, error) {
+	var result int64
+	overflow := false
+	switch op {
+	case ast.OpAdd:
+		result = l + r
+		overflow = (r > 0 && result < l) || (r < 0 && result > l)
+	case ast.OpSub:
+		result = l - r
+		overflow = (r > 0 && result > l) || (r < 0 && result < l)
+	case ast.OpMul:
+		result = l * r
+		overflow = l != 0 && (result/l != r || (l == -1 && r == math.MinInt64))
+	case ast.OpDiv, ast.OpMod:
+		if r == 0 {
+			return runtime.NewVoid(), fmt.Errorf("%w: %d %s 0", ErrDivisionByZero, l, op)
+		}
+		if l == math.MinInt64 && r == -1 {
+			if op == ast.OpMod {
+				return runtime.NewInt(0), nil
+			}
+			overflow = true
+			break
+		}
+		if op == ast.OpDiv {
+			result = l / r
+		} else {
+			result = l % r
+		}
+	}
+	if overflow {
+		return runtime.NewVoid(), fmt.Errorf("%w: %d %s %d", ErrOverflow, l, op, r)
+	}
+	return runtime.NewInt(result), nil
+}
+
+// floatArithmetic applies an arithmetic operator to two numbers, at least
+// one of them a float.
+func floatArithmetic(op string, left, right runtime.Value) (runtime.Value, error) {
+	l, _ := left.AsFloat()
+	r, _ := right.AsFloat()
+	switch op {
+	case ast.OpAdd:
+		return runtime.NewFloat(l + r), nil
+	case ast.OpSub:
+		return runtime.NewFloat(l - r), nil
+	case ast.OpMul:
+		return runtime.NewFloat(l * r), nil
+	case ast.OpDiv:
+		if r == 0 {
+			return runtime.NewVoid(), fmt.Errorf("%w: %v / 0", ErrDivisionByZero, l)
+		}
+		return runtime.NewFloat(l / r), nil
+	}
+	return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to float values", op)
+}
+
+// equal reports whether two constants are equal. Ints and floats compare by
+// value.
+func equal(left, right runtime.Value) (bool, error) {
+	if isNumeric(left) && isNumeric(right) {
+		if left.Type == runtime.ValueTypeInt && right.Type == runtime.ValueTypeInt {
+			l, _ := left.AsInt()
+			r, _ := right.AsInt()
+			return l == r, nil
+		}
+		l, _ := left.AsFloat()
+		r, _ := right.AsFloat()
+		return l == r, nil
+	}
+	if left.Type != right.Type {
+		return false, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+	}
+	switch left.Type {
+	case runtime.ValueTypeString:
+		l, _ := left.AsString()
+		r, _ := right.AsString()
+		return l == r, nil
+	case runtime.ValueTypeBool:
+		l, _ := left.AsBool()
+		r, _ := right.AsBool()
+		return l == r, nil
+	}
+	return false, fmt.Errorf("cannot compare %s values", typeName(left))
+}
+
+// compare applies an ordering operator to two numbers or two strings.
+// Comparisons involving NaN are false.
+func compare(op string, left, right runtime.Value) (runtime.Value, error) {
+	var order int
+	switch {
+	case left.Type == runtime.ValueTypeString && right.Type == runtime.ValueTypeString:
+		l, _ := left.AsString()
+		r, _ := right.AsString()
+		order = compareOrdered(l, r)
+	case left.Type == runtime.ValueTypeInt && right.Type == runtime.ValueTypeInt:
+		l, _ := left.AsInt()
+		r, _ := right.AsInt()
+		order = compareOrdered(l, r)
+	case isNumeric(left) && isNumeric(right):
+		l, _ := left.AsFloat()
+		r, _ := right.AsFloat()
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return runtime.NewBool(false), nil
+		}
+		order = compareOrdered(l, r)
+	default:
+		return runtime.NewVoid(), fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+	}
+
+	switch op {
+	case ast.OpLt:
+		return runtime.NewBool(order < 0), nil
+	case ast.OpLe:
+		return runtime.NewBool(order <= 0), nil
+	case ast.OpGt:
+		return runtime.NewBool(order > 0), nil
+	}
+	return runtime.NewBool(order >= 0), nil
+}
+
+// compareOrdered returns -1, 0 or 1 as l is less than, equal to or greater
+// than r.
+func compareOrdered[T int64 | float64 | string](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// cast converts a constant to a basic type, following the interpreter's
+// conversion rules.
+func cast(target string, operand runtime.Value) (runtime.Value, error) {
+	switch target {
+	case ast.TypeInt:
+		switch operand.Type {
+		case runtime.ValueTypeInt:
+			return operand, nil
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			truncated := math.Trunc(f)
+			if math.IsNaN(f) || truncated < math.MinInt64 || truncated >= math.MaxInt64 {
+				return runtime.NewVoid(), fmt.Errorf("%w: cannot cast %v to int", ErrOverflow, f)
+			}
+			return runtime.NewInt(int64(truncated)), nil
+		case runtime.ValueTypeBool:
+			if operand.IsTruthy() {
+				return runtime.NewInt(1), nil
+			}
+			return runtime.NewInt(0), nil
+		}
+	case ast.TypeFloat:
+		if isNumeric(operand) {
+			f, _ := operand.AsFloat()
+			return runtime.NewFloat(f), nil
+		}
+		if operand.Type == runtime.ValueTypeBool {
+			if operand.IsTruthy() {
+				return runtime.NewFloat(1), nil
+			}
+			return runtime.NewFloat(0), nil
+		}
+	case ast.TypeBool:
+		if operand.Type == runtime.ValueTypeBool || isNumeric(operand) {
+			return runtime.NewBool(operand.IsTruthy()), nil
+		}
+	case ast.TypeString:
+		switch operand.Type {
+		case runtime.ValueTypeString:
+			return operand, nil
+		case runtime.ValueTypeInt:
+			n, _ := operand.AsInt()
+			return runtime.NewString(strconv.FormatInt(n, 10)), nil
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			return runtime.NewString(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		case runtime.ValueTypeBool:
+			b, _ := operand.AsBool()
+			return runtime.NewString(strconv.FormatBool(b)), nil
+		}
+	}
+	// String parsing is left to run time, where a bad string is an error
+	// the program can observe
+	return runtime.NewVoid(), fmt.Errorf("%w: cast of %s to %s", ErrNotConstant, typeName(operand), target)
+}
+
+// isNumeric reports whether a constant is an int or a float.
+func isNumeric(v runtime.Value) bool {
+	return v.Type == runtime.ValueTypeInt || v.Type == runtime.ValueTypeFloat
+}
+
+// typeName returns the ALaS name of a constant's type.
+func typeName(v runtime.Value) string {
+	switch v.Type {
+	case runtime.ValueTypeInt:
+		return ast.TypeInt
+	case runtime.ValueTypeFloat:
+		return ast.TypeFloat
+	case runtime.ValueTypeString:
+		return ast.TypeString
+	case runtime.ValueTypeBool:
+		return ast.TypeBool
+	}
+	return ast.TypeVoid
+}
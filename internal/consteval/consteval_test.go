@@ -0,0 +1,107 @@
+package consteval
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func lit(v interface{}) *ast.Expression {
+	return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+}
+
+func binaryExpr(op string, left, right *ast.Expression) *ast.Expression {
+	return &ast.Expression{Type: ast.ExprBinary, Op: op, Left: left, Right: right}
+}
+
+func TestEvalConst(t *testing.T) {
+	tests := []struct {
+		name string
+		expr *ast.Expression
+		want runtime.Value
+	}{
+		{name: "integral number is an int", expr: lit(3.0), want: runtime.NewInt(3)},
+		{name: "float literal", expr: &ast.Expression{Type: ast.ExprLiteral, Value: 3.0, To: ast.TypeFloat}, want: runtime.NewFloat(3)},
+		{name: "go int literal", expr: lit(7), want: runtime.NewInt(7)},
+		{name: "int arithmetic", expr: binaryExpr(ast.OpAdd, lit(2.0), binaryExpr(ast.OpMul, lit(3.0), lit(4.0))), want: runtime.NewInt(14)},
+		{name: "int division truncates", expr: binaryExpr(ast.OpDiv, lit(-7.0), lit(2.0)), want: runtime.NewInt(-3)},
+		{name: "modulo", expr: binaryExpr(ast.OpMod, lit(17.0), lit(5.0)), want: runtime.NewInt(2)},
+		{name: "int division of small values", expr: binaryExpr(ast.OpDiv, lit(1.0), lit(4.0)), want: runtime.NewInt(0)},
+		{name: "mixed arithmetic", expr: binaryExpr(ast.OpAdd, lit(1.5), lit(2.0)), want: runtime.NewFloat(3.5)},
+		{name: "string concatenation", expr: binaryExpr(ast.OpAdd, lit("ab"), lit("cd")), want: runtime.NewString("abcd")},
+		{name: "comparison", expr: binaryExpr(ast.OpLt, lit(1.0), lit(2.5)), want: runtime.NewBool(true)},
+		{name: "string comparison", expr: binaryExpr(ast.OpGe, lit("a"), lit("b")), want: runtime.NewBool(false)},
+		{name: "mixed equality", expr: binaryExpr(ast.OpEq, lit(2.0), &ast.Expression{Type: ast.ExprLiteral, Value: 2.0, To: ast.TypeFloat}), want: runtime.NewBool(true)},
+		{name: "logic", expr: binaryExpr(ast.OpOr, lit(false), binaryExpr(ast.OpAnd, lit(true), lit(true))), want: runtime.NewBool(true)},
+		{name: "negation", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: lit(5.0)}, want: runtime.NewInt(-5)},
+		{name: "not", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNot, Right: lit(true)}, want: runtime.NewBool(false)},
+		{name: "cast truncates", expr: &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: lit(-2.7)}, want: runtime.NewInt(-2)},
+		{name: "cast to string", expr: &ast.Expression{Type: ast.ExprCast, To: ast.TypeString, Operand: lit(1.5)}, want: runtime.NewString("1.5")},
+		{name: "min int modulo minus one", expr: binaryExpr(ast.OpMod, lit(int64(math.MinInt64)), lit(-1.0)), want: runtime.NewInt(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalConst(tt.expr)
+			if err != nil {
+				t.Fatalf("EvalConst() error = %v", err)
+			}
+			if got.Type != tt.want.Type || got.Value != tt.want.Value {
+				t.Errorf("EvalConst() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalConstErrors(t *testing.T) {
+	maxInt := lit(int64(math.MaxInt64))
+	minInt := lit(int64(math.MinInt64))
+
+	tests := []struct {
+		name    string
+		expr    *ast.Expression
+		wantErr error
+	}{
+		{name: "variable", expr: &ast.Expression{Type: ast.ExprVariable, Name: "x"}, wantErr: ErrNotConstant},
+		{name: "call", expr: binaryExpr(ast.OpAdd, lit(1.0), &ast.Expression{Type: ast.ExprCall, Name: "f"}), wantErr: ErrNotConstant},
+		{name: "string parse", expr: &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: lit("12")}, wantErr: ErrNotConstant},
+		{name: "addition overflow", expr: binaryExpr(ast.OpAdd, maxInt, lit(1.0)), wantErr: ErrOverflow},
+		{name: "subtraction overflow", expr: binaryExpr(ast.OpSub, minInt, lit(1.0)), wantErr: ErrOverflow},
+		{name: "multiplication overflow", expr: binaryExpr(ast.OpMul, lit(int64(1)<<62), lit(4.0)), wantErr: ErrOverflow},
+		{name: "min int times minus one", expr: binaryExpr(ast.OpMul, lit(-1.0), minInt), wantErr: ErrOverflow},
+		{name: "min int divided by minus one", expr: binaryExpr(ast.OpDiv, minInt, lit(-1.0)), wantErr: ErrOverflow},
+		{name: "negating min int", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: minInt}, wantErr: ErrOverflow},
+		{name: "cast out of range", expr: &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: lit(1e19)}, wantErr: ErrOverflow},
+		{name: "division by zero", expr: binaryExpr(ast.OpDiv, lit(1.0), lit(0.0)), wantErr: ErrDivisionByZero},
+		{name: "float division by zero", expr: binaryExpr(ast.OpDiv, lit(1.5), lit(0.0)), wantErr: ErrDivisionByZero},
+		{name: "modulo by zero", expr: binaryExpr(ast.OpMod, lit(1.0), lit(0.0)), wantErr: ErrDivisionByZero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EvalConst(tt.expr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("EvalConst() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalConstIn(t *testing.T) {
+	constants := map[string]runtime.Value{"size": runtime.NewInt(8)}
+	expr := binaryExpr(ast.OpMul, &ast.Expression{Type: ast.ExprVariable, Name: "size"}, lit(2.0))
+
+	got, err := EvalConstIn(expr, constants)
+	if err != nil {
+		t.Fatalf("EvalConstIn() error = %v", err)
+	}
+	if n, _ := got.AsInt(); got.Type != runtime.ValueTypeInt || n != 16 {
+		t.Errorf("EvalConstIn() = %#v, want 16", got)
+	}
+	if _, err := EvalConst(expr); !errors.Is(err, ErrNotConstant) {
+		t.Errorf("EvalConst() without constants error = %v, want ErrNotConstant", err)
+	}
+}
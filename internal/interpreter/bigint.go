@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// eitherBigInt reports whether either operand is a bigint.
+func eitherBigInt(left, right runtime.Value) bool {
+	return left.Type == runtime.ValueTypeBigInt || right.Type == runtime.ValueTypeBigInt
+}
+
+// bigArithmetic applies an arithmetic operator to two ints or bigints. The
+// result is an int again when it fits in 64 bits. Division truncates toward
+// zero, as it does for ints.
+func bigArithmetic(op string, left, right runtime.Value) (runtime.Value, error) {
+	l, err := left.AsBigInt()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, valueTypeName(left.Type), valueTypeName(right.Type))
+	}
+	r, err := right.AsBigInt()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, valueTypeName(left.Type), valueTypeName(right.Type))
+	}
+
+	result := new(big.Int)
+	switch op {
+	case ast.OpAdd:
+		result.Add(l, r)
+	case ast.OpSub:
+		result.Sub(l, r)
+	case ast.OpMul:
+		result.Mul(l, r)
+	case ast.OpDiv:
+		if r.Sign() == 0 {
+			return runtime.NewVoid(), fmt.Errorf("division by zero")
+		}
+		result.Quo(l, r)
+	case ast.OpMod:
+		if r.Sign() == 0 {
+			return runtime.NewVoid(), fmt.Errorf("modulo by zero")
+		}
+		result.Rem(l, r)
+	default:
+		return runtime.NewVoid(), fmt.Errorf("unknown binary operator: %s", op)
+	}
+	return runtime.NewBigInt(result), nil
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability names checked by the interpreter. They match the capabilities a
+// plugin manifest can declare.
+const (
+	CapabilityIO         = "io"
+	CapabilityFileSystem = "filesystem"
+	CapabilityNetwork    = "network"
+	CapabilityProcess    = "process"
+	CapabilityNative     = "native"
+)
+
+// CapabilityError reports a builtin call rejected because the calling plugin
+// module was not granted the capability the builtin requires.
+type CapabilityError struct {
+	Module     string
+	Builtin    string
+	Capability string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("plugin module '%s' cannot call %s: missing '%s' capability",
+		e.Module, e.Builtin, e.Capability)
+}
+
+// RestrictModule marks a module as plugin code that may only call restricted
+// builtins covered by the given capabilities. Modules that are never
+// restricted keep full access. Calling it again replaces the granted set.
+func (i *Interpreter) RestrictModule(module string, capabilities []string) {
+	granted := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		granted[capability] = true
+	}
+	i.mu.Lock()
+	i.restricted[module] = granted
+	i.mu.Unlock()
+}
+
+// builtinCapability returns the capability required to call a builtin from
+// plugin code, or "" if the builtin is unrestricted. File access in the io
+// namespace needs the filesystem capability rather than io, and the
+// environment variables of the os namespace belong to the process.
+func builtinCapability(name string) string {
+	switch name {
+	case "io.readFile", "io.writeFile":
+		return CapabilityFileSystem
+	}
+	namespace, _, _ := strings.Cut(strings.TrimPrefix(name, "std."), ".")
+	switch namespace {
+	case "io":
+		return CapabilityIO
+	case "fs", "file":
+		return CapabilityFileSystem
+	case "net", "http":
+		return CapabilityNetwork
+	case "process", "os":
+		return CapabilityProcess
+	}
+	return ""
+}
+
+// checkBuiltinAccess rejects a builtin call made from a restricted plugin
+// module that lacks the capability the builtin requires.
+func (i *Interpreter) checkBuiltinAccess(name string, env *Environment) error {
+	module := env.moduleName()
+	i.mu.RLock()
+	granted, ok := i.restricted[module]
+	i.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	capability := builtinCapability(name)
+	if capability == "" || granted[capability] {
+		return nil
+	}
+	return &CapabilityError{Module: module, Builtin: name, Capability: capability}
+}
@@ -0,0 +1,105 @@
+package interpreter
+
+import (
+	"sync"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// coverage records which statements have executed.
+type coverage struct {
+	mu    sync.Mutex // guards slots and hits, since modules load while functions run
+	slots map[*ast.Statement]coverageSlot
+	hits  map[string][]bool // function name -> executed flag per statement index
+}
+
+// coverageSlot locates a statement's executed flag.
+type coverageSlot struct {
+	function string
+	index    int
+}
+
+// EnableCoverage starts recording which statements execute. Statements are
+// numbered per function in source order, including those nested in if, while,
+// for, and match bodies. Methods are recorded as "Type.method", and functions
+// whose name is already taken by another module as "module.function".
+// It must be called before any function runs.
+func (i *Interpreter) EnableCoverage() {
+	if i.coverage != nil {
+		return
+	}
+	i.coverage = &coverage{
+		slots: make(map[*ast.Statement]coverageSlot),
+		hits:  make(map[string][]bool),
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, name := range i.moduleOrder {
+		i.coverage.addModule(i.modules[name])
+	}
+}
+
+// Coverage returns, per function, which statement indices have executed.
+// It returns nil if coverage is not enabled.
+func (i *Interpreter) Coverage() map[string][]bool {
+	if i.coverage == nil {
+		return nil
+	}
+	i.coverage.mu.Lock()
+	defer i.coverage.mu.Unlock()
+
+	result := make(map[string][]bool, len(i.coverage.hits))
+	for name, hits := range i.coverage.hits {
+		result[name] = append([]bool(nil), hits...)
+	}
+	return result
+}
+
+// addModule numbers the statements of every function in module.
+func (c *coverage) addModule(module *ast.Module) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for idx := range module.Functions {
+		fn := &module.Functions[idx]
+		name := fn.Name
+		if fn.Receiver != nil {
+			name = fn.Receiver.Type + "." + fn.Name
+		}
+		if _, taken := c.hits[name]; taken {
+			name = module.Name + "." + name
+		}
+
+		count := 0
+		c.addStatements(name, fn.Body, &count)
+		c.hits[name] = make([]bool, count)
+	}
+}
+
+// addStatements assigns indices to stmts and their nested statements in
+// source order.
+func (c *coverage) addStatements(function string, stmts []ast.Statement, count *int) {
+	for idx := range stmts {
+		stmt := &stmts[idx]
+		c.slots[stmt] = coverageSlot{function: function, index: *count}
+		*count++
+
+		c.addStatements(function, stmt.Then, count)
+		c.addStatements(function, stmt.Else, count)
+		c.addStatements(function, stmt.Init, count)
+		c.addStatements(function, stmt.Body, count)
+		c.addStatements(function, stmt.Update, count)
+		for caseIdx := range stmt.Cases {
+			c.addStatements(function, stmt.Cases[caseIdx].Body, count)
+		}
+		c.addStatements(function, stmt.Default, count)
+	}
+}
+
+// record marks stmt as executed.
+func (c *coverage) record(stmt *ast.Statement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slot, ok := c.slots[stmt]; ok {
+		c.hits[slot.function][slot.index] = true
+	}
+}
@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// eitherDecimal reports whether either operand is a decimal.
+func eitherDecimal(left, right runtime.Value) bool {
+	return left.Type == runtime.ValueTypeDecimal || right.Type == runtime.ValueTypeDecimal
+}
+
+// decimalArithmetic applies an arithmetic operator to a decimal and another
+// decimal or an int. Floats are rejected rather than rounded. Division must
+// have an exact decimal result; decimal.div rounds to a given scale instead.
+func decimalArithmetic(op string, left, right runtime.Value) (runtime.Value, error) {
+	l, err := left.AsDecimal()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, valueTypeName(left.Type), valueTypeName(right.Type))
+	}
+	r, err := right.AsDecimal()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, valueTypeName(left.Type), valueTypeName(right.Type))
+	}
+
+	result := new(big.Rat)
+	switch op {
+	case ast.OpAdd:
+		result.Add(l, r)
+	case ast.OpSub:
+		result.Sub(l, r)
+	case ast.OpMul:
+		result.Mul(l, r)
+	case ast.OpDiv:
+		if result, err = runtime.QuoDecimal(l, r); err != nil {
+			return runtime.NewVoid(), err
+		}
+	default:
+		return runtime.NewVoid(), fmt.Errorf("operator %s cannot be applied to %s and %s", op, valueTypeName(left.Type), valueTypeName(right.Type))
+	}
+	return runtime.NewDecimal(result), nil
+}
+
+// evaluateDecimalLiteral parses a literal written as a string with
+// "to": "decimal".
+func evaluateDecimalLiteral(value interface{}) (runtime.Value, error) {
+	s, ok := value.(string)
+	if !ok {
+		return runtime.NewVoid(), fmt.Errorf("decimal literal must be a string, got %T", value)
+	}
+	d, err := runtime.ParseDecimal(s)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	return runtime.NewDecimal(d), nil
+}
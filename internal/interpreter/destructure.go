@@ -0,0 +1,54 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// executeDestructure evaluates a destructure statement's value once and binds
+// its targets to the leading array elements, or its bindings to map fields.
+// Nothing is bound unless every element or field is present.
+func (i *Interpreter) executeDestructure(stmt *ast.Statement, env *Environment) (runtime.Value, error) {
+	source, err := i.evaluateExpression(stmt.Value, env)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	var names []string
+	var values []runtime.Value
+	if len(stmt.Targets) > 0 {
+		arr, err := source.AsArray()
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("cannot destructure %s as an array", valueTypeName(source.Type))
+		}
+		if len(arr) < len(stmt.Targets) {
+			return runtime.NewVoid(), fmt.Errorf("array has %d elements, cannot bind %d targets", len(arr), len(stmt.Targets))
+		}
+		for idx, name := range stmt.Targets {
+			if name != "_" {
+				names = append(names, name)
+				values = append(values, arr[idx])
+			}
+		}
+	} else {
+		m, err := source.AsMap()
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("cannot destructure %s as a map", valueTypeName(source.Type))
+		}
+		for _, field := range stmt.BoundFields() {
+			val, ok := m[field]
+			if !ok {
+				return runtime.NewVoid(), fmt.Errorf("cannot destructure missing field %s", field)
+			}
+			names = append(names, stmt.Bindings[field])
+			values = append(values, val)
+		}
+	}
+
+	for idx, name := range names {
+		env.Assign(name, values[idx])
+	}
+	return source, nil
+}
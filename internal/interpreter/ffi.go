@@ -0,0 +1,58 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// maxExternArgs is the most arguments the interpreter passes to a C function.
+const maxExternArgs = 6
+
+// externCall is a call to the C function an extern function is bound to,
+// with the parameter and result types resolved to int, float, bool, string,
+// or void.
+type externCall struct {
+	symbol string
+	params []string
+	result string
+}
+
+// callExtern calls the C function bound to an extern function, looking the
+// symbol up among the libraries loaded into the process. The interpreter
+// supports functions taking only floats and returning a float, and functions
+// taking ints, bools, and strings and returning anything but a float;
+// compiled programs call any signature the validator accepts. Like native
+// plugin functions, extern functions declared by a plugin module need the
+// native capability.
+func (i *Interpreter) callExtern(fn *ast.Function, args []runtime.Value) (runtime.Value, error) {
+	i.mu.RLock()
+	module := i.owners[fn]
+	granted, restricted := i.restricted[module]
+	i.mu.RUnlock()
+	if restricted && !granted[CapabilityNative] {
+		return runtime.NewVoid(), &CapabilityError{Module: module, Builtin: fn.Name, Capability: CapabilityNative}
+	}
+	if len(args) > maxExternArgs {
+		return runtime.NewVoid(), fmt.Errorf("extern function '%s': the interpreter passes at most %d arguments to C", fn.Name, maxExternArgs)
+	}
+
+	call := externCall{symbol: fn.Extern}
+	result, err := ast.ResolveTypeAlias(fn.Returns, i.lookupType)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	call.result = result
+	for _, param := range fn.Params {
+		t, err := ast.ResolveTypeAlias(param.Type, i.lookupType)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		if (t == ast.TypeFloat) != (call.result == ast.TypeFloat) {
+			return runtime.NewVoid(), fmt.Errorf("extern function '%s': the interpreter cannot call C functions that mix float and non-float values; compile the program to call it", fn.Name)
+		}
+		call.params = append(call.params, t)
+	}
+	return ffiCall(call, args)
+}
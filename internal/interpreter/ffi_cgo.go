@@ -0,0 +1,112 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package interpreter
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+static void* alas_ffi_lookup(const char* name) {
+	return dlsym(RTLD_DEFAULT, name);
+}
+
+// Calls fn with n arguments of type arg from a, as a function returning ret
+#define ALAS_FFI_CALL(ret, arg, fn, n, a) \
+	switch (n) { \
+	case 0: return ((ret (*)(void))(fn))(); \
+	case 1: return ((ret (*)(arg))(fn))(a[0]); \
+	case 2: return ((ret (*)(arg, arg))(fn))(a[0], a[1]); \
+	case 3: return ((ret (*)(arg, arg, arg))(fn))(a[0], a[1], a[2]); \
+	case 4: return ((ret (*)(arg, arg, arg, arg))(fn))(a[0], a[1], a[2], a[3]); \
+	case 5: return ((ret (*)(arg, arg, arg, arg, arg))(fn))(a[0], a[1], a[2], a[3], a[4]); \
+	default: return ((ret (*)(arg, arg, arg, arg, arg, arg))(fn))(a[0], a[1], a[2], a[3], a[4], a[5]); \
+	}
+
+static int64_t alas_ffi_call_int(void* fn, int n, int64_t* a) { ALAS_FFI_CALL(int64_t, int64_t, fn, n, a) }
+static bool alas_ffi_call_bool(void* fn, int n, int64_t* a) { ALAS_FFI_CALL(bool, int64_t, fn, n, a) }
+static char* alas_ffi_call_string(void* fn, int n, int64_t* a) { ALAS_FFI_CALL(char*, int64_t, fn, n, a) }
+static double alas_ffi_call_float(void* fn, int n, double* a) { ALAS_FFI_CALL(double, double, fn, n, a) }
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// ffiCall looks up a C function with dlsym and calls it. Ints, bools, and
+// string pointers are passed in integer registers, which the C calling
+// conventions of the supported platforms treat alike.
+func ffiCall(call externCall, args []runtime.Value) (runtime.Value, error) {
+	symbol := C.CString(call.symbol)
+	defer C.free(unsafe.Pointer(symbol))
+	fn := C.alas_ffi_lookup(symbol)
+	if fn == nil {
+		return runtime.NewVoid(), fmt.Errorf("C symbol %s not found in the running process", call.symbol)
+	}
+	n := C.int(len(args))
+
+	if call.result == ast.TypeFloat {
+		var floats [maxExternArgs]C.double
+		for idx, arg := range args {
+			f, err := arg.AsFloat()
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("%s argument %d: %v", call.symbol, idx+1, err)
+			}
+			floats[idx] = C.double(f)
+		}
+		return runtime.NewFloat(float64(C.alas_ffi_call_float(fn, n, &floats[0]))), nil
+	}
+
+	var ints [maxExternArgs]C.int64_t
+	for idx, arg := range args {
+		switch call.params[idx] {
+		case ast.TypeInt:
+			v, err := arg.AsInt()
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("%s argument %d: %v", call.symbol, idx+1, err)
+			}
+			ints[idx] = C.int64_t(v)
+		case ast.TypeBool:
+			b, err := arg.AsBool()
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("%s argument %d: %v", call.symbol, idx+1, err)
+			}
+			if b {
+				ints[idx] = 1
+			}
+		case ast.TypeString:
+			s, err := arg.AsString()
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("%s argument %d: %v", call.symbol, idx+1, err)
+			}
+			cs := C.CString(s)
+			defer C.free(unsafe.Pointer(cs))
+			ints[idx] = C.int64_t(uintptr(unsafe.Pointer(cs)))
+		default:
+			return runtime.NewVoid(), fmt.Errorf("%s argument %d: %s values cannot be passed to C", call.symbol, idx+1, call.params[idx])
+		}
+	}
+
+	switch call.result {
+	case ast.TypeInt:
+		return runtime.NewInt(int64(C.alas_ffi_call_int(fn, n, &ints[0]))), nil
+	case ast.TypeBool:
+		return runtime.NewBool(bool(C.alas_ffi_call_bool(fn, n, &ints[0]))), nil
+	case ast.TypeString:
+		s := C.alas_ffi_call_string(fn, n, &ints[0])
+		if s == nil {
+			return runtime.NewString(""), nil
+		}
+		return runtime.NewString(C.GoString(s)), nil
+	default:
+		C.alas_ffi_call_int(fn, n, &ints[0])
+		return runtime.NewVoid(), nil
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package interpreter
+
+import (
+	"fmt"
+	goruntime "runtime"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// ffiCall reports that the interpreter cannot call C functions on this platform.
+func ffiCall(call externCall, args []runtime.Value) (runtime.Value, error) {
+	return runtime.NewVoid(), fmt.Errorf("cannot call C function %s: the interpreter needs cgo on linux, darwin, or freebsd, not %s/%s", call.symbol, goruntime.GOOS, goruntime.GOARCH)
+}
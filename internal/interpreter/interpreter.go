@@ -1,26 +1,71 @@
 package interpreter
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/modpath"
 	"github.com/dshills/alas/internal/runtime"
 	"github.com/dshills/alas/internal/stdlib"
 )
 
 // Interpreter executes ALaS programs.
 type Interpreter struct {
+	// mu guards the module tables, so functions may run on several
+	// goroutines while modules are loaded and unloaded.
+	mu            sync.RWMutex
 	modules       map[string]*ast.Module
 	functions     map[string]*ast.Function
 	exportedFuncs map[string]map[string]*ast.Function // module -> function name -> function
 	moduleLoader  ModuleLoader
 	stdlib        *stdlib.Registry
-	importMap     map[string]string              // maps import alias to actual module name
-	customTypes   map[string]*ast.TypeDefinition // type name -> type definition
+	importMap     map[string]string                   // maps import alias to actual module name
+	customTypes   map[string]*ast.TypeDefinition      // type name -> type definition
+	methods       map[string]map[string]*ast.Function // type name -> method name -> method
+	moduleOrder   []string                            // module names in load order
+	coverage      *coverage                           // statement coverage, nil unless enabled
+	profile       *profile                            // call counts and times, nil unless enabled
+	traceHook     TraceHook                           // called before each statement, nil unless set
+	owners        map[*ast.Function]string            // function -> name of the module declaring it
+	restricted    map[string]map[string]bool          // plugin module -> granted capabilities
+	checkOverflow bool                                // int overflow is an error rather than a bigint
+	lenient       Lenient                             // runtime errors replaced with fallback values
+}
+
+// TraceHook is called before each statement executes with the name of the
+// running function, the statement, and the current environment. Methods are
+// named "Type.method", module functions "module.function", and lambdas "<lambda>".
+// The hook runs synchronously, so blocking in it pauses execution.
+type TraceHook func(fn string, stmt *ast.Statement, env Environment)
+
+// SetTraceHook installs a hook called before every statement. Passing nil
+// removes it. It must not be called while functions are running.
+func (i *Interpreter) SetTraceHook(hook TraceHook) {
+	i.traceHook = hook
+}
+
+// SetLogOutput sets the writer receiving the messages of the log builtins,
+// stderr by default.
+func (i *Interpreter) SetLogOutput(w io.Writer) {
+	i.stdlib.SetLogOutput(w)
+}
+
+// SetLogLevel sets the least severe level the log builtins write, info by
+// default.
+func (i *Interpreter) SetLogLevel(level stdlib.LogLevel) {
+	i.stdlib.SetLogLevel(level)
 }
 
 // ModuleLoader defines the interface for loading modules.
@@ -51,11 +96,11 @@ func (l *FileModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
 		for _, searchPath := range searchPaths {
 			fileName := filepath.Join(searchPath, moduleName+".alas.json")
 			if data, err := os.ReadFile(fileName); err == nil {
-				var module ast.Module
-				if err := json.Unmarshal(data, &module); err != nil {
+				module, err := ast.ParseModule(data, fileName)
+				if err != nil {
 					return nil, fmt.Errorf("failed to parse module %s: %v", name, err)
 				}
-				return &module, nil
+				return module, nil
 			}
 		}
 	} else {
@@ -63,30 +108,31 @@ func (l *FileModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
 		for _, searchPath := range l.searchPaths {
 			fileName := filepath.Join(searchPath, moduleName+".alas.json")
 			if data, err := os.ReadFile(fileName); err == nil {
-				var module ast.Module
-				if err := json.Unmarshal(data, &module); err != nil {
+				module, err := ast.ParseModule(data, fileName)
+				if err != nil {
 					return nil, fmt.Errorf("failed to parse module %s: %v", name, err)
 				}
-				return &module, nil
+				return module, nil
 			}
 		}
 	}
 	return nil, fmt.Errorf("module %s not found in search paths", name)
 }
 
-// New creates a new interpreter.
+// New creates a new interpreter that loads imported modules from the
+// directories in ALAS_PATH and the default search paths.
 func New() *Interpreter {
-	// Default search paths for modules (try both from current directory and from parent)
-	searchPaths := []string{".", "examples/modules", "../examples/modules", "stdlib"}
-
 	return &Interpreter{
 		modules:       make(map[string]*ast.Module),
 		functions:     make(map[string]*ast.Function),
 		exportedFuncs: make(map[string]map[string]*ast.Function),
-		moduleLoader:  NewFileModuleLoader(searchPaths),
+		moduleLoader:  NewFileModuleLoader(modpath.SearchPaths()),
 		stdlib:        stdlib.NewRegistry(),
 		importMap:     make(map[string]string),
 		customTypes:   make(map[string]*ast.TypeDefinition),
+		methods:       make(map[string]map[string]*ast.Function),
+		owners:        make(map[*ast.Function]string),
+		restricted:    make(map[string]map[string]bool),
 	}
 }
 
@@ -100,6 +146,9 @@ func NewWithLoader(loader ModuleLoader) *Interpreter {
 		stdlib:        stdlib.NewRegistry(),
 		importMap:     make(map[string]string),
 		customTypes:   make(map[string]*ast.TypeDefinition),
+		methods:       make(map[string]map[string]*ast.Function),
+		owners:        make(map[*ast.Function]string),
+		restricted:    make(map[string]map[string]bool),
 	}
 }
 
@@ -110,17 +159,29 @@ func (i *Interpreter) LoadModule(module *ast.Module) error {
 
 // LoadModuleWithDependencies loads a module and all its dependencies.
 func (i *Interpreter) LoadModuleWithDependencies(module *ast.Module) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.loadModule(module)
+}
+
+// loadModule loads a module and its imports. The caller holds i.mu.
+func (i *Interpreter) loadModule(module *ast.Module) error {
 	// Check if module is already loaded
 	if _, exists := i.modules[module.Name]; exists {
 		return nil // Already loaded
 	}
 
 	// Load all imported modules first
-	for _, importName := range module.Imports {
+	for _, imp := range module.Imports {
+		importName := imp.Module
+
 		// Check if already loaded (by import name or actual name)
 		if actualName, exists := i.importMap[importName]; exists {
 			// Already have this import mapped
 			if _, loaded := i.modules[actualName]; loaded {
+				if imp.Alias != "" {
+					i.importMap[imp.Alias] = actualName
+				}
 				continue
 			}
 		}
@@ -140,13 +201,22 @@ func (i *Interpreter) LoadModuleWithDependencies(module *ast.Module) error {
 			i.importMap[shortName] = importedModule.Name
 		}
 
-		if err := i.LoadModuleWithDependencies(importedModule); err != nil {
+		// Module calls name an aliased import by its alias
+		if imp.Alias != "" {
+			i.importMap[imp.Alias] = importedModule.Name
+		}
+
+		if err := i.loadModule(importedModule); err != nil {
 			return err
 		}
 	}
 
 	// Now load the current module
 	i.modules[module.Name] = module
+	i.moduleOrder = append(i.moduleOrder, module.Name)
+	if i.coverage != nil {
+		i.coverage.addModule(module)
+	}
 
 	// Register custom types
 	for idx := range module.Types {
@@ -154,9 +224,18 @@ func (i *Interpreter) LoadModuleWithDependencies(module *ast.Module) error {
 		i.customTypes[typeDef.Name] = typeDef
 	}
 
-	// Register all functions in global namespace (for local calls)
+	// Register all functions in global namespace (for local calls) and
+	// methods under their receiver type
 	for idx := range module.Functions {
 		fn := &module.Functions[idx]
+		i.owners[fn] = module.Name
+		if fn.Receiver != nil {
+			if i.methods[fn.Receiver.Type] == nil {
+				i.methods[fn.Receiver.Type] = make(map[string]*ast.Function)
+			}
+			i.methods[fn.Receiver.Type][fn.Name] = fn
+			continue
+		}
 		i.functions[fn.Name] = fn
 	}
 
@@ -166,7 +245,7 @@ func (i *Interpreter) LoadModuleWithDependencies(module *ast.Module) error {
 		// Find the function with this name
 		for idx := range module.Functions {
 			fn := &module.Functions[idx]
-			if fn.Name == exportName {
+			if fn.Name == exportName && fn.Receiver == nil {
 				i.exportedFuncs[module.Name][exportName] = fn
 				break
 			}
@@ -176,21 +255,138 @@ func (i *Interpreter) LoadModuleWithDependencies(module *ast.Module) error {
 	return nil
 }
 
+// UnloadModule removes a loaded module and exactly the functions, methods,
+// types, exports, and import aliases it registered. Names the module had
+// shadowed are restored from the remaining modules. Unloading a module that
+// another loaded module imports is refused.
+func (i *Interpreter) UnloadModule(name string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	module, exists := i.modules[name]
+	if !exists {
+		return fmt.Errorf("module '%s' is not loaded", name)
+	}
+
+	var dependents []string
+	for _, other := range i.moduleOrder {
+		if other == name {
+			continue
+		}
+		for _, imp := range i.modules[other].Imports {
+			if imp.Module == name || i.importMap[imp.Module] == name {
+				dependents = append(dependents, other)
+				break
+			}
+		}
+	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("cannot unload module '%s': imported by %s", name, strings.Join(dependents, ", "))
+	}
+
+	removedFuncs := make(map[string]bool)
+	removedMethods := make(map[string]bool) // "Type.method"
+	for idx := range module.Functions {
+		fn := &module.Functions[idx]
+		delete(i.owners, fn)
+		if fn.Receiver != nil {
+			if i.methods[fn.Receiver.Type][fn.Name] == fn {
+				delete(i.methods[fn.Receiver.Type], fn.Name)
+				removedMethods[fn.Receiver.Type+"."+fn.Name] = true
+			}
+			continue
+		}
+		if i.functions[fn.Name] == fn {
+			delete(i.functions, fn.Name)
+			removedFuncs[fn.Name] = true
+		}
+	}
+	removedTypes := make(map[string]bool)
+	for idx := range module.Types {
+		typeDef := &module.Types[idx]
+		if i.customTypes[typeDef.Name] == typeDef {
+			delete(i.customTypes, typeDef.Name)
+			removedTypes[typeDef.Name] = true
+		}
+	}
+
+	delete(i.modules, name)
+	delete(i.exportedFuncs, name)
+	for idx, loaded := range i.moduleOrder {
+		if loaded == name {
+			i.moduleOrder = append(i.moduleOrder[:idx], i.moduleOrder[idx+1:]...)
+			break
+		}
+	}
+	for alias, actual := range i.importMap {
+		if actual == name {
+			delete(i.importMap, alias)
+		}
+	}
+
+	// Later modules win, as when they were loaded
+	for _, other := range i.moduleOrder {
+		remaining := i.modules[other]
+		for idx := range remaining.Functions {
+			fn := &remaining.Functions[idx]
+			if fn.Receiver != nil {
+				if removedMethods[fn.Receiver.Type+"."+fn.Name] {
+					i.methods[fn.Receiver.Type][fn.Name] = fn
+				}
+			} else if removedFuncs[fn.Name] {
+				i.functions[fn.Name] = fn
+			}
+		}
+		for idx := range remaining.Types {
+			if typeDef := &remaining.Types[idx]; removedTypes[typeDef.Name] {
+				i.customTypes[typeDef.Name] = typeDef
+			}
+		}
+	}
+
+	return nil
+}
+
 // CallBuiltinFunction calls a builtin standard library function directly.
 // This is mainly used for testing purposes.
 func (i *Interpreter) CallBuiltinFunction(name string, args []runtime.Value) (runtime.Value, error) {
 	return i.stdlib.Call(name, args)
 }
 
+// RegisterBuiltin makes fn callable from ALaS code as the builtin name,
+// replacing any builtin already registered under that name.
+func (i *Interpreter) RegisterBuiltin(name string, fn stdlib.BuiltinFunction) {
+	i.stdlib.Register(name, fn)
+}
+
+// UnregisterBuiltin removes a builtin function.
+func (i *Interpreter) UnregisterBuiltin(name string) {
+	i.stdlib.Unregister(name)
+}
+
 // Environment represents the execution environment.
 type Environment struct {
-	vars   map[string]runtime.Value
-	parent *Environment
+	mu       *sync.RWMutex // guards vars, which lambdas running as async tasks share
+	vars     map[string]runtime.Value
+	parent   *Environment
+	function string          // function whose body runs in this environment, if any
+	module   string          // module declaring that function
+	deferred []deferredEntry // deferred expressions of that function, in defer order
+	block    bool            // a scope within a function body, such as a match case
+	ctx      context.Context // context of the run or async task calling that function, if any
+}
+
+// deferredEntry is an expression deferred until its function returns, with
+// the environment the defer statement ran in.
+type deferredEntry struct {
+	expr *ast.Expression
+	env  *Environment
 }
 
 // NewEnvironment creates a new environment.
 func NewEnvironment(parent *Environment) *Environment {
 	return &Environment{
+		mu:     &sync.RWMutex{},
 		vars:   make(map[string]runtime.Value),
 		parent: parent,
 	}
@@ -198,7 +394,10 @@ func NewEnvironment(parent *Environment) *Environment {
 
 // Get retrieves a variable value.
 func (e *Environment) Get(name string) (runtime.Value, bool) {
-	if val, ok := e.vars[name]; ok {
+	e.mu.RLock()
+	val, ok := e.vars[name]
+	e.mu.RUnlock()
+	if ok {
 		return val, true
 	}
 	if e.parent != nil {
@@ -209,6 +408,8 @@ func (e *Environment) Get(name string) (runtime.Value, bool) {
 
 // Set sets a variable value.
 func (e *Environment) Set(name string, value runtime.Value) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	// Release old GC object if it exists
 	if oldVal, exists := e.vars[name]; exists {
 		oldVal.Release()
@@ -216,16 +417,107 @@ func (e *Environment) Set(name string, value runtime.Value) {
 	e.vars[name] = value
 }
 
+// Assign sets a variable for an assignment. In a block scope, a variable of
+// an enclosing scope of the same function is updated in place; any other
+// variable is created in the innermost scope.
+func (e *Environment) Assign(name string, value runtime.Value) {
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		_, ok := env.vars[name]
+		env.mu.RUnlock()
+		if ok {
+			env.Set(name, value)
+			return
+		}
+		if !env.block {
+			break
+		}
+	}
+	e.Set(name, value)
+}
+
+// Bindings returns the variables visible in this environment, including
+// those of enclosing environments. Inner bindings shadow outer ones.
+func (e *Environment) Bindings() map[string]runtime.Value {
+	bindings := make(map[string]runtime.Value)
+	if e.parent != nil {
+		bindings = e.parent.Bindings()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for name, val := range e.vars {
+		bindings[name] = val
+	}
+	return bindings
+}
+
+// functionName returns the name of the function running in this environment.
+func (e *Environment) functionName() string {
+	for env := e; env != nil; env = env.parent {
+		if env.function != "" {
+			return env.function
+		}
+	}
+	return ""
+}
+
+// context returns the context of the run or async task calling the function
+// running in this environment. Blocking builtins stop waiting when it is done.
+func (e *Environment) context() context.Context {
+	for env := e; env != nil; env = env.parent {
+		if env.function != "" {
+			if env.ctx != nil {
+				return env.ctx
+			}
+			break
+		}
+	}
+	return context.Background()
+}
+
+// moduleName returns the module whose code runs in this environment.
+func (e *Environment) moduleName() string {
+	for env := e; env != nil; env = env.parent {
+		if env.function != "" {
+			return env.module
+		}
+	}
+	return ""
+}
+
+// frame returns the environment of the function running in this
+// environment, which holds its deferred expressions.
+func (e *Environment) frame() *Environment {
+	for env := e; env != nil; env = env.parent {
+		if env.function != "" {
+			return env
+		}
+	}
+	return e
+}
+
 // Cleanup releases all garbage-collected objects in this environment.
 func (e *Environment) Cleanup() {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	for _, val := range e.vars {
 		val.Release()
 	}
 }
 
 // Run executes a function by name.
+// It is safe to call Run from several goroutines at once.
 func (i *Interpreter) Run(functionName string, args []runtime.Value) (runtime.Value, error) {
+	return i.RunContext(context.Background(), functionName, args)
+}
+
+// RunContext is like Run, but builtins that block, such as channel.send and
+// channel.recv, stop waiting and fail once ctx is done.
+func (i *Interpreter) RunContext(ctx context.Context, functionName string, args []runtime.Value) (runtime.Value, error) {
+	i.mu.RLock()
 	fn, ok := i.functions[functionName]
+	module := i.owners[fn]
+	i.mu.RUnlock()
 	if !ok {
 		return runtime.NewVoid(), fmt.Errorf("function '%s' not found", functionName)
 	}
@@ -235,9 +527,15 @@ func (i *Interpreter) Run(functionName string, args []runtime.Value) (runtime.Va
 		return runtime.NewVoid(), fmt.Errorf("function '%s' expects %d arguments, got %d",
 			functionName, len(fn.Params), len(args))
 	}
+	if fn.Extern != "" {
+		return i.callExtern(fn, args)
+	}
 
 	// Create new environment for function execution
 	env := NewEnvironment(nil)
+	env.function = functionName
+	env.module = module
+	env.ctx = ctx
 
 	// Bind parameters
 	for idx, param := range fn.Params {
@@ -245,27 +543,330 @@ func (i *Interpreter) Run(functionName string, args []runtime.Value) (runtime.Va
 	}
 
 	// Execute function body
-	result, _, err := i.executeStatements(fn.Body, env)
+	result, err := i.runFunctionBody(fn.Params, fn.Body, env)
 
 	// Cleanup environment before returning
 	defer env.Cleanup()
 
 	if err != nil {
-		return runtime.NewVoid(), fmt.Errorf("error executing function '%s': %v", functionName, err)
+		var exit *ExitError
+		if errors.As(err, &exit) {
+			return runtime.NewVoid(), exit
+		}
+		// The error's stack trace already names the function
+		return runtime.NewVoid(), err
 	}
 
 	return result, nil
 }
 
-// RunModuleFunction executes a function from a specific module.
-func (i *Interpreter) RunModuleFunction(moduleName, functionName string, args []runtime.Value) (runtime.Value, error) {
-	// For std.* modules, try builtin functions first
-	if strings.HasPrefix(moduleName, "std.") {
-		builtinName := strings.TrimPrefix(moduleName, "std.") + "." + functionName
-		if i.stdlib.HasFunction(builtinName) {
-			return i.stdlib.Call(builtinName, args)
+// ExitError is returned by Run when the program called os.exit. Its Code is
+// the exit status the program asked for.
+type ExitError = stdlib.ExitError
+
+// runFunctionBody executes the body of the function running in env, then
+// evaluates the expressions it deferred in reverse order. Deferred
+// expressions run on early returns and errors too; the body's error takes
+// precedence over errors from deferred expressions. Like Go's os.Exit,
+// os.exit skips deferred expressions. Errors carry a stack frame for the
+// function, which params describe.
+func (i *Interpreter) runFunctionBody(params []ast.Parameter, body []ast.Statement, env *Environment) (runtime.Value, error) {
+	if i.profile != nil {
+		defer i.profile.record(env.function, time.Now())
+	}
+	result, _, err := i.executeStatements(body, env)
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return runtime.NewVoid(), err
+	}
+	for len(env.deferred) > 0 {
+		last := len(env.deferred) - 1
+		entry := env.deferred[last]
+		env.deferred = env.deferred[:last]
+		if _, deferErr := i.evaluateExpression(entry.expr, entry.env); deferErr != nil && err == nil {
+			err = fmt.Errorf("deferred expression: %w", deferErr)
 		}
 	}
+	if err != nil {
+		return runtime.NewVoid(), addFrame(err, env, params)
+	}
+	return result, nil
+}
+
+// makeClosure creates a function value for a lambda. The closure captures the
+// defining environment by reference, so it sees later updates to captured variables.
+func (i *Interpreter) makeClosure(lambda *ast.Expression, captured *Environment) runtime.Value {
+	return runtime.NewContextFunction("", len(lambda.Params), func(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+		if len(args) != len(lambda.Params) {
+			return runtime.NewVoid(), fmt.Errorf("lambda expects %d arguments, got %d", len(lambda.Params), len(args))
+		}
+
+		env := NewEnvironment(captured)
+		env.function = "<lambda>"
+		env.module = captured.moduleName()
+		env.ctx = ctx
+		for idx, param := range lambda.Params {
+			env.Set(param.Name, args[idx])
+		}
+
+		result, err := i.runFunctionBody(lambda.Params, lambda.Body, env)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return result, nil
+	})
+}
+
+// evaluateFuncRef returns a function value that calls a named function, or an
+// exported function of an imported module.
+func (i *Interpreter) evaluateFuncRef(expr *ast.Expression) (runtime.Value, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if expr.Module == "" {
+		fn, ok := i.functions[expr.Name]
+		if !ok {
+			return runtime.NewVoid(), fmt.Errorf("function '%s' not found", expr.Name)
+		}
+		return runtime.NewContextFunction(expr.Name, len(fn.Params), func(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+			return i.RunContext(ctx, expr.Name, args)
+		}), nil
+	}
+
+	actualModuleName := expr.Module
+	if mapped, exists := i.importMap[expr.Module]; exists {
+		actualModuleName = mapped
+	}
+	fn, ok := i.exportedFuncs[actualModuleName][expr.Name]
+	if !ok {
+		return runtime.NewVoid(), fmt.Errorf("function '%s' not exported from module '%s'", expr.Name, expr.Module)
+	}
+	return runtime.NewContextFunction(expr.Module+"."+expr.Name, len(fn.Params), func(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+		return i.runModuleFunction(ctx, expr.Module, expr.Name, args)
+	}), nil
+}
+
+// evaluateVariant constructs an enum value, evaluating and checking its payload fields.
+func (i *Interpreter) evaluateVariant(expr *ast.Expression, env *Environment) (runtime.Value, error) {
+	enumName, err := ast.ResolveTypeAlias(expr.Enum, i.lookupType)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	typeDef := i.lookupType(enumName)
+	if typeDef == nil || typeDef.Definition.Kind != ast.TypeKindEnum {
+		return runtime.NewVoid(), fmt.Errorf("unknown enum type: %s", expr.Enum)
+	}
+	variant, ok := findVariant(typeDef, expr.Variant)
+	if !ok {
+		return runtime.NewVoid(), fmt.Errorf("enum %s has no variant %s", expr.Enum, expr.Variant)
+	}
+
+	fields := make(map[string]runtime.Value, len(expr.Pairs))
+	for _, pair := range expr.Pairs {
+		name, ok := pair.Key.Value.(string)
+		if !ok {
+			return runtime.NewVoid(), fmt.Errorf("variant field name must be a string literal")
+		}
+		if !variantHasField(variant, name) {
+			return runtime.NewVoid(), fmt.Errorf("variant %s.%s has no field %s", expr.Enum, expr.Variant, name)
+		}
+		val, err := i.evaluateExpression(&pair.Value, env)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		fields[name] = val
+	}
+
+	for _, field := range variant.Fields {
+		if _, ok := fields[field.Name]; !ok {
+			return runtime.NewVoid(), fmt.Errorf("variant %s.%s missing field %s", expr.Enum, expr.Variant, field.Name)
+		}
+	}
+
+	return runtime.NewEnum(enumName, expr.Variant, fields), nil
+}
+
+// executeMatch runs the case whose variant matches the discriminant, binding
+// the requested payload fields. Plain enums represented as strings are matched
+// by value.
+func (i *Interpreter) executeMatch(stmt *ast.Statement, env *Environment) (runtime.Value, bool, error) {
+	subject, err := i.evaluateExpression(stmt.Value, env)
+	if err != nil {
+		return runtime.NewVoid(), false, err
+	}
+
+	var variantName string
+	var fields map[string]runtime.Value
+	switch subject.Type {
+	case runtime.ValueTypeEnum:
+		ev, _ := subject.AsEnum()
+		variantName, fields = ev.Variant, ev.Fields
+	case runtime.ValueTypeString:
+		variantName, _ = subject.AsString()
+	default:
+		return runtime.NewVoid(), false, fmt.Errorf("cannot match on %s value", valueTypeName(subject.Type))
+	}
+
+	for _, matchCase := range stmt.Cases {
+		if matchCase.Variant != variantName {
+			continue
+		}
+		// Payload bindings are visible only in the case body, and shadow
+		// variables of the same name outside it
+		caseEnv := NewEnvironment(env)
+		caseEnv.block = true
+		for field, varName := range matchCase.Bindings {
+			val, ok := fields[field]
+			if !ok {
+				return runtime.NewVoid(), false, fmt.Errorf("variant %s has no field %s", variantName, field)
+			}
+			caseEnv.Set(varName, val)
+		}
+		return i.executeStatements(matchCase.Body, caseEnv)
+	}
+
+	if stmt.Default != nil {
+		return i.executeStatements(stmt.Default, env)
+	}
+	return runtime.NewVoid(), false, fmt.Errorf("no match case for variant %s", variantName)
+}
+
+// findVariant looks up a variant of an enum type by name.
+func findVariant(typeDef *ast.TypeDefinition, name string) (ast.EnumVariant, bool) {
+	for _, variant := range typeDef.Definition.EnumVariants() {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return ast.EnumVariant{}, false
+}
+
+// variantHasField reports whether a variant declares a payload field.
+func variantHasField(variant ast.EnumVariant, name string) bool {
+	for _, field := range variant.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// callMethod executes the method declared for the receiver's struct type,
+// binding the receiver to the method's receiver parameter.
+func (i *Interpreter) callMethod(ctx context.Context, receiver runtime.Value, methodName string, args []runtime.Value) (runtime.Value, error) {
+	typeName, err := i.receiverTypeName(receiver, methodName)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	i.mu.RLock()
+	fn := i.methods[typeName][methodName]
+	module := i.owners[fn]
+	i.mu.RUnlock()
+
+	// Check argument count
+	if len(args) != len(fn.Params) {
+		return runtime.NewVoid(), fmt.Errorf("method '%s.%s' expects %d arguments, got %d",
+			typeName, methodName, len(fn.Params), len(args))
+	}
+
+	// Create new environment for method execution
+	env := NewEnvironment(nil)
+	env.function = typeName + "." + methodName
+	env.module = module
+	env.ctx = ctx
+	defer env.Cleanup()
+
+	// Bind receiver and parameters
+	env.Set(fn.Receiver.Name, receiver)
+	for idx, param := range fn.Params {
+		env.Set(param.Name, args[idx])
+	}
+
+	result, err := i.runFunctionBody(fn.Params, fn.Body, env)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	return result, nil
+}
+
+// receiverTypeName determines which struct type's method to dispatch to.
+// Struct values are maps at runtime, so the receiver's fields are matched
+// against the definition of each struct type declaring the method.
+func (i *Interpreter) receiverTypeName(receiver runtime.Value, methodName string) (string, error) {
+	fields, err := receiver.AsMap()
+	if err != nil {
+		return "", fmt.Errorf("cannot call method '%s' on %s", methodName, valueTypeName(receiver.Type))
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var candidates []string
+	for typeName, methods := range i.methods {
+		if _, ok := methods[methodName]; ok {
+			candidates = append(candidates, typeName)
+		}
+	}
+	sort.Strings(candidates)
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("method '%s' not found", methodName)
+	}
+
+	var matches []string
+	for _, typeName := range candidates {
+		if typeDef, ok := i.customTypes[typeName]; ok && structMatchesFields(typeDef, fields) {
+			matches = append(matches, typeName)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("cannot call method '%s': receiver is not a %s",
+			methodName, strings.Join(candidates, " or "))
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("ambiguous method call '%s': receiver could be any of %s",
+		methodName, strings.Join(matches, ", "))
+}
+
+// lookupType returns the custom type definition of a name, or nil.
+func (i *Interpreter) lookupType(name string) *ast.TypeDefinition {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.customTypes[name]
+}
+
+// isStruct reports whether a map value is a struct, having exactly the fields
+// of one of the module's struct types.
+func (i *Interpreter) isStruct(fields map[string]runtime.Value) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, typeDef := range i.customTypes {
+		if structMatchesFields(typeDef, fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// structMatchesFields reports whether a map value has exactly the fields of a struct type.
+func structMatchesFields(typeDef *ast.TypeDefinition, fields map[string]runtime.Value) bool {
+	if typeDef.Definition.Kind != ast.TypeKindStruct || len(typeDef.Definition.Fields) != len(fields) {
+		return false
+	}
+	for _, field := range typeDef.Definition.Fields {
+		if _, ok := fields[field.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupModuleFunction resolves a module name through the import map and
+// returns the module's actual name and its exported function.
+func (i *Interpreter) lookupModuleFunction(moduleName, functionName string) (string, *ast.Function, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 
 	// Resolve module name through import map
 	actualModuleName := moduleName
@@ -275,18 +876,47 @@ func (i *Interpreter) RunModuleFunction(moduleName, functionName string, args []
 
 	// Check if module exists
 	if _, exists := i.modules[actualModuleName]; !exists {
-		return runtime.NewVoid(), fmt.Errorf("module '%s' not found", moduleName)
+		return "", nil, fmt.Errorf("module '%s' not found", moduleName)
 	}
 
 	// Check if function is exported from the module
 	moduleExports, exists := i.exportedFuncs[actualModuleName]
 	if !exists {
-		return runtime.NewVoid(), fmt.Errorf("module '%s' has no exports", moduleName)
+		return "", nil, fmt.Errorf("module '%s' has no exports", moduleName)
 	}
 
 	fn, exists := moduleExports[functionName]
 	if !exists {
-		return runtime.NewVoid(), fmt.Errorf("function '%s' not exported from module '%s'", functionName, actualModuleName)
+		return "", nil, fmt.Errorf("function '%s' not exported from module '%s'", functionName, actualModuleName)
+	}
+	return actualModuleName, fn, nil
+}
+
+// functionModule returns the name of the module declaring fn.
+func (i *Interpreter) functionModule(fn *ast.Function) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.owners[fn]
+}
+
+// RunModuleFunction executes a function from a specific module.
+func (i *Interpreter) RunModuleFunction(moduleName, functionName string, args []runtime.Value) (runtime.Value, error) {
+	return i.runModuleFunction(context.Background(), moduleName, functionName, args)
+}
+
+// runModuleFunction executes a function from a specific module under ctx.
+func (i *Interpreter) runModuleFunction(ctx context.Context, moduleName, functionName string, args []runtime.Value) (runtime.Value, error) {
+	// For std.* modules, try builtin functions first
+	if strings.HasPrefix(moduleName, "std.") {
+		builtinName := strings.TrimPrefix(moduleName, "std.") + "." + functionName
+		if i.stdlib.HasFunction(builtinName) {
+			return i.stdlib.CallContext(ctx, builtinName, args)
+		}
+	}
+
+	actualModuleName, fn, err := i.lookupModuleFunction(moduleName, functionName)
+	if err != nil {
+		return runtime.NewVoid(), err
 	}
 
 	// Check argument count
@@ -294,9 +924,15 @@ func (i *Interpreter) RunModuleFunction(moduleName, functionName string, args []
 		return runtime.NewVoid(), fmt.Errorf("function '%s.%s' expects %d arguments, got %d",
 			actualModuleName, functionName, len(fn.Params), len(args))
 	}
+	if fn.Extern != "" {
+		return i.callExtern(fn, args)
+	}
 
 	// Create new environment for function execution
 	env := NewEnvironment(nil)
+	env.function = moduleName + "." + functionName
+	env.module = i.functionModule(fn)
+	env.ctx = ctx
 
 	// Bind parameters
 	for idx, param := range fn.Params {
@@ -304,24 +940,64 @@ func (i *Interpreter) RunModuleFunction(moduleName, functionName string, args []
 	}
 
 	// Execute function body
-	result, _, err := i.executeStatements(fn.Body, env)
+	result, err := i.runFunctionBody(fn.Params, fn.Body, env)
 
 	// Cleanup environment before returning
 	defer env.Cleanup()
 
 	if err != nil {
-		return runtime.NewVoid(), fmt.Errorf("error executing function '%s.%s': %v", moduleName, functionName, err)
+		return runtime.NewVoid(), err
 	}
 
 	return result, nil
 }
 
+// RuntimeError is an error raised while executing a node with a known source location.
+type RuntimeError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+// Error formats the error as "file:line:column: message".
+func (e *RuntimeError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "<unknown>"
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", file, e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// withLocation attaches a source location to err. Errors that already carry a
+// location keep it, so the innermost node that failed is reported. The
+// location is also offered to the error's stack trace, if it has one.
+func withLocation(err error, file string, line, column int) error {
+	if err == nil || line <= 0 {
+		return err
+	}
+	var stack *StackError
+	if errors.As(err, &stack) {
+		stack.locate(file, line, column)
+	}
+	var located *RuntimeError
+	if errors.As(err, &located) {
+		return err
+	}
+	return &RuntimeError{File: file, Line: line, Column: column, Err: err}
+}
+
 // executeStatements executes a list of statements.
 func (i *Interpreter) executeStatements(stmts []ast.Statement, env *Environment) (runtime.Value, bool, error) {
 	var lastValue = runtime.NewVoid()
 
-	for _, stmt := range stmts {
-		val, isReturn, err := i.executeStatement(&stmt, env)
+	for idx := range stmts {
+		val, isReturn, err := i.executeStatement(&stmts[idx], env)
 		if err != nil {
 			return runtime.NewVoid(), false, err
 		}
@@ -335,14 +1011,28 @@ func (i *Interpreter) executeStatements(stmts []ast.Statement, env *Environment)
 }
 
 // executeStatement executes a single statement.
-func (i *Interpreter) executeStatement(stmt *ast.Statement, env *Environment) (runtime.Value, bool, error) {
+func (i *Interpreter) executeStatement(stmt *ast.Statement, env *Environment) (val runtime.Value, isReturn bool, err error) {
+	defer func() { err = withLocation(err, stmt.File, stmt.Line, stmt.Column) }()
+	if i.coverage != nil {
+		i.coverage.record(stmt)
+	}
+	if i.traceHook != nil {
+		i.traceHook(env.functionName(), stmt, *env)
+	}
+
 	switch stmt.Type {
 	case ast.StmtAssign:
 		val, err := i.evaluateExpression(stmt.Value, env)
 		if err != nil {
 			return runtime.NewVoid(), false, err
 		}
-		env.Set(stmt.Target, val)
+		if stmt.Lvalue != nil {
+			if err := i.assignElement(stmt.Lvalue, val, env); err != nil {
+				return runtime.NewVoid(), false, err
+			}
+			return val, false, nil
+		}
+		env.Assign(stmt.Target, val)
 		return val, false, nil
 
 	case ast.StmtIf:
@@ -380,7 +1070,11 @@ func (i *Interpreter) executeStatement(stmt *ast.Statement, env *Environment) (r
 		return runtime.NewVoid(), false, nil
 
 	case ast.StmtFor:
-		// For loops in ALaS are essentially while loops with a condition
+		// A for loop runs init once, then behaves like a while loop whose
+		// body is followed by update
+		if _, _, err := i.executeStatements(stmt.Init, env); err != nil {
+			return runtime.NewVoid(), false, err
+		}
 		for {
 			cond, err := i.evaluateExpression(stmt.Cond, env)
 			if err != nil {
@@ -398,6 +1092,9 @@ func (i *Interpreter) executeStatement(stmt *ast.Statement, env *Environment) (r
 			if isReturn {
 				return runtime.NewVoid(), true, nil
 			}
+			if _, _, err := i.executeStatements(stmt.Update, env); err != nil {
+				return runtime.NewVoid(), false, err
+			}
 		}
 		return runtime.NewVoid(), false, nil
 
@@ -418,16 +1115,53 @@ func (i *Interpreter) executeStatement(stmt *ast.Statement, env *Environment) (r
 		}
 		return val, false, nil
 
+	case ast.StmtMatch:
+		return i.executeMatch(stmt, env)
+
+	case ast.StmtDestructure:
+		val, err := i.executeDestructure(stmt, env)
+		return val, false, err
+
+	case ast.StmtDefer:
+		frame := env.frame()
+		frame.deferred = append(frame.deferred, deferredEntry{expr: stmt.Value, env: env})
+		return runtime.NewVoid(), false, nil
+
+	case ast.StmtAssert:
+		cond, err := i.evaluateExpression(stmt.Cond, env)
+		if err != nil {
+			return runtime.NewVoid(), false, err
+		}
+		ok, err := cond.AsBool()
+		if err != nil {
+			return runtime.NewVoid(), false, fmt.Errorf("assert condition must be a boolean, got %s", valueTypeName(cond.Type))
+		}
+		if !ok {
+			if stmt.Message != "" {
+				return runtime.NewVoid(), false, fmt.Errorf("assertion failed: %s", stmt.Message)
+			}
+			return runtime.NewVoid(), false, fmt.Errorf("assertion failed")
+		}
+		return runtime.NewVoid(), false, nil
+
 	default:
 		return runtime.NewVoid(), false, fmt.Errorf("unknown statement type: %s", stmt.Type)
 	}
 }
 
 // evaluateExpression evaluates an expression.
-func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment) (runtime.Value, error) {
+func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment) (val runtime.Value, err error) {
+	defer func() { err = withLocation(err, expr.File, expr.Line, expr.Column) }()
+
 	switch expr.Type {
 	case ast.ExprLiteral:
-		return i.evaluateLiteral(expr.Value)
+		if f, ok := expr.LiteralValue().(float64); ok && expr.To == ast.TypeFloat {
+			return runtime.NewFloat(f), nil
+		}
+		if expr.To == ast.TypeDecimal {
+			return evaluateDecimalLiteral(expr.Value)
+		}
+		return i.evaluateLiteral(expr.LiteralValue())
 
 	case ast.ExprVariable:
 		val, ok := env.Get(expr.Name)
@@ -474,7 +1208,12 @@ func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment)
 			}
 			args[idx] = val
 		}
-		return i.Run(expr.Name, args)
+		// A variable holding a function value shadows named functions
+		if callee, ok := env.Get(expr.Name); ok && callee.Type == runtime.ValueTypeFunction {
+			fn, _ := callee.AsFunction()
+			return fn.CallWith(env.context(), args)
+		}
+		return i.RunContext(env.context(), expr.Name, args)
 
 	case ast.ExprModuleCall:
 		// Evaluate arguments for module function call
@@ -486,7 +1225,39 @@ func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment)
 			}
 			args[idx] = val
 		}
-		return i.RunModuleFunction(expr.Module, expr.Name, args)
+		if strings.HasPrefix(expr.Module, "std.") {
+			if err := i.checkBuiltinAccess(expr.Module+"."+expr.Name, env); err != nil {
+				return runtime.NewVoid(), err
+			}
+		}
+		return i.runModuleFunction(env.context(), expr.Module, expr.Name, args)
+
+	case ast.ExprVariant:
+		return i.evaluateVariant(expr, env)
+
+	case ast.ExprLambda:
+		return i.makeClosure(expr, env), nil
+
+	case ast.ExprFuncRef:
+		return i.evaluateFuncRef(expr)
+
+	case ast.ExprMethodCall:
+		if expr.Object == nil {
+			return runtime.NewVoid(), fmt.Errorf("method call missing receiver")
+		}
+		receiver, err := i.evaluateExpression(expr.Object, env)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		args := make([]runtime.Value, len(expr.Args))
+		for idx, arg := range expr.Args {
+			val, err := i.evaluateExpression(&arg, env)
+			if err != nil {
+				return runtime.NewVoid(), err
+			}
+			args[idx] = val
+		}
+		return i.callMethod(env.context(), receiver, expr.Name, args)
 
 	case ast.ExprArrayLit:
 		// Evaluate array literal
@@ -513,9 +1284,10 @@ func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment)
 				return runtime.NewVoid(), err
 			}
 
-			// Convert key to string representation
-			// This allows using any type as a key, which will be stringified
-			keyStr := key.String()
+			keyStr, err := mapKey(key)
+			if err != nil {
+				return runtime.NewVoid(), err
+			}
 			mapValue[keyStr] = value
 		}
 		return runtime.NewGCMap(mapValue), nil
@@ -543,7 +1315,13 @@ func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment)
 			args[idx] = val
 		}
 
-		return i.stdlib.Call(expr.Name, args)
+		if err := i.checkBuiltinAccess(expr.Name, env); err != nil {
+			return runtime.NewVoid(), err
+		}
+		if strings.HasPrefix(expr.Name, "log.") {
+			return i.stdlib.Log(expr.Name, env.functionName(), args)
+		}
+		return i.stdlib.CallContext(env.context(), expr.Name, args)
 
 	case ast.ExprField:
 		// Evaluate field access (object.field)
@@ -553,8 +1331,19 @@ func (i *Interpreter) evaluateExpression(expr *ast.Expression, env *Environment)
 		}
 		return i.evaluateFieldAccess(object, expr.Field)
 
+	case ast.ExprCast:
+		// Evaluate explicit type conversion (cast)
+		if expr.Operand == nil {
+			return runtime.NewVoid(), fmt.Errorf("cast expression missing operand")
+		}
+		operand, err := i.evaluateExpression(expr.Operand, env)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return i.evaluateCast(expr.To, operand)
+
 	default:
-		return runtime.NewVoid(), fmt.Errorf("unknown expression type: %s (available types: literal, variable, binary, unary, call, array_literal, map_literal, index, module_call, builtin, field)", expr.Type)
+		return runtime.NewVoid(), fmt.Errorf("unknown expression type: %s (available types: literal, variable, binary, unary, call, array_literal, map_literal, index, module_call, builtin, field, cast, method_call, variant, lambda, func_ref)", expr.Type)
 	}
 }
 
@@ -567,6 +1356,8 @@ func (i *Interpreter) evaluateLiteral(value interface{}) (runtime.Value, error)
 			return runtime.NewInt(int64(v)), nil
 		}
 		return runtime.NewFloat(v), nil
+	case *big.Int:
+		return runtime.NewBigInt(v), nil
 	case int:
 		// Handle Go int values (from programmatic AST creation)
 		return runtime.NewInt(int64(v)), nil
@@ -592,57 +1383,93 @@ func (i *Interpreter) evaluateBinaryOp(op string, left, right runtime.Value) (ru
 			// String concatenation
 			return runtime.NewString(left.String() + right.String()), nil
 		}
+		if eitherDecimal(left, right) {
+			return decimalArithmetic(op, left, right)
+		}
 		// Numeric addition
 		if left.Type == runtime.ValueTypeFloat || right.Type == runtime.ValueTypeFloat {
 			l, _ := left.AsFloat()
 			r, _ := right.AsFloat()
 			return runtime.NewFloat(l + r), nil
 		}
+		if eitherBigInt(left, right) {
+			return bigArithmetic(op, left, right)
+		}
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
-		return runtime.NewInt(l + r), nil
+		return i.intArithmetic(op, l, r)
 
 	case ast.OpSub:
+		if eitherDecimal(left, right) {
+			return decimalArithmetic(op, left, right)
+		}
 		if left.Type == runtime.ValueTypeFloat || right.Type == runtime.ValueTypeFloat {
 			l, _ := left.AsFloat()
 			r, _ := right.AsFloat()
 			return runtime.NewFloat(l - r), nil
 		}
+		if eitherBigInt(left, right) {
+			return bigArithmetic(op, left, right)
+		}
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
-		return runtime.NewInt(l - r), nil
+		return i.intArithmetic(op, l, r)
 
 	case ast.OpMul:
+		if eitherDecimal(left, right) {
+			return decimalArithmetic(op, left, right)
+		}
 		if left.Type == runtime.ValueTypeFloat || right.Type == runtime.ValueTypeFloat {
 			l, _ := left.AsFloat()
 			r, _ := right.AsFloat()
 			return runtime.NewFloat(l * r), nil
 		}
+		if eitherBigInt(left, right) {
+			return bigArithmetic(op, left, right)
+		}
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
-		return runtime.NewInt(l * r), nil
+		return i.intArithmetic(op, l, r)
 
 	case ast.OpDiv:
+		if eitherDecimal(left, right) {
+			return decimalArithmetic(op, left, right)
+		}
 		if left.Type == runtime.ValueTypeFloat || right.Type == runtime.ValueTypeFloat {
 			l, _ := left.AsFloat()
 			r, _ := right.AsFloat()
-			if r == 0 {
+			if r == 0 && !i.isLenient(LenientDivision) {
 				return runtime.NewVoid(), fmt.Errorf("division by zero")
 			}
 			return runtime.NewFloat(l / r), nil
 		}
+		if isZeroInt(right) {
+			return i.divideIntByZero(op)
+		}
+		if eitherBigInt(left, right) {
+			return bigArithmetic(op, left, right)
+		}
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
 		if r == 0 {
-			return runtime.NewVoid(), fmt.Errorf("division by zero")
+			return i.divideIntByZero(op)
 		}
-		return runtime.NewInt(l / r), nil
+		return i.intArithmetic(op, l, r)
 
 	case ast.OpMod:
+		if eitherDecimal(left, right) {
+			return decimalArithmetic(op, left, right)
+		}
+		if isZeroInt(right) {
+			return i.divideIntByZero(op)
+		}
+		if eitherBigInt(left, right) {
+			return bigArithmetic(op, left, right)
+		}
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
 		if r == 0 {
-			return runtime.NewVoid(), fmt.Errorf("modulo by zero")
+			return i.divideIntByZero(op)
 		}
 		return runtime.NewInt(l % r), nil
 
@@ -653,20 +1480,20 @@ func (i *Interpreter) evaluateBinaryOp(op string, left, right runtime.Value) (ru
 		return runtime.NewBool(!i.valuesEqual(left, right)), nil
 
 	case ast.OpLt:
-		result := i.compareValues(left, right)
-		return runtime.NewBool(result < 0), nil
+		result, ordered := i.compareValues(left, right)
+		return runtime.NewBool(ordered && result < 0), nil
 
 	case ast.OpLe:
-		result := i.compareValues(left, right)
-		return runtime.NewBool(result <= 0), nil
+		result, ordered := i.compareValues(left, right)
+		return runtime.NewBool(ordered && result <= 0), nil
 
 	case ast.OpGt:
-		result := i.compareValues(left, right)
-		return runtime.NewBool(result > 0), nil
+		result, ordered := i.compareValues(left, right)
+		return runtime.NewBool(ordered && result > 0), nil
 
 	case ast.OpGe:
-		result := i.compareValues(left, right)
-		return runtime.NewBool(result >= 0), nil
+		result, ordered := i.compareValues(left, right)
+		return runtime.NewBool(ordered && result >= 0), nil
 
 	case ast.OpAnd:
 		return runtime.NewBool(left.IsTruthy() && right.IsTruthy()), nil
@@ -690,14 +1517,168 @@ func (i *Interpreter) evaluateUnaryOp(op string, operand runtime.Value) (runtime
 			v, _ := operand.AsFloat()
 			return runtime.NewFloat(-v), nil
 		}
+		if operand.Type == runtime.ValueTypeBigInt {
+			v, _ := operand.AsBigInt()
+			return runtime.NewBigInt(v.Neg(v)), nil
+		}
+		if operand.Type == runtime.ValueTypeDecimal {
+			v, _ := operand.AsDecimal()
+			return runtime.NewDecimal(v.Neg(v)), nil
+		}
 		v, _ := operand.AsInt()
-		return runtime.NewInt(-v), nil
+		return i.intArithmetic(ast.OpSub, 0, v)
 
 	default:
 		return runtime.NewVoid(), fmt.Errorf("unknown unary operator: %s", op)
 	}
 }
 
+// evaluateCast converts a value to the given basic type.
+// Float to int conversion truncates toward zero; NaN, infinities and values
+// outside the int64 range are reported as runtime errors.
+func (i *Interpreter) evaluateCast(target string, operand runtime.Value) (runtime.Value, error) {
+	switch target {
+	case ast.TypeInt:
+		switch operand.Type {
+		case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
+			return operand, nil
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return runtime.NewVoid(), fmt.Errorf("cannot cast %v to int", f)
+			}
+			truncated := math.Trunc(f)
+			if truncated < math.MinInt64 || truncated >= math.MaxInt64 {
+				return runtime.NewVoid(), fmt.Errorf("cannot cast %v to int: value out of range", f)
+			}
+			return runtime.NewInt(int64(truncated)), nil
+		case runtime.ValueTypeBool:
+			b, _ := operand.AsBool()
+			if b {
+				return runtime.NewInt(1), nil
+			}
+			return runtime.NewInt(0), nil
+		case runtime.ValueTypeString:
+			s, _ := operand.AsString()
+			n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+			if !ok {
+				return runtime.NewVoid(), fmt.Errorf("cannot cast string %q to int", s)
+			}
+			return runtime.NewBigInt(n), nil
+		case runtime.ValueTypeEnum:
+			// Enum values give their variant's index
+			ev, _ := operand.AsEnum()
+			if typeDef := i.lookupType(ev.Enum); typeDef != nil {
+				if index, ok := typeDef.Definition.VariantIndex(ev.Variant); ok {
+					return runtime.NewInt(int64(index)), nil
+				}
+			}
+		}
+
+	case ast.TypeFloat:
+		switch operand.Type {
+		case runtime.ValueTypeFloat:
+			return operand, nil
+		case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
+			f, _ := operand.AsFloat()
+			return runtime.NewFloat(f), nil
+		case runtime.ValueTypeBool:
+			b, _ := operand.AsBool()
+			if b {
+				return runtime.NewFloat(1), nil
+			}
+			return runtime.NewFloat(0), nil
+		case runtime.ValueTypeString:
+			s, _ := operand.AsString()
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("cannot cast string %q to float", s)
+			}
+			return runtime.NewFloat(f), nil
+		}
+
+	case ast.TypeBool:
+		switch operand.Type {
+		case runtime.ValueTypeBool:
+			return operand, nil
+		case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
+			return runtime.NewBool(operand.IsTruthy()), nil
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			return runtime.NewBool(f != 0), nil
+		case runtime.ValueTypeString:
+			s, _ := operand.AsString()
+			b, err := strconv.ParseBool(strings.TrimSpace(s))
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("cannot cast string %q to bool", s)
+			}
+			return runtime.NewBool(b), nil
+		}
+
+	case ast.TypeString:
+		switch operand.Type {
+		case runtime.ValueTypeString:
+			return operand, nil
+		case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
+			return runtime.NewString(operand.String()), nil
+		case runtime.ValueTypeFloat:
+			f, _ := operand.AsFloat()
+			return runtime.NewString(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		case runtime.ValueTypeBool:
+			b, _ := operand.AsBool()
+			return runtime.NewString(strconv.FormatBool(b)), nil
+		}
+
+	default:
+		return runtime.NewVoid(), fmt.Errorf("unsupported cast target type: %s", target)
+	}
+
+	return runtime.NewVoid(), fmt.Errorf("cannot cast %s to %s", valueTypeName(operand.Type), target)
+}
+
+// mapKey returns the string under which a map stores key.
+func mapKey(key runtime.Value) (string, error) {
+	str, ok := runtime.MapKey(key)
+	if !ok {
+		return "", fmt.Errorf("map keys must be int, bool or string, got %s", valueTypeName(key.Type))
+	}
+	return str, nil
+}
+
+// valueTypeName returns the ALaS type name for a runtime value type.
+func valueTypeName(t runtime.ValueType) string {
+	switch t {
+	case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
+		return ast.TypeInt
+	case runtime.ValueTypeFloat:
+		return ast.TypeFloat
+	case runtime.ValueTypeString:
+		return ast.TypeString
+	case runtime.ValueTypeBool:
+		return ast.TypeBool
+	case runtime.ValueTypeArray:
+		return ast.TypeArray
+	case runtime.ValueTypeMap:
+		return ast.TypeMap
+	case runtime.ValueTypeVoid:
+		return ast.TypeVoid
+	case runtime.ValueTypeEnum:
+		return ast.TypeKindEnum
+	case runtime.ValueTypeFunction:
+		return ast.TypeFunc
+	case runtime.ValueTypeDecimal:
+		return ast.TypeDecimal
+	case runtime.ValueTypeStringBuilder:
+		return "stringBuilder"
+	case runtime.ValueTypeChannel:
+		return "channel"
+	case runtime.ValueTypeMutex:
+		return "mutex"
+	default:
+		return "unknown"
+	}
+}
+
 // valuesEqual checks if two values are equal.
 func (i *Interpreter) valuesEqual(left, right runtime.Value) bool {
 	if left.Type != right.Type {
@@ -709,6 +1690,14 @@ func (i *Interpreter) valuesEqual(left, right runtime.Value) bool {
 		l, _ := left.AsInt()
 		r, _ := right.AsInt()
 		return l == r
+	case runtime.ValueTypeBigInt:
+		l, _ := left.AsBigInt()
+		r, _ := right.AsBigInt()
+		return l.Cmp(r) == 0
+	case runtime.ValueTypeDecimal:
+		l, _ := left.AsDecimal()
+		r, _ := right.AsDecimal()
+		return l.Cmp(r) == 0
 	case runtime.ValueTypeFloat:
 		l, _ := left.AsFloat()
 		r, _ := right.AsFloat()
@@ -747,22 +1736,59 @@ func (i *Interpreter) valuesEqual(left, right runtime.Value) bool {
 			}
 		}
 		return true
+	case runtime.ValueTypeEnum:
+		l, _ := left.AsEnum()
+		r, _ := right.AsEnum()
+		if l.Enum != r.Enum || l.Variant != r.Variant || len(l.Fields) != len(r.Fields) {
+			return false
+		}
+		for k, v := range l.Fields {
+			if rv, ok := r.Fields[k]; !ok || !i.valuesEqual(v, rv) {
+				return false
+			}
+		}
+		return true
+	case runtime.ValueTypeFunction, runtime.ValueTypeStringBuilder, runtime.ValueTypeChannel, runtime.ValueTypeMutex:
+		// Functions, builders, channels and mutexes are equal only when they are the same value
+		return left.Value == right.Value
 	default:
 		return false
 	}
 }
 
-// compareValues compares two values.
-func (i *Interpreter) compareValues(left, right runtime.Value) int {
+// compareValues compares two values, returning -1, 0 or 1. The values are
+// unordered, and ordered is false, when either is a float NaN or a decimal is
+// compared with a float.
+func (i *Interpreter) compareValues(left, right runtime.Value) (result int, ordered bool) {
 	if left.Type == runtime.ValueTypeString && right.Type == runtime.ValueTypeString {
 		l, _ := left.AsString()
 		r, _ := right.AsString()
 		if l < r {
-			return -1
+			return -1, true
 		} else if l > r {
-			return 1
+			return 1, true
+		}
+		return 0, true
+	}
+
+	// Decimals compare exactly with each other and with ints, and are
+	// unordered with floats
+	if eitherDecimal(left, right) {
+		l, lerr := left.AsDecimal()
+		r, rerr := right.AsDecimal()
+		if lerr != nil || rerr != nil {
+			return 0, false
+		}
+		return l.Cmp(r), true
+	}
+
+	// Ints and bigints compare exactly
+	if eitherBigInt(left, right) {
+		l, lerr := left.AsBigInt()
+		r, rerr := right.AsBigInt()
+		if lerr == nil && rerr == nil {
+			return l.Cmp(r), true
 		}
-		return 0
 	}
 
 	// Numeric comparison
@@ -777,12 +1803,15 @@ func (i *Interpreter) compareValues(left, right runtime.Value) int {
 		r = float64(ri)
 	}
 
+	if math.IsNaN(l) || math.IsNaN(r) {
+		return 0, false
+	}
 	if l < r {
-		return -1
+		return -1, true
 	} else if l > r {
-		return 1
+		return 1, true
 	}
-	return 0
+	return 0, true
 }
 
 // evaluateIndexAccess handles array and map indexing.
@@ -800,6 +1829,9 @@ func (i *Interpreter) evaluateIndexAccess(object, index runtime.Value) (runtime.
 		}
 
 		if idx < 0 || idx >= int64(len(arr)) {
+			if i.isLenient(LenientBounds) {
+				return runtime.NewVoid(), nil
+			}
 			return runtime.NewVoid(), fmt.Errorf("array index out of bounds: %d", idx)
 		}
 
@@ -811,15 +1843,19 @@ func (i *Interpreter) evaluateIndexAccess(object, index runtime.Value) (runtime.
 			return runtime.NewVoid(), err
 		}
 
-		// Convert key to string representation
-		// This allows using any type as a key, which will be stringified
-		key := index.String()
+		key, err := mapKey(index)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
 
 		if val, ok := m[key]; ok {
 			return val, nil
 		}
+		if i.isLenient(LenientMissingKeys) {
+			return runtime.NewVoid(), nil
+		}
 
-		return runtime.NewVoid(), fmt.Errorf("map key not found: %s", key)
+		return runtime.NewVoid(), fmt.Errorf("map key not found: %s", index)
 
 	case runtime.ValueTypeInt, runtime.ValueTypeFloat, runtime.ValueTypeString, runtime.ValueTypeBool, runtime.ValueTypeVoid:
 		return runtime.NewVoid(), fmt.Errorf("cannot index into %v", object.Type)
@@ -828,25 +1864,107 @@ func (i *Interpreter) evaluateIndexAccess(object, index runtime.Value) (runtime.
 	}
 }
 
-// evaluateFieldAccess handles field access on objects (maps).
-func (i *Interpreter) evaluateFieldAccess(object runtime.Value, field string) (runtime.Value, error) {
-	switch object.Type {
-	case runtime.ValueTypeMap:
-		m, err := object.AsMap()
+// assignElement stores a value into the array element, map entry, or struct
+// field named by an index or field expression. Arrays and maps share their
+// storage with every variable holding them, so the update is made in place.
+// Structs are values: the struct is copied with the new field and the copy
+// stored back where the struct was held, leaving other copies unchanged.
+func (i *Interpreter) assignElement(target *ast.Expression, value runtime.Value, env *Environment) error {
+	object, err := i.evaluateExpression(target.Object, env)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case target.Type == ast.ExprIndex && object.Type == runtime.ValueTypeArray:
+		arr, err := object.AsArray()
 		if err != nil {
-			return runtime.NewVoid(), err
+			return err
 		}
+		index, err := i.evaluateExpression(target.Index, env)
+		if err != nil {
+			return err
+		}
+		idx, err := index.AsInt()
+		if err != nil {
+			return fmt.Errorf("array index must be an integer: %v", err)
+		}
+		if idx < 0 || idx >= int64(len(arr)) {
+			if i.isLenient(LenientBounds) {
+				return nil
+			}
+			return fmt.Errorf("array index out of bounds: %d", idx)
+		}
+		arr[idx].Release()
+		arr[idx] = value
+		return nil
 
-		if val, ok := m[field]; ok {
-			return val, nil
+	case object.Type == runtime.ValueTypeMap:
+		m, err := object.AsMap()
+		if err != nil {
+			return err
+		}
+		key := target.Field
+		if target.Type == ast.ExprIndex {
+			index, err := i.evaluateExpression(target.Index, env)
+			if err != nil {
+				return err
+			}
+			if key, err = mapKey(index); err != nil {
+				return err
+			}
+		}
+		if !i.isStruct(m) {
+			if old, ok := m[key]; ok {
+				old.Release()
+			}
+			m[key] = value
+			return nil
 		}
 
-		return runtime.NewVoid(), fmt.Errorf("field not found: %s", field)
+		fields := make(map[string]runtime.Value, len(m))
+		for name, field := range m {
+			if name != key {
+				field.Retain()
+				fields[name] = field
+			}
+		}
+		fields[key] = value
+		updated := runtime.NewGCMap(fields)
+		if target.Object.Type == ast.ExprVariable {
+			env.Assign(target.Object.Name, updated)
+			return nil
+		}
+		return i.assignElement(target.Object, updated, env)
 
-	case runtime.ValueTypeInt, runtime.ValueTypeFloat, runtime.ValueTypeString,
-		runtime.ValueTypeBool, runtime.ValueTypeArray, runtime.ValueTypeVoid:
-		return runtime.NewVoid(), fmt.Errorf("cannot access field on %v", object.Type)
+	case target.Type == ast.ExprIndex:
+		return fmt.Errorf("cannot index into %s", valueTypeName(object.Type))
 	default:
-		return runtime.NewVoid(), fmt.Errorf("cannot access field on %v", object.Type)
+		return fmt.Errorf("cannot access field on %s", valueTypeName(object.Type))
+	}
+}
+
+// evaluateFieldAccess reads a field of a struct. Struct values, and the
+// maps field access also applies to, are maps at runtime.
+func (i *Interpreter) evaluateFieldAccess(object runtime.Value, field string) (runtime.Value, error) {
+	if object.Type != runtime.ValueTypeMap {
+		return runtime.NewVoid(), fmt.Errorf("cannot access field '%s' on %s value", field, valueTypeName(object.Type))
+	}
+	m, err := object.AsMap()
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	if val, ok := m[field]; ok {
+		return val, nil
+	}
+
+	if len(m) == 0 {
+		return runtime.NewVoid(), fmt.Errorf("field not found: %s (value has no fields)", field)
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return runtime.NewVoid(), fmt.Errorf("field not found: %s (available fields: %s)", field, strings.Join(names, ", "))
 }
@@ -0,0 +1,89 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestAssertStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    *ast.Expression
+		message string
+		wantErr string
+	}{
+		{
+			name: "passing assertion",
+			cond: &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    ast.OpGt,
+				Left:  &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0},
+			},
+		},
+		{
+			name: "failing assertion with message",
+			cond: &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    ast.OpLt,
+				Left:  &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0},
+			},
+			message: "x must be negative",
+			wantErr: "assertion failed: x must be negative",
+		},
+		{
+			name:    "failing assertion without message",
+			cond:    &ast.Expression{Type: ast.ExprLiteral, Value: false},
+			wantErr: "assertion failed",
+		},
+		{
+			name:    "non-boolean condition",
+			cond:    &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			wantErr: "assert condition must be a boolean, got int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_assert",
+				Functions: []ast.Function{
+					{
+						Type:    "function",
+						Name:    "main",
+						Params:  []ast.Parameter{{Name: "x", Type: ast.TypeInt}},
+						Returns: ast.TypeInt,
+						Body: []ast.Statement{
+							{Type: ast.StmtAssert, Cond: tt.cond, Message: tt.message},
+							{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}},
+						},
+					},
+				},
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{runtime.NewInt(5)})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, runtime.NewInt(5)) {
+				t.Errorf("Run() = %v, want 5", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+	"github.com/dshills/alas/internal/validator"
+)
+
+const asyncModule = `{"type": "module", "name": "workers", "functions": [
+	{"type": "function", "name": "fib", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "if", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 2}},
+			"then": [{"type": "return", "value": {"type": "variable", "name": "n"}}]},
+		{"type": "return", "value": {"type": "binary", "op": "+",
+			"left": {"type": "call", "name": "fib", "args": [{"type": "binary", "op": "-", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 1}}]},
+			"right": {"type": "call", "name": "fib", "args": [{"type": "binary", "op": "-", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 2}}]}}}]},
+	{"type": "function", "name": "main", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "assign", "target": "base", "value": {"type": "literal", "value": 1000}},
+		{"type": "assign", "target": "fibTask", "value": {"type": "builtin", "name": "async.spawn", "args": [
+			{"type": "func_ref", "name": "fib"},
+			{"type": "array_literal", "elements": [{"type": "variable", "name": "n"}]}]}},
+		{"type": "assign", "target": "offsetTask", "value": {"type": "builtin", "name": "async.spawn", "args": [
+			{"type": "lambda", "params": [{"name": "x", "type": "int"}], "returns": "int", "body": [
+				{"type": "return", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "x"}, "right": {"type": "variable", "name": "base"}}}]},
+			{"type": "array_literal", "elements": [{"type": "variable", "name": "n"}]}]}},
+		{"type": "assign", "target": "fibResult", "value": {"type": "builtin", "name": "async.await", "args": [{"type": "variable", "name": "fibTask"}]}},
+		{"type": "assign", "target": "offsetResult", "value": {"type": "builtin", "name": "async.await", "args": [{"type": "variable", "name": "offsetTask"}]}},
+		{"type": "return", "value": {"type": "binary", "op": "+",
+			"left": {"type": "field", "object": {"type": "variable", "name": "fibResult"}, "field": "value"},
+			"right": {"type": "field", "object": {"type": "variable", "name": "offsetResult"}, "field": "value"}}}]}]}`
+
+func loadAsyncModule(t *testing.T) *Interpreter {
+	t.Helper()
+	if err := validator.ValidateJSON([]byte(asyncModule)); err != nil {
+		t.Fatalf("ValidateJSON() error = %v", err)
+	}
+	module, err := ast.ParseModule([]byte(asyncModule), "workers.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	return interp
+}
+
+func TestAsyncSpawnRunsFunctions(t *testing.T) {
+	interp := loadAsyncModule(t)
+
+	// fib(15) = 610 on one task, 15 + base on the other
+	got, err := interp.Run("main", []runtime.Value{runtime.NewInt(15)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if n, _ := got.AsInt(); n != 610+1015 {
+		t.Errorf("Run() = %s, want %d", got, 610+1015)
+	}
+}
+
+func TestConcurrentRun(t *testing.T) {
+	interp := loadAsyncModule(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			got, err := interp.Run("main", []runtime.Value{runtime.NewInt(n)})
+			if err != nil {
+				errs <- err
+				return
+			}
+			want := fib(n) + n + 1000
+			if v, _ := got.AsInt(); v != want {
+				errs <- fmt.Errorf("main(%d) = %s, want %d", n, got, want)
+			}
+		}(int64(g))
+	}
+
+	// Loading other modules while functions run must not disturb them
+	for m := 0; m < 16; m++ {
+		wg.Add(1)
+		go func(m int) {
+			defer wg.Done()
+			name := fmt.Sprintf("extra%d", m)
+			if err := interp.LoadModule(&ast.Module{Type: "module", Name: name, Functions: []ast.Function{
+				{Type: "function", Name: "f" + name, Body: []ast.Statement{{Type: ast.StmtReturn}}},
+			}}); err != nil {
+				errs <- err
+			}
+		}(m)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentRunWithCoverage(t *testing.T) {
+	interp := New()
+	interp.EnableCoverage()
+	area := methodCall(structLiteral(map[string]float64{"w": 3, "h": 4}), "area")
+	if err := interp.LoadModule(shapeModule([]ast.Statement{{Type: ast.StmtReturn, Value: area}})); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				got, err := interp.Run("main", nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if v, _ := got.AsInt(); v != 12 {
+					errs <- fmt.Errorf("main() = %s, want 12", got)
+					return
+				}
+			}
+		}()
+	}
+
+	// Coverage numbers the statements of modules loaded while main runs
+	for m := 0; m < 8; m++ {
+		wg.Add(1)
+		go func(m int) {
+			defer wg.Done()
+			name := fmt.Sprintf("scratch%d", m)
+			if err := interp.LoadModule(&ast.Module{Type: "module", Name: name, Functions: []ast.Function{
+				{Type: "function", Name: "f" + name, Body: []ast.Statement{{Type: ast.StmtReturn}}},
+			}}); err != nil {
+				errs <- err
+				return
+			}
+			if err := interp.UnloadModule(name); err != nil {
+				errs <- err
+			}
+		}(m)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if hits := interp.Coverage()["Rect.area"]; len(hits) != 1 || !hits[0] {
+		t.Errorf("Coverage()[Rect.area] = %v, want [true]", hits)
+	}
+}
+
+const channelModule = `{"type": "module", "name": "pipeline", "functions": [
+	{"type": "function", "name": "main", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "assign", "target": "ch", "value": {"type": "builtin", "name": "channel.make", "args": [{"type": "literal", "value": 0}]}},
+		{"type": "assign", "target": "producer", "value": {"type": "builtin", "name": "async.spawn", "args": [
+			{"type": "lambda", "params": [], "returns": "void", "body": [
+				{"type": "assign", "target": "k", "value": {"type": "literal", "value": 1}},
+				{"type": "while", "cond": {"type": "binary", "op": "<=", "left": {"type": "variable", "name": "k"}, "right": {"type": "variable", "name": "n"}}, "body": [
+					{"type": "expr", "value": {"type": "builtin", "name": "channel.send", "args": [{"type": "variable", "name": "ch"}, {"type": "variable", "name": "k"}]}},
+					{"type": "assign", "target": "k", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "k"}, "right": {"type": "literal", "value": 1}}}]},
+				{"type": "expr", "value": {"type": "builtin", "name": "channel.close", "args": [{"type": "variable", "name": "ch"}]}},
+				{"type": "return"}]}]}},
+		{"type": "assign", "target": "sum", "value": {"type": "literal", "value": 0}},
+		{"type": "assign", "target": "received", "value": {"type": "literal", "value": 0}},
+		{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "received"}, "right": {"type": "variable", "name": "n"}}, "body": [
+			{"type": "assign", "target": "sum", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "sum"},
+				"right": {"type": "builtin", "name": "channel.recv", "args": [{"type": "variable", "name": "ch"}, {"type": "literal", "value": 1000}]}}},
+			{"type": "assign", "target": "received", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "received"}, "right": {"type": "literal", "value": 1}}}]},
+		{"type": "expr", "value": {"type": "builtin", "name": "async.await", "args": [{"type": "variable", "name": "producer"}]}},
+		{"type": "return", "value": {"type": "variable", "name": "sum"}}]}]}`
+
+func TestChannelBetweenTasks(t *testing.T) {
+	if err := validator.ValidateJSON([]byte(channelModule)); err != nil {
+		t.Fatalf("ValidateJSON() error = %v", err)
+	}
+	module, err := ast.ParseModule([]byte(channelModule), "pipeline.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	// The spawned producer sends 1..n through an unbuffered channel
+	got, err := interp.Run("main", []runtime.Value{runtime.NewInt(100)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if n, _ := got.AsInt(); n != 5050 {
+		t.Errorf("Run() = %s, want 5050", got)
+	}
+}
+
+const blockedRecvModule = `{"type": "module", "name": "blocked", "functions": [
+	{"type": "function", "name": "wait", "params": [{"name": "ch", "type": "channel"}], "returns": "int", "body": [
+		{"type": "return", "value": {"type": "builtin", "name": "channel.recv", "args": [{"type": "variable", "name": "ch"}]}}]},
+	{"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+		{"type": "assign", "target": "ch", "value": {"type": "builtin", "name": "channel.make", "args": [{"type": "literal", "value": 0}]}},
+		{"type": "return", "value": {"type": "call", "name": "wait", "args": [{"type": "variable", "name": "ch"}]}}]}]}`
+
+func TestRunContextCancelWakesRecv(t *testing.T) {
+	module, err := ast.ParseModule([]byte(blockedRecvModule), "blocked.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	// Nothing is ever sent, so only canceling the run ends the receive
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := interp.RunContext(ctx, "main", []runtime.Value{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "channel.recv: context canceled") {
+			t.Errorf("RunContext() error = %v, want the receive canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RunContext() still blocked after its context was canceled")
+	}
+}
+
+const mutexModule = `{"type": "module", "name": "counter", "functions": [
+	{"type": "function", "name": "main", "params": [{"name": "tasks", "type": "int"}, {"name": "rounds", "type": "int"}], "returns": "int", "body": [
+		{"type": "assign", "target": "mu", "value": {"type": "builtin", "name": "sync.mutexCreate", "args": []}},
+		{"type": "assign", "target": "counter", "value": {"type": "map_literal", "pairs": [{"key": {"type": "literal", "value": "n"}, "value": {"type": "literal", "value": 0}}]}},
+		{"type": "assign", "target": "work", "value": {"type": "lambda", "params": [], "returns": "void", "body": [
+			{"type": "assign", "target": "r", "value": {"type": "literal", "value": 0}},
+			{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "r"}, "right": {"type": "variable", "name": "rounds"}}, "body": [
+				{"type": "expr", "value": {"type": "builtin", "name": "sync.mutexLock", "args": [{"type": "variable", "name": "mu"}]}},
+				{"type": "assign", "lvalue": {"type": "index", "object": {"type": "variable", "name": "counter"}, "index": {"type": "literal", "value": "n"}},
+					"value": {"type": "binary", "op": "+", "left": {"type": "index", "object": {"type": "variable", "name": "counter"}, "index": {"type": "literal", "value": "n"}}, "right": {"type": "literal", "value": 1}}},
+				{"type": "expr", "value": {"type": "builtin", "name": "sync.mutexUnlock", "args": [{"type": "variable", "name": "mu"}]}},
+				{"type": "assign", "target": "r", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "r"}, "right": {"type": "literal", "value": 1}}}]},
+			{"type": "return"}]}},
+		{"type": "assign", "target": "handles", "value": {"type": "array_literal", "elements": []}},
+		{"type": "assign", "target": "t", "value": {"type": "literal", "value": 0}},
+		{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "t"}, "right": {"type": "variable", "name": "tasks"}}, "body": [
+			{"type": "assign", "target": "handles", "value": {"type": "builtin", "name": "collections.append", "args": [{"type": "variable", "name": "handles"},
+				{"type": "builtin", "name": "async.spawn", "args": [{"type": "variable", "name": "work"}]}]}},
+			{"type": "assign", "target": "t", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "t"}, "right": {"type": "literal", "value": 1}}}]},
+		{"type": "expr", "value": {"type": "builtin", "name": "async.parallel", "args": [{"type": "variable", "name": "handles"}]}},
+		{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "counter"}, "index": {"type": "literal", "value": "n"}}}]}]}`
+
+func TestMutexGuardsSharedState(t *testing.T) {
+	if err := validator.ValidateJSON([]byte(mutexModule)); err != nil {
+		t.Fatalf("ValidateJSON() error = %v", err)
+	}
+	module, err := ast.ParseModule([]byte(mutexModule), "counter.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	// Eight tasks each increment the shared counter 50 times
+	got, err := interp.Run("main", []runtime.Value{runtime.NewInt(8), runtime.NewInt(50)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if n, _ := got.AsInt(); n != 400 {
+		t.Errorf("Run() = %s, want 400", got)
+	}
+}
+
+func fib(n int64) int64 {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
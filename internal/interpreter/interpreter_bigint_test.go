@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+const factorialModule = `{"type": "module", "name": "factorial", "functions": [
+	{"type": "function", "name": "factorial", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+		{"type": "if", "cond": {"type": "binary", "op": "<=", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 1}},
+			"then": [{"type": "return", "value": {"type": "literal", "value": 1}}]},
+		{"type": "return", "value": {"type": "binary", "op": "*", "left": {"type": "variable", "name": "n"},
+			"right": {"type": "call", "name": "factorial", "args": [{"type": "binary", "op": "-", "left": {"type": "variable", "name": "n"}, "right": {"type": "literal", "value": 1}}]}}}]},
+	{"type": "function", "name": "shrink", "params": [], "returns": "int", "body": [
+		{"type": "return", "value": {"type": "binary", "op": "/",
+			"left": {"type": "literal", "value": 25852016738884976640000},
+			"right": {"type": "call", "name": "factorial", "args": [{"type": "literal", "value": 23}]}}}]}]}`
+
+func TestBigIntFactorial(t *testing.T) {
+	module, err := ast.ParseModule([]byte(factorialModule), "factorial.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	tests := []struct {
+		n        int64
+		want     string
+		wantType runtime.ValueType
+	}{
+		{n: 20, want: "2432902008176640000", wantType: runtime.ValueTypeInt},
+		{n: 21, want: "51090942171709440000", wantType: runtime.ValueTypeBigInt},
+		{n: 25, want: "15511210043330985984000000", wantType: runtime.ValueTypeBigInt},
+	}
+	for _, tt := range tests {
+		got, err := interp.Run("factorial", []runtime.Value{runtime.NewInt(tt.n)})
+		if err != nil {
+			t.Fatalf("factorial(%d) error = %v", tt.n, err)
+		}
+		if got.Type != tt.wantType || got.String() != tt.want {
+			t.Errorf("factorial(%d) = %s (type %v), want %s (type %v)", tt.n, got, got.Type, tt.want, tt.wantType)
+		}
+	}
+
+	// A bigint literal divided back into range is an ordinary int
+	got, err := interp.Run("shrink", nil)
+	if err != nil {
+		t.Fatalf("shrink() error = %v", err)
+	}
+	if n, err := got.AsInt(); err != nil || n != 1 {
+		t.Errorf("shrink() = %v (type %v), want int 1", got, got.Type)
+	}
+}
+
+func TestBigIntOperations(t *testing.T) {
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	binary := func(op string, left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: op, Left: left, Right: right}
+	}
+	cast := func(to string, operand *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCast, To: to, Operand: operand}
+	}
+
+	tests := []struct {
+		name string
+		expr *ast.Expression
+		want string
+	}{
+		{name: "subtract int", expr: binary(ast.OpSub, lit(huge), lit(1.0)), want: "99999999999999999999"},
+		{name: "division truncates toward zero", expr: binary(ast.OpDiv, &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: lit(huge)}, lit(7.0)), want: "-14285714285714285714"},
+		{name: "modulo", expr: binary(ast.OpMod, lit(huge), lit(7.0)), want: "2"},
+		{name: "mixed with float", expr: binary(ast.OpMul, lit(huge), lit(0.5)), want: "50000000000000000000.000000"},
+		{name: "greater than any int", expr: binary(ast.OpGt, lit(huge), lit(9.2e18)), want: "true"},
+		{name: "exact comparison", expr: binary(ast.OpLt, lit(huge), binary(ast.OpAdd, lit(huge), lit(1.0))), want: "true"},
+		{name: "equality", expr: binary(ast.OpEq, lit(huge), binary(ast.OpMul, lit(1e10), lit(1e10))), want: "true"},
+		{name: "never equal to an int", expr: binary(ast.OpEq, lit(huge), lit(1.0)), want: "false"},
+		{name: "negating min int", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: lit(int64(math.MinInt64))}, want: "9223372036854775808"},
+		{name: "min int divided by minus one", expr: binary(ast.OpDiv, lit(int64(math.MinInt64)), lit(-1.0)), want: "9223372036854775808"},
+		{name: "cast to float", expr: cast(ast.TypeFloat, lit(huge)), want: "100000000000000000000.000000"},
+		{name: "cast to string", expr: cast(ast.TypeString, lit(huge)), want: "100000000000000000000"},
+		{name: "cast from string", expr: cast(ast.TypeInt, lit("123456789012345678901234567890")), want: "123456789012345678901234567890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFloatMain(t, ast.TypeInt, tt.expr)
+			if got.String() != tt.want {
+				t.Errorf("Run() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
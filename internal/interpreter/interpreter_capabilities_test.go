@@ -0,0 +1,136 @@
+package interpreter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func capabilityTestModules() (*ast.Module, *ast.Module) {
+	variable := func(name string) ast.Expression { return ast.Expression{Type: ast.ExprVariable, Name: name} }
+	builtin := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: args}
+	}
+	function := func(name string, params []ast.Parameter, value *ast.Expression) ast.Function {
+		return ast.Function{
+			Type:    "function",
+			Name:    name,
+			Params:  params,
+			Returns: ast.TypeString,
+			Body:    []ast.Statement{{Type: ast.StmtReturn, Value: value}},
+		}
+	}
+	pathParam := []ast.Parameter{{Name: "path", Type: ast.TypeString}}
+
+	plugin := &ast.Module{
+		Type:    "module",
+		Name:    "greeter",
+		Exports: []string{"read", "log"},
+		Functions: []ast.Function{
+			function("read", pathParam, builtin("io.readFile", variable("path"))),
+			// log reaches io.print through an unexported helper
+			function("log", pathParam, &ast.Expression{Type: ast.ExprCall, Name: "emit", Args: []ast.Expression{variable("path")}}),
+			function("emit", pathParam, builtin("io.print", variable("path"))),
+		},
+	}
+	host := &ast.Module{
+		Type:      "module",
+		Name:      "app",
+		Functions: []ast.Function{function("hostRead", pathParam, builtin("io.readFile", variable("path")))},
+	}
+	return plugin, host
+}
+
+func TestRestrictModuleCapabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	args := []runtime.Value{runtime.NewString(path)}
+
+	tests := []struct {
+		name         string
+		capabilities []string
+		call         func(*Interpreter) (runtime.Value, error)
+		missing      string
+	}{
+		{
+			name:         "file access without filesystem capability",
+			capabilities: []string{"function", "io"},
+			call:         func(i *Interpreter) (runtime.Value, error) { return i.RunModuleFunction("greeter", "read", args) },
+			missing:      CapabilityFileSystem,
+		},
+		{
+			name:         "file access with filesystem capability",
+			capabilities: []string{"filesystem"},
+			call:         func(i *Interpreter) (runtime.Value, error) { return i.RunModuleFunction("greeter", "read", args) },
+		},
+		{
+			name:         "helper without io capability",
+			capabilities: []string{"function"},
+			call:         func(i *Interpreter) (runtime.Value, error) { return i.RunModuleFunction("greeter", "log", args) },
+			missing:      CapabilityIO,
+		},
+		{
+			name:         "helper with io capability",
+			capabilities: []string{"io"},
+			call:         func(i *Interpreter) (runtime.Value, error) { return i.RunModuleFunction("greeter", "log", args) },
+		},
+		{
+			name:         "host code keeps full access",
+			capabilities: nil,
+			call:         func(i *Interpreter) (runtime.Value, error) { return i.Run("hostRead", args) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			plugin, host := capabilityTestModules()
+			for _, module := range []*ast.Module{plugin, host} {
+				if err := interp.LoadModule(module); err != nil {
+					t.Fatalf("LoadModule() error = %v", err)
+				}
+			}
+			interp.RestrictModule("greeter", tt.capabilities)
+
+			_, err := tt.call(interp)
+			var capErr *CapabilityError
+			if tt.missing == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &capErr) {
+				t.Fatalf("error = %v, want CapabilityError", err)
+			}
+			if capErr.Module != "greeter" || capErr.Capability != tt.missing {
+				t.Errorf("CapabilityError = %+v, want module greeter missing %s", capErr, tt.missing)
+			}
+		})
+	}
+}
+
+func TestBuiltinCapability(t *testing.T) {
+	tests := map[string]string{
+		"io.print":     CapabilityIO,
+		"io.readFile":  CapabilityFileSystem,
+		"std.io.print": CapabilityIO,
+		"http.get":     CapabilityNetwork,
+		"os.getenv":    CapabilityProcess,
+		"os.setenv":    CapabilityProcess,
+		"os.exit":      CapabilityProcess,
+		"math.abs":     "",
+		"string.len":   "",
+	}
+	for name, want := range tests {
+		if got := builtinCapability(name); got != want {
+			t.Errorf("builtinCapability(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
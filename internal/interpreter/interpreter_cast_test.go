@@ -0,0 +1,91 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestCastExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		to      string
+		operand interface{}
+		want    runtime.Value
+		wantErr string
+	}{
+		{name: "int to float", to: ast.TypeFloat, operand: 3, want: runtime.NewFloat(3)},
+		{name: "float to int truncates positive", to: ast.TypeInt, operand: 2.9, want: runtime.NewInt(2)},
+		{name: "float to int truncates negative", to: ast.TypeInt, operand: -2.9, want: runtime.NewInt(-2)},
+		{name: "bool to int", to: ast.TypeInt, operand: true, want: runtime.NewInt(1)},
+		{name: "int to bool", to: ast.TypeBool, operand: 0, want: runtime.NewBool(false)},
+		{name: "float to bool", to: ast.TypeBool, operand: 0.5, want: runtime.NewBool(true)},
+		{name: "string to int", to: ast.TypeInt, operand: " 42 ", want: runtime.NewInt(42)},
+		{name: "string to float", to: ast.TypeFloat, operand: "2.5", want: runtime.NewFloat(2.5)},
+		{name: "string to bool", to: ast.TypeBool, operand: "true", want: runtime.NewBool(true)},
+		{name: "int to string", to: ast.TypeString, operand: 7, want: runtime.NewString("7")},
+		{name: "float to string", to: ast.TypeString, operand: 1.5, want: runtime.NewString("1.5")},
+		{name: "bool to string", to: ast.TypeString, operand: false, want: runtime.NewString("false")},
+		{name: "invalid string to int", to: ast.TypeInt, operand: "abc", wantErr: "cannot cast string \"abc\" to int"},
+		{name: "invalid string to float", to: ast.TypeFloat, operand: "x1", wantErr: "cannot cast string \"x1\" to float"},
+		{name: "unsupported target", to: ast.TypeArray, operand: 1, wantErr: "unsupported cast target type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_cast",
+				Functions: []ast.Function{
+					{
+						Type:    "function",
+						Name:    "main",
+						Params:  []ast.Parameter{},
+						Returns: tt.to,
+						Body: []ast.Statement{
+							{
+								Type: ast.StmtReturn,
+								Value: &ast.Expression{
+									Type:    ast.ExprCast,
+									To:      tt.to,
+									Operand: &ast.Expression{Type: ast.ExprLiteral, Value: tt.operand},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCastFloatToIntOutOfRange(t *testing.T) {
+	interp := New()
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e19, -1e19} {
+		if _, err := interp.evaluateCast(ast.TypeInt, runtime.NewFloat(f)); err == nil {
+			t.Errorf("evaluateCast(int, %v) expected error", f)
+		}
+	}
+}
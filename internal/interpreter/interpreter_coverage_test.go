@@ -0,0 +1,89 @@
+package interpreter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func coverageTestModule() *ast.Module {
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+
+	return &ast.Module{
+		Type: "module",
+		Name: "test_coverage",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{{Name: "x", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtAssign, Target: "y", Value: lit(0.0)},
+					{
+						Type: ast.StmtIf,
+						Cond: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpGt, Left: variable("x"), Right: lit(0.0)},
+						Then: []ast.Statement{{Type: ast.StmtAssign, Target: "y", Value: lit(1.0)}},
+						Else: []ast.Statement{{Type: ast.StmtAssign, Target: "y", Value: lit(2.0)}},
+					},
+					{Type: ast.StmtReturn, Value: variable("y")},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "unused",
+				Params:  []ast.Parameter{},
+				Returns: ast.TypeInt,
+				Body:    []ast.Statement{{Type: ast.StmtReturn, Value: lit(0.0)}},
+			},
+		},
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	interp := New()
+	if err := interp.LoadModule(coverageTestModule()); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if got := interp.Coverage(); got != nil {
+		t.Fatalf("Coverage() before EnableCoverage = %v, want nil", got)
+	}
+
+	interp.EnableCoverage()
+	if _, err := interp.Run("main", []runtime.Value{runtime.NewInt(5)}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string][]bool{
+		"main":   {true, true, true, false, true},
+		"unused": {false},
+	}
+	if got := interp.Coverage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Coverage() = %v, want %v", got, want)
+	}
+
+	// Coverage accumulates across runs
+	if _, err := interp.Run("main", []runtime.Value{runtime.NewInt(-1)}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := interp.Coverage()["main"]; !reflect.DeepEqual(got, []bool{true, true, true, true, true}) {
+		t.Errorf("Coverage()[main] after second run = %v, want all executed", got)
+	}
+}
+
+func TestCoverageEnabledBeforeLoad(t *testing.T) {
+	interp := New()
+	interp.EnableCoverage()
+	if err := interp.LoadModule(coverageTestModule()); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if _, err := interp.Run("unused", []runtime.Value{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := interp.Coverage()["unused"]; !reflect.DeepEqual(got, []bool{true}) {
+		t.Errorf("Coverage()[unused] = %v, want [true]", got)
+	}
+}
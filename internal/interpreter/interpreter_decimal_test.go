@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestDecimalArithmetic(t *testing.T) {
+	dec := func(s string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: s, To: ast.TypeDecimal}
+	}
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	binary := func(op string, left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: op, Left: left, Right: right}
+	}
+
+	tests := []struct {
+		name string
+		expr *ast.Expression
+		want string
+	}{
+		{name: "0.1 + 0.2", expr: binary(ast.OpAdd, dec("0.1"), dec("0.2")), want: "0.3"},
+		{name: "0.1 + 0.2 equals 0.3", expr: binary(ast.OpEq, binary(ast.OpAdd, dec("0.1"), dec("0.2")), dec("0.3")), want: "true"},
+		{name: "trailing zeros are equal", expr: binary(ast.OpEq, dec("1.50"), dec("1.5")), want: "true"},
+		{name: "subtraction", expr: binary(ast.OpSub, dec("10.00"), dec("0.01")), want: "9.99"},
+		{name: "multiply by int", expr: binary(ast.OpMul, dec("19.99"), lit(3.0)), want: "59.97"},
+		{name: "int on the left", expr: binary(ast.OpSub, lit(1.0), dec("0.9")), want: "0.1"},
+		{name: "exact division", expr: binary(ast.OpDiv, dec("1"), dec("8")), want: "0.125"},
+		{name: "negation", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: dec("2.5")}, want: "-2.5"},
+		{name: "compare with int", expr: binary(ast.OpLt, dec("0.999"), lit(1.0)), want: "true"},
+		{name: "never equal to an int", expr: binary(ast.OpEq, dec("1"), lit(1.0)), want: "false"},
+		{name: "string concatenation", expr: binary(ast.OpAdd, lit("total: "), dec("4.20")), want: "total: 4.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFloatMain(t, "", tt.expr)
+			if got.String() != tt.want {
+				t.Errorf("Run() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name    string
+		expr    *ast.Expression
+		wantErr string
+	}{
+		{name: "inexact division", expr: binary(ast.OpDiv, dec("1"), dec("3")), wantErr: "1 / 3 has no exact decimal result"},
+		{name: "division by zero", expr: binary(ast.OpDiv, dec("1"), dec("0.0")), wantErr: "division by zero"},
+		{name: "mixed with float", expr: binary(ast.OpAdd, dec("0.1"), lit(0.2)), wantErr: "operator + cannot be applied to decimal and float"},
+		{name: "modulo", expr: binary(ast.OpMod, dec("5"), lit(2.0)), wantErr: "operator % cannot be applied to decimal and int"},
+		{name: "invalid literal", expr: dec("1/2"), wantErr: `invalid decimal "1/2"`},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			if err := interp.LoadModule(&ast.Module{Type: "module", Name: "test_decimal", Functions: []ast.Function{
+				{Type: "function", Name: "main", Body: []ast.Statement{{Type: ast.StmtReturn, Value: tt.expr}}},
+			}}); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			_, err := interp.Run("main", []runtime.Value{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// recordCall returns a builtin call expression that records its argument.
+func recordCall(arg *ast.Expression) *ast.Expression {
+	return &ast.Expression{Type: ast.ExprBuiltin, Name: "test.record", Args: []ast.Expression{*arg}}
+}
+
+func TestDeferStatement(t *testing.T) {
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	deferRecord := func(arg *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtDefer, Value: recordCall(arg)}
+	}
+	record := func(arg *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtExpr, Value: recordCall(arg)}
+	}
+
+	tests := []struct {
+		name       string
+		body       []ast.Statement
+		want       string
+		wantResult int64
+		wantErr    string
+	}{
+		{
+			name: "runs in reverse order after the body",
+			body: []ast.Statement{
+				deferRecord(lit("first")),
+				deferRecord(lit("second")),
+				record(lit("body")),
+				{Type: ast.StmtReturn, Value: lit(1.0)},
+			},
+			want:       "body,second,first",
+			wantResult: 1,
+		},
+		{
+			name: "runs on early return from a nested block",
+			body: []ast.Statement{
+				deferRecord(lit("cleanup")),
+				{Type: ast.StmtIf, Cond: lit(true), Then: []ast.Statement{{Type: ast.StmtReturn, Value: lit(2.0)}}},
+				record(lit("unreachable")),
+				{Type: ast.StmtReturn, Value: lit(3.0)},
+			},
+			want:       "cleanup",
+			wantResult: 2,
+		},
+		{
+			name: "sees variables as they are when the function returns",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "x", Value: lit(1.0)},
+				deferRecord(variable("x")),
+				{Type: ast.StmtAssign, Target: "x", Value: lit(5.0)},
+				{Type: ast.StmtReturn, Value: variable("x")},
+			},
+			want:       "5",
+			wantResult: 5,
+		},
+		{
+			name: "defers registered before an error still run",
+			body: []ast.Statement{
+				deferRecord(lit("cleanup")),
+				{Type: ast.StmtAssert, Cond: lit(false), Message: "boom"},
+				deferRecord(lit("never registered")),
+			},
+			want:    "cleanup",
+			wantErr: "assertion failed: boom",
+		},
+		{
+			name: "error in a deferred expression fails the call",
+			body: []ast.Statement{
+				{Type: ast.StmtDefer, Value: &ast.Expression{Type: ast.ExprCall, Name: "missing"}},
+				{Type: ast.StmtReturn, Value: lit(1.0)},
+			},
+			wantErr: "deferred expression",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_defer",
+				Functions: []ast.Function{
+					{Type: "function", Name: "main", Returns: ast.TypeInt, Body: tt.body},
+				},
+			}
+
+			interp := New()
+			var calls []string
+			interp.RegisterBuiltin("test.record", func(args []runtime.Value) (runtime.Value, error) {
+				calls = append(calls, args[0].String())
+				return runtime.NewVoid(), nil
+			})
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", nil)
+			if strings.Join(calls, ",") != tt.want {
+				t.Errorf("recorded %v, want %s", calls, tt.want)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if n, _ := got.AsInt(); n != tt.wantResult {
+				t.Errorf("Run() = %v, want %d", got, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestDeferIsScopedToTheDeferringFunction(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "test_defer",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Returns: ast.TypeVoid,
+				Body: []ast.Statement{
+					{Type: ast.StmtDefer, Value: recordCall(&ast.Expression{Type: ast.ExprLiteral, Value: "main"})},
+					{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprCall, Name: "helper"}},
+					{Type: ast.StmtExpr, Value: recordCall(&ast.Expression{Type: ast.ExprLiteral, Value: "after helper"})},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "helper",
+				Returns: ast.TypeVoid,
+				Body: []ast.Statement{
+					{Type: ast.StmtDefer, Value: recordCall(&ast.Expression{Type: ast.ExprLiteral, Value: "helper"})},
+				},
+			},
+		},
+	}
+
+	interp := New()
+	var calls []string
+	interp.RegisterBuiltin("test.record", func(args []runtime.Value) (runtime.Value, error) {
+		calls = append(calls, args[0].String())
+		return runtime.NewVoid(), nil
+	})
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if _, err := interp.Run("main", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := strings.Join(calls, ","), "helper,after helper,main"; got != want {
+		t.Errorf("recorded %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestDestructure(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	lit := func(v interface{}) ast.Expression {
+		return ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	arrayOf := func(elements ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprArrayLit, Elements: elements}
+	}
+	person := &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: lit("name"), Value: lit("Ann")}, {Key: lit("age"), Value: lit(41.0)}}}
+	join := func(names ...string) *ast.Statement {
+		expr := variable(names[0])
+		for _, name := range names[1:] {
+			expr = &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: expr, Right: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: &ast.Expression{Type: ast.ExprLiteral, Value: ","}, Right: variable(name)}}
+		}
+		return &ast.Statement{Type: ast.StmtReturn, Value: expr}
+	}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		want    string
+		wantErr string
+	}{
+		{
+			name: "array",
+			body: []ast.Statement{{Type: ast.StmtDestructure, Targets: []string{"a", "b"}, Value: arrayOf(lit("x"), lit("y"))}, *join("a", "b")},
+			want: "x,y",
+		},
+		{
+			name: "leading elements with a skip",
+			body: []ast.Statement{{Type: ast.StmtDestructure, Targets: []string{"_", "b"}, Value: arrayOf(lit("x"), lit("y"), lit("z"))}, *join("b")},
+			want: "y",
+		},
+		{
+			name: "map fields",
+			body: []ast.Statement{{Type: ast.StmtDestructure, Bindings: map[string]string{"name": "who", "age": "years"}, Value: person}, *join("who", "years")},
+			want: "Ann,41",
+		},
+		{
+			name: "source evaluated before binding",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "xs", Value: arrayOf(lit("x"), lit("y"))},
+				{Type: ast.StmtDestructure, Targets: []string{"xs", "b"}, Value: variable("xs")},
+				*join("xs", "b"),
+			},
+			want: "x,y",
+		},
+		{
+			name:    "too few elements",
+			body:    []ast.Statement{{Type: ast.StmtDestructure, Targets: []string{"a", "b"}, Value: arrayOf(lit("x"))}, *join("a")},
+			wantErr: "array has 1 elements, cannot bind 2 targets",
+		},
+		{
+			name:    "missing field",
+			body:    []ast.Statement{{Type: ast.StmtDestructure, Bindings: map[string]string{"email": "email"}, Value: person}, *join("email")},
+			wantErr: "cannot destructure missing field email",
+		},
+		{
+			name:    "not an array",
+			body:    []ast.Statement{{Type: ast.StmtDestructure, Targets: []string{"a"}, Value: person}, *join("a")},
+			wantErr: "cannot destructure map as an array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			if err := interp.LoadModule(&ast.Module{Type: "module", Name: "test_destructure", Functions: []ast.Function{
+				{Type: "function", Name: "main", Returns: ast.TypeString, Body: tt.body},
+			}}); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Run() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
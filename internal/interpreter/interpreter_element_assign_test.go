@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestElementAssignment(t *testing.T) {
+	lit := func(v interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	index := func(object, idx *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: idx}
+	}
+	field := func(object *ast.Expression, name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprField, Object: object, Field: name}
+	}
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	assignElement := func(lvalue, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Lvalue: lvalue, Value: value}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+	array := func(elements ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprArrayLit, Elements: elements}
+	}
+	mapLit := func(pairs ...ast.MapPair) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprMapLit, Pairs: pairs}
+	}
+
+	point := ast.TypeDefinition{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+		{Name: "x", Type: ast.TypeInt}, {Name: "y", Type: ast.TypeInt},
+	}}}
+	newPoint := mapLit(ast.MapPair{Key: *lit("x"), Value: *lit(1.0)}, ast.MapPair{Key: *lit("y"), Value: *lit(2.0)})
+
+	tests := []struct {
+		name    string
+		types   []ast.TypeDefinition
+		body    []ast.Statement
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name: "array element",
+			body: []ast.Statement{
+				assign("a", array(*lit(1.0), *lit(2.0), *lit(3.0))),
+				assignElement(index(variable("a"), lit(1.0)), lit(20.0)),
+				ret(index(variable("a"), lit(1.0))),
+			},
+			want: runtime.NewInt(20),
+		},
+		{
+			name: "array shared with another variable",
+			body: []ast.Statement{
+				assign("a", array(*lit(1.0), *lit(2.0))),
+				assign("b", variable("a")),
+				assignElement(index(variable("a"), lit(0.0)), lit(10.0)),
+				ret(index(variable("b"), lit(0.0))),
+			},
+			want: runtime.NewInt(10),
+		},
+		{
+			name: "new map entry",
+			body: []ast.Statement{
+				assign("m", mapLit()),
+				assignElement(index(variable("m"), lit("k")), lit("v")),
+				ret(index(variable("m"), lit("k"))),
+			},
+			want: runtime.NewString("v"),
+		},
+		{
+			name: "map field",
+			body: []ast.Statement{
+				assign("p", mapLit(ast.MapPair{Key: *lit("x"), Value: *lit(1.0)})),
+				assignElement(field(variable("p"), "x"), lit(5.0)),
+				ret(field(variable("p"), "x")),
+			},
+			want: runtime.NewInt(5),
+		},
+		{
+			name:  "struct copied by assignment",
+			types: []ast.TypeDefinition{point},
+			body: []ast.Statement{
+				assign("p", newPoint),
+				assign("q", variable("p")),
+				assignElement(field(variable("p"), "x"), lit(5.0)),
+				ret(&ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: field(variable("p"), "x"), Right: field(variable("q"), "x")}),
+			},
+			want: runtime.NewInt(6),
+		},
+		{
+			name:  "struct in an array element",
+			types: []ast.TypeDefinition{point},
+			body: []ast.Statement{
+				assign("a", array(*newPoint)),
+				assignElement(field(index(variable("a"), lit(0.0)), "y"), lit(9.0)),
+				ret(field(index(variable("a"), lit(0.0)), "y")),
+			},
+			want: runtime.NewInt(9),
+		},
+		{
+			name: "array nested in a map",
+			body: []ast.Statement{
+				assign("m", mapLit(ast.MapPair{Key: *lit("a"), Value: *array(*lit(0.0), *lit(0.0))})),
+				assignElement(index(index(variable("m"), lit("a")), lit(1.0)), lit(7.0)),
+				ret(index(index(variable("m"), lit("a")), lit(1.0))),
+			},
+			want: runtime.NewInt(7),
+		},
+		{
+			name: "index out of bounds",
+			body: []ast.Statement{
+				assign("a", array(*lit(1.0))),
+				assignElement(index(variable("a"), lit(3.0)), lit(0.0)),
+				ret(lit(0.0)),
+			},
+			wantErr: "array index out of bounds: 3",
+		},
+		{
+			name: "element of an int",
+			body: []ast.Statement{
+				assign("n", lit(1.0)),
+				assignElement(index(variable("n"), lit(0.0)), lit(0.0)),
+				ret(lit(0.0)),
+			},
+			wantErr: "cannot index into int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:  "module",
+				Name:  "test_element_assign",
+				Types: tt.types,
+				Functions: []ast.Function{
+					{Type: "function", Name: "main", Returns: ast.TypeInt, Body: tt.body},
+				},
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
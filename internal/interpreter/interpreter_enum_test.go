@@ -0,0 +1,282 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// shapeEnumType declares an enum whose variants carry payload data.
+func shapeEnumType() ast.TypeDefinition {
+	return ast.TypeDefinition{
+		Name: "Shape",
+		Definition: ast.TypeDefinitionDef{
+			Kind: ast.TypeKindEnum,
+			Variants: []ast.EnumVariant{
+				{Name: "Circle", Fields: []ast.TypeField{{Name: "radius", Type: "int"}}},
+				{Name: "Rect", Fields: []ast.TypeField{{Name: "w", Type: "int"}, {Name: "h", Type: "int"}}},
+				{Name: "Empty"},
+			},
+		},
+	}
+}
+
+func variantExpr(variant string, fields map[string]float64) *ast.Expression {
+	expr := &ast.Expression{Type: ast.ExprVariant, Enum: "Shape", Variant: variant}
+	for name, val := range fields {
+		expr.Pairs = append(expr.Pairs, ast.MapPair{
+			Key:   ast.Expression{Type: ast.ExprLiteral, Value: name},
+			Value: ast.Expression{Type: ast.ExprLiteral, Value: val},
+		})
+	}
+	return expr
+}
+
+// enumModule builds a module with an "area" function matching on a Shape
+// and a main function returning area(shape).
+func enumModule(shape *ast.Expression, cases []ast.MatchCase, def []ast.Statement) *ast.Module {
+	return &ast.Module{
+		Type:  "module",
+		Name:  "test_enum_data",
+		Types: []ast.TypeDefinition{shapeEnumType()},
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "area",
+				Params:  []ast.Parameter{{Name: "s", Type: "Shape"}},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:    ast.StmtMatch,
+						Value:   &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+						Cases:   cases,
+						Default: def,
+					},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body: []ast.Statement{
+					{
+						Type:  ast.StmtReturn,
+						Value: &ast.Expression{Type: ast.ExprCall, Name: "area", Args: []ast.Expression{*shape}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func returnBinary(op, left, right string) []ast.Statement {
+	return []ast.Statement{{
+		Type: ast.StmtReturn,
+		Value: &ast.Expression{
+			Type:  ast.ExprBinary,
+			Op:    op,
+			Left:  &ast.Expression{Type: ast.ExprVariable, Name: left},
+			Right: &ast.Expression{Type: ast.ExprVariable, Name: right},
+		},
+	}}
+}
+
+func returnLiteral(v float64) []ast.Statement {
+	return []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: v}}}
+}
+
+func TestEnumMatch(t *testing.T) {
+	allCases := []ast.MatchCase{
+		{Variant: "Circle", Bindings: map[string]string{"radius": "r"}, Body: returnBinary(ast.OpMul, "r", "r")},
+		{Variant: "Rect", Bindings: map[string]string{"w": "width", "h": "height"}, Body: returnBinary(ast.OpMul, "width", "height")},
+		{Variant: "Empty", Body: returnLiteral(0)},
+	}
+
+	tests := []struct {
+		name    string
+		shape   *ast.Expression
+		cases   []ast.MatchCase
+		def     []ast.Statement
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name:  "binds single payload field",
+			shape: variantExpr("Circle", map[string]float64{"radius": 3}),
+			cases: allCases,
+			want:  runtime.NewInt(9),
+		},
+		{
+			name:  "binds multiple payload fields",
+			shape: variantExpr("Rect", map[string]float64{"w": 2, "h": 5}),
+			cases: allCases,
+			want:  runtime.NewInt(10),
+		},
+		{
+			name:  "variant without payload",
+			shape: variantExpr("Empty", nil),
+			cases: allCases,
+			want:  runtime.NewInt(0),
+		},
+		{
+			name:  "falls through to default",
+			shape: variantExpr("Empty", nil),
+			cases: allCases[:1],
+			def:   returnLiteral(-1),
+			want:  runtime.NewInt(-1),
+		},
+		{
+			name:    "no matching case",
+			shape:   variantExpr("Empty", nil),
+			cases:   allCases[:1],
+			wantErr: "no match case for variant Empty",
+		},
+		{
+			name:    "missing payload field",
+			shape:   variantExpr("Rect", map[string]float64{"w": 2}),
+			cases:   allCases,
+			wantErr: "variant Shape.Rect missing field h",
+		},
+		{
+			name:    "unknown payload field",
+			shape:   variantExpr("Circle", map[string]float64{"radius": 1, "depth": 2}),
+			cases:   allCases,
+			wantErr: "variant Shape.Circle has no field depth",
+		},
+		{
+			name:    "unknown variant",
+			shape:   variantExpr("Triangle", nil),
+			cases:   allCases,
+			wantErr: "enum Shape has no variant Triangle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			if err := interp.LoadModule(enumModule(tt.shape, tt.cases, tt.def)); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumValueEquality(t *testing.T) {
+	interp := New()
+	a := runtime.NewEnum("Shape", "Circle", map[string]runtime.Value{"radius": runtime.NewInt(1)})
+	b := runtime.NewEnum("Shape", "Circle", map[string]runtime.Value{"radius": runtime.NewInt(1)})
+	c := runtime.NewEnum("Shape", "Circle", map[string]runtime.Value{"radius": runtime.NewInt(2)})
+	d := runtime.NewEnum("Shape", "Empty", nil)
+
+	if !interp.valuesEqual(a, b) {
+		t.Error("expected equal variants with equal payloads to be equal")
+	}
+	if interp.valuesEqual(a, c) {
+		t.Error("expected variants with different payloads to differ")
+	}
+	if interp.valuesEqual(a, d) {
+		t.Error("expected different variants to differ")
+	}
+}
+
+func TestEnumCastToInt(t *testing.T) {
+	castToInt := &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: &ast.Expression{Type: ast.ExprVariable, Name: "s"}}
+	module := enumModule(variantExpr("Rect", map[string]float64{"w": 3, "h": 4}), nil,
+		[]ast.Statement{{Type: ast.StmtReturn, Value: castToInt}})
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	got, err := interp.Run("main", []runtime.Value{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !valuesEqual(got, runtime.NewInt(1)) {
+		t.Errorf("Run() = %v, want the index of Rect, 1", got)
+	}
+}
+
+func TestEnumAliasVariant(t *testing.T) {
+	shape := variantExpr("Rect", map[string]float64{"w": 3, "h": 4})
+	shape.Enum = "Figure"
+	module := enumModule(shape, []ast.MatchCase{
+		{Variant: "Rect", Bindings: map[string]string{"w": "width", "h": "height"}, Body: returnBinary(ast.OpMul, "width", "height")},
+	}, returnLiteral(0))
+	module.Types = append(module.Types,
+		ast.TypeDefinition{Name: "Figure", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: "Polygon"}},
+		ast.TypeDefinition{Name: "Polygon", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: "Shape"}},
+	)
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	got, err := interp.Run("main", []runtime.Value{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !valuesEqual(got, runtime.NewInt(12)) {
+		t.Errorf("Run() = %v, want 12", got)
+	}
+}
+
+func TestEnumMatchBindingScope(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	literal := func(v float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+
+	// x = 5; total = 0
+	// match s { Circle{radius: x} => total = x }
+	// return x * 100 + total
+	module := enumModule(variantExpr("Circle", map[string]float64{"radius": 3}), nil, nil)
+	module.Functions[0].Body = []ast.Statement{
+		{Type: ast.StmtAssign, Target: "x", Value: literal(5)},
+		{Type: ast.StmtAssign, Target: "total", Value: literal(0)},
+		{
+			Type:  ast.StmtMatch,
+			Value: variable("s"),
+			Cases: []ast.MatchCase{{
+				Variant:  "Circle",
+				Bindings: map[string]string{"radius": "x"},
+				Body:     []ast.Statement{{Type: ast.StmtAssign, Target: "total", Value: variable("x")}},
+			}},
+		},
+		{Type: ast.StmtReturn, Value: &ast.Expression{
+			Type:  ast.ExprBinary,
+			Op:    ast.OpAdd,
+			Left:  &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: variable("x"), Right: literal(100)},
+			Right: variable("total"),
+		}},
+	}
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	got, err := interp.Run("main", []runtime.Value{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// The binding shadows x only inside the case, while the assignment to
+	// total updates the outer variable
+	if want := runtime.NewInt(503); !valuesEqual(got, want) {
+		t.Errorf("Run() = %v, want %v", got, want)
+	}
+}
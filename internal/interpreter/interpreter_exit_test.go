@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestExitBuiltin(t *testing.T) {
+	exit := func(code float64) ast.Statement {
+		return ast.Statement{Type: ast.StmtExpr, Value: &ast.Expression{
+			Type: ast.ExprBuiltin, Name: "os.exit", Args: []ast.Expression{{Type: ast.ExprLiteral, Value: code}},
+		}}
+	}
+	record := func(text string) *ast.Expression {
+		return recordCall(&ast.Expression{Type: ast.ExprLiteral, Value: text})
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "test_exit",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtDefer, Value: record("main")},
+					{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprCall, Name: "helper"}},
+					{Type: ast.StmtExpr, Value: record("after helper")},
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "helper",
+				Returns: ast.TypeVoid,
+				Body: []ast.Statement{
+					{Type: ast.StmtDefer, Value: record("helper")},
+					exit(3),
+				},
+			},
+		},
+	}
+
+	interp := New()
+	var calls []string
+	interp.RegisterBuiltin("test.record", func(args []runtime.Value) (runtime.Value, error) {
+		calls = append(calls, args[0].String())
+		return runtime.NewVoid(), nil
+	})
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	_, err := interp.Run("main", nil)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Run() error = %v, want *ExitError", err)
+	}
+	if exitErr.Code != 3 {
+		t.Errorf("exit code = %d, want 3", exitErr.Code)
+	}
+	if len(calls) != 0 {
+		t.Errorf("recorded %v after os.exit, want deferred expressions and later statements skipped", calls)
+	}
+}
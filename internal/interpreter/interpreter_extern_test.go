@@ -0,0 +1,94 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestExternFunctions(t *testing.T) {
+	t.Setenv("ALAS_EXTERN_TEST", "from C")
+	param := func(name, typ string) ast.Parameter { return ast.Parameter{Name: name, Type: typ} }
+	module := &ast.Module{
+		Type: "module",
+		Name: "ffi",
+		Functions: []ast.Function{
+			{Type: "function", Name: "abs", Extern: "labs", Params: []ast.Parameter{param("n", ast.TypeInt)}, Returns: ast.TypeInt},
+			{Type: "function", Name: "length", Extern: "strlen", Params: []ast.Parameter{param("s", ast.TypeString)}, Returns: ast.TypeInt},
+			{Type: "function", Name: "getenv", Extern: "getenv", Params: []ast.Parameter{param("name", ast.TypeString)}, Returns: ast.TypeString},
+			{Type: "function", Name: "copysign", Extern: "copysign", Params: []ast.Parameter{param("x", ast.TypeFloat), param("y", ast.TypeFloat)}, Returns: ast.TypeFloat},
+			{Type: "function", Name: "ldexp", Extern: "ldexp", Params: []ast.Parameter{param("x", ast.TypeFloat), param("e", ast.TypeInt)}, Returns: ast.TypeFloat},
+			{Type: "function", Name: "missing", Extern: "alas_no_such_symbol", Params: []ast.Parameter{}, Returns: ast.TypeVoid},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    interface{}
+		wantErr string
+	}{
+		{name: "int", fn: "abs", args: []runtime.Value{runtime.NewInt(-7)}, want: int64(7)},
+		{name: "string argument", fn: "length", args: []runtime.Value{runtime.NewString("hello")}, want: int64(5)},
+		{name: "string result", fn: "getenv", args: []runtime.Value{runtime.NewString("ALAS_EXTERN_TEST")}, want: "from C"},
+		{name: "null string result", fn: "getenv", args: []runtime.Value{runtime.NewString("ALAS_EXTERN_UNSET")}, want: ""},
+		{name: "floats", fn: "copysign", args: []runtime.Value{runtime.NewFloat(3), runtime.NewFloat(-1)}, want: -3.0},
+		{name: "mixed signature", fn: "ldexp", args: []runtime.Value{runtime.NewFloat(1), runtime.NewInt(3)}, wantErr: "cannot call C functions that mix float and non-float values"},
+		{name: "missing symbol", fn: "missing", wantErr: "C symbol alas_no_such_symbol not found"},
+	}
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interp.Run(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run(%s) error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run(%s) error = %v", tt.fn, err)
+			}
+			if got.Value != tt.want {
+				t.Errorf("Run(%s) = %v, want %v", tt.fn, got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternFunctionsNeedNativeCapability(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "plugin_ffi",
+		Functions: []ast.Function{
+			{Type: "function", Name: "abs", Extern: "labs", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: ast.TypeInt},
+		},
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	args := []runtime.Value{runtime.NewInt(-2)}
+
+	interp.RestrictModule("plugin_ffi", []string{CapabilityIO})
+	_, err := interp.Run("abs", args)
+	var capErr *CapabilityError
+	if !errors.As(err, &capErr) || capErr.Capability != CapabilityNative {
+		t.Fatalf("Run() error = %v, want a missing native capability", err)
+	}
+
+	interp.RestrictModule("plugin_ffi", []string{CapabilityNative})
+	if got, err := interp.Run("abs", args); err != nil || got.Value != int64(2) {
+		t.Errorf("Run() = %v, %v, want 2", got.Value, err)
+	}
+}
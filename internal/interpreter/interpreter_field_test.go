@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+	"github.com/dshills/alas/internal/validator"
+)
+
+func TestFieldAccessOnReturnedStruct(t *testing.T) {
+	v := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	field := func(object *ast.Expression, name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprField, Object: object, Field: name}
+	}
+	makePoint := &ast.Expression{Type: ast.ExprCall, Name: "makePoint", Args: []ast.Expression{
+		{Type: ast.ExprLiteral, Value: 3.0},
+		{Type: ast.ExprLiteral, Value: 4.0},
+	}}
+
+	tests := []struct {
+		name   string
+		body   []ast.Statement
+		want   runtime.Value
+		errMsg string
+	}{
+		{
+			name: "directly on the call",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: field(makePoint, "y")}},
+			want: runtime.NewInt(4),
+		},
+		{
+			name: "after storing in a variable",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "p", Value: makePoint},
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: field(v("p"), "x"), Right: field(v("p"), "y")}},
+			},
+			want: runtime.NewInt(7),
+		},
+		{
+			name:   "missing field",
+			body:   []ast.Statement{{Type: ast.StmtReturn, Value: field(makePoint, "z")}},
+			errMsg: "field not found: z (available fields: x, y)",
+		},
+		{
+			name: "non-struct object",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "n", Value: field(makePoint, "x")},
+				{Type: ast.StmtReturn, Value: field(v("n"), "x")},
+			},
+			errMsg: "cannot access field 'x' on int value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "points",
+				Types: []ast.TypeDefinition{{Name: "Point", Definition: ast.TypeDefinitionDef{
+					Kind:   ast.TypeKindStruct,
+					Fields: []ast.TypeField{{Name: "x", Type: ast.TypeInt}, {Name: "y", Type: ast.TypeInt}},
+				}}},
+				Functions: []ast.Function{
+					{
+						Type:    "function",
+						Name:    "makePoint",
+						Params:  []ast.Parameter{{Name: "x", Type: ast.TypeInt}, {Name: "y", Type: ast.TypeInt}},
+						Returns: "Point",
+						Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+							{Key: ast.Expression{Type: ast.ExprLiteral, Value: "x"}, Value: *v("x")},
+							{Key: ast.Expression{Type: ast.ExprLiteral, Value: "y"}, Value: *v("y")},
+						}}}},
+					},
+					{Type: "function", Name: "main", Params: []ast.Parameter{}, Returns: ast.TypeInt, Body: tt.body},
+				},
+			}
+
+			// Field access is checked at runtime, so every case validates
+			if err := validator.New().ValidateModule(module); err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("main", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Run() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// floatLit returns a float literal, using the special spelling for NaN and
+// infinities.
+func floatLit(value interface{}) *ast.Expression {
+	return &ast.Expression{Type: ast.ExprLiteral, Value: value, To: ast.TypeFloat}
+}
+
+func TestSpecialFloatLiterals(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		check func(float64) bool
+	}{
+		{name: "NaN", value: ast.FloatNaN, check: math.IsNaN},
+		{name: "Infinity", value: ast.FloatInfinity, check: func(f float64) bool { return math.IsInf(f, 1) }},
+		{name: "-Infinity", value: ast.FloatNegInfinity, check: func(f float64) bool { return math.IsInf(f, -1) }},
+		{name: "integral float", value: 2.0, check: func(f float64) bool { return f == 2 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFloatMain(t, ast.TypeFloat, floatLit(tt.value))
+			f, err := got.AsFloat()
+			if err != nil || got.Type != runtime.ValueTypeFloat || !tt.check(f) {
+				t.Errorf("Run() = %v, want %s", got, tt.name)
+			}
+		})
+	}
+}
+
+func TestFloatComparisonsFollowIEEE(t *testing.T) {
+	tests := []struct {
+		op          string
+		left, right interface{}
+		want        bool
+	}{
+		{op: ast.OpEq, left: ast.FloatNaN, right: ast.FloatNaN, want: false},
+		{op: ast.OpNe, left: ast.FloatNaN, right: ast.FloatNaN, want: true},
+		{op: ast.OpLt, left: ast.FloatNaN, right: 1.5, want: false},
+		{op: ast.OpLe, left: ast.FloatNaN, right: ast.FloatNaN, want: false},
+		{op: ast.OpGt, left: 1.5, right: ast.FloatNaN, want: false},
+		{op: ast.OpGe, left: ast.FloatNaN, right: 1.5, want: false},
+		{op: ast.OpEq, left: ast.FloatInfinity, right: ast.FloatInfinity, want: true},
+		{op: ast.OpGt, left: ast.FloatInfinity, right: 1e308, want: true},
+		{op: ast.OpLt, left: ast.FloatNegInfinity, right: -1e308, want: true},
+		{op: ast.OpGe, left: ast.FloatNegInfinity, right: ast.FloatInfinity, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			got := runFloatMain(t, ast.TypeBool, &ast.Expression{
+				Type:  ast.ExprBinary,
+				Op:    tt.op,
+				Left:  floatLit(tt.left),
+				Right: floatLit(tt.right),
+			})
+			if !valuesEqual(got, runtime.NewBool(tt.want)) {
+				t.Errorf("%v %s %v = %v, want %v", tt.left, tt.op, tt.right, got, tt.want)
+			}
+		})
+	}
+}
+
+// runFloatMain runs a main function that returns expr.
+func runFloatMain(t *testing.T, returns string, expr *ast.Expression) runtime.Value {
+	t.Helper()
+	module := &ast.Module{
+		Type: "module",
+		Name: "test_float",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: returns,
+				Body:    []ast.Statement{{Type: ast.StmtReturn, Value: expr}},
+			},
+		},
+	}
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	got, err := interp.Run("main", []runtime.Value{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return got
+}
+
+func TestFloatLiteralDivision(t *testing.T) {
+	tests := []struct {
+		left, right string
+		want        runtime.Value
+	}{
+		{left: "22.0", right: "7.0", want: runtime.NewFloat(22.0 / 7.0)},
+		{left: "22.0", right: "7", want: runtime.NewFloat(22.0 / 7.0)},
+		{left: "22", right: "7", want: runtime.NewInt(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.left+"/"+tt.right, func(t *testing.T) {
+			data := []byte(`{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [], "returns": "float",
+				"body": [{"type": "return", "value": {"type": "binary", "op": "/",
+					"left": {"type": "literal", "value": ` + tt.left + `}, "right": {"type": "literal", "value": ` + tt.right + `}}}]}]}`)
+			module, err := ast.ParseModule(data, "")
+			if err != nil {
+				t.Fatalf("ParseModule() error = %v", err)
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("main", []runtime.Value{})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
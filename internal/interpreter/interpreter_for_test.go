@@ -0,0 +1,69 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+	"github.com/dshills/alas/internal/validator"
+)
+
+func TestForLoopInitAndUpdate(t *testing.T) {
+	v := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(n float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: n} }
+	add := func(left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: left, Right: right}
+	}
+
+	tests := []struct {
+		name string
+		n    int64
+		want runtime.Value
+	}{
+		{name: "several iterations", n: 5, want: runtime.NewInt(10)},
+		{name: "condition false after init", n: 0, want: runtime.NewInt(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// total = 0; for (i = 0; i < n; i = i + 1) { total = total + i }
+			body := []ast.Statement{
+				{Type: ast.StmtAssign, Target: "total", Value: lit(0)},
+				{
+					Type:   ast.StmtFor,
+					Init:   []ast.Statement{{Type: ast.StmtAssign, Target: "i", Value: lit(0)}},
+					Cond:   &ast.Expression{Type: ast.ExprBinary, Op: ast.OpLt, Left: v("i"), Right: v("n")},
+					Update: []ast.Statement{{Type: ast.StmtAssign, Target: "i", Value: add(v("i"), lit(1))}},
+					Body:   []ast.Statement{{Type: ast.StmtAssign, Target: "total", Value: add(v("total"), v("i"))}},
+				},
+				{Type: ast.StmtReturn, Value: v("total")},
+			}
+			module := &ast.Module{
+				Type: "module",
+				Name: "loops",
+				Functions: []ast.Function{{
+					Type:    "function",
+					Name:    "sum",
+					Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+					Returns: ast.TypeInt,
+					Body:    body,
+				}},
+			}
+
+			if err := validator.New().ValidateModule(module); err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("sum", []runtime.Value{runtime.NewInt(tt.n)})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
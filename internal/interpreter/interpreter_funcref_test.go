@@ -0,0 +1,165 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestFunctionReferences(t *testing.T) {
+	variable := func(name string) ast.Expression {
+		return ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	literal := func(v interface{}) ast.Expression {
+		return ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	funcRef := func(name string) ast.Expression {
+		return ast.Expression{Type: ast.ExprFuncRef, Name: name}
+	}
+	builtin := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: args}
+	}
+	ints := func(values ...int64) runtime.Value {
+		arr := make([]runtime.Value, len(values))
+		for i, v := range values {
+			arr[i] = runtime.NewInt(v)
+		}
+		return runtime.NewArray(arr)
+	}
+	numbers := ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{literal(1), literal(2), literal(3), literal(4)}}
+
+	helpers := []ast.Function{
+		{
+			Type: "function", Name: "square", Params: []ast.Parameter{{Name: "x", Type: ast.TypeInt}}, Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBinary, Op: ast.OpMul, Left: &ast.Expression{Type: ast.ExprVariable, Name: "x"}, Right: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			}}},
+		},
+		{
+			Type: "function", Name: "is_even", Params: []ast.Parameter{{Name: "x", Type: ast.TypeInt}}, Returns: ast.TypeBool,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBinary, Op: ast.OpEq,
+				Left:  &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMod, Left: &ast.Expression{Type: ast.ExprVariable, Name: "x"}, Right: &ast.Expression{Type: ast.ExprLiteral, Value: 2}},
+				Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0},
+			}}},
+		},
+		{
+			Type: "function", Name: "add", Params: []ast.Parameter{{Name: "a", Type: ast.TypeInt}, {Name: "b", Type: ast.TypeInt}}, Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBinary, Op: ast.OpAdd, Left: &ast.Expression{Type: ast.ExprVariable, Name: "a"}, Right: &ast.Expression{Type: ast.ExprVariable, Name: "b"},
+			}}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		returns string
+		body    []ast.Statement
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name:    "call through reference",
+			returns: ast.TypeInt,
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "f", Value: &ast.Expression{Type: ast.ExprFuncRef, Name: "add"}},
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprCall, Name: "f", Args: []ast.Expression{literal(2), literal(5)}}},
+			},
+			want: runtime.NewInt(7),
+		},
+		{
+			name:    "map with named function",
+			returns: ast.TypeArray,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("collections.map", numbers, funcRef("square"))}},
+			want:    ints(1, 4, 9, 16),
+		},
+		{
+			name:    "filter with named function",
+			returns: ast.TypeArray,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("collections.filter", numbers, funcRef("is_even"))}},
+			want:    ints(2, 4),
+		},
+		{
+			name:    "map with capturing lambda",
+			returns: ast.TypeArray,
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "offset", Value: &ast.Expression{Type: ast.ExprLiteral, Value: 10}},
+				{Type: ast.StmtReturn, Value: builtin("collections.map", numbers, ast.Expression{
+					Type:   ast.ExprLambda,
+					Params: []ast.Parameter{{Name: "x", Type: ast.TypeInt}},
+					Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+						Type: ast.ExprBinary, Op: ast.OpAdd, Left: &ast.Expression{Type: ast.ExprVariable, Name: "x"}, Right: &ast.Expression{Type: ast.ExprVariable, Name: "offset"},
+					}}},
+				})},
+			},
+			want: ints(11, 12, 13, 14),
+		},
+		{
+			name:    "function value reports its name",
+			returns: ast.TypeString,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("type.toString", funcRef("square"))}},
+			want:    runtime.NewString("<function square>"),
+		},
+		{
+			name:    "map rejects wrong arity",
+			returns: ast.TypeArray,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("collections.map", numbers, funcRef("add"))}},
+			wantErr: "collections.map: function argument must take 1 parameters, takes 2",
+		},
+		{
+			name:    "filter requires bool predicate",
+			returns: ast.TypeArray,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("collections.filter", numbers, funcRef("square"))}},
+			wantErr: "collections.filter: predicate must return a bool",
+		},
+		{
+			name:    "map requires a function",
+			returns: ast.TypeArray,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: builtin("collections.map", numbers, variable("missing"))}},
+			wantErr: "undefined variable: missing",
+		},
+		{
+			name:    "unknown function",
+			returns: ast.TypeInt,
+			body:    []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprFuncRef, Name: "nope"}}},
+			wantErr: "function 'nope' not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_funcref",
+				Functions: append([]ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Params:  []ast.Parameter{},
+					Returns: tt.returns,
+					Body:    tt.body,
+				}}, helpers...),
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got.String() != tt.want.String() {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
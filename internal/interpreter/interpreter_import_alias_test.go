@@ -0,0 +1,66 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+func TestImportAlias(t *testing.T) {
+	loader := mapLoader{
+		"geometry.numbers": constModule("numbers", 1, nil, "value"),
+		"art.numbers":      constModule("art_numbers", 2, nil, "value"),
+	}
+	call := func(module string) ast.Expression {
+		return ast.Expression{Type: ast.ExprModuleCall, Module: module, Name: "value", Args: []ast.Expression{}}
+	}
+	app := func(imports ...ast.Import) *ast.Module {
+		left, right := call("geo"), call("art")
+		return &ast.Module{
+			Type:    "module",
+			Name:    "app",
+			Imports: imports,
+			Functions: []ast.Function{{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{{
+					Type:  ast.StmtReturn,
+					Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: &left, Right: &right},
+				}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		preload string // module loaded before app, if any
+	}{
+		{name: "aliases of newly loaded modules"},
+		{name: "alias of an already loaded module", preload: "geometry.numbers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := NewWithLoader(loader)
+			if tt.preload != "" {
+				if err := interp.LoadModule(&ast.Module{Type: "module", Name: "pre", Imports: []ast.Import{{Module: tt.preload}}}); err != nil {
+					t.Fatalf("LoadModule(pre) error = %v", err)
+				}
+			}
+			module := app(ast.Import{Module: "geometry.numbers", Alias: "geo"}, ast.Import{Module: "art.numbers", Alias: "art"})
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got.Value != int64(3) {
+				t.Errorf("Run() = %v, want 3", got.Value)
+			}
+		})
+	}
+}
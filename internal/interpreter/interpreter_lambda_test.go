@@ -0,0 +1,141 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestLambdaClosures(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	literal := func(v interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	add := func(left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: left, Right: right}
+	}
+	call := func(name string, args ...*ast.Expression) *ast.Expression {
+		expr := &ast.Expression{Type: ast.ExprCall, Name: name, Args: []ast.Expression{}}
+		for _, arg := range args {
+			expr.Args = append(expr.Args, *arg)
+		}
+		return expr
+	}
+	lambda := func(params []string, body ...ast.Statement) *ast.Expression {
+		expr := &ast.Expression{Type: ast.ExprLambda, Returns: ast.TypeInt, Body: body}
+		for _, name := range params {
+			expr.Params = append(expr.Params, ast.Parameter{Name: name, Type: ast.TypeInt})
+		}
+		return expr
+	}
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		helpers []ast.Function
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name: "call lambda with arguments",
+			body: []ast.Statement{
+				assign("add", lambda([]string{"a", "b"}, ret(add(variable("a"), variable("b"))))),
+				ret(call("add", literal(2), literal(3))),
+			},
+			want: runtime.NewInt(5),
+		},
+		{
+			name: "captures enclosing variables by reference",
+			body: []ast.Statement{
+				assign("base", literal(10)),
+				assign("addBase", lambda([]string{"x"}, ret(add(variable("x"), variable("base"))))),
+				assign("base", literal(100)),
+				ret(call("addBase", literal(1))),
+			},
+			want: runtime.NewInt(101),
+		},
+		{
+			name: "lambda returned from a function keeps its environment",
+			helpers: []ast.Function{{
+				Type:    "function",
+				Name:    "make_adder",
+				Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+				Returns: ast.TypeFunc,
+				Body:    []ast.Statement{ret(lambda([]string{"x"}, ret(add(variable("x"), variable("n")))))},
+			}},
+			body: []ast.Statement{
+				assign("add5", call("make_adder", literal(5))),
+				ret(call("add5", literal(7))),
+			},
+			want: runtime.NewInt(12),
+		},
+		{
+			name: "variable shadows function of the same name",
+			helpers: []ast.Function{{
+				Type:    "function",
+				Name:    "double",
+				Params:  []ast.Parameter{{Name: "x", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body:    []ast.Statement{ret(add(variable("x"), variable("x")))},
+			}},
+			body: []ast.Statement{
+				assign("double", lambda([]string{"x"}, ret(literal(0)))),
+				ret(call("double", literal(4))),
+			},
+			want: runtime.NewInt(0),
+		},
+		{
+			name: "wrong argument count",
+			body: []ast.Statement{
+				assign("f", lambda([]string{"x"}, ret(variable("x")))),
+				ret(call("f")),
+			},
+			wantErr: "lambda expects 1 arguments, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_lambda",
+				Functions: append([]ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Params:  []ast.Parameter{},
+					Returns: ast.TypeInt,
+					Body:    tt.body,
+				}}, tt.helpers...),
+			}
+
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestLenient(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(v interface{}) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: v} }
+	binary := func(op string, left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: op, Left: left, Right: right}
+	}
+	index := func(object, idx *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: idx}
+	}
+	xs := &ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{*lit(1.0), *lit(2.0)}}
+	m := &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: *lit("a"), Value: *lit(1.0)}}}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		lenient Lenient
+		want    string
+		wantErr string
+	}{
+		{name: "int division", body: []ast.Statement{{Type: ast.StmtReturn, Value: binary(ast.OpDiv, variable("n"), lit(0.0))}}, lenient: LenientDivision, want: "0", wantErr: "division by zero"},
+		{name: "int modulo", body: []ast.Statement{{Type: ast.StmtReturn, Value: binary(ast.OpMod, variable("n"), lit(0.0))}}, lenient: LenientDivision, want: "0", wantErr: "modulo by zero"},
+		{name: "bigint division", body: []ast.Statement{{Type: ast.StmtReturn, Value: binary(ast.OpDiv, lit(new(big.Int).Lsh(big.NewInt(1), 70)), lit(0.0))}}, lenient: LenientDivision, want: "0", wantErr: "division by zero"},
+		{name: "float division", body: []ast.Statement{{Type: ast.StmtReturn, Value: binary(ast.OpDiv, lit(-1.5), lit(0.0))}}, lenient: LenientDivision, want: runtime.NewFloat(math.Inf(-1)).String(), wantErr: "division by zero"},
+		{name: "array read", body: []ast.Statement{{Type: ast.StmtReturn, Value: index(xs, lit(5.0))}}, lenient: LenientBounds, want: "void", wantErr: "array index out of bounds: 5"},
+		{
+			name: "array write",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "xs", Value: xs},
+				{Type: ast.StmtAssign, Lvalue: index(variable("xs"), lit(-1.0)), Value: lit(9.0)},
+				{Type: ast.StmtReturn, Value: index(variable("xs"), lit(1.0))},
+			},
+			lenient: LenientBounds,
+			want:    "2",
+			wantErr: "array index out of bounds: -1",
+		},
+		{name: "missing map key", body: []ast.Statement{{Type: ast.StmtReturn, Value: index(m, lit("b"))}}, lenient: LenientMissingKeys, want: "void", wantErr: "map key not found: b"},
+		{name: "other toggles", body: []ast.Statement{{Type: ast.StmtReturn, Value: index(m, lit("b"))}}, lenient: LenientDivision | LenientBounds, wantErr: "map key not found: b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "test_lenient", Functions: []ast.Function{
+				{Type: "function", Name: "main", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Body: tt.body},
+			}}
+			args := []runtime.Value{runtime.NewInt(7)}
+
+			strict := New()
+			if err := strict.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			if _, err := strict.Run("main", args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want error containing %q", err, tt.wantErr)
+			}
+
+			lenient := New()
+			lenient.SetLenient(tt.lenient)
+			if err := lenient.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := lenient.Run("main", args)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("lenient Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lenient Run() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("lenient Run() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
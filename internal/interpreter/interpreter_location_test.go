@@ -0,0 +1,111 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestRuntimeErrorLocation(t *testing.T) {
+	data := []byte(`{
+  "type": "module",
+  "name": "test",
+  "functions": [
+    {
+      "type": "function",
+      "name": "divide",
+      "params": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}],
+      "returns": "int",
+      "body": [
+        {
+          "type": "return",
+          "value": {
+            "type": "binary",
+            "op": "/",
+            "left": {"type": "variable", "name": "a"},
+            "right": {"type": "variable", "name": "b"}
+          }
+        }
+      ]
+    },
+    {
+      "type": "function",
+      "name": "main",
+      "params": [],
+      "returns": "int",
+      "body": [
+        {
+          "type": "return",
+          "value": {
+            "type": "call",
+            "name": "divide",
+            "args": [{"type": "literal", "value": 1}, {"type": "literal", "value": 0}]
+          }
+        }
+      ]
+    }
+  ]
+}`)
+
+	module, err := ast.ParseModule(data, "divide.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	_, err = interp.Run("main", []runtime.Value{})
+	if err == nil {
+		t.Fatal("expected division by zero error")
+	}
+
+	// The innermost failing node (the division inside divide) is reported.
+	var located *RuntimeError
+	if !errors.As(err, &located) {
+		t.Fatalf("expected RuntimeError in chain, got %v", err)
+	}
+	if located.File != "divide.alas.json" || located.Line != 13 || located.Column != 20 {
+		t.Errorf("location = %s:%d:%d, want divide.alas.json:13:20", located.File, located.Line, located.Column)
+	}
+	if !strings.Contains(err.Error(), "divide.alas.json:13:20: division by zero") {
+		t.Errorf("error = %q, want location-prefixed division by zero", err.Error())
+	}
+}
+
+func TestRuntimeErrorWithoutLocation(t *testing.T) {
+	module := &ast.Module{
+		Type: "module",
+		Name: "test",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "void",
+				Body: []ast.Statement{
+					{Type: ast.StmtAssert, Cond: &ast.Expression{Type: ast.ExprLiteral, Value: false}},
+				},
+			},
+		},
+	}
+
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	_, err := interp.Run("main", []runtime.Value{})
+	if err == nil {
+		t.Fatal("expected assertion error")
+	}
+	var located *RuntimeError
+	if errors.As(err, &located) {
+		t.Errorf("unexpected location on error from hand-built AST: %v", err)
+	}
+}
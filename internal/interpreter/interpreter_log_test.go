@@ -0,0 +1,55 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+	"github.com/dshills/alas/internal/stdlib"
+)
+
+func TestLogBuiltins(t *testing.T) {
+	logCall := func(name, message string) ast.Statement {
+		return ast.Statement{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: []ast.Expression{{Type: ast.ExprLiteral, Value: message}}}}
+	}
+	module := &ast.Module{Type: "module", Name: "test_log", Functions: []ast.Function{
+		{Type: "function", Name: "main", Returns: ast.TypeVoid, Body: []ast.Statement{
+			logCall("log.debug", "starting"),
+			{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprCall, Name: "work"}},
+			logCall("log.info", "done"),
+		}},
+		{Type: "function", Name: "work", Returns: ast.TypeVoid, Body: []ast.Statement{
+			logCall("log.warn", "slow"),
+			logCall("log.error", "failed"),
+		}},
+	}}
+
+	tests := []struct {
+		name  string
+		level stdlib.LogLevel
+		want  string
+	}{
+		{name: "default level", level: stdlib.LogInfo, want: "[WARN] work: slow\n[ERROR] work: failed\n[INFO] main: done\n"},
+		{name: "debug", level: stdlib.LogDebug, want: "[DEBUG] main: starting\n[WARN] work: slow\n[ERROR] work: failed\n[INFO] main: done\n"},
+		{name: "errors only", level: stdlib.LogError, want: "[ERROR] work: failed\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			var out strings.Builder
+			interp.SetLogOutput(&out)
+			interp.SetLogLevel(tt.level)
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			if _, err := interp.Run("main", []runtime.Value{}); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("log output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestMapKeys(t *testing.T) {
+	lit := func(v interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	index := func(key *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: variable("m"), Index: key}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+	squares := ast.Statement{Type: ast.StmtAssign, Target: "m", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+		{Key: *lit(2.0), Value: *lit(4.0)},
+		{Key: *lit(3.0), Value: *lit(9.0)},
+		{Key: *lit(true), Value: *lit("yes")},
+	}}}
+
+	mixed := ast.Statement{Type: ast.StmtAssign, Target: "m", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+		{Key: *lit(1.0), Value: *lit("a")},
+		{Key: *lit("1"), Value: *lit("b")},
+		{Key: *lit(true), Value: *lit("c")},
+		{Key: *lit("true"), Value: *lit("d")},
+	}}}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name: "int key",
+			body: []ast.Statement{squares, ret(index(lit(3.0)))},
+			want: runtime.NewInt(9),
+		},
+		{
+			name: "computed int key",
+			body: []ast.Statement{squares, ret(index(&ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: lit(1.0), Right: lit(1.0)}))},
+			want: runtime.NewInt(4),
+		},
+		{
+			name: "bool key",
+			body: []ast.Statement{squares, ret(index(lit(true)))},
+			want: runtime.NewString("yes"),
+		},
+		{
+			name: "assign to an int key",
+			body: []ast.Statement{
+				squares,
+				{Type: ast.StmtAssign, Lvalue: index(lit(4.0)), Value: lit(16.0)},
+				ret(index(lit(4.0))),
+			},
+			want: runtime.NewInt(16),
+		},
+		{
+			name: "int key apart from its string form",
+			body: []ast.Statement{mixed, ret(index(lit(1.0)))},
+			want: runtime.NewString("a"),
+		},
+		{
+			name: "string key apart from the int it spells",
+			body: []ast.Statement{mixed, ret(index(lit("1")))},
+			want: runtime.NewString("b"),
+		},
+		{
+			name: "bool key apart from its string form",
+			body: []ast.Statement{mixed, ret(index(lit("true")))},
+			want: runtime.NewString("d"),
+		},
+		{
+			name: "every mixed key kept",
+			body: []ast.Statement{mixed, ret(&ast.Expression{Type: ast.ExprBuiltin, Name: "collections.length", Args: []ast.Expression{*variable("m")}})},
+			want: runtime.NewInt(4),
+		},
+		{
+			name:    "missing int key",
+			body:    []ast.Statement{squares, ret(index(lit(5.0)))},
+			wantErr: "map key not found: 5",
+		},
+		{
+			name:    "float key",
+			body:    []ast.Statement{squares, ret(index(lit(2.5)))},
+			wantErr: "map keys must be int, bool or string, got float",
+		},
+		{
+			name: "array key in a literal",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "m", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+					{Key: ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{}}, Value: *lit(1.0)},
+				}}},
+				ret(lit(0.0)),
+			},
+			wantErr: "map keys must be int, bool or string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			if err := interp.LoadModule(&ast.Module{Type: "module", Name: "test_map_keys", Functions: []ast.Function{
+				{Type: "function", Name: "main", Body: tt.body},
+			}}); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got.Type != tt.want.Type || got.String() != tt.want.String() {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
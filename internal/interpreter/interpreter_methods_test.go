@@ -0,0 +1,180 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// shapeModule declares two struct types that both define an "area" method,
+// plus a "scale" method that takes an extra argument.
+func shapeModule(body []ast.Statement) *ast.Module {
+	fieldOf := func(obj, field string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprField, Object: &ast.Expression{Type: ast.ExprVariable, Name: obj}, Field: field}
+	}
+	return &ast.Module{
+		Type: "module",
+		Name: "shapes",
+		Types: []ast.TypeDefinition{
+			{
+				Name: "Rect",
+				Definition: ast.TypeDefinitionDef{
+					Kind:   ast.TypeKindStruct,
+					Fields: []ast.TypeField{{Name: "w", Type: "int"}, {Name: "h", Type: "int"}},
+				},
+			},
+			{
+				Name: "Square",
+				Definition: ast.TypeDefinitionDef{
+					Kind:   ast.TypeKindStruct,
+					Fields: []ast.TypeField{{Name: "side", Type: "int"}},
+				},
+			},
+		},
+		Functions: []ast.Function{
+			{
+				Type:     "function",
+				Name:     "area",
+				Receiver: &ast.Parameter{Name: "r", Type: "Rect"},
+				Params:   []ast.Parameter{},
+				Returns:  "int",
+				Body: []ast.Statement{
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: fieldOf("r", "w"), Right: fieldOf("r", "h")}},
+				},
+			},
+			{
+				Type:     "function",
+				Name:     "area",
+				Receiver: &ast.Parameter{Name: "s", Type: "Square"},
+				Params:   []ast.Parameter{},
+				Returns:  "int",
+				Body: []ast.Statement{
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: fieldOf("s", "side"), Right: fieldOf("s", "side")}},
+				},
+			},
+			{
+				Type:     "function",
+				Name:     "scaled",
+				Receiver: &ast.Parameter{Name: "s", Type: "Square"},
+				Params:   []ast.Parameter{{Name: "k", Type: "int"}},
+				Returns:  "int",
+				Body: []ast.Statement{
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: fieldOf("s", "side"), Right: &ast.Expression{Type: ast.ExprVariable, Name: "k"}}},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{},
+				Returns: "int",
+				Body:    body,
+			},
+		},
+	}
+}
+
+func structLiteral(fields map[string]float64) *ast.Expression {
+	expr := &ast.Expression{Type: ast.ExprMapLit}
+	for name, val := range fields {
+		expr.Pairs = append(expr.Pairs, ast.MapPair{
+			Key:   ast.Expression{Type: ast.ExprLiteral, Value: name},
+			Value: ast.Expression{Type: ast.ExprLiteral, Value: val},
+		})
+	}
+	return expr
+}
+
+func methodCall(receiver *ast.Expression, name string, args ...ast.Expression) *ast.Expression {
+	return &ast.Expression{Type: ast.ExprMethodCall, Object: receiver, Name: name, Args: args}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    *ast.Expression
+		want    runtime.Value
+		wantErr string
+	}{
+		{
+			name: "dispatch to Rect.area",
+			call: methodCall(structLiteral(map[string]float64{"w": 3, "h": 4}), "area"),
+			want: runtime.NewInt(12),
+		},
+		{
+			name: "dispatch to Square.area",
+			call: methodCall(structLiteral(map[string]float64{"side": 5}), "area"),
+			want: runtime.NewInt(25),
+		},
+		{
+			name: "single candidate with argument",
+			call: methodCall(structLiteral(map[string]float64{"side": 5}), "scaled", ast.Expression{Type: ast.ExprLiteral, Value: 2.0}),
+			want: runtime.NewInt(10),
+		},
+		{
+			name:    "unknown method",
+			call:    methodCall(structLiteral(map[string]float64{"side": 5}), "perimeter"),
+			wantErr: "method 'perimeter' not found",
+		},
+		{
+			name:    "receiver matching no candidate",
+			call:    methodCall(structLiteral(map[string]float64{"radius": 1}), "area"),
+			wantErr: "cannot call method 'area': receiver is not a Rect or Square",
+		},
+		{
+			name:    "receiver not matching the single candidate",
+			call:    methodCall(structLiteral(map[string]float64{"w": 3, "h": 4}), "scaled", ast.Expression{Type: ast.ExprLiteral, Value: 2}),
+			wantErr: "cannot call method 'scaled': receiver is not a Square",
+		},
+		{
+			name:    "empty map receiver",
+			call:    methodCall(&ast.Expression{Type: ast.ExprMapLit}, "scaled", ast.Expression{Type: ast.ExprLiteral, Value: 2}),
+			wantErr: "cannot call method 'scaled': receiver is not a Square",
+		},
+		{
+			name:    "wrong argument count",
+			call:    methodCall(structLiteral(map[string]float64{"side": 5}), "scaled"),
+			wantErr: "method 'Square.scaled' expects 1 arguments, got 0",
+		},
+		{
+			name:    "non-struct receiver",
+			call:    methodCall(&ast.Expression{Type: ast.ExprLiteral, Value: 1.0}, "area"),
+			wantErr: "cannot call method 'area' on int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := New()
+			module := shapeModule([]ast.Statement{{Type: ast.StmtReturn, Value: tt.call}})
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			got, err := interp.Run("main", []runtime.Value{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethodsAreNotGlobalFunctions(t *testing.T) {
+	interp := New()
+	if err := interp.LoadModule(shapeModule([]ast.Statement{})); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if _, err := interp.Run("area", []runtime.Value{}); err == nil {
+		t.Error("expected methods not to be callable as plain functions")
+	}
+}
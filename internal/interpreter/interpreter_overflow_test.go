@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestOverflowChecks(t *testing.T) {
+	variable := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	module := &ast.Module{
+		Type: "module",
+		Name: "test_overflow",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "apply",
+				Params:  []ast.Parameter{{Name: "op", Type: ast.TypeString}, {Name: "a", Type: ast.TypeInt}, {Name: "b", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtIf, Cond: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpEq, Left: variable("op"), Right: &ast.Expression{Type: ast.ExprLiteral, Value: "+"}},
+						Then: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: variable("a"), Right: variable("b")}}}},
+					{Type: ast.StmtIf, Cond: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpEq, Left: variable("op"), Right: &ast.Expression{Type: ast.ExprLiteral, Value: "-"}},
+						Then: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpSub, Left: variable("a"), Right: variable("b")}}}},
+					{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: variable("a"), Right: variable("b")}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		op       string
+		a, b     int64
+		want     int64
+		promoted string // result without overflow checks
+		wantErr  string
+	}{
+		{name: "addition in range", op: "+", a: math.MaxInt64 - 1, b: 1, want: math.MaxInt64, promoted: "9223372036854775807"},
+		{name: "addition overflow", op: "+", a: math.MaxInt64, b: 1, promoted: "9223372036854775808", wantErr: "integer overflow: 9223372036854775807 + 1"},
+		{name: "subtraction overflow", op: "-", a: math.MinInt64, b: 1, promoted: "-9223372036854775809", wantErr: "integer overflow"},
+		{name: "negative subtraction in range", op: "-", a: -5, b: -10, want: 5, promoted: "5"},
+		{name: "multiplication in range", op: "*", a: 1 << 31, b: 1 << 31, want: 1 << 62, promoted: "4611686018427387904"},
+		{name: "multiplication overflow", op: "*", a: 1 << 32, b: 1 << 32, promoted: "18446744073709551616", wantErr: "integer overflow"},
+		{name: "min int times minus one", op: "*", a: -1, b: math.MinInt64, promoted: "9223372036854775808", wantErr: "integer overflow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []runtime.Value{runtime.NewString(tt.op), runtime.NewInt(tt.a), runtime.NewInt(tt.b)}
+
+			promoting := New()
+			if err := promoting.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			result, err := promoting.Run("apply", args)
+			if err != nil {
+				t.Fatalf("Run() without checks error = %v", err)
+			}
+			if got := result.String(); got != tt.promoted {
+				t.Errorf("Run() without checks = %s, want %s", got, tt.promoted)
+			}
+
+			checked := New()
+			checked.EnableOverflowChecks()
+			if err := checked.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			result, err = checked.Run("apply", args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Run() with checks error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() with checks error = %v", err)
+			}
+			if n, _ := result.AsInt(); n != tt.want {
+				t.Errorf("Run() with checks = %d, want %d", n, tt.want)
+			}
+		})
+	}
+}
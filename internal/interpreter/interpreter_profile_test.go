@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestProfile(t *testing.T) {
+	call := func(name string) ast.Statement {
+		return ast.Statement{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprCall, Name: name}}
+	}
+	interp := New()
+	if err := interp.LoadModule(&ast.Module{Type: "module", Name: "test_profile", Functions: []ast.Function{
+		{Type: "function", Name: "main", Returns: ast.TypeVoid, Body: []ast.Statement{call("work"), call("work"), call("work")}},
+		{Type: "function", Name: "work", Returns: ast.TypeVoid, Body: []ast.Statement{call("leaf")}},
+		{Type: "function", Name: "leaf", Returns: ast.TypeVoid, Body: []ast.Statement{}},
+		{Type: "function", Name: "unused", Returns: ast.TypeVoid, Body: []ast.Statement{}},
+	}}); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if _, err := interp.Run("main", []runtime.Value{}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := interp.Profile(); got != nil {
+		t.Fatalf("Profile() before EnableProfiling = %v, want nil", got)
+	}
+
+	interp.EnableProfiling()
+	for run := 0; run < 2; run++ {
+		if _, err := interp.Run("main", []runtime.Value{}); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	got := interp.Profile()
+	calls := make(map[string]int64, len(got))
+	for _, fp := range got {
+		calls[fp.Name] = fp.Calls
+	}
+	want := map[string]int64{"main": 2, "work": 6, "leaf": 6}
+	if len(calls) != len(want) {
+		t.Errorf("Profile() recorded %v, want %v", calls, want)
+	}
+	for name, n := range want {
+		if calls[name] != n {
+			t.Errorf("Profile() calls of %s = %d, want %d", name, calls[name], n)
+		}
+	}
+
+	// A caller's time includes its callees, so main costs the most
+	if len(got) == 0 || got[0].Name != "main" {
+		t.Errorf("Profile() = %v, want main first", got)
+	}
+	for idx := 1; idx < len(got); idx++ {
+		if got[idx].Time > got[idx-1].Time {
+			t.Errorf("Profile() not sorted by time: %v", got)
+		}
+	}
+}
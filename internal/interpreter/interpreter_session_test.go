@@ -0,0 +1,131 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// sessionModule has a counter that increments a session variable and a
+// function that reads a variable it never assigns.
+func sessionModule() *ast.Module {
+	count := &ast.Expression{Type: ast.ExprVariable, Name: "count"}
+	return &ast.Module{
+		Type: "module",
+		Name: "session",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "start",
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtAssign, Target: "count", Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}},
+					{Type: ast.StmtReturn, Value: count},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "add",
+				Params:  []ast.Parameter{{Name: "n", Type: ast.TypeInt}},
+				Returns: ast.TypeInt,
+				Body: []ast.Statement{
+					{Type: ast.StmtAssign, Target: "count", Value: &ast.Expression{
+						Type:  ast.ExprBinary,
+						Op:    ast.OpAdd,
+						Left:  count,
+						Right: &ast.Expression{Type: ast.ExprVariable, Name: "n"},
+					}},
+					{Type: ast.StmtReturn, Value: count},
+				},
+			},
+		},
+	}
+}
+
+func TestRunInEnvironment(t *testing.T) {
+	interp := New()
+	if err := interp.LoadModule(sessionModule()); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	env := interp.NewEnvironment()
+	defer env.Cleanup()
+
+	if _, err := interp.RunInEnvironment(env, "start", nil); err != nil {
+		t.Fatalf("RunInEnvironment(start) error = %v", err)
+	}
+	for _, n := range []int64{2, 3} {
+		if _, err := interp.RunInEnvironment(env, "add", []runtime.Value{runtime.NewInt(n)}); err != nil {
+			t.Fatalf("RunInEnvironment(add) error = %v", err)
+		}
+	}
+
+	bindings := env.Bindings()
+	if got, _ := bindings["count"].AsInt(); got != 5 {
+		t.Errorf("count = %v, want 5", bindings["count"])
+	}
+	if got, _ := bindings["n"].AsInt(); got != 3 {
+		t.Errorf("n = %v, want the last argument 3", bindings["n"])
+	}
+	if env.function != "" {
+		t.Errorf("environment still runs as %q after the call", env.function)
+	}
+
+	// Values set by the host are visible to the function
+	env.Set("count", runtime.NewInt(100))
+	got, err := interp.RunInEnvironment(env, "add", []runtime.Value{runtime.NewInt(1)})
+	if err != nil {
+		t.Fatalf("RunInEnvironment(add) error = %v", err)
+	}
+	if n, _ := got.AsInt(); n != 101 {
+		t.Errorf("add(1) = %v, want 101", got)
+	}
+
+	// Run still uses a fresh environment each time
+	if _, err := interp.Run("add", []runtime.Value{runtime.NewInt(1)}); err == nil {
+		t.Error("Run(add) succeeded without a session, want undefined variable error")
+	}
+
+	if _, err := interp.RunInEnvironment(env, "missing", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RunInEnvironment(missing) error = %v, want not found", err)
+	}
+	if _, err := interp.RunInEnvironment(env, "add", nil); err == nil || !strings.Contains(err.Error(), "expects 1 arguments") {
+		t.Errorf("RunInEnvironment(add) error = %v, want argument count error", err)
+	}
+}
+
+func TestEnvironmentSnapshotRestore(t *testing.T) {
+	interp := New()
+	if err := interp.LoadModule(sessionModule()); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	env := interp.NewEnvironment()
+	defer env.Cleanup()
+	if _, err := interp.RunInEnvironment(env, "start", nil); err != nil {
+		t.Fatalf("RunInEnvironment(start) error = %v", err)
+	}
+	env.Set("items", runtime.NewGCArray([]runtime.Value{runtime.NewInt(1)}))
+
+	snapshot := env.Snapshot()
+	for i := 0; i < 2; i++ {
+		if _, err := interp.RunInEnvironment(env, "add", []runtime.Value{runtime.NewInt(10)}); err != nil {
+			t.Fatalf("RunInEnvironment(add) error = %v", err)
+		}
+		env.Set("items", runtime.NewGCArray(nil))
+
+		env.Restore(snapshot)
+		bindings := env.Bindings()
+		if got, _ := bindings["count"].AsInt(); got != 0 {
+			t.Errorf("restore %d: count = %v, want 0", i, bindings["count"])
+		}
+		if _, ok := bindings["n"]; ok {
+			t.Errorf("restore %d: n is still defined", i)
+		}
+		if items, err := bindings["items"].AsArray(); err != nil || len(items) != 1 {
+			t.Errorf("restore %d: items = %v, %v, want [1]", i, items, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	data := []byte(`{"type": "module", "name": "deep", "functions": [
+{"type": "function", "name": "divide", "params": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}], "returns": "int", "body": [
+  {"type": "return", "value": {"type": "binary", "op": "/", "left": {"type": "variable", "name": "a"}, "right": {"type": "variable", "name": "b"}}}]},
+{"type": "function", "name": "average", "params": [{"name": "total", "type": "int"}, {"name": "items", "type": "array"}], "returns": "int", "body": [
+  {"type": "assign", "target": "count", "value": {"type": "literal", "value": 0}},
+  {"type": "return", "value": {"type": "call", "name": "divide", "args": [{"type": "variable", "name": "total"}, {"type": "variable", "name": "count"}]}}]},
+{"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+  {"type": "return", "value": {"type": "call", "name": "average", "args": [{"type": "literal", "value": 10}, {"type": "array_literal", "elements": []}]}}]},
+{"type": "function", "name": "viaLambda", "params": [{"name": "n", "type": "int"}], "returns": "int", "body": [
+  {"type": "assign", "target": "f", "value": {"type": "lambda", "params": [{"name": "x", "type": "int"}], "returns": "int", "body": [
+    {"type": "return", "value": {"type": "call", "name": "divide", "args": [{"type": "literal", "value": 1}, {"type": "variable", "name": "x"}]}}]}},
+  {"type": "return", "value": {"type": "call", "name": "f", "args": [{"type": "variable", "name": "n"}]}}]}
+]}`)
+
+	tests := []struct {
+		name     string
+		function string
+		args     []runtime.Value
+		want     []Frame
+		message  string
+	}{
+		{
+			name:     "nested calls",
+			function: "main",
+			want: []Frame{
+				{Function: "divide", Args: "a=10, b=0", File: "deep.alas", Line: 3, Column: 31},
+				{Function: "average", Args: "total=10, items=[]", File: "deep.alas", Line: 6, Column: 31},
+				{Function: "main", File: "deep.alas", Line: 8, Column: 31},
+			},
+			message: "deep.alas:3:31: division by zero",
+		},
+		{
+			name:     "lambda",
+			function: "viaLambda",
+			args:     []runtime.Value{runtime.NewInt(0)},
+			want: []Frame{
+				{Function: "divide", Args: "a=1, b=0", File: "deep.alas", Line: 3, Column: 31},
+				{Function: "<lambda>", Args: "x=0", File: "deep.alas", Line: 11, Column: 33},
+				{Function: "viaLambda", Args: "n=0", File: "deep.alas", Line: 12, Column: 31},
+			},
+			message: "deep.alas:3:31: division by zero",
+		},
+		{
+			name:     "no error",
+			function: "viaLambda",
+			args:     []runtime.Value{runtime.NewInt(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := ast.ParseModule(data, "deep.alas")
+			if err != nil {
+				t.Fatalf("ParseModule() error = %v", err)
+			}
+			interp := New()
+			if err := interp.LoadModule(module); err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+
+			_, err = interp.Run(tt.function, tt.args)
+			if got := StackTrace(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StackTrace() = %+v, want %+v (error %v)", got, tt.want, err)
+			}
+			if err == nil {
+				return
+			}
+			var stack *StackError
+			if !errors.As(err, &stack) {
+				t.Errorf("Run() error %v does not wrap a *StackError", err)
+			}
+			// Frames name the calls, so the message is not wrapped per call
+			if err.Error() != tt.message {
+				t.Errorf("Run() error = %q, want %q", err.Error(), tt.message)
+			}
+		})
+	}
+}
+
+func TestStackErrorTrace(t *testing.T) {
+	recursive := func(n int) Frame {
+		return Frame{Function: "f", Args: fmt.Sprintf("n=%d", n), File: "rec.alas", Line: 5, Column: 27}
+	}
+	leaf := Frame{Function: "f", Args: "n=0", File: "rec.alas", Line: 4, Column: 29}
+	main := Frame{Function: "main", File: "rec.alas", Line: 6, Column: 10}
+
+	tests := []struct {
+		name   string
+		frames []Frame
+		want   string
+	}{
+		{
+			name:   "distinct frames",
+			frames: []Frame{leaf, recursive(1), main},
+			want:   "  f(n=0) at rec.alas:4:29\n  f(n=1) at rec.alas:5:27\n  main() at rec.alas:6:10\n",
+		},
+		{
+			name:   "deep recursion",
+			frames: []Frame{leaf, recursive(1), recursive(2), recursive(3), recursive(4), main},
+			want: "  f(n=0) at rec.alas:4:29\n  f(n=1) at rec.alas:5:27\n  ... 2 more calls to f\n" +
+				"  f(n=4) at rec.alas:5:27\n  main() at rec.alas:6:10\n",
+		},
+		{
+			name:   "identical frames",
+			frames: []Frame{recursive(1), recursive(1), recursive(1)},
+			want:   "  f(n=1) at rec.alas:5:27\n  ... 1 more call to f\n  f(n=1) at rec.alas:5:27\n",
+		},
+		{
+			name:   "no location",
+			frames: []Frame{{Function: "<lambda>", Args: "x=1"}},
+			want:   "  <lambda>(x=1)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := &StackError{Err: errors.New("failed"), Frames: tt.frames}
+			if got := stack.Trace(); got != tt.want {
+				t.Errorf("Trace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeValue(t *testing.T) {
+	nested := runtime.NewArray([]runtime.Value{runtime.NewInt(1), runtime.NewArray([]runtime.Value{runtime.NewInt(2)})})
+	tests := []struct {
+		name  string
+		value runtime.Value
+		want  string
+	}{
+		{"int", runtime.NewInt(42), "42"},
+		{"string", runtime.NewString("hi"), `"hi"`},
+		{"nested array", nested, "[1, [...]]"},
+		{"long string", runtime.NewString("abcdefghijklmnopqrstuvwxyz0123456789abcdefgh"), `"abcdefghijklmnopqrstuvwxyz0123456789abc...`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeValue(tt.value); got != tt.want {
+				t.Errorf("summarizeValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
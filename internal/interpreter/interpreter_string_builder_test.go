@@ -0,0 +1,77 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+	"github.com/dshills/alas/internal/validator"
+)
+
+const stringBuilderModule = `{"type": "module", "name": "builder", "functions": [
+	{"type": "function", "name": "appendDigits", "params": [{"name": "sb", "type": "stringBuilder"}, {"name": "n", "type": "int"}], "returns": "void", "body": [
+		{"type": "assign", "target": "i", "value": {"type": "literal", "value": 0}},
+		{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "i"}, "right": {"type": "variable", "name": "n"}}, "body": [
+			{"type": "expr", "value": {"type": "builtin", "name": "string.builderAppend", "args": [{"type": "variable", "name": "sb"},
+				{"type": "binary", "op": "%", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 10}}]}},
+			{"type": "assign", "target": "i", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 1}}}]},
+		{"type": "return"}]},
+	{"type": "function", "name": "main", "params": [{"name": "n", "type": "int"}], "returns": "string", "body": [
+		{"type": "assign", "target": "sb", "value": {"type": "builtin", "name": "string.builderCreate", "args": []}},
+		{"type": "expr", "value": {"type": "builtin", "name": "string.builderAppend", "args": [{"type": "variable", "name": "sb"}, {"type": "literal", "value": "digits:"}]}},
+		{"type": "expr", "value": {"type": "call", "name": "appendDigits", "args": [{"type": "variable", "name": "sb"}, {"type": "variable", "name": "n"}]}},
+		{"type": "return", "value": {"type": "builtin", "name": "string.builderToString", "args": [{"type": "variable", "name": "sb"}]}}]}]}`
+
+func TestStringBuilder(t *testing.T) {
+	if err := validator.ValidateJSON([]byte(stringBuilderModule)); err != nil {
+		t.Fatalf("ValidateJSON() error = %v", err)
+	}
+	module, err := ast.ParseModule([]byte(stringBuilderModule), "builder.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	// The callee appends to the caller's builder
+	got, err := interp.Run("main", []runtime.Value{runtime.NewInt(12)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if s, _ := got.AsString(); s != "digits:012345678901" {
+		t.Errorf("Run() = %q, want %q", s, "digits:012345678901")
+	}
+
+	got, err = interp.Run("main", []runtime.Value{runtime.NewInt(100000)})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if s, _ := got.AsString(); len(s) != len("digits:")+100000 || !strings.HasSuffix(s, "6789") {
+		t.Errorf("Run() returned %d bytes, want %d", len(s), len("digits:")+100000)
+	}
+}
+
+func TestStringBuilderErrors(t *testing.T) {
+	appendToString := `{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [], "returns": "void", "body": [
+		{"type": "expr", "value": {"type": "builtin", "name": "string.builderAppend", "args": [{"type": "literal", "value": "text"}, {"type": "literal", "value": "more"}]}},
+		{"type": "return"}]}]}`
+	err := validator.ValidateJSON([]byte(appendToString))
+	if err == nil || !strings.Contains(err.Error(), "string.builderAppend: argument 0 must be stringBuilder, got string") {
+		t.Errorf("ValidateJSON() error = %v, want builder argument error", err)
+	}
+
+	module, err := ast.ParseModule([]byte(appendToString), "m.alas.json")
+	if err != nil {
+		t.Fatalf("ParseModule() error = %v", err)
+	}
+	interp := New()
+	if err := interp.LoadModule(module); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if _, err := interp.Run("main", nil); err == nil || !strings.Contains(err.Error(), "string.builderAppend: value is not a string builder") {
+		t.Errorf("Run() error = %v, want builder argument error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package interpreter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestTraceHook(t *testing.T) {
+	interp := New()
+	if err := interp.LoadModule(coverageTestModule()); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	type step struct {
+		fn       string
+		stmtType string
+		y        interface{}
+	}
+	var steps []step
+	interp.SetTraceHook(func(fn string, stmt *ast.Statement, env Environment) {
+		bindings := env.Bindings()
+		if got := bindings["x"]; got.Value != int64(5) {
+			t.Errorf("bindings[x] = %v, want 5", got)
+		}
+		var y interface{}
+		if val, ok := bindings["y"]; ok {
+			y = val.Value
+		}
+		steps = append(steps, step{fn: fn, stmtType: stmt.Type, y: y})
+	})
+
+	if _, err := interp.Run("main", []runtime.Value{runtime.NewInt(5)}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []step{
+		{"main", ast.StmtAssign, nil},
+		{"main", ast.StmtIf, int64(0)},
+		{"main", ast.StmtAssign, int64(0)},
+		{"main", ast.StmtReturn, int64(1)},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("trace = %v, want %v", steps, want)
+	}
+
+	// Removing the hook stops tracing
+	interp.SetTraceHook(nil)
+	steps = nil
+	if _, err := interp.Run("main", []runtime.Value{runtime.NewInt(5)}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("expected no trace after SetTraceHook(nil), got %v", steps)
+	}
+}
+
+func TestEnvironmentBindingsShadowing(t *testing.T) {
+	outer := NewEnvironment(nil)
+	outer.Set("a", runtime.NewInt(1))
+	outer.Set("b", runtime.NewInt(2))
+	inner := NewEnvironment(outer)
+	inner.Set("b", runtime.NewInt(3))
+	inner.function = "f"
+
+	bindings := inner.Bindings()
+	if len(bindings) != 2 || bindings["a"].Value != int64(1) || bindings["b"].Value != int64(3) {
+		t.Errorf("Bindings() = %v, want a=1 b=3", bindings)
+	}
+	if got := NewEnvironment(inner).functionName(); got != "f" {
+		t.Errorf("functionName() = %q, want f", got)
+	}
+}
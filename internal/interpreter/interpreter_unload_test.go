@@ -0,0 +1,96 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// constModule returns a module exporting functions that return value.
+func constModule(name string, value int64, imports []ast.Import, functions ...string) *ast.Module {
+	module := &ast.Module{Type: "module", Name: name, Imports: imports, Exports: functions}
+	for _, fn := range functions {
+		module.Functions = append(module.Functions, ast.Function{
+			Type:    "function",
+			Name:    fn,
+			Params:  []ast.Parameter{},
+			Returns: ast.TypeInt,
+			Body:    []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: float64(value)}}},
+		})
+	}
+	module.Types = []ast.TypeDefinition{{Name: "Shared", Definition: ast.TypeDefinitionDef{Kind: "struct"}}}
+	return module
+}
+
+func TestUnloadModule(t *testing.T) {
+	interp := New()
+	first := constModule("first", 1, nil, "shared", "onlyFirst")
+	second := constModule("second", 2, nil, "shared")
+	for _, module := range []*ast.Module{first, second} {
+		if err := interp.LoadModule(module); err != nil {
+			t.Fatalf("LoadModule() error = %v", err)
+		}
+	}
+	if got, _ := interp.Run("shared", nil); got.Value != int64(2) {
+		t.Fatalf("shared() = %v before unload, want 2", got.Value)
+	}
+
+	if err := interp.UnloadModule("second"); err != nil {
+		t.Fatalf("UnloadModule() error = %v", err)
+	}
+	if _, err := interp.RunModuleFunction("second", "shared", nil); err == nil {
+		t.Errorf("expected second.shared to be gone")
+	}
+	// The name second had shadowed is restored from first
+	if got, err := interp.Run("shared", nil); err != nil || got.Value != int64(1) {
+		t.Errorf("shared() = %v, %v after unload, want 1", got.Value, err)
+	}
+	if interp.customTypes["Shared"] != &first.Types[0] {
+		t.Errorf("expected type Shared to be restored from first")
+	}
+	if got, err := interp.RunModuleFunction("first", "onlyFirst", []runtime.Value{}); err != nil || got.Value != int64(1) {
+		t.Errorf("first.onlyFirst() = %v, %v, want 1", got.Value, err)
+	}
+
+	// Reloading registers the module again
+	if err := interp.LoadModule(constModule("second", 3, nil, "shared")); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+	if got, _ := interp.RunModuleFunction("second", "shared", nil); got.Value != int64(3) {
+		t.Errorf("second.shared() = %v after reload, want 3", got.Value)
+	}
+}
+
+func TestUnloadModuleRefusesImportedModule(t *testing.T) {
+	interp := NewWithLoader(mapLoader{"lib": constModule("lib", 1, nil, "value")})
+	if err := interp.LoadModule(constModule("app", 2, []ast.Import{{Module: "lib"}}, "main")); err != nil {
+		t.Fatalf("LoadModule() error = %v", err)
+	}
+
+	err := interp.UnloadModule("lib")
+	if err == nil || !strings.Contains(err.Error(), "imported by app") {
+		t.Fatalf("UnloadModule(lib) error = %v, want refusal naming app", err)
+	}
+	if err := interp.UnloadModule("app"); err != nil {
+		t.Fatalf("UnloadModule(app) error = %v", err)
+	}
+	if err := interp.UnloadModule("lib"); err != nil {
+		t.Errorf("UnloadModule(lib) after unloading app error = %v", err)
+	}
+	if err := interp.UnloadModule("lib"); err == nil {
+		t.Errorf("expected unloading an unloaded module to fail")
+	}
+}
+
+// mapLoader loads modules from a map.
+type mapLoader map[string]*ast.Module
+
+func (l mapLoader) LoadModuleByName(name string) (*ast.Module, error) {
+	if module, ok := l[name]; ok {
+		return module, nil
+	}
+	return nil, fmt.Errorf("module %s not found", name)
+}
@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// Lenient selects runtime errors that the interpreter replaces with defined
+// fallback values, for running programs that are meant to be compiled with
+// -unsafe, where the corresponding checks are left out. Values combine with |.
+type Lenient uint
+
+const (
+	// LenientDivision makes int division and modulo by zero yield 0, and
+	// float division by zero yield +Inf, -Inf or NaN as compiled code does.
+	LenientDivision Lenient = 1 << iota
+	// LenientBounds makes reading an array element outside the array yield
+	// void, and assigning to one do nothing.
+	LenientBounds
+	// LenientMissingKeys makes reading a missing map key yield void, as
+	// compiled map lookups do.
+	LenientMissingKeys
+
+	// LenientAll enables every fallback.
+	LenientAll = LenientDivision | LenientBounds | LenientMissingKeys
+)
+
+// SetLenient replaces the set of runtime errors that yield fallback values
+// instead. The default, zero, reports all of them. It must be called before
+// any function runs.
+func (i *Interpreter) SetLenient(lenient Lenient) {
+	i.lenient = lenient
+}
+
+// isLenient reports whether the given runtime error yields a fallback value.
+func (i *Interpreter) isLenient(l Lenient) bool {
+	return i.lenient&l != 0
+}
+
+// divideIntByZero returns the result of an int / or % by zero: an error, or
+// 0 with LenientDivision.
+func (i *Interpreter) divideIntByZero(op string) (runtime.Value, error) {
+	if i.isLenient(LenientDivision) {
+		return runtime.NewInt(0), nil
+	}
+	if op == ast.OpMod {
+		return runtime.NewVoid(), fmt.Errorf("modulo by zero")
+	}
+	return runtime.NewVoid(), fmt.Errorf("division by zero")
+}
+
+// isZeroInt reports whether v is the int 0. Bigints are never zero, since
+// results that fit in 64 bits are ints.
+func isZeroInt(v runtime.Value) bool {
+	n, err := v.AsInt()
+	return v.Type == runtime.ValueTypeInt && err == nil && n == 0
+}
@@ -0,0 +1,45 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// EnableOverflowChecks makes int arithmetic fail with a runtime error when
+// the result does not fit in 64 bits, instead of promoting it to a bigint.
+// It must be called before any function runs.
+func (i *Interpreter) EnableOverflowChecks() {
+	i.checkOverflow = true
+}
+
+// intArithmetic applies +, -, * or / to two ints. A result that does not fit
+// in 64 bits becomes a bigint unless overflow checks are enabled. The
+// divisor of / must not be zero.
+func (i *Interpreter) intArithmetic(op string, l, r int64) (runtime.Value, error) {
+	var result int64
+	var overflow bool
+	switch op {
+	case ast.OpAdd:
+		result = l + r
+		overflow = (r > 0 && result < l) || (r < 0 && result > l)
+	case ast.OpSub:
+		result = l - r
+		overflow = (r > 0 && result > l) || (r < 0 && result < l)
+	case ast.OpMul:
+		result = l * r
+		overflow = l != 0 && (result/l != r || (l == -1 && r == math.MinInt64))
+	case ast.OpDiv:
+		result = l / r
+		overflow = l == math.MinInt64 && r == -1
+	}
+	if overflow {
+		if i.checkOverflow {
+			return runtime.NewVoid(), fmt.Errorf("integer overflow: %d %s %d", l, op, r)
+		}
+		return bigArithmetic(op, runtime.NewInt(l), runtime.NewInt(r))
+	}
+	return runtime.NewInt(result), nil
+}
@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FunctionProfile is the call count and cumulative running time of one
+// function, named as in coverage.
+type FunctionProfile struct {
+	Name  string
+	Calls int64
+	Time  time.Duration // includes the time spent in the functions it calls
+}
+
+// profile records how often each function is called and how long it runs.
+type profile struct {
+	mu        sync.Mutex // guards functions, since async tasks call functions concurrently
+	functions map[string]*FunctionProfile
+}
+
+// EnableProfiling starts counting the calls to each function and the time
+// they take. Methods are recorded as "Type.method", module functions as
+// "module.function", and lambdas as "<lambda>". A recursive call's time is
+// also counted in each of its callers' frames. It must be called before any
+// function runs.
+func (i *Interpreter) EnableProfiling() {
+	if i.profile != nil {
+		return
+	}
+	i.profile = &profile{functions: make(map[string]*FunctionProfile)}
+}
+
+// Profile returns the functions called so far, most expensive first: by
+// cumulative time, then by call count, then by name. It returns nil if
+// profiling is not enabled.
+func (i *Interpreter) Profile() []FunctionProfile {
+	if i.profile == nil {
+		return nil
+	}
+	i.profile.mu.Lock()
+	defer i.profile.mu.Unlock()
+
+	result := make([]FunctionProfile, 0, len(i.profile.functions))
+	for _, fp := range i.profile.functions {
+		result = append(result, *fp)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Time != result[b].Time {
+			return result[a].Time > result[b].Time
+		}
+		if result[a].Calls != result[b].Calls {
+			return result[a].Calls > result[b].Calls
+		}
+		return result[a].Name < result[b].Name
+	})
+	return result
+}
+
+// record counts a call to function that started at start.
+func (p *profile) record(function string, start time.Time) {
+	elapsed := time.Since(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.functions[function]
+	if !ok {
+		fp = &FunctionProfile{Name: function}
+		p.functions[function] = fp
+	}
+	fp.Calls++
+	fp.Time += elapsed
+}
@@ -0,0 +1,10 @@
+package interpreter
+
+import "github.com/dshills/alas/internal/modpath"
+
+// SetModulePaths makes imported modules be looked for in paths, in order,
+// before the directories in ALAS_PATH and the default search paths. It
+// replaces the module loader, so it must be called before loading modules.
+func (i *Interpreter) SetModulePaths(paths []string) {
+	i.moduleLoader = NewFileModuleLoader(modpath.SearchPaths(paths...))
+}
@@ -0,0 +1,63 @@
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/modpath"
+)
+
+func TestSetModulePaths(t *testing.T) {
+	dir := t.TempDir()
+	lib := `{"type": "module", "name": "searchpath_lib", "exports": ["seven"], "functions": [
+  {"type": "function", "name": "seven", "params": [], "returns": "int", "body": [{"type": "return", "value": {"type": "literal", "value": 7}}]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "searchpath_lib.alas.json"), []byte(lib), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := &ast.Module{
+		Type:    "module",
+		Name:    "app",
+		Imports: []ast.Import{{Module: "searchpath_lib"}},
+		Functions: []ast.Function{{Type: "function", Name: "main", Params: []ast.Parameter{}, Returns: ast.TypeInt, Body: []ast.Statement{
+			{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprModuleCall, Module: "searchpath_lib", Name: "seven", Args: []ast.Expression{}}},
+		}}},
+	}
+
+	tests := []struct {
+		name  string
+		env   string
+		paths []string
+		found bool
+	}{
+		{name: "not on the search path"},
+		{name: "module path", paths: []string{dir}, found: true},
+		{name: "ALAS_PATH", env: dir, found: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(modpath.PathEnv, tt.env)
+			interp := New()
+			interp.SetModulePaths(tt.paths)
+			err := interp.LoadModule(app)
+			if !tt.found {
+				if err == nil {
+					t.Error("LoadModule() succeeded, want a module not found error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadModule() error = %v", err)
+			}
+			got, err := interp.Run("main", nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if n, _ := got.AsInt(); n != 7 {
+				t.Errorf("Run() = %v, want 7", got)
+			}
+		})
+	}
+}
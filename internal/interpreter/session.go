@@ -0,0 +1,91 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// NewEnvironment returns an empty environment for RunInEnvironment, letting
+// a host keep variable state across calls, as a notebook or REPL session
+// does. The environment belongs to the caller. It keeps its variables until
+// Cleanup is called, which releases their garbage-collected values. It is
+// not safe for concurrent use.
+func (i *Interpreter) NewEnvironment() *Environment {
+	return NewEnvironment(nil)
+}
+
+// RunInEnvironment executes a function with env as its variable scope.
+// Unlike Run, the environment is not cleared afterwards. Parameters and every
+// variable the function assigns remain in env, visible to later calls and to
+// Get and Bindings. Values already in env are visible to the function, and
+// assignments overwrite them in place. Lambdas created by the call capture env
+// by reference, so they observe later changes to it.
+func (i *Interpreter) RunInEnvironment(env *Environment, functionName string, args []runtime.Value) (runtime.Value, error) {
+	i.mu.RLock()
+	fn, ok := i.functions[functionName]
+	i.mu.RUnlock()
+	if !ok {
+		return runtime.NewVoid(), fmt.Errorf("function '%s' not found", functionName)
+	}
+
+	// Check argument count
+	if len(args) != len(fn.Params) {
+		return runtime.NewVoid(), fmt.Errorf("function '%s' expects %d arguments, got %d",
+			functionName, len(fn.Params), len(args))
+	}
+	if fn.Extern != "" {
+		return i.callExtern(fn, args)
+	}
+
+	// Run as the function for the duration of the call only, so env stays a
+	// plain session environment between calls
+	function, module := env.function, env.module
+	env.function = functionName
+	env.module = i.functionModule(fn)
+	defer func() { env.function, env.module = function, module }()
+
+	// Bind parameters
+	for idx, param := range fn.Params {
+		env.Set(param.Name, args[idx])
+	}
+
+	result, err := i.runFunctionBody(fn.Params, fn.Body, env)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	return result, nil
+}
+
+// Snapshot returns a copy of the variables defined directly in this
+// environment. The snapshot retains garbage-collected arrays and maps, so it
+// stays valid however the environment changes later. Composite values are
+// shared, not deep-copied.
+func (e *Environment) Snapshot() map[string]runtime.Value {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	snapshot := make(map[string]runtime.Value, len(e.vars))
+	for name, val := range e.vars {
+		val.Retain()
+		snapshot[name] = val
+	}
+	return snapshot
+}
+
+// Restore replaces the variables defined directly in this environment with
+// those of a snapshot. Values being replaced are released. Restored values
+// are retained again, so one snapshot can be restored more than once.
+func (e *Environment) Restore(snapshot map[string]runtime.Value) {
+	e.mu.Lock()
+	for name, val := range e.vars {
+		if _, kept := snapshot[name]; !kept {
+			val.Release()
+			delete(e.vars, name)
+		}
+	}
+	e.mu.Unlock()
+	for name, val := range snapshot {
+		val.Retain()
+		e.Set(name, val)
+	}
+}
@@ -0,0 +1,159 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// maxArgLength is the longest an argument is written in a stack frame
+// before it is cut short.
+const maxArgLength = 40
+
+// Frame is a function call that was active when a runtime error occurred.
+type Frame struct {
+	Function string // Named as for TraceHook
+	Args     string // Parameter values as the error left the call, e.g. `n=3, name="x"`
+	File     string
+	Line     int // Where the error passed through the function, 0 if unknown
+	Column   int
+}
+
+// String formats the frame as "function(args) at file:line:column".
+func (f Frame) String() string {
+	call := f.Function + "(" + f.Args + ")"
+	if f.Line <= 0 {
+		return call
+	}
+	file := f.File
+	if file == "" {
+		file = "<unknown>"
+	}
+	return fmt.Sprintf("%s at %s:%d:%d", call, file, f.Line, f.Column)
+}
+
+// StackError attaches the interpreter's call stack to a runtime error. Its
+// message is that of the error it wraps. Frames are collected only as the
+// error propagates out of each call, so calls that succeed pay nothing for
+// them.
+type StackError struct {
+	Err    error
+	Frames []Frame // Innermost call first
+
+	// Innermost location the error has reached in the caller of the last
+	// frame, which becomes the location of that caller's frame
+	siteFile   string
+	siteLine   int
+	siteColumn int
+}
+
+// Error returns the message of the wrapped error.
+func (e *StackError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *StackError) Unwrap() error {
+	return e.Err
+}
+
+// Trace formats the frames one per line, innermost call first. Runs of
+// calls to a function from the same place, as in deep recursion, are
+// shortened to their first and last frames.
+func (e *StackError) Trace() string {
+	var b strings.Builder
+	for start := 0; start < len(e.Frames); {
+		end := start + 1
+		for end < len(e.Frames) && e.Frames[end].samePlace(e.Frames[start]) {
+			end++
+		}
+		writeFrame(&b, e.Frames[start])
+		switch hidden := end - start - 2; {
+		case hidden == 1:
+			fmt.Fprintf(&b, "  ... 1 more call to %s\n", e.Frames[start].Function)
+		case hidden > 1:
+			fmt.Fprintf(&b, "  ... %d more calls to %s\n", hidden, e.Frames[start].Function)
+		}
+		if end-start > 1 {
+			writeFrame(&b, e.Frames[end-1])
+		}
+		start = end
+	}
+	return b.String()
+}
+
+// writeFrame writes one line of a trace.
+func writeFrame(b *strings.Builder, frame Frame) {
+	b.WriteString("  ")
+	b.WriteString(frame.String())
+	b.WriteByte('\n')
+}
+
+// samePlace reports whether two frames are calls to the same function that
+// the error passed through at the same location.
+func (f Frame) samePlace(other Frame) bool {
+	return f.Function == other.Function && f.File == other.File && f.Line == other.Line && f.Column == other.Column
+}
+
+// StackTrace returns the call stack attached to err, innermost call first,
+// or nil if err carries none.
+func StackTrace(err error) []Frame {
+	var stack *StackError
+	if errors.As(err, &stack) {
+		return stack.Frames
+	}
+	return nil
+}
+
+// locate records where err passed through the caller of its innermost
+// frame. Locations are reported innermost first, so only the first is kept.
+func (e *StackError) locate(file string, line, column int) {
+	if e.siteLine == 0 {
+		e.siteFile, e.siteLine, e.siteColumn = file, line, column
+	}
+}
+
+// addFrame records that err propagated out of the function running in env,
+// whose parameters are params.
+func addFrame(err error, env *Environment, params []ast.Parameter) error {
+	frame := Frame{Function: env.functionName(), Args: summarizeArgs(env, params)}
+	var stack *StackError
+	if errors.As(err, &stack) {
+		frame.File, frame.Line, frame.Column = stack.siteFile, stack.siteLine, stack.siteColumn
+		stack.Frames = append(stack.Frames, frame)
+		stack.siteFile, stack.siteLine, stack.siteColumn = "", 0, 0
+		return err
+	}
+	var located *RuntimeError
+	if errors.As(err, &located) {
+		frame.File, frame.Line, frame.Column = located.File, located.Line, located.Column
+	}
+	return &StackError{Err: err, Frames: []Frame{frame}}
+}
+
+// summarizeArgs writes the values of params in env as "name=value" pairs.
+func summarizeArgs(env *Environment, params []ast.Parameter) string {
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		val, ok := env.Get(param.Name)
+		if !ok {
+			parts = append(parts, param.Name+"=?")
+			continue
+		}
+		parts = append(parts, param.Name+"="+summarizeValue(val))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summarizeValue formats a value for a stack frame, eliding nested
+// collections and cutting long values short.
+func summarizeValue(val runtime.Value) string {
+	text := val.Format(runtime.FormatOptions{MaxDepth: 1, SortKeys: true})
+	if runes := []rune(text); len(runes) > maxArgLength {
+		return string(runes[:maxArgLength]) + "..."
+	}
+	return text
+}
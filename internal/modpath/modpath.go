@@ -0,0 +1,51 @@
+// Package modpath decides where the ALaS tools look for imported modules.
+// Every tool searches the directories it is given, then those listed in
+// ALAS_PATH, then a fixed set of defaults.
+package modpath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathEnv is the environment variable listing extra module search paths,
+// separated by the OS path list separator.
+const PathEnv = "ALAS_PATH"
+
+// defaultSearchPaths are searched for modules after any configured paths.
+// They are relative to the working directory, so they suit running from
+// the repository or one level below it.
+var defaultSearchPaths = []string{".", "examples/modules", "../examples/modules", "stdlib"}
+
+// SearchPaths returns the directories searched for modules: paths first,
+// then those listed in ALAS_PATH, then the defaults.
+func SearchPaths(paths ...string) []string {
+	searchPaths := append([]string{}, paths...)
+	for _, dir := range filepath.SplitList(os.Getenv(PathEnv)) {
+		if dir != "" {
+			searchPaths = append(searchPaths, dir)
+		}
+	}
+	return append(searchPaths, defaultSearchPaths...)
+}
+
+// PathList is a flag.Value collecting module search paths. The flag may be
+// given several times, and each value may hold several paths separated by
+// the OS path list separator.
+type PathList []string
+
+// String returns the paths joined by the OS path list separator.
+func (p *PathList) String() string {
+	return strings.Join(*p, string(filepath.ListSeparator))
+}
+
+// Set adds the paths listed in value.
+func (p *PathList) Set(value string) error {
+	for _, dir := range filepath.SplitList(value) {
+		if dir != "" {
+			*p = append(*p, dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package modpath
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchPaths(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	tests := []struct {
+		name  string
+		env   string
+		paths []string
+		want  []string
+	}{
+		{name: "defaults", want: defaultSearchPaths},
+		{name: "paths first", paths: []string{"lib", "vendor"}, want: append([]string{"lib", "vendor"}, defaultSearchPaths...)},
+		{name: "environment after paths", env: "/opt/alas" + sep + sep + "shared", paths: []string{"lib"},
+			want: append([]string{"lib", "/opt/alas", "shared"}, defaultSearchPaths...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(PathEnv, tt.env)
+			if got := SearchPaths(tt.paths...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathList(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	var paths PathList
+	for _, value := range []string{"lib", "a" + sep + "b", ""} {
+		if err := paths.Set(value); err != nil {
+			t.Fatalf("Set(%q) error = %v", value, err)
+		}
+	}
+	if want := (PathList{"lib", "a", "b"}); !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if got, want := paths.String(), "lib"+sep+"a"+sep+"b"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
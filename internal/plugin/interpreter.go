@@ -2,7 +2,9 @@ package plugin
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/dshills/alas/internal/ast"
 	"github.com/dshills/alas/internal/interpreter"
 	"github.com/dshills/alas/internal/runtime"
 )
@@ -71,9 +73,26 @@ func (i *PluginAwareInterpreter) GetBuiltinFunction(module, name string) (Builti
 	return i.builtinRegistry.Get(module, name)
 }
 
-// LoadPlugin loads a plugin and registers its functions.
+// LoadPlugin loads a plugin and registers its functions. Code in the
+// plugin's module may only call io, filesystem, network, and process builtins
+// if its manifest grants the matching capability.
 func (i *PluginAwareInterpreter) LoadPlugin(name string) error {
-	return i.registry.Load(name)
+	if err := i.registry.Load(name); err != nil {
+		return err
+	}
+	plugin, _ := i.registry.Get(name)
+	i.RestrictPluginModule(plugin.Manifest)
+	return nil
+}
+
+// RestrictPluginModule limits the builtins available to code in a plugin's
+// module to those covered by the capabilities its manifest declares.
+func (i *PluginAwareInterpreter) RestrictPluginModule(manifest *Manifest) {
+	capabilities := make([]string, len(manifest.Capabilities))
+	for idx, capability := range manifest.Capabilities {
+		capabilities[idx] = string(capability)
+	}
+	i.RestrictModule(manifest.Module, capabilities)
 }
 
 // UnloadPlugin unloads a plugin and unregisters its functions.
@@ -83,14 +102,28 @@ func (i *PluginAwareInterpreter) UnloadPlugin(name string) error {
 		return fmt.Errorf("plugin %s not found", name)
 	}
 
+	if err := i.registry.Unload(name); err != nil {
+		return err
+	}
+
 	// Unregister builtin functions
 	for _, fn := range plugin.Manifest.Functions {
 		if fn.Native {
 			i.builtinRegistry.Unregister(plugin.Manifest.Module, fn.Name)
 		}
 	}
+	return nil
+}
 
-	return i.registry.Unload(name)
+// ReloadPlugin reloads a plugin from disk, re-registering its functions and
+// capabilities from the new manifest.
+func (i *PluginAwareInterpreter) ReloadPlugin(name string) error {
+	if err := i.registry.Reload(name); err != nil {
+		return err
+	}
+	plugin, _ := i.registry.Get(name)
+	i.RestrictPluginModule(plugin.Manifest)
+	return nil
 }
 
 // GetRegistry returns the plugin registry.
@@ -148,12 +181,24 @@ func (m *InterpreterPluginManager) Initialize(pluginPaths []string) error {
 	}
 
 	// Register default loaders
-	moduleLoader := NewModuleLoader(func(name string) (interface{}, error) {
-		// This would integrate with the base interpreter's module loading
-		return nil, fmt.Errorf("module loading integration needed")
+	base := m.interpreter.Interpreter
+	moduleLoader := NewModuleLoader(func(path string) (interface{}, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		module, err := ast.ParseModule(data, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := base.LoadModule(module); err != nil {
+			return nil, err
+		}
+		return module, nil
 	})
+	moduleLoader.SetUnloader(base.UnloadModule)
 
-	nativeLoader := NewNativeLoader()
+	nativeLoader := NewNativeLoader(base)
 	hybridLoader := NewHybridLoader(moduleLoader, nativeLoader, m.interpreter.builtinRegistry)
 
 	m.registry.RegisterLoader(PluginTypeModule, moduleLoader)
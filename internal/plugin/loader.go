@@ -2,7 +2,10 @@ package plugin
 
 import (
 	"fmt"
+	"path/filepath"
+	"sync"
 
+	"github.com/dshills/alas/internal/interpreter"
 	"github.com/dshills/alas/internal/runtime"
 )
 
@@ -67,12 +70,17 @@ func (r *BuiltinFunctionRegistry) List() []BuiltinFunction {
 
 // ModuleLoader loads ALaS module plugins.
 type ModuleLoader struct {
-	moduleLoader ModuleLoaderFunc
+	moduleLoader   ModuleLoaderFunc
+	moduleUnloader ModuleUnloaderFunc
 }
 
 // ModuleLoaderFunc is a function type for loading ALaS modules.
 type ModuleLoaderFunc func(name string) (interface{}, error)
 
+// ModuleUnloaderFunc removes a module loaded by a ModuleLoaderFunc, given the
+// module's name.
+type ModuleUnloaderFunc func(module string) error
+
 // NewModuleLoader creates a new module loader.
 func NewModuleLoader(moduleLoader ModuleLoaderFunc) *ModuleLoader {
 	return &ModuleLoader{
@@ -80,10 +88,15 @@ func NewModuleLoader(moduleLoader ModuleLoaderFunc) *ModuleLoader {
 	}
 }
 
+// SetUnloader sets the function that removes a plugin's module on unload.
+func (l *ModuleLoader) SetUnloader(unloader ModuleUnloaderFunc) {
+	l.moduleUnloader = unloader
+}
+
 // Load loads an ALaS module plugin.
 func (l *ModuleLoader) Load(plugin *Plugin) error {
-	if plugin.Manifest.Type != PluginTypeModule {
-		return fmt.Errorf("module loader can only load module plugins")
+	if plugin.Manifest.Type != PluginTypeModule && plugin.Manifest.Type != PluginTypeHybrid {
+		return fmt.Errorf("module loader can only load module or hybrid plugins")
 	}
 
 	// Load the ALaS module file
@@ -96,10 +109,13 @@ func (l *ModuleLoader) Load(plugin *Plugin) error {
 	return nil
 }
 
-// Unload unloads an ALaS module plugin.
+// Unload unloads an ALaS module plugin, removing its module if an unloader
+// is set.
 func (l *ModuleLoader) Unload(plugin *Plugin) error {
-	// ALaS modules don't need explicit unloading
-	return nil
+	if l.moduleUnloader == nil {
+		return nil
+	}
+	return l.moduleUnloader(plugin.Manifest.Module)
 }
 
 // Call calls a function in an ALaS module plugin.
@@ -109,37 +125,163 @@ func (l *ModuleLoader) Call(plugin *Plugin, function string, args []runtime.Valu
 	return runtime.Value{}, fmt.Errorf("module function calls require interpreter integration")
 }
 
-// NativeLoader loads native shared library plugins.
+// NativeSymbol is the symbol a native plugin exports for registration.
+const NativeSymbol = "AlasPlugin"
+
+// NativeFunction is a function implemented by a native plugin. Arguments and
+// results are plain Go values: nil, bool, int64, float64, string,
+// []interface{}, and map[string]interface{}.
+type NativeFunction = func(args []interface{}) (interface{}, error)
+
+// NativeRegistrar is implemented by the value a native plugin exports as
+// NativeSymbol. Register calls register once for each function it provides.
+type NativeRegistrar interface {
+	Register(register func(name string, fn NativeFunction))
+}
+
+// NativeLoader loads native plugins: Go shared objects built with
+// -buildmode=plugin. Their functions are registered with the interpreter as
+// builtins named "module.function".
 type NativeLoader struct {
-	// Platform-specific native library loading would go here
+	interp    *interpreter.Interpreter
+	mu        sync.Mutex
+	functions map[string]map[string]NativeFunction // plugin name -> function name -> implementation
 }
 
-// NewNativeLoader creates a new native loader.
-func NewNativeLoader() *NativeLoader {
-	return &NativeLoader{}
+// NewNativeLoader creates a new native loader. Loaded functions are registered
+// as builtins of interp when it is not nil.
+func NewNativeLoader(interp *interpreter.Interpreter) *NativeLoader {
+	return &NativeLoader{
+		interp:    interp,
+		functions: make(map[string]map[string]NativeFunction),
+	}
 }
 
-// Load loads a native plugin.
+// Load opens a native plugin's shared object and registers its functions.
 func (l *NativeLoader) Load(plugin *Plugin) error {
-	if plugin.Manifest.Type != PluginTypeNative {
-		return fmt.Errorf("native loader can only load native plugins")
+	manifest := plugin.Manifest
+	if manifest.Type != PluginTypeNative && manifest.Type != PluginTypeHybrid {
+		return fmt.Errorf("native loader can only load native or hybrid plugins")
+	}
+	if !manifest.HasCapability(CapabilityNative) {
+		return fmt.Errorf("plugin %s must declare the '%s' capability to load native code", manifest.Name, CapabilityNative)
 	}
 
-	// TODO: Implement native library loading
-	// This would use dlopen/LoadLibrary depending on platform
-	return fmt.Errorf("native plugin loading not yet implemented")
+	path, err := nativeBinaryPath(plugin)
+	if err != nil {
+		return err
+	}
+	registrar, err := openNativePlugin(path)
+	if err != nil {
+		return err
+	}
+	return l.register(plugin, registrar)
 }
 
-// Unload unloads a native plugin.
+// nativeBinaryPath returns the shared object of a native plugin: its first
+// binary, or the entry point of a native plugin.
+func nativeBinaryPath(plugin *Plugin) (string, error) {
+	impl := plugin.Manifest.Implementation
+	name := impl.EntryPoint
+	if len(impl.Binaries) > 0 {
+		name = impl.Binaries[0]
+	} else if plugin.Manifest.Type != PluginTypeNative {
+		name = ""
+	}
+	if name == "" {
+		return "", fmt.Errorf("plugin %s does not name a native binary", plugin.Manifest.Name)
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	return filepath.Join(plugin.Path, name), nil
+}
+
+// register collects the functions of registrar, checks that they match the
+// native functions declared by the manifest, and registers them.
+func (l *NativeLoader) register(plugin *Plugin, registrar NativeRegistrar) error {
+	manifest := plugin.Manifest
+	exported := make(map[string]NativeFunction)
+	var duplicate string
+	registrar.Register(func(name string, fn NativeFunction) {
+		if _, exists := exported[name]; exists {
+			duplicate = name
+		}
+		exported[name] = fn
+	})
+	if duplicate != "" {
+		return fmt.Errorf("native plugin %s registers %s more than once", manifest.Name, duplicate)
+	}
+
+	declared := make(map[string]bool)
+	for _, fn := range manifest.Functions {
+		if fn.Native || manifest.Type == PluginTypeNative {
+			declared[fn.Name] = true
+			if _, ok := exported[fn.Name]; !ok {
+				return fmt.Errorf("native plugin %s does not export declared function %s", manifest.Name, fn.Name)
+			}
+		}
+	}
+	for name := range exported {
+		if !declared[name] {
+			return fmt.Errorf("native plugin %s exports undeclared function %s", manifest.Name, name)
+		}
+	}
+
+	l.mu.Lock()
+	l.functions[manifest.Name] = exported
+	l.mu.Unlock()
+
+	if l.interp != nil {
+		for name, fn := range exported {
+			native := fn
+			l.interp.RegisterBuiltin(manifest.Module+"."+name, func(args []runtime.Value) (runtime.Value, error) {
+				return callNative(native, args)
+			})
+		}
+	}
+	return nil
+}
+
+// callNative calls a native function, converting arguments and result
+// between runtime and Go values.
+func callNative(fn NativeFunction, args []runtime.Value) (runtime.Value, error) {
+	nativeArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		nativeArgs[i] = fromRuntimeValue(arg)
+	}
+	result, err := fn(nativeArgs)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	return toRuntimeValue(result), nil
+}
+
+// Unload removes the functions a native plugin registered. Go cannot unload
+// a shared object, so its code stays mapped until the process exits.
 func (l *NativeLoader) Unload(plugin *Plugin) error {
-	// TODO: Implement native library unloading
-	return fmt.Errorf("native plugin unloading not yet implemented")
+	l.mu.Lock()
+	exported := l.functions[plugin.Manifest.Name]
+	delete(l.functions, plugin.Manifest.Name)
+	l.mu.Unlock()
+
+	if l.interp != nil {
+		for name := range exported {
+			l.interp.UnregisterBuiltin(plugin.Manifest.Module + "." + name)
+		}
+	}
+	return nil
 }
 
 // Call calls a function in a native plugin.
 func (l *NativeLoader) Call(plugin *Plugin, function string, args []runtime.Value) (runtime.Value, error) {
-	// TODO: Implement native function calling
-	return runtime.Value{}, fmt.Errorf("native plugin calls not yet implemented")
+	l.mu.Lock()
+	fn, ok := l.functions[plugin.Manifest.Name][function]
+	l.mu.Unlock()
+	if !ok {
+		return runtime.Value{}, fmt.Errorf("native function %s not found in plugin %s", function, plugin.Manifest.Name)
+	}
+	return callNative(fn, args)
 }
 
 // HybridLoader loads hybrid plugins (ALaS modules with native functions).
@@ -222,7 +364,7 @@ func (l *HybridLoader) Call(plugin *Plugin, function string, args []runtime.Valu
 		if builtinFn, exists := l.builtinRegistry.Get(plugin.Manifest.Module, function); exists {
 			return builtinFn.Call(args)
 		}
-		return runtime.Value{}, fmt.Errorf("native function %s not found in registry", function)
+		return l.nativeLoader.Call(plugin, function, args)
 	}
 
 	// Otherwise delegate to module loader
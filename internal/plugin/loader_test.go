@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/interpreter"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// fakeRegistrar registers a fixed set of native functions.
+type fakeRegistrar map[string]NativeFunction
+
+func (r fakeRegistrar) Register(register func(name string, fn NativeFunction)) {
+	for name, fn := range r {
+		register(name, fn)
+	}
+}
+
+func nativeTestPlugin(functions ...string) *Plugin {
+	manifest := &Manifest{
+		Name:         "greet",
+		Version:      "0.1.0",
+		Type:         PluginTypeNative,
+		Capabilities: []Capability{CapabilityFunction, CapabilityNative},
+		Module:       "greet",
+		Implementation: Implementation{
+			Language:   "go",
+			EntryPoint: "greet.so",
+		},
+	}
+	for _, name := range functions {
+		manifest.Functions = append(manifest.Functions, FunctionDef{
+			Name:    name,
+			Params:  []ParamDef{{Name: "name", Type: "string"}},
+			Returns: "string",
+		})
+	}
+	return &Plugin{Manifest: manifest, Path: "/plugins/greet"}
+}
+
+func hello(args []interface{}) (interface{}, error) {
+	return "Hello, " + args[0].(string), nil
+}
+
+func TestNativeLoaderRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared []string
+		exported fakeRegistrar
+		wantErr  string
+	}{
+		{name: "matching functions", declared: []string{"hello"}, exported: fakeRegistrar{"hello": hello}},
+		{name: "missing export", declared: []string{"hello", "bye"}, exported: fakeRegistrar{"hello": hello}, wantErr: "does not export declared function bye"},
+		{name: "undeclared export", declared: []string{"hello"}, exported: fakeRegistrar{"hello": hello, "bye": hello}, wantErr: "exports undeclared function bye"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := interpreter.New()
+			loader := NewNativeLoader(interp)
+			plugin := nativeTestPlugin(tt.declared...)
+
+			err := loader.register(plugin, tt.exported)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("register() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := interp.CallBuiltinFunction("greet.hello", []runtime.Value{runtime.NewString("x")}); err == nil {
+					t.Errorf("expected no builtins to be registered after a failed load")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("register() error = %v", err)
+			}
+
+			got, err := interp.CallBuiltinFunction("greet.hello", []runtime.Value{runtime.NewString("World")})
+			if err != nil || got.Value != "Hello, World" {
+				t.Errorf("greet.hello = %v, %v, want Hello, World", got, err)
+			}
+			got, err = loader.Call(plugin, "hello", []runtime.Value{runtime.NewString("Bob")})
+			if err != nil || got.Value != "Hello, Bob" {
+				t.Errorf("Call(hello) = %v, %v, want Hello, Bob", got, err)
+			}
+
+			if err := loader.Unload(plugin); err != nil {
+				t.Fatalf("Unload() error = %v", err)
+			}
+			if _, err := interp.CallBuiltinFunction("greet.hello", []runtime.Value{runtime.NewString("x")}); err == nil {
+				t.Errorf("expected greet.hello to be unregistered after Unload")
+			}
+		})
+	}
+}
+
+func TestNativeLoaderRequiresNativeCapability(t *testing.T) {
+	plugin := nativeTestPlugin("hello")
+	plugin.Manifest.Capabilities = []Capability{CapabilityFunction}
+
+	err := NewNativeLoader(nil).Load(plugin)
+	if err == nil || !strings.Contains(err.Error(), "'native' capability") {
+		t.Errorf("Load() error = %v, want missing native capability", err)
+	}
+}
+
+func TestNativeBinaryPath(t *testing.T) {
+	plugin := nativeTestPlugin("hello")
+	if got, err := nativeBinaryPath(plugin); err != nil || got != "/plugins/greet/greet.so" {
+		t.Errorf("nativeBinaryPath() = %q, %v", got, err)
+	}
+
+	plugin.Manifest.Implementation.Binaries = []string{"build/greet.so"}
+	if got, err := nativeBinaryPath(plugin); err != nil || got != "/plugins/greet/build/greet.so" {
+		t.Errorf("nativeBinaryPath() with binaries = %q, %v", got, err)
+	}
+
+	plugin.Manifest.Type = PluginTypeHybrid
+	plugin.Manifest.Implementation.Binaries = nil
+	if _, err := nativeBinaryPath(plugin); err == nil {
+		t.Errorf("expected hybrid plugin without binaries to have no native binary")
+	}
+}
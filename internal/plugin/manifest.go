@@ -60,6 +60,7 @@ const (
 	CapabilityNetwork    Capability = "network"    // Network access
 	CapabilityFileSystem Capability = "filesystem" // File system access
 	CapabilityProcess    Capability = "process"    // Process execution
+	CapabilityNative     Capability = "native"     // Loads compiled native code
 )
 
 // FunctionDef defines a function provided by the plugin.
@@ -191,7 +192,7 @@ func (m *Manifest) Validate() error {
 		switch cap {
 		case CapabilityFunction, CapabilityType, CapabilityModule, CapabilityCodegen,
 			CapabilityValidation, CapabilityIO, CapabilityNetwork, CapabilityFileSystem,
-			CapabilityProcess:
+			CapabilityProcess, CapabilityNative:
 			// Valid
 		default:
 			return fmt.Errorf("invalid capability: %s", cap)
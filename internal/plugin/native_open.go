@@ -0,0 +1,25 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package plugin
+
+import (
+	"fmt"
+	goplugin "plugin"
+)
+
+// openNativePlugin opens a Go plugin and returns its registrar.
+func openNativePlugin(path string) (NativeRegistrar, error) {
+	p, err := goplugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open native plugin %s: %w", path, err)
+	}
+	symbol, err := p.Lookup(NativeSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("native plugin %s does not export %s: %w", path, NativeSymbol, err)
+	}
+	registrar, ok := symbol.(NativeRegistrar)
+	if !ok {
+		return nil, fmt.Errorf("native plugin %s: %s does not implement Register(func(string, func([]interface{}) (interface{}, error)))", path, NativeSymbol)
+	}
+	return registrar, nil
+}
@@ -0,0 +1,13 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package plugin
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// openNativePlugin reports that Go plugins are unavailable on this platform.
+func openNativePlugin(path string) (NativeRegistrar, error) {
+	return nil, fmt.Errorf("cannot open native plugin %s: native plugins are not supported on %s/%s", path, runtime.GOOS, runtime.GOARCH)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -196,11 +197,15 @@ func (r *Registry) Unload(name string) error {
 		r.mu.Unlock()
 		return fmt.Errorf("plugin %s not found", name)
 	}
+	dependents := r.loadedDependents(name)
 	r.mu.Unlock()
 
 	if plugin.State != StateLoaded {
 		return nil
 	}
+	if len(dependents) > 0 {
+		return fmt.Errorf("cannot unload plugin %s: required by %s", name, strings.Join(dependents, ", "))
+	}
 
 	if plugin.Loader != nil {
 		if err := plugin.Loader.Unload(plugin); err != nil {
@@ -213,6 +218,60 @@ func (r *Registry) Unload(name string) error {
 	return nil
 }
 
+// Reload unloads a plugin, re-reads its manifest from disk, and loads it
+// again, so changes to the plugin take effect.
+func (r *Registry) Reload(name string) error {
+	if err := r.Unload(name); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	plugin := r.plugins[name]
+	r.mu.Unlock()
+
+	manifest, err := LoadManifest(filepath.Join(plugin.Path, "plugin.json"))
+	if err != nil {
+		return fmt.Errorf("failed to reload plugin %s: %w", name, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return fmt.Errorf("failed to reload plugin %s: invalid manifest: %w", name, err)
+	}
+	if manifest.Name != name {
+		return fmt.Errorf("failed to reload plugin %s: manifest now names plugin %s", name, manifest.Name)
+	}
+
+	plugin.Manifest = manifest
+	return r.loadPlugin(plugin)
+}
+
+// loadedDependents returns the names of loaded plugins that depend on the
+// named plugin. The caller must hold r.mu.
+func (r *Registry) loadedDependents(name string) []string {
+	var dependents []string
+	for _, other := range r.plugins {
+		if other.State != StateLoaded || other.Manifest.Name == name {
+			continue
+		}
+		for _, dep := range other.Manifest.Dependencies {
+			if dependencyName(dep) == name {
+				dependents = append(dependents, other.Manifest.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// dependencyName returns the plugin name of a dependency such as
+// "strings" or "strings@^1.0.0".
+func dependencyName(dep string) string {
+	if idx := strings.IndexAny(dep, "@ <>=^~"); idx >= 0 {
+		dep = dep[:idx]
+	}
+	return dep
+}
+
 // Get returns a plugin by name.
 func (r *Registry) Get(name string) (*Plugin, bool) {
 	r.mu.RLock()
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/interpreter"
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// writeModulePlugin writes a module plugin whose greet function returns
+// greeting, optionally depending on other plugins.
+func writeModulePlugin(t *testing.T, dir, name, greeting string, dependencies ...string) {
+	t.Helper()
+	pluginDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &Manifest{
+		Name:         name,
+		Version:      "0.1.0",
+		Type:         PluginTypeModule,
+		Capabilities: []Capability{CapabilityFunction},
+		Module:       name,
+		Functions:    []FunctionDef{{Name: "greet", Returns: "string"}},
+		Dependencies: dependencies,
+		Implementation: Implementation{
+			Language:   "alas",
+			EntryPoint: name + ".alas.json",
+		},
+	}
+	if err := manifest.SaveManifest(filepath.Join(pluginDir, "plugin.json")); err != nil {
+		t.Fatal(err)
+	}
+	module := fmt.Sprintf(`{
+  "type": "module",
+  "name": %q,
+  "exports": ["greet"],
+  "functions": [
+    {"type": "function", "name": "greet", "params": [], "returns": "string",
+     "body": [{"type": "return", "value": {"type": "literal", "value": %q}}]}
+  ]
+}`, name, greeting)
+	if err := os.WriteFile(filepath.Join(pluginDir, name+".alas.json"), []byte(module), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryReload(t *testing.T) {
+	dir := t.TempDir()
+	writeModulePlugin(t, dir, "greeter", "hello")
+
+	manager := NewInterpreterPluginManager(interpreter.New())
+	if err := manager.Initialize([]string{dir}); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	interp := manager.GetInterpreter()
+	if err := interp.LoadPlugin("greeter"); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	if got, err := interp.RunModuleFunction("greeter", "greet", nil); err != nil || got.Value != "hello" {
+		t.Fatalf("greeter.greet() = %v, %v, want hello", got, err)
+	}
+
+	writeModulePlugin(t, dir, "greeter", "bonjour")
+	if err := interp.ReloadPlugin("greeter"); err != nil {
+		t.Fatalf("ReloadPlugin() error = %v", err)
+	}
+	if got, err := interp.RunModuleFunction("greeter", "greet", nil); err != nil || got.Value != "bonjour" {
+		t.Errorf("greeter.greet() after reload = %v, %v, want bonjour", got, err)
+	}
+
+	if err := interp.UnloadPlugin("greeter"); err != nil {
+		t.Fatalf("UnloadPlugin() error = %v", err)
+	}
+	if _, err := interp.RunModuleFunction("greeter", "greet", []runtime.Value{}); err == nil {
+		t.Errorf("expected greeter.greet to be gone after unload")
+	}
+}
+
+func TestRegistryUnloadRefusesDependedOnPlugin(t *testing.T) {
+	dir := t.TempDir()
+	writeModulePlugin(t, dir, "base", "base")
+	writeModulePlugin(t, dir, "app", "app", "base@^0.1.0")
+
+	manager := NewInterpreterPluginManager(interpreter.New())
+	if err := manager.Initialize([]string{dir}); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	registry := manager.GetRegistry()
+	for _, name := range []string{"base", "app"} {
+		if err := registry.Load(name); err != nil {
+			t.Fatalf("Load(%s) error = %v", name, err)
+		}
+	}
+
+	err := registry.Unload("base")
+	if err == nil || !strings.Contains(err.Error(), "required by app") {
+		t.Fatalf("Unload(base) error = %v, want refusal naming app", err)
+	}
+	if p, _ := registry.Get("base"); p.State != StateLoaded {
+		t.Errorf("base state = %s after refused unload, want loaded", p.State)
+	}
+
+	if err := registry.Unload("app"); err != nil {
+		t.Fatalf("Unload(app) error = %v", err)
+	}
+	if err := registry.Unload("base"); err != nil {
+		t.Errorf("Unload(base) after unloading app error = %v", err)
+	}
+}
@@ -181,7 +181,7 @@ func (tr *TestRunner) runTestCase(plugin *Plugin, testCase *TestCase) TestResult
 		// Convert args to runtime values
 		args := make([]runtime.Value, len(testCase.Args))
 		for i, arg := range testCase.Args {
-			args[i] = tr.toRuntimeValue(arg)
+			args[i] = toRuntimeValue(arg)
 		}
 
 		// Call plugin function
@@ -221,9 +221,9 @@ func (tr *TestRunner) runTestCase(plugin *Plugin, testCase *TestCase) TestResult
 			// Compare output with expected
 			if tr.compareValues(output, testCase.Expected) {
 				result.Passed = true
-				result.Output = tr.fromRuntimeValue(output)
+				result.Output = fromRuntimeValue(output)
 			} else {
-				result.Error = fmt.Sprintf("expected %v, got %v", testCase.Expected, tr.fromRuntimeValue(output))
+				result.Error = fmt.Sprintf("expected %v, got %v", testCase.Expected, fromRuntimeValue(output))
 			}
 		}
 	}
@@ -276,8 +276,9 @@ func (tr *TestRunner) runStep(step *TestStep) error {
 	}
 }
 
-// toRuntimeValue converts a test value to a runtime value.
-func (tr *TestRunner) toRuntimeValue(value interface{}) runtime.Value {
+// toRuntimeValue converts a Go value from a test case or native plugin to a
+// runtime value.
+func toRuntimeValue(value interface{}) runtime.Value {
 	switch v := value.(type) {
 	case nil:
 		return runtime.NewVoid()
@@ -294,13 +295,14 @@ func (tr *TestRunner) toRuntimeValue(value interface{}) runtime.Value {
 	case []interface{}:
 		elements := make([]runtime.Value, len(v))
 		for i, elem := range v {
-			elements[i] = tr.toRuntimeValue(elem)
+			elements[i] = toRuntimeValue(elem)
 		}
 		return runtime.NewArray(elements)
 	case map[string]interface{}:
 		pairs := make(map[string]runtime.Value)
 		for k, val := range v {
-			pairs[k] = tr.toRuntimeValue(val)
+			stored, _ := runtime.MapKey(runtime.NewString(k))
+			pairs[stored] = toRuntimeValue(val)
 		}
 		return runtime.NewMap(pairs)
 	default:
@@ -308,8 +310,9 @@ func (tr *TestRunner) toRuntimeValue(value interface{}) runtime.Value {
 	}
 }
 
-// fromRuntimeValue converts a runtime value to a test value.
-func (tr *TestRunner) fromRuntimeValue(value runtime.Value) interface{} {
+// fromRuntimeValue converts a runtime value to the Go value used by test cases
+// and native plugins.
+func fromRuntimeValue(value runtime.Value) interface{} {
 	switch value.Type {
 	case runtime.ValueTypeVoid:
 		return nil
@@ -337,7 +340,7 @@ func (tr *TestRunner) fromRuntimeValue(value runtime.Value) interface{} {
 		if arr, err := value.AsArray(); err == nil {
 			result := make([]interface{}, len(arr))
 			for i, elem := range arr {
-				result[i] = tr.fromRuntimeValue(elem)
+				result[i] = fromRuntimeValue(elem)
 			}
 			return result
 		}
@@ -346,7 +349,7 @@ func (tr *TestRunner) fromRuntimeValue(value runtime.Value) interface{} {
 		if m, err := value.AsMap(); err == nil {
 			result := make(map[string]interface{})
 			for k, val := range m {
-				result[k] = tr.fromRuntimeValue(val)
+				result[runtime.MapKeyValue(k).String()] = fromRuntimeValue(val)
 			}
 			return result
 		}
@@ -358,7 +361,7 @@ func (tr *TestRunner) fromRuntimeValue(value runtime.Value) interface{} {
 
 // compareValues compares a runtime value with an expected test value.
 func (tr *TestRunner) compareValues(actual runtime.Value, expected interface{}) bool {
-	expectedValue := tr.toRuntimeValue(expected)
+	expectedValue := toRuntimeValue(expected)
 	return tr.valuesEqual(actual, expectedValue)
 }
 
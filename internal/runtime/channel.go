@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Channel passes values between async tasks. It is a Go channel that can be
+// closed from either end without panicking: closing releases every blocked
+// sender and receiver, and receivers still drain values sent before the close.
+type Channel struct {
+	values    chan Value
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewChannel creates a channel buffering up to capacity values. A capacity
+// of 0 makes every send wait for a receiver.
+func NewChannel(capacity int) (Value, error) {
+	if capacity < 0 {
+		return NewVoid(), fmt.Errorf("channel capacity must not be negative, got %d", capacity)
+	}
+	return Value{Type: ValueTypeChannel, Value: &Channel{
+		values: make(chan Value, capacity),
+		done:   make(chan struct{}),
+	}}, nil
+}
+
+// AsChannel returns the value as a channel.
+func (v Value) AsChannel() (*Channel, error) {
+	if v.Type != ValueTypeChannel {
+		return nil, fmt.Errorf("value is not a channel")
+	}
+	return v.Value.(*Channel), nil
+}
+
+// Send blocks until a receiver takes value or there is room in the buffer.
+// It fails if the channel is closed or ctx is done first.
+func (c *Channel) Send(ctx context.Context, value Value) error {
+	select {
+	case <-c.done:
+		return fmt.Errorf("send on closed channel")
+	default:
+	}
+
+	select {
+	case c.values <- value:
+		return nil
+	case <-c.done:
+		return fmt.Errorf("send on closed channel")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Recv blocks until a value is available and returns it. It fails once the
+// channel is closed and drained, or if ctx is done first.
+func (c *Channel) Recv(ctx context.Context) (Value, error) {
+	select {
+	case value := <-c.values:
+		return value, nil
+	case <-c.done:
+		// Values sent before the close are still delivered
+		select {
+		case value := <-c.values:
+			return value, nil
+		default:
+			return NewVoid(), fmt.Errorf("receive on closed channel")
+		}
+	case <-ctx.Done():
+		return NewVoid(), ctx.Err()
+	}
+}
+
+// Close closes the channel. It reports whether this call closed it.
+func (c *Channel) Close() bool {
+	closed := false
+	c.closeOnce.Do(func() {
+		close(c.done)
+		closed = true
+	})
+	return closed
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustChannel(t *testing.T, capacity int) *Channel {
+	t.Helper()
+	v, err := NewChannel(capacity)
+	if err != nil {
+		t.Fatalf("NewChannel(%d) error = %v", capacity, err)
+	}
+	ch, _ := v.AsChannel()
+	return ch
+}
+
+func TestChannelSendRecv(t *testing.T) {
+	ctx := context.Background()
+	ch := mustChannel(t, 2)
+
+	for _, n := range []int64{1, 2} {
+		if err := ch.Send(ctx, NewInt(n)); err != nil {
+			t.Fatalf("Send(%d) error = %v", n, err)
+		}
+	}
+	for _, want := range []int64{1, 2} {
+		got, err := ch.Recv(ctx)
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if n, _ := got.AsInt(); n != want {
+			t.Errorf("Recv() = %s, want %d", got, want)
+		}
+	}
+
+	// An unbuffered send waits for the receiver
+	unbuffered := mustChannel(t, 0)
+	go func() {
+		_ = unbuffered.Send(ctx, NewString("ping"))
+	}()
+	got, err := unbuffered.Recv(ctx)
+	if err != nil || got.String() != "ping" {
+		t.Errorf("Recv() = %s, %v, want ping", got, err)
+	}
+}
+
+func TestChannelClose(t *testing.T) {
+	ctx := context.Background()
+	ch := mustChannel(t, 1)
+	if err := ch.Send(ctx, NewInt(7)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !ch.Close() {
+		t.Error("Close() = false, want true")
+	}
+	if ch.Close() {
+		t.Error("second Close() = true, want false")
+	}
+
+	// Buffered values are drained before receives fail
+	if got, err := ch.Recv(ctx); err != nil || got.String() != "7" {
+		t.Errorf("Recv() = %s, %v, want 7", got, err)
+	}
+	if _, err := ch.Recv(ctx); err == nil || !strings.Contains(err.Error(), "closed channel") {
+		t.Errorf("Recv() error = %v, want closed channel", err)
+	}
+	if err := ch.Send(ctx, NewInt(8)); err == nil || !strings.Contains(err.Error(), "closed channel") {
+		t.Errorf("Send() error = %v, want closed channel", err)
+	}
+
+	// Closing releases a blocked receiver
+	blocked := mustChannel(t, 0)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := blocked.Recv(ctx)
+		errs <- err
+	}()
+	blocked.Close()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Recv() on a closed channel succeeded")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Recv() still blocked after Close()")
+	}
+}
+
+func TestChannelContext(t *testing.T) {
+	ch := mustChannel(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ch.Recv(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Recv() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := ch.Send(ctx, NewInt(1)); err != context.DeadlineExceeded {
+		t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := NewChannel(-1); err == nil {
+		t.Error("NewChannel(-1) succeeded, want error")
+	}
+}
@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// Rounding modes accepted by RoundDecimal.
+const (
+	RoundHalfEven = "halfEven" // to nearest, ties to the even digit
+	RoundHalfUp   = "halfUp"   // to nearest, ties away from zero
+	RoundHalfDown = "halfDown" // to nearest, ties toward zero
+	RoundUp       = "up"       // away from zero
+	RoundDown     = "down"     // toward zero
+	RoundCeiling  = "ceiling"  // toward positive infinity
+	RoundFloor    = "floor"    // toward negative infinity
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// NewDecimal creates a decimal value. The rational must have a finite
+// decimal expansion, as every result of ParseDecimal, RoundDecimal and
+// QuoDecimal does.
+func NewDecimal(v *big.Rat) Value {
+	return Value{Type: ValueTypeDecimal, Value: new(big.Rat).Set(v)}
+}
+
+// AsDecimal returns a decimal, int or bigint value as a new rational.
+func (v Value) AsDecimal() (*big.Rat, error) {
+	switch v.Type {
+	case ValueTypeDecimal:
+		return new(big.Rat).Set(v.Value.(*big.Rat)), nil
+	case ValueTypeInt:
+		return new(big.Rat).SetInt64(v.Value.(int64)), nil
+	case ValueTypeBigInt:
+		return new(big.Rat).SetInt(v.Value.(*big.Int)), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v to decimal", v.Type)
+	}
+}
+
+// ParseDecimal parses a decimal number such as "-12.50". Exponents and
+// fractions are not accepted.
+func ParseDecimal(s string) (*big.Rat, error) {
+	if !decimalPattern.MatchString(s) {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+// DecimalString formats a decimal with as many fraction digits as it needs.
+func DecimalString(r *big.Rat) string {
+	scale, ok := decimalScale(r)
+	if !ok {
+		return r.RatString()
+	}
+	return r.FloatString(scale)
+}
+
+// QuoDecimal divides two decimals exactly. It fails when the quotient has
+// no finite decimal expansion, such as 1 / 3.
+func QuoDecimal(a, b *big.Rat) (*big.Rat, error) {
+	if b.Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	q := new(big.Rat).Quo(a, b)
+	if _, ok := decimalScale(q); !ok {
+		return nil, fmt.Errorf("%s / %s has no exact decimal result", DecimalString(a), DecimalString(b))
+	}
+	return q, nil
+}
+
+// RoundDecimal rounds r to scale fraction digits using the given rounding
+// mode.
+func RoundDecimal(r *big.Rat, scale int, mode string) (*big.Rat, error) {
+	if scale < 0 {
+		return nil, fmt.Errorf("decimal scale must not be negative, got %d", scale)
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(unit))
+	q, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+
+	// Compare the discarded fraction with one half
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	cmpHalf := half.Cmp(scaled.Denom())
+
+	sign := scaled.Sign()
+	var away bool
+	switch mode {
+	case RoundHalfEven:
+		away = cmpHalf > 0 || cmpHalf == 0 && q.Bit(0) == 1
+	case RoundHalfUp:
+		away = cmpHalf >= 0
+	case RoundHalfDown:
+		away = cmpHalf > 0
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	default:
+		return nil, fmt.Errorf("unknown rounding mode %q", mode)
+	}
+	if away && rem.Sign() != 0 {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return new(big.Rat).SetFrac(q, unit), nil
+}
+
+// decimalScale returns the number of fraction digits r needs, or false when
+// its decimal expansion does not terminate.
+func decimalScale(r *big.Rat) (int, bool) {
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	five, mod := big.NewInt(5), new(big.Int)
+	for denom.Bit(0) == 0 {
+		denom.Rsh(denom, 1)
+		twos++
+	}
+	for {
+		quo, rem := new(big.Int).QuoRem(denom, five, mod)
+		if rem.Sign() != 0 {
+			break
+		}
+		denom = quo
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return max(twos, fives), true
+}
@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustDecimal(t *testing.T, s string) Value {
+	t.Helper()
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatalf("ParseDecimal(%q) error = %v", s, err)
+	}
+	return NewDecimal(d)
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "0.1", want: "0.1"},
+		{input: "-12.50", want: "-12.5"},
+		{input: "+7", want: "7"},
+		{input: ".25", want: "0.25"},
+		{input: "3.", want: "3"},
+		{input: "123456789012345678901234567890.000000000000000000001", want: "123456789012345678901234567890.000000000000000000001"},
+		{input: "1/3", wantErr: true},
+		{input: "1e5", wantErr: true},
+		{input: " 1", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "0x10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDecimal(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDecimal(%q) = %s, want error", tt.input, DecimalString(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDecimal(%q) error = %v", tt.input, err)
+			}
+			if got := NewDecimal(d).String(); got != tt.want {
+				t.Errorf("ParseDecimal(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundDecimal(t *testing.T) {
+	tests := []struct {
+		input string
+		scale int
+		mode  string
+		want  string
+	}{
+		{input: "2.345", scale: 2, mode: RoundHalfEven, want: "2.34"},
+		{input: "2.355", scale: 2, mode: RoundHalfEven, want: "2.36"},
+		{input: "-2.345", scale: 2, mode: RoundHalfEven, want: "-2.34"},
+		{input: "2.345", scale: 2, mode: RoundHalfUp, want: "2.35"},
+		{input: "-2.345", scale: 2, mode: RoundHalfUp, want: "-2.35"},
+		{input: "2.345", scale: 2, mode: RoundHalfDown, want: "2.34"},
+		{input: "2.3451", scale: 2, mode: RoundHalfDown, want: "2.35"},
+		{input: "2.341", scale: 2, mode: RoundUp, want: "2.35"},
+		{input: "-2.341", scale: 2, mode: RoundUp, want: "-2.35"},
+		{input: "2.349", scale: 2, mode: RoundDown, want: "2.34"},
+		{input: "-2.349", scale: 2, mode: RoundDown, want: "-2.34"},
+		{input: "-2.341", scale: 2, mode: RoundCeiling, want: "-2.34"},
+		{input: "2.341", scale: 2, mode: RoundCeiling, want: "2.35"},
+		{input: "-2.341", scale: 2, mode: RoundFloor, want: "-2.35"},
+		{input: "2.5", scale: 0, mode: RoundHalfEven, want: "2"},
+		{input: "3.5", scale: 0, mode: RoundHalfEven, want: "4"},
+		{input: "1.5", scale: 3, mode: RoundUp, want: "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.mode, func(t *testing.T) {
+			d, _ := mustDecimal(t, tt.input).AsDecimal()
+			rounded, err := RoundDecimal(d, tt.scale, tt.mode)
+			if err != nil {
+				t.Fatalf("RoundDecimal() error = %v", err)
+			}
+			if got := DecimalString(rounded); got != tt.want {
+				t.Errorf("RoundDecimal(%s, %d, %s) = %s, want %s", tt.input, tt.scale, tt.mode, got, tt.want)
+			}
+		})
+	}
+
+	d, _ := mustDecimal(t, "1.5").AsDecimal()
+	if _, err := RoundDecimal(d, 2, "nearest"); err == nil || !strings.Contains(err.Error(), "unknown rounding mode") {
+		t.Errorf("RoundDecimal() with unknown mode error = %v", err)
+	}
+	if _, err := RoundDecimal(d, -1, RoundHalfEven); err == nil {
+		t.Error("RoundDecimal() with negative scale succeeded, want error")
+	}
+}
+
+func TestQuoDecimal(t *testing.T) {
+	one, _ := mustDecimal(t, "1").AsDecimal()
+	four, _ := mustDecimal(t, "4").AsDecimal()
+	three, _ := mustDecimal(t, "3").AsDecimal()
+	zero, _ := mustDecimal(t, "0").AsDecimal()
+
+	q, err := QuoDecimal(one, four)
+	if err != nil || DecimalString(q) != "0.25" {
+		t.Errorf("QuoDecimal(1, 4) = %v, %v, want 0.25", q, err)
+	}
+	if _, err := QuoDecimal(one, three); err == nil || !strings.Contains(err.Error(), "no exact decimal result") {
+		t.Errorf("QuoDecimal(1, 3) error = %v, want inexact error", err)
+	}
+	if _, err := QuoDecimal(one, zero); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("QuoDecimal(1, 0) error = %v, want division by zero", err)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	data, err := mustDecimal(t, "-0.30").MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	if string(data) != "-0.3" {
+		t.Errorf("MarshalJSON() = %s, want -0.3", data)
+	}
+}
@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions controls how Format lays out a value.
+type FormatOptions struct {
+	// Indent is repeated once per nesting level before each array element
+	// and map entry, which are then written one per line. An empty Indent
+	// writes arrays and maps on a single line.
+	Indent string
+	// MaxDepth is the number of nested arrays, maps, and enum payloads
+	// written out; deeper ones are elided as [...] or {...}. Zero means no
+	// limit.
+	MaxDepth int
+	// SortKeys writes map entries and enum payload fields in key order
+	// rather than Go's random map order.
+	SortKeys bool
+}
+
+// DefaultFormatOptions returns the options used for debug dumps: two-space
+// indentation, no depth limit, and sorted keys.
+func DefaultFormatOptions() FormatOptions {
+	return FormatOptions{Indent: "  ", SortKeys: true}
+}
+
+// Format returns a readable representation of the value. Unlike String,
+// strings are quoted, floats are written in their shortest form, and nested
+// arrays and maps are written element by element. An array or map that
+// contains itself is written as <cycle> where it recurs.
+func (v Value) Format(opts FormatOptions) string {
+	f := formatter{opts: opts, visiting: make(map[uintptr]bool)}
+	f.value(v, 0)
+	return f.b.String()
+}
+
+// formatter writes a value for Format.
+type formatter struct {
+	opts     FormatOptions
+	b        strings.Builder
+	visiting map[uintptr]bool // Arrays and maps being written, by address
+}
+
+func (f *formatter) value(v Value, depth int) {
+	switch v.Type {
+	case ValueTypeInt:
+		f.b.WriteString(strconv.FormatInt(v.Value.(int64), 10))
+	case ValueTypeFloat:
+		f.b.WriteString(strconv.FormatFloat(v.Value.(float64), 'g', -1, 64))
+	case ValueTypeString:
+		f.b.WriteString(strconv.Quote(v.Value.(string)))
+	case ValueTypeArray:
+		arr, err := v.AsArray()
+		if err != nil {
+			f.b.WriteString(err.Error())
+			return
+		}
+		f.container(depth, "[", "]", len(arr), addressOf(arr), func(i int) {
+			f.value(arr[i], depth+1)
+		})
+	case ValueTypeMap:
+		m, err := v.AsMap()
+		if err != nil {
+			f.b.WriteString(err.Error())
+			return
+		}
+		keys := f.keys(m)
+		f.container(depth, "{", "}", len(keys), addressOf(m), func(i int) {
+			f.value(MapKeyValue(keys[i]), depth+1)
+			f.b.WriteString(": ")
+			f.value(m[keys[i]], depth+1)
+		})
+	case ValueTypeEnum:
+		ev := v.Value.(*EnumValue)
+		fmt.Fprintf(&f.b, "%s.%s", ev.Enum, ev.Variant)
+		if len(ev.Fields) == 0 {
+			return
+		}
+		keys := f.keys(ev.Fields)
+		f.container(depth, "{", "}", len(keys), 0, func(i int) {
+			f.b.WriteString(keys[i])
+			f.b.WriteString(": ")
+			f.value(ev.Fields[keys[i]], depth+1)
+		})
+	default:
+		f.b.WriteString(v.String())
+	}
+}
+
+// container writes n elements between open and close, calling element to
+// write each one. A nonzero addr identifies the array or map for cycle
+// detection.
+func (f *formatter) container(depth int, open, close string, n int, addr uintptr, element func(i int)) {
+	switch {
+	case n == 0:
+		f.b.WriteString(open + close)
+		return
+	case f.opts.MaxDepth > 0 && depth >= f.opts.MaxDepth:
+		f.b.WriteString(open + "..." + close)
+		return
+	case addr != 0 && f.visiting[addr]:
+		f.b.WriteString("<cycle>")
+		return
+	}
+	if addr != 0 {
+		f.visiting[addr] = true
+		defer delete(f.visiting, addr)
+	}
+
+	f.b.WriteString(open)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			f.b.WriteString(",")
+			if f.opts.Indent == "" {
+				f.b.WriteString(" ")
+			}
+		}
+		f.newline(depth + 1)
+		element(i)
+	}
+	f.newline(depth)
+	f.b.WriteString(close)
+}
+
+// newline starts a line indented to depth, when indenting.
+func (f *formatter) newline(depth int) {
+	if f.opts.Indent != "" {
+		f.b.WriteString("\n")
+		f.b.WriteString(strings.Repeat(f.opts.Indent, depth))
+	}
+}
+
+// keys returns the keys of a map, sorted if requested.
+func (f *formatter) keys(m map[string]Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	if f.opts.SortKeys {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// addressOf returns the address of the storage backing a slice or map.
+func addressOf(x interface{}) uintptr {
+	return reflect.ValueOf(x).Pointer()
+}
@@ -0,0 +1,88 @@
+package runtime
+
+import "testing"
+
+func TestValueFormat(t *testing.T) {
+	nested := NewMap(map[string]Value{
+		"name": NewString("Ann"),
+		"tags": NewArray([]Value{NewString("a"), NewString("b")}),
+		"pos":  NewMap(map[string]Value{"y": NewFloat(2.5), "x": NewInt(1)}),
+	})
+	compact := FormatOptions{SortKeys: true}
+
+	tests := []struct {
+		name  string
+		value Value
+		opts  FormatOptions
+		want  string
+	}{
+		{name: "int", value: NewInt(-42), opts: compact, want: `-42`},
+		{name: "float", value: NewFloat(0.1), opts: compact, want: `0.1`},
+		{name: "string", value: NewString("say \"hi\"\n"), opts: compact, want: `"say \"hi\"\n"`},
+		{name: "void", value: NewVoid(), opts: compact, want: `void`},
+		{name: "empty array", value: NewArray(nil), opts: DefaultFormatOptions(), want: `[]`},
+		{name: "empty map", value: NewGCMap(map[string]Value{}), opts: DefaultFormatOptions(), want: `{}`},
+		{
+			name:  "compact nested",
+			value: nested,
+			opts:  compact,
+			want:  `{"name": "Ann", "pos": {"x": 1, "y": 2.5}, "tags": ["a", "b"]}`,
+		},
+		{
+			name:  "indented nested",
+			value: nested,
+			opts:  DefaultFormatOptions(),
+			want: `{
+  "name": "Ann",
+  "pos": {
+    "x": 1,
+    "y": 2.5
+  },
+  "tags": [
+    "a",
+    "b"
+  ]
+}`,
+		},
+		{
+			name:  "max depth",
+			value: nested,
+			opts:  FormatOptions{MaxDepth: 1, SortKeys: true},
+			want:  `{"name": "Ann", "pos": {...}, "tags": [...]}`,
+		},
+		{
+			name:  "gc array",
+			value: NewGCArray([]Value{NewInt(7), NewBool(true)}),
+			opts:  FormatOptions{Indent: "\t"},
+			want:  "[\n\t7,\n\ttrue\n]",
+		},
+		{name: "plain enum", value: NewEnum("Color", "Red", nil), opts: compact, want: `Color.Red`},
+		{
+			name:  "enum with payload",
+			value: NewEnum("Shape", "Rect", map[string]Value{"w": NewFloat(2), "h": NewInt(3)}),
+			opts:  compact,
+			want:  `Shape.Rect{h: 3, w: 2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.value.Release()
+			if got := tt.value.Format(tt.opts); got != tt.want {
+				t.Errorf("Format() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueFormatCycle(t *testing.T) {
+	m := map[string]Value{"n": NewInt(1)}
+	m["self"] = NewMap(m)
+	shared := NewArray([]Value{NewInt(0)})
+	m["shared"] = NewArray([]Value{shared, shared})
+
+	want := `{"n": 1, "self": <cycle>, "shared": [[0], [0]]}`
+	if got := NewMap(m).Format(FormatOptions{SortKeys: true}); got != want {
+		t.Errorf("Format() = %s, want %s", got, want)
+	}
+}
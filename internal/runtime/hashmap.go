@@ -0,0 +1,253 @@
+package runtime
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// HashMap is a hash table keyed by int, bool or string values, used as the backing
+// store for maps in compiled code. Keys and values are returned in insertion order.
+type HashMap struct {
+	slots      []int32 // 0 = empty, -1 = deleted, otherwise entry index + 1
+	entries    []hashEntry
+	count      int
+	tombstones int
+}
+
+type hashEntry struct {
+	key     Value
+	value   Value
+	hash    uint64
+	removed bool
+}
+
+const (
+	hashMapMinSlots = 8
+	slotEmpty       = 0
+	slotDeleted     = -1
+)
+
+// NewHashMap creates an empty hash map.
+func NewHashMap() *HashMap {
+	return &HashMap{slots: make([]int32, hashMapMinSlots)}
+}
+
+// hashKey hashes an int, bool or string key.
+func hashKey(key Value) (uint64, error) {
+	switch key.Type {
+	case ValueTypeInt, ValueTypeBool:
+		// splitmix64 finalizer spreads sequential integers across slots
+		var x uint64
+		if key.Type == ValueTypeInt {
+			x = uint64(key.Value.(int64))
+		} else if key.Value.(bool) {
+			x = 1
+		}
+		x ^= x >> 30
+		x *= 0xbf58476d1ce4e5b9
+		x ^= x >> 27
+		x *= 0x94d049bb133111eb
+		x ^= x >> 31
+		return x, nil
+	case ValueTypeString:
+		h := fnv.New64a()
+		h.Write([]byte(key.Value.(string)))
+		return h.Sum64(), nil
+	default:
+		return 0, fmt.Errorf("map keys must be int, bool or string, got %v", key.Type)
+	}
+}
+
+// mapKeyTag starts the stored form of every int and bool key, and of string
+// keys that themselves start with it, so that no two keys share a form.
+const mapKeyTag = "\x00"
+
+// MapKey returns the string under which an interpreter map stores key, and
+// false if key cannot be a map key. Keys must be int, bool or string and, as
+// for HashMap, keys of different types are distinct: the int 1 and the string
+// "1" name different entries. String keys are stored as themselves, so struct
+// fields keep their names.
+func MapKey(key Value) (string, bool) {
+	switch key.Type {
+	case ValueTypeInt:
+		return mapKeyTag + "i" + strconv.FormatInt(key.Value.(int64), 10), true
+	case ValueTypeBool:
+		return mapKeyTag + "b" + strconv.FormatBool(key.Value.(bool)), true
+	case ValueTypeString:
+		if s := key.Value.(string); strings.HasPrefix(s, mapKeyTag) {
+			return mapKeyTag + "s" + s, true
+		}
+		return key.Value.(string), true
+	default:
+		return "", false
+	}
+}
+
+// MapKeyValue returns the key an interpreter map stores under the string
+// returned by MapKey.
+func MapKeyValue(stored string) Value {
+	if !strings.HasPrefix(stored, mapKeyTag) || len(stored) < len(mapKeyTag)+1 {
+		return NewString(stored)
+	}
+	text := stored[len(mapKeyTag)+1:]
+	switch stored[len(mapKeyTag)] {
+	case 'i':
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return NewInt(n)
+		}
+	case 'b':
+		if b, err := strconv.ParseBool(text); err == nil {
+			return NewBool(b)
+		}
+	case 's':
+		return NewString(text)
+	}
+	return NewString(stored)
+}
+
+func keysEqual(a, b Value) bool {
+	return a.Type == b.Type && a.Value == b.Value
+}
+
+// find returns the slot holding key, or -1 if the key is absent.
+func (m *HashMap) find(key Value, hash uint64) int {
+	mask := uint64(len(m.slots) - 1)
+	for i, probe := hash&mask, 0; probe < len(m.slots); i, probe = (i+1)&mask, probe+1 {
+		switch slot := m.slots[i]; slot {
+		case slotEmpty:
+			return -1
+		case slotDeleted:
+			continue
+		default:
+			entry := &m.entries[slot-1]
+			if entry.hash == hash && keysEqual(entry.key, key) {
+				return int(i)
+			}
+		}
+	}
+	return -1
+}
+
+// grow rebuilds the table when it is three quarters full, dropping removed
+// entries and doubling the slot count if needed.
+func (m *HashMap) grow() {
+	if (m.count+m.tombstones+1)*4 < len(m.slots)*3 {
+		return
+	}
+
+	size := len(m.slots)
+	for (m.count+1)*2 > size {
+		size *= 2
+	}
+
+	entries := make([]hashEntry, 0, m.count+1)
+	for _, entry := range m.entries {
+		if !entry.removed {
+			entries = append(entries, entry)
+		}
+	}
+	m.entries = entries
+	m.slots = make([]int32, size)
+	m.tombstones = 0
+	mask := uint64(size - 1)
+	for idx, entry := range m.entries {
+		i := entry.hash & mask
+		for m.slots[i] != slotEmpty {
+			i = (i + 1) & mask
+		}
+		m.slots[i] = int32(idx + 1) // #nosec G115 -- entry count is bounded by slot count
+	}
+}
+
+// Put inserts or updates the value for key.
+func (m *HashMap) Put(key, value Value) error {
+	hash, err := hashKey(key)
+	if err != nil {
+		return err
+	}
+	if i := m.find(key, hash); i >= 0 {
+		m.entries[m.slots[i]-1].value = value
+		return nil
+	}
+
+	m.grow()
+	mask := uint64(len(m.slots) - 1)
+	i := hash & mask
+	for m.slots[i] != slotEmpty && m.slots[i] != slotDeleted {
+		i = (i + 1) & mask
+	}
+	if m.slots[i] == slotDeleted {
+		m.tombstones--
+	}
+	m.entries = append(m.entries, hashEntry{key: key, value: value, hash: hash})
+	m.slots[i] = int32(len(m.entries)) // #nosec G115 -- entry count is bounded by slot count
+	m.count++
+	return nil
+}
+
+// Get returns the value for key and whether it was present.
+func (m *HashMap) Get(key Value) (Value, bool) {
+	hash, err := hashKey(key)
+	if err != nil {
+		return NewVoid(), false
+	}
+	i := m.find(key, hash)
+	if i < 0 {
+		return NewVoid(), false
+	}
+	return m.entries[m.slots[i]-1].value, true
+}
+
+// Contains reports whether key is present.
+func (m *HashMap) Contains(key Value) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Remove deletes key, reporting whether it was present.
+func (m *HashMap) Remove(key Value) bool {
+	hash, err := hashKey(key)
+	if err != nil {
+		return false
+	}
+	i := m.find(key, hash)
+	if i < 0 {
+		return false
+	}
+	entry := &m.entries[m.slots[i]-1]
+	entry.removed = true
+	entry.key, entry.value = NewVoid(), NewVoid()
+	m.slots[i] = slotDeleted
+	m.count--
+	m.tombstones++
+	return true
+}
+
+// Len returns the number of entries.
+func (m *HashMap) Len() int {
+	return m.count
+}
+
+// Keys returns the keys in insertion order.
+func (m *HashMap) Keys() []Value {
+	keys := make([]Value, 0, m.count)
+	for _, entry := range m.entries {
+		if !entry.removed {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+// Values returns the values in insertion order.
+func (m *HashMap) Values() []Value {
+	values := make([]Value, 0, m.count)
+	for _, entry := range m.entries {
+		if !entry.removed {
+			values = append(values, entry.value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashMap_PutGet(t *testing.T) {
+	m := NewHashMap()
+
+	if err := m.Put(NewString("name"), NewString("Alice")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := m.Put(NewInt(7), NewInt(49)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := m.Put(NewString("name"), NewString("Bob")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", m.Len())
+	}
+	if v, ok := m.Get(NewString("name")); !ok || v.Value != "Bob" {
+		t.Errorf("Expected name = Bob, got %v (found=%v)", v, ok)
+	}
+	if v, ok := m.Get(NewInt(7)); !ok || v.Value != int64(49) {
+		t.Errorf("Expected 7 = 49, got %v (found=%v)", v, ok)
+	}
+	// int 7 and string "7" are distinct keys
+	if m.Contains(NewString("7")) {
+		t.Error("Expected string key \"7\" to be absent")
+	}
+	if v, ok := m.Get(NewString("missing")); ok || v.Type != ValueTypeVoid {
+		t.Errorf("Expected missing key to return void, got %v (found=%v)", v, ok)
+	}
+}
+
+func TestHashMap_InvalidKey(t *testing.T) {
+	m := NewHashMap()
+	if err := m.Put(NewFloat(1.5), NewInt(1)); err == nil {
+		t.Error("Expected error for float key")
+	}
+	if m.Contains(NewBool(true)) {
+		t.Error("Expected bool key to be absent")
+	}
+}
+
+func TestHashMap_BoolKeys(t *testing.T) {
+	m := NewHashMap()
+	for _, key := range []Value{NewBool(true), NewBool(false), NewInt(1), NewInt(0)} {
+		if err := m.Put(key, key); err != nil {
+			t.Fatalf("Put(%v) error = %v", key, err)
+		}
+	}
+	if m.Len() != 4 {
+		t.Errorf("Len() = %d, want bool keys distinct from int keys", m.Len())
+	}
+	if got, ok := m.Get(NewBool(true)); !ok || got.Type != ValueTypeBool {
+		t.Errorf("Get(true) = %v, %v", got, ok)
+	}
+}
+
+func TestMapKey(t *testing.T) {
+	keys := []Value{NewInt(1), NewString("1"), NewBool(true), NewString("true"), NewInt(-7), NewString("\x00i1"), NewString("")}
+	seen := make(map[string]Value)
+	for _, key := range keys {
+		stored, ok := MapKey(key)
+		if !ok {
+			t.Fatalf("MapKey(%v) not ok", key)
+		}
+		if other, dup := seen[stored]; dup {
+			t.Errorf("MapKey(%v) = MapKey(%v) = %q", key, other, stored)
+		}
+		seen[stored] = key
+		if got := MapKeyValue(stored); !keysEqual(got, key) {
+			t.Errorf("MapKeyValue(MapKey(%v)) = %v (%v)", key, got, got.Type)
+		}
+	}
+	if stored, _ := MapKey(NewString("name")); stored != "name" {
+		t.Errorf("MapKey(\"name\") = %q, want string keys stored as themselves", stored)
+	}
+	if _, ok := MapKey(NewFloat(1.5)); ok {
+		t.Error("MapKey(1.5) ok, want float keys rejected")
+	}
+}
+
+func TestHashMap_RemoveAndGrow(t *testing.T) {
+	m := NewHashMap()
+	const n = 1000
+
+	for i := 0; i < n; i++ {
+		if err := m.Put(NewInt(int64(i)), NewString(fmt.Sprint(i))); err != nil {
+			t.Fatalf("Put(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < n; i += 2 {
+		if !m.Remove(NewInt(int64(i))) {
+			t.Fatalf("Remove(%d) = false, want true", i)
+		}
+	}
+	if m.Remove(NewInt(0)) {
+		t.Error("Expected second Remove(0) to return false")
+	}
+	if m.Len() != n/2 {
+		t.Fatalf("Expected %d entries, got %d", n/2, m.Len())
+	}
+
+	// Reinsert into tombstoned slots and force further growth
+	for i := n; i < 2*n; i++ {
+		if err := m.Put(NewInt(int64(i)), NewString(fmt.Sprint(i))); err != nil {
+			t.Fatalf("Put(%d) error = %v", i, err)
+		}
+	}
+	for i := 0; i < 2*n; i++ {
+		want := i%2 == 1 || i >= n
+		if got := m.Contains(NewInt(int64(i))); got != want {
+			t.Errorf("Contains(%d) = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestHashMap_InsertionOrder(t *testing.T) {
+	m := NewHashMap()
+	for _, k := range []string{"c", "a", "b", "d"} {
+		_ = m.Put(NewString(k), NewString(k+k))
+	}
+	m.Remove(NewString("a"))
+	_ = m.Put(NewString("a"), NewString("again"))
+
+	keys := m.Keys()
+	values := m.Values()
+	wantKeys := []string{"c", "b", "d", "a"}
+	wantValues := []string{"cc", "bb", "dd", "again"}
+	if len(keys) != len(wantKeys) || len(values) != len(wantValues) {
+		t.Fatalf("Expected %d keys and values, got %d and %d", len(wantKeys), len(keys), len(values))
+	}
+	for i := range wantKeys {
+		if keys[i].Value != wantKeys[i] {
+			t.Errorf("Keys()[%d] = %v, want %s", i, keys[i], wantKeys[i])
+		}
+		if values[i].Value != wantValues[i] {
+			t.Errorf("Values()[%d] = %v, want %s", i, values[i], wantValues[i])
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON implements json.Marshaler. Numbers, strings and booleans
+// encode as the matching JSON values, arrays as JSON arrays, maps as JSON
+// objects, and void as null. Enums encode as an object holding the enum and
+// variant names and, when present, the payload fields. Functions, string
+// builders, channels, mutexes and non-finite floats have no JSON form and
+// return an error.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.Type {
+	case ValueTypeInt:
+		return json.Marshal(v.Value.(int64))
+	case ValueTypeBigInt:
+		return []byte(v.Value.(*big.Int).String()), nil
+	case ValueTypeDecimal:
+		return []byte(DecimalString(v.Value.(*big.Rat))), nil
+	case ValueTypeFloat:
+		f := v.Value.(float64)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("cannot encode %v as JSON", f)
+		}
+		return json.Marshal(f)
+	case ValueTypeString:
+		return json.Marshal(v.Value.(string))
+	case ValueTypeBool:
+		return json.Marshal(v.Value.(bool))
+	case ValueTypeArray:
+		arr, err := v.AsArray()
+		if err != nil {
+			return nil, err
+		}
+		if arr == nil {
+			arr = []Value{}
+		}
+		return json.Marshal(arr)
+	case ValueTypeMap:
+		m, err := v.AsMap()
+		if err != nil {
+			return nil, err
+		}
+		// JSON object keys are text, so int and bool keys are written as theirs
+		fields := make(map[string]Value, len(m))
+		for key, val := range m {
+			fields[MapKeyValue(key).String()] = val
+		}
+		return json.Marshal(fields)
+	case ValueTypeVoid:
+		return []byte("null"), nil
+	case ValueTypeEnum:
+		ev := v.Value.(*EnumValue)
+		return json.Marshal(struct {
+			Enum    string           `json:"enum"`
+			Variant string           `json:"variant"`
+			Fields  map[string]Value `json:"fields,omitempty"`
+		}{ev.Enum, ev.Variant, ev.Fields})
+	case ValueTypeFunction, ValueTypeStringBuilder, ValueTypeChannel, ValueTypeMutex:
+		return nil, fmt.Errorf("cannot encode %s as JSON", v.String())
+	default:
+		return nil, fmt.Errorf("cannot encode value of type %d as JSON", v.Type)
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler, mapping JSON types directly to
+// runtime values. Numbers written with a fraction or exponent become floats
+// and other numbers ints, or bigints when they do not fit in 64 bits. Arrays
+// become arrays, objects maps, and null void. Objects are always decoded as
+// maps, never as enums, and numbers never as decimals.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	val, err := fromJSON(raw)
+	if err != nil {
+		return err
+	}
+	*v = val
+	return nil
+}
+
+// fromJSON converts a value decoded with json.Decoder.UseNumber.
+func fromJSON(raw interface{}) (Value, error) {
+	switch raw := raw.(type) {
+	case nil:
+		return NewVoid(), nil
+	case bool:
+		return NewBool(raw), nil
+	case string:
+		return NewString(raw), nil
+	case json.Number:
+		if strings.ContainsAny(raw.String(), ".eE") {
+			f, err := raw.Float64()
+			if err != nil {
+				return NewVoid(), fmt.Errorf("invalid number %s: %v", raw, err)
+			}
+			return NewFloat(f), nil
+		}
+		if n, err := strconv.ParseInt(raw.String(), 10, 64); err == nil {
+			return NewInt(n), nil
+		}
+		n, ok := new(big.Int).SetString(raw.String(), 10)
+		if !ok {
+			return NewVoid(), fmt.Errorf("invalid integer %s", raw)
+		}
+		return NewBigInt(n), nil
+	case []interface{}:
+		elements := make([]Value, len(raw))
+		for i, element := range raw {
+			val, err := fromJSON(element)
+			if err != nil {
+				return NewVoid(), err
+			}
+			elements[i] = val
+		}
+		return NewArray(elements), nil
+	case map[string]interface{}:
+		m := make(map[string]Value, len(raw))
+		for key, element := range raw {
+			val, err := fromJSON(element)
+			if err != nil {
+				return NewVoid(), err
+			}
+			stored, _ := MapKey(NewString(key))
+			m[stored] = val
+		}
+		return NewMap(m), nil
+	default:
+		return NewVoid(), fmt.Errorf("unsupported JSON value %v", raw)
+	}
+}
@@ -0,0 +1,112 @@
+package runtime
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValueMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   Value
+		want    string
+		wantErr string
+	}{
+		{name: "int", value: NewInt(-42), want: `-42`},
+		{name: "float", value: NewFloat(2.5), want: `2.5`},
+		{name: "string", value: NewString("say \"hi\"\n"), want: `"say \"hi\"\n"`},
+		{name: "bool", value: NewBool(true), want: `true`},
+		{name: "void", value: NewVoid(), want: `null`},
+		{name: "empty array", value: NewArray(nil), want: `[]`},
+		{
+			name:  "nested array",
+			value: NewArray([]Value{NewInt(1), NewArray([]Value{NewString("x"), NewVoid()})}),
+			want:  `[1,["x",null]]`,
+		},
+		{
+			name:  "map with sorted keys",
+			value: NewMap(map[string]Value{"b": NewBool(false), "a": NewFloat(0.5)}),
+			want:  `{"a":0.5,"b":false}`,
+		},
+		{name: "empty map", value: NewMap(nil), want: `{}`},
+		{name: "gc array", value: NewGCArray([]Value{NewInt(7)}), want: `[7]`},
+		{name: "gc map", value: NewGCMap(map[string]Value{"k": NewInt(1)}), want: `{"k":1}`},
+		{name: "plain enum", value: NewEnum("Color", "Red", nil), want: `{"enum":"Color","variant":"Red"}`},
+		{
+			name:  "enum with payload",
+			value: NewEnum("Shape", "Circle", map[string]Value{"radius": NewFloat(1.5)}),
+			want:  `{"enum":"Shape","variant":"Circle","fields":{"radius":1.5}}`,
+		},
+		{name: "NaN", value: NewFloat(math.NaN()), wantErr: "cannot encode NaN"},
+		{name: "infinity in array", value: NewArray([]Value{NewFloat(math.Inf(1))}), wantErr: "cannot encode +Inf"},
+		{name: "function", value: NewFunction("f", 0, nil), wantErr: "cannot encode <function f>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("json.Marshal() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantType ValueType
+		want     string
+		wantErr  string
+	}{
+		{name: "int", input: `42`, wantType: ValueTypeInt, want: `42`},
+		{name: "negative int", input: `-7`, wantType: ValueTypeInt, want: `-7`},
+		{name: "float with fraction", input: `1.0`, wantType: ValueTypeFloat, want: `1`},
+		{name: "float with exponent", input: `2e3`, wantType: ValueTypeFloat, want: `2000`},
+		{name: "numeric string stays a string", input: `"123"`, wantType: ValueTypeString, want: `"123"`},
+		{name: "bool", input: `false`, wantType: ValueTypeBool, want: `false`},
+		{name: "null", input: `null`, wantType: ValueTypeVoid, want: `null`},
+		{name: "array", input: `[1, 2.5, "x", [true]]`, wantType: ValueTypeArray, want: `[1,2.5,"x",[true]]`},
+		{name: "object", input: `{"b": {"c": 1}, "a": []}`, wantType: ValueTypeMap, want: `{"a":[],"b":{"c":1}}`},
+		{name: "integer beyond int64 is a bigint", input: `9223372036854775808`, wantType: ValueTypeBigInt, want: `9223372036854775808`},
+		{name: "invalid JSON", input: `[1,`, wantErr: "unexpected end of JSON input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Value
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("json.Unmarshal() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got.Type != tt.wantType {
+				t.Errorf("type = %v, want %v", got.Type, tt.wantType)
+			}
+			encoded, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(encoded) != tt.want {
+				t.Errorf("round trip = %s, want %s", encoded, tt.want)
+			}
+		})
+	}
+}
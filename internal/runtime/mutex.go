@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+)
+
+// Mutex provides mutual exclusion between async tasks. Like sync.Mutex it
+// is not reentrant and is not owned by the task that locked it, but locking
+// can give up when a context is done and unlocking an unlocked mutex is an
+// error rather than a crash.
+type Mutex struct {
+	sem chan struct{}
+}
+
+// NewMutex creates an unlocked mutex.
+func NewMutex() Value {
+	return Value{Type: ValueTypeMutex, Value: &Mutex{sem: make(chan struct{}, 1)}}
+}
+
+// AsMutex returns the value as a mutex.
+func (v Value) AsMutex() (*Mutex, error) {
+	if v.Type != ValueTypeMutex {
+		return nil, fmt.Errorf("value is not a mutex")
+	}
+	return v.Value.(*Mutex), nil
+}
+
+// Lock blocks until the mutex is unlocked and then locks it. It fails if
+// ctx is done first.
+func (m *Mutex) Lock(ctx context.Context) error {
+	select {
+	case m.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock unlocks the mutex, which must be locked.
+func (m *Mutex) Unlock() error {
+	select {
+	case <-m.sem:
+		return nil
+	default:
+		return fmt.Errorf("unlock of unlocked mutex")
+	}
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMutex(t *testing.T) {
+	m, err := NewMutex().AsMutex()
+	if err != nil {
+		t.Fatalf("AsMutex() error = %v", err)
+	}
+	if err := m.Unlock(); err == nil || !strings.Contains(err.Error(), "unlock of unlocked mutex") {
+		t.Errorf("Unlock() of unlocked mutex error = %v", err)
+	}
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	// Locking again is not reentrant, so it waits until the context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Lock(ctx); err != context.DeadlineExceeded {
+		t.Errorf("second Lock() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Another goroutine may unlock it
+	locked := make(chan error, 1)
+	go func() {
+		locked <- m.Lock(context.Background())
+	}()
+	if err := m.Unlock(); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Errorf("Lock() after Unlock() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Lock() still blocked after Unlock()")
+	}
+
+	if _, err := NewInt(1).AsMutex(); err == nil {
+		t.Error("AsMutex() of an int succeeded, want error")
+	}
+}
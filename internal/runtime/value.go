@@ -1,7 +1,10 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"math/big"
+	"strings"
 )
 
 // GCValue wraps a garbage-collected object with its ID.
@@ -21,6 +24,13 @@ const (
 	ValueTypeArray
 	ValueTypeMap
 	ValueTypeVoid
+	ValueTypeEnum
+	ValueTypeFunction
+	ValueTypeBigInt
+	ValueTypeDecimal
+	ValueTypeStringBuilder
+	ValueTypeChannel
+	ValueTypeMutex
 )
 
 // Value represents a runtime value in ALaS.
@@ -29,11 +39,44 @@ type Value struct {
 	Type  ValueType
 }
 
+// EnumValue is a tagged enum value: the variant name plus its payload fields.
+type EnumValue struct {
+	Enum    string
+	Variant string
+	Fields  map[string]Value
+}
+
+// FunctionValue is a callable value such as a closure.
+type FunctionValue struct {
+	Name        string // empty for anonymous functions
+	Arity       int
+	Call        func(args []Value) (Value, error)
+	CallContext func(ctx context.Context, args []Value) (Value, error) // nil if the function ignores contexts
+}
+
+// CallWith calls the function under ctx, which blocking builtins it calls
+// stop waiting on when it is done. Functions without CallContext ignore ctx.
+func (f *FunctionValue) CallWith(ctx context.Context, args []Value) (Value, error) {
+	if f.CallContext != nil {
+		return f.CallContext(ctx, args)
+	}
+	return f.Call(args)
+}
+
 // NewInt creates a new integer value.
 func NewInt(v int64) Value {
 	return Value{Type: ValueTypeInt, Value: v}
 }
 
+// NewBigInt creates an integer value from a big integer. Values that fit in
+// int64 become plain ints, so a bigint always lies outside the int64 range.
+func NewBigInt(v *big.Int) Value {
+	if v.IsInt64() {
+		return NewInt(v.Int64())
+	}
+	return Value{Type: ValueTypeBigInt, Value: new(big.Int).Set(v)}
+}
+
 // NewFloat creates a new float value.
 func NewFloat(v float64) Value {
 	return Value{Type: ValueTypeFloat, Value: v}
@@ -73,6 +116,37 @@ func NewGCMap(v map[string]Value) Value {
 	return Value{Type: ValueTypeMap, Value: gcVal}
 }
 
+// NewEnum creates a new enum value for the given variant and payload.
+func NewEnum(enum, variant string, fields map[string]Value) Value {
+	if fields == nil {
+		fields = make(map[string]Value)
+	}
+	return Value{Type: ValueTypeEnum, Value: &EnumValue{Enum: enum, Variant: variant, Fields: fields}}
+}
+
+// NewFunction creates a new function value that calls the given callback.
+func NewFunction(name string, arity int, call func(args []Value) (Value, error)) Value {
+	return Value{Type: ValueTypeFunction, Value: &FunctionValue{Name: name, Arity: arity, Call: call}}
+}
+
+// NewContextFunction creates a new function value that calls the given
+// callback with the context it is called under, or context.Background when
+// called without one.
+func NewContextFunction(name string, arity int, call func(ctx context.Context, args []Value) (Value, error)) Value {
+	return Value{Type: ValueTypeFunction, Value: &FunctionValue{
+		Name:        name,
+		Arity:       arity,
+		Call:        func(args []Value) (Value, error) { return call(context.Background(), args) },
+		CallContext: call,
+	}}
+}
+
+// NewStringBuilder creates an empty string builder. A builder is a mutable
+// reference: every copy of the value appends to the same buffer.
+func NewStringBuilder() Value {
+	return Value{Type: ValueTypeStringBuilder, Value: &strings.Builder{}}
+}
+
 // NewVoid creates a void value.
 func NewVoid() Value {
 	return Value{Type: ValueTypeVoid, Value: nil}
@@ -85,6 +159,8 @@ func (v Value) AsInt() (int64, error) {
 		return v.Value.(int64), nil
 	case ValueTypeFloat:
 		return int64(v.Value.(float64)), nil
+	case ValueTypeBigInt:
+		return 0, fmt.Errorf("integer %s does not fit in 64 bits", v.Value.(*big.Int))
 	case ValueTypeString, ValueTypeBool, ValueTypeArray, ValueTypeMap, ValueTypeVoid:
 		return 0, fmt.Errorf("cannot convert %v to int", v.Type)
 	default:
@@ -99,6 +175,9 @@ func (v Value) AsFloat() (float64, error) {
 		return v.Value.(float64), nil
 	case ValueTypeInt:
 		return float64(v.Value.(int64)), nil
+	case ValueTypeBigInt:
+		f, _ := new(big.Float).SetInt(v.Value.(*big.Int)).Float64()
+		return f, nil
 	case ValueTypeString, ValueTypeBool, ValueTypeArray, ValueTypeMap, ValueTypeVoid:
 		return 0, fmt.Errorf("cannot convert %v to float", v.Type)
 	default:
@@ -106,6 +185,18 @@ func (v Value) AsFloat() (float64, error) {
 	}
 }
 
+// AsBigInt returns an int or bigint value as a new big integer.
+func (v Value) AsBigInt() (*big.Int, error) {
+	switch v.Type {
+	case ValueTypeInt:
+		return big.NewInt(v.Value.(int64)), nil
+	case ValueTypeBigInt:
+		return new(big.Int).Set(v.Value.(*big.Int)), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %v to bigint", v.Type)
+	}
+}
+
 // AsString returns the value as a string.
 func (v Value) AsString() (string, error) {
 	if v.Type != ValueTypeString {
@@ -158,6 +249,30 @@ func (v Value) AsMap() (map[string]Value, error) {
 	return v.Value.(map[string]Value), nil
 }
 
+// AsEnum returns the value as an enum value.
+func (v Value) AsEnum() (*EnumValue, error) {
+	if v.Type != ValueTypeEnum {
+		return nil, fmt.Errorf("value is not an enum")
+	}
+	return v.Value.(*EnumValue), nil
+}
+
+// AsFunction returns the value as a function.
+func (v Value) AsFunction() (*FunctionValue, error) {
+	if v.Type != ValueTypeFunction {
+		return nil, fmt.Errorf("value is not a function")
+	}
+	return v.Value.(*FunctionValue), nil
+}
+
+// AsStringBuilder returns the value as a string builder.
+func (v Value) AsStringBuilder() (*strings.Builder, error) {
+	if v.Type != ValueTypeStringBuilder {
+		return nil, fmt.Errorf("value is not a string builder")
+	}
+	return v.Value.(*strings.Builder), nil
+}
+
 // IsTruthy returns whether the value is truthy.
 func (v Value) IsTruthy() bool {
 	switch v.Type {
@@ -165,6 +280,10 @@ func (v Value) IsTruthy() bool {
 		return v.Value.(bool)
 	case ValueTypeInt:
 		return v.Value.(int64) != 0
+	case ValueTypeBigInt:
+		return v.Value.(*big.Int).Sign() != 0
+	case ValueTypeDecimal:
+		return v.Value.(*big.Rat).Sign() != 0
 	case ValueTypeFloat:
 		return v.Value.(float64) != 0
 	case ValueTypeString:
@@ -187,6 +306,8 @@ func (v Value) IsTruthy() bool {
 		return len(v.Value.(map[string]Value)) > 0
 	case ValueTypeVoid:
 		return false
+	case ValueTypeEnum, ValueTypeFunction, ValueTypeStringBuilder, ValueTypeChannel, ValueTypeMutex:
+		return true
 	default:
 		return false
 	}
@@ -197,6 +318,10 @@ func (v Value) String() string {
 	switch v.Type {
 	case ValueTypeInt:
 		return fmt.Sprintf("%d", v.Value.(int64))
+	case ValueTypeBigInt:
+		return v.Value.(*big.Int).String()
+	case ValueTypeDecimal:
+		return DecimalString(v.Value.(*big.Rat))
 	case ValueTypeFloat:
 		return fmt.Sprintf("%f", v.Value.(float64))
 	case ValueTypeString:
@@ -215,6 +340,23 @@ func (v Value) String() string {
 		return fmt.Sprintf("%v", v.Value)
 	case ValueTypeVoid:
 		return "void"
+	case ValueTypeEnum:
+		ev := v.Value.(*EnumValue)
+		if len(ev.Fields) == 0 {
+			return fmt.Sprintf("%s.%s", ev.Enum, ev.Variant)
+		}
+		return fmt.Sprintf("%s.%s%v", ev.Enum, ev.Variant, ev.Fields)
+	case ValueTypeFunction:
+		if fv := v.Value.(*FunctionValue); fv.Name != "" {
+			return fmt.Sprintf("<function %s>", fv.Name)
+		}
+		return "<lambda>"
+	case ValueTypeStringBuilder:
+		return "<stringBuilder>"
+	case ValueTypeChannel:
+		return "<channel>"
+	case ValueTypeMutex:
+		return "<mutex>"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,4 @@
+package runtime
+
+// Version is the ALaS version reported by the runtime.version builtin.
+const Version = "0.1.0"
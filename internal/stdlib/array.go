@@ -0,0 +1,264 @@
+package stdlib
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerArrayFunctions registers the std.array higher-order builtin functions.
+func (r *Registry) registerArrayFunctions() {
+	r.Register("array.map", func(args []runtime.Value) (runtime.Value, error) {
+		return mapArray("array.map", args)
+	})
+	r.Register("array.filter", func(args []runtime.Value) (runtime.Value, error) {
+		return filterArray("array.filter", args)
+	})
+	r.Register("array.reduce", arrayReduce)
+	r.Register("array.sort", arraySort)
+	r.Register("array.reverse", arrayReverse)
+	r.Register("array.indexOf", arrayIndexOf)
+	r.Register("array.contains", arrayContains)
+	r.Register("array.concat", arrayConcat)
+}
+
+// arrayFunctionArgs validates an (array, function, ...) argument list, where
+// the function must take arity arguments.
+func arrayFunctionArgs(name string, args []runtime.Value, wantArgs, arity int) ([]runtime.Value, *runtime.FunctionValue, error) {
+	if len(args) != wantArgs {
+		return nil, nil, fmt.Errorf("%s expects %d arguments, got %d", name, wantArgs, len(args))
+	}
+	arr, err := args[0].AsArray()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: first argument must be an array", name)
+	}
+	fn, err := args[1].AsFunction()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: second argument must be a function", name)
+	}
+	if fn.Arity != arity {
+		return nil, nil, fmt.Errorf("%s: function argument must take %d parameters, takes %d", name, arity, fn.Arity)
+	}
+	return arr, fn, nil
+}
+
+// mapArray calls a function on each array element, collecting the results.
+func mapArray(name string, args []runtime.Value) (runtime.Value, error) {
+	arr, fn, err := arrayFunctionArgs(name, args, 2, 1)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	result := make([]runtime.Value, len(arr))
+	for i, elem := range arr {
+		val, err := fn.Call([]runtime.Value{elem})
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		result[i] = val
+	}
+	return runtime.NewArray(result), nil
+}
+
+// filterArray keeps the array elements for which a predicate returns true.
+func filterArray(name string, args []runtime.Value) (runtime.Value, error) {
+	arr, fn, err := arrayFunctionArgs(name, args, 2, 1)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	result := make([]runtime.Value, 0, len(arr))
+	for _, elem := range arr {
+		val, err := fn.Call([]runtime.Value{elem})
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		keep, err := val.AsBool()
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("%s: predicate must return a bool", name)
+		}
+		if keep {
+			result = append(result, elem)
+		}
+	}
+	return runtime.NewArray(result), nil
+}
+
+// arrayReduce implements array.reduce builtin function. The function is called
+// with the accumulator and each element in turn, starting from the initial value.
+func arrayReduce(args []runtime.Value) (runtime.Value, error) {
+	arr, fn, err := arrayFunctionArgs("array.reduce", args, 3, 2)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	acc := args[2]
+	for _, elem := range arr {
+		acc, err = fn.Call([]runtime.Value{acc, elem})
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+	}
+	return acc, nil
+}
+
+// arraySort implements array.sort builtin function. Without a comparator the
+// elements must all be numbers or all be strings and are sorted ascending.
+// A comparator takes two elements and returns a negative int, zero, or a
+// positive int when the first sorts before, with, or after the second. The
+// input array is left unmodified.
+func arraySort(args []runtime.Value) (runtime.Value, error) {
+	if len(args) == 2 {
+		return sortArrayWith(args)
+	}
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("array.sort expects 1 to 2 arguments, got %d", len(args))
+	}
+	arr, err := args[0].AsArray()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.sort: first argument must be an array")
+	}
+
+	result := append([]runtime.Value(nil), arr...)
+	for i := 1; i < len(result); i++ {
+		if !orderable(result[0], result[i]) {
+			return runtime.NewVoid(), fmt.Errorf("array.sort: cannot compare %s and %s", valueTypeName(result[0]), valueTypeName(result[i]))
+		}
+	}
+	if len(result) > 0 && !isNumber(result[0]) && result[0].Type != runtime.ValueTypeString {
+		return runtime.NewVoid(), fmt.Errorf("array.sort: cannot sort %s elements without a comparator", valueTypeName(result[0]))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Type == runtime.ValueTypeString {
+			a, _ := result[i].AsString()
+			b, _ := result[j].AsString()
+			return a < b
+		}
+		a, _ := result[i].AsFloat()
+		b, _ := result[j].AsFloat()
+		return a < b
+	})
+	return runtime.NewArray(result), nil
+}
+
+// sortArrayWith sorts a copy of an array using a comparator function.
+func sortArrayWith(args []runtime.Value) (runtime.Value, error) {
+	arr, fn, err := arrayFunctionArgs("array.sort", args, 2, 2)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	result := append([]runtime.Value(nil), arr...)
+	var callErr error
+	sort.SliceStable(result, func(i, j int) bool {
+		if callErr != nil {
+			return false
+		}
+		val, err := fn.Call([]runtime.Value{result[i], result[j]})
+		if err != nil {
+			callErr = err
+			return false
+		}
+		order, err := val.AsInt()
+		if err != nil {
+			callErr = fmt.Errorf("array.sort: comparator must return an int")
+			return false
+		}
+		return order < 0
+	})
+	if callErr != nil {
+		return runtime.NewVoid(), callErr
+	}
+	return runtime.NewArray(result), nil
+}
+
+// arrayReverse implements array.reverse builtin function, returning a new
+// array with the elements in reverse order.
+func arrayReverse(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("array.reverse expects 1 argument, got %d", len(args))
+	}
+	arr, err := args[0].AsArray()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.reverse: first argument must be an array")
+	}
+
+	result := make([]runtime.Value, len(arr))
+	for i, elem := range arr {
+		result[len(arr)-1-i] = elem
+	}
+	return runtime.NewArray(result), nil
+}
+
+// arrayIndexOf implements array.indexOf builtin function. Elements are
+// compared with Equal, so an int never matches a float and NaN never matches.
+func arrayIndexOf(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 2 {
+		return runtime.NewVoid(), fmt.Errorf("array.indexOf expects 2 arguments, got %d", len(args))
+	}
+	arr, err := args[0].AsArray()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.indexOf: first argument must be an array")
+	}
+
+	for i, elem := range arr {
+		if Equal(elem, args[1]) {
+			return runtime.NewInt(int64(i)), nil
+		}
+	}
+	return runtime.NewInt(-1), nil
+}
+
+// arrayContains implements array.contains builtin function.
+func arrayContains(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 2 {
+		return runtime.NewVoid(), fmt.Errorf("array.contains expects 2 arguments, got %d", len(args))
+	}
+	index, err := arrayIndexOf(args)
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.contains: first argument must be an array")
+	}
+
+	i, _ := index.AsInt()
+	return runtime.NewBool(i >= 0), nil
+}
+
+// arrayConcat implements array.concat builtin function, returning a new array
+// with the elements of both arrays.
+func arrayConcat(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 2 {
+		return runtime.NewVoid(), fmt.Errorf("array.concat expects 2 arguments, got %d", len(args))
+	}
+	a, err := args[0].AsArray()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.concat: first argument must be an array")
+	}
+	b, err := args[1].AsArray()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("array.concat: second argument must be an array")
+	}
+
+	result := make([]runtime.Value, 0, len(a)+len(b))
+	result = append(result, a...)
+	result = append(result, b...)
+	return runtime.NewArray(result), nil
+}
+
+// isNumber reports whether a value is an int or a float.
+func isNumber(v runtime.Value) bool {
+	return v.Type == runtime.ValueTypeInt || v.Type == runtime.ValueTypeFloat
+}
+
+// orderable reports whether two values can be ordered against each other
+// without a comparator. Ints and floats compare by value.
+func orderable(a, b runtime.Value) bool {
+	return a.Type == b.Type || isNumber(a) && isNumber(b)
+}
+
+// valueTypeName returns the type name type.typeOf reports for a value.
+func valueTypeName(v runtime.Value) string {
+	name, _ := typeTypeOf([]runtime.Value{v})
+	str, _ := name.AsString()
+	return str
+}
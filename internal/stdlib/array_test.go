@@ -0,0 +1,182 @@
+package stdlib
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestArrayHigherOrderFunctions(t *testing.T) {
+	registry := NewRegistry()
+	numbers := runtime.NewArray([]runtime.Value{runtime.NewInt(1), runtime.NewInt(2), runtime.NewInt(3), runtime.NewInt(4)})
+	double := runtime.NewFunction("double", 1, func(args []runtime.Value) (runtime.Value, error) {
+		n, _ := args[0].AsInt()
+		return runtime.NewInt(n * 2), nil
+	})
+	isOdd := runtime.NewFunction("is_odd", 1, func(args []runtime.Value) (runtime.Value, error) {
+		n, _ := args[0].AsInt()
+		return runtime.NewBool(n%2 == 1), nil
+	})
+	sum := runtime.NewFunction("sum", 2, func(args []runtime.Value) (runtime.Value, error) {
+		acc, _ := args[0].AsInt()
+		n, _ := args[1].AsInt()
+		return runtime.NewInt(acc + n), nil
+	})
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    string
+		wantErr string
+	}{
+		{name: "map", fn: "array.map", args: []runtime.Value{numbers, double}, want: "[2 4 6 8]"},
+		{name: "filter", fn: "array.filter", args: []runtime.Value{numbers, isOdd}, want: "[1 3]"},
+		{name: "reduce", fn: "array.reduce", args: []runtime.Value{numbers, sum, runtime.NewInt(10)}, want: "20"},
+		{name: "reduce empty array returns initial value", fn: "array.reduce", args: []runtime.Value{runtime.NewArray(nil), sum, runtime.NewInt(7)}, want: "7"},
+		{name: "map argument count", fn: "array.map", args: []runtime.Value{numbers}, wantErr: "array.map expects 2 arguments, got 1"},
+		{name: "map requires array", fn: "array.map", args: []runtime.Value{runtime.NewInt(1), double}, wantErr: "array.map: first argument must be an array"},
+		{name: "filter requires callable", fn: "array.filter", args: []runtime.Value{numbers, runtime.NewString("is_odd")}, wantErr: "array.filter: second argument must be a function"},
+		{name: "filter predicate must return bool", fn: "array.filter", args: []runtime.Value{numbers, double}, wantErr: "array.filter: predicate must return a bool"},
+		{name: "reduce function arity", fn: "array.reduce", args: []runtime.Value{numbers, double, runtime.NewInt(0)}, wantErr: "array.reduce: function argument must take 2 parameters, takes 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := registry.Call(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, result.String())
+			}
+		})
+	}
+}
+
+func TestArraySortAndReverse(t *testing.T) {
+	registry := NewRegistry()
+	ints := func(ns ...int64) runtime.Value {
+		vals := make([]runtime.Value, len(ns))
+		for i, n := range ns {
+			vals[i] = runtime.NewInt(n)
+		}
+		return runtime.NewArray(vals)
+	}
+	words := runtime.NewArray([]runtime.Value{runtime.NewString("pear"), runtime.NewString("apple"), runtime.NewString("fig")})
+	descending := runtime.NewFunction("descending", 2, func(args []runtime.Value) (runtime.Value, error) {
+		a, _ := args[0].AsInt()
+		b, _ := args[1].AsInt()
+		return runtime.NewInt(b - a), nil
+	})
+	isOdd := runtime.NewFunction("is_odd", 1, func(args []runtime.Value) (runtime.Value, error) {
+		return runtime.NewBool(true), nil
+	})
+	less := runtime.NewFunction("less", 2, func(args []runtime.Value) (runtime.Value, error) {
+		return runtime.NewBool(true), nil
+	})
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    string
+		wantErr string
+	}{
+		{name: "sort ints", fn: "array.sort", args: []runtime.Value{ints(3, 1, 2)}, want: "[1 2 3]"},
+		{name: "sort strings", fn: "array.sort", args: []runtime.Value{words}, want: "[apple fig pear]"},
+		{name: "sort mixed numbers", fn: "array.sort", args: []runtime.Value{runtime.NewArray([]runtime.Value{runtime.NewFloat(2.5), runtime.NewInt(1), runtime.NewInt(3)})}, want: "[1 2.500000 3]"},
+		{name: "sort empty", fn: "array.sort", args: []runtime.Value{ints()}, want: "[]"},
+		{name: "sort with comparator", fn: "array.sort", args: []runtime.Value{ints(3, 1, 2), descending}, want: "[3 2 1]"},
+		{name: "reverse", fn: "array.reverse", args: []runtime.Value{ints(1, 2, 3)}, want: "[3 2 1]"},
+		{name: "sort mixed types", fn: "array.sort", args: []runtime.Value{runtime.NewArray([]runtime.Value{runtime.NewInt(1), runtime.NewString("a")})}, wantErr: "array.sort: cannot compare int and string"},
+		{name: "sort bools", fn: "array.sort", args: []runtime.Value{runtime.NewArray([]runtime.Value{runtime.NewBool(true), runtime.NewBool(false)})}, wantErr: "array.sort: cannot sort bool elements without a comparator"},
+		{name: "sort comparator arity", fn: "array.sort", args: []runtime.Value{ints(1, 2), isOdd}, wantErr: "array.sort: function argument must take 2 parameters, takes 1"},
+		{name: "sort comparator result", fn: "array.sort", args: []runtime.Value{ints(1, 2), less}, wantErr: "array.sort: comparator must return an int"},
+		{name: "reverse requires array", fn: "array.reverse", args: []runtime.Value{runtime.NewInt(1)}, wantErr: "array.reverse: first argument must be an array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := registry.Call(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, result.String())
+			}
+		})
+	}
+
+	// The input array is left unmodified
+	input := ints(3, 1, 2)
+	if _, err := registry.Call("array.sort", []runtime.Value{input}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := registry.Call("array.reverse", []runtime.Value{input}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input.String() != "[3 1 2]" {
+		t.Errorf("expected input to be unchanged, got %s", input.String())
+	}
+}
+
+func TestArraySearchAndConcat(t *testing.T) {
+	registry := NewRegistry()
+	pair := func(a, b runtime.Value) runtime.Value { return runtime.NewArray([]runtime.Value{a, b}) }
+	nested := runtime.NewArray([]runtime.Value{
+		pair(runtime.NewInt(1), runtime.NewInt(2)),
+		runtime.NewInt(3),
+		runtime.NewFloat(math.NaN()),
+	})
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    string
+		wantErr string
+	}{
+		{name: "indexOf deep equal", fn: "array.indexOf", args: []runtime.Value{nested, pair(runtime.NewInt(1), runtime.NewInt(2))}, want: "0"},
+		{name: "indexOf scalar", fn: "array.indexOf", args: []runtime.Value{nested, runtime.NewInt(3)}, want: "1"},
+		{name: "indexOf absent", fn: "array.indexOf", args: []runtime.Value{nested, runtime.NewInt(4)}, want: "-1"},
+		{name: "indexOf int does not match float", fn: "array.indexOf", args: []runtime.Value{nested, runtime.NewFloat(3)}, want: "-1"},
+		{name: "indexOf NaN never matches", fn: "array.indexOf", args: []runtime.Value{nested, runtime.NewFloat(math.NaN())}, want: "-1"},
+		{name: "contains", fn: "array.contains", args: []runtime.Value{nested, runtime.NewInt(3)}, want: "true"},
+		{name: "contains absent", fn: "array.contains", args: []runtime.Value{nested, runtime.NewString("3")}, want: "false"},
+		{name: "concat", fn: "array.concat", args: []runtime.Value{pair(runtime.NewInt(1), runtime.NewInt(2)), pair(runtime.NewInt(3), runtime.NewInt(4))}, want: "[1 2 3 4]"},
+		{name: "indexOf requires array", fn: "array.indexOf", args: []runtime.Value{runtime.NewInt(1), runtime.NewInt(1)}, wantErr: "array.indexOf: first argument must be an array"},
+		{name: "concat requires arrays", fn: "array.concat", args: []runtime.Value{nested, runtime.NewInt(1)}, wantErr: "array.concat: second argument must be an array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := registry.Call(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, result.String())
+			}
+		})
+	}
+}
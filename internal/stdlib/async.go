@@ -10,27 +10,29 @@ import (
 
 // RegisterAsyncFunctions registers all async-related standard library functions
 func (r *Registry) registerAsyncFunctions() {
-	// async.spawn - Spawn an async task
+	// async.spawn - Run a function on its own goroutine
 	r.Register("async.spawn", func(args []runtime.Value) (runtime.Value, error) {
-		if len(args) != 1 {
-			return runtime.NewVoid(), fmt.Errorf("async.spawn: expected 1 argument, got %d", len(args))
+		if len(args) < 1 || len(args) > 2 {
+			return runtime.NewVoid(), fmt.Errorf("async.spawn: expected 1 or 2 arguments, got %d", len(args))
 		}
 
-		// For now, we'll store the function value and execute it later
-		// In a full implementation, this would need to handle function values properly
-		fn := args[0]
+		fn, err := args[0].AsFunction()
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("async.spawn: first argument must be a function")
+		}
 
-		// Create a task that executes the function
-		task := runtime.GetGlobalAsyncManager().SpawnTask(func(ctx context.Context) (runtime.Value, error) {
-			// TODO: Execute the ALaS function here
-			// For now, simulate async work
-			select {
-			case <-ctx.Done():
-				return runtime.NewVoid(), ctx.Err()
-			case <-time.After(100 * time.Millisecond):
-				// Return the function argument as a placeholder
-				return fn, nil
+		var callArgs []runtime.Value
+		if len(args) == 2 {
+			elements, err := args[1].AsArray()
+			if err != nil {
+				return runtime.NewVoid(), fmt.Errorf("async.spawn: arguments must be an array")
 			}
+			// Copy so later changes to the caller's array don't race with the task
+			callArgs = append([]runtime.Value(nil), elements...)
+		}
+
+		task := runtime.GetGlobalAsyncManager().SpawnTask(func(ctx context.Context) (runtime.Value, error) {
+			return callWithContext(ctx, fn, callArgs)
 		})
 
 		return task.ToValue(), nil
@@ -247,8 +249,10 @@ func (r *Registry) registerAsyncFunctions() {
 			return runtime.NewVoid(), fmt.Errorf("async.timeout: expected 2 arguments, got %d", len(args))
 		}
 
-		// First arg is function, second is timeout
-		fn := args[0]
+		fn, err := args[0].AsFunction()
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("async.timeout: first argument must be a function")
+		}
 
 		if args[1].Type != runtime.ValueTypeInt {
 			return runtime.NewVoid(), fmt.Errorf("async.timeout: timeout must be int")
@@ -263,14 +267,7 @@ func (r *Registry) registerAsyncFunctions() {
 			ctx, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 
-			// TODO: Execute the ALaS function here with timeout context
-			// For now, simulate work
-			select {
-			case <-ctx.Done():
-				return runtime.NewVoid(), ctx.Err()
-			case <-time.After(timeout / 2): // Simulate some work
-				return fn, nil
-			}
+			return callWithContext(ctx, fn, nil)
 		})
 
 		return task.ToValue(), nil
@@ -319,3 +316,26 @@ func (r *Registry) registerAsyncFunctions() {
 		return runtime.NewBool(task.IsCompleted()), nil
 	})
 }
+
+// callWithContext calls fn under ctx on its own goroutine and returns its
+// result, or ctx.Err() if ctx is done first. A function abandoned this way
+// keeps running until it returns, since the interpreter cannot interrupt a
+// call, but sends and receives it is blocked in fail once ctx is done.
+func callWithContext(ctx context.Context, fn *runtime.FunctionValue, args []runtime.Value) (runtime.Value, error) {
+	type outcome struct {
+		value runtime.Value
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn.CallWith(ctx, args)
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return runtime.NewVoid(), ctx.Err()
+	case out := <-done:
+		return out.value, out.err
+	}
+}
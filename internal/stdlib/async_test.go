@@ -1,6 +1,7 @@
 package stdlib
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -17,7 +18,9 @@ func TestAsyncSpawn(t *testing.T) {
 
 	// Test spawn
 	args := []runtime.Value{
-		runtime.NewString("test function"), // Placeholder for function
+		runtime.NewFunction("work", 0, func(args []runtime.Value) (runtime.Value, error) {
+			return runtime.NewVoid(), nil
+		}),
 	}
 
 	result, err := registry.Call("async.spawn", args)
@@ -49,7 +52,11 @@ func TestAsyncAwait(t *testing.T) {
 
 	// First spawn a task
 	spawnArgs := []runtime.Value{
-		runtime.NewString("test function"),
+		runtime.NewFunction("greet", 1, func(args []runtime.Value) (runtime.Value, error) {
+			name, _ := args[0].AsString()
+			return runtime.NewString("hello " + name), nil
+		}),
+		runtime.NewArray([]runtime.Value{runtime.NewString("world")}),
 	}
 	task, err := registry.Call("async.spawn", spawnArgs)
 	if err != nil {
@@ -73,8 +80,8 @@ func TestAsyncAwait(t *testing.T) {
 		t.Errorf("expected ok=true, got %v", okVal)
 	}
 	valueStr, _ := resultMap["value"].AsString()
-	if valueStr != "test function" {
-		t.Errorf("expected value='test function', got %v", valueStr)
+	if valueStr != "hello world" {
+		t.Errorf("expected value='hello world', got %v", valueStr)
 	}
 	errStr, _ := resultMap["error"].AsString()
 	if errStr != "" {
@@ -82,6 +89,44 @@ func TestAsyncAwait(t *testing.T) {
 	}
 }
 
+func TestAsyncSpawnErrors(t *testing.T) {
+	registry := NewRegistry()
+	failing := runtime.NewFunction("fail", 0, func(args []runtime.Value) (runtime.Value, error) {
+		return runtime.NewVoid(), fmt.Errorf("boom")
+	})
+
+	tests := []struct {
+		name    string
+		args    []runtime.Value
+		wantErr string
+	}{
+		{name: "not a function", args: []runtime.Value{runtime.NewString("main")}, wantErr: "first argument must be a function"},
+		{name: "arguments not an array", args: []runtime.Value{failing, runtime.NewInt(1)}, wantErr: "arguments must be an array"},
+		{name: "no arguments", args: nil, wantErr: "expected 1 or 2 arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := registry.Call("async.spawn", tt.args); err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("async.spawn error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A failing function surfaces its error through await
+	task, err := registry.Call("async.spawn", []runtime.Value{failing})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, _ := registry.Call("async.await", []runtime.Value{task})
+	resultMap, _ := result.AsMap()
+	if ok, _ := resultMap["ok"].AsBool(); ok {
+		t.Error("expected ok=false for a failing function")
+	}
+	if errStr, _ := resultMap["error"].AsString(); errStr != "boom" {
+		t.Errorf("expected error 'boom', got %q", errStr)
+	}
+}
+
 func TestAsyncAwaitTimeout(t *testing.T) {
 	registry := NewRegistry()
 
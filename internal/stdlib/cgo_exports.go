@@ -1,5 +1,6 @@
 package stdlib
 
+// #include <stdbool.h>
 // #include <stdint.h>
 // #include <stdlib.h>
 // #include <string.h>
@@ -14,6 +15,12 @@ package stdlib
 //     void* map_val;
 // } CValue;
 //
+// // C representation of an array returned to compiled code: 8-byte slots
+// typedef struct {
+//     void* data;
+//     int64_t length;
+// } CArray;
+//
 // // Helper to create C string from Go string
 // static char* go_string_to_c(const char* s, size_t len) {
 //     char* c_str = (char*)malloc(len + 1);
@@ -25,6 +32,12 @@ package stdlib
 // }
 import "C"
 import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/dshills/alas/internal/runtime"
@@ -55,7 +68,12 @@ func convertCValueToGo(cval *C.CValue) runtime.Value {
 		return runtime.NewBool(cval.int_val != 0)
 	case CValueTypeVoid:
 		return runtime.NewVoid()
-	// TODO: Handle arrays and maps
+	case CValueTypeMap:
+		if m := lookupMap(cval.map_val); m != nil {
+			return hashMapToGo(m)
+		}
+		return runtime.NewVoid()
+	// TODO: Handle arrays
 	default:
 		return runtime.NewVoid()
 	}
@@ -90,8 +108,12 @@ func setCValueFields(cval *C.CValue, val runtime.Value) {
 		// TODO: Handle arrays
 		cval._type = CValueTypeVoid
 	case runtime.ValueTypeMap:
-		// TODO: Handle maps
-		cval._type = CValueTypeVoid
+		cval._type = CValueTypeMap
+		if h, ok := val.Value.(mapHandle); ok {
+			cval.map_val = h.ptr
+		} else {
+			cval.map_val = newMapFromGo(val)
+		}
 	default:
 		cval._type = CValueTypeVoid
 	}
@@ -123,6 +145,72 @@ func alas_builtin_io_print(val *C.CValue) {
 	registry.Call("io.print", args)
 }
 
+// alas_runtime_array_init returns the heap block of size bytes for an array
+// literal too large for the stack. The block is held in handle, a stack
+// slot of the function running the literal, and is allocated zeroed the
+// first time the literal runs in a call.
+//
+//export alas_runtime_array_init
+func alas_runtime_array_init(handle *unsafe.Pointer, size C.int64_t) unsafe.Pointer {
+	if *handle == nil {
+		*handle = C.calloc(1, C.size_t(size))
+	}
+	return *handle
+}
+
+// alas_runtime_array_release frees the block held in handle when the
+// function owning it returns.
+//
+//export alas_runtime_array_release
+func alas_runtime_array_release(handle *unsafe.Pointer) {
+	C.free(*handle)
+	*handle = nil
+}
+
+// alas_runtime_log writes a message of a log builtin to stderr. Compiled
+// code writes every level, prefixed with the level and the name of the
+// calling function.
+//
+//export alas_runtime_log
+func alas_runtime_log(level C.int32_t, function *C.char, message *C.CValue, fields *C.CValue) {
+	args := []runtime.Value{convertCValueToGo(message)}
+	if fields != nil {
+		args = append(args, convertCValueToGo(fields))
+	}
+	line, err := FormatLogLine(LogLevel(level), C.GoString(function), "log", args)
+	if err != nil {
+		line = err.Error()
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
+//export alas_builtin_io_debug
+func alas_builtin_io_debug(val *C.CValue) {
+	goVal := convertCValueToGo(val)
+	args := []runtime.Value{goVal}
+
+	// Get the registry and call the function
+	registry := NewRegistry()
+	registry.Call("io.debug", args)
+}
+
+//export alas_builtin_os_getenv
+func alas_builtin_os_getenv(name *C.CValue) *C.CValue {
+	registry := NewRegistry()
+	result, err := registry.Call("os.getenv", []runtime.Value{convertCValueToGo(name)})
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewString(""))
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_os_setenv
+func alas_builtin_os_setenv(name *C.CValue, value *C.CValue) {
+	registry := NewRegistry()
+	registry.Call("os.setenv", []runtime.Value{convertCValueToGo(name), convertCValueToGo(value)})
+}
+
 //export alas_builtin_math_sqrt
 func alas_builtin_math_sqrt(val *C.CValue) *C.CValue {
 	goVal := convertCValueToGo(val)
@@ -153,20 +241,88 @@ func alas_builtin_math_abs(val *C.CValue) *C.CValue {
 	return convertGoValueToCPtr(result)
 }
 
-//export alas_builtin_collections_length
-func alas_builtin_collections_length(val *C.CValue) *C.CValue {
-	goVal := convertCValueToGo(val)
-	args := []runtime.Value{goVal}
+// callMathBuiltin calls a float-valued math builtin, returning 0 on failure.
+func callMathBuiltin(name string, vals ...*C.CValue) *C.CValue {
+	args := make([]runtime.Value, len(vals))
+	for i, val := range vals {
+		args[i] = convertCValueToGo(val)
+	}
 
 	registry := NewRegistry()
-	result, err := registry.Call("collections.length", args)
+	result, err := registry.Call(name, args)
 	if err != nil {
-		return convertGoValueToCPtr(runtime.NewInt(0))
+		return convertGoValueToCPtr(runtime.NewFloat(0))
 	}
 
 	return convertGoValueToCPtr(result)
 }
 
+//export alas_builtin_math_pow
+func alas_builtin_math_pow(base *C.CValue, exp *C.CValue) *C.CValue {
+	return callMathBuiltin("math.pow", base, exp)
+}
+
+//export alas_builtin_math_floor
+func alas_builtin_math_floor(val *C.CValue) *C.CValue {
+	return callMathBuiltin("math.floor", val)
+}
+
+//export alas_builtin_math_ceil
+func alas_builtin_math_ceil(val *C.CValue) *C.CValue {
+	return callMathBuiltin("math.ceil", val)
+}
+
+//export alas_builtin_math_round
+func alas_builtin_math_round(val *C.CValue) *C.CValue {
+	return callMathBuiltin("math.round", val)
+}
+
+//export alas_builtin_math_sin
+func alas_builtin_math_sin(val *C.CValue) *C.CValue {
+	return callMathBuiltin("math.sin", val)
+}
+
+//export alas_builtin_math_cos
+func alas_builtin_math_cos(val *C.CValue) *C.CValue {
+	return callMathBuiltin("math.cos", val)
+}
+
+//export alas_builtin_math_log
+func alas_builtin_math_log(val *C.CValue) *C.CValue {
+	// Compiled code reports a NULL result as a runtime error
+	registry := NewRegistry()
+	result, err := registry.Call("math.log", []runtime.Value{convertCValueToGo(val)})
+	if err != nil {
+		return nil
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_math_pi
+func alas_builtin_math_pi() *C.CValue {
+	return callMathBuiltin("math.pi")
+}
+
+//export alas_builtin_collections_length
+func alas_builtin_collections_length(val *C.CValue) *C.CValue {
+	// Branch on the type tag rather than converting the whole collection;
+	// values that are not collections have length 0
+	var length int64
+	switch {
+	case val == nil:
+	case val._type == CValueTypeArray && val.array_val != nil:
+		length = int64((*C.CArray)(val.array_val).length)
+	case val._type == CValueTypeMap:
+		if m := lookupMap(val.map_val); m != nil {
+			length = int64(m.Len())
+		}
+	case val._type == CValueTypeString && val.string_val != nil:
+		length = int64(C.strlen(val.string_val))
+	}
+	return convertGoValueToCPtr(runtime.NewInt(length))
+}
+
 //export alas_builtin_string_toUpper
 func alas_builtin_string_toUpper(val *C.CValue) *C.CValue {
 	goVal := convertCValueToGo(val)
@@ -195,6 +351,457 @@ func alas_builtin_type_typeOf(val *C.CValue) *C.CValue {
 	return convertGoValueToCPtr(result)
 }
 
+//export alas_builtin_type_toString
+func alas_builtin_type_toString(val *C.CValue) *C.CValue {
+	goVal := convertCValueToGo(val)
+	args := []runtime.Value{goVal}
+
+	registry := NewRegistry()
+	result, err := registry.Call("type.toString", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewString(""))
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_type_parseInt
+func alas_builtin_type_parseInt(val *C.CValue) *C.CValue {
+	goVal := convertCValueToGo(val)
+	args := []runtime.Value{goVal}
+
+	// Compiled code reports a NULL result as a runtime error
+	registry := NewRegistry()
+	result, err := registry.Call("type.parseInt", args)
+	if err != nil {
+		return nil
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_type_parseFloat
+func alas_builtin_type_parseFloat(val *C.CValue) *C.CValue {
+	goVal := convertCValueToGo(val)
+	args := []runtime.Value{goVal}
+
+	// Compiled code reports a NULL result as a runtime error
+	registry := NewRegistry()
+	result, err := registry.Call("type.parseFloat", args)
+	if err != nil {
+		return nil
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+// alas_runtime_int_to_string implements casts from int to string.
+//
+//export alas_runtime_int_to_string
+func alas_runtime_int_to_string(n C.int64_t) *C.char {
+	return C.CString(strconv.FormatInt(int64(n), 10))
+}
+
+// alas_runtime_float_to_string implements casts from float to string,
+// writing the shortest representation as the interpreter does.
+//
+//export alas_runtime_float_to_string
+func alas_runtime_float_to_string(f C.double) *C.char {
+	return C.CString(strconv.FormatFloat(float64(f), 'g', -1, 64))
+}
+
+// alas_runtime_bool_to_string implements casts from bool to string. Compiled
+// code passes an i1, so only the low bit of b is defined.
+//
+//export alas_runtime_bool_to_string
+func alas_runtime_bool_to_string(b C.uint8_t) *C.char {
+	return C.CString(strconv.FormatBool(b&1 != 0))
+}
+
+// alas_runtime_string_to_int implements casts from string to int. A string
+// that is not an int ends the program with a runtime error at file:line.
+//
+//export alas_runtime_string_to_int
+func alas_runtime_string_to_int(s *C.char, file *C.char, line C.int32_t) C.int64_t {
+	str := C.GoString(s)
+	n, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	if err != nil {
+		castFailed(file, line, str, "int")
+	}
+	return C.int64_t(n)
+}
+
+// alas_runtime_string_to_float implements casts from string to float.
+//
+//export alas_runtime_string_to_float
+func alas_runtime_string_to_float(s *C.char, file *C.char, line C.int32_t) C.double {
+	str := C.GoString(s)
+	f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		castFailed(file, line, str, "float")
+	}
+	return C.double(f)
+}
+
+// alas_runtime_string_to_bool implements casts from string to bool.
+//
+//export alas_runtime_string_to_bool
+func alas_runtime_string_to_bool(s *C.char, file *C.char, line C.int32_t) C.bool {
+	str := C.GoString(s)
+	b, err := strconv.ParseBool(strings.TrimSpace(str))
+	if err != nil {
+		castFailed(file, line, str, "bool")
+	}
+	return C.bool(b)
+}
+
+// castFailed reports a string that could not be cast, with the same message
+// as the interpreter, and exits.
+func castFailed(file *C.char, line C.int32_t, s, target string) {
+	fmt.Fprintf(os.Stderr, "%s:%d: runtime error: cannot cast string %q to %s\n", C.GoString(file), int(line), s, target)
+	os.Exit(1)
+}
+
+// alas_runtime_error reports a runtime error detected by a check in compiled
+// code, such as an integer overflow or an out of range cast, and exits.
+//
+//export alas_runtime_error
+func alas_runtime_error(message *C.char, file *C.char, line C.int32_t, column C.int32_t) {
+	location := fmt.Sprintf("%s:%d", C.GoString(file), int(line))
+	if column > 0 {
+		location += fmt.Sprintf(":%d", int(column))
+	}
+	fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n", location, C.GoString(message))
+	os.Exit(1)
+}
+
+//export alas_builtin_array_map
+func alas_builtin_array_map(array *C.CValue, fn *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array), convertCValueToGo(fn)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.map", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_filter
+func alas_builtin_array_filter(array *C.CValue, fn *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array), convertCValueToGo(fn)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.filter", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_sort
+func alas_builtin_array_sort(array *C.CValue, cmp *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array)}
+	if cmp != nil {
+		args = append(args, convertCValueToGo(cmp))
+	}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.sort", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_reverse
+func alas_builtin_array_reverse(array *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.reverse", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_indexOf
+func alas_builtin_array_indexOf(array *C.CValue, item *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array), convertCValueToGo(item)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.indexOf", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewInt(-1))
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_contains
+func alas_builtin_array_contains(array *C.CValue, item *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array), convertCValueToGo(item)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.contains", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewBool(false))
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_concat
+func alas_builtin_array_concat(a *C.CValue, b *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(a), convertCValueToGo(b)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.concat", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+//export alas_builtin_array_reduce
+func alas_builtin_array_reduce(array *C.CValue, fn *C.CValue, init *C.CValue) *C.CValue {
+	args := []runtime.Value{convertCValueToGo(array), convertCValueToGo(fn), convertCValueToGo(init)}
+
+	registry := NewRegistry()
+	result, err := registry.Call("array.reduce", args)
+	if err != nil {
+		return convertGoValueToCPtr(runtime.NewVoid())
+	}
+
+	return convertGoValueToCPtr(result)
+}
+
+// mapHandle is how a compiled map stored inside another map is represented
+// on the Go side, so nested maps keep their identity.
+type mapHandle struct {
+	ptr unsafe.Pointer
+}
+
+// mapHandles maps the opaque handles given to compiled code to hash maps.
+var (
+	mapHandlesMu sync.Mutex
+	mapHandles   = make(map[unsafe.Pointer]*runtime.HashMap)
+)
+
+// registerMap allocates a handle for m.
+func registerMap(m *runtime.HashMap) unsafe.Pointer {
+	handle := C.malloc(1)
+	mapHandlesMu.Lock()
+	mapHandles[handle] = m
+	mapHandlesMu.Unlock()
+	return handle
+}
+
+// lookupMap returns the hash map for a handle, or nil.
+func lookupMap(handle unsafe.Pointer) *runtime.HashMap {
+	mapHandlesMu.Lock()
+	defer mapHandlesMu.Unlock()
+	return mapHandles[handle]
+}
+
+// newMapFromGo copies a runtime map value into a new hash map handle.
+func newMapFromGo(val runtime.Value) unsafe.Pointer {
+	m := runtime.NewHashMap()
+	entries, _ := val.AsMap()
+	for k, v := range entries {
+		_ = m.Put(runtime.MapKeyValue(k), v)
+	}
+	return registerMap(m)
+}
+
+// hashMapToGo snapshots a hash map as a runtime map value for builtins.
+func hashMapToGo(m *runtime.HashMap) runtime.Value {
+	keys, values := m.Keys(), m.Values()
+	entries := make(map[string]runtime.Value, len(keys))
+	for i, k := range keys {
+		v := values[i]
+		if h, ok := v.Value.(mapHandle); ok {
+			if nested := lookupMap(h.ptr); nested != nil {
+				v = hashMapToGo(nested)
+			}
+		}
+		stored, _ := runtime.MapKey(k)
+		entries[stored] = v
+	}
+	return runtime.NewMap(entries)
+}
+
+// mapEntryFromC converts a CValue to a value stored in a hash map.
+func mapEntryFromC(cval *C.CValue) runtime.Value {
+	if cval._type == CValueTypeMap {
+		return runtime.Value{Type: runtime.ValueTypeMap, Value: mapHandle{ptr: cval.map_val}}
+	}
+	return convertCValueToGo(cval)
+}
+
+// newCArray allocates 8-byte slots for values, storing ints and bools
+// directly, floats as their bit pattern, and strings and maps as pointers.
+func newCArray(values []runtime.Value) C.CArray {
+	arr := C.CArray{length: C.int64_t(len(values))}
+	if len(values) == 0 {
+		return arr
+	}
+	arr.data = C.malloc(C.size_t(len(values) * 8))
+	slots := unsafe.Slice((*C.int64_t)(arr.data), len(values))
+	for i, v := range values {
+		switch v.Type {
+		case runtime.ValueTypeInt:
+			n, _ := v.AsInt()
+			slots[i] = C.int64_t(n)
+		case runtime.ValueTypeFloat:
+			f, _ := v.AsFloat()
+			slots[i] = C.int64_t(math.Float64bits(f))
+		case runtime.ValueTypeBool:
+			if v.IsTruthy() {
+				slots[i] = 1
+			}
+		case runtime.ValueTypeString:
+			s, _ := v.AsString()
+			slots[i] = C.int64_t(uintptr(unsafe.Pointer(C.CString(s))))
+		case runtime.ValueTypeMap:
+			cval := convertGoValueToC(v)
+			slots[i] = C.int64_t(uintptr(cval.map_val))
+		}
+	}
+	return arr
+}
+
+// alas_runtime_map_create builds a map from count key/value pairs, each a
+// pair of CValue pointers.
+//
+//export alas_runtime_map_create
+func alas_runtime_map_create(pairs unsafe.Pointer, count C.int64_t) unsafe.Pointer {
+	return registerMap(hashMapFromPairs(pairs, count))
+}
+
+// hashMapFromPairs builds a hash map from count key/value pairs of CValue
+// pointers.
+func hashMapFromPairs(pairs unsafe.Pointer, count C.int64_t) *runtime.HashMap {
+	m := runtime.NewHashMap()
+	if count > 0 {
+		entries := unsafe.Slice((**C.CValue)(pairs), int(count)*2)
+		for i := 0; i < len(entries); i += 2 {
+			// Keys other than int, bool or string are rejected by codegen
+			_ = m.Put(convertCValueToGo(entries[i]), mapEntryFromC(entries[i+1]))
+		}
+	}
+	return m
+}
+
+// alas_runtime_map_init creates a hash map like alas_runtime_map_create, but
+// registers it under handle, the address of a stack slot in the frame of a
+// function the map cannot outlive, instead of a new handle. The map is on
+// the Go heap like any other; alas_runtime_map_release drops it.
+//
+//export alas_runtime_map_init
+func alas_runtime_map_init(handle unsafe.Pointer, pairs unsafe.Pointer, count C.int64_t) {
+	m := hashMapFromPairs(pairs, count)
+	mapHandlesMu.Lock()
+	mapHandles[handle] = m
+	mapHandlesMu.Unlock()
+}
+
+// alas_runtime_map_release drops the map registered by alas_runtime_map_init
+// when the function owning its handle returns, so that it can be collected.
+//
+//export alas_runtime_map_release
+func alas_runtime_map_release(handle unsafe.Pointer) {
+	mapHandlesMu.Lock()
+	delete(mapHandles, handle)
+	mapHandlesMu.Unlock()
+}
+
+// alas_runtime_map_get returns the value for key, or void if it is missing.
+//
+//export alas_runtime_map_get
+func alas_runtime_map_get(handle unsafe.Pointer, key *C.CValue) *C.CValue {
+	if m := lookupMap(handle); m != nil {
+		if v, ok := m.Get(convertCValueToGo(key)); ok {
+			return convertGoValueToCPtr(v)
+		}
+	}
+	return convertGoValueToCPtr(runtime.NewVoid())
+}
+
+// alas_runtime_map_get_field returns the value for a string key.
+//
+//export alas_runtime_map_get_field
+func alas_runtime_map_get_field(handle unsafe.Pointer, field *C.char) *C.CValue {
+	if m := lookupMap(handle); m != nil {
+		if v, ok := m.Get(runtime.NewString(C.GoString(field))); ok {
+			return convertGoValueToCPtr(v)
+		}
+	}
+	return convertGoValueToCPtr(runtime.NewVoid())
+}
+
+// alas_runtime_map_put inserts or updates the value for key.
+//
+//export alas_runtime_map_put
+func alas_runtime_map_put(handle unsafe.Pointer, key *C.CValue, val *C.CValue) {
+	if m := lookupMap(handle); m != nil {
+		_ = m.Put(convertCValueToGo(key), mapEntryFromC(val))
+	}
+}
+
+// alas_runtime_map_contains reports whether key is present.
+//
+//export alas_runtime_map_contains
+func alas_runtime_map_contains(handle unsafe.Pointer, key *C.CValue) C.bool {
+	m := lookupMap(handle)
+	return C.bool(m != nil && m.Contains(convertCValueToGo(key)))
+}
+
+// alas_runtime_map_remove deletes key.
+//
+//export alas_runtime_map_remove
+func alas_runtime_map_remove(handle unsafe.Pointer, key *C.CValue) {
+	if m := lookupMap(handle); m != nil {
+		m.Remove(convertCValueToGo(key))
+	}
+}
+
+// alas_runtime_map_size returns the number of entries.
+//
+//export alas_runtime_map_size
+func alas_runtime_map_size(handle unsafe.Pointer) C.int64_t {
+	if m := lookupMap(handle); m != nil {
+		return C.int64_t(m.Len())
+	}
+	return 0
+}
+
+// alas_runtime_map_keys returns the keys in insertion order.
+//
+//export alas_runtime_map_keys
+func alas_runtime_map_keys(handle unsafe.Pointer) C.CArray {
+	if m := lookupMap(handle); m != nil {
+		return newCArray(m.Keys())
+	}
+	return C.CArray{}
+}
+
+// alas_runtime_map_values returns the values in insertion order.
+//
+//export alas_runtime_map_values
+func alas_runtime_map_values(handle unsafe.Pointer) C.CArray {
+	if m := lookupMap(handle); m != nil {
+		return newCArray(m.Values())
+	}
+	return C.CArray{}
+}
+
 // FreeCString frees a C string allocated by Go
 //
 //export alas_free_cstring
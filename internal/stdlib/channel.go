@@ -0,0 +1,123 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerChannelFunctions registers all std.channel builtin functions.
+func (r *Registry) registerChannelFunctions() {
+	r.Register("channel.make", channelMake)
+	r.RegisterContext("channel.send", channelSend)
+	r.RegisterContext("channel.recv", channelRecv)
+	r.Register("channel.close", channelClose)
+}
+
+// channelMake implements channel.make builtin function.
+func channelMake(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("channel.make expects 1 argument, got %d", len(args))
+	}
+
+	if args[0].Type != runtime.ValueTypeInt {
+		return runtime.NewVoid(), fmt.Errorf("channel.make: capacity must be an int")
+	}
+	capacity, _ := args[0].AsInt()
+	ch, err := runtime.NewChannel(int(capacity))
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("channel.make: %v", err)
+	}
+
+	return ch, nil
+}
+
+// channelSend implements channel.send builtin function.
+// It blocks until the value is taken or buffered, and returns the channel.
+func channelSend(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return runtime.NewVoid(), fmt.Errorf("channel.send expects 2 or 3 arguments, got %d", len(args))
+	}
+
+	ch, err := args[0].AsChannel()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("channel.send: %v", err)
+	}
+	ctx, cancel, err := channelContext(ctx, "channel.send", args[2:])
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	defer cancel()
+
+	if err := ch.Send(ctx, args[1]); err != nil {
+		return runtime.NewVoid(), channelError(ctx, "channel.send", err)
+	}
+
+	return args[0], nil
+}
+
+// channelRecv implements channel.recv builtin function.
+func channelRecv(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return runtime.NewVoid(), fmt.Errorf("channel.recv expects 1 or 2 arguments, got %d", len(args))
+	}
+
+	ch, err := args[0].AsChannel()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("channel.recv: %v", err)
+	}
+	ctx, cancel, err := channelContext(ctx, "channel.recv", args[1:])
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	defer cancel()
+
+	value, err := ch.Recv(ctx)
+	if err != nil {
+		return runtime.NewVoid(), channelError(ctx, "channel.recv", err)
+	}
+
+	return value, nil
+}
+
+// channelClose implements channel.close builtin function.
+// It returns whether this call closed the channel.
+func channelClose(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("channel.close expects 1 argument, got %d", len(args))
+	}
+
+	ch, err := args[0].AsChannel()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("channel.close: %v", err)
+	}
+
+	return runtime.NewBool(ch.Close()), nil
+}
+
+// channelContext returns the context a send or receive waits under: the
+// caller's context, which also expires after the optional timeout argument
+// in milliseconds.
+func channelContext(parent context.Context, name string, args []runtime.Value) (context.Context, context.CancelFunc, error) {
+	if len(args) == 0 {
+		ctx, cancel := context.WithCancel(parent)
+		return ctx, cancel, nil
+	}
+	if args[0].Type != runtime.ValueTypeInt {
+		return nil, nil, fmt.Errorf("%s: timeout must be an int", name)
+	}
+	timeoutMs, _ := args[0].AsInt()
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutMs)*time.Millisecond)
+	return ctx, cancel, nil
+}
+
+// channelError describes a failed send or receive, reporting an expired
+// timeout as such rather than as a context error.
+func channelError(ctx context.Context, name string, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: timed out", name)
+	}
+	return fmt.Errorf("%s: %v", name, err)
+}
@@ -0,0 +1,99 @@
+package stdlib
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestChannelFunctions(t *testing.T) {
+	registry := NewRegistry()
+	n := runtime.NewInt
+	makeChannel := func(capacity int64) runtime.Value {
+		t.Helper()
+		ch, err := registry.Call("channel.make", []runtime.Value{n(capacity)})
+		if err != nil {
+			t.Fatalf("channel.make(%d) error = %v", capacity, err)
+		}
+		return ch
+	}
+
+	buffered := makeChannel(1)
+	if _, err := registry.Call("channel.send", []runtime.Value{buffered, runtime.NewString("hello")}); err != nil {
+		t.Fatalf("channel.send() error = %v", err)
+	}
+	got, err := registry.Call("channel.recv", []runtime.Value{buffered})
+	if err != nil || got.String() != "hello" {
+		t.Errorf("channel.recv() = %s, %v, want hello", got, err)
+	}
+
+	full := makeChannel(1)
+	if _, err := registry.Call("channel.send", []runtime.Value{full, n(1)}); err != nil {
+		t.Fatalf("channel.send() error = %v", err)
+	}
+	closed := makeChannel(0)
+	if _, err := registry.Call("channel.close", []runtime.Value{closed}); err != nil {
+		t.Fatalf("channel.close() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		wantErr string
+	}{
+		{name: "recv timeout", fn: "channel.recv", args: []runtime.Value{makeChannel(0), n(10)}, wantErr: "channel.recv: timed out"},
+		{name: "send timeout", fn: "channel.send", args: []runtime.Value{full, n(2), n(10)}, wantErr: "channel.send: timed out"},
+		{name: "recv closed", fn: "channel.recv", args: []runtime.Value{closed}, wantErr: "channel.recv: receive on closed channel"},
+		{name: "send closed", fn: "channel.send", args: []runtime.Value{closed, n(1)}, wantErr: "channel.send: send on closed channel"},
+		{name: "negative capacity", fn: "channel.make", args: []runtime.Value{n(-1)}, wantErr: "channel capacity must not be negative"},
+		{name: "float capacity", fn: "channel.make", args: []runtime.Value{runtime.NewFloat(1)}, wantErr: "channel.make: capacity must be an int"},
+		{name: "not a channel", fn: "channel.recv", args: []runtime.Value{n(1)}, wantErr: "channel.recv: value is not a channel"},
+		{name: "float timeout", fn: "channel.recv", args: []runtime.Value{full, runtime.NewFloat(10)}, wantErr: "channel.recv: timeout must be an int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Call(tt.fn, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s() error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChannelRecvCanceled(t *testing.T) {
+	registry := NewRegistry()
+	ch, err := registry.Call("channel.make", []runtime.Value{runtime.NewInt(0)})
+	if err != nil {
+		t.Fatalf("channel.make() error = %v", err)
+	}
+
+	// A task blocked receiving on a channel nobody sends on
+	recvErr := make(chan error, 1)
+	recv := runtime.NewContextFunction("", 0, func(ctx context.Context, args []runtime.Value) (runtime.Value, error) {
+		_, err := registry.CallContext(ctx, "channel.recv", []runtime.Value{ch})
+		recvErr <- err
+		return runtime.NewVoid(), err
+	})
+	task, err := registry.Call("async.spawn", []runtime.Value{recv})
+	if err != nil {
+		t.Fatalf("async.spawn() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := registry.Call("async.cancel", []runtime.Value{task}); err != nil {
+		t.Fatalf("async.cancel() error = %v", err)
+	}
+
+	select {
+	case err := <-recvErr:
+		if err == nil || !strings.Contains(err.Error(), "channel.recv: context canceled") {
+			t.Errorf("channel.recv() error = %v, want it canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel.recv() still blocked after its task was canceled")
+	}
+}
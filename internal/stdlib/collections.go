@@ -13,6 +13,12 @@ func (r *Registry) registerCollectionsFunctions() {
 	r.Register("collections.contains", collectionsContains)
 	r.Register("collections.indexOf", collectionsIndexOf)
 	r.Register("collections.slice", collectionsSlice)
+	r.Register("collections.map", func(args []runtime.Value) (runtime.Value, error) {
+		return mapArray("collections.map", args)
+	})
+	r.Register("collections.filter", func(args []runtime.Value) (runtime.Value, error) {
+		return filterArray("collections.filter", args)
+	})
 }
 
 // validateSliceArgs validates slice arguments and returns start/end indices.
@@ -119,9 +125,9 @@ func collectionsContains(args []runtime.Value) (runtime.Value, error) {
 		if err != nil {
 			return runtime.NewVoid(), err
 		}
-		keyStr, err := args[1].AsString()
-		if err != nil {
-			return runtime.NewVoid(), fmt.Errorf("collections.contains: map key must be string")
+		keyStr, ok := runtime.MapKey(args[1])
+		if !ok {
+			return runtime.NewVoid(), fmt.Errorf("collections.contains: map keys must be int, bool or string, got %s", valueTypeName(args[1]))
 		}
 		_, exists := m[keyStr]
 		return runtime.NewBool(exists), nil
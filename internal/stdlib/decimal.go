@@ -0,0 +1,143 @@
+package stdlib
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerDecimalFunctions registers all std.decimal builtin functions.
+func (r *Registry) registerDecimalFunctions() {
+	r.Register("decimal.fromString", decimalFromString)
+	r.Register("decimal.toString", decimalToString)
+	r.Register("decimal.add", decimalOp("decimal.add", (*big.Rat).Add))
+	r.Register("decimal.sub", decimalOp("decimal.sub", (*big.Rat).Sub))
+	r.Register("decimal.mul", decimalOp("decimal.mul", (*big.Rat).Mul))
+	r.Register("decimal.div", decimalDiv)
+}
+
+// decimalFromString implements decimal.fromString builtin function.
+func decimalFromString(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("decimal.fromString expects 1 argument, got %d", len(args))
+	}
+
+	s, err := args[0].AsString()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("decimal.fromString: %v", err)
+	}
+	d, err := runtime.ParseDecimal(s)
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("decimal.fromString: %v", err)
+	}
+
+	return runtime.NewDecimal(d), nil
+}
+
+// decimalToString implements decimal.toString builtin function.
+// With a scale, the decimal is rounded and always printed with that many
+// fraction digits, so 1.5 at scale 2 is "1.50".
+func decimalToString(args []runtime.Value) (runtime.Value, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return runtime.NewVoid(), fmt.Errorf("decimal.toString expects 1 to 3 arguments, got %d", len(args))
+	}
+
+	d, err := args[0].AsDecimal()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("decimal.toString: %v", err)
+	}
+	if len(args) == 1 {
+		return runtime.NewString(runtime.DecimalString(d)), nil
+	}
+
+	scale, rounded, err := roundDecimalArgs("decimal.toString", d, args[1:])
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	return runtime.NewString(rounded.FloatString(scale)), nil
+}
+
+// decimalOp returns a builtin applying op to two decimals or ints. The
+// result is rounded when a scale and optionally a rounding mode are given.
+func decimalOp(name string, op func(z, a, b *big.Rat) *big.Rat) BuiltinFunction {
+	return func(args []runtime.Value) (runtime.Value, error) {
+		a, b, err := decimalOperands(name, args)
+		if err != nil {
+			return runtime.NewVoid(), err
+		}
+		return roundedDecimal(name, op(new(big.Rat), a, b), args[2:])
+	}
+}
+
+// decimalDiv implements decimal.div builtin function.
+// Without a scale the quotient must have a finite decimal expansion, so
+// 1 / 4 is 0.25 but 1 / 3 needs a scale to round to.
+func decimalDiv(args []runtime.Value) (runtime.Value, error) {
+	a, b, err := decimalOperands("decimal.div", args)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	if len(args) == 2 {
+		q, err := runtime.QuoDecimal(a, b)
+		if err != nil {
+			return runtime.NewVoid(), fmt.Errorf("decimal.div: %v", err)
+		}
+		return runtime.NewDecimal(q), nil
+	}
+	if b.Sign() == 0 {
+		return runtime.NewVoid(), fmt.Errorf("decimal.div: division by zero")
+	}
+	return roundedDecimal("decimal.div", a.Quo(a, b), args[2:])
+}
+
+// decimalOperands returns the two decimal or int operands of a decimal
+// arithmetic builtin, which takes an optional scale and rounding mode after
+// them.
+func decimalOperands(name string, args []runtime.Value) (*big.Rat, *big.Rat, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return nil, nil, fmt.Errorf("%s expects 2 to 4 arguments, got %d", name, len(args))
+	}
+	a, err := args[0].AsDecimal()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: first argument: %v", name, err)
+	}
+	b, err := args[1].AsDecimal()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: second argument: %v", name, err)
+	}
+	return a, b, nil
+}
+
+// roundedDecimal returns d as a decimal value, rounded if the scale and
+// rounding mode arguments are present.
+func roundedDecimal(name string, d *big.Rat, args []runtime.Value) (runtime.Value, error) {
+	if len(args) == 0 {
+		return runtime.NewDecimal(d), nil
+	}
+	_, rounded, err := roundDecimalArgs(name, d, args)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+	return runtime.NewDecimal(rounded), nil
+}
+
+// roundDecimalArgs rounds d using the scale and optional rounding mode
+// arguments of a decimal builtin. The mode defaults to halfEven.
+func roundDecimalArgs(name string, d *big.Rat, args []runtime.Value) (int, *big.Rat, error) {
+	scale, err := args[0].AsInt()
+	if err != nil || args[0].Type != runtime.ValueTypeInt {
+		return 0, nil, fmt.Errorf("%s: scale must be an int", name)
+	}
+	mode := runtime.RoundHalfEven
+	if len(args) > 1 {
+		if mode, err = args[1].AsString(); err != nil {
+			return 0, nil, fmt.Errorf("%s: rounding mode must be a string", name)
+		}
+	}
+	rounded, err := runtime.RoundDecimal(d, int(scale), mode)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return int(scale), rounded, nil
+}
@@ -0,0 +1,67 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestDecimalFunctions(t *testing.T) {
+	registry := NewRegistry()
+	dec := func(s string) runtime.Value {
+		t.Helper()
+		result, err := registry.Call("decimal.fromString", []runtime.Value{runtime.NewString(s)})
+		if err != nil {
+			t.Fatalf("decimal.fromString(%q) error = %v", s, err)
+		}
+		return result
+	}
+	str := runtime.NewString
+	n := runtime.NewInt
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    string
+		wantErr string
+	}{
+		{name: "add", fn: "decimal.add", args: []runtime.Value{dec("0.1"), dec("0.2")}, want: "0.3"},
+		{name: "sub", fn: "decimal.sub", args: []runtime.Value{dec("5"), dec("0.01")}, want: "4.99"},
+		{name: "mul with int", fn: "decimal.mul", args: []runtime.Value{dec("1.10"), n(3)}, want: "3.3"},
+		{name: "mul rounded half even", fn: "decimal.mul", args: []runtime.Value{dec("1.005"), n(1), n(2)}, want: "1"},
+		{name: "mul rounded half up", fn: "decimal.mul", args: []runtime.Value{dec("1.005"), n(1), n(2), str(runtime.RoundHalfUp)}, want: "1.01"},
+		{name: "exact div", fn: "decimal.div", args: []runtime.Value{n(1), n(4)}, want: "0.25"},
+		{name: "rounded div", fn: "decimal.div", args: []runtime.Value{dec("10"), n(3), n(2)}, want: "3.33"},
+		{name: "rounded div ceiling", fn: "decimal.div", args: []runtime.Value{dec("10"), n(3), n(2), str(runtime.RoundCeiling)}, want: "3.34"},
+		{name: "inexact div", fn: "decimal.div", args: []runtime.Value{dec("10"), n(3)}, wantErr: "decimal.div: 10 / 3 has no exact decimal result"},
+		{name: "div by zero", fn: "decimal.div", args: []runtime.Value{dec("1"), dec("0"), n(2)}, wantErr: "decimal.div: division by zero"},
+		{name: "float operand", fn: "decimal.add", args: []runtime.Value{dec("1"), runtime.NewFloat(0.1)}, wantErr: "decimal.add: second argument"},
+		{name: "unknown mode", fn: "decimal.add", args: []runtime.Value{dec("1"), dec("2"), n(2), str("nearest")}, wantErr: `decimal.add: unknown rounding mode "nearest"`},
+		{name: "float scale", fn: "decimal.add", args: []runtime.Value{dec("1"), dec("2"), runtime.NewFloat(2)}, wantErr: "decimal.add: scale must be an int"},
+		{name: "toString", fn: "decimal.toString", args: []runtime.Value{dec("-0.50")}, want: "-0.5"},
+		{name: "toString with scale", fn: "decimal.toString", args: []runtime.Value{dec("1.5"), n(2)}, want: "1.50"},
+		{name: "toString rounded", fn: "decimal.toString", args: []runtime.Value{dec("2.675"), n(2), str(runtime.RoundDown)}, want: "2.67"},
+		{name: "invalid string", fn: "decimal.fromString", args: []runtime.Value{str("1e3")}, wantErr: `decimal.fromString: invalid decimal "1e3"`},
+		{name: "too few arguments", fn: "decimal.add", args: []runtime.Value{dec("1")}, wantErr: "decimal.add expects 2 to 4 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := registry.Call(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("%s() error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.fn, err)
+			}
+			if got := result.String(); got != tt.want {
+				t.Errorf("%s() = %s, want %s", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"github.com/dshills/alas/internal/runtime"
 )
 
-// Equal compares two runtime values for equality.
+// Equal compares two runtime values for deep equality, matching the
+// interpreter's == operator. Values of different types are never equal, so
+// an int never equals a float, and NaN is not equal to itself.
 func Equal(a, b runtime.Value) bool {
 	if a.Type != b.Type {
 		return false
@@ -17,6 +19,14 @@ func Equal(a, b runtime.Value) bool {
 		aVal, _ := a.AsInt()
 		bVal, _ := b.AsInt()
 		return aVal == bVal
+	case runtime.ValueTypeBigInt:
+		aVal, _ := a.AsBigInt()
+		bVal, _ := b.AsBigInt()
+		return aVal.Cmp(bVal) == 0
+	case runtime.ValueTypeDecimal:
+		aVal, _ := a.AsDecimal()
+		bVal, _ := b.AsDecimal()
+		return aVal.Cmp(bVal) == 0
 	case runtime.ValueTypeFloat:
 		aVal, _ := a.AsFloat()
 		bVal, _ := b.AsFloat()
@@ -31,10 +41,45 @@ func Equal(a, b runtime.Value) bool {
 		return aVal == bVal
 	case runtime.ValueTypeVoid:
 		return true
-	case runtime.ValueTypeArray, runtime.ValueTypeMap:
-		// For simplicity, only compare by reference for complex types
-		// A full deep comparison would be more complex
-		return false
+	case runtime.ValueTypeArray:
+		aVal, _ := a.AsArray()
+		bVal, _ := b.AsArray()
+		if len(aVal) != len(bVal) {
+			return false
+		}
+		for i := range aVal {
+			if !Equal(aVal[i], bVal[i]) {
+				return false
+			}
+		}
+		return true
+	case runtime.ValueTypeMap:
+		aVal, _ := a.AsMap()
+		bVal, _ := b.AsMap()
+		if len(aVal) != len(bVal) {
+			return false
+		}
+		for k, v := range aVal {
+			if bv, ok := bVal[k]; !ok || !Equal(v, bv) {
+				return false
+			}
+		}
+		return true
+	case runtime.ValueTypeEnum:
+		aVal, _ := a.AsEnum()
+		bVal, _ := b.AsEnum()
+		if aVal.Enum != bVal.Enum || aVal.Variant != bVal.Variant || len(aVal.Fields) != len(bVal.Fields) {
+			return false
+		}
+		for k, v := range aVal.Fields {
+			if bv, ok := bVal.Fields[k]; !ok || !Equal(v, bv) {
+				return false
+			}
+		}
+		return true
+	case runtime.ValueTypeFunction, runtime.ValueTypeStringBuilder, runtime.ValueTypeChannel, runtime.ValueTypeMutex:
+		// Functions, builders, channels and mutexes are equal only when they are the same value
+		return a.Value == b.Value
 	default:
 		return false
 	}
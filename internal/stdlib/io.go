@@ -3,6 +3,7 @@ package stdlib
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/dshills/alas/internal/runtime"
@@ -13,6 +14,7 @@ func (r *Registry) registerIOFunctions() {
 	r.Register("io.readFile", ioReadFile)
 	r.Register("io.writeFile", ioWriteFile)
 	r.Register("io.print", ioPrint)
+	r.Register("io.debug", ioDebug)
 	r.Register("io.readLine", ioReadLine)
 }
 
@@ -61,6 +63,20 @@ func ioWriteFile(args []runtime.Value) (runtime.Value, error) {
 	return createIOWriteResult(true, ""), nil
 }
 
+// debugOutput receives the dumps written by io.debug.
+var debugOutput io.Writer = os.Stderr
+
+// ioDebug implements io.debug builtin function.
+// Writes an indented dump of the value, with map keys sorted, to stderr and
+// returns void.
+func ioDebug(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("io.debug expects 1 argument, got %d", len(args))
+	}
+	fmt.Fprintln(debugOutput, args[0].Format(runtime.DefaultFormatOptions()))
+	return runtime.NewVoid(), nil
+}
+
 // ioPrint implements io.print builtin function.
 // Prints value to stdout, returns void.
 func ioPrint(args []runtime.Value) (runtime.Value, error) {
@@ -106,7 +122,7 @@ func ioPrint(args []runtime.Value) (runtime.Value, error) {
 			if !first {
 				fmt.Print(", ")
 			}
-			fmt.Printf("%s: ", key)
+			fmt.Printf("%s: ", runtime.MapKeyValue(key))
 			ioPrint([]runtime.Value{value})
 			first = false
 		}
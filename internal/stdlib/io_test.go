@@ -0,0 +1,35 @@
+package stdlib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestIODebug(t *testing.T) {
+	var out bytes.Buffer
+	stderr := debugOutput
+	debugOutput = &out
+	defer func() { debugOutput = stderr }()
+
+	value := runtime.NewMap(map[string]runtime.Value{
+		"b": runtime.NewArray([]runtime.Value{runtime.NewInt(1)}),
+		"a": runtime.NewString("x"),
+	})
+	result, err := NewRegistry().Call("io.debug", []runtime.Value{value})
+	if err != nil {
+		t.Fatalf("io.debug error = %v", err)
+	}
+	if result.Type != runtime.ValueTypeVoid {
+		t.Errorf("io.debug returned %v, want void", result)
+	}
+	want := "{\n  \"a\": \"x\",\n  \"b\": [\n    1\n  ]\n}\n"
+	if out.String() != want {
+		t.Errorf("io.debug wrote %q, want %q", out.String(), want)
+	}
+
+	if _, err := NewRegistry().Call("io.debug", nil); err == nil {
+		t.Error("io.debug with no arguments succeeded, want an error")
+	}
+}
@@ -0,0 +1,138 @@
+package stdlib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// LogLevel is the severity of a message written by the log builtins.
+type LogLevel int
+
+// Log levels, from least to most severe.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// logLevels maps each log builtin to the level it writes at.
+var logLevels = map[string]LogLevel{
+	"log.debug": LogDebug,
+	"log.info":  LogInfo,
+	"log.warn":  LogWarn,
+	"log.error": LogError,
+}
+
+// String returns the prefix written for messages of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// registerLogFunctions registers all std.log builtin functions. Messages
+// go to stderr at level info and above until SetLogOutput and SetLogLevel
+// change that.
+func (r *Registry) registerLogFunctions() {
+	r.logOutput = os.Stderr
+	r.logLevel = LogInfo
+	for name := range logLevels {
+		name := name
+		r.Register(name, func(args []runtime.Value) (runtime.Value, error) {
+			return r.Log(name, "", args)
+		})
+	}
+}
+
+// SetLogOutput sets the writer receiving the messages of the log builtins.
+func (r *Registry) SetLogOutput(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logOutput = w
+}
+
+// SetLogLevel sets the least severe level the log builtins write.
+func (r *Registry) SetLogLevel(level LogLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logLevel = level
+}
+
+// Log implements the log builtin name, such as log.info, for a call made
+// from function, which is left out of the message when empty.
+func (r *Registry) Log(name, function string, args []runtime.Value) (runtime.Value, error) {
+	level, ok := logLevels[name]
+	if !ok {
+		return runtime.NewVoid(), fmt.Errorf("builtin function not found: %s", name)
+	}
+	line, err := FormatLogLine(level, function, name, args)
+	if err != nil {
+		return runtime.NewVoid(), err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if level >= r.logLevel {
+		fmt.Fprintln(r.logOutput, line)
+	}
+	return runtime.NewVoid(), nil
+}
+
+// FormatLogLine formats a message of the log builtin name as
+// "[LEVEL] function: message key=value ...". The optional second argument
+// is a map of fields, written sorted by key; strings that would be ambiguous
+// unquoted are quoted.
+func FormatLogLine(level LogLevel, function, name string, args []runtime.Value) (string, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", fmt.Errorf("%s expects 1 or 2 arguments, got %d", name, len(args))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] ", level)
+	if function != "" {
+		fmt.Fprintf(&b, "%s: ", function)
+	}
+	b.WriteString(args[0].String())
+
+	if len(args) == 2 {
+		fields, err := args[1].AsMap()
+		if err != nil {
+			return "", fmt.Errorf("%s: fields must be a map", name)
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=%s", runtime.MapKeyValue(key), logFieldValue(fields[key]))
+		}
+	}
+	return b.String(), nil
+}
+
+// logFieldValue formats a field value, quoting strings that are empty or
+// contain spaces, quotes or equals signs.
+func logFieldValue(v runtime.Value) string {
+	s := v.String()
+	if v.Type == runtime.ValueTypeString && (s == "" || strings.ContainsAny(s, " \t\n\"=")) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestLogFunctions(t *testing.T) {
+	fields := runtime.NewMap(map[string]runtime.Value{
+		"user":  runtime.NewString("ann"),
+		"count": runtime.NewInt(3),
+		"note":  runtime.NewString("two words"),
+	})
+
+	tests := []struct {
+		name     string
+		fn       string
+		function string
+		level    LogLevel
+		args     []runtime.Value
+		want     string
+		wantErr  string
+	}{
+		{name: "info", fn: "log.info", level: LogInfo, args: []runtime.Value{runtime.NewString("started")}, want: "[INFO] started\n"},
+		{name: "function name", fn: "log.warn", level: LogInfo, function: "main", args: []runtime.Value{runtime.NewString("slow")}, want: "[WARN] main: slow\n"},
+		{name: "non-string message", fn: "log.error", level: LogInfo, args: []runtime.Value{runtime.NewInt(42)}, want: "[ERROR] 42\n"},
+		{name: "fields sorted and quoted", fn: "log.info", level: LogInfo, args: []runtime.Value{runtime.NewString("login"), fields}, want: "[INFO] login count=3 note=\"two words\" user=ann\n"},
+		{name: "debug filtered", fn: "log.debug", level: LogInfo, args: []runtime.Value{runtime.NewString("detail")}, want: ""},
+		{name: "debug enabled", fn: "log.debug", level: LogDebug, args: []runtime.Value{runtime.NewString("detail")}, want: "[DEBUG] detail\n"},
+		{name: "below error level", fn: "log.warn", level: LogError, args: []runtime.Value{runtime.NewString("slow")}, want: ""},
+		{name: "fields not a map", fn: "log.info", level: LogInfo, args: []runtime.Value{runtime.NewString("x"), runtime.NewInt(1)}, wantErr: "log.info: fields must be a map"},
+		{name: "no message", fn: "log.info", level: LogInfo, wantErr: "log.info expects 1 or 2 arguments, got 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			var out strings.Builder
+			registry.SetLogOutput(&out)
+			registry.SetLogLevel(tt.level)
+
+			_, err := registry.Log(tt.fn, tt.function, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("%s() error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.fn, err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("%s() wrote %q, want %q", tt.fn, out.String(), tt.want)
+			}
+		})
+	}
+}
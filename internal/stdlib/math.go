@@ -13,6 +13,7 @@ import (
 func (r *Registry) registerMathFunctions() {
 	// Constants
 	r.Register("math.PI", mathPI)
+	r.Register("math.pi", mathPI)
 	r.Register("math.E", mathE)
 
 	// Basic operations
@@ -21,6 +22,7 @@ func (r *Registry) registerMathFunctions() {
 	r.Register("math.max", mathMax)
 	r.Register("math.pow", mathPow)
 	r.Register("math.sqrt", mathSqrt)
+	r.Register("math.log", mathLog)
 
 	// Trigonometric functions
 	r.Register("math.sin", mathSin)
@@ -59,10 +61,10 @@ func validateTwoFloatArgs(args []runtime.Value, funcName string) (float64, float
 	return a, b, nil
 }
 
-// mathPI implements math.PI builtin function (returns PI constant).
+// mathPI implements the math.PI and math.pi builtin functions (returns PI constant).
 func mathPI(args []runtime.Value) (runtime.Value, error) {
 	if len(args) != 0 {
-		return runtime.NewVoid(), fmt.Errorf("math.PI expects 0 arguments, got %d", len(args))
+		return runtime.NewVoid(), fmt.Errorf("math.pi expects 0 arguments, got %d", len(args))
 	}
 	return runtime.NewFloat(math.Pi), nil
 }
@@ -137,6 +139,24 @@ func mathSqrt(args []runtime.Value) (runtime.Value, error) {
 	return runtime.NewFloat(math.Sqrt(val)), nil
 }
 
+// mathLog implements math.log builtin function (natural logarithm).
+func mathLog(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("math.log expects 1 argument, got %d", len(args))
+	}
+
+	val, err := args[0].AsFloat()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("math.log: %v", err)
+	}
+
+	if val <= 0 {
+		return runtime.NewVoid(), fmt.Errorf("math.log: logarithm of non-positive number")
+	}
+
+	return runtime.NewFloat(math.Log(val)), nil
+}
+
 // mathSin implements math.sin builtin function.
 func mathSin(args []runtime.Value) (runtime.Value, error) {
 	if len(args) != 1 {
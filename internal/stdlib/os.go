@@ -0,0 +1,77 @@
+package stdlib
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerOSFunctions registers all std.os builtin functions.
+func (r *Registry) registerOSFunctions() {
+	r.Register("os.getenv", osGetenv)
+	r.Register("os.setenv", osSetenv)
+	r.Register("os.exit", osExit)
+}
+
+// ExitError is returned by os.exit to unwind the program. Code is the exit
+// status the program asked to terminate with.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// osGetenv implements os.getenv builtin function.
+// Returns the value of an environment variable, or "" if it is not set.
+func osGetenv(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("os.getenv expects 1 argument, got %d", len(args))
+	}
+
+	name, err := args[0].AsString()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("os.getenv: %v", err)
+	}
+
+	return runtime.NewString(os.Getenv(name)), nil
+}
+
+// osSetenv implements os.setenv builtin function.
+// Sets an environment variable for the rest of the process.
+func osSetenv(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 2 {
+		return runtime.NewVoid(), fmt.Errorf("os.setenv expects 2 arguments, got %d", len(args))
+	}
+
+	name, err := args[0].AsString()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("os.setenv: %v", err)
+	}
+	value, err := args[1].AsString()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("os.setenv: %v", err)
+	}
+
+	if err := os.Setenv(name, value); err != nil {
+		return runtime.NewVoid(), fmt.Errorf("os.setenv: %v", err)
+	}
+	return runtime.NewVoid(), nil
+}
+
+// osExit implements os.exit builtin function.
+// Returns an *ExitError carrying the exit code rather than ending the process,
+// so the embedding program decides how to exit.
+func osExit(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("os.exit expects 1 argument, got %d", len(args))
+	}
+
+	code, err := args[0].AsInt()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("os.exit: %v", err)
+	}
+	return runtime.NewVoid(), &ExitError{Code: int(code)}
+}
@@ -0,0 +1,71 @@
+package stdlib
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestOSEnvironment(t *testing.T) {
+	t.Setenv("ALAS_TEST_ENV", "before")
+	registry := NewRegistry()
+	getenv := func(name string) string {
+		t.Helper()
+		result, err := registry.Call("os.getenv", []runtime.Value{runtime.NewString(name)})
+		if err != nil {
+			t.Fatalf("os.getenv(%q) error = %v", name, err)
+		}
+		s, err := result.AsString()
+		if err != nil {
+			t.Fatalf("os.getenv(%q) returned %v, want a string", name, result)
+		}
+		return s
+	}
+
+	if got := getenv("ALAS_TEST_ENV"); got != "before" {
+		t.Errorf("os.getenv = %q, want %q", got, "before")
+	}
+	os.Unsetenv("ALAS_TEST_ENV")
+	if got := getenv("ALAS_TEST_ENV"); got != "" {
+		t.Errorf("os.getenv of a missing variable = %q, want empty string", got)
+	}
+
+	result, err := registry.Call("os.setenv", []runtime.Value{runtime.NewString("ALAS_TEST_ENV"), runtime.NewString("after")})
+	if err != nil {
+		t.Fatalf("os.setenv error = %v", err)
+	}
+	if result.Type != runtime.ValueTypeVoid {
+		t.Errorf("os.setenv returned %v, want void", result)
+	}
+	if got := getenv("ALAS_TEST_ENV"); got != "after" {
+		t.Errorf("os.getenv after os.setenv = %q, want %q", got, "after")
+	}
+
+	for _, tt := range []struct {
+		name string
+		args []runtime.Value
+	}{
+		{"os.getenv", nil},
+		{"os.getenv", []runtime.Value{runtime.NewInt(1)}},
+		{"os.setenv", []runtime.Value{runtime.NewString("ALAS_TEST_ENV")}},
+		{"os.setenv", []runtime.Value{runtime.NewString(""), runtime.NewString("x")}},
+		{"os.exit", []runtime.Value{runtime.NewString("1")}},
+	} {
+		if _, err := registry.Call(tt.name, tt.args); err == nil {
+			t.Errorf("%s(%v) succeeded, want an error", tt.name, tt.args)
+		}
+	}
+}
+
+func TestOSExit(t *testing.T) {
+	_, err := NewRegistry().Call("os.exit", []runtime.Value{runtime.NewInt(3)})
+	var exit *ExitError
+	if !errors.As(err, &exit) {
+		t.Fatalf("os.exit error = %v, want *ExitError", err)
+	}
+	if exit.Code != 3 {
+		t.Errorf("exit code = %d, want 3", exit.Code)
+	}
+}
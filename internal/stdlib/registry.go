@@ -1,7 +1,10 @@
 package stdlib
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/dshills/alas/internal/runtime"
 )
@@ -9,52 +12,94 @@ import (
 // BuiltinFunction represents a native function that can be called from ALaS.
 type BuiltinFunction func(args []runtime.Value) (runtime.Value, error)
 
+// ContextBuiltinFunction is a builtin that blocks, and stops waiting when the
+// context of the run or async task calling it is done.
+type ContextBuiltinFunction func(ctx context.Context, args []runtime.Value) (runtime.Value, error)
+
 // Registry manages all built-in standard library functions.
+// It is safe for concurrent use.
 type Registry struct {
-	functions map[string]BuiltinFunction
+	mu        sync.RWMutex
+	functions map[string]ContextBuiltinFunction
+	logOutput io.Writer // receives messages of the log builtins
+	logLevel  LogLevel  // least severe level the log builtins write
 }
 
 // NewRegistry creates a new standard library function registry.
 func NewRegistry() *Registry {
 	r := &Registry{
-		functions: make(map[string]BuiltinFunction),
+		functions: make(map[string]ContextBuiltinFunction),
 	}
 
 	// Register all standard library modules
 	r.registerIOFunctions()
+	r.registerOSFunctions()
 	r.registerMathFunctions()
+	r.registerDecimalFunctions()
 	r.registerCollectionsFunctions()
+	r.registerArrayFunctions()
 	r.registerStringFunctions()
 	r.registerTypeFunctions()
 	r.registerResultFunctions()
 	r.registerAsyncFunctions()
+	r.registerChannelFunctions()
+	r.registerSyncFunctions()
+	r.registerRuntimeFunctions()
+	r.registerLogFunctions()
 
 	return r
 }
 
 // Register registers a builtin function.
 func (r *Registry) Register(name string, fn BuiltinFunction) {
+	r.RegisterContext(name, func(_ context.Context, args []runtime.Value) (runtime.Value, error) {
+		return fn(args)
+	})
+}
+
+// RegisterContext registers a builtin function that takes its caller's context.
+func (r *Registry) RegisterContext(name string, fn ContextBuiltinFunction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.functions[name] = fn
 }
 
 // Call calls a builtin function by name.
 func (r *Registry) Call(name string, args []runtime.Value) (runtime.Value, error) {
+	return r.CallContext(context.Background(), name, args)
+}
+
+// CallContext calls a builtin function by name under ctx.
+func (r *Registry) CallContext(ctx context.Context, name string, args []runtime.Value) (runtime.Value, error) {
+	r.mu.RLock()
 	fn, exists := r.functions[name]
+	r.mu.RUnlock()
 	if !exists {
 		return runtime.NewVoid(), fmt.Errorf("builtin function not found: %s", name)
 	}
 
-	return fn(args)
+	return fn(ctx, args)
+}
+
+// Unregister removes a builtin function.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.functions, name)
 }
 
 // HasFunction checks if a builtin function exists.
 func (r *Registry) HasFunction(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	_, exists := r.functions[name]
 	return exists
 }
 
 // ListFunctions returns all registered function names.
 func (r *Registry) ListFunctions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, 0, len(r.functions))
 	for name := range r.functions {
 		names = append(names, name)
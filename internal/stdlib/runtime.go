@@ -0,0 +1,35 @@
+package stdlib
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerRuntimeFunctions registers all std.runtime builtin functions.
+func (r *Registry) registerRuntimeFunctions() {
+	r.Register("runtime.version", runtimeVersion)
+	r.Register("runtime.hasBuiltin", r.runtimeHasBuiltin)
+}
+
+// runtimeVersion implements runtime.version builtin function.
+func runtimeVersion(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 0 {
+		return runtime.NewVoid(), fmt.Errorf("runtime.version expects 0 arguments, got %d", len(args))
+	}
+	return runtime.NewString(runtime.Version), nil
+}
+
+// runtimeHasBuiltin implements runtime.hasBuiltin builtin function. It
+// reports the builtins registered when it is called, including those added
+// with Register.
+func (r *Registry) runtimeHasBuiltin(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("runtime.hasBuiltin expects 1 argument, got %d", len(args))
+	}
+	name, err := args[0].AsString()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("runtime.hasBuiltin: %v", err)
+	}
+	return runtime.NewBool(r.HasFunction(name)), nil
+}
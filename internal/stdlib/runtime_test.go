@@ -0,0 +1,49 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestRuntimeFunctions(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("regex.match", func([]runtime.Value) (runtime.Value, error) {
+		return runtime.NewBool(true), nil
+	})
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		want    string
+		wantErr string
+	}{
+		{name: "version", fn: "runtime.version", want: runtime.Version},
+		{name: "stdlib builtin", fn: "runtime.hasBuiltin", args: []runtime.Value{runtime.NewString("sync.mutexLock")}, want: "true"},
+		{name: "registered builtin", fn: "runtime.hasBuiltin", args: []runtime.Value{runtime.NewString("regex.match")}, want: "true"},
+		{name: "missing builtin", fn: "runtime.hasBuiltin", args: []runtime.Value{runtime.NewString("regex.replace")}, want: "false"},
+		{name: "itself", fn: "runtime.hasBuiltin", args: []runtime.Value{runtime.NewString("runtime.hasBuiltin")}, want: "true"},
+		{name: "name not a string", fn: "runtime.hasBuiltin", args: []runtime.Value{runtime.NewInt(1)}, wantErr: "runtime.hasBuiltin: "},
+		{name: "version with arguments", fn: "runtime.version", args: []runtime.Value{runtime.NewInt(1)}, wantErr: "runtime.version expects 0 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Call(tt.fn, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("%s() error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.fn, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("%s() = %s, want %s", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,9 @@ func (r *Registry) registerStringFunctions() {
 	r.Register("string.toLower", stringToLower)
 	r.Register("string.trim", stringTrim)
 	r.Register("string.replace", stringReplace)
+	r.Register("string.builderCreate", stringBuilderCreate)
+	r.Register("string.builderAppend", stringBuilderAppend)
+	r.Register("string.builderToString", stringBuilderToString)
 }
 
 // stringLength implements string.length builtin function.
@@ -157,3 +160,45 @@ func stringReplace(args []runtime.Value) (runtime.Value, error) {
 	result := strings.ReplaceAll(str, old, new)
 	return runtime.NewString(result), nil
 }
+
+// stringBuilderCreate implements string.builderCreate builtin function.
+// Appending to a builder takes time proportional to the appended text, so
+// building a string in a loop is linear rather than quadratic as with +.
+func stringBuilderCreate(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 0 {
+		return runtime.NewVoid(), fmt.Errorf("string.builderCreate expects 0 arguments, got %d", len(args))
+	}
+
+	return runtime.NewStringBuilder(), nil
+}
+
+// stringBuilderAppend implements string.builderAppend builtin function.
+// Non-string values are appended as + would concatenate them. The builder
+// is changed in place and also returned.
+func stringBuilderAppend(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 2 {
+		return runtime.NewVoid(), fmt.Errorf("string.builderAppend expects 2 arguments, got %d", len(args))
+	}
+
+	builder, err := args[0].AsStringBuilder()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("string.builderAppend: %v", err)
+	}
+	builder.WriteString(args[1].String())
+
+	return args[0], nil
+}
+
+// stringBuilderToString implements string.builderToString builtin function.
+func stringBuilderToString(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("string.builderToString expects 1 argument, got %d", len(args))
+	}
+
+	builder, err := args[0].AsStringBuilder()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("string.builderToString: %v", err)
+	}
+
+	return runtime.NewString(builder.String()), nil
+}
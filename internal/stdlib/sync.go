@@ -0,0 +1,71 @@
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+// registerSyncFunctions registers all std.sync builtin functions.
+func (r *Registry) registerSyncFunctions() {
+	r.Register("sync.mutexCreate", syncMutexCreate)
+	r.Register("sync.mutexLock", syncMutexLock)
+	r.Register("sync.mutexUnlock", syncMutexUnlock)
+}
+
+// syncMutexCreate implements sync.mutexCreate builtin function.
+func syncMutexCreate(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 0 {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexCreate expects 0 arguments, got %d", len(args))
+	}
+	return runtime.NewMutex(), nil
+}
+
+// syncMutexLock implements sync.mutexLock builtin function.
+// With a timeout in milliseconds it fails instead of waiting forever, which
+// turns a task locking a mutex it already holds into an error.
+func syncMutexLock(args []runtime.Value) (runtime.Value, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexLock expects 1 or 2 arguments, got %d", len(args))
+	}
+
+	m, err := args[0].AsMutex()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexLock: %v", err)
+	}
+
+	if len(args) == 1 {
+		return runtime.NewVoid(), m.Lock(context.Background())
+	}
+
+	if args[1].Type != runtime.ValueTypeInt {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexLock: timeout must be an int")
+	}
+	timeoutMs, _ := args[1].AsInt()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutMs)*time.Millisecond)
+	defer cancel()
+	if err := m.Lock(ctx); err != nil {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexLock: timed out after %dms, possible deadlock", timeoutMs)
+	}
+
+	return runtime.NewVoid(), nil
+}
+
+// syncMutexUnlock implements sync.mutexUnlock builtin function.
+func syncMutexUnlock(args []runtime.Value) (runtime.Value, error) {
+	if len(args) != 1 {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexUnlock expects 1 argument, got %d", len(args))
+	}
+
+	m, err := args[0].AsMutex()
+	if err != nil {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexUnlock: %v", err)
+	}
+	if err := m.Unlock(); err != nil {
+		return runtime.NewVoid(), fmt.Errorf("sync.mutexUnlock: %v", err)
+	}
+
+	return runtime.NewVoid(), nil
+}
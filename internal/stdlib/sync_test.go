@@ -0,0 +1,51 @@
+package stdlib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/runtime"
+)
+
+func TestSyncMutexFunctions(t *testing.T) {
+	registry := NewRegistry()
+	mutex, err := registry.Call("sync.mutexCreate", nil)
+	if err != nil {
+		t.Fatalf("sync.mutexCreate() error = %v", err)
+	}
+	if got, _ := registry.Call("type.typeOf", []runtime.Value{mutex}); got.String() != "mutex" {
+		t.Errorf("type.typeOf(mutex) = %s, want mutex", got)
+	}
+
+	if _, err := registry.Call("sync.mutexLock", []runtime.Value{mutex}); err != nil {
+		t.Fatalf("sync.mutexLock() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []runtime.Value
+		wantErr string
+	}{
+		{name: "double lock", fn: "sync.mutexLock", args: []runtime.Value{mutex, runtime.NewInt(10)}, wantErr: "sync.mutexLock: timed out after 10ms, possible deadlock"},
+		{name: "float timeout", fn: "sync.mutexLock", args: []runtime.Value{mutex, runtime.NewFloat(10)}, wantErr: "sync.mutexLock: timeout must be an int"},
+		{name: "not a mutex", fn: "sync.mutexUnlock", args: []runtime.Value{runtime.NewString("m")}, wantErr: "sync.mutexUnlock: value is not a mutex"},
+		{name: "create with arguments", fn: "sync.mutexCreate", args: []runtime.Value{runtime.NewInt(1)}, wantErr: "sync.mutexCreate expects 0 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Call(tt.fn, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s() error = %v, want error containing %q", tt.fn, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := registry.Call("sync.mutexUnlock", []runtime.Value{mutex}); err != nil {
+		t.Fatalf("sync.mutexUnlock() error = %v", err)
+	}
+	if _, err := registry.Call("sync.mutexUnlock", []runtime.Value{mutex}); err == nil || !strings.Contains(err.Error(), "unlock of unlocked mutex") {
+		t.Errorf("second sync.mutexUnlock() error = %v, want unlock of unlocked mutex", err)
+	}
+}
@@ -29,10 +29,12 @@ func typeTypeOf(args []runtime.Value) (runtime.Value, error) {
 
 	val := args[0]
 	switch val.Type {
-	case runtime.ValueTypeInt:
+	case runtime.ValueTypeInt, runtime.ValueTypeBigInt:
 		return runtime.NewString("int"), nil
 	case runtime.ValueTypeFloat:
 		return runtime.NewString("float"), nil
+	case runtime.ValueTypeDecimal:
+		return runtime.NewString("decimal"), nil
 	case runtime.ValueTypeString:
 		return runtime.NewString("string"), nil
 	case runtime.ValueTypeBool:
@@ -43,6 +45,16 @@ func typeTypeOf(args []runtime.Value) (runtime.Value, error) {
 		return runtime.NewString("map"), nil
 	case runtime.ValueTypeVoid:
 		return runtime.NewString("void"), nil
+	case runtime.ValueTypeEnum:
+		return runtime.NewString("enum"), nil
+	case runtime.ValueTypeFunction:
+		return runtime.NewString("function"), nil
+	case runtime.ValueTypeStringBuilder:
+		return runtime.NewString("stringBuilder"), nil
+	case runtime.ValueTypeChannel:
+		return runtime.NewString("channel"), nil
+	case runtime.ValueTypeMutex:
+		return runtime.NewString("mutex"), nil
 	default:
 		return runtime.NewString("unknown"), nil
 	}
@@ -74,6 +86,9 @@ func typeToString(args []runtime.Value) (runtime.Value, error) {
 		return runtime.NewString("{Map}"), nil
 	case runtime.ValueTypeVoid:
 		return runtime.NewString("void"), nil
+	case runtime.ValueTypeBigInt, runtime.ValueTypeDecimal, runtime.ValueTypeEnum, runtime.ValueTypeFunction,
+		runtime.ValueTypeStringBuilder, runtime.ValueTypeChannel, runtime.ValueTypeMutex:
+		return runtime.NewString(val.String()), nil
 	default:
 		return runtime.NewString("unknown"), nil
 	}
@@ -123,7 +138,7 @@ func typeIsInt(args []runtime.Value) (runtime.Value, error) {
 		return runtime.NewVoid(), fmt.Errorf("type.isInt expects 1 argument, got %d", len(args))
 	}
 
-	return runtime.NewBool(args[0].Type == runtime.ValueTypeInt), nil
+	return runtime.NewBool(args[0].Type == runtime.ValueTypeInt || args[0].Type == runtime.ValueTypeBigInt), nil
 }
 
 // typeIsFloat implements type.isFloat builtin function.
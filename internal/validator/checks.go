@@ -0,0 +1,108 @@
+package validator
+
+import (
+	"strings"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// checkFunction runs the optional checks selected by the validator's options
+// on a function that passed validation.
+func (v *Validator) checkFunction(fn *ast.Function) {
+	if v.options.UnreachableCode != SeverityIgnore {
+		v.checkUnreachable(fn.Name, fn.Body)
+	}
+	if v.options.MissingReturn != SeverityIgnore && fn.Returns != "" && fn.Returns != ast.TypeVoid && !terminates(fn.Body) {
+		v.report(v.options.MissingReturn, sourcePos{}, "function '%s' can end without returning a %s", fn.Name, fn.Returns)
+	}
+	if v.options.UnusedVariables != SeverityIgnore {
+		v.checkUnused(fn)
+	}
+}
+
+// checkUnreachable reports statements that follow a statement that always
+// returns, in body and every block nested in it.
+func (v *Validator) checkUnreachable(function string, body []ast.Statement) {
+	for i := range body {
+		stmt := &body[i]
+		v.checkUnreachable(function, stmt.Then)
+		v.checkUnreachable(function, stmt.Else)
+		v.checkUnreachable(function, stmt.Init)
+		v.checkUnreachable(function, stmt.Body)
+		v.checkUnreachable(function, stmt.Update)
+		for _, matchCase := range stmt.Cases {
+			v.checkUnreachable(function, matchCase.Body)
+		}
+		v.checkUnreachable(function, stmt.Default)
+
+		if i+1 < len(body) && terminates(body[i:i+1]) {
+			v.report(v.options.UnreachableCode, stmtPos(&body[i+1]), "function '%s': %s statement after a return is unreachable", function, body[i+1].Type)
+			return
+		}
+	}
+}
+
+// terminates reports whether executing stmts always ends in a return.
+func terminates(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt.Type {
+		case ast.StmtReturn:
+			return true
+		case ast.StmtIf:
+			if terminates(stmt.Then) && terminates(stmt.Else) {
+				return true
+			}
+		case ast.StmtMatch:
+			// A match without a default has been checked to be exhaustive
+			all := stmt.Default == nil || terminates(stmt.Default)
+			for _, matchCase := range stmt.Cases {
+				all = all && terminates(matchCase.Body)
+			}
+			if all {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkUnused reports variables that a function assigns but never reads.
+// Names starting with an underscore are exempt.
+func (v *Validator) checkUnused(fn *ast.Function) {
+	type assignment struct {
+		name string
+		stmt *ast.Statement
+	}
+	var assigned []assignment
+	seen := make(map[string]bool)
+	used := make(map[string]bool)
+	assign := func(name string, stmt *ast.Statement) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			assigned = append(assigned, assignment{name, stmt})
+		}
+	}
+	ast.WalkStatements(fn.Body, func(stmt *ast.Statement) {
+		switch stmt.Type {
+		case ast.StmtAssign:
+			assign(stmt.Target, stmt)
+		case ast.StmtDestructure:
+			for _, name := range stmt.Targets {
+				assign(name, stmt)
+			}
+			for _, field := range stmt.BoundFields() {
+				assign(stmt.Bindings[field], stmt)
+			}
+		}
+	}, func(expr *ast.Expression) {
+		if expr.Type == ast.ExprVariable || expr.Type == ast.ExprCall {
+			used[expr.Name] = true
+		}
+	})
+
+	for _, a := range assigned {
+		if !used[a.name] && !strings.HasPrefix(a.name, "_") {
+			v.report(v.options.UnusedVariables, stmtPos(a.stmt), "function '%s': variable '%s' is assigned but never used", fn.Name, a.name)
+		}
+	}
+}
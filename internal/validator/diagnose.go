@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// Position is a 1-based line and column in the source.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Range is the span of source a diagnostic applies to. End is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// Diagnostic is a problem found in a module, in a form an editor can show.
+// Diagnostics whose node has no known position span the start of the file.
+type Diagnostic struct {
+	Severity Severity // SeverityError or SeverityWarning
+	Message  string
+	Path     string // Node that failed, as in ValidationError
+	Range    Range
+}
+
+// Diagnose validates ALaS JSON input with the default options and returns
+// every error and warning as a diagnostic. Unlike ValidateJSON it does not
+// stop at the first error in a function, and it never fails: malformed JSON
+// is reported as a diagnostic too.
+func Diagnose(data []byte) []Diagnostic {
+	return DiagnoseWithOptions(data, DefaultOptions())
+}
+
+// DiagnoseWithOptions is like Diagnose but runs the optional checks selected
+// by opts. Every error is always reported, whatever opts.AllErrors says. The
+// optional checks only run on functions that have no errors.
+func DiagnoseWithOptions(data []byte, opts Options) []Diagnostic {
+	lines := newLineIndex(data)
+
+	module, err := ast.ParseModule(data, "")
+	if err != nil {
+		start := Position{Line: 1, Column: 1}
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			// The offset is just past the offending byte
+			start = lines.position(max(syntaxErr.Offset-1, 0))
+		case errors.As(err, &typeErr):
+			start = lines.position(typeErr.Offset)
+		}
+		return []Diagnostic{{
+			Severity: SeverityError,
+			Message:  "invalid JSON: " + err.Error(),
+			Range:    Range{Start: start, End: start},
+		}}
+	}
+
+	opts.AllErrors = true
+	v := NewWithOptions(opts)
+	err = v.ValidateModule(module)
+
+	var diagnostics []Diagnostic
+	for _, ve := range ValidationErrors(err) {
+		diagnostics = append(diagnostics, lines.diagnostic(SeverityError, ve))
+	}
+	for _, warning := range v.warnings {
+		diagnostics = append(diagnostics, lines.diagnostic(SeverityWarning, warning))
+	}
+	return diagnostics
+}
+
+// lineIndex maps between byte offsets and positions in a source file.
+type lineIndex struct {
+	data       []byte
+	lineStarts []int
+}
+
+func newLineIndex(data []byte) *lineIndex {
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &lineIndex{data: data, lineStarts: lineStarts}
+}
+
+// position returns the position of a byte offset.
+func (l *lineIndex) position(offset int64) Position {
+	line := sort.Search(len(l.lineStarts), func(i int) bool {
+		return int64(l.lineStarts[i]) > offset
+	})
+	return Position{Line: line, Column: int(offset) - l.lineStarts[line-1] + 1}
+}
+
+// offset returns the byte offset of a position, or -1 if it is outside the
+// source.
+func (l *lineIndex) offset(pos Position) int64 {
+	if pos.Line < 1 || pos.Line > len(l.lineStarts) || pos.Column < 1 {
+		return -1
+	}
+	offset := int64(l.lineStarts[pos.Line-1] + pos.Column - 1)
+	if offset >= int64(len(l.data)) {
+		return -1
+	}
+	return offset
+}
+
+// diagnostic converts a validation error to a diagnostic. The range covers
+// the JSON value of the node that failed.
+func (l *lineIndex) diagnostic(severity Severity, ve *ValidationError) Diagnostic {
+	start := Position{Line: ve.Line, Column: ve.Column}
+	if ve.Line <= 0 {
+		start = Position{Line: 1, Column: 1}
+	}
+	end := start
+	if offset := l.offset(start); offset >= 0 && ve.Line > 0 {
+		var value json.RawMessage
+		if err := json.NewDecoder(bytes.NewReader(l.data[offset:])).Decode(&value); err == nil {
+			end = l.position(offset + int64(len(value)))
+		}
+	}
+	return Diagnostic{
+		Severity: severity,
+		Message:  ve.Message,
+		Path:     ve.Path,
+		Range:    Range{Start: start, End: end},
+	}
+}
@@ -0,0 +1,164 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidationError is a single validation error. Path names the node that
+// failed, from its function down through statements and operands. File,
+// Line, and Column locate the innermost node with a known source position;
+// they are zero when the module was decoded without positions.
+type ValidationError struct {
+	Message string
+	File    string
+	Line    int
+	Column  int
+	Path    string
+}
+
+// Error formats the error as "file:line:column: path: message", leaving out
+// the parts that are unknown.
+func (e *ValidationError) Error() string {
+	msg := e.Message
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	if e.Line <= 0 {
+		return msg
+	}
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, msg)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, msg)
+}
+
+// ValidationErrors returns the individual errors in an error returned by
+// ValidateModule or ValidateJSON, in the order they were found.
+func ValidationErrors(err error) []*ValidationError {
+	var result []*ValidationError
+	for err != nil {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				result = append(result, ValidationErrors(e)...)
+			}
+			return result
+		}
+		if ve, ok := err.(*ValidationError); ok {
+			return append(result, ve)
+		}
+		err = errors.Unwrap(err)
+	}
+	return result
+}
+
+// errorList collects the errors found while validating a node. In fail-fast
+// mode the first error stops validation of the node; when all errors are
+// wanted, its remaining children are still validated and every error is kept.
+type errorList struct {
+	all  bool
+	errs []error
+}
+
+// newErrorList returns an error list for the validator's error mode.
+func (v *Validator) newErrorList() *errorList {
+	return &errorList{all: v.options.AllErrors}
+}
+
+// report records err, if any, and reports whether validation of the node
+// should stop.
+func (l *errorList) report(err error) bool {
+	if err == nil {
+		return false
+	}
+	l.errs = append(l.errs, splitErrors(err)...)
+	return !l.all
+}
+
+// add records the errors in err, if any, prefixed with the position of the
+// child that produced them, and reports whether validation of the node
+// should stop.
+func (l *errorList) add(err error, format string, args ...interface{}) bool {
+	if err == nil {
+		return false
+	}
+	prefix := fmt.Sprintf(format, args...)
+	for _, e := range splitErrors(err) {
+		l.errs = append(l.errs, withPath(e, prefix))
+	}
+	return !l.all
+}
+
+// fail records err and returns all errors recorded so far. It is used for
+// errors that prevent validating the rest of the node.
+func (l *errorList) fail(err error) error {
+	l.report(err)
+	return l.err()
+}
+
+// failed reports whether any error has been recorded.
+func (l *errorList) failed() bool {
+	return len(l.errs) > 0
+}
+
+// err returns the recorded errors, joined if there are several, or nil.
+func (l *errorList) err() error {
+	switch len(l.errs) {
+	case 0:
+		return nil
+	case 1:
+		return l.errs[0]
+	}
+	return errors.Join(l.errs...)
+}
+
+// withPath returns err as a ValidationError whose path starts with prefix.
+func withPath(err error, prefix string) *ValidationError {
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return &ValidationError{Message: err.Error(), Path: prefix}
+	}
+	located := *ve
+	if located.Path == "" {
+		located.Path = prefix
+	} else {
+		located.Path = prefix + ": " + located.Path
+	}
+	return &located
+}
+
+// withPosition attaches a source position to the errors in err that do not
+// have one yet, so each error reports the innermost node that failed.
+func withPosition(err error, file string, line, column int) error {
+	if err == nil || line <= 0 {
+		return err
+	}
+	var located []error
+	for _, e := range splitErrors(err) {
+		ve, ok := e.(*ValidationError)
+		switch {
+		case !ok:
+			ve = &ValidationError{Message: e.Error()}
+		case ve.Line > 0:
+			located = append(located, ve)
+			continue
+		default:
+			copied := *ve
+			ve = &copied
+		}
+		ve.File, ve.Line, ve.Column = file, line, column
+		located = append(located, ve)
+	}
+	if len(located) == 1 {
+		return located[0]
+	}
+	return errors.Join(located...)
+}
+
+// splitErrors returns the individual errors in a joined error, or err itself.
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// Severity controls how the validator reports an optional check.
+type Severity int
+
+// Severities of optional checks.
+const (
+	SeverityIgnore  Severity = iota // The check is not run
+	SeverityWarning                 // Findings are reported as warnings
+	SeverityError                   // Findings fail validation
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "ignore"
+}
+
+// Options selects how strictly a Validator checks a module. Checks that do
+// not make a module invalid, only suspicious, are configured here. The zero
+// value ignores them all.
+type Options struct {
+	UnreachableCode   Severity // Statements after a return, unreachable match cases
+	UnusedVariables   Severity // Variables assigned but never read
+	NumericConversion Severity // Arithmetic and comparisons mixing int and float
+	MissingReturn     Severity // Functions that can end without returning a value
+
+	// AllErrors reports every error in a function instead of stopping at the
+	// first one.
+	AllErrors bool
+}
+
+// DefaultOptions returns the options used by New, which ignore every
+// optional check. Unreachable match cases are still reported as warnings.
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// StrictOptions returns options that make every optional check an error.
+func StrictOptions() Options {
+	return Options{
+		UnreachableCode:   SeverityError,
+		UnusedVariables:   SeverityError,
+		NumericConversion: SeverityError,
+		MissingReturn:     SeverityError,
+	}
+}
+
+// unreachableCase returns the severity of an unreachable match case. These
+// are always reported, as warnings unless unreachable code is an error.
+func (o Options) unreachableCase() Severity {
+	if o.UnreachableCode == SeverityError {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// report records a finding of an optional check at the given severity,
+// located at pos.
+func (v *Validator) report(severity Severity, pos sourcePos, format string, args ...interface{}) {
+	finding := &ValidationError{Message: fmt.Sprintf(format, args...), File: pos.file, Line: pos.line, Column: pos.column}
+	switch severity {
+	case SeverityWarning:
+		v.warnings = append(v.warnings, finding)
+	case SeverityError:
+		v.errors = append(v.errors, finding)
+	}
+}
+
+// sourcePos is the source position of an AST node; it is zero if unknown.
+type sourcePos struct {
+	file         string
+	line, column int
+}
+
+// stmtPos returns the source position of a statement.
+func stmtPos(stmt *ast.Statement) sourcePos {
+	return sourcePos{stmt.File, stmt.Line, stmt.Column}
+}
+
+// exprPos returns the source position of an expression.
+func exprPos(expr *ast.Expression) sourcePos {
+	return sourcePos{expr.File, expr.Line, expr.Column}
+}
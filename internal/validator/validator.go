@@ -1,29 +1,91 @@
 package validator
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/builtins"
+	"github.com/dshills/alas/internal/consteval"
+	"github.com/dshills/alas/internal/runtime"
 )
 
 // Validator validates ALaS AST structures.
 type Validator struct {
-	errors []string
+	errors          []error
+	warnings        []*ValidationError
+	enums           map[string]*ast.TypeDefinition // enum types visible to the module being validated
+	types           map[string]*ast.TypeDefinition // custom types, including aliases, of the module being validated
+	functionReturns map[string]string              // function name -> declared return type
+	functionArity   map[string]int                 // function name -> parameter count
+	importedParams  map[string][]string            // "module.function" -> parameter types of an imported function
+	exported        map[string]bool                // "module.function" names exported by imported modules
+	hiddenTypes     map[string]bool                // "module.Type" names of types imported modules do not export
+	importedModules map[string]bool                // imports loaded through the module loader
+	localTypes      map[string]string              // variable name -> known type in the current function
+	localArity      map[string]int                 // variable name -> parameter count of the function value it holds
+	returnType      string                         // resolved return type of the function or lambda being validated
+	typeNames       map[string]bool                // names of the custom types of the module being validated
+	module          *ast.Module                    // module last validated by ValidateModule
+	loader          ModuleLoader
+	options         Options
 }
 
-// New creates a new validator.
+// ModuleLoader loads imported modules so that their types can be checked.
+type ModuleLoader interface {
+	LoadModuleByName(name string) (*ast.Module, error)
+}
+
+// New creates a new validator with the default options.
 func New() *Validator {
+	return NewWithOptions(DefaultOptions())
+}
+
+// NewWithOptions creates a new validator that runs the optional checks
+// selected by opts.
+func NewWithOptions(opts Options) *Validator {
 	return &Validator{
-		errors: make([]string, 0),
+		errors:          make([]error, 0),
+		warnings:        make([]*ValidationError, 0),
+		enums:           make(map[string]*ast.TypeDefinition),
+		types:           make(map[string]*ast.TypeDefinition),
+		functionReturns: make(map[string]string),
+		functionArity:   make(map[string]int),
+		importedParams:  make(map[string][]string),
+		exported:        make(map[string]bool),
+		hiddenTypes:     make(map[string]bool),
+		importedModules: make(map[string]bool),
+		localTypes:      make(map[string]string),
+		localArity:      make(map[string]int),
+		options:         opts,
+	}
+}
+
+// SetModuleLoader sets the loader used to resolve imported modules. Without a
+// loader, types from imported modules are not known to the validator.
+func (v *Validator) SetModuleLoader(loader ModuleLoader) {
+	v.loader = loader
+}
+
+// Warnings returns the warnings reported by the last validation.
+func (v *Validator) Warnings() []string {
+	warnings := make([]string, len(v.warnings))
+	for i, warning := range v.warnings {
+		warnings[i] = warning.Error()
 	}
+	return warnings
 }
 
-// ValidateModule validates a complete module.
+// ValidateModule validates a complete module. The module's types, imports,
+// and function signatures are kept, so that ValidateFunction can re-check
+// single functions of it afterwards.
 func (v *Validator) ValidateModule(m *ast.Module) error {
-	v.errors = make([]string, 0)
+	v.errors = make([]error, 0)
+	v.warnings = make([]*ValidationError, 0)
 
 	// Validate module type
 	if m.Type != "module" {
@@ -37,17 +99,8 @@ func (v *Validator) ValidateModule(m *ast.Module) error {
 		v.addError("invalid module name '%s', must be valid module name", m.Name)
 	}
 
-	// Validate custom types
-	typeNames := make(map[string]bool)
-	for i, typeDef := range m.Types {
-		if err := v.validateTypeDefinition(&typeDef); err != nil {
-			v.addError("type %d: %v", i, err)
-		}
-		if typeNames[typeDef.Name] {
-			v.addError("duplicate type name: %s", typeDef.Name)
-		}
-		typeNames[typeDef.Name] = true
-	}
+	structTypes := v.buildSymbols(m)
+	typeNames := v.typeNames
 
 	// Validate functions
 	if len(m.Functions) == 0 {
@@ -55,9 +108,26 @@ func (v *Validator) ValidateModule(m *ast.Module) error {
 	}
 
 	functionNames := make(map[string]bool)
+	methodNames := make(map[string]map[string]bool) // receiver type -> method names
 	for i, fn := range m.Functions {
 		if err := v.validateFunction(&fn, typeNames); err != nil {
-			v.addError("function %d: %v", i, err)
+			v.addErrors(err, "function %d", i)
+		} else if fn.Extern == "" {
+			v.checkFunction(&fn)
+		}
+		if fn.Receiver != nil {
+			receiverType := fn.Receiver.Type
+			if typeNames[receiverType] && !structTypes[receiverType] {
+				v.addError("function %d: receiver type '%s' must be a struct type", i, receiverType)
+			}
+			if methodNames[receiverType] == nil {
+				methodNames[receiverType] = make(map[string]bool)
+			}
+			if methodNames[receiverType][fn.Name] {
+				v.addError("duplicate method name: %s.%s", receiverType, fn.Name)
+			}
+			methodNames[receiverType][fn.Name] = true
+			continue
 		}
 		if functionNames[fn.Name] {
 			v.addError("duplicate function name: %s", fn.Name)
@@ -65,6 +135,18 @@ func (v *Validator) ValidateModule(m *ast.Module) error {
 		functionNames[fn.Name] = true
 	}
 
+	// An extern symbol cannot also name a function defined in the module
+	for i, fn := range m.Functions {
+		if fn.Extern == "" || fn.Extern == fn.Name {
+			continue
+		}
+		for _, other := range m.Functions {
+			if other.Name == fn.Extern && other.Extern == "" && other.Receiver == nil {
+				v.addError("function %d: extern symbol '%s' conflicts with function '%s'", i, fn.Extern, other.Name)
+			}
+		}
+	}
+
 	// Validate exports reference actual functions
 	for i, export := range m.Exports {
 		if export == "" {
@@ -75,34 +157,175 @@ func (v *Validator) ValidateModule(m *ast.Module) error {
 			v.addError("exported function '%s' not found in module", export)
 		}
 	}
+	for _, fn := range m.Functions {
+		if exported, ok := fn.MetaBool(ast.MetaExport); !ok || exported || fn.Receiver != nil {
+			continue
+		}
+		for _, export := range m.Exports {
+			if export == fn.Name {
+				v.addError("function '%s': meta export false conflicts with the module exporting it", fn.Name)
+			}
+		}
+	}
+
+	v.validateModuleKind(m)
+
+	// Validate type exports reference actual types
+	for i, export := range m.TypeExports {
+		if export == "" {
+			v.addError("type export %d: name cannot be empty", i)
+		} else if !isValidIdentifier(export) {
+			v.addError("type export %d: invalid name '%s'", i, export)
+		} else if !typeNames[export] {
+			v.addError("exported type '%s' not found in module", export)
+		}
+	}
 
 	// Validate imports are non-empty strings and don't include self
-	for i, importName := range m.Imports {
-		if importName == "" {
+	for i, imp := range m.Imports {
+		if imp.Module == "" {
 			v.addError("import %d: name cannot be empty", i)
-		} else if !isValidModuleName(importName) {
-			v.addError("import %d: invalid name '%s'", i, importName)
-		} else if importName == m.Name {
+		} else if !isValidModuleName(imp.Module) {
+			v.addError("import %d: invalid name '%s'", i, imp.Module)
+		} else if imp.Module == m.Name {
 			v.addError("module cannot import itself")
 		}
+		if imp.Alias != "" && !isValidIdentifier(imp.Alias) {
+			v.addError("import %d: invalid alias '%s'", i, imp.Alias)
+		}
 	}
 
-	// Check for duplicate imports
+	// Check for duplicate imports, which are told apart by their local names
 	importSet := make(map[string]bool)
-	for i, importName := range m.Imports {
-		if importSet[importName] {
-			v.addError("import %d: duplicate import '%s'", i, importName)
+	for i, imp := range m.Imports {
+		if importSet[imp.Name()] {
+			if imp.Alias != "" {
+				v.addError("import %d: duplicate alias '%s'", i, imp.Alias)
+			} else {
+				v.addError("import %d: duplicate import '%s'", i, imp.Module)
+			}
 		}
-		importSet[importName] = true
+		importSet[imp.Name()] = true
 	}
 
 	if len(v.errors) > 0 {
-		return fmt.Errorf("validation errors:\n%s", strings.Join(v.errors, "\n"))
+		return fmt.Errorf("validation errors:\n%w", errors.Join(v.errors...))
+	}
+
+	return nil
+}
+
+// buildSymbols records the types, imports, and function signatures of m
+// that functions are validated against, reporting errors in the type
+// definitions. It returns the names of m's struct types.
+func (v *Validator) buildSymbols(m *ast.Module) map[string]bool {
+	v.module = m
+	v.enums = make(map[string]*ast.TypeDefinition)
+	v.types = make(map[string]*ast.TypeDefinition)
+	v.typeNames = make(map[string]bool)
+	v.functionReturns = make(map[string]string)
+	v.functionArity = make(map[string]int)
+	v.importedParams = make(map[string][]string)
+	v.exported = make(map[string]bool)
+	v.hiddenTypes = make(map[string]bool)
+	v.importedModules = make(map[string]bool)
+
+	// Validate custom types
+	structTypes := make(map[string]bool)
+	for i, typeDef := range m.Types {
+		if err := v.validateTypeDefinition(&typeDef); err != nil {
+			v.addErrors(err, "type %d", i)
+		}
+		if v.typeNames[typeDef.Name] {
+			v.addError("duplicate type name: %s", typeDef.Name)
+		}
+		v.typeNames[typeDef.Name] = true
+		v.types[typeDef.Name] = &m.Types[i]
+		switch typeDef.Definition.Kind {
+		case ast.TypeKindStruct:
+			structTypes[typeDef.Name] = true
+		case ast.TypeKindEnum:
+			v.enums[typeDef.Name] = &m.Types[i]
+		}
+	}
+
+	// Make imported enum types visible, both qualified and unqualified
+	v.registerImports(m.Imports)
+	for i, typeDef := range m.Types {
+		if err := v.checkTypeDefinitionExports(&typeDef); err != nil {
+			v.addErrors(err, "type %d", i)
+		}
+	}
+
+	// Resolve aliases, so that an alias of an enum can name its variants
+	for _, typeDef := range m.Types {
+		if typeDef.Definition.Kind != ast.TypeKindAlias {
+			continue
+		}
+		target, err := ast.ResolveTypeAlias(typeDef.Name, v.lookupType)
+		if err != nil {
+			v.addError("%v", err)
+			continue
+		}
+		if enumDef, ok := v.enums[target]; ok {
+			v.enums[typeDef.Name] = enumDef
+		}
+	}
+
+	for _, fn := range m.Functions {
+		if fn.Receiver == nil {
+			v.functionReturns[fn.Name] = v.resolveType(fn.Returns)
+			v.functionArity[fn.Name] = len(fn.Params)
+		}
+	}
+	return structTypes
+}
+
+// ValidateFunction validates fn against the module last validated by
+// ValidateModule, reusing that module's types, imports, and function
+// signatures, so an editor can re-check only the function being edited.
+// fn may replace a function of the module or be new to it. Its signature
+// replaces the one recorded for its name, which later calls to
+// ValidateFunction see; checks that span functions, such as duplicate
+// names, wait for the next ValidateModule. Warnings are reset to fn's.
+func (v *Validator) ValidateFunction(fn *ast.Function) error {
+	if v.module == nil {
+		return fmt.Errorf("no module has been validated")
+	}
+	v.errors = make([]error, 0)
+	v.warnings = make([]*ValidationError, 0)
+
+	if fn.Receiver == nil {
+		v.functionReturns[fn.Name] = v.resolveType(fn.Returns)
+		v.functionArity[fn.Name] = len(fn.Params)
+	}
+	if err := v.validateFunction(fn, v.typeNames); err != nil {
+		v.addErrors(err, "function %d", v.functionIndex(fn))
+	} else if fn.Extern == "" {
+		v.checkFunction(fn)
 	}
 
+	if len(v.errors) > 0 {
+		return fmt.Errorf("validation errors:\n%w", errors.Join(v.errors...))
+	}
 	return nil
 }
 
+// functionIndex returns the position of fn's name among the functions of
+// the validated module, or the position after them for a new function, so
+// errors are reported as ValidateModule reports them.
+func (v *Validator) functionIndex(fn *ast.Function) int {
+	for i, other := range v.module.Functions {
+		if other.Name != fn.Name || (other.Receiver == nil) != (fn.Receiver == nil) {
+			continue
+		}
+		if fn.Receiver == nil || other.Receiver.Type == fn.Receiver.Type {
+			return i
+		}
+	}
+	return len(v.module.Functions)
+}
+
 // validateTypeDefinition validates a custom type definition.
 func (v *Validator) validateTypeDefinition(typeDef *ast.TypeDefinition) error {
 	if typeDef.Name == "" {
@@ -128,18 +351,41 @@ func (v *Validator) validateTypeDefinition(typeDef *ast.TypeDefinition) error {
 			}
 		}
 	case ast.TypeKindEnum:
-		if len(typeDef.Definition.Values) == 0 {
+		if len(typeDef.Definition.Values) > 0 && len(typeDef.Definition.Variants) > 0 {
+			return fmt.Errorf("enum type '%s' cannot define both values and variants", typeDef.Name)
+		}
+		if len(typeDef.Definition.Values) == 0 && len(typeDef.Definition.Variants) == 0 {
 			return fmt.Errorf("enum type '%s' must have at least one value", typeDef.Name)
 		}
 		valueNames := make(map[string]bool)
-		for _, value := range typeDef.Definition.Values {
-			if value == "" {
+		for _, variant := range typeDef.Definition.EnumVariants() {
+			if variant.Name == "" {
 				return fmt.Errorf("enum value cannot be empty")
 			}
-			if valueNames[value] {
-				return fmt.Errorf("duplicate enum value: %s", value)
+			if valueNames[variant.Name] {
+				return fmt.Errorf("duplicate enum value: %s", variant.Name)
+			}
+			valueNames[variant.Name] = true
+			fieldNames := make(map[string]bool)
+			for i, field := range variant.Fields {
+				if field.Name == "" {
+					return fmt.Errorf("variant %s field %d: name cannot be empty", variant.Name, i)
+				}
+				if fieldNames[field.Name] {
+					return fmt.Errorf("variant %s: duplicate field name: %s", variant.Name, field.Name)
+				}
+				fieldNames[field.Name] = true
+				if !isValidType(field.Type, nil) {
+					return fmt.Errorf("variant %s field %s: invalid type '%s'", variant.Name, field.Name, field.Type)
+				}
 			}
-			valueNames[value] = true
+		}
+	case ast.TypeKindAlias:
+		if typeDef.Definition.Type == "" {
+			return fmt.Errorf("alias type '%s' must name the type it stands for", typeDef.Name)
+		}
+		if !isValidType(typeDef.Definition.Type, nil) {
+			return fmt.Errorf("alias type '%s': invalid type '%s'", typeDef.Name, typeDef.Definition.Type)
 		}
 	default:
 		return fmt.Errorf("unknown type kind: %s", typeDef.Definition.Kind)
@@ -148,8 +394,119 @@ func (v *Validator) validateTypeDefinition(typeDef *ast.TypeDefinition) error {
 	return nil
 }
 
+// lookupType returns the custom type definition of a name, or nil.
+func (v *Validator) lookupType(name string) *ast.TypeDefinition {
+	return v.types[name]
+}
+
+// resolveType returns the type an alias stands for, or t itself if it is not
+// an alias. Alias cycles are reported when the module's types are resolved.
+func (v *Validator) resolveType(t string) string {
+	resolved, err := ast.ResolveTypeAlias(t, v.lookupType)
+	if err != nil {
+		return t
+	}
+	return resolved
+}
+
+// structField returns the field of a struct type, if typeName names a struct
+// declaring it.
+func (v *Validator) structField(typeName, field string) (ast.TypeField, bool) {
+	def := v.lookupType(typeName)
+	if def == nil || def.Definition.Kind != ast.TypeKindStruct {
+		return ast.TypeField{}, false
+	}
+	for _, f := range def.Definition.Fields {
+		if f.Name == field {
+			return f, true
+		}
+	}
+	return ast.TypeField{}, false
+}
+
+// checkStructLiteral checks a map literal that constructs the struct type
+// typeName: it must name every field of the struct exactly once, and values
+// whose type is known must be assignable to their field. Nested map literals
+// are checked against struct field types in turn. Other expressions, and
+// types other than structs, are left alone.
+func (v *Validator) checkStructLiteral(expr *ast.Expression, typeName string) error {
+	def := v.lookupType(typeName)
+	if expr.Type != ast.ExprMapLit || def == nil || def.Definition.Kind != ast.TypeKindStruct {
+		return nil
+	}
+	provided := make(map[string]bool, len(expr.Pairs))
+	var extra []string
+	for i, pair := range expr.Pairs {
+		name, ok := pair.Key.Value.(string)
+		if pair.Key.Type != ast.ExprLiteral || !ok {
+			return fmt.Errorf("struct %s literal: field %d: name must be a string literal", typeName, i)
+		}
+		if provided[name] {
+			return fmt.Errorf("struct %s literal: duplicate field %s", typeName, name)
+		}
+		provided[name] = true
+		field, ok := v.structField(typeName, name)
+		if !ok {
+			extra = append(extra, name)
+			continue
+		}
+		fieldType := v.resolveType(field.Type)
+		if pair.Value.Type == ast.ExprMapLit && v.lookupType(fieldType) != nil {
+			if err := v.checkStructLiteral(&pair.Value, fieldType); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			continue
+		}
+		if valueType := v.exprType(&pair.Value); isKnownType(valueType) && isKnownType(fieldType) && !isAssignableType(valueType, fieldType) {
+			return fmt.Errorf("struct %s literal: field %s: expected %s, got %s", typeName, name, fieldType, valueType)
+		}
+	}
+	if len(extra) > 0 {
+		return fmt.Errorf("struct %s has no field %s", typeName, strings.Join(extra, ", "))
+	}
+	var missing []string
+	for _, field := range def.Definition.Fields {
+		if !provided[field.Name] {
+			missing = append(missing, field.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("struct %s literal missing field %s", typeName, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// validateModuleKind checks what a module's kind requires of it: a program
+// must define a main function the toolchain can run, and a library must
+// export functions. Modules without a kind are not checked.
+func (v *Validator) validateModuleKind(m *ast.Module) {
+	switch m.Kind {
+	case "":
+	case ast.ModuleKindProgram:
+		for _, fn := range m.Functions {
+			if fn.Name != "main" || fn.Receiver != nil {
+				continue
+			}
+			if fn.Extern != "" {
+				v.addError("program main function cannot be extern")
+			} else if len(fn.Params) > 1 || len(fn.Params) == 1 && ast.BaseType(v.resolveType(fn.Params[0].Type)) != ast.TypeArray {
+				v.addError("program main function must take no parameters or a single array of arguments")
+			}
+			return
+		}
+		v.addError("program module must define a main function")
+	case ast.ModuleKindLibrary:
+		if len(m.Exports) == 0 {
+			v.addError("library module must export at least one function")
+		}
+	default:
+		v.addError("unknown module kind '%s', must be '%s' or '%s'", m.Kind, ast.ModuleKindProgram, ast.ModuleKindLibrary)
+	}
+}
+
 // validateFunction validates a function definition.
 func (v *Validator) validateFunction(fn *ast.Function, typeNames map[string]bool) error {
+	errs := v.newErrorList()
 	if fn.Type != "function" {
 		return fmt.Errorf("type must be 'function', got '%s'", fn.Type)
 	}
@@ -163,6 +520,21 @@ func (v *Validator) validateFunction(fn *ast.Function, typeNames map[string]bool
 
 	// Validate parameters
 	paramNames := make(map[string]bool)
+
+	// Validate receiver; it shares the parameter namespace
+	if fn.Receiver != nil {
+		if fn.Receiver.Name == "" {
+			return fmt.Errorf("receiver name cannot be empty")
+		}
+		if !isValidIdentifier(fn.Receiver.Name) {
+			return fmt.Errorf("invalid receiver name '%s'", fn.Receiver.Name)
+		}
+		if !typeNames[fn.Receiver.Type] {
+			return fmt.Errorf("receiver type '%s' must be a custom type defined in the module", fn.Receiver.Type)
+		}
+		paramNames[fn.Receiver.Name] = true
+	}
+
 	for i, param := range fn.Params {
 		if param.Name == "" {
 			return fmt.Errorf("parameter %d: name cannot be empty", i)
@@ -178,12 +550,26 @@ func (v *Validator) validateFunction(fn *ast.Function, typeNames map[string]bool
 		if !isValidType(param.Type, typeNames) {
 			return fmt.Errorf("parameter %s: invalid type '%s'", param.Name, param.Type)
 		}
+		if err := v.checkTypeExported(param.Type); err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
 	}
 
 	// Validate return type
 	if fn.Returns != "" && !isValidType(fn.Returns, typeNames) {
 		return fmt.Errorf("invalid return type '%s'", fn.Returns)
 	}
+	if err := v.checkTypeExported(fn.Returns); err != nil {
+		return fmt.Errorf("return type: %w", err)
+	}
+
+	if err := validateFunctionMeta(fn); err != nil {
+		return err
+	}
+
+	if fn.Extern != "" {
+		return v.validateExtern(fn)
+	}
 
 	// Validate body exists
 	if fn.Body == nil {
@@ -196,20 +582,147 @@ func (v *Validator) validateFunction(fn *ast.Function, typeNames map[string]bool
 		scope[name] = true
 	}
 
+	// Track declared types of parameters for static checks
+	v.localTypes = make(map[string]string)
+	v.localArity = make(map[string]int)
+	v.returnType = v.resolveType(fn.Returns)
+	if fn.Receiver != nil {
+		v.localTypes[fn.Receiver.Name] = fn.Receiver.Type
+	}
+	for _, param := range fn.Params {
+		v.localTypes[param.Name] = v.resolveType(param.Type)
+	}
+
 	// Validate body statements
 	for i, stmt := range fn.Body {
-		if err := v.validateStatement(&stmt, scope, typeNames); err != nil {
-			return fmt.Errorf("statement %d: %v", i, err)
+		if errs.add(v.validateStatement(&stmt, scope, typeNames), "statement %d", i) {
+			return errs.err()
 		}
 	}
 
+	return errs.err()
+}
+
+// validateFunctionMeta type-checks the meta keys the compiler honors.
+// Unknown keys are left alone.
+func validateFunctionMeta(fn *ast.Function) error {
+	if inline, ok := fn.Meta[ast.MetaInline]; ok && inline != ast.InlineAlways && inline != ast.InlineNever {
+		return fmt.Errorf("meta '%s' must be \"%s\" or \"%s\"", ast.MetaInline, ast.InlineAlways, ast.InlineNever)
+	}
+	for _, key := range []string{ast.MetaNoOpt, ast.MetaExport} {
+		if _, ok := fn.Meta[key]; ok {
+			if _, isBool := fn.MetaBool(key); !isBool {
+				return fmt.Errorf("meta '%s' must be a bool", key)
+			}
+		}
+	}
+	if noopt, _ := fn.MetaBool(ast.MetaNoOpt); noopt && fn.MetaString(ast.MetaInline) == ast.InlineAlways {
+		return fmt.Errorf("meta '%s' \"%s\" conflicts with '%s'", ast.MetaInline, ast.InlineAlways, ast.MetaNoOpt)
+	}
+	if exported, ok := fn.MetaBool(ast.MetaExport); ok && !exported && fn.Name == "main" && fn.Receiver == nil {
+		return fmt.Errorf("function 'main' cannot have meta '%s' false", ast.MetaExport)
+	}
+	return nil
+}
+
+// validateExtern validates a function bound to a C symbol. Its parameters
+// and result must have a C representation: ints are int64_t, floats are
+// double, bools are bool, and strings are NUL-terminated char pointers.
+func (v *Validator) validateExtern(fn *ast.Function) error {
+	if !isValidIdentifier(fn.Extern) {
+		return fmt.Errorf("invalid extern symbol '%s'", fn.Extern)
+	}
+	if fn.Receiver != nil {
+		return fmt.Errorf("extern function cannot be a method")
+	}
+	if len(fn.Body) > 0 {
+		return fmt.Errorf("extern function cannot have a body")
+	}
+	for _, param := range fn.Params {
+		if !isFFIType(v.resolveType(param.Type)) {
+			return fmt.Errorf("parameter %s: type '%s' cannot be passed to C", param.Name, param.Type)
+		}
+	}
+	if returns := v.resolveType(fn.Returns); returns != "" && returns != ast.TypeVoid && !isFFIType(returns) {
+		return fmt.Errorf("return type '%s' cannot be returned from C", fn.Returns)
+	}
 	return nil
 }
 
+// isFFIType reports whether values of a type can cross into C.
+func isFFIType(t string) bool {
+	switch t {
+	case ast.TypeInt, ast.TypeFloat, ast.TypeBool, ast.TypeString:
+		return true
+	}
+	return false
+}
+
+// validateForClause validates an init or update statement of a for loop,
+// which may only assign a variable or evaluate an expression.
+func (v *Validator) validateForClause(stmt *ast.Statement, scope map[string]bool, typeNames map[string]bool) error {
+	if stmt.Type != ast.StmtAssign && stmt.Type != ast.StmtExpr {
+		return fmt.Errorf("%s statement is not allowed, must be %s or %s", stmt.Type, ast.StmtAssign, ast.StmtExpr)
+	}
+	return v.validateStatement(stmt, scope, typeNames)
+}
+
+// validateLvalue validates the target of an element assignment: a chain of
+// index and field expressions rooted at a variable.
+func (v *Validator) validateLvalue(expr *ast.Expression, scope map[string]bool, typeNames map[string]bool) error {
+	for e := expr; e.Type != ast.ExprVariable; e = e.Object {
+		if e.Type != ast.ExprIndex && e.Type != ast.ExprField {
+			return fmt.Errorf("assignment target must be an index or field expression, got %s", e.Type)
+		}
+		if e.Object == nil {
+			return fmt.Errorf("%s expression must have an object", e.Type)
+		}
+		objType := v.exprType(e.Object)
+		if isCastableType(objType) {
+			return fmt.Errorf("cannot assign to an element of type %s", objType)
+		}
+		if e.Type == ast.ExprField {
+			if def := v.lookupType(objType); def != nil && def.Definition.Kind == ast.TypeKindStruct {
+				if _, ok := v.structField(objType, e.Field); !ok {
+					return fmt.Errorf("struct %s has no field %s", objType, e.Field)
+				}
+			}
+		}
+	}
+	if expr.Type == ast.ExprVariable {
+		return fmt.Errorf("assignment lvalue must be an index or field expression, use target to assign variable '%s'", expr.Name)
+	}
+	return v.validateExpression(expr, scope, typeNames)
+}
+
 // validateStatement validates a statement.
-func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool, typeNames map[string]bool) error {
+func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool, typeNames map[string]bool) (err error) {
+	defer func() { err = withPosition(err, stmt.File, stmt.Line, stmt.Column) }()
+	errs := v.newErrorList()
 	switch stmt.Type {
 	case ast.StmtAssign:
+		if stmt.Lvalue != nil {
+			if stmt.Target != "" {
+				return fmt.Errorf("assign statement must have either a target or an lvalue, not both")
+			}
+			if stmt.Value == nil {
+				return fmt.Errorf("assign statement must have a value")
+			}
+			if errs.add(v.validateLvalue(stmt.Lvalue, scope, typeNames), "assign lvalue") {
+				return errs.err()
+			}
+			if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "assign value") {
+				return errs.err()
+			}
+			targetType, valueType := v.exprType(stmt.Lvalue), v.exprType(stmt.Value)
+			if targetType != "" && valueType != "" && !isAssignableType(valueType, targetType) {
+				if stmt.Lvalue.Type == ast.ExprField {
+					return errs.fail(fmt.Errorf("field %s: expected %s, got %s", stmt.Lvalue.Field, targetType, valueType))
+				}
+				return errs.fail(fmt.Errorf("element: expected %s, got %s", targetType, valueType))
+			}
+			return errs.err()
+		}
 		if stmt.Target == "" {
 			return fmt.Errorf("assign statement must have a target")
 		}
@@ -219,35 +732,45 @@ func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool
 		if stmt.Value == nil {
 			return fmt.Errorf("assign statement must have a value")
 		}
-		if err := v.validateExpression(stmt.Value, scope, typeNames); err != nil {
-			return fmt.Errorf("assign value: %v", err)
+		if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "assign value") {
+			return errs.err()
 		}
 		// Add target to scope
 		scope[stmt.Target] = true
+		if valueType := v.exprType(stmt.Value); valueType != "" {
+			v.localTypes[stmt.Target] = valueType
+		} else {
+			delete(v.localTypes, stmt.Target)
+		}
+		if arity, ok := v.exprArity(stmt.Value); ok {
+			v.localArity[stmt.Target] = arity
+		} else {
+			delete(v.localArity, stmt.Target)
+		}
 
 	case ast.StmtIf:
 		if stmt.Cond == nil {
 			return fmt.Errorf("if statement must have a condition")
 		}
-		if err := v.validateExpression(stmt.Cond, scope, typeNames); err != nil {
-			return fmt.Errorf("if condition: %v", err)
+		if errs.add(v.validateExpression(stmt.Cond, scope, typeNames), "if condition") {
+			return errs.err()
 		}
 		if len(stmt.Then) == 0 {
-			return fmt.Errorf("if statement must have a then block")
+			return errs.fail(fmt.Errorf("if statement must have a then block"))
 		}
 		// Validate then block
 		thenScope := copyScope(scope)
 		for i, s := range stmt.Then {
-			if err := v.validateStatement(&s, thenScope, typeNames); err != nil {
-				return fmt.Errorf("then block statement %d: %v", i, err)
+			if errs.add(v.validateStatement(&s, thenScope, typeNames), "then block statement %d", i) {
+				return errs.err()
 			}
 		}
 		// Validate else block if present
 		if len(stmt.Else) > 0 {
 			elseScope := copyScope(scope)
 			for i, s := range stmt.Else {
-				if err := v.validateStatement(&s, elseScope, typeNames); err != nil {
-					return fmt.Errorf("else block statement %d: %v", i, err)
+				if errs.add(v.validateStatement(&s, elseScope, typeNames), "else block statement %d", i) {
+					return errs.err()
 				}
 			}
 		}
@@ -256,17 +779,17 @@ func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool
 		if stmt.Cond == nil {
 			return fmt.Errorf("while statement must have a condition")
 		}
-		if err := v.validateExpression(stmt.Cond, scope, typeNames); err != nil {
-			return fmt.Errorf("while condition: %v", err)
+		if errs.add(v.validateExpression(stmt.Cond, scope, typeNames), "while condition") {
+			return errs.err()
 		}
 		if len(stmt.Body) == 0 {
-			return fmt.Errorf("while statement must have a body")
+			return errs.fail(fmt.Errorf("while statement must have a body"))
 		}
 		// Validate body
 		bodyScope := copyScope(scope)
 		for i, s := range stmt.Body {
-			if err := v.validateStatement(&s, bodyScope, typeNames); err != nil {
-				return fmt.Errorf("while body statement %d: %v", i, err)
+			if errs.add(v.validateStatement(&s, bodyScope, typeNames), "while body statement %d", i) {
+				return errs.err()
 			}
 		}
 
@@ -274,24 +797,39 @@ func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool
 		if stmt.Cond == nil {
 			return fmt.Errorf("for statement must have a condition")
 		}
-		if err := v.validateExpression(stmt.Cond, scope, typeNames); err != nil {
-			return fmt.Errorf("for condition: %v", err)
+		// Variables assigned by init are visible to the whole loop but not after it
+		loopScope := copyScope(scope)
+		for i, s := range stmt.Init {
+			if errs.add(v.validateForClause(&s, loopScope, typeNames), "for init statement %d", i) {
+				return errs.err()
+			}
+		}
+		if errs.add(v.validateExpression(stmt.Cond, loopScope, typeNames), "for condition") {
+			return errs.err()
 		}
 		if len(stmt.Body) == 0 {
-			return fmt.Errorf("for statement must have a body")
+			return errs.fail(fmt.Errorf("for statement must have a body"))
 		}
 		// Validate body
-		bodyScope := copyScope(scope)
+		bodyScope := copyScope(loopScope)
 		for i, s := range stmt.Body {
-			if err := v.validateStatement(&s, bodyScope, typeNames); err != nil {
-				return fmt.Errorf("for body statement %d: %v", i, err)
+			if errs.add(v.validateStatement(&s, bodyScope, typeNames), "for body statement %d", i) {
+				return errs.err()
+			}
+		}
+		for i, s := range stmt.Update {
+			if errs.add(v.validateForClause(&s, loopScope, typeNames), "for update statement %d", i) {
+				return errs.err()
 			}
 		}
 
 	case ast.StmtReturn:
 		if stmt.Value != nil {
-			if err := v.validateExpression(stmt.Value, scope, typeNames); err != nil {
-				return fmt.Errorf("return value: %v", err)
+			if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "return value") {
+				return errs.err()
+			}
+			if err := v.checkStructLiteral(stmt.Value, v.returnType); err != nil {
+				return errs.fail(fmt.Errorf("return value: %w", err))
 			}
 		}
 
@@ -299,27 +837,118 @@ func (v *Validator) validateStatement(stmt *ast.Statement, scope map[string]bool
 		if stmt.Value == nil {
 			return fmt.Errorf("expression statement must have a value")
 		}
-		if err := v.validateExpression(stmt.Value, scope, typeNames); err != nil {
-			return fmt.Errorf("expression: %v", err)
+		if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "expression") {
+			return errs.err()
+		}
+
+	case ast.StmtMatch:
+		if stmt.Value == nil {
+			return fmt.Errorf("match statement must have a value")
+		}
+		if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "match value") {
+			return errs.err()
+		}
+		if len(stmt.Cases) == 0 && stmt.Default == nil {
+			return errs.fail(fmt.Errorf("match statement must have at least one case"))
+		}
+		enumDef, err := v.matchEnum(stmt)
+		if err != nil {
+			return errs.fail(err)
+		}
+		covered := make(map[string]bool)
+		for i, matchCase := range stmt.Cases {
+			if matchCase.Variant == "" {
+				return errs.fail(fmt.Errorf("case %d: variant name cannot be empty", i))
+			}
+			variant, ok := findVariant(enumDef, matchCase.Variant)
+			switch {
+			case !ok:
+				v.report(v.options.unreachableCase(), stmtPos(stmt), "case %d: %s is not a variant of %s; case can never match", i, matchCase.Variant, enumDef.Name)
+			case covered[matchCase.Variant]:
+				v.report(v.options.unreachableCase(), stmtPos(stmt), "case %d: duplicate case for variant %s.%s is unreachable", i, enumDef.Name, matchCase.Variant)
+			}
+			var caseVariant *ast.EnumVariant
+			if ok {
+				caseVariant = &variant
+			}
+			if errs.add(v.validateMatchCase(&matchCase, caseVariant, scope, typeNames), "case %d", i) {
+				return errs.err()
+			}
+			covered[matchCase.Variant] = true
+		}
+		defaultScope := copyScope(scope)
+		for i, s := range stmt.Default {
+			if errs.add(v.validateStatement(&s, defaultScope, typeNames), "default statement %d", i) {
+				return errs.err()
+			}
+		}
+		if stmt.Default == nil {
+			var missing []string
+			for _, variant := range enumDef.Definition.EnumVariants() {
+				if !covered[variant.Name] {
+					missing = append(missing, variant.Name)
+				}
+			}
+			if len(missing) > 0 {
+				return errs.fail(fmt.Errorf("non-exhaustive match on %s: missing variants %s", enumDef.Name, strings.Join(missing, ", ")))
+			}
+		}
+
+	case ast.StmtDestructure:
+		if stmt.Value == nil {
+			return fmt.Errorf("destructure statement must have a value")
+		}
+		if (len(stmt.Targets) == 0) == (len(stmt.Bindings) == 0) {
+			return fmt.Errorf("destructure statement must have either targets or bindings")
+		}
+		if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "destructure value") {
+			return errs.err()
+		}
+		if err := v.validateDestructure(stmt, scope); err != nil {
+			return errs.fail(err)
+		}
+
+	case ast.StmtDefer:
+		if stmt.Value == nil {
+			return fmt.Errorf("defer statement must have a value")
+		}
+		if errs.add(v.validateExpression(stmt.Value, scope, typeNames), "deferred expression") {
+			return errs.err()
+		}
+
+	case ast.StmtAssert:
+		if stmt.Cond == nil {
+			return fmt.Errorf("assert statement must have a condition")
+		}
+		if errs.add(v.validateExpression(stmt.Cond, scope, typeNames), "assert condition") {
+			return errs.err()
+		}
+		if condType := staticExprType(stmt.Cond); condType != "" && condType != ast.TypeBool {
+			return errs.fail(fmt.Errorf("assert condition must be a boolean, got %s", condType))
 		}
 
 	default:
 		return fmt.Errorf("unknown statement type: %s", stmt.Type)
 	}
 
-	return nil
+	return errs.err()
 }
 
 // validateExpression validates an expression with comprehensive schema checking.
 // The typeNames parameter is currently unused but kept for future type checking enhancements.
 //
 //nolint:unparam // typeNames will be used for type inference in future
-func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bool, typeNames map[string]bool) error {
+func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bool, typeNames map[string]bool) (err error) {
+	defer func() { err = withPosition(err, expr.File, expr.Line, expr.Column) }()
+	errs := v.newErrorList()
 	switch expr.Type {
 	case ast.ExprLiteral:
 		if expr.Value == nil {
 			return fmt.Errorf("literal expression must have a value")
 		}
+		if expr.To != "" {
+			return v.validateTypedLiteral(expr)
+		}
 		// Enhanced literal validation based on value type
 		switch expr.Value.(type) {
 		case string:
@@ -360,11 +989,28 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		if expr.Left == nil || expr.Right == nil {
 			return fmt.Errorf("binary expression must have left and right operands")
 		}
-		if err := v.validateExpression(expr.Left, scope, typeNames); err != nil {
-			return fmt.Errorf("left operand: %v", err)
+		if errs.add(v.validateExpression(expr.Left, scope, typeNames), "left operand") {
+			return errs.err()
+		}
+		if errs.add(v.validateExpression(expr.Right, scope, typeNames), "right operand") {
+			return errs.err()
 		}
-		if err := v.validateExpression(expr.Right, scope, typeNames); err != nil {
-			return fmt.Errorf("right operand: %v", err)
+		// Operand types are only meaningful once both operands are valid
+		if errs.failed() {
+			return errs.err()
+		}
+		leftType, rightType := v.exprType(expr.Left), v.exprType(expr.Right)
+		if err := checkBinaryTypes(expr.Op, leftType, rightType); err != nil {
+			return err
+		}
+		if err := checkConstant(expr); err != nil {
+			return err
+		}
+		// Numeric literals adapt to the other operand, so only mixing typed
+		// values counts as an implicit conversion
+		if isNumericType(leftType) && isNumericType(rightType) && leftType != rightType &&
+			expr.Left.Type != ast.ExprLiteral && expr.Right.Type != ast.ExprLiteral {
+			v.report(v.options.NumericConversion, exprPos(expr), "operator '%s' implicitly converts %s and %s operands; add a cast", expr.Op, leftType, rightType)
 		}
 
 	case ast.ExprUnary:
@@ -383,8 +1029,11 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		} else {
 			return fmt.Errorf("unary expression must have an operand")
 		}
-		if err := v.validateExpression(operandExpr, scope, typeNames); err != nil {
-			return fmt.Errorf("unary operand: %v", err)
+		if errs.add(v.validateExpression(operandExpr, scope, typeNames), "unary operand") {
+			return errs.err()
+		}
+		if err := checkConstant(expr); err != nil {
+			return errs.fail(err)
 		}
 
 	case ast.ExprCall:
@@ -399,10 +1048,19 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		if expr.Args == nil {
 			return fmt.Errorf("function call must have args field (can be empty)")
 		}
+		if arity, ok := v.localArity[expr.Name]; ok && scope[expr.Name] && len(expr.Args) != arity {
+			return fmt.Errorf("function value '%s' expects %d arguments, got %d", expr.Name, arity, len(expr.Args))
+		}
+		// Variables holding function values shadow named functions
+		if arity, ok := v.functionArity[expr.Name]; ok && !scope[expr.Name] {
+			if err := checkArity(expr.Name, arity, len(expr.Args)); err != nil {
+				return err
+			}
+		}
 		// Validate arguments
 		for i, arg := range expr.Args {
-			if err := v.validateExpression(&arg, scope, typeNames); err != nil {
-				return fmt.Errorf("argument %d: %v", i, err)
+			if errs.add(v.validateExpression(&arg, scope, typeNames), "argument %d", i) {
+				return errs.err()
 			}
 		}
 
@@ -413,12 +1071,12 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		}
 		// Validate array elements
 		for i, elem := range expr.Elements {
-			if err := v.validateExpression(&elem, scope, typeNames); err != nil {
-				return fmt.Errorf("array element %d: %v", i, err)
+			if errs.add(v.validateExpression(&elem, scope, typeNames), "array element %d", i) {
+				return errs.err()
 			}
 			// Validate array element has proper structure
 			if elem.Type == "" {
-				return fmt.Errorf("array element %d: missing type field", i)
+				return errs.fail(fmt.Errorf("array element %d: missing type field", i))
 			}
 		}
 
@@ -429,18 +1087,21 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		}
 		// Validate map key-value pairs
 		for i, pair := range expr.Pairs {
-			if err := v.validateExpression(&pair.Key, scope, typeNames); err != nil {
-				return fmt.Errorf("map pair %d key: %v", i, err)
+			if errs.add(v.validateExpression(&pair.Key, scope, typeNames), "map pair %d key", i) {
+				return errs.err()
 			}
-			if err := v.validateExpression(&pair.Value, scope, typeNames); err != nil {
-				return fmt.Errorf("map pair %d value: %v", i, err)
+			if errs.add(v.validateExpression(&pair.Value, scope, typeNames), "map pair %d value", i) {
+				return errs.err()
 			}
 			// Validate key and value have proper structure
 			if pair.Key.Type == "" {
-				return fmt.Errorf("map pair %d key: missing type field", i)
+				return errs.fail(fmt.Errorf("map pair %d key: missing type field", i))
 			}
 			if pair.Value.Type == "" {
-				return fmt.Errorf("map pair %d value: missing type field", i)
+				return errs.fail(fmt.Errorf("map pair %d value: missing type field", i))
+			}
+			if keyType := v.exprType(&pair.Key); isKnownType(keyType) && !isMapKeyType(keyType) {
+				return errs.fail(fmt.Errorf("map pair %d key: map keys must be int, bool or string, got %s", i, keyType))
 			}
 		}
 
@@ -451,11 +1112,14 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		if expr.Index == nil {
 			return fmt.Errorf("index expression must have an index")
 		}
-		if err := v.validateExpression(expr.Object, scope, typeNames); err != nil {
-			return fmt.Errorf("index object: %v", err)
+		if errs.add(v.validateExpression(expr.Object, scope, typeNames), "index object") {
+			return errs.err()
 		}
-		if err := v.validateExpression(expr.Index, scope, typeNames); err != nil {
-			return fmt.Errorf("index: %v", err)
+		if errs.add(v.validateExpression(expr.Index, scope, typeNames), "index") {
+			return errs.err()
+		}
+		if err := v.checkIndexType(expr); err != nil {
+			return err
 		}
 
 	case ast.ExprModuleCall:
@@ -476,12 +1140,26 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		if expr.Args == nil {
 			return fmt.Errorf("module call must have args field (can be empty)")
 		}
+		// Calls into modules loaded through the loader are checked against
+		// their exports; other modules are resolved at run time
+		if v.importedModules[expr.Module] {
+			qualified := expr.Module + "." + expr.Name
+			if !v.exported[qualified] {
+				return fmt.Errorf("function '%s' is not exported from module '%s'", expr.Name, expr.Module)
+			}
+			if err := checkArity(qualified, v.functionArity[qualified], len(expr.Args)); err != nil {
+				return err
+			}
+		}
 		// Validate arguments
 		for i, arg := range expr.Args {
-			if err := v.validateExpression(&arg, scope, typeNames); err != nil {
-				return fmt.Errorf("module call argument %d: %v", i, err)
+			if errs.add(v.validateExpression(&arg, scope, typeNames), "module call argument %d", i) {
+				return errs.err()
 			}
 		}
+		if err := v.checkModuleCallArgs(expr); err != nil {
+			return err
+		}
 
 	case ast.ExprBuiltin:
 		if expr.Name == "" {
@@ -497,10 +1175,17 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		}
 		// Validate arguments
 		for i, arg := range expr.Args {
-			if err := v.validateExpression(&arg, scope, typeNames); err != nil {
-				return fmt.Errorf("builtin call argument %d: %v", i, err)
+			if errs.add(v.validateExpression(&arg, scope, typeNames), "builtin call argument %d", i) {
+				return errs.err()
 			}
 		}
+		// Argument types are only meaningful once the arguments are valid
+		if errs.failed() {
+			return errs.err()
+		}
+		if err := v.validateBuiltinArgs(expr); err != nil {
+			return err
+		}
 
 	case ast.ExprField:
 		if expr.Object == nil {
@@ -509,41 +1194,545 @@ func (v *Validator) validateExpression(expr *ast.Expression, scope map[string]bo
 		if expr.Field == "" {
 			return fmt.Errorf("field expression must have a field name")
 		}
-		if err := v.validateExpression(expr.Object, scope, typeNames); err != nil {
-			return fmt.Errorf("field object: %v", err)
+		if errs.add(v.validateExpression(expr.Object, scope, typeNames), "field object") {
+			return errs.err()
 		}
 
-	default:
-		return fmt.Errorf("unknown expression type: %s", expr.Type)
-	}
-
-	return nil
-}
-
-// Helper functions
-
-func (v *Validator) addError(format string, args ...interface{}) {
-	v.errors = append(v.errors, fmt.Sprintf(format, args...))
-}
-
-func isValidType(t string, typeNames map[string]bool) bool {
-	switch t {
-	case ast.TypeInt, ast.TypeFloat, ast.TypeString, ast.TypeBool,
-		ast.TypeArray, ast.TypeMap, ast.TypeVoid:
-		return true
-	default:
-		// Check if it's a custom type
-		if typeNames != nil && typeNames[t] {
-			return true
+	case ast.ExprMethodCall:
+		if expr.Object == nil {
+			return fmt.Errorf("method call must have a receiver object")
 		}
-		// For backward compatibility, accept any non-empty string as a type
-		return t != ""
-	}
-}
-
-func isValidBinaryOp(op string) bool {
-	switch op {
-	case ast.OpAdd, ast.OpSub, ast.OpMul, ast.OpDiv, ast.OpMod,
+		if expr.Name == "" {
+			return fmt.Errorf("method call must have a method name")
+		}
+		if !isValidIdentifier(expr.Name) {
+			return fmt.Errorf("invalid method name '%s'", expr.Name)
+		}
+		if errs.add(v.validateExpression(expr.Object, scope, typeNames), "method receiver") {
+			return errs.err()
+		}
+		if expr.Args == nil {
+			return errs.fail(fmt.Errorf("method call must have args field (can be empty)"))
+		}
+		for i, arg := range expr.Args {
+			if errs.add(v.validateExpression(&arg, scope, typeNames), "argument %d", i) {
+				return errs.err()
+			}
+		}
+
+	case ast.ExprVariant:
+		if expr.Enum == "" {
+			return fmt.Errorf("variant expression must have an enum type")
+		}
+		if expr.Variant == "" {
+			return fmt.Errorf("variant expression must have a variant name")
+		}
+		if err := v.checkTypeExported(expr.Enum); err != nil {
+			return err
+		}
+		enumDef, ok := v.enums[expr.Enum]
+		if !ok {
+			return fmt.Errorf("unknown enum type: %s", expr.Enum)
+		}
+		variant, ok := findVariant(enumDef, expr.Variant)
+		if !ok {
+			return fmt.Errorf("enum %s has no variant %s", expr.Enum, expr.Variant)
+		}
+		provided := make(map[string]bool)
+		for i, pair := range expr.Pairs {
+			name, ok := pair.Key.Value.(string)
+			if pair.Key.Type != ast.ExprLiteral || !ok {
+				return errs.fail(fmt.Errorf("field %d: name must be a string literal", i))
+			}
+			fieldType, ok := variantFieldType(variant, name)
+			if !ok {
+				return errs.fail(fmt.Errorf("variant %s.%s has no field %s", expr.Enum, expr.Variant, name))
+			}
+			fieldType = v.resolveType(fieldType)
+			if provided[name] {
+				return errs.fail(fmt.Errorf("duplicate field %s", name))
+			}
+			provided[name] = true
+			if errs.add(v.validateExpression(&pair.Value, scope, typeNames), "field %s", name) {
+				return errs.err()
+			}
+			if valueType := staticExprType(&pair.Value); valueType != "" && !isAssignableType(valueType, fieldType) {
+				return errs.fail(fmt.Errorf("field %s: expected %s, got %s", name, fieldType, valueType))
+			}
+		}
+		for _, field := range variant.Fields {
+			if !provided[field.Name] {
+				return errs.fail(fmt.Errorf("variant %s.%s missing field %s", expr.Enum, expr.Variant, field.Name))
+			}
+		}
+
+	case ast.ExprCast:
+		if expr.To == "" {
+			return fmt.Errorf("cast expression must have a target type")
+		}
+		if !isCastableType(expr.To) {
+			return fmt.Errorf("invalid cast target type '%s', must be one of: int, float, bool, string", expr.To)
+		}
+		if expr.Operand == nil {
+			return fmt.Errorf("cast expression must have an operand")
+		}
+		if errs.add(v.validateExpression(expr.Operand, scope, typeNames), "cast operand") {
+			return errs.err()
+		}
+		// Enum values cast to int give their variant's index
+		if srcType := v.exprType(expr.Operand); isKnownType(srcType) && !isCastableType(srcType) &&
+			(expr.To != ast.TypeInt || v.enums[v.resolveType(srcType)] == nil) {
+			return errs.fail(fmt.Errorf("cannot cast %s to %s", srcType, expr.To))
+		}
+		if err := checkConstant(expr); err != nil {
+			return errs.fail(err)
+		}
+
+	case ast.ExprLambda:
+		return v.validateLambda(expr, scope, typeNames)
+
+	case ast.ExprFuncRef:
+		if expr.Name == "" {
+			return fmt.Errorf("function reference must have a function name")
+		}
+		if !isValidIdentifier(expr.Name) {
+			return fmt.Errorf("invalid function name '%s'", expr.Name)
+		}
+		if expr.Module == "" {
+			if _, ok := v.functionArity[expr.Name]; !ok {
+				return fmt.Errorf("undefined function: %s", expr.Name)
+			}
+		} else if v.importedModules[expr.Module] {
+			if _, ok := v.functionArity[expr.Module+"."+expr.Name]; !ok {
+				return fmt.Errorf("module %s has no function %s", expr.Module, expr.Name)
+			}
+			if !v.exported[expr.Module+"."+expr.Name] {
+				return fmt.Errorf("function '%s' is not exported from module '%s'", expr.Name, expr.Module)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown expression type: %s", expr.Type)
+	}
+
+	return errs.err()
+}
+
+// validateLambda validates a lambda's parameters and body. The body sees the
+// enclosing scope plus the lambda parameters.
+func (v *Validator) validateLambda(expr *ast.Expression, scope map[string]bool, typeNames map[string]bool) error {
+	errs := v.newErrorList()
+	if expr.Body == nil {
+		return fmt.Errorf("lambda must have a body")
+	}
+
+	lambdaScope := copyScope(scope)
+	lambdaTypes := make(map[string]string, len(v.localTypes)+len(expr.Params))
+	for name, typ := range v.localTypes {
+		lambdaTypes[name] = typ
+	}
+	lambdaArity := make(map[string]int, len(v.localArity))
+	for name, arity := range v.localArity {
+		lambdaArity[name] = arity
+	}
+	paramNames := make(map[string]bool)
+	for i, param := range expr.Params {
+		if param.Name == "" {
+			return fmt.Errorf("lambda parameter %d: name cannot be empty", i)
+		}
+		if !isValidIdentifier(param.Name) {
+			return fmt.Errorf("lambda parameter %d: invalid name '%s'", i, param.Name)
+		}
+		if paramNames[param.Name] {
+			return fmt.Errorf("duplicate lambda parameter name: %s", param.Name)
+		}
+		paramNames[param.Name] = true
+		if !isValidType(param.Type, typeNames) {
+			return fmt.Errorf("lambda parameter %s: invalid type '%s'", param.Name, param.Type)
+		}
+		if err := v.checkTypeExported(param.Type); err != nil {
+			return fmt.Errorf("lambda parameter %s: %w", param.Name, err)
+		}
+		lambdaScope[param.Name] = true
+		lambdaTypes[param.Name] = v.resolveType(param.Type)
+		delete(lambdaArity, param.Name)
+	}
+	if expr.Returns != "" && !isValidType(expr.Returns, typeNames) {
+		return fmt.Errorf("invalid lambda return type '%s'", expr.Returns)
+	}
+	if err := v.checkTypeExported(expr.Returns); err != nil {
+		return fmt.Errorf("lambda return type: %w", err)
+	}
+
+	// Type information recorded inside the body does not leak out of it
+	outerTypes, outerArity, outerReturn := v.localTypes, v.localArity, v.returnType
+	v.localTypes, v.localArity, v.returnType = lambdaTypes, lambdaArity, v.resolveType(expr.Returns)
+	defer func() { v.localTypes, v.localArity, v.returnType = outerTypes, outerArity, outerReturn }()
+
+	for i, stmt := range expr.Body {
+		if errs.add(v.validateStatement(&stmt, lambdaScope, typeNames), "lambda statement %d", i) {
+			return errs.err()
+		}
+	}
+	return errs.err()
+}
+
+// Helper functions
+
+func (v *Validator) addError(format string, args ...interface{}) {
+	v.errors = append(v.errors, &ValidationError{Message: fmt.Sprintf(format, args...)})
+}
+
+// addErrors records each error in err under the path given by format.
+func (v *Validator) addErrors(err error, format string, args ...interface{}) {
+	prefix := fmt.Sprintf(format, args...)
+	for _, e := range splitErrors(err) {
+		v.errors = append(v.errors, withPath(e, prefix))
+	}
+}
+
+// registerImports loads imported modules and records their enum types and
+// function signatures. Imports that cannot be loaded are skipped.
+func (v *Validator) registerImports(imports []ast.Import) {
+	if v.loader == nil {
+		return
+	}
+	for _, imp := range imports {
+		imported, err := v.loader.LoadModuleByName(imp.Module)
+		if err != nil {
+			continue
+		}
+		importName := imp.Name()
+		v.importedModules[importName] = true
+		for i := range imported.Types {
+			typeDef := &imported.Types[i]
+			if !imported.ExportsType(typeDef.Name) {
+				v.hiddenTypes[importName+"."+typeDef.Name] = true
+				continue
+			}
+			if typeDef.Definition.Kind != ast.TypeKindEnum {
+				continue
+			}
+			v.enums[importName+"."+typeDef.Name] = typeDef
+			if _, exists := v.enums[typeDef.Name]; !exists {
+				v.enums[typeDef.Name] = typeDef
+			}
+		}
+		for _, fn := range imported.Functions {
+			qualified := importName + "." + fn.Name
+			v.functionReturns[qualified] = importedType(imported, importName, fn.Returns)
+			v.functionArity[qualified] = len(fn.Params)
+			params := make([]string, len(fn.Params))
+			for i, param := range fn.Params {
+				params[i] = importedType(imported, importName, param.Type)
+			}
+			v.importedParams[qualified] = params
+		}
+		for _, name := range imported.Exports {
+			v.exported[importName+"."+name] = true
+		}
+	}
+}
+
+// importedType returns how type t of an imported module is named in the
+// importing module: aliases are resolved and the module's own types are
+// qualified with importName.
+func importedType(imported *ast.Module, importName, t string) string {
+	lookup := func(name string) *ast.TypeDefinition {
+		for i := range imported.Types {
+			if imported.Types[i].Name == name {
+				return &imported.Types[i]
+			}
+		}
+		return nil
+	}
+	if resolved, err := ast.ResolveTypeAlias(t, lookup); err == nil {
+		t = resolved
+	}
+	if base, args, err := ast.ParseType(t); err == nil && len(args) > 0 {
+		for i, arg := range args {
+			args[i] = importedType(imported, importName, arg)
+		}
+		return ast.ContainerType(base, args...)
+	}
+	if lookup(t) != nil {
+		return importName + "." + t
+	}
+	return t
+}
+
+// checkModuleCallArgs reports module call arguments whose static types do
+// not match the parameters declared by the imported function.
+func (v *Validator) checkModuleCallArgs(expr *ast.Expression) error {
+	params, ok := v.importedParams[expr.Module+"."+expr.Name]
+	if !ok || len(params) != len(expr.Args) {
+		return nil
+	}
+	for i := range expr.Args {
+		argType := v.exprType(&expr.Args[i])
+		if argType == "" || params[i] == "" || params[i] == "any" || isAssignableType(argType, params[i]) {
+			continue
+		}
+		// Imported types may also be named without their module
+		if argType == strings.TrimPrefix(params[i], expr.Module+".") {
+			continue
+		}
+		return fmt.Errorf("argument %d to function '%s' from module '%s': expected %s, got %s",
+			i, expr.Name, expr.Module, params[i], argType)
+	}
+	return nil
+}
+
+// checkTypeExported reports a qualified type name naming a type its imported
+// module does not export.
+func (v *Validator) checkTypeExported(t string) error {
+	if _, args, err := ast.ParseType(t); err == nil && len(args) > 0 {
+		for _, arg := range args {
+			if err := v.checkTypeExported(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !v.hiddenTypes[t] {
+		return nil
+	}
+	module, name, _ := strings.Cut(t, ".")
+	return fmt.Errorf("type '%s' is not exported from module '%s'", name, module)
+}
+
+// checkTypeDefinitionExports reports fields and alias targets of a type
+// definition naming types their imported modules do not export.
+func (v *Validator) checkTypeDefinitionExports(typeDef *ast.TypeDefinition) error {
+	if err := v.checkTypeExported(typeDef.Definition.Type); err != nil {
+		return err
+	}
+	for _, field := range typeDef.Definition.Fields {
+		if err := v.checkTypeExported(field.Type); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	for _, variant := range typeDef.Definition.Variants {
+		for _, field := range variant.Fields {
+			if err := v.checkTypeExported(field.Type); err != nil {
+				return fmt.Errorf("variant %s field %s: %w", variant.Name, field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkArity reports a call to a named function with the wrong number of
+// arguments. Every parameter is currently required; default and variadic
+// parameters would widen the accepted range here.
+func checkArity(name string, params, args int) error {
+	if args != params {
+		return fmt.Errorf("function '%s' expects %d arguments, got %d", name, params, args)
+	}
+	return nil
+}
+
+// exprType returns the type of an expression when it is known statically,
+// including variables and calls whose types were recorded in this module.
+func (v *Validator) exprType(expr *ast.Expression) string {
+	switch expr.Type {
+	case ast.ExprVariable:
+		return v.localTypes[expr.Name]
+	case ast.ExprCall:
+		if v.localTypes[expr.Name] == ast.TypeFunc {
+			// Calls through function values have no known return type
+			return ""
+		}
+		return v.functionReturns[expr.Name]
+	case ast.ExprModuleCall:
+		return v.functionReturns[expr.Module+"."+expr.Name]
+	case ast.ExprField:
+		if expr.Object != nil {
+			if field, ok := v.structField(v.exprType(expr.Object), expr.Field); ok {
+				return v.resolveType(field.Type)
+			}
+		}
+	case ast.ExprIndex:
+		if expr.Object != nil {
+			if elemType := ast.ElementType(v.exprType(expr.Object)); elemType != "" {
+				return v.resolveType(elemType)
+			}
+		}
+	case ast.ExprArrayLit:
+		if elemType := v.commonType(expr.Elements); elemType != "" {
+			return ast.ContainerType(ast.TypeArray, elemType)
+		}
+	case ast.ExprMapLit:
+		keys := make([]ast.Expression, len(expr.Pairs))
+		values := make([]ast.Expression, len(expr.Pairs))
+		for i, pair := range expr.Pairs {
+			keys[i], values[i] = pair.Key, pair.Value
+		}
+		if keyType, valueType := v.commonType(keys), v.commonType(values); keyType != "" && valueType != "" {
+			return ast.ContainerType(ast.TypeMap, keyType, valueType)
+		}
+	case ast.ExprBinary:
+		if expr.Left != nil && expr.Right != nil {
+			if typ := binaryResultType(expr.Op, v.exprType(expr.Left), v.exprType(expr.Right)); typ != "" {
+				return typ
+			}
+		}
+	}
+	return staticExprType(expr)
+}
+
+// commonType returns the type shared by all of the expressions, or an empty
+// string if there are none or their types differ or are unknown. It infers
+// the element types of container literals.
+func (v *Validator) commonType(exprs []ast.Expression) string {
+	common := ""
+	for i := range exprs {
+		typ := v.exprType(&exprs[i])
+		if !isKnownType(typ) || common != "" && typ != common {
+			return ""
+		}
+		common = typ
+	}
+	return common
+}
+
+// checkIndexType reports indexing an array<T> with a non-int index or a
+// map<K,V> with a key that is not a K.
+func (v *Validator) checkIndexType(expr *ast.Expression) error {
+	base, args, err := ast.ParseType(v.exprType(expr.Object))
+	if err != nil {
+		return nil
+	}
+	if len(args) == 0 {
+		if indexType := v.exprType(expr.Index); base == ast.TypeMap && isKnownType(indexType) && !isMapKeyType(indexType) {
+			return fmt.Errorf("map keys must be int, bool or string, got %s", indexType)
+		}
+		return nil
+	}
+	want := ast.TypeInt
+	if base == ast.TypeMap {
+		want = args[0]
+	}
+	if indexType := v.exprType(expr.Index); isKnownType(indexType) && indexType != want {
+		return fmt.Errorf("index of %s must be %s, got %s", ast.ContainerType(base, args...), want, indexType)
+	}
+	return nil
+}
+
+// exprArity returns the parameter count of a function value when it is known
+// statically.
+func (v *Validator) exprArity(expr *ast.Expression) (int, bool) {
+	switch expr.Type {
+	case ast.ExprLambda:
+		return len(expr.Params), true
+	case ast.ExprFuncRef:
+		name := expr.Name
+		if expr.Module != "" {
+			name = expr.Module + "." + name
+		}
+		arity, ok := v.functionArity[name]
+		return arity, ok
+	case ast.ExprVariable:
+		arity, ok := v.localArity[expr.Name]
+		return arity, ok
+	}
+	return 0, false
+}
+
+// validateBuiltinArgs checks the argument count and the statically known
+// argument types of a builtin call against its signature.
+func (v *Validator) validateBuiltinArgs(expr *ast.Expression) error {
+	sig, ok := builtins.Lookup(expr.Name)
+	if !ok {
+		return nil
+	}
+	if err := sig.CheckArgCount(expr.Name, len(expr.Args)); err != nil {
+		return err
+	}
+	if err := v.validateFunctionArgument(expr); err != nil {
+		return err
+	}
+	for i := range expr.Args {
+		if argType := v.exprType(&expr.Args[i]); !sig.Accepts(i, argType) {
+			return fmt.Errorf("%s: argument %d must be %s, got %s", expr.Name, i, sig.Params[i], argType)
+		}
+	}
+	return nil
+}
+
+// higherOrderBuiltins maps builtins that call a function value to the
+// parameter count of their function argument.
+var higherOrderBuiltins = map[string]int{
+	"collections.map":    1,
+	"collections.filter": 1,
+	"array.map":          1,
+	"array.filter":       1,
+	"array.reduce":       2,
+	"array.sort":         2,
+}
+
+// validateFunctionArgument checks that the second argument of a builtin
+// taking a function value is a callable of the right arity. The argument
+// count is checked by validateBuiltinArgs.
+func (v *Validator) validateFunctionArgument(expr *ast.Expression) error {
+	want, ok := higherOrderBuiltins[expr.Name]
+	if !ok || len(expr.Args) < 2 {
+		return nil
+	}
+	if argType := v.exprType(&expr.Args[1]); argType != "" && argType != ast.TypeFunc {
+		return fmt.Errorf("%s: second argument must be a function, got %s", expr.Name, argType)
+	}
+	if arity, ok := v.exprArity(&expr.Args[1]); ok && arity != want {
+		return fmt.Errorf("%s: function argument must take %d parameters, takes %d", expr.Name, want, arity)
+	}
+	return nil
+}
+
+func isValidType(t string, typeNames map[string]bool) bool {
+	if strings.ContainsAny(t, "<>,") {
+		return isValidContainerType(t, typeNames)
+	}
+	switch t {
+	case ast.TypeInt, ast.TypeFloat, ast.TypeString, ast.TypeBool,
+		ast.TypeArray, ast.TypeMap, ast.TypeVoid, ast.TypeFunc, ast.TypeDecimal:
+		return true
+	default:
+		// Check if it's a custom type
+		if typeNames != nil && typeNames[t] {
+			return true
+		}
+		// For backward compatibility, accept any non-empty string as a type
+		return t != ""
+	}
+}
+
+// isValidContainerType reports whether t is a well-formed array<T> or
+// map<K,V>. Map keys are ints or strings.
+func isValidContainerType(t string, typeNames map[string]bool) bool {
+	base, args, err := ast.ParseType(t)
+	if err != nil {
+		return false
+	}
+	switch {
+	case base == ast.TypeArray && len(args) == 1:
+	case base == ast.TypeMap && len(args) == 2:
+		if !isMapKeyType(args[0]) {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, arg := range args {
+		if arg == ast.TypeVoid || !isValidType(arg, typeNames) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidBinaryOp(op string) bool {
+	switch op {
+	case ast.OpAdd, ast.OpSub, ast.OpMul, ast.OpDiv, ast.OpMod,
 		ast.OpEq, ast.OpNe, ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe,
 		ast.OpAnd, ast.OpOr:
 		return true
@@ -552,6 +1741,88 @@ func isValidBinaryOp(op string) bool {
 	}
 }
 
+// checkBinaryTypes reports a binary operator applied to operand types it
+// does not support. Operands whose type is not known statically are accepted.
+func checkBinaryTypes(op, left, right string) error {
+	if !isKnownType(left) || !isKnownType(right) {
+		return nil
+	}
+
+	var ok bool
+	switch op {
+	case ast.OpAdd:
+		ok = isNumericType(left) && isNumericType(right) || isDecimalOperands(left, right) ||
+			left == ast.TypeString && right == ast.TypeString
+	case ast.OpSub, ast.OpMul, ast.OpDiv:
+		ok = isNumericType(left) && isNumericType(right) || isDecimalOperands(left, right)
+	case ast.OpMod:
+		ok = left == ast.TypeInt && right == ast.TypeInt
+	case ast.OpEq, ast.OpNe:
+		ok = ast.BaseType(left) == ast.BaseType(right) || isNumericType(left) && isNumericType(right) || isDecimalOperands(left, right)
+	case ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe:
+		ok = isNumericType(left) && isNumericType(right) || isDecimalOperands(left, right) ||
+			left == ast.TypeString && right == ast.TypeString
+	case ast.OpAnd, ast.OpOr:
+		ok = left == ast.TypeBool && right == ast.TypeBool
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("operator '%s' cannot be applied to %s and %s", op, left, right)
+	}
+	return nil
+}
+
+// binaryResultType returns the type a binary operator produces for the given
+// operand types, or "" if it is not known. Arithmetic on an int and a float
+// promotes to float.
+func binaryResultType(op, left, right string) string {
+	switch op {
+	case ast.OpEq, ast.OpNe, ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe, ast.OpAnd, ast.OpOr:
+		return ast.TypeBool
+	case ast.OpAdd:
+		if left == ast.TypeString && right == ast.TypeString {
+			return ast.TypeString
+		}
+		fallthrough
+	case ast.OpSub, ast.OpMul, ast.OpDiv, ast.OpMod:
+		if op != ast.OpMod && isDecimalOperands(left, right) {
+			return ast.TypeDecimal
+		}
+		if !isNumericType(left) || !isNumericType(right) {
+			return ""
+		}
+		if left == ast.TypeFloat || right == ast.TypeFloat {
+			return ast.TypeFloat
+		}
+		return ast.TypeInt
+	}
+	return ""
+}
+
+// isKnownType reports whether a type name inferred for an expression is
+// specific enough to check operators against.
+func isKnownType(typ string) bool {
+	return typ != "" && typ != builtins.TypeAny
+}
+
+// isMapKeyType reports whether values of the type can be map keys.
+func isMapKeyType(typ string) bool {
+	return typ == ast.TypeInt || typ == ast.TypeBool || typ == ast.TypeString
+}
+
+// isNumericType reports whether values of the type support arithmetic.
+func isNumericType(typ string) bool {
+	return typ == ast.TypeInt || typ == ast.TypeFloat
+}
+
+// isDecimalOperands reports whether a decimal is combined with another
+// decimal or an int. Decimals never mix with floats.
+func isDecimalOperands(left, right string) bool {
+	return left == ast.TypeDecimal && (right == ast.TypeDecimal || right == ast.TypeInt) ||
+		right == ast.TypeDecimal && left == ast.TypeInt
+}
+
 func isValidUnaryOp(op string) bool {
 	switch op {
 	case ast.OpNot, ast.OpNeg:
@@ -561,6 +1832,292 @@ func isValidUnaryOp(op string) bool {
 	}
 }
 
+// matchEnum determines the enum type a match statement is over: the static
+// type of the discriminant when known, otherwise the only enum declaring every case.
+func (v *Validator) matchEnum(stmt *ast.Statement) (*ast.TypeDefinition, error) {
+	// Plain enum values may also be matched as strings
+	if valueType := v.exprType(stmt.Value); valueType != "" && valueType != ast.TypeString {
+		enumDef, ok := v.enums[valueType]
+		if !ok {
+			return nil, fmt.Errorf("match value must be an enum, got %s", valueType)
+		}
+		return enumDef, nil
+	}
+
+	candidates := make(map[string]*ast.TypeDefinition)
+	for _, enumDef := range v.enums {
+		declaresAll := true
+		for _, matchCase := range stmt.Cases {
+			if _, ok := findVariant(enumDef, matchCase.Variant); !ok {
+				declaresAll = false
+				break
+			}
+		}
+		if declaresAll {
+			// Imported enums are registered under two names
+			candidates[enumDef.Name] = enumDef
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("match cases do not belong to any enum type")
+	case 1:
+		for _, enumDef := range candidates {
+			return enumDef, nil
+		}
+	}
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("ambiguous match: cases belong to enums %s", strings.Join(names, ", "))
+}
+
+// validateMatchCase validates one match arm. Bindings are checked against the
+// variant's payload fields unless the variant is nil (an impossible case).
+func (v *Validator) validateMatchCase(matchCase *ast.MatchCase, variant *ast.EnumVariant, scope map[string]bool, typeNames map[string]bool) error {
+	errs := v.newErrorList()
+	caseScope := copyScope(scope)
+	fields := make([]string, 0, len(matchCase.Bindings))
+	for field := range matchCase.Bindings {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if variant != nil {
+			if _, ok := variantFieldType(*variant, field); !ok {
+				return fmt.Errorf("variant %s has no field %s", variant.Name, field)
+			}
+		}
+		varName := matchCase.Bindings[field]
+		if !isValidIdentifier(varName) {
+			return fmt.Errorf("invalid binding name '%s' for field %s", varName, field)
+		}
+		caseScope[varName] = true
+	}
+
+	for i, s := range matchCase.Body {
+		if errs.add(v.validateStatement(&s, caseScope, typeNames), "statement %d", i) {
+			return errs.err()
+		}
+	}
+	return errs.err()
+}
+
+// validateDestructure checks that a destructure statement's source can be
+// destructured as it asks, and adds the variables it binds to scope. Array
+// literals must have an element for every target, and struct types and map
+// literals must have every bound field.
+func (v *Validator) validateDestructure(stmt *ast.Statement, scope map[string]bool) error {
+	sourceType := v.resolveType(v.exprType(stmt.Value))
+	bound := make(map[string]bool)
+	bind := func(name, typ string) error {
+		if !isValidIdentifier(name) {
+			return fmt.Errorf("invalid destructure target '%s'", name)
+		}
+		if bound[name] {
+			return fmt.Errorf("variable '%s' is bound more than once", name)
+		}
+		bound[name] = true
+		scope[name] = true
+		if typ != "" {
+			v.localTypes[name] = typ
+		} else {
+			delete(v.localTypes, name)
+		}
+		delete(v.localArity, name)
+		return nil
+	}
+
+	if len(stmt.Targets) > 0 {
+		if sourceType != "" && ast.BaseType(sourceType) != ast.TypeArray {
+			return fmt.Errorf("cannot destructure %s as an array", sourceType)
+		}
+		var elements []ast.Expression
+		if stmt.Value.Type == ast.ExprArrayLit {
+			elements = stmt.Value.Elements
+			if len(elements) < len(stmt.Targets) {
+				return fmt.Errorf("array literal has %d elements, cannot bind %d targets", len(elements), len(stmt.Targets))
+			}
+		}
+		for idx, name := range stmt.Targets {
+			if name == "_" {
+				continue
+			}
+			elemType := v.resolveType(ast.ElementType(sourceType))
+			if elements != nil {
+				elemType = v.exprType(&elements[idx])
+			}
+			if err := bind(name, elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	def := v.lookupType(sourceType)
+	isStruct := def != nil && def.Definition.Kind == ast.TypeKindStruct
+	if sourceType != "" && ast.BaseType(sourceType) != ast.TypeMap && !isStruct {
+		return fmt.Errorf("cannot destructure %s as a map", sourceType)
+	}
+	literalKeys := mapLiteralKeys(stmt.Value)
+	for _, field := range stmt.BoundFields() {
+		fieldType := v.resolveType(ast.ElementType(sourceType))
+		if isStruct {
+			f, ok := v.structField(sourceType, field)
+			if !ok {
+				return fmt.Errorf("struct %s has no field %s", sourceType, field)
+			}
+			fieldType = v.resolveType(f.Type)
+		} else if literalKeys != nil && !literalKeys[field] {
+			return fmt.Errorf("map literal has no field %s", field)
+		}
+		if err := bind(stmt.Bindings[field], fieldType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapLiteralKeys returns the keys of a map literal whose keys are all string
+// literals, or nil for any other expression.
+func mapLiteralKeys(expr *ast.Expression) map[string]bool {
+	if expr.Type != ast.ExprMapLit {
+		return nil
+	}
+	keys := make(map[string]bool, len(expr.Pairs))
+	for _, pair := range expr.Pairs {
+		key, ok := pair.Key.Value.(string)
+		if pair.Key.Type != ast.ExprLiteral || !ok {
+			return nil
+		}
+		keys[key] = true
+	}
+	return keys
+}
+
+// findVariant looks up a variant of an enum type by name.
+func findVariant(enumDef *ast.TypeDefinition, name string) (ast.EnumVariant, bool) {
+	for _, variant := range enumDef.Definition.EnumVariants() {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return ast.EnumVariant{}, false
+}
+
+// variantFieldType returns the declared type of a variant payload field.
+func variantFieldType(variant ast.EnumVariant, name string) (string, bool) {
+	for _, field := range variant.Fields {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+	return "", false
+}
+
+// isAssignableType reports whether a value of static type valueType may be
+// stored where declaredType is expected. Integer literals widen to float.
+func isAssignableType(valueType, declaredType string) bool {
+	return valueType == declaredType || (valueType == ast.TypeInt && declaredType == ast.TypeFloat) ||
+		isCompatibleContainer(valueType, declaredType)
+}
+
+// isCompatibleContainer reports whether two array or map types can hold the
+// same values. A plain array or map has unknown element types and is
+// compatible with any parameterized one; otherwise element types must match
+// exactly, since elements are not converted.
+func isCompatibleContainer(a, b string) bool {
+	baseA, argsA, errA := ast.ParseType(a)
+	baseB, argsB, errB := ast.ParseType(b)
+	if errA != nil || errB != nil || baseA != baseB || baseA != ast.TypeArray && baseA != ast.TypeMap {
+		return false
+	}
+	if len(argsA) == 0 || len(argsB) == 0 {
+		return true
+	}
+	if len(argsA) != len(argsB) {
+		return false
+	}
+	for i := range argsA {
+		if argsA[i] != argsB[i] && !isCompatibleContainer(argsA[i], argsB[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCastableType reports whether values can be converted to and from the given type.
+func isCastableType(t string) bool {
+	switch t {
+	case ast.TypeInt, ast.TypeFloat, ast.TypeBool, ast.TypeString:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkConstant reports a constant expression that always fails to
+// evaluate: a division by zero, or a cast of a float outside the int range.
+// Integer arithmetic that overflows is not an error, as the interpreter
+// promotes the result to a bigint.
+func checkConstant(expr *ast.Expression) error {
+	_, err := consteval.EvalConst(expr)
+	if errors.Is(err, consteval.ErrDivisionByZero) ||
+		(expr.Type == ast.ExprCast && errors.Is(err, consteval.ErrOverflow)) {
+		return fmt.Errorf("constant expression: %v", err)
+	}
+	return nil
+}
+
+// staticExprType returns the type of an expression when it can be determined
+// without type inference, or an empty string otherwise.
+func staticExprType(expr *ast.Expression) string {
+	switch expr.Type {
+	case ast.ExprLiteral:
+		if expr.To == ast.TypeFloat || expr.To == ast.TypeDecimal {
+			return expr.To
+		}
+		switch v := expr.LiteralValue().(type) {
+		case string:
+			return ast.TypeString
+		case bool:
+			return ast.TypeBool
+		case float64:
+			if float64(int64(v)) == v {
+				return ast.TypeInt
+			}
+			return ast.TypeFloat
+		case float32:
+			return ast.TypeFloat
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, *big.Int:
+			return ast.TypeInt
+		}
+	case ast.ExprArrayLit:
+		return ast.TypeArray
+	case ast.ExprMapLit:
+		return ast.TypeMap
+	case ast.ExprBinary:
+		switch expr.Op {
+		case ast.OpEq, ast.OpNe, ast.OpLt, ast.OpLe, ast.OpGt, ast.OpGe, ast.OpAnd, ast.OpOr:
+			return ast.TypeBool
+		}
+	case ast.ExprUnary:
+		if expr.Op == ast.OpNot {
+			return ast.TypeBool
+		}
+	case ast.ExprCast:
+		return expr.To
+	case ast.ExprVariant:
+		return expr.Enum
+	case ast.ExprLambda, ast.ExprFuncRef:
+		return ast.TypeFunc
+	}
+	return ""
+}
+
 func copyScope(scope map[string]bool) map[string]bool {
 	newScope := make(map[string]bool)
 	for k, v := range scope {
@@ -582,6 +2139,7 @@ func (v *Validator) validateBuiltinName(name string) error {
 	// Validate known builtin namespaces
 	knownNamespaces := map[string]bool{
 		"io":          true,
+		"os":          true,
 		"math":        true,
 		"string":      true,
 		"array":       true,
@@ -589,9 +2147,14 @@ func (v *Validator) validateBuiltinName(name string) error {
 		"collections": true,
 		"type":        true,
 		"async":       true,
+		"decimal":     true,
+		"channel":     true,
+		"sync":        true,
+		"runtime":     true,
+		"log":         true,
 	}
 	if !knownNamespaces[parts[0]] {
-		return fmt.Errorf("unknown builtin namespace '%s', expected one of: io, math, string, array, map, collections, type, async", parts[0])
+		return fmt.Errorf("unknown builtin namespace '%s', expected one of: io, os, math, string, array, map, collections, type, async, decimal, channel, sync, runtime, log", parts[0])
 	}
 	return nil
 }
@@ -627,7 +2190,7 @@ func (v *Validator) validateNumericLiteral(value interface{}) error {
 		return fmt.Errorf("numeric literal cannot be null")
 	}
 	switch value.(type) {
-	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, *big.Int:
 		return nil
 	case float32, float64:
 		return nil
@@ -636,6 +2199,33 @@ func (v *Validator) validateNumericLiteral(value interface{}) error {
 	}
 }
 
+// validateTypedLiteral validates a literal with an explicit type: a float
+// given as a number or as one of the special float spellings, or a decimal
+// given as a string.
+func (v *Validator) validateTypedLiteral(expr *ast.Expression) error {
+	if expr.To == ast.TypeDecimal {
+		s, ok := expr.Value.(string)
+		if !ok {
+			return fmt.Errorf("decimal literal must be a string, got %T", expr.Value)
+		}
+		if _, err := runtime.ParseDecimal(s); err != nil {
+			return fmt.Errorf("decimal literal: %v", err)
+		}
+		return nil
+	}
+	if expr.To != ast.TypeFloat {
+		return fmt.Errorf("literal type must be '%s' or '%s', got '%s'", ast.TypeFloat, ast.TypeDecimal, expr.To)
+	}
+	switch value := expr.LiteralValue().(type) {
+	case float32, float64:
+		return nil
+	case string:
+		return fmt.Errorf("float literal must be a number, '%s', '%s' or '%s', got '%s'", ast.FloatNaN, ast.FloatInfinity, ast.FloatNegInfinity, value)
+	default:
+		return fmt.Errorf("float literal must be a number, got %T", value)
+	}
+}
+
 // validateBooleanLiteral validates boolean literal values.
 func (v *Validator) validateBooleanLiteral(value interface{}) error {
 	if value == nil {
@@ -647,13 +2237,27 @@ func (v *Validator) validateBooleanLiteral(value interface{}) error {
 	return nil
 }
 
-// ValidateJSON validates ALaS JSON input.
+// ValidateJSON validates ALaS JSON input. Errors carry the line and column
+// of the node that failed.
 func ValidateJSON(input []byte) error {
-	var module ast.Module
-	if err := json.Unmarshal(input, &module); err != nil {
+	module, err := ast.ParseModule(input, "")
+	if err != nil {
 		return fmt.Errorf("invalid JSON: %v", err)
 	}
 
 	validator := New()
-	return validator.ValidateModule(&module)
+	return validator.ValidateModule(module)
+}
+
+// ValidateJSONWithOptions validates ALaS JSON input, running the optional
+// checks selected by opts. Warnings are returned even when validation fails.
+func ValidateJSONWithOptions(input []byte, opts Options) ([]string, error) {
+	module, err := ast.ParseModule(input, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	validator := NewWithOptions(opts)
+	err = validator.ValidateModule(module)
+	return validator.Warnings(), err
 }
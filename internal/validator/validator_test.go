@@ -2,6 +2,9 @@ package validator
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"strings"
 	"testing"
 
@@ -106,7 +109,7 @@ func TestValidateModule(t *testing.T) {
 			module: ast.Module{
 				Type:    "module",
 				Name:    "test",
-				Imports: []string{""},
+				Imports: []ast.Import{{Module: ""}},
 				Functions: []ast.Function{
 					{Type: "function", Name: "main", Body: []ast.Statement{}},
 				},
@@ -119,7 +122,7 @@ func TestValidateModule(t *testing.T) {
 			module: ast.Module{
 				Type:    "module",
 				Name:    "test",
-				Imports: []string{"test"},
+				Imports: []ast.Import{{Module: "test"}},
 				Functions: []ast.Function{
 					{Type: "function", Name: "main", Body: []ast.Statement{}},
 				},
@@ -918,6 +921,27 @@ func TestValidateJSON(t *testing.T) {
 			}`,
 			wantErr: false,
 		},
+		{
+			name: "comments are ignored",
+			json: `{
+				"type": "module",
+				"name": "test",
+				"comment": "module note",
+				"functions": [{
+					"type": "function",
+					"name": "main",
+					"comment": "function note",
+					"params": [],
+					"returns": "int",
+					"body": [{
+						"type": "return",
+						"comment": "statement note",
+						"value": {"type": "literal", "value": 42}
+					}]
+				}]
+			}`,
+			wantErr: false,
+		},
 		{
 			name:    "invalid JSON",
 			json:    `{"type": "module", invalid json`,
@@ -1026,7 +1050,7 @@ func TestComplexValidation(t *testing.T) {
 	module := ast.Module{
 		Type:    "module",
 		Name:    "complex",
-		Imports: []string{"std.io", "std.math"},
+		Imports: []ast.Import{{Module: "std.io"}, {Module: "std.math"}},
 		Exports: []string{"fibonacci"},
 		Functions: []ast.Function{
 			{
@@ -1619,7 +1643,7 @@ func TestEnhancedValidation(t *testing.T) {
 			module: ast.Module{
 				Type:    "module",
 				Name:    "test",
-				Imports: []string{"123invalid"},
+				Imports: []ast.Import{{Module: "123invalid"}},
 				Functions: []ast.Function{{
 					Type: "function",
 					Name: "main",
@@ -1634,7 +1658,7 @@ func TestEnhancedValidation(t *testing.T) {
 			module: ast.Module{
 				Type:    "module",
 				Name:    "test",
-				Imports: []string{"std_io", "std_io"},
+				Imports: []ast.Import{{Module: "std_io"}, {Module: "std_io"}},
 				Functions: []ast.Function{{
 					Type: "function",
 					Name: "main",
@@ -1644,6 +1668,79 @@ func TestEnhancedValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "duplicate import 'std_io'",
 		},
+		{
+			name: "type export of unknown type",
+			module: ast.Module{
+				Type:        "module",
+				Name:        "test",
+				TypeExports: []string{"Missing"},
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Body: []ast.Statement{},
+				}},
+			},
+			wantErr: true,
+			errMsg:  "exported type 'Missing' not found in module",
+		},
+		{
+			name: "aliased import",
+			module: ast.Module{
+				Type:    "module",
+				Name:    "test",
+				Imports: []ast.Import{{Module: "geometry.shapes", Alias: "shapes"}, {Module: "std_io"}},
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Body: []ast.Statement{},
+				}},
+			},
+		},
+		{
+			name: "invalid import alias",
+			module: ast.Module{
+				Type:    "module",
+				Name:    "test",
+				Imports: []ast.Import{{Module: "geometry.shapes", Alias: "geometry.shapes"}},
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Body: []ast.Statement{},
+				}},
+			},
+			wantErr: true,
+			errMsg:  "import 0: invalid alias 'geometry.shapes'",
+		},
+		{
+			name: "duplicate import alias",
+			module: ast.Module{
+				Type:    "module",
+				Name:    "test",
+				Imports: []ast.Import{{Module: "geometry.shapes", Alias: "shapes"}, {Module: "art.shapes", Alias: "shapes"}},
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Body: []ast.Statement{},
+				}},
+			},
+			wantErr: true,
+			errMsg:  "import 1: duplicate alias 'shapes'",
+		},
+		{
+			name: "import alias shadowing another import",
+			module: ast.Module{
+				Type:    "module",
+				Name:    "test",
+				Imports: []ast.Import{{Module: "shapes"}, {Module: "art.shapes", Alias: "shapes"}},
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Body: []ast.Statement{},
+				}},
+			},
+			wantErr: true,
+			errMsg:  "import 1: duplicate alias 'shapes'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1735,6 +1832,25 @@ func TestBuiltinValidation(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid log builtin",
+			expr: ast.Expression{
+				Type: ast.ExprBuiltin,
+				Name: "log.warn",
+				Args: []ast.Expression{{Type: ast.ExprLiteral, Value: "slow"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "log fields not a map",
+			expr: ast.Expression{
+				Type: ast.ExprBuiltin,
+				Name: "log.info",
+				Args: []ast.Expression{{Type: ast.ExprLiteral, Value: "login"}, {Type: ast.ExprLiteral, Value: "ann"}},
+			},
+			wantErr: true,
+			errMsg:  "log.info",
+		},
 		{
 			name: "valid string builtin",
 			expr: ast.Expression{
@@ -2001,3 +2117,2638 @@ func TestValidateFieldExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestCastValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    ast.Expression
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid int to float cast",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "float",
+				Operand: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid string literal to int cast",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "int",
+				Operand: &ast.Expression{Type: ast.ExprLiteral, Value: "42"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing target type",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				Operand: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			},
+			wantErr: true,
+			errMsg:  "cast expression must have a target type",
+		},
+		{
+			name: "invalid target type",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "array",
+				Operand: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			},
+			wantErr: true,
+			errMsg:  "invalid cast target type 'array'",
+		},
+		{
+			name:    "missing operand",
+			expr:    ast.Expression{Type: ast.ExprCast, To: "int"},
+			wantErr: true,
+			errMsg:  "cast expression must have an operand",
+		},
+		{
+			name: "undefined operand variable",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "int",
+				Operand: &ast.Expression{Type: ast.ExprVariable, Name: "missing"},
+			},
+			wantErr: true,
+			errMsg:  "undefined variable: missing",
+		},
+		{
+			name: "array literal cannot be cast",
+			expr: ast.Expression{
+				Type:    ast.ExprCast,
+				To:      "string",
+				Operand: &ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{}},
+			},
+			wantErr: true,
+			errMsg:  "cannot cast array to string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			err := v.validateExpression(&tt.expr, map[string]bool{"x": true}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateExpression() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestAssertValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		stmt    ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid comparison condition",
+			stmt: ast.Statement{
+				Type: ast.StmtAssert,
+				Cond: &ast.Expression{
+					Type:  ast.ExprBinary,
+					Op:    ast.OpGe,
+					Left:  &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+					Right: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0},
+				},
+				Message: "x must be non-negative",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid variable condition",
+			stmt: ast.Statement{
+				Type: ast.StmtAssert,
+				Cond: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "missing condition",
+			stmt:    ast.Statement{Type: ast.StmtAssert},
+			wantErr: true,
+			errMsg:  "assert statement must have a condition",
+		},
+		{
+			name: "non-boolean literal condition",
+			stmt: ast.Statement{
+				Type: ast.StmtAssert,
+				Cond: &ast.Expression{Type: ast.ExprLiteral, Value: "yes"},
+			},
+			wantErr: true,
+			errMsg:  "assert condition must be a boolean, got string",
+		},
+		{
+			name: "undefined variable in condition",
+			stmt: ast.Statement{
+				Type: ast.StmtAssert,
+				Cond: &ast.Expression{Type: ast.ExprVariable, Name: "missing"},
+			},
+			wantErr: true,
+			errMsg:  "undefined variable: missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			err := v.validateStatement(&tt.stmt, map[string]bool{"x": true}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStatement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateStatement() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDeferValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		stmt    ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid deferred call",
+			stmt: ast.Statement{
+				Type: ast.StmtDefer,
+				Value: &ast.Expression{
+					Type: ast.ExprBuiltin,
+					Name: "io.print",
+					Args: []ast.Expression{{Type: ast.ExprVariable, Name: "x"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "missing value",
+			stmt:    ast.Statement{Type: ast.StmtDefer},
+			wantErr: true,
+			errMsg:  "defer statement must have a value",
+		},
+		{
+			name: "undefined variable in deferred expression",
+			stmt: ast.Statement{
+				Type: ast.StmtDefer,
+				Value: &ast.Expression{
+					Type: ast.ExprBuiltin,
+					Name: "io.print",
+					Args: []ast.Expression{{Type: ast.ExprVariable, Name: "missing"}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "deferred expression: builtin call argument 0: undefined variable: missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			err := v.validateStatement(&tt.stmt, map[string]bool{"x": true}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStatement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateStatement() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestMethodValidation(t *testing.T) {
+	types := []ast.TypeDefinition{
+		{
+			Name: "Point",
+			Definition: ast.TypeDefinitionDef{
+				Kind:   ast.TypeKindStruct,
+				Fields: []ast.TypeField{{Name: "x", Type: "int"}, {Name: "y", Type: "int"}},
+			},
+		},
+		{
+			Name: "Color",
+			Definition: ast.TypeDefinitionDef{
+				Kind:   ast.TypeKindEnum,
+				Values: []string{"red", "green"},
+			},
+		},
+	}
+	method := func(name string, receiver ast.Parameter, params ...ast.Parameter) ast.Function {
+		return ast.Function{
+			Type:     "function",
+			Name:     name,
+			Receiver: &receiver,
+			Params:   append([]ast.Parameter{}, params...),
+			Returns:  "int",
+			Body: []ast.Statement{
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprField, Object: &ast.Expression{Type: ast.ExprVariable, Name: receiver.Name}, Field: "x"}},
+			},
+		}
+	}
+	mainFn := ast.Function{
+		Type:    "function",
+		Name:    "main",
+		Params:  []ast.Parameter{},
+		Returns: "int",
+		Body: []ast.Statement{
+			{Type: ast.StmtAssign, Target: "p", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{}}},
+			{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type:   ast.ExprMethodCall,
+				Object: &ast.Expression{Type: ast.ExprVariable, Name: "p"},
+				Name:   "getX",
+				Args:   []ast.Expression{},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		functions []ast.Function
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "valid method and call",
+			functions: []ast.Function{method("getX", ast.Parameter{Name: "p", Type: "Point"}), mainFn},
+			wantErr:   false,
+		},
+		{
+			name: "method may share a name with a function",
+			functions: []ast.Function{
+				method("main", ast.Parameter{Name: "p", Type: "Point"}),
+				mainFn,
+			},
+			wantErr: false,
+		},
+		{
+			name:      "unknown receiver type",
+			functions: []ast.Function{method("getX", ast.Parameter{Name: "p", Type: "Missing"}), mainFn},
+			wantErr:   true,
+			errMsg:    "receiver type 'Missing' must be a custom type defined in the module",
+		},
+		{
+			name:      "enum receiver type",
+			functions: []ast.Function{method("getX", ast.Parameter{Name: "p", Type: "Color"}), mainFn},
+			wantErr:   true,
+			errMsg:    "receiver type 'Color' must be a struct type",
+		},
+		{
+			name: "duplicate method on same type",
+			functions: []ast.Function{
+				method("getX", ast.Parameter{Name: "p", Type: "Point"}),
+				method("getX", ast.Parameter{Name: "q", Type: "Point"}),
+				mainFn,
+			},
+			wantErr: true,
+			errMsg:  "duplicate method name: Point.getX",
+		},
+		{
+			name: "parameter shadows receiver",
+			functions: []ast.Function{
+				method("getX", ast.Parameter{Name: "p", Type: "Point"}, ast.Parameter{Name: "p", Type: "int"}),
+				mainFn,
+			},
+			wantErr: true,
+			errMsg:  "duplicate parameter name: p",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "test_module", Types: types, Functions: tt.functions}
+			err := New().ValidateModule(module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestMethodCallValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    ast.Expression
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "missing receiver",
+			expr:    ast.Expression{Type: ast.ExprMethodCall, Name: "area", Args: []ast.Expression{}},
+			wantErr: true,
+			errMsg:  "method call must have a receiver object",
+		},
+		{
+			name:    "missing method name",
+			expr:    ast.Expression{Type: ast.ExprMethodCall, Object: &ast.Expression{Type: ast.ExprVariable, Name: "x"}, Args: []ast.Expression{}},
+			wantErr: true,
+			errMsg:  "method call must have a method name",
+		},
+		{
+			name:    "undefined receiver variable",
+			expr:    ast.Expression{Type: ast.ExprMethodCall, Object: &ast.Expression{Type: ast.ExprVariable, Name: "missing"}, Name: "area", Args: []ast.Expression{}},
+			wantErr: true,
+			errMsg:  "method receiver: undefined variable: missing",
+		},
+		{
+			name: "invalid argument",
+			expr: ast.Expression{
+				Type:   ast.ExprMethodCall,
+				Object: &ast.Expression{Type: ast.ExprVariable, Name: "x"},
+				Name:   "scale",
+				Args:   []ast.Expression{{Type: ast.ExprVariable, Name: "missing"}},
+			},
+			wantErr: true,
+			errMsg:  "argument 0: undefined variable: missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New().validateExpression(&tt.expr, map[string]bool{"x": true}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateExpression() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestEnumVariantValidation(t *testing.T) {
+	shape := ast.TypeDefinition{
+		Name: "Shape",
+		Definition: ast.TypeDefinitionDef{
+			Kind: ast.TypeKindEnum,
+			Variants: []ast.EnumVariant{
+				{Name: "Circle", Fields: []ast.TypeField{{Name: "radius", Type: "float"}}},
+				{Name: "Rect", Fields: []ast.TypeField{{Name: "w", Type: "int"}, {Name: "h", Type: "int"}}},
+				{Name: "Empty"},
+			},
+		},
+	}
+	pair := func(name string, value interface{}) ast.MapPair {
+		return ast.MapPair{
+			Key:   ast.Expression{Type: ast.ExprLiteral, Value: name},
+			Value: ast.Expression{Type: ast.ExprLiteral, Value: value},
+		}
+	}
+	returnZero := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}}}
+	allCases := []ast.MatchCase{
+		{Variant: "Circle", Bindings: map[string]string{"radius": "r"}, Body: returnZero},
+		{Variant: "Rect", Bindings: map[string]string{"w": "w", "h": "h"}, Body: returnZero},
+		{Variant: "Empty", Body: returnZero},
+	}
+
+	tests := []struct {
+		name    string
+		types   []ast.TypeDefinition
+		body    []ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid construction",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Rect",
+				Pairs: []ast.MapPair{pair("w", 1.0), pair("h", 2.0)},
+			}}},
+			wantErr: false,
+		},
+		{
+			name: "int literal widens to float field",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Circle",
+				Pairs: []ast.MapPair{pair("radius", 2.0)},
+			}}},
+			wantErr: false,
+		},
+		{
+			name: "unknown enum",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Color", Variant: "Red",
+			}}},
+			wantErr: true,
+			errMsg:  "unknown enum type: Color",
+		},
+		{
+			name: "unknown variant",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Triangle",
+			}}},
+			wantErr: true,
+			errMsg:  "enum Shape has no variant Triangle",
+		},
+		{
+			name: "unknown payload field",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Circle",
+				Pairs: []ast.MapPair{pair("radius", 1.5), pair("depth", 1.0)},
+			}}},
+			wantErr: true,
+			errMsg:  "variant Shape.Circle has no field depth",
+		},
+		{
+			name: "missing payload field",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Rect",
+				Pairs: []ast.MapPair{pair("w", 1.0)},
+			}}},
+			wantErr: true,
+			errMsg:  "variant Shape.Rect missing field h",
+		},
+		{
+			name: "wrong payload type",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprVariant, Enum: "Shape", Variant: "Rect",
+				Pairs: []ast.MapPair{pair("w", "wide"), pair("h", 1.0)},
+			}}},
+			wantErr: true,
+			errMsg:  "field w: expected int, got string",
+		},
+		{
+			name:    "exhaustive match",
+			body:    []ast.Statement{{Type: ast.StmtMatch, Value: &ast.Expression{Type: ast.ExprVariable, Name: "s"}, Cases: allCases}},
+			wantErr: false,
+		},
+		{
+			name: "non-exhaustive match",
+			body: []ast.Statement{{
+				Type:  ast.StmtMatch,
+				Value: &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+				Cases: allCases[:1],
+			}},
+			wantErr: true,
+			errMsg:  "non-exhaustive match on Shape: missing variants Rect, Empty",
+		},
+		{
+			name: "default makes match exhaustive",
+			body: []ast.Statement{{
+				Type:    ast.StmtMatch,
+				Value:   &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+				Cases:   allCases[:1],
+				Default: returnZero,
+			}},
+			wantErr: false,
+		},
+		{
+			name: "binding unknown payload field",
+			body: []ast.Statement{{
+				Type:  ast.StmtMatch,
+				Value: &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+				Cases: []ast.MatchCase{
+					{Variant: "Circle", Bindings: map[string]string{"diameter": "d"}, Body: returnZero},
+				},
+				Default: returnZero,
+			}},
+			wantErr: true,
+			errMsg:  "variant Circle has no field diameter",
+		},
+		{
+			name: "bound variable in scope",
+			body: []ast.Statement{{
+				Type:  ast.StmtMatch,
+				Value: &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+				Cases: []ast.MatchCase{
+					{Variant: "Circle", Bindings: map[string]string{"radius": "r"}, Body: []ast.Statement{
+						{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "r"}},
+					}},
+				},
+				Default: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "r"}}},
+			}},
+			wantErr: true,
+			errMsg:  "default statement 0: return value: undefined variable: r",
+		},
+		{
+			name: "cases from no enum",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "name", Value: &ast.Expression{Type: ast.ExprLiteral, Value: "Triangle"}},
+				{
+					Type:    ast.StmtMatch,
+					Value:   &ast.Expression{Type: ast.ExprVariable, Name: "name"},
+					Cases:   []ast.MatchCase{{Variant: "Triangle", Body: returnZero}},
+					Default: returnZero,
+				},
+			},
+			wantErr: true,
+			errMsg:  "match cases do not belong to any enum type",
+		},
+		{
+			name: "variant cast to int",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprCast, To: ast.TypeInt, Operand: &ast.Expression{Type: ast.ExprVariant, Enum: "Shape", Variant: "Empty"},
+			}}},
+			wantErr: false,
+		},
+		{
+			name: "enum parameter cast to int",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprCast, To: ast.TypeInt, Operand: &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+			}}},
+			wantErr: false,
+		},
+		{
+			name: "enum cast to float",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprCast, To: ast.TypeFloat, Operand: &ast.Expression{Type: ast.ExprVariable, Name: "s"},
+			}}},
+			wantErr: true,
+			errMsg:  "cannot cast Shape to float",
+		},
+		{
+			name: "enum with both values and variants",
+			types: []ast.TypeDefinition{{
+				Name: "Mixed",
+				Definition: ast.TypeDefinitionDef{
+					Kind:     ast.TypeKindEnum,
+					Values:   []string{"a"},
+					Variants: []ast.EnumVariant{{Name: "b"}},
+				},
+			}},
+			body:    returnZero,
+			wantErr: true,
+			errMsg:  "enum type 'Mixed' cannot define both values and variants",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:  "module",
+				Name:  "test_module",
+				Types: append([]ast.TypeDefinition{shape}, tt.types...),
+				Functions: []ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Params:  []ast.Parameter{{Name: "s", Type: "Shape"}},
+					Returns: "int",
+					Body:    tt.body,
+				}},
+			}
+			err := New().ValidateModule(module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+type stubModuleLoader map[string]*ast.Module
+
+func (l stubModuleLoader) LoadModuleByName(name string) (*ast.Module, error) {
+	if m, ok := l[name]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("module not found: %s", name)
+}
+
+func TestMatchExhaustiveness(t *testing.T) {
+	color := ast.TypeDefinition{
+		Name:       "Color",
+		Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Values: []string{"Red", "Green", "Blue"}},
+	}
+	shape := ast.TypeDefinition{
+		Name: "Shape",
+		Definition: ast.TypeDefinitionDef{
+			Kind:     ast.TypeKindEnum,
+			Variants: []ast.EnumVariant{{Name: "Square", Fields: []ast.TypeField{{Name: "side", Type: "int"}}}, {Name: "Red"}},
+		},
+	}
+	returnZero := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}}}
+	loader := stubModuleLoader{
+		"palette": {
+			Type:        "module",
+			Name:        "palette",
+			Exports:     []string{"pick"},
+			TypeExports: []string{"Color"},
+			Types:       []ast.TypeDefinition{color, shape},
+			Functions: []ast.Function{{
+				Type: "function", Name: "pick", Params: []ast.Parameter{}, Returns: "Color", Body: returnZero,
+			}},
+		},
+	}
+	matchOn := func(value *ast.Expression, cases ...string) ast.Statement {
+		stmt := ast.Statement{Type: ast.StmtMatch, Value: value}
+		for _, name := range cases {
+			stmt.Cases = append(stmt.Cases, ast.MatchCase{Variant: name, Body: returnZero})
+		}
+		return stmt
+	}
+	param := &ast.Expression{Type: ast.ExprVariable, Name: "c"}
+
+	tests := []struct {
+		name      string
+		types     []ast.TypeDefinition
+		imports   []ast.Import
+		paramType string
+		body      []ast.Statement
+		errMsg    string
+		warnings  []string
+	}{
+		{
+			name:      "all values handled",
+			types:     []ast.TypeDefinition{color},
+			paramType: "Color",
+			body:      []ast.Statement{matchOn(param, "Red", "Green", "Blue")},
+		},
+		{
+			name:      "missing values",
+			types:     []ast.TypeDefinition{color},
+			paramType: "Color",
+			body:      []ast.Statement{matchOn(param, "Green")},
+			errMsg:    "non-exhaustive match on Color: missing variants Red, Blue",
+		},
+		{
+			name:      "known type disambiguates shared variant names",
+			types:     []ast.TypeDefinition{color, shape},
+			paramType: "Color",
+			body:      []ast.Statement{matchOn(param, "Red")},
+			errMsg:    "non-exhaustive match on Color: missing variants Green, Blue",
+		},
+		{
+			name:      "type of assigned call result",
+			types:     []ast.TypeDefinition{color, shape},
+			paramType: "Color",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "x", Value: &ast.Expression{Type: ast.ExprCall, Name: "main", Args: []ast.Expression{*param}}},
+				matchOn(&ast.Expression{Type: ast.ExprVariable, Name: "x"}, "Red", "Green", "Blue"),
+			},
+		},
+		{
+			name:      "impossible and duplicate cases warn",
+			types:     []ast.TypeDefinition{color},
+			paramType: "Color",
+			body:      []ast.Statement{matchOn(param, "Red", "Green", "Blue", "Purple", "Red")},
+			warnings: []string{
+				"case 3: Purple is not a variant of Color; case can never match",
+				"case 4: duplicate case for variant Color.Red is unreachable",
+			},
+		},
+		{
+			name:      "non-enum discriminant",
+			types:     []ast.TypeDefinition{color},
+			paramType: "int",
+			body:      []ast.Statement{matchOn(param, "Red")},
+			errMsg:    "match value must be an enum, got int",
+		},
+		{
+			name:      "imported enum",
+			imports:   []ast.Import{{Module: "palette"}},
+			paramType: "palette.Color",
+			body:      []ast.Statement{matchOn(param, "Red", "Blue")},
+			errMsg:    "non-exhaustive match on Color: missing variants Green",
+		},
+		{
+			name:      "imported function result",
+			types:     []ast.TypeDefinition{shape},
+			imports:   []ast.Import{{Module: "palette"}},
+			paramType: "int",
+			body: []ast.Statement{matchOn(
+				&ast.Expression{Type: ast.ExprModuleCall, Module: "palette", Name: "pick", Args: []ast.Expression{}},
+				"Red", "Green", "Blue", "Square",
+			)},
+			warnings: []string{"case 3: Square is not a variant of Color; case can never match"},
+		},
+		{
+			name:      "unexported imported enum",
+			imports:   []ast.Import{{Module: "palette"}},
+			paramType: "palette.Shape",
+			body:      []ast.Statement{matchOn(param, "Square", "Red")},
+			errMsg:    "parameter c: type 'Shape' is not exported from module 'palette'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:    "module",
+				Name:    "test_module",
+				Imports: tt.imports,
+				Types:   tt.types,
+				Functions: []ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Params:  []ast.Parameter{{Name: "c", Type: tt.paramType}},
+					Returns: tt.paramType,
+					Body:    append(tt.body, returnZero...),
+				}},
+			}
+			v := New()
+			v.SetModuleLoader(loader)
+			err := v.ValidateModule(module)
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+			if len(v.Warnings()) != len(tt.warnings) {
+				t.Fatalf("Warnings() = %v, want %v", v.Warnings(), tt.warnings)
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(v.Warnings()[i], want) {
+					t.Errorf("Warnings()[%d] = %q, want %q", i, v.Warnings()[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCallArityValidation(t *testing.T) {
+	returnZero := []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}}}
+	intParams := func(names ...string) []ast.Parameter {
+		params := make([]ast.Parameter, len(names))
+		for i, name := range names {
+			params[i] = ast.Parameter{Name: name, Type: ast.TypeInt}
+		}
+		return params
+	}
+	args := func(n int) []ast.Expression {
+		list := make([]ast.Expression, n)
+		for i := range list {
+			list[i] = ast.Expression{Type: ast.ExprLiteral, Value: float64(i)}
+		}
+		return list
+	}
+	loader := stubModuleLoader{
+		"mathx": {
+			Type:    "module",
+			Name:    "mathx",
+			Exports: []string{"add"},
+			Functions: []ast.Function{
+				{Type: "function", Name: "add", Params: intParams("a", "b"), Returns: ast.TypeInt, Body: returnZero},
+				{Type: "function", Name: "internal", Params: intParams(), Returns: ast.TypeInt, Body: returnZero},
+			},
+		},
+	}
+	call := func(expr ast.Expression) []ast.Statement {
+		return []ast.Statement{{Type: ast.StmtExpr, Value: &expr}}
+	}
+
+	tests := []struct {
+		name   string
+		body   []ast.Statement
+		errMsg string
+	}{
+		{
+			name: "local call with matching arity",
+			body: call(ast.Expression{Type: ast.ExprCall, Name: "helper", Args: args(1)}),
+		},
+		{
+			name:   "local call with too few arguments",
+			body:   call(ast.Expression{Type: ast.ExprCall, Name: "helper", Args: args(0)}),
+			errMsg: "function 'helper' expects 1 arguments, got 0",
+		},
+		{
+			name:   "local call with too many arguments",
+			body:   call(ast.Expression{Type: ast.ExprCall, Name: "helper", Args: args(3)}),
+			errMsg: "function 'helper' expects 1 arguments, got 3",
+		},
+		{
+			name: "variable holding a function shadows the named function",
+			body: append([]ast.Statement{{
+				Type:   ast.StmtAssign,
+				Target: "helper",
+				Value: &ast.Expression{
+					Type:    ast.ExprLambda,
+					Params:  intParams("a", "b"),
+					Returns: ast.TypeInt,
+					Body:    returnZero,
+				},
+			}}, call(ast.Expression{Type: ast.ExprCall, Name: "helper", Args: args(2)})...),
+		},
+		{
+			name: "imported call with matching arity",
+			body: call(ast.Expression{Type: ast.ExprModuleCall, Module: "mathx", Name: "add", Args: args(2)}),
+		},
+		{
+			name:   "imported call with wrong arity",
+			body:   call(ast.Expression{Type: ast.ExprModuleCall, Module: "mathx", Name: "add", Args: args(1)}),
+			errMsg: "function 'mathx.add' expects 2 arguments, got 1",
+		},
+		{
+			name:   "imported function that is not exported",
+			body:   call(ast.Expression{Type: ast.ExprModuleCall, Module: "mathx", Name: "internal", Args: args(0)}),
+			errMsg: "function 'internal' is not exported from module 'mathx'",
+		},
+		{
+			name: "call through an import alias",
+			body: call(ast.Expression{Type: ast.ExprModuleCall, Module: "mx", Name: "add", Args: args(2)}),
+		},
+		{
+			name:   "call through an import alias with wrong arity",
+			body:   call(ast.Expression{Type: ast.ExprModuleCall, Module: "mx", Name: "add", Args: args(1)}),
+			errMsg: "function 'mx.add' expects 2 arguments, got 1",
+		},
+		{
+			name: "module that cannot be loaded is checked at run time",
+			body: call(ast.Expression{Type: ast.ExprModuleCall, Module: "elsewhere", Name: "f", Args: args(5)}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:    "module",
+				Name:    "test_module",
+				Imports: []ast.Import{{Module: "mathx"}, {Module: "mathx", Alias: "mx"}},
+				Functions: []ast.Function{
+					{Type: "function", Name: "main", Params: intParams(), Returns: ast.TypeInt, Body: append(tt.body, returnZero...)},
+					{Type: "function", Name: "helper", Params: intParams("x"), Returns: ast.TypeInt, Body: returnZero},
+				},
+			}
+			v := New()
+			v.SetModuleLoader(loader)
+			err := v.ValidateModule(module)
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestModuleCallArgumentTypes(t *testing.T) {
+	lit := func(value interface{}) ast.Expression {
+		return ast.Expression{Type: ast.ExprLiteral, Value: value}
+	}
+	loader := stubModuleLoader{
+		"geo": {
+			Type:    "module",
+			Name:    "geo",
+			Exports: []string{"scale", "show", "area"},
+			Types: []ast.TypeDefinition{
+				{Name: "Meters", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: ast.TypeFloat}},
+				{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{{Name: "x", Type: ast.TypeInt}}}},
+			},
+			Functions: []ast.Function{
+				{Type: "function", Name: "scale", Params: []ast.Parameter{{Name: "label", Type: ast.TypeString}, {Name: "by", Type: "Meters"}}, Returns: ast.TypeVoid},
+				{Type: "function", Name: "show", Params: []ast.Parameter{{Name: "value", Type: "any"}}, Returns: ast.TypeVoid},
+				{Type: "function", Name: "area", Params: []ast.Parameter{{Name: "p", Type: "Point"}}, Returns: ast.TypeInt},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		call   ast.Expression
+		errMsg string
+	}{
+		{
+			name: "matching types",
+			call: ast.Expression{Type: ast.ExprModuleCall, Module: "geo", Name: "scale", Args: []ast.Expression{lit("a"), lit(2.5)}},
+		},
+		{
+			name: "int widens to an aliased float",
+			call: ast.Expression{Type: ast.ExprModuleCall, Module: "geo", Name: "scale", Args: []ast.Expression{lit("a"), lit(2.0)}},
+		},
+		{
+			name:   "mismatched argument",
+			call:   ast.Expression{Type: ast.ExprModuleCall, Module: "geo", Name: "scale", Args: []ast.Expression{lit(1.0), lit(2.5)}},
+			errMsg: "argument 0 to function 'scale' from module 'geo': expected string, got int",
+		},
+		{
+			name: "any accepts every type",
+			call: ast.Expression{Type: ast.ExprModuleCall, Module: "geo", Name: "show", Args: []ast.Expression{lit(true)}},
+		},
+		{
+			name:   "imported struct type",
+			call:   ast.Expression{Type: ast.ExprModuleCall, Module: "geo", Name: "area", Args: []ast.Expression{lit("p")}},
+			errMsg: "argument 0 to function 'area' from module 'geo': expected geo.Point, got string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := tt.call
+			module := &ast.Module{
+				Type:    "module",
+				Name:    "test_module",
+				Imports: []ast.Import{{Module: "geo"}},
+				Functions: []ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Returns: ast.TypeVoid,
+					Body:    []ast.Statement{{Type: ast.StmtExpr, Value: &call}},
+				}},
+			}
+			v := New()
+			v.SetModuleLoader(loader)
+			err := v.ValidateModule(module)
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBinaryOperandTypes(t *testing.T) {
+	lit := func(value interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: value}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	binary := func(op string, left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: op, Left: left, Right: right}
+	}
+
+	tests := []struct {
+		name   string
+		expr   *ast.Expression
+		errMsg string
+	}{
+		{name: "int arithmetic", expr: binary(ast.OpSub, lit(5.0), variable("n"))},
+		{name: "int and float promote", expr: binary(ast.OpMul, variable("n"), variable("f"))},
+		{name: "string concatenation", expr: binary(ast.OpAdd, lit("a"), variable("s"))},
+		{name: "int modulo", expr: binary(ast.OpMod, variable("n"), lit(2.0))},
+		{name: "numeric comparison", expr: binary(ast.OpLt, variable("n"), variable("f"))},
+		{name: "string comparison", expr: binary(ast.OpGe, lit("a"), variable("s"))},
+		{name: "equal types", expr: binary(ast.OpEq, variable("b"), lit(true))},
+		{name: "logical operands", expr: binary(ast.OpAnd, variable("b"), binary(ast.OpGt, variable("n"), lit(0.0)))},
+		{name: "unknown operand type", expr: binary(ast.OpSub, variable("x"), lit(1.0))},
+		{
+			name:   "string minus int",
+			expr:   binary(ast.OpSub, lit("hello"), lit(5.0)),
+			errMsg: "operator '-' cannot be applied to string and int",
+		},
+		{
+			name:   "bool times int",
+			expr:   binary(ast.OpMul, lit(true), lit(3.0)),
+			errMsg: "operator '*' cannot be applied to bool and int",
+		},
+		{
+			name:   "string plus int",
+			expr:   binary(ast.OpAdd, variable("s"), variable("n")),
+			errMsg: "operator '+' cannot be applied to string and int",
+		},
+		{
+			name:   "float modulo",
+			expr:   binary(ast.OpMod, variable("f"), lit(2.0)),
+			errMsg: "operator '%' cannot be applied to float and int",
+		},
+		{
+			name:   "comparing string with int",
+			expr:   binary(ast.OpEq, variable("s"), variable("n")),
+			errMsg: "operator '==' cannot be applied to string and int",
+		},
+		{
+			name:   "ordering bools",
+			expr:   binary(ast.OpLt, variable("b"), lit(false)),
+			errMsg: "operator '<' cannot be applied to bool and bool",
+		},
+		{
+			name:   "logical op on int",
+			expr:   binary(ast.OpOr, variable("b"), variable("n")),
+			errMsg: "operator '||' cannot be applied to bool and int",
+		},
+		{
+			name:   "inferred type of nested expression",
+			expr:   binary(ast.OpAdd, binary(ast.OpMul, variable("n"), variable("f")), lit("s")),
+			errMsg: "operator '+' cannot be applied to float and string",
+		},
+		{name: "constant arithmetic", expr: binary(ast.OpMul, lit(1e9), lit(1e9))},
+		{name: "constant overflow promotes to bigint", expr: binary(ast.OpAdd, lit(int64(math.MaxInt64)), lit(1.0))},
+		{name: "constant negation of min int", expr: &ast.Expression{Type: ast.ExprUnary, Op: ast.OpNeg, Operand: lit(int64(math.MinInt64))}},
+		{
+			name:   "constant cast out of range",
+			expr:   &ast.Expression{Type: ast.ExprCast, To: ast.TypeInt, Operand: lit(1e19)},
+			errMsg: "constant expression: integer overflow: cannot cast 1e+19 to int",
+		},
+		{
+			name:   "constant division by zero",
+			expr:   binary(ast.OpDiv, lit(1.0), binary(ast.OpSub, lit(2.0), lit(2.0))),
+			errMsg: "constant expression: division by zero",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_module",
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Params: []ast.Parameter{
+						{Name: "n", Type: ast.TypeInt},
+						{Name: "f", Type: ast.TypeFloat},
+						{Name: "s", Type: ast.TypeString},
+						{Name: "b", Type: ast.TypeBool},
+					},
+					Returns: ast.TypeVoid,
+					Body: []ast.Statement{
+						{Type: ast.StmtAssign, Target: "x", Value: &ast.Expression{Type: ast.ExprBuiltin, Name: "io.readLine", Args: []ast.Expression{}}},
+						{Type: ast.StmtExpr, Value: tt.expr},
+						{Type: ast.StmtReturn},
+					},
+				}},
+			}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestSpecialFloatLiterals(t *testing.T) {
+	tests := []struct {
+		name    string
+		literal string
+		errMsg  string
+	}{
+		{name: "NaN", literal: `{"type": "literal", "value": "NaN", "to": "float"}`},
+		{name: "Infinity", literal: `{"type": "literal", "value": "Infinity", "to": "float"}`},
+		{name: "-Infinity", literal: `{"type": "literal", "value": "-Infinity", "to": "float"}`},
+		{name: "number", literal: `{"type": "literal", "value": 2, "to": "float"}`},
+		{
+			name:    "unknown spelling",
+			literal: `{"type": "literal", "value": "nan", "to": "float"}`,
+			errMsg:  "float literal must be a number, 'NaN', 'Infinity' or '-Infinity', got 'nan'",
+		},
+		{
+			name:    "non-float type",
+			literal: `{"type": "literal", "value": 2, "to": "int"}`,
+			errMsg:  "literal type must be 'float' or 'decimal', got 'int'",
+		},
+		{
+			name:    "float operand type",
+			literal: `{"type": "binary", "op": "+", "left": {"type": "literal", "value": "NaN", "to": "float"}, "right": {"type": "literal", "value": true}}`,
+			errMsg:  "operator '+' cannot be applied to float and bool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [], "returns": "void",
+				"body": [{"type": "expr", "value": ` + tt.literal + `}, {"type": "return"}]}]}`
+			err := ValidateJSON([]byte(input))
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateJSON() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateJSON() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDecimalLiterals(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		errMsg string
+	}{
+		{name: "decimal", expr: `{"type": "literal", "value": "0.1", "to": "decimal"}`},
+		{name: "decimal plus int", expr: `{"type": "binary", "op": "+", "left": {"type": "literal", "value": "0.1", "to": "decimal"}, "right": {"type": "literal", "value": 1}}`},
+		{name: "decimal builtin", expr: `{"type": "builtin", "name": "decimal.div", "args": [{"type": "literal", "value": "10", "to": "decimal"}, {"type": "literal", "value": 3}, {"type": "literal", "value": 2}, {"type": "literal", "value": "halfUp"}]}`},
+		{
+			name:   "number value",
+			expr:   `{"type": "literal", "value": 0.1, "to": "decimal"}`,
+			errMsg: "decimal literal must be a string, got float64",
+		},
+		{
+			name:   "fraction",
+			expr:   `{"type": "literal", "value": "1/3", "to": "decimal"}`,
+			errMsg: `decimal literal: invalid decimal "1/3"`,
+		},
+		{
+			name:   "mixed with float",
+			expr:   `{"type": "binary", "op": "*", "left": {"type": "literal", "value": "0.1", "to": "decimal"}, "right": {"type": "literal", "value": 0.5}}`,
+			errMsg: "operator '*' cannot be applied to decimal and float",
+		},
+		{
+			name:   "modulo",
+			expr:   `{"type": "binary", "op": "%", "left": {"type": "literal", "value": "5", "to": "decimal"}, "right": {"type": "literal", "value": 2}}`,
+			errMsg: "operator '%' cannot be applied to decimal and int",
+		},
+		{
+			name:   "float builtin argument",
+			expr:   `{"type": "builtin", "name": "decimal.add", "args": [{"type": "literal", "value": "1", "to": "decimal"}, {"type": "literal", "value": 0.5}]}`,
+			errMsg: "decimal.add",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [{"name": "price", "type": "decimal"}], "returns": "decimal",
+				"body": [{"type": "expr", "value": ` + tt.expr + `}, {"type": "return", "value": {"type": "variable", "name": "price"}}]}]}`
+			err := ValidateJSON([]byte(input))
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateJSON() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateJSON() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidationOptions(t *testing.T) {
+	lit := func(value interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: value}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	ifElse := func(then, els []ast.Statement) ast.Statement {
+		return ast.Statement{Type: ast.StmtIf, Cond: variable("flag"), Then: then, Else: els}
+	}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		opts    Options
+		errMsg  string
+		warning string
+	}{
+		{
+			name: "defaults ignore optional checks",
+			body: []ast.Statement{assign("unused", lit(1.0)), ret(variable("n")), ret(lit(0.0))},
+			opts: DefaultOptions(),
+		},
+		{
+			name:    "unreachable code as warning",
+			body:    []ast.Statement{ret(variable("n")), assign("x", lit(1.0))},
+			opts:    Options{UnreachableCode: SeverityWarning},
+			warning: "function 'main': assign statement after a return is unreachable",
+		},
+		{
+			name:   "unreachable code after if returning on both branches",
+			body:   []ast.Statement{ifElse([]ast.Statement{ret(lit(1.0))}, []ast.Statement{ret(lit(2.0))}), ret(lit(0.0))},
+			opts:   Options{UnreachableCode: SeverityError},
+			errMsg: "function 'main': return statement after a return is unreachable",
+		},
+		{
+			name: "if returning on one branch is not terminal",
+			body: []ast.Statement{ifElse([]ast.Statement{ret(lit(1.0))}, nil), ret(lit(0.0))},
+			opts: StrictOptions(),
+		},
+		{
+			name:    "unused variable as warning",
+			body:    []ast.Statement{assign("unused", lit(1.0)), ret(variable("n"))},
+			opts:    Options{UnusedVariables: SeverityWarning},
+			warning: "function 'main': variable 'unused' is assigned but never used",
+		},
+		{
+			name: "underscore variables may be unused",
+			body: []ast.Statement{assign("_ignored", lit(1.0)), ret(variable("n"))},
+			opts: StrictOptions(),
+		},
+		{
+			name: "variable read in lambda is used",
+			body: []ast.Statement{
+				assign("k", lit(2.0)),
+				assign("f", &ast.Expression{
+					Type: ast.ExprLambda, Params: []ast.Parameter{{Name: "x", Type: ast.TypeInt}}, Returns: ast.TypeInt,
+					Body: []ast.Statement{ret(&ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: variable("x"), Right: variable("k")})},
+				}),
+				ret(&ast.Expression{Type: ast.ExprCall, Name: "f", Args: []ast.Expression{*variable("n")}}),
+			},
+			opts: StrictOptions(),
+		},
+		{
+			name:   "implicit numeric conversion",
+			body:   []ast.Statement{assign("x", &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: variable("n"), Right: variable("f")}), ret(variable("n"))},
+			opts:   Options{NumericConversion: SeverityError},
+			errMsg: "operator '*' implicitly converts int and float operands; add a cast",
+		},
+		{
+			name: "numeric literals do not convert",
+			body: []ast.Statement{ret(&ast.Expression{
+				Type: ast.ExprCast, To: ast.TypeInt,
+				Operand: &ast.Expression{Type: ast.ExprBinary, Op: ast.OpMul, Left: variable("f"), Right: lit(2.0)},
+			})},
+			opts: StrictOptions(),
+		},
+		{
+			name:   "missing return",
+			body:   []ast.Statement{ifElse([]ast.Statement{ret(lit(1.0))}, nil)},
+			opts:   Options{MissingReturn: SeverityError},
+			errMsg: "function 'main' can end without returning a int",
+		},
+		{
+			name:    "missing return as warning",
+			body:    []ast.Statement{assign("x", variable("n"))},
+			opts:    Options{MissingReturn: SeverityWarning},
+			warning: "function 'main' can end without returning a int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_module",
+				Functions: []ast.Function{{
+					Type: "function",
+					Name: "main",
+					Params: []ast.Parameter{
+						{Name: "n", Type: ast.TypeInt},
+						{Name: "f", Type: ast.TypeFloat},
+						{Name: "flag", Type: ast.TypeBool},
+					},
+					Returns: ast.TypeInt,
+					Body:    tt.body,
+				}},
+			}
+			data, err := json.Marshal(module)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			warnings, err := ValidateJSONWithOptions(data, tt.opts)
+			if tt.errMsg == "" && err != nil {
+				t.Fatalf("ValidateJSONWithOptions() error = %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Fatalf("ValidateJSONWithOptions() error = %v, want error containing %q", err, tt.errMsg)
+			}
+			if tt.warning == "" && len(warnings) > 0 {
+				t.Fatalf("ValidateJSONWithOptions() warnings = %v, want none", warnings)
+			}
+			if tt.warning != "" && (len(warnings) != 1 || !strings.HasSuffix(warnings[0], tt.warning)) {
+				t.Fatalf("ValidateJSONWithOptions() warnings = %v, want one ending in %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestAllErrors(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	module := &ast.Module{
+		Type: "module",
+		Name: "test_module",
+		Functions: []ast.Function{
+			{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{{Name: "flag", Type: ast.TypeBool}},
+				Returns: ast.TypeVoid,
+				Body: []ast.Statement{
+					{Type: ast.StmtAssign, Target: "x", Value: variable("missing")},
+					{Type: ast.StmtIf, Cond: variable("flag"), Then: []ast.Statement{
+						{Type: ast.StmtExpr, Value: &ast.Expression{
+							Type: ast.ExprBinary, Op: ast.OpAdd, Left: variable("a"), Right: variable("b"),
+						}},
+						{Type: "bogus"},
+					}},
+					{Type: ast.StmtExpr, Value: variable("x")},
+				},
+			},
+			{
+				Type:    "function",
+				Name:    "other",
+				Returns: ast.TypeVoid,
+				Body:    []ast.Statement{{Type: ast.StmtReturn, Value: variable("y")}},
+			},
+		},
+	}
+	want := []string{
+		"function 0: statement 0: assign value: undefined variable: missing",
+		"function 0: statement 1: then block statement 0: expression: left operand: undefined variable: a",
+		"function 0: statement 1: then block statement 0: expression: right operand: undefined variable: b",
+		"function 0: statement 1: then block statement 1: unknown statement type: bogus",
+		"function 1: statement 0: return value: undefined variable: y",
+	}
+
+	err := NewWithOptions(Options{AllErrors: true}).ValidateModule(module)
+	if err == nil {
+		t.Fatal("ValidateModule() succeeded, want errors")
+	}
+	joined, ok := errors.Unwrap(err).(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("ValidateModule() error %v does not wrap a joined error", err)
+	}
+	var got []string
+	for _, e := range joined.Unwrap() {
+		got = append(got, e.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidateModule() errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Without AllErrors each function reports only its first error
+	err = New().ValidateModule(module)
+	want = []string{want[0], want[4]}
+	if err == nil || err.Error() != "validation errors:\n"+strings.Join(want, "\n") {
+		t.Errorf("ValidateModule() error = %v, want\n%s", err, strings.Join(want, "\n"))
+	}
+}
+
+func TestValidationErrorPositions(t *testing.T) {
+	input := `{
+  "type": "module",
+  "name": "test_module",
+  "functions": [{
+    "type": "function", "name": "main", "params": [], "returns": "void",
+    "body": [
+      {"type": "assign", "target": "x",
+       "value": {"type": "binary", "op": "+",
+                 "left": {"type": "literal", "value": 1},
+                 "right": {"type": "variable", "name": "y"}}},
+      {"type": "bogus"}
+    ]
+  }],
+  "exports": ["missing"]
+}`
+
+	err := ValidateJSON([]byte(input))
+	if err == nil {
+		t.Fatal("ValidateJSON() succeeded, want errors")
+	}
+	want := []ValidationError{
+		{Message: "undefined variable: y", Line: 10, Column: 27, Path: "function 0: statement 0: assign value: right operand"},
+		{Message: "exported function 'missing' not found in module"},
+	}
+	got := ValidationErrors(err)
+	if len(got) != len(want) {
+		t.Fatalf("ValidationErrors() = %v, want %d errors", got, len(want))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("error %d = %+v, want %+v", i, *got[i], want[i])
+		}
+	}
+	if msg := got[0].Error(); msg != "10:27: function 0: statement 0: assign value: right operand: undefined variable: y" {
+		t.Errorf("Error() = %q", msg)
+	}
+
+	// Errors are reported at the innermost node with a position
+	_, err = ValidateJSONWithOptions([]byte(input), Options{AllErrors: true})
+	got = ValidationErrors(err)
+	if len(got) != 3 || got[1].Line != 11 || got[1].Column != 7 || got[1].Message != "unknown statement type: bogus" {
+		t.Errorf("ValidationErrors() = %v, want the unknown statement at 11:7", got)
+	}
+
+	ve := &ValidationError{Message: "bad", File: "main.alas.json", Line: 3, Column: 4, Path: "function 0"}
+	if msg := ve.Error(); msg != "main.alas.json:3:4: function 0: bad" {
+		t.Errorf("Error() = %q", msg)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	input := `{
+  "type": "module",
+  "name": "test_module",
+  "functions": [{
+    "type": "function", "name": "main", "params": [], "returns": "void",
+    "body": [
+      {"type": "assign", "target": "unused", "value": {"type": "literal", "value": 1}},
+      {"type": "expr", "value": {"type": "variable", "name": "a"}},
+      {"type": "expr", "value": {"type": "variable", "name": "b"}}
+    ]
+  }]
+}`
+
+	tests := []struct {
+		name string
+		data string
+		opts Options
+		want []Diagnostic
+	}{
+		{
+			name: "all errors with ranges",
+			data: input,
+			opts: DefaultOptions(),
+			want: []Diagnostic{
+				{
+					Severity: SeverityError,
+					Message:  "undefined variable: a",
+					Path:     "function 0: statement 1: expression",
+					Range:    Range{Start: Position{Line: 8, Column: 33}, End: Position{Line: 8, Column: 66}},
+				},
+				{
+					Severity: SeverityError,
+					Message:  "undefined variable: b",
+					Path:     "function 0: statement 2: expression",
+					Range:    Range{Start: Position{Line: 9, Column: 33}, End: Position{Line: 9, Column: 66}},
+				},
+			},
+		},
+		{
+			name: "enabled warnings",
+			data: `{
+  "type": "module",
+  "name": "test_module",
+  "functions": [{
+    "type": "function", "name": "main", "params": [], "returns": "void",
+    "body": [
+      {"type": "assign", "target": "unused", "value": {"type": "literal", "value": 1}}
+    ]
+  }]
+}`,
+			opts: Options{UnusedVariables: SeverityWarning},
+			want: []Diagnostic{{
+				Severity: SeverityWarning,
+				Message:  "function 'main': variable 'unused' is assigned but never used",
+				Range:    Range{Start: Position{Line: 7, Column: 7}, End: Position{Line: 7, Column: 87}},
+			}},
+		},
+		{
+			name: "invalid JSON",
+			data: "{\n  \"type\": \"module\",\n  \"name\" \"m\"\n}",
+			want: []Diagnostic{{
+				Severity: SeverityError,
+				Message:  "invalid JSON: invalid character '\"' after object key",
+				Range:    Range{Start: Position{Line: 3, Column: 10}, End: Position{Line: 3, Column: 10}},
+			}},
+		},
+		{
+			name: "valid module",
+			data: `{"type": "module", "name": "m", "functions": [{"type": "function", "name": "main", "params": [], "returns": "void", "body": []}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiagnoseWithOptions([]byte(tt.data), tt.opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DiagnoseWithOptions() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("diagnostic %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLambdaValidation(t *testing.T) {
+	returnVar := func(name string) []ast.Statement {
+		return []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: name}}}
+	}
+	assignLambda := func(params []ast.Parameter, body []ast.Statement) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: "f", Value: &ast.Expression{
+			Type: ast.ExprLambda, Params: params, Returns: "int", Body: body,
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "uses parameter",
+			body: []ast.Statement{assignLambda([]ast.Parameter{{Name: "x", Type: "int"}}, returnVar("x"))},
+		},
+		{
+			name: "captures enclosing variable",
+			body: []ast.Statement{assignLambda([]ast.Parameter{}, returnVar("n"))},
+		},
+		{
+			name: "called through variable",
+			body: []ast.Statement{
+				assignLambda([]ast.Parameter{{Name: "x", Type: "int"}}, returnVar("x")),
+				{Type: ast.StmtReturn, Value: &ast.Expression{
+					Type: ast.ExprCall, Name: "f", Args: []ast.Expression{{Type: ast.ExprVariable, Name: "n"}},
+				}},
+			},
+		},
+		{
+			name:    "undefined variable in body",
+			body:    []ast.Statement{assignLambda([]ast.Parameter{}, returnVar("y"))},
+			wantErr: true,
+			errMsg:  "lambda statement 0",
+		},
+		{
+			name: "lambda parameters do not leak",
+			body: append([]ast.Statement{
+				assignLambda([]ast.Parameter{{Name: "x", Type: "int"}}, returnVar("x")),
+			}, returnVar("x")...),
+			wantErr: true,
+			errMsg:  "undefined variable: x",
+		},
+		{
+			name:    "duplicate parameter",
+			body:    []ast.Statement{assignLambda([]ast.Parameter{{Name: "x", Type: "int"}, {Name: "x", Type: "int"}}, returnVar("x"))},
+			wantErr: true,
+			errMsg:  "duplicate lambda parameter name: x",
+		},
+		{
+			name:    "missing body",
+			body:    []ast.Statement{assignLambda([]ast.Parameter{}, nil)},
+			wantErr: true,
+			errMsg:  "lambda must have a body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test_module",
+				Functions: []ast.Function{{
+					Type:    "function",
+					Name:    "main",
+					Params:  []ast.Parameter{{Name: "n", Type: "int"}},
+					Returns: "int",
+					Body:    tt.body,
+				}},
+			}
+			err := New().ValidateModule(module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestFuncRefValidation(t *testing.T) {
+	funcRef := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprFuncRef, Name: name}
+	}
+	numbers := ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{}}
+	mapCall := func(fn ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: &ast.Expression{
+			Type: ast.ExprBuiltin, Name: "collections.map", Args: []ast.Expression{numbers, fn},
+		}}
+	}
+	callF := func(args ...ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprCall, Name: "f", Args: append([]ast.Expression{}, args...)}}
+	}
+	one := ast.Expression{Type: ast.ExprLiteral, Value: 1.0}
+	loader := stubModuleLoader{
+		"mathx": {Type: "module", Name: "mathx", Exports: []string{"inc"}, Functions: []ast.Function{
+			{
+				Type: "function", Name: "inc", Params: []ast.Parameter{{Name: "x", Type: "int"}}, Returns: "int",
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}}},
+			},
+			{
+				Type: "function", Name: "internal", Params: []ast.Parameter{{Name: "x", Type: "int"}}, Returns: "int",
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}}},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		body    []ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "reference to module function",
+			body: []ast.Statement{mapCall(*funcRef("double"))},
+		},
+		{
+			name: "reference to imported function",
+			body: []ast.Statement{mapCall(ast.Expression{Type: ast.ExprFuncRef, Module: "mathx", Name: "inc"})},
+		},
+		{
+			name:    "missing imported function",
+			body:    []ast.Statement{mapCall(ast.Expression{Type: ast.ExprFuncRef, Module: "mathx", Name: "dec"})},
+			wantErr: true,
+			errMsg:  "module mathx has no function dec",
+		},
+		{
+			name:    "reference to unexported imported function",
+			body:    []ast.Statement{mapCall(ast.Expression{Type: ast.ExprFuncRef, Module: "mathx", Name: "internal"})},
+			wantErr: true,
+			errMsg:  "function 'internal' is not exported from module 'mathx'",
+		},
+		{
+			name:    "undefined function",
+			body:    []ast.Statement{mapCall(*funcRef("triple"))},
+			wantErr: true,
+			errMsg:  "undefined function: triple",
+		},
+		{
+			name:    "map function arity mismatch",
+			body:    []ast.Statement{mapCall(*funcRef("add"))},
+			wantErr: true,
+			errMsg:  "collections.map: function argument must take 1 parameters, takes 2",
+		},
+		{
+			name:    "map argument is not a function",
+			body:    []ast.Statement{mapCall(one)},
+			wantErr: true,
+			errMsg:  "collections.map: second argument must be a function, got int",
+		},
+		{
+			name: "reduce with two-parameter function",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBuiltin, Name: "array.reduce", Args: []ast.Expression{numbers, *funcRef("add"), one},
+			}}},
+		},
+		{
+			name: "reduce function arity mismatch",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBuiltin, Name: "array.reduce", Args: []ast.Expression{numbers, *funcRef("double"), one},
+			}}},
+			wantErr: true,
+			errMsg:  "array.reduce: function argument must take 2 parameters, takes 1",
+		},
+		{
+			name: "reduce argument count",
+			body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+				Type: ast.ExprBuiltin, Name: "array.reduce", Args: []ast.Expression{numbers, *funcRef("add")},
+			}}},
+			wantErr: true,
+			errMsg:  "array.reduce expects 3 arguments, got 2",
+		},
+		{
+			name: "call through variable with matching arity",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "f", Value: funcRef("add")},
+				callF(one, one),
+			},
+		},
+		{
+			name: "call through variable with wrong arity",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "f", Value: funcRef("add")},
+				callF(one),
+			},
+			wantErr: true,
+			errMsg:  "function value 'f' expects 2 arguments, got 1",
+		},
+		{
+			name: "reassigned variable forgets arity",
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "f", Value: funcRef("add")},
+				{Type: ast.StmtAssign, Target: "f", Value: &ast.Expression{Type: ast.ExprVariable, Name: "g"}},
+				callF(one),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:    "module",
+				Name:    "test_module",
+				Imports: []ast.Import{{Module: "mathx"}},
+				Functions: []ast.Function{
+					{
+						Type: "function", Name: "main", Params: []ast.Parameter{{Name: "g", Type: "function"}}, Returns: "array",
+						Body: tt.body,
+					},
+					{
+						Type: "function", Name: "double", Params: []ast.Parameter{{Name: "x", Type: "int"}}, Returns: "int",
+						Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "x"}}},
+					},
+					{
+						Type: "function", Name: "add", Params: []ast.Parameter{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, Returns: "int",
+						Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "a"}}},
+					},
+				},
+			}
+			v := New()
+			v.SetModuleLoader(loader)
+			err := v.ValidateModule(module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBuiltinSignatureValidation(t *testing.T) {
+	builtin := func(name string, args ...ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtExpr, Value: &ast.Expression{Type: ast.ExprBuiltin, Name: name, Args: append([]ast.Expression{}, args...)}}
+	}
+	str := ast.Expression{Type: ast.ExprLiteral, Value: "hi"}
+	num := ast.Expression{Type: ast.ExprLiteral, Value: 2.0}
+	arr := ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{num}}
+
+	tests := []struct {
+		name   string
+		stmt   ast.Statement
+		errMsg string
+	}{
+		{name: "valid call", stmt: builtin("string.length", str)},
+		{name: "number accepts int", stmt: builtin("math.sqrt", num)},
+		{name: "optional argument omitted", stmt: builtin("collections.slice", arr, num)},
+		{name: "sort without comparator", stmt: builtin("array.sort", arr)},
+		{name: "sort comparator must be a function", stmt: builtin("array.sort", arr, num), errMsg: "array.sort: second argument must be a function, got int"},
+		{name: "missing argument", stmt: builtin("array.length"), errMsg: "array.length expects 1 argument, got 0"},
+		{name: "extra argument", stmt: builtin("math.max", num, num, num), errMsg: "math.max expects 2 arguments, got 3"},
+		{name: "wrong argument type", stmt: builtin("string.length", num), errMsg: "string.length: argument 0 must be string, got int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type: "module",
+				Name: "test",
+				Functions: []ast.Function{{
+					Type: "function", Name: "main", Params: []ast.Parameter{}, Returns: "void",
+					Body: []ast.Statement{tt.stmt},
+				}},
+			}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestTypeAliasValidation(t *testing.T) {
+	alias := func(name, target string) ast.TypeDefinition {
+		return ast.TypeDefinition{Name: name, Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: target}}
+	}
+	color := ast.TypeDefinition{Name: "Color", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindEnum, Values: []string{"Red", "Green"}}}
+	celsius := []ast.TypeDefinition{alias("Celsius", "float"), alias("Temp", "Celsius")}
+	returnBinary := func(op string, right ast.Expression) []ast.Statement {
+		return []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{
+			Type: ast.ExprBinary, Op: op, Left: &ast.Expression{Type: ast.ExprVariable, Name: "t"}, Right: &right,
+		}}}
+	}
+
+	tests := []struct {
+		name    string
+		types   []ast.TypeDefinition
+		body    []ast.Statement
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:  "alias of alias is numeric",
+			types: celsius,
+			body:  returnBinary("*", ast.Expression{Type: ast.ExprLiteral, Value: 2.0}),
+		},
+		{
+			name:    "alias keeps operand checks of its type",
+			types:   celsius,
+			body:    returnBinary("+", ast.Expression{Type: ast.ExprLiteral, Value: "degrees"}),
+			wantErr: true,
+			errMsg:  "operator '+' cannot be applied to float and string",
+		},
+		{
+			name:  "alias of enum constructs variants",
+			types: append([]ast.TypeDefinition{color, alias("Hue", "Color")}, celsius...),
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "h", Value: &ast.Expression{Type: ast.ExprVariant, Enum: "Hue", Variant: "Green"}},
+				{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprVariable, Name: "t"}},
+			},
+		},
+		{
+			name:    "alias without target",
+			types:   append([]ast.TypeDefinition{alias("Nothing", "")}, celsius...),
+			body:    returnBinary("*", ast.Expression{Type: ast.ExprLiteral, Value: 2.0}),
+			wantErr: true,
+			errMsg:  "alias type 'Nothing' must name the type it stands for",
+		},
+		{
+			name:    "alias cycle",
+			types:   []ast.TypeDefinition{alias("Celsius", "Temp"), alias("Temp", "Celsius")},
+			body:    returnBinary("*", ast.Expression{Type: ast.ExprLiteral, Value: 2.0}),
+			wantErr: true,
+			errMsg:  "type alias cycle: Celsius -> Temp -> Celsius",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{
+				Type:  "module",
+				Name:  "test_module",
+				Types: tt.types,
+				Functions: []ast.Function{{
+					Type: "function", Name: "main", Params: []ast.Parameter{{Name: "t", Type: "Temp"}}, Returns: "Temp",
+					Body: tt.body,
+				}},
+			}
+			err := New().ValidateModule(module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateModule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestElementAssignmentValidation(t *testing.T) {
+	index := func(object *ast.Expression, i float64) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: object, Index: &ast.Expression{Type: ast.ExprLiteral, Value: i}}
+	}
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	one := &ast.Expression{Type: ast.ExprLiteral, Value: 1.0}
+
+	tests := []struct {
+		name   string
+		stmt   ast.Statement
+		errMsg string
+	}{
+		{
+			name: "array element",
+			stmt: ast.Statement{Type: ast.StmtAssign, Lvalue: index(variable("arr"), 0), Value: one},
+		},
+		{
+			name: "struct field",
+			stmt: ast.Statement{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("m"), Field: "x"}, Value: one},
+		},
+		{
+			name: "nested index",
+			stmt: ast.Statement{Type: ast.StmtAssign, Lvalue: index(&ast.Expression{Type: ast.ExprIndex, Object: variable("m"),
+				Index: &ast.Expression{Type: ast.ExprLiteral, Value: "a"}}, 0), Value: one},
+		},
+		{
+			name:   "target and lvalue",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Target: "arr", Lvalue: index(variable("arr"), 0), Value: one},
+			errMsg: "must have either a target or an lvalue, not both",
+		},
+		{
+			name:   "missing value",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: index(variable("arr"), 0)},
+			errMsg: "assign statement must have a value",
+		},
+		{
+			name:   "bare variable",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: variable("arr"), Value: one},
+			errMsg: "use target to assign variable 'arr'",
+		},
+		{
+			name:   "call result",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: index(&ast.Expression{Type: ast.ExprCall, Name: "make"}, 0), Value: one},
+			errMsg: "assignment target must be an index or field expression, got call",
+		},
+		{
+			name:   "element of an int",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: index(variable("n"), 0), Value: one},
+			errMsg: "cannot assign to an element of type int",
+		},
+		{
+			name:   "undefined variable",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: index(variable("missing"), 0), Value: one},
+			errMsg: "undefined variable: missing",
+		},
+		{
+			name: "struct field of the field's type",
+			stmt: ast.Statement{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "name"},
+				Value: &ast.Expression{Type: ast.ExprLiteral, Value: "Ann"}},
+		},
+		{
+			name: "int to float field",
+			stmt: ast.Statement{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "height"}, Value: one},
+		},
+		{
+			name:   "unknown struct field",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "age"}, Value: one},
+			errMsg: "struct Person has no field age",
+		},
+		{
+			name:   "struct field of another type",
+			stmt:   ast.Statement{Type: ast.StmtAssign, Lvalue: &ast.Expression{Type: ast.ExprField, Object: variable("p"), Field: "name"}, Value: one},
+			errMsg: "field name: expected string, got int",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.localTypes["n"] = ast.TypeInt
+			v.localTypes["p"] = "Person"
+			v.types["Person"] = &ast.TypeDefinition{Name: "Person", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+				{Name: "name", Type: ast.TypeString}, {Name: "height", Type: ast.TypeFloat},
+			}}}
+			scope := map[string]bool{"arr": true, "m": true, "n": true, "p": true}
+			err := v.validateStatement(&tt.stmt, scope, nil)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("validateStatement() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateStatement() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDestructureValidation(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	lit := func(v interface{}) ast.Expression {
+		return ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	pair := &ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{lit(1.0), lit("two")}}
+	person := &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: lit("name"), Value: lit("Ann")}, {Key: lit("age"), Value: lit(41.0)}}}
+
+	tests := []struct {
+		name      string
+		stmt      ast.Statement
+		errMsg    string
+		wantTypes map[string]string
+	}{
+		{
+			name:      "array literal",
+			stmt:      ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a", "b"}, Value: pair},
+			wantTypes: map[string]string{"a": ast.TypeInt, "b": ast.TypeString},
+		},
+		{
+			name: "leading elements with a skip",
+			stmt: ast.Statement{Type: ast.StmtDestructure, Targets: []string{"_", "b"}, Value: variable("arr")},
+		},
+		{
+			name:      "struct fields",
+			stmt:      ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"name": "who", "height": "h"}, Value: variable("p")},
+			wantTypes: map[string]string{"who": ast.TypeString, "h": ast.TypeFloat},
+		},
+		{
+			name: "map literal fields",
+			stmt: ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"name": "name", "age": "age"}, Value: person},
+		},
+		{
+			name: "untyped map",
+			stmt: ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"anything": "x"}, Value: variable("m")},
+		},
+		{
+			name:   "too many array targets",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a", "b", "c"}, Value: pair},
+			errMsg: "array literal has 2 elements, cannot bind 3 targets",
+		},
+		{
+			name:   "unknown struct field",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"age": "age"}, Value: variable("p")},
+			errMsg: "struct Person has no field age",
+		},
+		{
+			name:   "missing map literal field",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"email": "email"}, Value: person},
+			errMsg: "map literal has no field email",
+		},
+		{
+			name:   "int as array",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a"}, Value: variable("n")},
+			errMsg: "cannot destructure int as an array",
+		},
+		{
+			name:   "array as map",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Bindings: map[string]string{"x": "x"}, Value: pair},
+			errMsg: "cannot destructure array as a map",
+		},
+		{
+			name:   "duplicate variable",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a", "a"}, Value: pair},
+			errMsg: "variable 'a' is bound more than once",
+		},
+		{
+			name:   "invalid target",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"1a"}, Value: variable("arr")},
+			errMsg: "invalid destructure target '1a'",
+		},
+		{
+			name:   "targets and bindings",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a"}, Bindings: map[string]string{"x": "x"}, Value: variable("m")},
+			errMsg: "destructure statement must have either targets or bindings",
+		},
+		{
+			name:   "missing value",
+			stmt:   ast.Statement{Type: ast.StmtDestructure, Targets: []string{"a"}},
+			errMsg: "destructure statement must have a value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			v.localTypes["n"] = ast.TypeInt
+			v.localTypes["p"] = "Person"
+			v.types["Person"] = &ast.TypeDefinition{Name: "Person", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct, Fields: []ast.TypeField{
+				{Name: "name", Type: ast.TypeString}, {Name: "height", Type: ast.TypeFloat},
+			}}}
+			scope := map[string]bool{"arr": true, "m": true, "n": true, "p": true}
+			err := v.validateStatement(&tt.stmt, scope, nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("validateStatement() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateStatement() error = %v", err)
+			}
+			for name, want := range tt.wantTypes {
+				if !scope[name] {
+					t.Errorf("%s is not in scope after destructuring", name)
+				}
+				if got := v.localTypes[name]; got != want {
+					t.Errorf("type of %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestContainerTypeValidation(t *testing.T) {
+	variable := func(name string) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprVariable, Name: name}
+	}
+	lit := func(v interface{}) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprLiteral, Value: v}
+	}
+	index := func(object string, idx *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprIndex, Object: variable(object), Index: idx}
+	}
+	ret := func(value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtReturn, Value: value}
+	}
+	add := func(left, right *ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: left, Right: right}
+	}
+
+	tests := []struct {
+		name      string
+		paramType string
+		body      []ast.Statement
+		errMsg    string
+	}{
+		{name: "array element", paramType: "array<int>", body: []ast.Statement{ret(add(index("xs", lit(0.0)), lit(1.0)))}},
+		{name: "nested map", paramType: "map<string, array<int>>", body: []ast.Statement{ret(index("xs", lit("a")))}},
+		{name: "plain array", paramType: ast.TypeArray, body: []ast.Statement{ret(add(index("xs", lit(0.0)), lit("s")))}},
+		{name: "element type mismatch", paramType: "array<string>", body: []ast.Statement{ret(add(index("xs", lit(0.0)), lit(1.0)))}, errMsg: "operator '+' cannot be applied to string and int"},
+		{name: "array index type", paramType: "array<int>", body: []ast.Statement{ret(index("xs", lit("a")))}, errMsg: "index of array<int> must be int, got string"},
+		{name: "map key type", paramType: "map<string,int>", body: []ast.Statement{ret(index("xs", lit(1.0)))}, errMsg: "index of map<string,int> must be string, got int"},
+		{
+			name:      "element assignment",
+			paramType: "array<int>",
+			body:      []ast.Statement{{Type: ast.StmtAssign, Lvalue: index("xs", lit(0.0)), Value: lit("s")}, ret(lit(0.0))},
+			errMsg:    "element: expected int, got string",
+		},
+		{
+			name:      "inferred literal element type",
+			paramType: ast.TypeInt,
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "ys", Value: &ast.Expression{Type: ast.ExprArrayLit, Elements: []ast.Expression{*lit(1.0), *lit(2.0)}}},
+				{Type: ast.StmtAssign, Lvalue: index("ys", lit(0.0)), Value: lit(true)},
+				ret(lit(0.0)),
+			},
+			errMsg: "element: expected int, got bool",
+		},
+		{name: "missing element type", paramType: "array<>", errMsg: "invalid type 'array<>'"},
+		{name: "too many type arguments", paramType: "array<int,int>", errMsg: "invalid type 'array<int,int>'"},
+		{name: "float map key", paramType: "map<float,int>", errMsg: "invalid type 'map<float,int>'"},
+		{name: "bool map key", paramType: "map<bool,int>", body: []ast.Statement{ret(index("xs", lit(true)))}},
+		{name: "float index of a plain map", paramType: ast.TypeMap, body: []ast.Statement{ret(index("xs", lit(1.5)))}, errMsg: "map keys must be int, bool or string, got float"},
+		{
+			name:      "int keys in a map literal",
+			paramType: ast.TypeInt,
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "ys", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: *lit(1.0), Value: *lit(10.0)}, {Key: *variable("xs"), Value: *lit(20.0)}}}},
+				ret(index("ys", lit(1.0))),
+			},
+		},
+		{
+			name:      "float key in a map literal",
+			paramType: ast.TypeInt,
+			body: []ast.Statement{
+				{Type: ast.StmtAssign, Target: "ys", Value: &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{{Key: *lit(1.5), Value: *lit(10.0)}}}},
+				ret(lit(0.0)),
+			},
+			errMsg: "map pair 0 key: map keys must be int, bool or string, got float",
+		},
+		{name: "not a container", paramType: "int<string>", errMsg: "invalid type 'int<string>'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if body == nil {
+				body = []ast.Statement{ret(lit(0.0))}
+			}
+			module := &ast.Module{Type: "module", Name: "test", Functions: []ast.Function{{
+				Type:    "function",
+				Name:    "main",
+				Params:  []ast.Parameter{{Name: "xs", Type: tt.paramType}},
+				Returns: ast.TypeInt,
+				Body:    body,
+			}}}
+			err := New().ValidateModule(module)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateModule() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestExternValidation(t *testing.T) {
+	extern := func(name, symbol, returns string, params ...ast.Parameter) ast.Function {
+		return ast.Function{Type: "function", Name: name, Extern: symbol, Params: params, Returns: returns}
+	}
+	callMain := ast.Function{Type: "function", Name: "main", Params: []ast.Parameter{}, Returns: ast.TypeInt, Body: []ast.Statement{{
+		Type:  ast.StmtReturn,
+		Value: &ast.Expression{Type: ast.ExprCall, Name: "abs", Args: []ast.Expression{{Type: ast.ExprLiteral, Value: -3.0}}},
+	}}}
+
+	tests := []struct {
+		name      string
+		functions []ast.Function
+		types     []ast.TypeDefinition
+		errMsg    string
+	}{
+		{
+			name:      "scalar signature",
+			functions: []ast.Function{extern("abs", "labs", ast.TypeInt, ast.Parameter{Name: "n", Type: ast.TypeInt}), callMain},
+		},
+		{
+			name: "strings, bools, and aliases",
+			functions: []ast.Function{
+				extern("puts", "puts", ast.TypeVoid, ast.Parameter{Name: "s", Type: "Text"}),
+				extern("ready", "ready", ast.TypeBool, ast.Parameter{Name: "x", Type: ast.TypeFloat}),
+				extern("abs", "labs", ast.TypeInt, ast.Parameter{Name: "n", Type: ast.TypeInt}),
+				callMain,
+			},
+			types: []ast.TypeDefinition{{Name: "Text", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: ast.TypeString}}},
+		},
+		{
+			name:      "map parameter",
+			functions: []ast.Function{extern("size", "size", ast.TypeInt, ast.Parameter{Name: "m", Type: ast.TypeMap})},
+			errMsg:    "parameter m: type 'map' cannot be passed to C",
+		},
+		{
+			name:      "array result",
+			functions: []ast.Function{extern("items", "items", ast.TypeArray)},
+			errMsg:    "return type 'array' cannot be returned from C",
+		},
+		{
+			name:      "invalid symbol",
+			functions: []ast.Function{extern("f", "not-a-symbol", ast.TypeVoid)},
+			errMsg:    "invalid extern symbol 'not-a-symbol'",
+		},
+		{
+			name: "body",
+			functions: []ast.Function{{Type: "function", Name: "f", Extern: "f", Returns: ast.TypeVoid,
+				Body: []ast.Statement{{Type: ast.StmtReturn}}}},
+			errMsg: "extern function cannot have a body",
+		},
+		{
+			name: "symbol of a defined function",
+			functions: []ast.Function{
+				extern("abs", "main", ast.TypeInt, ast.Parameter{Name: "n", Type: ast.TypeInt}),
+				callMain,
+			},
+			errMsg: "extern symbol 'main' conflicts with function 'main'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "ffi", Functions: tt.functions, Types: tt.types}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestFunctionMetaValidation(t *testing.T) {
+	withMeta := func(name string, meta map[string]interface{}) ast.Function {
+		return ast.Function{Type: "function", Name: name, Params: []ast.Parameter{}, Returns: ast.TypeInt, Meta: meta,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}}}}
+	}
+
+	tests := []struct {
+		name     string
+		function ast.Function
+		exports  []string
+		errMsg   string
+	}{
+		{
+			name:     "honored keys",
+			function: withMeta("f", map[string]interface{}{"inline": "never", "noopt": true, "export": true}),
+		},
+		{
+			name:     "unknown keys are ignored",
+			function: withMeta("f", map[string]interface{}{"author": "someone", "hot": 1.0}),
+		},
+		{
+			name:     "invalid inline value",
+			function: withMeta("f", map[string]interface{}{"inline": "sometimes"}),
+			errMsg:   `meta 'inline' must be "always" or "never"`,
+		},
+		{
+			name:     "non-bool noopt",
+			function: withMeta("f", map[string]interface{}{"noopt": "yes"}),
+			errMsg:   "meta 'noopt' must be a bool",
+		},
+		{
+			name:     "non-bool export",
+			function: withMeta("f", map[string]interface{}{"export": 1.0}),
+			errMsg:   "meta 'export' must be a bool",
+		},
+		{
+			name:     "always inline without optimization",
+			function: withMeta("f", map[string]interface{}{"inline": "always", "noopt": true}),
+			errMsg:   `meta 'inline' "always" conflicts with 'noopt'`,
+		},
+		{
+			name:     "internal main",
+			function: withMeta("main", map[string]interface{}{"export": false}),
+			errMsg:   "function 'main' cannot have meta 'export' false",
+		},
+		{
+			name:     "internal function in module exports",
+			function: withMeta("f", map[string]interface{}{"export": false}),
+			exports:  []string{"f"},
+			errMsg:   "function 'f': meta export false conflicts with the module exporting it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "meta", Exports: tt.exports, Functions: []ast.Function{tt.function}}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestStructLiteralValidation(t *testing.T) {
+	lit := func(value interface{}) ast.Expression { return ast.Expression{Type: ast.ExprLiteral, Value: value} }
+	pair := func(key string, value ast.Expression) ast.MapPair { return ast.MapPair{Key: lit(key), Value: value} }
+	mapLit := func(pairs ...ast.MapPair) *ast.Expression { return &ast.Expression{Type: ast.ExprMapLit, Pairs: pairs} }
+	returning := func(returns string, value *ast.Expression) ast.Function {
+		return ast.Function{Type: "function", Name: "make", Params: []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, Returns: returns,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: value}}}
+	}
+	n := ast.Expression{Type: ast.ExprVariable, Name: "n"}
+	types := []ast.TypeDefinition{
+		{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct,
+			Fields: []ast.TypeField{{Name: "x", Type: ast.TypeFloat}, {Name: "y", Type: ast.TypeFloat}}}},
+		{Name: "Line", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct,
+			Fields: []ast.TypeField{{Name: "from", Type: "Point"}, {Name: "to", Type: "Point"}}}},
+		{Name: "Spot", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindAlias, Type: "Point"}},
+	}
+	origin := mapLit(pair("x", lit(0.0)), pair("y", lit(0.0)))
+
+	tests := []struct {
+		name     string
+		function ast.Function
+		errMsg   string
+	}{
+		{
+			name:     "exact fields with an int for a float",
+			function: returning("Point", mapLit(pair("y", lit(2.5)), pair("x", n))),
+		},
+		{
+			name:     "nested struct literals",
+			function: returning("Line", mapLit(pair("from", *origin), pair("to", *origin))),
+		},
+		{
+			name:     "map return type is not checked",
+			function: returning(ast.TypeMap, mapLit(pair("z", n))),
+		},
+		{
+			name:     "missing field",
+			function: returning("Point", mapLit(pair("x", n))),
+			errMsg:   "return value: struct Point literal missing field y",
+		},
+		{
+			name:     "extra fields",
+			function: returning("Point", mapLit(pair("x", n), pair("y", n), pair("z", n), pair("w", n))),
+			errMsg:   "return value: struct Point has no field z, w",
+		},
+		{
+			name:     "incompatible value",
+			function: returning("Point", mapLit(pair("x", n), pair("y", lit("up")))),
+			errMsg:   "return value: struct Point literal: field y: expected float, got string",
+		},
+		{
+			name:     "through an alias",
+			function: returning("Spot", mapLit(pair("y", n))),
+			errMsg:   "return value: struct Point literal missing field x",
+		},
+		{
+			name:     "nested literal missing a field",
+			function: returning("Line", mapLit(pair("from", *origin), pair("to", *mapLit(pair("x", n))))),
+			errMsg:   "return value: field to: struct Point literal missing field y",
+		},
+		{
+			name: "lambda returning a struct",
+			function: ast.Function{Type: "function", Name: "make", Params: []ast.Parameter{}, Returns: ast.TypeFunc,
+				Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLambda, Params: []ast.Parameter{}, Returns: "Point",
+					Body: []ast.Statement{{Type: ast.StmtReturn, Value: mapLit(pair("x", lit(1.0)))}}}}}},
+			errMsg: "struct Point literal missing field y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "shapes", Types: types, Functions: []ast.Function{tt.function}}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestModuleKindValidation(t *testing.T) {
+	function := func(name string, params ...ast.Parameter) ast.Function {
+		return ast.Function{Type: "function", Name: name, Params: params, Returns: ast.TypeInt,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: &ast.Expression{Type: ast.ExprLiteral, Value: 0.0}}}}
+	}
+
+	tests := []struct {
+		name      string
+		kind      string
+		exports   []string
+		functions []ast.Function
+		errMsg    string
+	}{
+		{
+			name:      "no kind without main or exports",
+			functions: []ast.Function{function("helper")},
+		},
+		{
+			name:      "program",
+			kind:      ast.ModuleKindProgram,
+			functions: []ast.Function{function("main")},
+		},
+		{
+			name:      "program taking arguments",
+			kind:      ast.ModuleKindProgram,
+			functions: []ast.Function{function("main", ast.Parameter{Name: "args", Type: "array<string>"})},
+		},
+		{
+			name:      "program without main",
+			kind:      ast.ModuleKindProgram,
+			functions: []ast.Function{function("helper")},
+			errMsg:    "program module must define a main function",
+		},
+		{
+			name:      "program main with other parameters",
+			kind:      ast.ModuleKindProgram,
+			functions: []ast.Function{function("main", ast.Parameter{Name: "n", Type: ast.TypeInt})},
+			errMsg:    "program main function must take no parameters or a single array of arguments",
+		},
+		{
+			name:      "library",
+			kind:      ast.ModuleKindLibrary,
+			exports:   []string{"helper"},
+			functions: []ast.Function{function("helper")},
+		},
+		{
+			name:      "library without exports",
+			kind:      ast.ModuleKindLibrary,
+			functions: []ast.Function{function("helper")},
+			errMsg:    "library module must export at least one function",
+		},
+		{
+			name:      "unknown kind",
+			kind:      "plugin",
+			functions: []ast.Function{function("main")},
+			errMsg:    "unknown module kind 'plugin', must be 'program' or 'library'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &ast.Module{Type: "module", Name: "app", Kind: tt.kind, Exports: tt.exports, Functions: tt.functions}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestForInitUpdateValidation(t *testing.T) {
+	v := func(name string) *ast.Expression { return &ast.Expression{Type: ast.ExprVariable, Name: name} }
+	lit := func(n float64) *ast.Expression { return &ast.Expression{Type: ast.ExprLiteral, Value: n} }
+	assign := func(target string, value *ast.Expression) ast.Statement {
+		return ast.Statement{Type: ast.StmtAssign, Target: target, Value: value}
+	}
+	increment := assign("i", &ast.Expression{Type: ast.ExprBinary, Op: ast.OpAdd, Left: v("i"), Right: lit(1)})
+	cond := &ast.Expression{Type: ast.ExprBinary, Op: ast.OpLt, Left: v("i"), Right: lit(10)}
+
+	tests := []struct {
+		name   string
+		loop   ast.Statement
+		after  []ast.Statement
+		errMsg string
+	}{
+		{
+			name: "init and update",
+			loop: ast.Statement{Type: ast.StmtFor, Init: []ast.Statement{assign("i", lit(0))}, Cond: cond,
+				Update: []ast.Statement{increment}, Body: []ast.Statement{assign("x", v("i"))}},
+		},
+		{
+			name:   "condition without init",
+			loop:   ast.Statement{Type: ast.StmtFor, Cond: cond, Body: []ast.Statement{increment}},
+			errMsg: "undefined variable: i",
+		},
+		{
+			name: "update sees variables from init only",
+			loop: ast.Statement{Type: ast.StmtFor, Init: []ast.Statement{assign("i", lit(0))}, Cond: cond,
+				Update: []ast.Statement{assign("i", v("x"))}, Body: []ast.Statement{assign("x", v("i"))}},
+			errMsg: "for update statement 0",
+		},
+		{
+			name: "init variable out of scope after the loop",
+			loop: ast.Statement{Type: ast.StmtFor, Init: []ast.Statement{assign("i", lit(0))}, Cond: cond,
+				Update: []ast.Statement{increment}, Body: []ast.Statement{{Type: ast.StmtExpr, Value: v("i")}}},
+			after:  []ast.Statement{{Type: ast.StmtReturn, Value: v("i")}},
+			errMsg: "undefined variable: i",
+		},
+		{
+			name: "return in init",
+			loop: ast.Statement{Type: ast.StmtFor, Init: []ast.Statement{{Type: ast.StmtReturn, Value: lit(0)}}, Cond: cond,
+				Body: []ast.Statement{increment}},
+			errMsg: "for init statement 0: return statement is not allowed, must be assign or expr",
+		},
+		{
+			name: "loop in update",
+			loop: ast.Statement{Type: ast.StmtFor, Init: []ast.Statement{assign("i", lit(0))}, Cond: cond,
+				Update: []ast.Statement{{Type: ast.StmtWhile, Cond: cond, Body: []ast.Statement{increment}}},
+				Body:   []ast.Statement{increment}},
+			errMsg: "for update statement 0: while statement is not allowed, must be assign or expr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := append([]ast.Statement{tt.loop}, tt.after...)
+			if len(tt.after) == 0 {
+				body = append(body, ast.Statement{Type: ast.StmtReturn, Value: lit(0)})
+			}
+			module := &ast.Module{Type: "module", Name: "loops", Functions: []ast.Function{
+				{Type: "function", Name: "main", Returns: ast.TypeInt, Body: body},
+			}}
+			err := New().ValidateModule(module)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateModule() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateModule() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateFunctionIncrementally(t *testing.T) {
+	call := func(name string, args ...ast.Expression) *ast.Expression {
+		return &ast.Expression{Type: ast.ExprCall, Name: name, Args: args}
+	}
+	one := ast.Expression{Type: ast.ExprLiteral, Value: 1.0}
+	function := func(name string, params []ast.Parameter, returns string, value *ast.Expression) ast.Function {
+		return ast.Function{Type: "function", Name: name, Params: params, Returns: returns,
+			Body: []ast.Statement{{Type: ast.StmtReturn, Value: value}}}
+	}
+	newModule := func() *ast.Module {
+		return &ast.Module{
+			Type: "module",
+			Name: "app",
+			Types: []ast.TypeDefinition{{Name: "Point", Definition: ast.TypeDefinitionDef{Kind: ast.TypeKindStruct,
+				Fields: []ast.TypeField{{Name: "x", Type: ast.TypeInt}}}}},
+			Functions: []ast.Function{
+				function("helper", []ast.Parameter{{Name: "n", Type: ast.TypeInt}}, ast.TypeInt, &ast.Expression{Type: ast.ExprVariable, Name: "n"}),
+				function("main", []ast.Parameter{}, ast.TypeInt, call("helper", one)),
+			},
+		}
+	}
+
+	if err := New().ValidateFunction(&newModule().Functions[0]); err == nil || !strings.Contains(err.Error(), "no module has been validated") {
+		t.Errorf("ValidateFunction() before ValidateModule error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		edits  []ast.Function // validated in order; only the last result is checked
+		errMsg string
+	}{
+		{
+			name:  "unchanged function",
+			edits: []ast.Function{function("main", []ast.Parameter{}, ast.TypeInt, call("helper", one))},
+		},
+		{
+			name:   "edited body",
+			edits:  []ast.Function{function("main", []ast.Parameter{}, ast.TypeInt, &ast.Expression{Type: ast.ExprVariable, Name: "missing"})},
+			errMsg: "function 1: statement 0: return value: undefined variable: missing",
+		},
+		{
+			name: "new function using module types",
+			edits: []ast.Function{function("origin", []ast.Parameter{}, "Point", &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+				{Key: ast.Expression{Type: ast.ExprLiteral, Value: "x"}, Value: one}}})},
+		},
+		{
+			name: "new function with an invalid struct literal",
+			edits: []ast.Function{function("origin", []ast.Parameter{}, "Point", &ast.Expression{Type: ast.ExprMapLit, Pairs: []ast.MapPair{
+				{Key: ast.Expression{Type: ast.ExprLiteral, Value: "y"}, Value: one}}})},
+			errMsg: "function 2: statement 0: return value: struct Point has no field y",
+		},
+		{
+			name: "changed signature is seen by later edits",
+			edits: []ast.Function{
+				function("helper", []ast.Parameter{{Name: "a", Type: ast.TypeInt}, {Name: "b", Type: ast.TypeInt}}, ast.TypeInt, &ast.Expression{Type: ast.ExprVariable, Name: "a"}),
+				function("main", []ast.Parameter{}, ast.TypeInt, call("helper", one)),
+			},
+			errMsg: "function 1: statement 0: return value: function 'helper' expects 2 arguments, got 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			if err := v.ValidateModule(newModule()); err != nil {
+				t.Fatalf("ValidateModule() error = %v", err)
+			}
+			var err error
+			for i := range tt.edits {
+				err = v.ValidateFunction(&tt.edits[i])
+			}
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateFunction() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateFunction() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package watch re-runs a build step when an ALaS module or one of its
+// imported modules changes on disk.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// Default polling settings.
+const (
+	DefaultInterval = 250 * time.Millisecond
+	DefaultDebounce = 300 * time.Millisecond
+)
+
+// ModuleLoader defines the interface for resolving imported modules.
+type ModuleLoader interface {
+	LoadModuleByName(name string) (*ast.Module, error)
+}
+
+// ModuleFiles returns the path of the module at path followed by the files of
+// every module it imports, transitively, as resolved by loader. Imports that
+// cannot be loaded are skipped; the module itself is always included.
+func ModuleFiles(path string, loader ModuleLoader) []string {
+	files := []string{path}
+	data, err := os.ReadFile(path)
+	if err != nil || loader == nil {
+		return files
+	}
+	module, err := ast.ParseModule(data, path)
+	if err != nil {
+		return files
+	}
+
+	seen := map[string]bool{module.Name: true}
+	seenFiles := map[string]bool{filepath.Clean(path): true}
+	queue := module.ImportedModules()
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		imported, err := loader.LoadModuleByName(name)
+		if err != nil {
+			continue
+		}
+		if imported.File != "" && !seenFiles[filepath.Clean(imported.File)] {
+			seenFiles[filepath.Clean(imported.File)] = true
+			files = append(files, imported.File)
+		}
+		queue = append(queue, imported.ImportedModules()...)
+	}
+	return files
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// Watcher polls a set of files and reports when they change.
+type Watcher struct {
+	Interval time.Duration // How often files are polled
+	Debounce time.Duration // How long files must be unchanged before reporting
+
+	files  func() []string
+	stamps map[string]fileStamp
+}
+
+// New creates a watcher for the files returned by files. The list is
+// recomputed after every change so newly imported modules are picked up.
+func New(files func() []string) *Watcher {
+	return &Watcher{
+		Interval: DefaultInterval,
+		Debounce: DefaultDebounce,
+		files:    files,
+	}
+}
+
+// snapshot records the current state of the watched files.
+func (w *Watcher) snapshot() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, file := range w.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			stamps[file] = fileStamp{}
+			continue
+		}
+		stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+	}
+	return stamps
+}
+
+// changed reports whether the watched files differ from the last snapshot.
+func (w *Watcher) changed() bool {
+	current := w.snapshot()
+	if len(current) != len(w.stamps) {
+		w.stamps = current
+		return true
+	}
+	for file, stamp := range current {
+		if w.stamps[file] != stamp {
+			w.stamps = current
+			return true
+		}
+	}
+	return false
+}
+
+// Run calls onChange once, then again each time the watched files change,
+// until stop is closed. Rapid successive writes are coalesced: onChange runs
+// only after the files have been unchanged for the debounce period.
+func (w *Watcher) Run(stop <-chan struct{}, onChange func()) {
+	w.stamps = w.snapshot()
+	onChange()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	var pending bool
+	var lastChange time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if w.changed() {
+				pending = true
+				lastChange = now
+				continue
+			}
+			if pending && now.Sub(lastChange) >= w.Debounce {
+				pending = false
+				// Pick up imports added or removed by the change
+				w.stamps = w.snapshot()
+				onChange()
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dshills/alas/internal/ast"
+)
+
+// stubLoader resolves imports to files in a directory.
+type stubLoader struct {
+	dir string
+}
+
+func (l stubLoader) LoadModuleByName(name string) (*ast.Module, error) {
+	path := filepath.Join(l.dir, name+".alas.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ast.ParseModule(data, path)
+}
+
+func writeModule(t *testing.T, path, name string, imports ...string) {
+	t.Helper()
+	importList := ""
+	for i, imp := range imports {
+		if i > 0 {
+			importList += ","
+		}
+		importList += `"` + imp + `"`
+	}
+	data := `{"type":"module","name":"` + name + `","imports":[` + importList + `],"functions":[]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestModuleFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.alas.json")
+	writeModule(t, main, "main", "a", "b", "missing")
+	writeModule(t, filepath.Join(dir, "a.alas.json"), "a", "b")
+	writeModule(t, filepath.Join(dir, "b.alas.json"), "b", "main")
+
+	got := ModuleFiles(main, stubLoader{dir: dir})
+	want := []string{main, filepath.Join(dir, "a.alas.json"), filepath.Join(dir, "b.alas.json")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ModuleFiles() = %v, want %v", got, want)
+	}
+
+	if got := ModuleFiles(filepath.Join(dir, "nope.alas.json"), stubLoader{dir: dir}); len(got) != 1 {
+		t.Errorf("ModuleFiles() for missing file = %v, want just the file", got)
+	}
+}
+
+func TestWatcherDebouncesChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.alas.json")
+	writeModule(t, path, "main")
+
+	w := New(func() []string { return []string{path} })
+	w.Interval = 5 * time.Millisecond
+	w.Debounce = 40 * time.Millisecond
+
+	var runs atomic.Int32
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.Run(stop, func() { runs.Add(1) })
+		close(done)
+	}()
+
+	waitFor := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for runs.Load() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d runs, got %d", want, runs.Load())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// Initial run
+	waitFor(1)
+
+	// A burst of writes produces a single run
+	for i := 0; i < 3; i++ {
+		writeModule(t, path, "main", "x"+string(rune('a'+i)))
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitFor(2)
+	time.Sleep(100 * time.Millisecond)
+	if got := runs.Load(); got != 2 {
+		t.Errorf("expected burst of writes to trigger 1 run, got %d", got-1)
+	}
+
+	// Deleting the file is also a change
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	waitFor(3)
+
+	close(stop)
+	<-done
+}
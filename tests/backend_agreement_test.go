@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/bench"
+	"github.com/dshills/alas/internal/codegen"
+)
+
+// TestBackendsAgree compiles and runs example programs and checks that main
+// returns the same result as in the interpreter.
+func TestBackendsAgree(t *testing.T) {
+	programs := []struct {
+		file     string
+		expected string
+	}{
+		{"examples/programs/hello.alas.json", "Hello, ALaS!"},
+		{"examples/programs/fibonacci.alas.json", "55"},
+		{"examples/programs/factorial.alas.json", "120"},
+		{"examples/programs/loops.alas.json", "55"},
+		{"examples/programs/optimization_demo.alas.json", "65"},
+		{"examples/programs/test_for_loop.alas.json", "10"},
+	}
+
+	levels := []codegen.OptimizationLevel{codegen.OptNone, codegen.OptAggressive}
+
+	for _, tc := range programs {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(tc.file)
+			if err != nil {
+				// Try with ../ prefix in case we're still in tests directory
+				altFile := "../" + tc.file
+				data, err = os.ReadFile(altFile)
+				if err != nil {
+					t.Skipf("Skipping test, file not found: %s or %s", tc.file, altFile)
+				}
+			}
+
+			module, err := ast.ParseModule(data, tc.file)
+			if err != nil {
+				t.Fatalf("Failed to parse JSON: %v", err)
+			}
+
+			for _, level := range levels {
+				opts := bench.DefaultOptions()
+				opts.Iterations = 1
+				opts.OptLevel = level
+
+				result, err := bench.Run(module, opts)
+				if errors.Is(err, bench.ErrToolchainNotFound) {
+					t.Skip(err)
+				}
+				if err != nil {
+					t.Fatalf("O%d: %v", level, err)
+				}
+				if !result.Match() {
+					t.Errorf("O%d: interpreter returned %q, compiled program %q", level, result.Interpreted, result.Compiled)
+				}
+				if result.Compiled != tc.expected {
+					t.Errorf("O%d: expected %q, got %q", level, tc.expected, result.Compiled)
+				}
+			}
+		})
+	}
+}
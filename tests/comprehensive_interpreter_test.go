@@ -74,8 +74,8 @@ func TestBasicDataTypes(t *testing.T) {
 								Value: &ast.Expression{
 									Type:  ast.ExprBinary,
 									Op:    "/",
-									Left:  &ast.Expression{Type: ast.ExprLiteral, Value: 22.0},
-									Right: &ast.Expression{Type: ast.ExprLiteral, Value: 7.0},
+									Left:  &ast.Expression{Type: ast.ExprLiteral, Value: 22.0, To: ast.TypeFloat},
+									Right: &ast.Expression{Type: ast.ExprLiteral, Value: 7.0, To: ast.TypeFloat},
 								},
 							},
 						},
@@ -83,7 +83,7 @@ func TestBasicDataTypes(t *testing.T) {
 				},
 			},
 			function: "main",
-			expected: runtime.NewInt(3), // Integer division in ALaS
+			expected: runtime.NewFloat(22.0 / 7.0),
 		},
 		{
 			name: "String Concatenation",
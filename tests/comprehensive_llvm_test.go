@@ -585,7 +585,7 @@ func TestLLVMMultiModuleCodegen(t *testing.T) {
 	mainModule := &ast.Module{
 		Type:    "module",
 		Name:    "main",
-		Imports: []string{"math_utils"},
+		Imports: []ast.Import{{Module: "math_utils"}},
 		Functions: []ast.Function{
 			{
 				Type:    "function",
@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dshills/alas/internal/ast"
+	"github.com/dshills/alas/internal/bench"
+	"github.com/dshills/alas/internal/codegen"
+)
+
+// differentialCase is a program whose main function must return the same
+// result in the interpreter and when compiled.
+type differentialCase struct {
+	name     string
+	source   []byte
+	stdlib   bool   // Links against libalas_stdlib.so
+	diverges string // Why the backends currently disagree, if they do
+}
+
+// Example programs that need the standard library to link.
+var differentialStdlibExamples = map[string]bool{
+	"arrays_maps.alas.json":                    true,
+	"builtin_test.alas.json":                   true,
+	"comprehensive_builtin_test.alas.json":     true,
+	"comprehensive_builtin_test_new.alas.json": true,
+	"custom_types.alas.json":                   true,
+	"field_access.alas.json":                   true,
+	"llvm_builtin_test.alas.json":              true,
+	"math_test.alas.json":                      true,
+	"simple_builtin_test.alas.json":            true,
+	"simple_number_test.alas.json":             true,
+	"stdlib_comprehensive_test.alas.json":      true,
+	"test_maps.alas.json":                      true,
+	"unary_expressions.alas.json":              true,
+}
+
+// Reasons shared by several known divergences.
+const (
+	divergesImports = "imported modules are neither found by the interpreter nor linked by the compiler"
+)
+
+// Example programs on which the backends currently disagree.
+var differentialKnownDivergences = map[string]string{
+	"async_demo.alas.json":                divergesImports,
+	"complex_modules.alas.json":           divergesImports,
+	"error_handling_test.alas.json":       "the module has no main function",
+	"module_demo.alas.json":               divergesImports,
+	"stdlib_comprehensive_test.alas.json": "math.max is missing from the compiled standard library",
+	"stdlib_test.alas.json":               divergesImports,
+}
+
+// differentialMain returns a module whose main function has the given return
+// type and body, written as a JSON array of statements.
+func differentialMain(returns, body string) []byte {
+	return []byte(fmt.Sprintf(`{"type": "module", "name": "differential", "functions": [
+		{"type": "function", "name": "main", "params": [], "returns": %q, "body": %s}]}`, returns, body))
+}
+
+// differentialGeneratedCases covers value types and operations separately from
+// the example programs, so that a divergence points at a single feature.
+func differentialGeneratedCases() []differentialCase {
+	// More elements than codegen.DefaultHeapArrayThreshold
+	largeArray := make([]string, 2*codegen.DefaultHeapArrayThreshold)
+	for i := range largeArray {
+		largeArray[i] = fmt.Sprintf(`{"type": "literal", "value": %d}`, i)
+	}
+
+	return []differentialCase{
+		{
+			name: "int arithmetic",
+			source: differentialMain("int", `[
+				{"type": "return", "value": {"type": "binary", "op": "-",
+					"left": {"type": "binary", "op": "*", "left": {"type": "literal", "value": 7}, "right": {"type": "literal", "value": 6}},
+					"right": {"type": "unary", "op": "-", "operand": {"type": "literal", "value": 5}}}}]`),
+		},
+		{
+			name:     "int division",
+			diverges: "division calls alas_runtime_check_div_zero, which no runtime library defines",
+			source: differentialMain("int", `[
+				{"type": "return", "value": {"type": "binary", "op": "+",
+					"left": {"type": "binary", "op": "/", "left": {"type": "literal", "value": -10}, "right": {"type": "literal", "value": 3}},
+					"right": {"type": "binary", "op": "%", "left": {"type": "literal", "value": 17}, "right": {"type": "literal", "value": 5}}}}]`),
+		},
+		{
+			name: "float arithmetic",
+			source: differentialMain("float", `[
+				{"type": "assign", "target": "x", "value": {"type": "literal", "value": 1.5}},
+				{"type": "return", "value": {"type": "binary", "op": "-",
+					"left": {"type": "binary", "op": "*", "left": {"type": "variable", "name": "x"}, "right": {"type": "literal", "value": 4.0}},
+					"right": {"type": "binary", "op": "/", "left": {"type": "literal", "value": 22.0}, "right": {"type": "literal", "value": 7.0}}}}]`),
+		},
+		{
+			name: "bool logic",
+			source: differentialMain("bool", `[
+				{"type": "return", "value": {"type": "binary", "op": "||",
+					"left": {"type": "binary", "op": "&&",
+						"left": {"type": "binary", "op": "<", "left": {"type": "literal", "value": 3}, "right": {"type": "literal", "value": 5}},
+						"right": {"type": "unary", "op": "!", "operand": {"type": "literal", "value": true}}},
+					"right": {"type": "binary", "op": ">=", "left": {"type": "literal", "value": 2.5}, "right": {"type": "literal", "value": 2.5}}}}]`),
+		},
+		{
+			name: "while loop",
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "i", "value": {"type": "literal", "value": 0}},
+				{"type": "assign", "target": "sum", "value": {"type": "literal", "value": 0}},
+				{"type": "while", "cond": {"type": "binary", "op": "<", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 100}},
+					"body": [
+						{"type": "assign", "target": "sum", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "sum"},
+							"right": {"type": "binary", "op": "*", "left": {"type": "variable", "name": "i"}, "right": {"type": "variable", "name": "i"}}}},
+						{"type": "assign", "target": "i", "value": {"type": "binary", "op": "+", "left": {"type": "variable", "name": "i"}, "right": {"type": "literal", "value": 1}}}]},
+				{"type": "return", "value": {"type": "variable", "name": "sum"}}]`),
+		},
+		{
+			name: "string literal",
+			source: differentialMain("string", `[
+				{"type": "assign", "target": "s", "value": {"type": "literal", "value": "differential"}},
+				{"type": "return", "value": {"type": "variable", "name": "s"}}]`),
+		},
+		{
+			name: "array of ints",
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [
+					{"type": "literal", "value": 10}, {"type": "literal", "value": 20}, {"type": "literal", "value": 30}]}},
+				{"type": "return", "value": {"type": "binary", "op": "+",
+					"left": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 0}},
+					"right": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 2}}}}]`),
+		},
+		{
+			name: "array of floats",
+			source: differentialMain("float", `[
+				{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [
+					{"type": "literal", "value": 0.5}, {"type": "literal", "value": 1.25}, {"type": "literal", "value": 2.75}]}},
+				{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 1}}}]`),
+		},
+		{
+			name: "array of strings",
+			source: differentialMain("string", `[
+				{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [
+					{"type": "literal", "value": "red"}, {"type": "literal", "value": "green"}, {"type": "literal", "value": "blue"}]}},
+				{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 2}}}]`),
+		},
+		{
+			name:   "map lookup by int",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "m", "value": {"type": "map_literal", "pairs": [
+					{"key": {"type": "literal", "value": 1}, "value": {"type": "literal", "value": 100}},
+					{"key": {"type": "literal", "value": 2}, "value": {"type": "literal", "value": 200}}]}},
+				{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "m"}, "index": {"type": "literal", "value": 2}}}]`),
+		},
+		{
+			name:   "map lookup by string",
+			stdlib: true,
+			source: differentialMain("string", `[
+				{"type": "assign", "target": "m", "value": {"type": "map_literal", "pairs": [
+					{"key": {"type": "literal", "value": "en"}, "value": {"type": "literal", "value": "hello"}},
+					{"key": {"type": "literal", "value": "fr"}, "value": {"type": "literal", "value": "bonjour"}}]}},
+				{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "m"}, "index": {"type": "literal", "value": "fr"}}}]`),
+		},
+		{
+			name:   "length of an array",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [
+					{"type": "literal", "value": 1}, {"type": "literal", "value": 2}, {"type": "literal", "value": 3}]}},
+				{"type": "return", "value": {"type": "builtin", "name": "collections.length", "args": [{"type": "variable", "name": "a"}]}}]`),
+		},
+		{
+			name:   "large array on the heap",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "a", "value": {"type": "array_literal", "elements": [`+strings.Join(largeArray, ", ")+`]}},
+				{"type": "return", "value": {"type": "binary", "op": "+",
+					"left": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": 1000}},
+					"right": {"type": "index", "object": {"type": "variable", "name": "a"}, "index": {"type": "literal", "value": `+fmt.Sprint(len(largeArray)-1)+`}}}}]`),
+		},
+		{
+			name:   "length of a map",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "m", "value": {"type": "map_literal", "pairs": [
+					{"key": {"type": "literal", "value": "a"}, "value": {"type": "literal", "value": 1}},
+					{"key": {"type": "literal", "value": "b"}, "value": {"type": "literal", "value": 2}}]}},
+				{"type": "return", "value": {"type": "builtin", "name": "collections.length", "args": [{"type": "variable", "name": "m"}]}}]`),
+		},
+		{
+			name:   "length of a string",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "return", "value": {"type": "builtin", "name": "collections.length", "args": [{"type": "literal", "value": "hello"}]}}]`),
+		},
+		{
+			name:   "environment variable set by the program",
+			stdlib: true,
+			source: differentialMain("string", `[
+				{"type": "expr", "value": {"type": "builtin", "name": "os.setenv", "args": [
+					{"type": "literal", "value": "ALAS_DIFFERENTIAL_ENV"}, {"type": "literal", "value": "configured"}]}},
+				{"type": "return", "value": {"type": "builtin", "name": "os.getenv", "args": [{"type": "literal", "value": "ALAS_DIFFERENTIAL_ENV"}]}}]`),
+		},
+		{
+			name:   "missing environment variable",
+			stdlib: true,
+			source: differentialMain("string", `[
+				{"type": "return", "value": {"type": "builtin", "name": "os.getenv", "args": [{"type": "literal", "value": "ALAS_DIFFERENTIAL_UNSET"}]}}]`),
+		},
+		{
+			name:   "map entry assignment",
+			stdlib: true,
+			source: differentialMain("int", `[
+				{"type": "assign", "target": "m", "value": {"type": "map_literal", "pairs": [
+					{"key": {"type": "literal", "value": "a"}, "value": {"type": "literal", "value": 1}}]}},
+				{"type": "assign", "lvalue": {"type": "index", "object": {"type": "variable", "name": "m"}, "index": {"type": "literal", "value": "a"}},
+					"value": {"type": "literal", "value": 10}},
+				{"type": "assign", "lvalue": {"type": "index", "object": {"type": "variable", "name": "m"}, "index": {"type": "literal", "value": "b"}},
+					"value": {"type": "literal", "value": 32}},
+				{"type": "return", "value": {"type": "index", "object": {"type": "variable", "name": "m"}, "index": {"type": "literal", "value": "b"}}}]`),
+		},
+		{
+			name: "struct field assignment",
+			source: []byte(`{"type": "module", "name": "differential",
+				"types": [{"name": "Point", "definition": {"kind": "struct", "fields": [{"name": "x", "type": "int"}, {"name": "y", "type": "int"}]}}],
+				"functions": [
+					{"type": "function", "name": "origin", "params": [], "returns": "Point", "body": [
+						{"type": "return", "value": {"type": "map_literal", "pairs": [
+							{"key": {"type": "literal", "value": "x"}, "value": {"type": "literal", "value": 0}},
+							{"key": {"type": "literal", "value": "y"}, "value": {"type": "literal", "value": 0}}]}}]},
+					{"type": "function", "name": "moved", "params": [{"name": "p", "type": "Point"}], "returns": "int", "body": [
+						{"type": "assign", "target": "q", "value": {"type": "variable", "name": "p"}},
+						{"type": "assign", "lvalue": {"type": "field", "object": {"type": "variable", "name": "p"}, "field": "y"},
+							"value": {"type": "literal", "value": 7}},
+						{"type": "return", "value": {"type": "binary", "op": "-",
+							"left": {"type": "field", "object": {"type": "variable", "name": "p"}, "field": "y"},
+							"right": {"type": "field", "object": {"type": "variable", "name": "q"}, "field": "y"}}}]},
+					{"type": "function", "name": "main", "params": [], "returns": "int", "body": [
+						{"type": "return", "value": {"type": "call", "name": "moved", "args": [{"type": "call", "name": "origin", "args": []}]}}]}]}`),
+		},
+	}
+}
+
+// differentialExampleCases returns a case for every example program.
+func differentialExampleCases(t *testing.T) []differentialCase {
+	files, _ := filepath.Glob("examples/programs/*.alas.json")
+	if len(files) == 0 {
+		// Try with ../ prefix in case we're still in tests directory
+		files, _ = filepath.Glob("../examples/programs/*.alas.json")
+	}
+	var cases []differentialCase
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		name := filepath.Base(file)
+		cases = append(cases, differentialCase{
+			name:     name,
+			source:   data,
+			stdlib:   differentialStdlibExamples[name],
+			diverges: differentialKnownDivergences[name],
+		})
+	}
+	return cases
+}
+
+// stdlibDir returns the absolute path of the directory holding the built
+// standard library, or "" if it has not been built.
+func stdlibDir() string {
+	for _, dir := range []string{"lib", "../lib"} {
+		if _, err := os.Stat(filepath.Join(dir, "libalas_stdlib.so")); err == nil {
+			if abs, err := filepath.Abs(dir); err == nil {
+				return abs
+			}
+		}
+	}
+	return ""
+}
+
+// TestDifferential runs example programs and generated modules in the
+// interpreter and compiled, and checks that main returns the same result on
+// both backends. Cases with a known divergence are skipped while they
+// disagree at any optimization level, and fail once they agree at all of
+// them so that the note can be removed.
+func TestDifferential(t *testing.T) {
+	libDir := stdlibDir()
+	cases := append(differentialGeneratedCases(), differentialExampleCases(t)...)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.stdlib && libDir == "" {
+				t.Skip("Skipping test, standard library not built (make build-stdlib)")
+			}
+
+			module, err := ast.ParseModule(tc.source, tc.name)
+			if err != nil {
+				t.Fatalf("Failed to parse JSON: %v", err)
+			}
+
+			var disagreements []string
+			for _, level := range []codegen.OptimizationLevel{codegen.OptNone, codegen.OptStandard} {
+				opts := bench.DefaultOptions()
+				opts.Iterations = 1
+				opts.OptLevel = level
+				if tc.stdlib {
+					opts.LibDir = libDir
+				}
+
+				result, err := bench.Run(module, opts)
+				if errors.Is(err, bench.ErrToolchainNotFound) {
+					t.Skip(err)
+				}
+				if err != nil {
+					disagreements = append(disagreements, fmt.Sprintf("%s: %v", getOptLevelString(level), err))
+				} else if !result.Match() {
+					disagreements = append(disagreements, fmt.Sprintf("%s: interpreter returned %q, compiled program %q",
+						getOptLevelString(level), result.Interpreted, result.Compiled))
+				}
+			}
+
+			switch {
+			case tc.diverges != "" && len(disagreements) > 0:
+				t.Skipf("Known divergence, %s:\n%s", tc.diverges, strings.Join(disagreements, "\n"))
+			case tc.diverges != "":
+				t.Errorf("Backends now agree, remove the known divergence %q", tc.diverges)
+			}
+			for _, disagreement := range disagreements {
+				t.Error(disagreement)
+			}
+		})
+	}
+}
@@ -432,7 +432,7 @@ func TestModuleSystem(t *testing.T) {
 	mainModule := &ast.Module{
 		Type:    "module",
 		Name:    "main",
-		Imports: []string{"math_utils"},
+		Imports: []ast.Import{{Module: "math_utils"}},
 		Functions: []ast.Function{
 			{
 				Type:    "function",
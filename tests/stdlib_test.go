@@ -110,6 +110,54 @@ func TestStandardLibraryMath(t *testing.T) {
 			args:     []runtime.Value{runtime.NewFloat(3.14), runtime.NewFloat(2.71)},
 			expected: runtime.NewFloat(2.71),
 		},
+		{
+			name:     "math.pow promotes int arguments",
+			function: "math.pow",
+			args:     []runtime.Value{runtime.NewInt(2), runtime.NewInt(10)},
+			expected: runtime.NewFloat(1024),
+		},
+		{
+			name:     "math.floor",
+			function: "math.floor",
+			args:     []runtime.Value{runtime.NewFloat(-2.5)},
+			expected: runtime.NewFloat(-3),
+		},
+		{
+			name:     "math.ceil",
+			function: "math.ceil",
+			args:     []runtime.Value{runtime.NewFloat(2.1)},
+			expected: runtime.NewFloat(3),
+		},
+		{
+			name:     "math.round",
+			function: "math.round",
+			args:     []runtime.Value{runtime.NewFloat(2.5)},
+			expected: runtime.NewFloat(3),
+		},
+		{
+			name:     "math.sin",
+			function: "math.sin",
+			args:     []runtime.Value{runtime.NewInt(0)},
+			expected: runtime.NewFloat(0),
+		},
+		{
+			name:     "math.cos",
+			function: "math.cos",
+			args:     []runtime.Value{runtime.NewInt(0)},
+			expected: runtime.NewFloat(1),
+		},
+		{
+			name:     "math.log",
+			function: "math.log",
+			args:     []runtime.Value{runtime.NewInt(1)},
+			expected: runtime.NewFloat(0),
+		},
+		{
+			name:     "math.pi",
+			function: "math.pi",
+			args:     []runtime.Value{},
+			expected: runtime.NewFloat(3.141592653589793),
+		},
 	}
 
 	for _, tc := range mathTests {
@@ -124,6 +172,10 @@ func TestStandardLibraryMath(t *testing.T) {
 			}
 		})
 	}
+
+	if _, err := interp.CallBuiltinFunction("math.log", []runtime.Value{runtime.NewInt(0)}); err == nil {
+		t.Error("expected math.log of 0 to fail")
+	}
 }
 
 func TestStandardLibraryCollections(t *testing.T) {
@@ -258,6 +310,24 @@ func TestStandardLibraryType(t *testing.T) {
 			args:     []runtime.Value{runtime.NewString("hello")},
 			expected: runtime.NewBool(false),
 		},
+		{
+			name:     "type.parseInt",
+			function: "type.parseInt",
+			args:     []runtime.Value{runtime.NewString("-17")},
+			expected: runtime.NewInt(-17),
+		},
+		{
+			name:     "type.parseFloat",
+			function: "type.parseFloat",
+			args:     []runtime.Value{runtime.NewString("2.5")},
+			expected: runtime.NewFloat(2.5),
+		},
+		{
+			name:     "type.toString float",
+			function: "type.toString",
+			args:     []runtime.Value{runtime.NewFloat(2.5)},
+			expected: runtime.NewString("2.5"),
+		},
 	}
 
 	for _, tc := range typeTests {
@@ -272,4 +342,10 @@ func TestStandardLibraryType(t *testing.T) {
 			}
 		})
 	}
+
+	for _, function := range []string{"type.parseInt", "type.parseFloat"} {
+		if _, err := interp.CallBuiltinFunction(function, []runtime.Value{runtime.NewString("4x")}); err == nil {
+			t.Errorf("expected %s to fail on invalid input", function)
+		}
+	}
 }